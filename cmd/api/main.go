@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
@@ -20,13 +21,31 @@ import (
 	"github.com/jwalitptl/admin-api/internal/handler/appointment"
 	auditHandler "github.com/jwalitptl/admin-api/internal/handler/audit"
 	authHandler "github.com/jwalitptl/admin-api/internal/handler/auth"
+	ccdaHandler "github.com/jwalitptl/admin-api/internal/handler/ccda"
 	"github.com/jwalitptl/admin-api/internal/handler/clinic"
+	clinicalListHandler "github.com/jwalitptl/admin-api/internal/handler/clinicallist"
+	complianceHandler "github.com/jwalitptl/admin-api/internal/handler/compliance"
+	documentHandler "github.com/jwalitptl/admin-api/internal/handler/document"
 	"github.com/jwalitptl/admin-api/internal/handler/health"
+	hl7Handler "github.com/jwalitptl/admin-api/internal/handler/hl7"
+	identifierHandler "github.com/jwalitptl/admin-api/internal/handler/identifier"
+	keyringHandler "github.com/jwalitptl/admin-api/internal/handler/keyring"
+	medicalHandler "github.com/jwalitptl/admin-api/internal/handler/medical"
+	noteHandler "github.com/jwalitptl/admin-api/internal/handler/note"
+	observationHandler "github.com/jwalitptl/admin-api/internal/handler/observation"
 	"github.com/jwalitptl/admin-api/internal/handler/patient"
 	permissionHandler "github.com/jwalitptl/admin-api/internal/handler/permission"
+	prescriptionHandler "github.com/jwalitptl/admin-api/internal/handler/prescription"
 	"github.com/jwalitptl/admin-api/internal/handler/prometheus"
 	rbacHandler "github.com/jwalitptl/admin-api/internal/handler/rbac"
+	referralHandler "github.com/jwalitptl/admin-api/internal/handler/referral"
+	relationshipHandler "github.com/jwalitptl/admin-api/internal/handler/relationship"
+	reminderHandler "github.com/jwalitptl/admin-api/internal/handler/reminder"
+	scheduleHandler "github.com/jwalitptl/admin-api/internal/handler/schedule"
+	terminologyHandler "github.com/jwalitptl/admin-api/internal/handler/terminology"
+	timelineHandler "github.com/jwalitptl/admin-api/internal/handler/timeline"
 	"github.com/jwalitptl/admin-api/internal/handler/user"
+	waitlistHandler "github.com/jwalitptl/admin-api/internal/handler/waitlist"
 	"github.com/jwalitptl/admin-api/internal/middleware"
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository/postgres"
@@ -35,19 +54,40 @@ import (
 	appointmentService "github.com/jwalitptl/admin-api/internal/service/appointment"
 	"github.com/jwalitptl/admin-api/internal/service/audit"
 	"github.com/jwalitptl/admin-api/internal/service/auth"
+	ccdaService "github.com/jwalitptl/admin-api/internal/service/ccda"
 	clinicService "github.com/jwalitptl/admin-api/internal/service/clinic"
+	clinicalListService "github.com/jwalitptl/admin-api/internal/service/clinicallist"
+	complianceService "github.com/jwalitptl/admin-api/internal/service/compliance"
+	documentService "github.com/jwalitptl/admin-api/internal/service/document"
 	"github.com/jwalitptl/admin-api/internal/service/email"
 	"github.com/jwalitptl/admin-api/internal/service/geoip"
+	hl7Service "github.com/jwalitptl/admin-api/internal/service/hl7"
+	identifierService "github.com/jwalitptl/admin-api/internal/service/identifier"
+	"github.com/jwalitptl/admin-api/internal/service/insurance"
+	keyringService "github.com/jwalitptl/admin-api/internal/service/keyring"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	noteService "github.com/jwalitptl/admin-api/internal/service/note"
 	"github.com/jwalitptl/admin-api/internal/service/notification"
+	observationService "github.com/jwalitptl/admin-api/internal/service/observation"
 	patientService "github.com/jwalitptl/admin-api/internal/service/patient"
 	permissionService "github.com/jwalitptl/admin-api/internal/service/permission"
+	prescriptionService "github.com/jwalitptl/admin-api/internal/service/prescription"
 	rbacService "github.com/jwalitptl/admin-api/internal/service/rbac"
+	referralService "github.com/jwalitptl/admin-api/internal/service/referral"
 	"github.com/jwalitptl/admin-api/internal/service/region"
+	relationshipService "github.com/jwalitptl/admin-api/internal/service/relationship"
+	reminderService "github.com/jwalitptl/admin-api/internal/service/reminder"
+	scheduleService "github.com/jwalitptl/admin-api/internal/service/schedule"
+	terminologyService "github.com/jwalitptl/admin-api/internal/service/terminology"
+	timelineService "github.com/jwalitptl/admin-api/internal/service/timeline"
 	userService "github.com/jwalitptl/admin-api/internal/service/user"
+	waitlistService "github.com/jwalitptl/admin-api/internal/service/waitlist"
 	pkg_event "github.com/jwalitptl/admin-api/pkg/event"
 	"github.com/jwalitptl/admin-api/pkg/messaging"
 	"github.com/jwalitptl/admin-api/pkg/messaging/redis"
 	"github.com/jwalitptl/admin-api/pkg/metrics"
+	"github.com/jwalitptl/admin-api/pkg/security"
+	"github.com/jwalitptl/admin-api/pkg/storage"
 	"github.com/jwalitptl/admin-api/pkg/worker"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -86,6 +126,38 @@ func main() {
 	}
 	defer db.Close()
 
+	if cfg.Encryption.Key == "" {
+		log.Fatal().Msg("encryption.key is not set")
+	}
+	encryptionKey, err := hex.DecodeString(cfg.Encryption.Key)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid encryption key")
+	}
+	encryptor, err := security.NewAESEncryptor(encryptionKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize encryptor")
+	}
+
+	// Medical records are sealed under per-organization data keys wrapped by
+	// the KMS; the key above still opens data written before that
+	kms, err := security.NewLocalKMS(cfg.Encryption.KMSKeyFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize KMS")
+	}
+
+	// Patient PII is encrypted per field, searchable through blind indexes
+	if cfg.Encryption.BlindIndexKey == "" {
+		log.Fatal().Msg("encryption.blind_index_key is not set")
+	}
+	blindIndexKey, err := hex.DecodeString(cfg.Encryption.BlindIndexKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid blind index key")
+	}
+	piiCipher, err := security.NewFieldCipher(encryptor, blindIndexKey, 1)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize PII field cipher")
+	}
+
 	// Initialize repositories first
 	baseRepo := postgres.NewBaseRepository(db)
 	accountRepo := postgres.NewAccountRepository(baseRepo)
@@ -95,7 +167,9 @@ func main() {
 	userRepo := postgres.NewUserRepository(baseRepo)
 	rbacRepo := postgres.NewRBACRepository(baseRepo)
 	appointmentRepo := postgres.NewAppointmentRepository(baseRepo)
-	patientRepo := postgres.NewPatientRepository(baseRepo)
+	appointmentSeriesRepo := postgres.NewAppointmentSeriesRepository(baseRepo)
+	serviceRepo := postgres.NewServiceRepository(baseRepo)
+	patientRepo := postgres.NewPatientRepository(baseRepo, piiCipher)
 	permRepo := postgres.NewPermissionRepository(baseRepo)
 	outboxRepo := postgres.NewOutboxRepository(baseRepo)
 	auditRepo := postgres.NewAuditRepository(baseRepo)
@@ -103,6 +177,28 @@ func main() {
 	tokenRepo := postgres.NewTokenRepository(baseRepo)
 	notificationRepo := postgres.NewNotificationRepository(baseRepo)
 	medicalRecordRepo := postgres.NewMedicalRecordRepository(baseRepo)
+	complianceRepo := postgres.NewComplianceRepository(baseRepo)
+	consentRepo := postgres.NewConsentRepository(baseRepo)
+	legalHoldRepo := postgres.NewLegalHoldRepository(baseRepo)
+	erasureRepo := postgres.NewErasureRepository(baseRepo)
+	insuranceRepo := postgres.NewInsuranceRepository(baseRepo)
+	relationshipRepo := postgres.NewRelationshipRepository(baseRepo)
+	documentRepo := postgres.NewDocumentRepository(baseRepo)
+	identifierRepo := postgres.NewIdentifierRepository(baseRepo)
+	terminologyRepo := postgres.NewTerminologyRepository(baseRepo)
+	prescriptionRepo := postgres.NewPrescriptionRepository(baseRepo)
+	hl7Repo := postgres.NewHL7Repository(baseRepo)
+	careTeamRepo := postgres.NewCareTeamRepository(baseRepo)
+	dataKeyRepo := postgres.NewDataKeyRepository(baseRepo)
+	noteRepo := postgres.NewClinicalNoteRepository(baseRepo)
+	referralRepo := postgres.NewReferralRepository(baseRepo)
+	ccdaRepo := postgres.NewCCDARepository(baseRepo)
+	observationRepo := postgres.NewObservationRepository(baseRepo)
+	clinicalListRepo := postgres.NewClinicalListRepository(baseRepo)
+	scheduleRepo := postgres.NewScheduleRepository(baseRepo)
+	waitlistRepo := postgres.NewWaitlistRepository(baseRepo)
+	reminderRepo := postgres.NewReminderRepository(baseRepo)
+	timelineRepo := postgres.NewTimelineRepository(baseRepo)
 
 	// Initialize core services first
 	emailSvc := email.NewService(cfg.Email)
@@ -130,11 +226,153 @@ func main() {
 	userSvc := userService.NewService(userRepo, emailSvc, tokenRepo, auditSvc)
 	rbacSvc := rbacService.NewService(rbacRepo, auditSvc)
 	authSvc := auth.NewService(userRepo, jwtSvc, tokenRepo, emailSvc, auditSvc)
-	notificationSvc := notification.NewService(notificationRepo, emailSvc, broker, auditSvc)
-	appointmentSvc := appointmentService.NewService(appointmentRepo, notificationSvc, clinicianRepo, auditSvc)
+	relationshipSvc := relationshipService.NewService(relationshipRepo, patientRepo, userRepo, auditSvc)
+	notificationSvc := notification.NewService(notificationRepo, emailSvc, broker, relationshipSvc, auditSvc)
+	scheduleSvc := scheduleService.NewService(scheduleRepo, userRepo, clinicRepo, auditSvc)
+	// Reminders are sent by the worker; the API schedules them and answers
+	// their confirm and cancel links
+	reminderKey, err := cfg.Reminders.Key()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid reminder configuration")
+	}
+	reminderSvc := reminderService.NewService(
+		reminderRepo,
+		appointmentRepo,
+		patientRepo,
+		serviceRepo,
+		clinicRepo,
+		notificationSvc,
+		security.NewURLSigner(reminderKey),
+		auditSvc,
+		reminderService.Config{
+			BaseURL:      cfg.Reminders.BaseURL,
+			DefaultRules: cfg.Reminders.ToReminderRules(),
+			BatchSize:    cfg.Reminders.BatchSize,
+			MaxAttempts:  cfg.Reminders.MaxAttempts,
+		},
+	)
+	waitlistSvc := waitlistService.NewService(waitlistRepo, patientRepo, clinicRepo, notificationSvc, reminderSvc, auditSvc, waitlistService.Config{
+		OfferTTL:      cfg.Waitlist.OfferTTL,
+		OffersPerSlot: cfg.Waitlist.OffersPerSlot,
+	})
+	appointmentSvc := appointmentService.NewService(appointmentRepo, appointmentSeriesRepo, notificationSvc, clinicianRepo, serviceRepo, scheduleSvc, waitlistSvc, reminderSvc, auditSvc)
 	permSvc := permissionService.NewService(permRepo, auditSvc)
-	patientSvc := patientService.NewService(patientRepo, medicalRecordRepo, appointmentRepo, auditSvc)
+	identifierSvc := identifierService.NewService(identifierRepo, patientRepo, auditSvc)
+	patientSvc := patientService.NewService(patientRepo, medicalRecordRepo, appointmentRepo, identifierSvc, auditSvc)
 	regionSvc := region.NewService(regionRepo, geoIP, auditSvc, defaultConfig)
+	insuranceSvc := insurance.NewService(insuranceRepo, patientRepo, appointmentRepo, insurance.NewFakeEligibilityProvider(), auditSvc)
+
+	timelineSvc := timelineService.NewService(timelineRepo, patientRepo, auditSvc)
+	terminologySvc := terminologyService.NewService(terminologyRepo, auditSvc, terminologyService.Config{
+		ReleaseDir: cfg.Terminology.ReleaseDir,
+	})
+	keyringSvc := keyringService.NewService(dataKeyRepo, kms, encryptor, auditSvc, keyringService.Config{
+		ActiveKeyTTL: cfg.Encryption.DataKeyCacheTTL,
+	})
+	medicalSvc := medical.NewService(medicalRecordRepo, careTeamRepo, keyringSvc, terminologySvc, auditSvc)
+	noteSvc := noteService.NewService(noteRepo, patientRepo, auditRepo, keyringSvc, auditSvc)
+	referralSvc := referralService.NewService(referralRepo, patientRepo, medicalRecordRepo, medicalSvc, auditSvc)
+	observationSvc := observationService.NewService(observationRepo, patientRepo, appointmentRepo, auditSvc)
+	clinicalListSvc := clinicalListService.NewService(clinicalListRepo, patientRepo, terminologySvc, auditSvc)
+	medicalSvc.AddRecordListener(clinicalListSvc)
+	prescriptionSvc := prescriptionService.NewService(prescriptionRepo, patientRepo, auditSvc, prescriptionService.Config{
+		DatasetDir: cfg.Prescriptions.DatasetDir,
+	})
+	// Queued messages are resolved through the API, so it files results as
+	// the same interface user as the worker
+	hl7SystemUserID, err := cfg.HL7.SystemUser()
+	if err != nil && cfg.HL7.Enabled {
+		log.Fatal().Err(err).Msg("invalid HL7 configuration")
+	}
+	hl7OrganizationID, err := cfg.HL7.Organization()
+	if err != nil && cfg.HL7.Enabled {
+		log.Fatal().Err(err).Msg("invalid HL7 configuration")
+	}
+	hl7Svc := hl7Service.NewService(hl7Repo, patientSvc, identifierSvc, medicalSvc, encryptor, auditSvc, hl7Service.Config{
+		AssigningAuthorities: cfg.HL7.AssigningAuthorities,
+		LabAccessLevel:       cfg.HL7.LabAccessLevel,
+		SystemUserID:         hl7SystemUserID,
+		OrganizationID:       hl7OrganizationID,
+	})
+	ccdaSvc := ccdaService.NewService(
+		ccdaRepo,
+		patientRepo,
+		organizationRepo,
+		identifierRepo,
+		appointmentRepo,
+		prescriptionRepo,
+		clinicalListRepo,
+		medicalSvc,
+		terminologySvc,
+		keyringSvc,
+		auditSvc,
+		ccdaService.Config{
+			ImportAccessLevel: cfg.CCDA.ImportAccessLevel,
+			MaxDocumentBytes:  cfg.CCDA.MaxDocumentBytes,
+		},
+	)
+	documentStore, err := storage.New(storage.Config{
+		Backend:  cfg.Storage.Backend,
+		LocalDir: cfg.Storage.LocalDir,
+		S3: storage.S3Config{
+			Endpoint:     cfg.Storage.S3.Endpoint,
+			Region:       cfg.Storage.S3.Region,
+			Bucket:       cfg.Storage.S3.Bucket,
+			AccessKey:    cfg.Storage.S3.AccessKey,
+			SecretKey:    cfg.Storage.S3.SecretKey,
+			UsePathStyle: cfg.Storage.S3.UsePathStyle,
+		},
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize document storage")
+	}
+	complianceKey, err := cfg.Compliance.Key()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid compliance configuration")
+	}
+	complianceSvc := complianceService.NewService(
+		complianceRepo,
+		consentRepo,
+		patientRepo,
+		appointmentRepo,
+		notificationRepo,
+		auditRepo,
+		regionRepo,
+		legalHoldRepo,
+		erasureRepo,
+		documentRepo,
+		documentStore,
+		medicalSvc,
+		security.NewURLSigner(complianceKey),
+		auditSvc,
+		complianceService.Config{
+			ArtifactDir: cfg.Compliance.ArtifactDir,
+			BaseURL:     cfg.Compliance.BaseURL,
+			LinkTTL:     cfg.Compliance.LinkTTL,
+			ExportTTL:   cfg.Compliance.ExportTTL,
+		},
+	)
+
+	documentKey, err := cfg.Storage.Key()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid storage configuration")
+	}
+	documentSvc := documentService.NewService(
+		documentRepo,
+		patientRepo,
+		medicalRecordRepo,
+		medicalSvc,
+		documentStore,
+		encryptor,
+		security.NewURLSigner(documentKey),
+		auditSvc,
+		documentService.Config{
+			BaseURL:        cfg.Storage.BaseURL,
+			LinkTTL:        cfg.Storage.LinkTTL,
+			MaxUploadBytes: cfg.Storage.MaxUploadBytes,
+			AllowedTypes:   cfg.Storage.AllowedTypes,
+		},
+	)
 
 	// Initialize event tracking middleware
 	eventTracker := pkg_event.NewEventTrackerMiddleware(eventSvc)
@@ -148,12 +386,31 @@ func main() {
 	rbacHandler := rbacHandler.NewHandler(rbacSvc, outboxRepo)
 	appointmentHandler := appointment.NewHandler(appointmentSvc, outboxRepo)
 	permHandler := permissionHandler.NewHandler(permSvc, outboxRepo)
-	patientHandler := patient.NewHandler(patientSvc, outboxRepo, regionSvc)
+	patientHandler := patient.NewHandler(patientSvc, insuranceSvc, outboxRepo, regionSvc)
 	auditHandler := auditHandler.NewHandler(auditSvc)
+	complianceHandler := complianceHandler.NewHandler(complianceSvc)
+	relationshipHandler := relationshipHandler.NewHandler(relationshipSvc)
+	documentHandler := documentHandler.NewHandler(documentSvc)
+	timelineHandler := timelineHandler.NewHandler(timelineSvc)
+	identifierHandler := identifierHandler.NewHandler(identifierSvc)
+	medicalHandler := medicalHandler.NewHandler(medicalSvc)
+	terminologyHandler := terminologyHandler.NewHandler(terminologySvc)
+	prescriptionHandler := prescriptionHandler.NewHandler(prescriptionSvc)
+	hl7Handler := hl7Handler.NewHandler(hl7Svc)
+	keyringHandler := keyringHandler.NewHandler(keyringSvc)
+	noteHandler := noteHandler.NewHandler(noteSvc)
+	referralHandler := referralHandler.NewHandler(referralSvc)
+	ccdaHandler := ccdaHandler.NewHandler(ccdaSvc)
+	observationHandler := observationHandler.NewHandler(observationSvc)
+	clinicalListHandler := clinicalListHandler.NewHandler(clinicalListSvc)
+	scheduleHandler := scheduleHandler.NewHandler(scheduleSvc)
+	waitlistHandler := waitlistHandler.NewHandler(waitlistSvc)
+	reminderHandler := reminderHandler.NewHandler(reminderSvc, appointmentSvc)
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(rbacSvc, authSvc)
 	hipaaMiddleware := middleware.NewHIPAAMiddleware(auditSvc)
+	proxyMiddleware := middleware.NewProxyMiddleware(relationshipSvc)
 
 	// Initialize region middleware
 	regionMiddleware := middleware.NewRegionMiddleware(regionSvc, middleware.RegionConfig{
@@ -164,20 +421,39 @@ func main() {
 	// Setup router
 	r := router.NewRouter(
 		router.Config{
-			AuthMiddleware:     authMiddleware,
-			HIPAAMiddleware:    hipaaMiddleware,
-			RegionMiddleware:   regionMiddleware,
-			RegionValidation:   regionValidation,
-			AccountHandler:     accountHandler,
-			AuthHandler:        authHandler,
-			ClinicHandler:      clinicHandler,
-			UserHandler:        userHandler,
-			RBACHandler:        rbacHandler,
-			AppointmentHandler: appointmentHandler,
-			PermissionHandler:  permHandler,
-			PatientHandler:     patientHandler,
-			BaseHandler:        h,
-			EventTracker:       eventTracker,
+			AuthMiddleware:      authMiddleware,
+			HIPAAMiddleware:     hipaaMiddleware,
+			ProxyMiddleware:     proxyMiddleware,
+			RegionMiddleware:    regionMiddleware,
+			RegionValidation:    regionValidation,
+			AccountHandler:      accountHandler,
+			AuthHandler:         authHandler,
+			ClinicHandler:       clinicHandler,
+			UserHandler:         userHandler,
+			RBACHandler:         rbacHandler,
+			AppointmentHandler:  appointmentHandler,
+			PermissionHandler:   permHandler,
+			PatientHandler:      patientHandler,
+			ComplianceHandler:   complianceHandler,
+			RelationshipHandler: relationshipHandler,
+			DocumentHandler:     documentHandler,
+			TimelineHandler:     timelineHandler,
+			IdentifierHandler:   identifierHandler,
+			MedicalHandler:      medicalHandler,
+			TerminologyHandler:  terminologyHandler,
+			PrescriptionHandler: prescriptionHandler,
+			HL7Handler:          hl7Handler,
+			KeyringHandler:      keyringHandler,
+			NoteHandler:         noteHandler,
+			ReferralHandler:     referralHandler,
+			CCDAHandler:         ccdaHandler,
+			ObservationHandler:  observationHandler,
+			ClinicalListHandler: clinicalListHandler,
+			ScheduleHandler:     scheduleHandler,
+			WaitlistHandler:     waitlistHandler,
+			ReminderHandler:     reminderHandler,
+			BaseHandler:         h,
+			EventTracker:        eventTracker,
 		},
 	)
 
@@ -236,6 +512,42 @@ func main() {
 	// Start audit cleanup worker
 	go auditCleanup.Start(processorCtx)
 
+	// Start compliance worker for queued data subject requests
+	complianceWorker := worker.NewComplianceWorker(
+		complianceSvc,
+		cfg.Compliance.BatchSize,
+		cfg.Compliance.PollInterval,
+		&logger.Logger{ZL: log.Logger},
+	)
+	go complianceWorker.Start(processorCtx)
+
+	// Expire unanswered waitlist offers and offer their slots onward
+	waitlistWorker := worker.NewWaitlistWorker(
+		waitlistSvc,
+		cfg.Waitlist.PollInterval,
+		&logger.Logger{ZL: log.Logger},
+	)
+	go waitlistWorker.Start(processorCtx)
+
+	// Encrypt patient rows written before field encryption was enabled
+	piiBackfill := worker.NewPIIBackfillWorker(
+		patientRepo,
+		cfg.Encryption.BackfillBatchSize,
+		cfg.Encryption.BackfillInterval,
+		&logger.Logger{ZL: log.Logger},
+	)
+	go piiBackfill.Start(processorCtx)
+
+	// Finish data key and key-encryption key rotations
+	keyRotation := worker.NewKeyRotationWorker(
+		keyringSvc,
+		[]worker.Reencrypter{medicalSvc, noteSvc, ccdaSvc},
+		cfg.Encryption.RotationBatchSize,
+		cfg.Encryption.RotationInterval,
+		&logger.Logger{ZL: log.Logger},
+	)
+	go keyRotation.Start(processorCtx)
+
 	// Register audit routes
 	r.Engine().Group("/audit").Use(authMiddleware.Authenticate()).
 		Use(authMiddleware.RequireRole(model.UserTypeAdmin)).
@@ -0,0 +1,82 @@
+// Command hl7send is a local HL7 v2 test sender. It reads one or more
+// messages from a file, separated by blank lines, sends each over MLLP and
+// prints the acknowledgment it gets back.
+//
+//	go run ./cmd/hl7send -addr localhost:2575 -file cmd/hl7send/testdata/oru_r01.hl7
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jwalitptl/admin-api/pkg/hl7"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:2575", "MLLP listener address")
+	file := flag.String("file", "", "file holding the messages to send")
+	timeout := flag.Duration("timeout", 10*time.Second, "time to wait for each ACK")
+	flag.Parse()
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "usage: hl7send -addr host:port -file messages.hl7")
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read %s: %v\n", *file, err)
+		os.Exit(1)
+	}
+
+	conn, err := net.Dial("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to connect to %s: %v\n", *addr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+	client := hl7.NewClient(conn)
+
+	failed := false
+	for _, msg := range splitMessages(data) {
+		ack, err := client.Send(msg, *timeout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		code, text := hl7.AckStatus(ack)
+		sent, _ := hl7.Parse(msg)
+		controlID := ""
+		if sent != nil {
+			controlID = sent.ControlID()
+		}
+		fmt.Printf("%s\t%s\t%s\n", controlID, code, text)
+		if code != hl7.AckAccept {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// splitMessages splits on blank lines and turns the file's line endings into
+// the segment terminator HL7 expects
+func splitMessages(data []byte) [][]byte {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var messages [][]byte
+	for _, block := range strings.Split(text, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		messages = append(messages, bytes.ReplaceAll([]byte(block), []byte("\n"), []byte("\r")))
+	}
+	return messages
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
@@ -17,10 +18,23 @@ import (
 	"github.com/jwalitptl/admin-api/internal/config"
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository/postgres"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/email"
+	hl7Service "github.com/jwalitptl/admin-api/internal/service/hl7"
+	identifierService "github.com/jwalitptl/admin-api/internal/service/identifier"
+	keyringService "github.com/jwalitptl/admin-api/internal/service/keyring"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/internal/service/notification"
+	patientService "github.com/jwalitptl/admin-api/internal/service/patient"
+	relationshipService "github.com/jwalitptl/admin-api/internal/service/relationship"
+	reminderService "github.com/jwalitptl/admin-api/internal/service/reminder"
+	terminologyService "github.com/jwalitptl/admin-api/internal/service/terminology"
+	"github.com/jwalitptl/admin-api/pkg/hl7"
 	"github.com/jwalitptl/admin-api/pkg/logger"
 	"github.com/jwalitptl/admin-api/pkg/messaging"
 	"github.com/jwalitptl/admin-api/pkg/messaging/redis"
 	"github.com/jwalitptl/admin-api/pkg/metrics"
+	"github.com/jwalitptl/admin-api/pkg/security"
 	"github.com/jwalitptl/admin-api/pkg/worker"
 	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus"
@@ -180,9 +194,143 @@ func main() {
 		cancel()
 	}()
 
+	if cfg.HL7.Enabled {
+		hl7Svc, err := newHL7Service(cfg, baseRepo)
+		if err != nil {
+			logger.ZL.Fatal().Err(err).Msg("Failed to initialize HL7 interface")
+		}
+		server := &hl7.Server{
+			Addr:            cfg.HL7.ListenAddr,
+			Handler:         hl7Svc,
+			Logger:          logger,
+			IdleTimeout:     cfg.HL7.IdleTimeout,
+			MaxMessageBytes: cfg.HL7.MaxMessageBytes,
+		}
+		go func() {
+			if err := server.ListenAndServe(ctx); err != nil {
+				logger.ZL.Fatal().Err(err).Msg("MLLP listener failed")
+			}
+		}()
+	}
+
+	// Send appointment reminders as they fall due
+	reminderSvc, err := newReminderService(cfg, baseRepo, broker)
+	if err != nil {
+		logger.ZL.Fatal().Err(err).Msg("Failed to initialize appointment reminders")
+	}
+	reminderWorker := worker.NewReminderWorker(reminderSvc, cfg.Reminders.PollInterval, logger)
+	go reminderWorker.Start(ctx)
+
 	processor.Start(ctx)
 }
 
+// newEncryption builds the data-at-rest encryptor and the cipher for patient
+// PII fields
+func newEncryption(cfg *config.Config) (security.Encryptor, *security.FieldCipher, error) {
+	if cfg.Encryption.Key == "" {
+		return nil, nil, fmt.Errorf("encryption.key is not set")
+	}
+	encryptionKey, err := hex.DecodeString(cfg.Encryption.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	encryptor, err := security.NewAESEncryptor(encryptionKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize encryptor: %w", err)
+	}
+	if cfg.Encryption.BlindIndexKey == "" {
+		return nil, nil, fmt.Errorf("encryption.blind_index_key is not set")
+	}
+	blindIndexKey, err := hex.DecodeString(cfg.Encryption.BlindIndexKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid blind index key: %w", err)
+	}
+	piiCipher, err := security.NewFieldCipher(encryptor, blindIndexKey, 1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize PII field cipher: %w", err)
+	}
+	return encryptor, piiCipher, nil
+}
+
+// newReminderService builds the service that sends appointment reminders
+// through the notification service
+func newReminderService(cfg *config.Config, baseRepo postgres.BaseRepository, broker messaging.Broker) (*reminderService.Service, error) {
+	_, piiCipher, err := newEncryption(cfg)
+	if err != nil {
+		return nil, err
+	}
+	reminderKey, err := cfg.Reminders.Key()
+	if err != nil {
+		return nil, err
+	}
+
+	patientRepo := postgres.NewPatientRepository(baseRepo, piiCipher)
+	auditSvc := audit.NewService(postgres.NewAuditRepository(baseRepo))
+	// Reminders reach the patient's proxies too
+	relationshipSvc := relationshipService.NewService(postgres.NewRelationshipRepository(baseRepo), patientRepo, postgres.NewUserRepository(baseRepo), auditSvc)
+	notificationSvc := notification.NewService(postgres.NewNotificationRepository(baseRepo), email.NewService(cfg.Email), broker, relationshipSvc, auditSvc)
+
+	return reminderService.NewService(
+		postgres.NewReminderRepository(baseRepo),
+		postgres.NewAppointmentRepository(baseRepo),
+		patientRepo,
+		postgres.NewServiceRepository(baseRepo),
+		postgres.NewClinicRepository(baseRepo),
+		notificationSvc,
+		security.NewURLSigner(reminderKey),
+		auditSvc,
+		reminderService.Config{
+			BaseURL:      cfg.Reminders.BaseURL,
+			DefaultRules: cfg.Reminders.ToReminderRules(),
+			BatchSize:    cfg.Reminders.BatchSize,
+			MaxAttempts:  cfg.Reminders.MaxAttempts,
+		},
+	), nil
+}
+
+// newHL7Service builds the services inbound HL7 messages are filed through
+func newHL7Service(cfg *config.Config, baseRepo postgres.BaseRepository) (*hl7Service.Service, error) {
+	encryptor, piiCipher, err := newEncryption(cfg)
+	if err != nil {
+		return nil, err
+	}
+	kms, err := security.NewLocalKMS(cfg.Encryption.KMSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize KMS: %w", err)
+	}
+
+	patientRepo := postgres.NewPatientRepository(baseRepo, piiCipher)
+	medicalRecordRepo := postgres.NewMedicalRecordRepository(baseRepo)
+	appointmentRepo := postgres.NewAppointmentRepository(baseRepo)
+
+	auditSvc := audit.NewService(postgres.NewAuditRepository(baseRepo))
+	terminologySvc := terminologyService.NewService(postgres.NewTerminologyRepository(baseRepo), auditSvc, terminologyService.Config{
+		ReleaseDir: cfg.Terminology.ReleaseDir,
+	})
+	identifierSvc := identifierService.NewService(postgres.NewIdentifierRepository(baseRepo), patientRepo, auditSvc)
+	patientSvc := patientService.NewService(patientRepo, medicalRecordRepo, appointmentRepo, identifierSvc, auditSvc)
+	keyringSvc := keyringService.NewService(postgres.NewDataKeyRepository(baseRepo), kms, encryptor, auditSvc, keyringService.Config{
+		ActiveKeyTTL: cfg.Encryption.DataKeyCacheTTL,
+	})
+	medicalSvc := medical.NewService(medicalRecordRepo, postgres.NewCareTeamRepository(baseRepo), keyringSvc, terminologySvc, auditSvc)
+
+	systemUserID, err := cfg.HL7.SystemUser()
+	if err != nil {
+		return nil, err
+	}
+	organizationID, err := cfg.HL7.Organization()
+	if err != nil {
+		return nil, err
+	}
+
+	return hl7Service.NewService(postgres.NewHL7Repository(baseRepo), patientSvc, identifierSvc, medicalSvc, encryptor, auditSvc, hl7Service.Config{
+		AssigningAuthorities: cfg.HL7.AssigningAuthorities,
+		LabAccessLevel:       cfg.HL7.LabAccessLevel,
+		SystemUserID:         systemUserID,
+		OrganizationID:       organizationID,
+	}), nil
+}
+
 func (w *EventWorker) Start(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
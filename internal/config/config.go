@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/pkg/messaging/redis"
 	"github.com/jwalitptl/admin-api/pkg/worker"
 	"github.com/spf13/viper"
@@ -90,7 +92,132 @@ type Config struct {
 		PrometheusEnabled bool
 		MetricsPath       string
 	}
-	Outbox OutboxConfig `yaml:"outbox"`
+	Outbox        OutboxConfig       `yaml:"outbox"`
+	Encryption    EncryptionConfig   `yaml:"encryption" mapstructure:"encryption"`
+	Compliance    ComplianceConfig   `yaml:"compliance" mapstructure:"compliance"`
+	Storage       StorageConfig      `yaml:"storage" mapstructure:"storage"`
+	Terminology   TerminologyConfig  `yaml:"terminology" mapstructure:"terminology"`
+	Prescriptions PrescriptionConfig `yaml:"prescriptions" mapstructure:"prescriptions"`
+	HL7           HL7Config          `yaml:"hl7" mapstructure:"hl7"`
+	CCDA          CCDAConfig         `yaml:"ccda" mapstructure:"ccda"`
+	Waitlist      WaitlistConfig     `yaml:"waitlist" mapstructure:"waitlist"`
+	Reminders     RemindersConfig    `yaml:"reminders" mapstructure:"reminders"`
+}
+
+type EncryptionConfig struct {
+	// Key is the hex-encoded AES-256 key used for data at rest
+	Key string `yaml:"key" mapstructure:"key"`
+	// BlindIndexKey is the hex-encoded HMAC key for searchable PII indexes.
+	// It must differ from Key and is required at startup.
+	BlindIndexKey string `yaml:"blind_index_key" mapstructure:"blind_index_key"`
+	// BackfillBatchSize and BackfillInterval pace the job that encrypts
+	// patient rows written before field encryption was enabled
+	BackfillBatchSize int           `yaml:"backfill_batch_size" mapstructure:"backfill_batch_size"`
+	BackfillInterval  time.Duration `yaml:"backfill_interval" mapstructure:"backfill_interval"`
+	// KMSKeyFile holds the key-encryption keys of the local KMS that wraps
+	// each organization's data key
+	KMSKeyFile string `yaml:"kms_key_file" mapstructure:"kms_key_file"`
+	// DataKeyCacheTTL bounds how long an instance keeps using a data key
+	// after another instance rotated it
+	DataKeyCacheTTL time.Duration `yaml:"data_key_cache_ttl" mapstructure:"data_key_cache_ttl"`
+	// RotationBatchSize and RotationInterval pace the job that rewraps data
+	// keys and re-encrypts records after a rotation
+	RotationBatchSize int           `yaml:"rotation_batch_size" mapstructure:"rotation_batch_size"`
+	RotationInterval  time.Duration `yaml:"rotation_interval" mapstructure:"rotation_interval"`
+}
+
+type ComplianceConfig struct {
+	ArtifactDir  string        `yaml:"artifact_dir" mapstructure:"artifact_dir"`
+	BaseURL      string        `yaml:"base_url" mapstructure:"base_url"`
+	SigningKey   string        `yaml:"signing_key" mapstructure:"signing_key"`
+	LinkTTL      time.Duration `yaml:"link_ttl" mapstructure:"link_ttl"`
+	ExportTTL    time.Duration `yaml:"export_ttl" mapstructure:"export_ttl"`
+	BatchSize    int           `yaml:"batch_size" mapstructure:"batch_size"`
+	PollInterval time.Duration `yaml:"poll_interval" mapstructure:"poll_interval"`
+}
+
+type StorageConfig struct {
+	// Backend is "local" or "s3"
+	Backend        string          `yaml:"backend" mapstructure:"backend"`
+	LocalDir       string          `yaml:"local_dir" mapstructure:"local_dir"`
+	BaseURL        string          `yaml:"base_url" mapstructure:"base_url"`
+	SigningKey     string          `yaml:"signing_key" mapstructure:"signing_key"`
+	LinkTTL        time.Duration   `yaml:"link_ttl" mapstructure:"link_ttl"`
+	MaxUploadBytes int64           `yaml:"max_upload_bytes" mapstructure:"max_upload_bytes"`
+	AllowedTypes   []string        `yaml:"allowed_types" mapstructure:"allowed_types"`
+	S3             S3StorageConfig `yaml:"s3" mapstructure:"s3"`
+}
+
+type S3StorageConfig struct {
+	Endpoint     string `yaml:"endpoint" mapstructure:"endpoint"`
+	Region       string `yaml:"region" mapstructure:"region"`
+	Bucket       string `yaml:"bucket" mapstructure:"bucket"`
+	AccessKey    string `yaml:"access_key" mapstructure:"access_key"`
+	SecretKey    string `yaml:"secret_key" mapstructure:"secret_key"`
+	UsePathStyle bool   `yaml:"use_path_style" mapstructure:"use_path_style"`
+}
+
+type TerminologyConfig struct {
+	// ReleaseDir holds the ICD-10-CM, SNOMED CT and LOINC release files
+	ReleaseDir string `yaml:"release_dir" mapstructure:"release_dir"`
+}
+
+type PrescriptionConfig struct {
+	// DatasetDir holds the drug-drug and drug-allergy interaction datasets
+	DatasetDir string `yaml:"dataset_dir" mapstructure:"dataset_dir"`
+}
+
+// HL7Config configures the HL7 v2 MLLP listener run by the worker
+type HL7Config struct {
+	Enabled         bool          `yaml:"enabled" mapstructure:"enabled"`
+	ListenAddr      string        `yaml:"listen_addr" mapstructure:"listen_addr"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" mapstructure:"idle_timeout"`
+	MaxMessageBytes int           `yaml:"max_message_bytes" mapstructure:"max_message_bytes"`
+	// AssigningAuthorities maps PID-3 assigning authority namespaces to the
+	// identifier systems patients are matched on
+	AssigningAuthorities map[string]string `yaml:"assigning_authorities" mapstructure:"assigning_authorities"`
+	// LabAccessLevel is the access level of records filed from lab results
+	LabAccessLevel string `yaml:"lab_access_level" mapstructure:"lab_access_level"`
+	// SystemUserID is the user records filed from messages are attributed
+	// to; it must exist in users
+	SystemUserID string `yaml:"system_user_id" mapstructure:"system_user_id"`
+	// OrganizationID is the organization whose patients messages are
+	// matched against
+	OrganizationID string `yaml:"organization_id" mapstructure:"organization_id"`
+}
+
+// CCDAConfig configures C-CDA document import
+type CCDAConfig struct {
+	// ImportAccessLevel is the access level of records committed from
+	// reconciled imports
+	ImportAccessLevel string `yaml:"import_access_level" mapstructure:"import_access_level"`
+	MaxDocumentBytes  int64  `yaml:"max_document_bytes" mapstructure:"max_document_bytes"`
+}
+
+type WaitlistConfig struct {
+	// OfferTTL is how long an offered slot is held for the patient
+	OfferTTL      time.Duration `yaml:"offer_ttl" mapstructure:"offer_ttl"`
+	OffersPerSlot int           `yaml:"offers_per_slot" mapstructure:"offers_per_slot"`
+	// PollInterval is how often unanswered offers are expired
+	PollInterval time.Duration `yaml:"poll_interval" mapstructure:"poll_interval"`
+}
+
+// RemindersConfig configures the appointment reminders sent by the worker
+type RemindersConfig struct {
+	// BaseURL and SigningKey make the confirm and cancel links in reminders
+	BaseURL    string `yaml:"base_url" mapstructure:"base_url"`
+	SigningKey string `yaml:"signing_key" mapstructure:"signing_key"`
+	// DefaultRules apply to organizations without a reminder schedule
+	DefaultRules []ReminderRuleConfig `yaml:"default_rules" mapstructure:"default_rules"`
+	BatchSize    int                  `yaml:"batch_size" mapstructure:"batch_size"`
+	// MaxAttempts is how often a reminder is tried before it is failed
+	MaxAttempts  int           `yaml:"max_attempts" mapstructure:"max_attempts"`
+	PollInterval time.Duration `yaml:"poll_interval" mapstructure:"poll_interval"`
+}
+
+type ReminderRuleConfig struct {
+	Before  time.Duration `yaml:"before" mapstructure:"before"`
+	Channel string        `yaml:"channel" mapstructure:"channel"`
 }
 
 type OutboxConfig struct {
@@ -151,6 +278,30 @@ func LoadConfig() (*Config, error) {
 	if host := os.Getenv("DB_HOST"); host != "" {
 		config.Database.Host = host
 	}
+	if key := os.Getenv("ENCRYPTION_KEY"); key != "" {
+		config.Encryption.Key = key
+	}
+	if key := os.Getenv("BLIND_INDEX_KEY"); key != "" {
+		config.Encryption.BlindIndexKey = key
+	}
+	if path := os.Getenv("KMS_KEY_FILE"); path != "" {
+		config.Encryption.KMSKeyFile = path
+	}
+	if key := os.Getenv("COMPLIANCE_SIGNING_KEY"); key != "" {
+		config.Compliance.SigningKey = key
+	}
+	if key := os.Getenv("REMINDER_SIGNING_KEY"); key != "" {
+		config.Reminders.SigningKey = key
+	}
+	if key := os.Getenv("STORAGE_SIGNING_KEY"); key != "" {
+		config.Storage.SigningKey = key
+	}
+	if key := os.Getenv("S3_ACCESS_KEY"); key != "" {
+		config.Storage.S3.AccessKey = key
+	}
+	if key := os.Getenv("S3_SECRET_KEY"); key != "" {
+		config.Storage.S3.SecretKey = key
+	}
 	// ... other env overrides
 
 	return &config, nil
@@ -181,6 +332,73 @@ func (c *OutboxConfig) ToWorkerConfig() worker.OutboxProcessorConfig {
 	}
 }
 
+// ToReminderRules returns the default reminder rules in the form reminder
+// schedules store them
+func (c *RemindersConfig) ToReminderRules() []model.ReminderRule {
+	rules := make([]model.ReminderRule, 0, len(c.DefaultRules))
+	for _, r := range c.DefaultRules {
+		rules = append(rules, model.ReminderRule{
+			OffsetMinutes: int(r.Before / time.Minute),
+			Channel:       r.Channel,
+		})
+	}
+	return rules
+}
+
+// SystemUser returns the interface user HL7 records are attributed to
+func (c *HL7Config) SystemUser() (uuid.UUID, error) {
+	if c.SystemUserID == "" {
+		return uuid.Nil, fmt.Errorf("hl7.system_user_id is not set")
+	}
+	id, err := uuid.Parse(c.SystemUserID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid hl7.system_user_id: %w", err)
+	}
+	return id, nil
+}
+
+func (c *HL7Config) Organization() (uuid.UUID, error) {
+	if c.OrganizationID == "" {
+		return uuid.Nil, fmt.Errorf("hl7.organization_id is not set")
+	}
+	id, err := uuid.Parse(c.OrganizationID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid hl7.organization_id: %w", err)
+	}
+	return id, nil
+}
+
+// placeholderSigningKeys are the values config.yml shipped with before the
+// keys had to be set; they are public and must not sign links
+var placeholderSigningKeys = map[string]bool{
+	"your-reminder-signing-secret": true,
+	"your-download-signing-secret": true,
+	"your-document-signing-secret": true,
+}
+
+// signingKey rejects an unset or placeholder link signing key
+func signingKey(name, key string) ([]byte, error) {
+	if key == "" || placeholderSigningKeys[key] {
+		return nil, fmt.Errorf("%s is not set", name)
+	}
+	return []byte(key), nil
+}
+
+// Key returns the key that signs the confirm and cancel links in reminders
+func (c *RemindersConfig) Key() ([]byte, error) {
+	return signingKey("reminders.signing_key", c.SigningKey)
+}
+
+// Key returns the key that signs document download links
+func (c *StorageConfig) Key() ([]byte, error) {
+	return signingKey("storage.signing_key", c.SigningKey)
+}
+
+// Key returns the key that signs compliance artifact download links
+func (c *ComplianceConfig) Key() ([]byte, error) {
+	return signingKey("compliance.signing_key", c.SigningKey)
+}
+
 func (c *RedisConfig) ToBrokerConfig() redis.Config {
 	return redis.Config{
 		URL:          c.URL,
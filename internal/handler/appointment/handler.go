@@ -3,9 +3,11 @@ package appointment
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +31,7 @@ import (
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository/postgres"
 	"github.com/jwalitptl/admin-api/internal/service/appointment"
+	"github.com/jwalitptl/admin-api/internal/service/schedule"
 	"github.com/jwalitptl/admin-api/pkg/event"
 	"github.com/sony/gobreaker"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
@@ -143,6 +146,9 @@ func NewHandler(service *appointment.Service, outboxRepo postgres.OutboxReposito
 const (
 	defaultTimeout = 10 * time.Second
 
+	// defaultSlotRange is how far ahead slots are searched when no end is given
+	defaultSlotRange = 7 * 24 * time.Hour
+
 	// Rate limiting
 	requestsPerSecond = 100
 	burstSize         = 200
@@ -258,7 +264,12 @@ func (h *Handler) CreateAppointment(c *gin.Context) {
 		Notes:       req.Notes,
 	}
 
-	err := h.service.CreateAppointment(ctx, appointment)
+	var err error
+	if req.HoldID != nil {
+		err = h.service.CreateAppointmentFromHold(ctx, *req.HoldID, appointment)
+	} else {
+		err = h.service.CreateAppointment(ctx, appointment)
+	}
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "failed to create appointment")
@@ -269,10 +280,15 @@ func (h *Handler) CreateAppointment(c *gin.Context) {
 			h.recordMetrics(start, method, endpoint, "504")
 			return
 		}
-		h.recordError(method, endpoint, "internal")
-		logger.Error().Err(err).Interface("request", req).Msg("failed to create appointment")
-		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
-		h.recordMetrics(start, method, endpoint, "500")
+		status := respondBookingError(c, err)
+		if status == http.StatusInternalServerError {
+			h.recordError(method, endpoint, "internal")
+			logger.Error().Err(err).Interface("request", req).Msg("failed to create appointment")
+		} else {
+			h.recordError(method, endpoint, "booking")
+			logger.Warn().Err(err).Msg("appointment could not be booked")
+		}
+		h.recordMetrics(start, method, endpoint, strconv.Itoa(status))
 		return
 	}
 
@@ -528,7 +544,7 @@ func (h *Handler) UpdateAppointment(c *gin.Context) {
 			c.JSON(http.StatusGatewayTimeout, handler.NewErrorResponse("request timeout"))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		respondBookingError(c, err)
 		return
 	}
 
@@ -614,12 +630,33 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	{
 		appointments.GET("/health", h.HealthCheck)
 		appointments.GET("/availability", h.GetClinicianAvailability)
+		appointments.GET("/slots", h.FindSlots)
 		appointments.POST("", h.CreateAppointment)
 		appointments.GET("", h.ListAppointments)
 		appointments.GET("/:id", h.GetAppointment)
 		appointments.PUT("/:id", h.UpdateAppointment)
 		appointments.DELETE("/:id", h.DeleteAppointment)
 	}
+
+	holds := r.Group("/appointment-holds")
+	holds.Use(otelgin.Middleware("appointment-service"))
+	holds.Use(h.rateLimitMiddleware)
+	{
+		holds.POST("", h.HoldSlot)
+		holds.GET("/:id", h.GetHold)
+		holds.DELETE("/:id", h.ReleaseHold)
+	}
+
+	series := r.Group("/appointment-series")
+	series.Use(otelgin.Middleware("appointment-service"))
+	series.Use(h.rateLimitMiddleware)
+	{
+		series.POST("", h.CreateSeries)
+		series.POST("/check", h.CheckSeries)
+		series.GET("/:id", h.GetSeries)
+		series.PUT("/:id/occurrences/:appointmentId", h.UpdateSeriesOccurrence)
+		series.POST("/:id/occurrences/:appointmentId/cancel", h.CancelSeriesOccurrence)
+	}
 }
 
 func (h *Handler) GetClinicianAvailability(c *gin.Context) {
@@ -681,7 +718,7 @@ func (h *Handler) GetClinicianAvailability(c *gin.Context) {
 		return
 	}
 
-	slots := result.([]*model.TimeSlot)
+	slots := result.([]model.TimeSlot)
 	// Cache the result
 	h.cache.Set(cacheKey, slots, cache.DefaultExpiration)
 
@@ -703,8 +740,124 @@ func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *eve
 		appointments.PUT("/:id", eventTracker.TrackEvent("appointment", "update"), h.UpdateAppointment)
 		appointments.DELETE("/:id", eventTracker.TrackEvent("appointment", "delete"), h.DeleteAppointment)
 		appointments.GET("", h.ListAppointments)
+		appointments.GET("/slots", h.FindSlots)
 		appointments.GET("/:id", h.GetAppointment)
 	}
+
+	holds := r.Group("/appointment-holds")
+	{
+		holds.POST("", eventTracker.TrackEvent("appointment_hold", "create"), h.HoldSlot)
+		holds.DELETE("/:id", eventTracker.TrackEvent("appointment_hold", "delete"), h.ReleaseHold)
+		holds.GET("/:id", h.GetHold)
+	}
+
+	series := r.Group("/appointment-series")
+	{
+		series.POST("", eventTracker.TrackEvent("appointment_series", "create"), h.CreateSeries)
+		series.PUT("/:id/occurrences/:appointmentId", eventTracker.TrackEvent("appointment_series", "update"), h.UpdateSeriesOccurrence)
+		series.POST("/:id/occurrences/:appointmentId/cancel", eventTracker.TrackEvent("appointment_series", "cancel"), h.CancelSeriesOccurrence)
+		series.POST("/check", h.CheckSeries)
+		series.GET("/:id", h.GetSeries)
+	}
+}
+
+// FindSlots returns the bookable slots of a service across one or more
+// clinicians, given as repeated clinician_id parameters. From and to are
+// dates or RFC 3339 times and default to the coming week.
+func (h *Handler) FindSlots(c *gin.Context) {
+	start := time.Now()
+	method := "GET"
+	endpoint := "/appointments/slots"
+	logger := log.With().
+		Str("method", method).
+		Str("endpoint", endpoint).
+		Str("request_id", c.GetString("request_id")).
+		Logger()
+
+	serviceID, err := uuid.Parse(c.Query("service_id"))
+	if err != nil {
+		h.recordError(method, endpoint, "validation")
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid service ID"))
+		h.recordMetrics(start, method, endpoint, "400")
+		return
+	}
+
+	search := &model.SlotSearch{ServiceID: serviceID, From: time.Now()}
+	for _, v := range c.QueryArray("clinician_id") {
+		clinicianID, err := uuid.Parse(v)
+		if err != nil {
+			h.recordError(method, endpoint, "validation")
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid clinician ID"))
+			h.recordMetrics(start, method, endpoint, "400")
+			return
+		}
+		search.ClinicianIDs = append(search.ClinicianIDs, clinicianID)
+	}
+	if v := c.Query("clinic_id"); v != "" {
+		clinicID, err := uuid.Parse(v)
+		if err != nil {
+			h.recordError(method, endpoint, "validation")
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse(errInvalidClinicID))
+			h.recordMetrics(start, method, endpoint, "400")
+			return
+		}
+		search.ClinicID = &clinicID
+	}
+	if v := c.Query("from"); v != "" {
+		if search.From, err = parseTime(v); err != nil {
+			h.recordError(method, endpoint, "validation")
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse(errInvalidDate))
+			h.recordMetrics(start, method, endpoint, "400")
+			return
+		}
+	}
+	search.To = search.From.Add(defaultSlotRange)
+	if v := c.Query("to"); v != "" {
+		if search.To, err = parseTime(v); err != nil {
+			h.recordError(method, endpoint, "validation")
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse(errInvalidDate))
+			h.recordMetrics(start, method, endpoint, "400")
+			return
+		}
+	}
+
+	slots, err := h.service.FindSlots(c.Request.Context(), search)
+	if err != nil {
+		switch {
+		case errors.Is(err, appointment.ErrServiceNotFound):
+			h.recordError(method, endpoint, "not_found")
+			c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+			h.recordMetrics(start, method, endpoint, "404")
+		case errors.Is(err, appointment.ErrServiceUnavailable), errors.Is(err, appointment.ErrNoClinicians),
+			errors.Is(err, appointment.ErrTooManyClinicians), errors.Is(err, appointment.ErrInvalidSlotRange),
+			errors.Is(err, schedule.ErrRangeTooLong):
+			h.recordError(method, endpoint, "validation")
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+			h.recordMetrics(start, method, endpoint, "400")
+		default:
+			h.recordError(method, endpoint, "internal")
+			logger.Error().Err(err).Msg("failed to find slots")
+			c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+			h.recordMetrics(start, method, endpoint, "500")
+		}
+		return
+	}
+
+	logger.Info().
+		Int("slot_count", len(slots)).
+		Int("clinician_count", len(search.ClinicianIDs)).
+		Dur("duration", time.Since(start)).
+		Msg("slots retrieved successfully")
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(slots))
+	h.recordMetrics(start, method, endpoint, "200")
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
 }
 
 // HealthCheck returns the health status of the appointment service
@@ -0,0 +1,89 @@
+package appointment
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/appointment"
+)
+
+// HoldSlot reserves a slot while the booking form is completed. The hold's
+// ID is then given as hold_id when creating the appointment.
+func (h *Handler) HoldSlot(c *gin.Context) {
+	var req model.CreateAppointmentHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	hold, err := h.service.HoldSlot(c.Request.Context(), &req)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+	h.invalidateAvailabilityCache(hold.ClinicianID, hold.StartTime.Format("2006-01-02"))
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(hold))
+}
+
+func (h *Handler) GetHold(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid hold ID"))
+		return
+	}
+
+	hold, err := h.service.GetHold(c.Request.Context(), id)
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(hold))
+}
+
+func (h *Handler) ReleaseHold(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid hold ID"))
+		return
+	}
+
+	if err := h.service.ReleaseHold(c.Request.Context(), id); err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+// respondBookingError answers booking and hold errors and returns the
+// status it sent. A slot conflict is a 409 carrying the contested slot.
+func respondBookingError(c *gin.Context, err error) int {
+	var conflictErr *appointment.SlotConflictError
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &conflictErr):
+		c.JSON(http.StatusConflict, &handler.Response{
+			Status:  "error",
+			Message: conflictErr.Error(),
+			Data:    conflictErr,
+		})
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrSlotTaken):
+		status = http.StatusConflict
+	case errors.Is(err, appointment.ErrHoldNotFound), errors.Is(err, appointment.ErrServiceNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, appointment.ErrHoldMismatch), errors.Is(err, appointment.ErrServiceUnavailable),
+		errors.Is(err, repository.ErrInvalidReference):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, handler.NewErrorResponse(err.Error()))
+	return status
+}
@@ -0,0 +1,158 @@
+package appointment
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/appointment"
+)
+
+// CheckSeries reports which occurrences of a series would conflict with
+// existing bookings, without booking it
+func (h *Handler) CheckSeries(c *gin.Context) {
+	var req model.CreateAppointmentSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	occurrences, err := h.service.CheckSeries(c.Request.Context(), &req)
+	if err != nil {
+		respondSeriesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(occurrences))
+}
+
+func (h *Handler) CreateSeries(c *gin.Context) {
+	var req model.CreateAppointmentSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := h.service.CreateSeries(c.Request.Context(), &req)
+	if err != nil {
+		respondSeriesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(result))
+}
+
+func (h *Handler) GetSeries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid series ID"))
+		return
+	}
+
+	result, err := h.service.GetSeries(c.Request.Context(), id)
+	if err != nil {
+		respondSeriesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(result))
+}
+
+// UpdateSeriesOccurrence edits an occurrence with the scope given in the
+// body: "this", "this_and_following" or "all"
+func (h *Handler) UpdateSeriesOccurrence(c *gin.Context) {
+	seriesID, appointmentID, ok := parseSeriesIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateSeriesOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := h.service.UpdateOccurrence(c.Request.Context(), seriesID, appointmentID, &req)
+	if err != nil {
+		respondSeriesError(c, err)
+		return
+	}
+	h.invalidateSeriesCache(result)
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(result))
+}
+
+func (h *Handler) CancelSeriesOccurrence(c *gin.Context) {
+	seriesID, appointmentID, ok := parseSeriesIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.CancelSeriesOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := h.service.CancelOccurrence(c.Request.Context(), seriesID, appointmentID, &req)
+	if err != nil {
+		respondSeriesError(c, err)
+		return
+	}
+	h.invalidateSeriesCache(result)
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(result))
+}
+
+// invalidateSeriesCache drops cached copies of the series' appointments,
+// which a scoped change may have touched
+func (h *Handler) invalidateSeriesCache(result *model.AppointmentSeriesResult) {
+	for _, apt := range result.Appointments {
+		h.invalidateAppointmentCache(apt.ID)
+	}
+}
+
+func parseSeriesIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid series ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	appointmentID, err := uuid.Parse(c.Param("appointmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(errInvalidID))
+		return uuid.Nil, uuid.Nil, false
+	}
+	return seriesID, appointmentID, true
+}
+
+func respondSeriesError(c *gin.Context, err error) {
+	var conflictsErr *appointment.ConflictsError
+	switch {
+	case errors.As(err, &conflictsErr):
+		c.JSON(http.StatusConflict, &handler.Response{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    conflictsErr.Conflicts,
+		})
+	case errors.Is(err, appointment.ErrSeriesNotFound), errors.Is(err, appointment.ErrNotInSeries),
+		errors.Is(err, appointment.ErrServiceNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, appointment.ErrSeriesCancelled), errors.Is(err, appointment.ErrOccurrenceClosed),
+		errors.Is(err, repository.ErrSlotTaken):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, appointment.ErrInvalidRecurrence), errors.Is(err, appointment.ErrInvalidTimezone),
+		errors.Is(err, appointment.ErrSeriesTooLong), errors.Is(err, appointment.ErrNoOccurrences),
+		errors.Is(err, appointment.ErrSeriesDateChange), errors.Is(err, appointment.ErrInvalidSeriesTime),
+		errors.Is(err, appointment.ErrServiceUnavailable),
+		errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
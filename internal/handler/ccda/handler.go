@@ -0,0 +1,217 @@
+package ccda
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/ccda"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *ccda.Service
+}
+
+func NewHandler(service *ccda.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	documents := r.Group("/patients/:id/ccda", staffOnly)
+	{
+		documents.GET("", h.ExportDocument)
+		documents.GET("/imports", h.ListImports)
+		documents.POST("/imports", h.ImportDocument)
+		documents.GET("/imports/:importId", h.GetImport)
+		documents.POST("/imports/:importId/reconcile", h.ReconcileImport)
+		documents.POST("/imports/:importId/discard", h.DiscardImport)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	documents := r.Group("/patients/:id/ccda", staffOnly)
+	{
+		documents.POST("/imports", eventTracker.TrackEvent("CCDA_IMPORT", "CREATE"), h.ImportDocument)
+		documents.POST("/imports/:importId/reconcile", eventTracker.TrackEvent("CCDA_IMPORT", "UPDATE"), h.ReconcileImport)
+		documents.POST("/imports/:importId/discard", eventTracker.TrackEvent("CCDA_IMPORT", "UPDATE"), h.DiscardImport)
+		documents.GET("", h.ExportDocument)
+		documents.GET("/imports", h.ListImports)
+		documents.GET("/imports/:importId", h.GetImport)
+	}
+}
+
+// staffOnly keeps document exchange with staff; patients download their
+// records through the export endpoints
+func staffOnly(c *gin.Context) {
+	if c.GetString("user_type") == model.UserTypePatient {
+		c.AbortWithStatusJSON(http.StatusForbidden, handler.NewErrorResponse("C-CDA exchange is not available to patients"))
+		return
+	}
+	c.Next()
+}
+
+// ExportDocument returns the patient's Continuity of Care Document
+func (h *Handler) ExportDocument(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	data, err := h.service.Export(c.Request.Context(), patientID, recordReader(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("ccd-%s.xml", patientID)))
+	c.Data(http.StatusOK, "application/xml", data)
+}
+
+// ImportDocument stages the C-CDA document in the request body for
+// reconciliation
+func (h *Handler) ImportDocument(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	imp, err := h.service.Import(c.Request.Context(), patientID, c.GetString("user_type"), c.Request.Body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(imp))
+}
+
+func (h *Handler) ListImports(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	imports, err := h.service.List(c.Request.Context(), patientID, model.CCDAImportStatus(c.Query("status")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(imports))
+}
+
+func (h *Handler) GetImport(c *gin.Context) {
+	patientID, importID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	imp, err := h.service.Get(c.Request.Context(), patientID, importID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(imp))
+}
+
+func (h *Handler) ReconcileImport(c *gin.Context) {
+	patientID, importID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.ReconcileCCDAImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	imp, err := h.service.Reconcile(c.Request.Context(), patientID, importID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(imp))
+}
+
+func (h *Handler) DiscardImport(c *gin.Context) {
+	patientID, importID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.DiscardCCDAImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	imp, err := h.service.Discard(c.Request.Context(), patientID, importID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(imp))
+}
+
+func parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	importID, err := uuid.Parse(c.Param("importId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid import ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return patientID, importID, true
+}
+
+// recordReader describes the caller for the record access checks an export
+// goes through
+func recordReader(c *gin.Context) *model.RecordReader {
+	userID, _ := c.Get("user_id")
+	uid, _ := userID.(uuid.UUID)
+	return &model.RecordReader{
+		UserID:   uid,
+		UserType: c.GetString("user_type"),
+		Reason:   c.GetHeader("X-Access-Reason"),
+		Self:     c.GetBool("acting_for_self"),
+	}
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ccda.ErrNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, ccda.ErrNotClinician), errors.Is(err, medical.ErrAccessDenied),
+		errors.Is(err, medical.ErrAccessReasonRequired):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, ccda.ErrAlreadyImported), errors.Is(err, ccda.ErrNotStaged),
+		errors.Is(err, ccda.ErrConflict):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, ccda.ErrDocumentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, ccda.ErrInvalidDocument), errors.Is(err, ccda.ErrInvalidDecisions),
+		errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
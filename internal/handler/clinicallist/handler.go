@@ -0,0 +1,280 @@
+package clinicallist
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/clinicallist"
+	"github.com/jwalitptl/admin-api/internal/service/terminology"
+	"github.com/jwalitptl/admin-api/pkg/event"
+	"github.com/jwalitptl/admin-api/pkg/fhir"
+)
+
+type Handler struct {
+	service *clinicallist.Service
+}
+
+func NewHandler(service *clinicallist.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.GET("/problems", h.ListProblems)
+		patients.POST("/problems", h.AddProblem)
+		patients.GET("/problems/:problemId", h.GetProblem)
+		patients.PUT("/problems/:problemId", h.UpdateProblem)
+
+		patients.GET("/allergies", h.ListAllergies)
+		patients.POST("/allergies", h.AddAllergy)
+		patients.GET("/allergies/:allergyId", h.GetAllergy)
+		patients.PUT("/allergies/:allergyId", h.UpdateAllergy)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/problems", eventTracker.TrackEvent("PROBLEM", "CREATE"), h.AddProblem)
+		patients.PUT("/problems/:problemId", eventTracker.TrackEvent("PROBLEM", "UPDATE"), h.UpdateProblem)
+		patients.GET("/problems", h.ListProblems)
+		patients.GET("/problems/:problemId", h.GetProblem)
+
+		patients.POST("/allergies", eventTracker.TrackEvent("ALLERGY", "CREATE"), h.AddAllergy)
+		patients.PUT("/allergies/:allergyId", eventTracker.TrackEvent("ALLERGY", "UPDATE"), h.UpdateAllergy)
+		patients.GET("/allergies", h.ListAllergies)
+		patients.GET("/allergies/:allergyId", h.GetAllergy)
+	}
+}
+
+// ListProblems returns the problem list, or a FHIR searchset of Condition
+// resources when the caller accepts application/fhir+json
+func (h *Handler) ListProblems(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	problems, err := h.service.ListProblems(c.Request.Context(), patientID, model.ClinicalStatus(c.Query("status")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if wantsFHIR(c) {
+		respondFHIR(c, http.StatusOK, clinicallist.ProblemBundle(problems))
+		return
+	}
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(problems))
+}
+
+func (h *Handler) GetProblem(c *gin.Context) {
+	patientID, problemID, ok := parseIDs(c, "problemId")
+	if !ok {
+		return
+	}
+
+	problem, err := h.service.GetProblem(c.Request.Context(), patientID, problemID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if wantsFHIR(c) {
+		respondFHIR(c, http.StatusOK, clinicallist.Condition(problem))
+		return
+	}
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(problem))
+}
+
+func (h *Handler) AddProblem(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.CreateProblemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	problem, err := h.service.AddProblem(c.Request.Context(), patientID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(problem))
+}
+
+func (h *Handler) UpdateProblem(c *gin.Context) {
+	patientID, problemID, ok := parseIDs(c, "problemId")
+	if !ok {
+		return
+	}
+
+	var req model.UpdateProblemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	problem, err := h.service.UpdateProblem(c.Request.Context(), patientID, problemID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(problem))
+}
+
+// ListAllergies returns the allergy list, or a FHIR searchset of
+// AllergyIntolerance resources when the caller accepts application/fhir+json
+func (h *Handler) ListAllergies(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	allergies, err := h.service.ListAllergies(c.Request.Context(), patientID, model.ClinicalStatus(c.Query("status")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if wantsFHIR(c) {
+		respondFHIR(c, http.StatusOK, clinicallist.AllergyBundle(allergies))
+		return
+	}
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(allergies))
+}
+
+func (h *Handler) GetAllergy(c *gin.Context) {
+	patientID, allergyID, ok := parseIDs(c, "allergyId")
+	if !ok {
+		return
+	}
+
+	allergy, err := h.service.GetAllergy(c.Request.Context(), patientID, allergyID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if wantsFHIR(c) {
+		respondFHIR(c, http.StatusOK, clinicallist.AllergyIntolerance(allergy))
+		return
+	}
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(allergy))
+}
+
+func (h *Handler) AddAllergy(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.CreateAllergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	allergy, err := h.service.AddAllergy(c.Request.Context(), patientID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(allergy))
+}
+
+func (h *Handler) UpdateAllergy(c *gin.Context) {
+	patientID, allergyID, ok := parseIDs(c, "allergyId")
+	if !ok {
+		return
+	}
+
+	var req model.UpdateAllergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	allergy, err := h.service.UpdateAllergy(c.Request.Context(), patientID, allergyID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(allergy))
+}
+
+func wantsFHIR(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), fhir.ContentType)
+}
+
+// respondFHIR writes a bare FHIR resource; FHIR consumers don't expect the
+// API's response envelope
+func respondFHIR(c *gin.Context, status int, resource interface{}) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+	c.Data(status, fhir.ContentType, data)
+}
+
+func parseIDs(c *gin.Context, param string) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid "+param))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return patientID, id, true
+}
+
+func respondError(c *gin.Context, err error) {
+	var validationErr *terminology.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusUnprocessableEntity, &handler.Response{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    validationErr.Invalid,
+		})
+	case errors.Is(err, clinicallist.ErrNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, clinicallist.ErrNotClinician):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, clinicallist.ErrAlreadyListed), errors.Is(err, repository.ErrVersionConflict):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, clinicallist.ErrInvalidReaction), errors.Is(err, clinicallist.ErrInvalidDates),
+		errors.Is(err, clinicallist.ErrResolvedNotAllowed), errors.Is(err, clinicallist.ErrNoChanges),
+		errors.Is(err, terminology.ErrUnknownSystem), errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
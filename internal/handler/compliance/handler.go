@@ -0,0 +1,370 @@
+package compliance
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/compliance"
+	"github.com/jwalitptl/admin-api/pkg/event"
+	"github.com/jwalitptl/admin-api/pkg/security"
+)
+
+type Handler struct {
+	service *compliance.Service
+}
+
+func NewHandler(service *compliance.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/data-exports", h.RequestExport)
+		patients.POST("/erasure-requests", complianceOnly, h.RequestErasure)
+		patients.GET("/compliance-requests", h.ListRequests)
+		patients.GET("/compliance-requests/:requestId", h.GetPatientRequest)
+		patients.GET("/consents", h.ListConsents)
+		patients.POST("/consents", h.RecordConsent)
+		patients.DELETE("/consents/:consentId", h.WithdrawConsent)
+		patients.GET("/legal-holds", h.ListLegalHolds)
+		patients.POST("/legal-holds", complianceOnly, h.PlaceLegalHold)
+		patients.DELETE("/legal-holds/:holdId", complianceOnly, h.ReleaseLegalHold)
+	}
+
+	r.GET("/compliance/requests/:id", staffOnly, h.GetRequest)
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/data-exports", eventTracker.TrackEvent("COMPLIANCE_REQUEST", "CREATE"), h.RequestExport)
+		patients.POST("/erasure-requests", complianceOnly, eventTracker.TrackEvent("COMPLIANCE_REQUEST", "CREATE"), h.RequestErasure)
+		patients.POST("/consents", eventTracker.TrackEvent("CONSENT", "CREATE"), h.RecordConsent)
+		patients.DELETE("/consents/:consentId", eventTracker.TrackEvent("CONSENT", "DELETE"), h.WithdrawConsent)
+		patients.POST("/legal-holds", complianceOnly, eventTracker.TrackEvent("LEGAL_HOLD", "CREATE"), h.PlaceLegalHold)
+		patients.DELETE("/legal-holds/:holdId", complianceOnly, eventTracker.TrackEvent("LEGAL_HOLD", "DELETE"), h.ReleaseLegalHold)
+		patients.GET("/compliance-requests", h.ListRequests)
+		patients.GET("/compliance-requests/:requestId", h.GetPatientRequest)
+		patients.GET("/consents", h.ListConsents)
+		patients.GET("/legal-holds", h.ListLegalHolds)
+	}
+
+	r.GET("/compliance/requests/:id", staffOnly, h.GetRequest)
+}
+
+// RegisterPublicRoutes mounts the signed download endpoint. It sits outside the
+// authenticated group because the link itself carries the authorization.
+func (h *Handler) RegisterPublicRoutes(r *gin.RouterGroup) {
+	r.GET("/compliance/exports/:id/download", h.DownloadExport)
+}
+
+type requestResponse struct {
+	*model.ComplianceRequest
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+}
+
+// complianceOnly keeps legal holds and erasure with administrators and
+// compliance officers; an erasure cannot be undone, and a hold exists to stop
+// one the patient may want
+func complianceOnly(c *gin.Context) {
+	switch c.GetString("user_type") {
+	case model.UserTypeAdmin, model.UserTypeCompliance:
+		c.Next()
+	default:
+		c.AbortWithStatusJSON(http.StatusForbidden, handler.NewErrorResponse("only administrators and compliance officers may do this"))
+	}
+}
+
+// staffOnly keeps routes outside /patients/:id, which the proxy checks do
+// not cover, away from patient users
+func staffOnly(c *gin.Context) {
+	if c.GetString("user_type") == model.UserTypePatient {
+		c.AbortWithStatusJSON(http.StatusForbidden, handler.NewErrorResponse("not available to patients"))
+		return
+	}
+	c.Next()
+}
+
+// sameOrganization reports whether the request belongs to the caller's
+// organization
+func sameOrganization(c *gin.Context, req *model.ComplianceRequest) bool {
+	v, _ := c.Get("organization_id")
+	orgID, ok := v.(uuid.UUID)
+	return ok && orgID != uuid.Nil && orgID == req.OrganizationID
+}
+
+func (h *Handler) RequestExport(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	req, err := h.service.RequestExport(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, handler.NewSuccessResponse(req))
+}
+
+func (h *Handler) RequestErasure(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	req, err := h.service.RequestErasure(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, handler.NewSuccessResponse(req))
+}
+
+// GetRequest returns a compliance request of the caller's organization with
+// its download link
+func (h *Handler) GetRequest(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid request ID"))
+		return
+	}
+
+	req, err := h.service.GetRequest(c.Request.Context(), id)
+	if err != nil || !sameOrganization(c, req) {
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse("compliance request not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(h.withDownloadLink(c, req)))
+}
+
+// GetPatientRequest returns one of the patient's compliance requests. It sits
+// under /patients/:id so patients reach their own exports through the proxy
+// checks on that route.
+func (h *Handler) GetPatientRequest(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid request ID"))
+		return
+	}
+
+	req, err := h.service.GetRequest(c.Request.Context(), id)
+	if err != nil || req.PatientID != patientID {
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse("compliance request not found"))
+		return
+	}
+	if c.GetString("user_type") != model.UserTypePatient && !sameOrganization(c, req) {
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse("compliance request not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(h.withDownloadLink(c, req)))
+}
+
+func (h *Handler) ListRequests(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	reqs, err := h.service.ListRequests(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(reqs))
+}
+
+func (h *Handler) DownloadExport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid request ID"))
+		return
+	}
+
+	f, req, err := h.service.OpenArtifact(c.Request.Context(), id, c.Request.URL.Path, c.Request.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, security.ErrInvalidSignature):
+			c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+		case errors.Is(err, security.ErrSignatureExpired):
+			c.JSON(http.StatusGone, handler.NewErrorResponse("download link has expired"))
+		default:
+			c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+		}
+		return
+	}
+	defer f.Close()
+
+	ext := filepath.Ext(*req.ArtifactPath)
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s%s", req.Type, req.ID, ext)))
+	if req.ArtifactChecksum != nil {
+		c.Header("X-Content-SHA256", *req.ArtifactChecksum)
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, f)
+}
+
+func (h *Handler) RecordConsent(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.RecordConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	consent, err := h.service.RecordConsent(c.Request.Context(), patientID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(consent))
+}
+
+func (h *Handler) WithdrawConsent(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	consentID, err := uuid.Parse(c.Param("consentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid consent ID"))
+		return
+	}
+
+	consent, err := h.service.WithdrawConsent(c.Request.Context(), patientID, consentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(consent))
+}
+
+func (h *Handler) ListConsents(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	consents, err := h.service.ListConsents(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(consents))
+}
+
+func (h *Handler) PlaceLegalHold(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.PlaceLegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	hold, err := h.service.PlaceLegalHold(c.Request.Context(), patientID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(hold))
+}
+
+func (h *Handler) ReleaseLegalHold(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	holdID, err := uuid.Parse(c.Param("holdId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid legal hold ID"))
+		return
+	}
+
+	hold, err := h.service.ReleaseLegalHold(c.Request.Context(), patientID, holdID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(hold))
+}
+
+func (h *Handler) ListLegalHolds(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	holds, err := h.service.ListLegalHolds(c.Request.Context(), patientID, c.Query("active") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(holds))
+}
+
+func (h *Handler) withDownloadLink(c *gin.Context, req *model.ComplianceRequest) *requestResponse {
+	resp := &requestResponse{ComplianceRequest: req}
+	if req.Status != model.ComplianceRequestStatusCompleted {
+		return resp
+	}
+
+	link, expiresAt, err := h.service.DownloadURL(c.Request.Context(), req)
+	if err == nil {
+		resp.DownloadURL = link
+		resp.DownloadExpiresAt = &expiresAt
+	}
+	return resp
+}
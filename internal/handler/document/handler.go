@@ -0,0 +1,251 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/document"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/pkg/event"
+	"github.com/jwalitptl/admin-api/pkg/security"
+)
+
+// multipartOverhead allows for part headers and form fields on top of the
+// document itself when capping the request body.
+const multipartOverhead = 1 << 20
+
+type Handler struct {
+	service *document.Service
+}
+
+func NewHandler(service *document.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/documents", h.UploadDocument)
+		patients.GET("/documents", h.ListDocuments)
+		patients.GET("/documents/:documentId", h.GetDocument)
+		patients.DELETE("/documents/:documentId", h.DeleteDocument)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/documents", eventTracker.TrackEvent("DOCUMENT", "CREATE"), h.UploadDocument)
+		patients.DELETE("/documents/:documentId", eventTracker.TrackEvent("DOCUMENT", "DELETE"), h.DeleteDocument)
+		patients.GET("/documents", h.ListDocuments)
+		patients.GET("/documents/:documentId", h.GetDocument)
+	}
+}
+
+// RegisterPublicRoutes mounts the signed download endpoint. It sits outside the
+// authenticated group because the link itself carries the authorization.
+func (h *Handler) RegisterPublicRoutes(r *gin.RouterGroup) {
+	r.GET("/documents/:id/download", h.DownloadDocument)
+}
+
+type documentResponse struct {
+	*model.Document
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+}
+
+// UploadDocument accepts a multipart form with a "file" part. An optional
+// "medical_record_id" field attaches the document to a record and must come
+// before the file part, since the file is streamed as soon as it is reached.
+func (h *Handler) UploadDocument(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.service.MaxUploadBytes()+multipartOverhead)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("expected a multipart upload"))
+		return
+	}
+
+	var recordID *uuid.UUID
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("missing file part"))
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+			return
+		}
+
+		switch part.FormName() {
+		case "medical_record_id":
+			id, err := readFieldID(part)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid medical record ID"))
+				return
+			}
+			recordID = &id
+
+		case "file":
+			doc, err := h.service.Upload(c.Request.Context(), patientID, recordReader(c), part.FileName(), part, recordID)
+			part.Close()
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				switch {
+				case errors.Is(err, document.ErrTooLarge), errors.As(err, &maxErr):
+					c.JSON(http.StatusRequestEntityTooLarge, handler.NewErrorResponse(document.ErrTooLarge.Error()))
+				case errors.Is(err, document.ErrTypeNotAllowed):
+					c.JSON(http.StatusUnsupportedMediaType, handler.NewErrorResponse(err.Error()))
+				case errors.Is(err, medical.ErrAccessDenied), errors.Is(err, medical.ErrAccessReasonRequired):
+					c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+				default:
+					c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+				}
+				return
+			}
+
+			c.JSON(http.StatusCreated, handler.NewSuccessResponse(doc))
+			return
+		}
+		part.Close()
+	}
+}
+
+func (h *Handler) ListDocuments(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	docs, err := h.service.List(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(docs))
+}
+
+// GetDocument returns the document metadata with a short-lived download link
+func (h *Handler) GetDocument(c *gin.Context) {
+	patientID, documentID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	doc, err := h.service.Get(c.Request.Context(), patientID, documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	resp := &documentResponse{Document: doc}
+	link, expiresAt, err := h.service.DownloadURL(c.Request.Context(), doc)
+	if err == nil {
+		resp.DownloadURL = link
+		resp.DownloadExpiresAt = &expiresAt
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(resp))
+}
+
+func (h *Handler) DeleteDocument(c *gin.Context) {
+	patientID, documentID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), patientID, documentID); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(gin.H{"message": "document deleted"}))
+}
+
+func (h *Handler) DownloadDocument(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid document ID"))
+		return
+	}
+
+	content, doc, err := h.service.Open(c.Request.Context(), id, c.Request.URL.Path, c.Request.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, security.ErrInvalidSignature):
+			c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+		case errors.Is(err, security.ErrSignatureExpired):
+			c.JSON(http.StatusGone, handler.NewErrorResponse("download link has expired"))
+		default:
+			c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+		}
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Type", doc.ContentType)
+	c.Header("Content-Length", fmt.Sprintf("%d", doc.Size))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.Name))
+	c.Header("X-Content-SHA256", doc.Checksum)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	// A corrupt document fails before its last byte, so the response falls
+	// short of Content-Length and the client sees an incomplete download
+	if _, err := io.Copy(c.Writer, content); err != nil {
+		c.Error(err)
+	}
+}
+
+func readFieldID(part *multipart.Part) (uuid.UUID, error) {
+	value, err := io.ReadAll(io.LimitReader(part, 64))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(string(value))
+}
+
+func parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	documentID, err := uuid.Parse(c.Param("documentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid document ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return patientID, documentID, true
+}
+
+// recordReader describes the uploader for the access check on the medical
+// record a document is attached to
+func recordReader(c *gin.Context) *model.RecordReader {
+	userID, _ := c.Get("user_id")
+	uid, _ := userID.(uuid.UUID)
+	return &model.RecordReader{
+		UserID:   uid,
+		UserType: c.GetString("user_type"),
+		Reason:   c.GetHeader("X-Access-Reason"),
+		Self:     c.GetBool("acting_for_self"),
+	}
+}
@@ -0,0 +1,140 @@
+package hl7
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/hl7"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *hl7.Service
+}
+
+func NewHandler(service *hl7.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	messages := r.Group("/hl7/messages", staffOnly)
+	{
+		messages.GET("", h.ListMessages)
+		messages.GET("/:messageId", h.GetMessage)
+		messages.POST("/:messageId/resolve", h.ResolveMessage)
+		messages.POST("/:messageId/discard", h.DiscardMessage)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	messages := r.Group("/hl7/messages", staffOnly)
+	{
+		messages.POST("/:messageId/resolve", eventTracker.TrackEvent("HL7_MESSAGE", "UPDATE"), h.ResolveMessage)
+		messages.POST("/:messageId/discard", eventTracker.TrackEvent("HL7_MESSAGE", "UPDATE"), h.DiscardMessage)
+		messages.GET("", h.ListMessages)
+		messages.GET("/:messageId", h.GetMessage)
+	}
+}
+
+// staffOnly keeps patients away from the interface log; messages can belong
+// to anyone until they are reconciled
+func staffOnly(c *gin.Context) {
+	if c.GetString("user_type") == model.UserTypePatient {
+		c.AbortWithStatusJSON(http.StatusForbidden, handler.NewErrorResponse("HL7 messages are not available to patients"))
+		return
+	}
+	c.Next()
+}
+
+// ListMessages lists logged messages, optionally by status. The
+// reconciliation queue is ?status=unmatched.
+func (h *Handler) ListMessages(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	messages, err := h.service.List(c.Request.Context(), model.HL7MessageStatus(c.Query("status")), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(messages))
+}
+
+func (h *Handler) GetMessage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid message ID"))
+		return
+	}
+
+	message, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(message))
+}
+
+// ResolveMessage files an unmatched message against the chosen patient
+func (h *Handler) ResolveMessage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid message ID"))
+		return
+	}
+
+	var req model.ResolveHL7MessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	message, err := h.service.Resolve(c.Request.Context(), id, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(message))
+}
+
+func (h *Handler) DiscardMessage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid message ID"))
+		return
+	}
+
+	var req model.DiscardHL7MessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	message, err := h.service.Discard(c.Request.Context(), id, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(message))
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, hl7.ErrNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, hl7.ErrNotInQueue):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, hl7.ErrUnsupportedType), errors.Is(err, hl7.ErrMissingPID):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
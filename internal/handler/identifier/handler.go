@@ -0,0 +1,183 @@
+package identifier
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/identifier"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *identifier.Service
+}
+
+func NewHandler(service *identifier.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/patients/lookup", h.Lookup)
+
+	patients := r.Group("/patients/:id")
+	{
+		patients.GET("/identifiers", h.ListIdentifiers)
+		patients.POST("/identifiers", h.AddIdentifier)
+		patients.DELETE("/identifiers/:identifierId", h.EndIdentifier)
+	}
+
+	clinics := r.Group("/clinics/:id")
+	{
+		clinics.GET("/mrn-config", h.GetMRNConfig)
+		clinics.PUT("/mrn-config", h.SaveMRNConfig)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	r.GET("/patients/lookup", h.Lookup)
+
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/identifiers", eventTracker.TrackEvent("PATIENT_IDENTIFIER", "CREATE"), h.AddIdentifier)
+		patients.DELETE("/identifiers/:identifierId", eventTracker.TrackEvent("PATIENT_IDENTIFIER", "DELETE"), h.EndIdentifier)
+		patients.GET("/identifiers", h.ListIdentifiers)
+	}
+
+	clinics := r.Group("/clinics/:id")
+	{
+		clinics.PUT("/mrn-config", eventTracker.TrackEvent("MRN_CONFIG", "UPDATE"), h.SaveMRNConfig)
+		clinics.GET("/mrn-config", h.GetMRNConfig)
+	}
+}
+
+// Lookup finds the caller's organization's patients by any identifier value,
+// optionally within one system
+func (h *Handler) Lookup(c *gin.Context) {
+	value := c.Query("value")
+	if value == "" {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("value is required"))
+		return
+	}
+
+	v, _ := c.Get("organization_id")
+	orgID, ok := v.(uuid.UUID)
+	if !ok || orgID == uuid.Nil {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("no organization for the current user"))
+		return
+	}
+
+	patients, err := h.service.Lookup(c.Request.Context(), orgID, c.Query("system"), value)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(patients))
+}
+
+func (h *Handler) ListIdentifiers(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	identifiers, err := h.service.List(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(identifiers))
+}
+
+func (h *Handler) AddIdentifier(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.AddIdentifierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	ident, err := h.service.Add(c.Request.Context(), patientID, &req)
+	if err != nil {
+		if errors.Is(err, identifier.ErrIdentifierTaken) {
+			c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(ident))
+}
+
+func (h *Handler) EndIdentifier(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	identifierID, err := uuid.Parse(c.Param("identifierId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid identifier ID"))
+		return
+	}
+
+	ident, err := h.service.End(c.Request.Context(), patientID, identifierID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(ident))
+}
+
+func (h *Handler) GetMRNConfig(c *gin.Context) {
+	clinicID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid clinic ID"))
+		return
+	}
+
+	config, err := h.service.GetMRNConfig(c.Request.Context(), clinicID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(config))
+}
+
+func (h *Handler) SaveMRNConfig(c *gin.Context) {
+	clinicID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid clinic ID"))
+		return
+	}
+
+	var req model.MRNConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	config, err := h.service.SaveMRNConfig(c.Request.Context(), clinicID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(config))
+}
@@ -0,0 +1,94 @@
+package keyring
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/keyring"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *keyring.Service
+}
+
+func NewHandler(service *keyring.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	keys := r.Group("/organizations/:id/data-keys", adminOnly)
+	{
+		keys.GET("", h.ListDataKeys)
+		keys.POST("/rotate", h.RotateDataKey)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	keys := r.Group("/organizations/:id/data-keys", adminOnly)
+	{
+		keys.POST("/rotate", eventTracker.TrackEvent("DATA_KEY", "CREATE"), h.RotateDataKey)
+		keys.GET("", h.ListDataKeys)
+	}
+}
+
+// adminOnly limits key management to administrators
+func adminOnly(c *gin.Context) {
+	if c.GetString("user_type") != model.UserTypeAdmin {
+		c.AbortWithStatusJSON(http.StatusForbidden, handler.NewErrorResponse("data keys can only be managed by administrators"))
+		return
+	}
+	c.Next()
+}
+
+// ListDataKeys lists the organization's data keys, newest first. Key
+// material is never returned.
+func (h *Handler) ListDataKeys(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid organization ID"))
+		return
+	}
+
+	keys, err := h.service.List(c.Request.Context(), orgID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(keys))
+}
+
+// RotateDataKey retires the organization's data key and activates a new one.
+// Existing records are re-encrypted in the background.
+func (h *Handler) RotateDataKey(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid organization ID"))
+		return
+	}
+
+	key, err := h.service.Rotate(c.Request.Context(), orgID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(key))
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, keyring.ErrNoOrganization):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, keyring.ErrKeyNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
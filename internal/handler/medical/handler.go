@@ -0,0 +1,391 @@
+package medical
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/internal/service/terminology"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *medical.Service
+}
+
+func NewHandler(service *medical.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.GET("/records", h.ListRecords)
+		patients.GET("/records/:recordId", h.GetRecord)
+		patients.PUT("/records/:recordId", h.CorrectRecord)
+		patients.GET("/records/:recordId/versions", h.ListVersions)
+		patients.GET("/records/:recordId/versions/:version", h.GetVersion)
+
+		patients.GET("/amendments", h.ListAmendments)
+		patients.POST("/amendments", h.RequestAmendment)
+		patients.GET("/amendments/:amendmentId", h.GetAmendment)
+		patients.POST("/amendments/:amendmentId/decision", h.DecideAmendment)
+
+		patients.GET("/care-team", h.ListCareTeam)
+		patients.POST("/care-team", h.AddCareTeamMember)
+		patients.DELETE("/care-team/:userId", h.RemoveCareTeamMember)
+	}
+
+	r.GET("/reports/diagnoses", h.ReportByCode)
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.PUT("/records/:recordId", eventTracker.TrackEvent("MEDICAL_RECORD", "UPDATE"), h.CorrectRecord)
+		patients.GET("/records", h.ListRecords)
+		patients.GET("/records/:recordId", h.GetRecord)
+		patients.GET("/records/:recordId/versions", h.ListVersions)
+		patients.GET("/records/:recordId/versions/:version", h.GetVersion)
+
+		patients.POST("/amendments", eventTracker.TrackEvent("AMENDMENT_REQUEST", "CREATE"), h.RequestAmendment)
+		patients.POST("/amendments/:amendmentId/decision", eventTracker.TrackEvent("AMENDMENT_REQUEST", "UPDATE"), h.DecideAmendment)
+		patients.GET("/amendments", h.ListAmendments)
+		patients.GET("/amendments/:amendmentId", h.GetAmendment)
+
+		patients.POST("/care-team", eventTracker.TrackEvent("CARE_TEAM", "UPDATE"), h.AddCareTeamMember)
+		patients.DELETE("/care-team/:userId", eventTracker.TrackEvent("CARE_TEAM", "UPDATE"), h.RemoveCareTeamMember)
+		patients.GET("/care-team", h.ListCareTeam)
+	}
+
+	r.GET("/reports/diagnoses", h.ReportByCode)
+}
+
+// ListRecords returns the patient's records the caller may see, e.g.
+// ?type=lab_result&from=2024-01-01T00:00:00Z. HIPAA records are only included
+// when the X-Access-Reason header states the purpose of access.
+func (h *Handler) ListRecords(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	filters := &model.RecordFilters{Type: c.Query("type")}
+	for param, dst := range map[string]*time.Time{"from": &filters.StartDate, "to": &filters.EndDate} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid "+param+" time"))
+				return
+			}
+			*dst = t
+		}
+	}
+
+	records, err := h.service.ListMedicalRecords(c.Request.Context(), patientID, recordReader(c), filters)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(records))
+}
+
+func (h *Handler) GetRecord(c *gin.Context) {
+	patientID, recordID, ok := parseIDs(c, "recordId")
+	if !ok {
+		return
+	}
+
+	record, err := h.service.GetMedicalRecord(c.Request.Context(), patientID, recordID, recordReader(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(record))
+}
+
+// CorrectRecord lets a clinician change a record; the previous content stays
+// available as an earlier version
+func (h *Handler) CorrectRecord(c *gin.Context) {
+	patientID, recordID, ok := parseIDs(c, "recordId")
+	if !ok {
+		return
+	}
+
+	var req model.UpdateMedicalRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	record, err := h.service.CorrectRecord(c.Request.Context(), patientID, recordID, recordReader(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(record))
+}
+
+func (h *Handler) ListVersions(c *gin.Context) {
+	patientID, recordID, ok := parseIDs(c, "recordId")
+	if !ok {
+		return
+	}
+
+	versions, err := h.service.ListVersions(c.Request.Context(), patientID, recordID, recordReader(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(versions))
+}
+
+func (h *Handler) GetVersion(c *gin.Context) {
+	patientID, recordID, ok := parseIDs(c, "recordId")
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid version"))
+		return
+	}
+
+	v, err := h.service.GetVersion(c.Request.Context(), patientID, recordID, version, recordReader(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(v))
+}
+
+func (h *Handler) RequestAmendment(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.CreateAmendmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	amendment, err := h.service.RequestAmendment(c.Request.Context(), patientID, recordReader(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(amendment))
+}
+
+func (h *Handler) ListAmendments(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	amendments, err := h.service.ListAmendments(c.Request.Context(), patientID, recordReader(c), model.AmendmentStatus(c.Query("status")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(amendments))
+}
+
+func (h *Handler) GetAmendment(c *gin.Context) {
+	patientID, amendmentID, ok := parseIDs(c, "amendmentId")
+	if !ok {
+		return
+	}
+
+	amendment, err := h.service.GetAmendment(c.Request.Context(), patientID, amendmentID, recordReader(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(amendment))
+}
+
+func (h *Handler) DecideAmendment(c *gin.Context) {
+	patientID, amendmentID, ok := parseIDs(c, "amendmentId")
+	if !ok {
+		return
+	}
+
+	var req model.DecideAmendmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	amendment, err := h.service.DecideAmendment(c.Request.Context(), patientID, amendmentID, recordReader(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(amendment))
+}
+
+func (h *Handler) ListCareTeam(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	members, err := h.service.ListCareTeam(c.Request.Context(), patientID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(members))
+}
+
+func (h *Handler) AddCareTeamMember(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.AddCareTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	member, err := h.service.AddCareTeamMember(c.Request.Context(), patientID, recordReader(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(member))
+}
+
+func (h *Handler) RemoveCareTeamMember(c *gin.Context) {
+	patientID, userID, ok := parseIDs(c, "userId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveCareTeamMember(c.Request.Context(), patientID, userID, recordReader(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+// ReportByCode counts coded records per code, e.g.
+// ?system=icd10&code=E11&from=2024-01-01T00:00:00Z
+func (h *Handler) ReportByCode(c *gin.Context) {
+	v, _ := c.Get("organization_id")
+	orgID, ok := v.(uuid.UUID)
+	if !ok || orgID == uuid.Nil {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("no organization for the current user"))
+		return
+	}
+
+	filter := &model.CodeReportFilter{
+		OrganizationID: orgID,
+		System:         c.Query("system"),
+		Code:           c.Query("code"),
+	}
+	for param, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid "+param+" time"))
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	rows, err := h.service.ReportByCode(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(rows))
+}
+
+func respondError(c *gin.Context, err error) {
+	var validationErr *terminology.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusUnprocessableEntity, &handler.Response{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    validationErr.Invalid,
+		})
+	case errors.Is(err, medical.ErrRecordNotFound), errors.Is(err, medical.ErrNotOnCareTeam),
+		errors.Is(err, medical.ErrAmendmentNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, medical.ErrNotClinician), errors.Is(err, medical.ErrNotCareTeamManager),
+		errors.Is(err, medical.ErrAccessDenied), errors.Is(err, medical.ErrAccessReasonRequired):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, repository.ErrVersionConflict), errors.Is(err, medical.ErrAmendmentClosed):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, medical.ErrNoChanges), errors.Is(err, medical.ErrReasonRequired),
+		errors.Is(err, medical.ErrInvalidDiagnosis), errors.Is(err, terminology.ErrUnknownSystem),
+		errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
+
+func parseIDs(c *gin.Context, param string) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid "+param))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return patientID, id, true
+}
+
+// recordReader describes the caller for record access checks. The purpose of
+// access for HIPAA records comes in the X-Access-Reason header.
+func recordReader(c *gin.Context) *model.RecordReader {
+	userID, _ := c.Get("user_id")
+	uid, _ := userID.(uuid.UUID)
+	return &model.RecordReader{
+		UserID:   uid,
+		UserType: c.GetString("user_type"),
+		Reason:   c.GetHeader("X-Access-Reason"),
+		Self:     c.GetBool("acting_for_self"),
+	}
+}
@@ -0,0 +1,347 @@
+package note
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/note"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *note.Service
+}
+
+func NewHandler(service *note.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	templates := r.Group("/note-templates", staffOnly)
+	{
+		templates.GET("", h.ListTemplates)
+		templates.POST("", h.CreateTemplate)
+		templates.GET("/:templateId", h.GetTemplate)
+		templates.PUT("/:templateId", h.UpdateTemplate)
+	}
+
+	notes := r.Group("/patients/:id/notes", staffOnly)
+	{
+		notes.GET("", h.ListNotes)
+		notes.POST("", h.CreateNote)
+		notes.GET("/:noteId", h.GetNote)
+		notes.PUT("/:noteId", h.UpdateNote)
+		notes.POST("/:noteId/sign", h.SignNote)
+		notes.POST("/:noteId/cosign", h.CosignNote)
+		notes.POST("/:noteId/lock", h.LockNote)
+		notes.POST("/:noteId/addenda", h.AddAddendum)
+		notes.GET("/:noteId/verify", h.VerifyNote)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	templates := r.Group("/note-templates", staffOnly)
+	{
+		templates.POST("", eventTracker.TrackEvent("NOTE_TEMPLATE", "CREATE"), h.CreateTemplate)
+		templates.PUT("/:templateId", eventTracker.TrackEvent("NOTE_TEMPLATE", "UPDATE"), h.UpdateTemplate)
+		templates.GET("", h.ListTemplates)
+		templates.GET("/:templateId", h.GetTemplate)
+	}
+
+	notes := r.Group("/patients/:id/notes", staffOnly)
+	{
+		notes.POST("", eventTracker.TrackEvent("CLINICAL_NOTE", "CREATE"), h.CreateNote)
+		notes.PUT("/:noteId", eventTracker.TrackEvent("CLINICAL_NOTE", "UPDATE"), h.UpdateNote)
+		notes.POST("/:noteId/sign", eventTracker.TrackEvent("CLINICAL_NOTE", "UPDATE"), h.SignNote)
+		notes.POST("/:noteId/cosign", eventTracker.TrackEvent("CLINICAL_NOTE", "UPDATE"), h.CosignNote)
+		notes.POST("/:noteId/lock", eventTracker.TrackEvent("CLINICAL_NOTE", "UPDATE"), h.LockNote)
+		notes.POST("/:noteId/addenda", eventTracker.TrackEvent("CLINICAL_NOTE_ADDENDUM", "CREATE"), h.AddAddendum)
+		notes.GET("", h.ListNotes)
+		notes.GET("/:noteId", h.GetNote)
+		notes.GET("/:noteId/verify", h.VerifyNote)
+	}
+}
+
+// staffOnly keeps patients away from notes that are still being written
+// and from template management
+func staffOnly(c *gin.Context) {
+	if c.GetString("user_type") == model.UserTypePatient {
+		c.AbortWithStatusJSON(http.StatusForbidden, handler.NewErrorResponse("clinical notes are not available to patients"))
+		return
+	}
+	c.Next()
+}
+
+// ListTemplates lists an organization's templates; inactive ones are included
+// with ?include_inactive=true
+func (h *Handler) ListTemplates(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Query("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid organization ID"))
+		return
+	}
+
+	templates, err := h.service.ListTemplates(c.Request.Context(), orgID, c.Query("include_inactive") == "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(templates))
+}
+
+func (h *Handler) CreateTemplate(c *gin.Context) {
+	var req model.CreateNoteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	template, err := h.service.CreateTemplate(c.Request.Context(), c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(template))
+}
+
+func (h *Handler) GetTemplate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("templateId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid template ID"))
+		return
+	}
+
+	template, err := h.service.GetTemplate(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(template))
+}
+
+func (h *Handler) UpdateTemplate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("templateId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid template ID"))
+		return
+	}
+
+	var req model.UpdateNoteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	template, err := h.service.UpdateTemplate(c.Request.Context(), id, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(template))
+}
+
+func (h *Handler) ListNotes(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	notes, err := h.service.List(c.Request.Context(), patientID, model.NoteStatus(c.Query("status")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(notes))
+}
+
+// CreateNote starts a draft from a template
+func (h *Handler) CreateNote(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.CreateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	n, err := h.service.Create(c.Request.Context(), patientID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(n))
+}
+
+func (h *Handler) GetNote(c *gin.Context) {
+	patientID, noteID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	n, err := h.service.Get(c.Request.Context(), patientID, noteID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(n))
+}
+
+// UpdateNote replaces a draft's sections. A stale version is answered with
+// 409 so the author can reload the draft.
+func (h *Handler) UpdateNote(c *gin.Context) {
+	patientID, noteID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	n, err := h.service.Update(c.Request.Context(), patientID, noteID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(n))
+}
+
+func (h *Handler) SignNote(c *gin.Context) {
+	patientID, noteID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	n, err := h.service.Sign(c.Request.Context(), patientID, noteID, c.GetString("user_type"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(n))
+}
+
+func (h *Handler) CosignNote(c *gin.Context) {
+	patientID, noteID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	n, err := h.service.Cosign(c.Request.Context(), patientID, noteID, c.GetString("user_type"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(n))
+}
+
+func (h *Handler) LockNote(c *gin.Context) {
+	patientID, noteID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	n, err := h.service.Lock(c.Request.Context(), patientID, noteID, c.GetString("user_type"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(n))
+}
+
+func (h *Handler) AddAddendum(c *gin.Context) {
+	patientID, noteID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.AddAddendumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	addendum, err := h.service.AddAddendum(c.Request.Context(), patientID, noteID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(addendum))
+}
+
+// VerifyNote checks a signed note and its addenda against the hashes in the
+// audit log
+func (h *Handler) VerifyNote(c *gin.Context) {
+	patientID, noteID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Verify(c.Request.Context(), patientID, noteID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(result))
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, note.ErrNotFound), errors.Is(err, note.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, note.ErrNotClinician), errors.Is(err, note.ErrNotAuthor),
+		errors.Is(err, note.ErrNotCosigner), errors.Is(err, note.ErrNotTemplateManager):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, repository.ErrVersionConflict), errors.Is(err, note.ErrNotDraft),
+		errors.Is(err, note.ErrNotSigned), errors.Is(err, note.ErrNotLocked),
+		errors.Is(err, note.ErrCosignNotNeeded), errors.Is(err, note.ErrCosignRequired),
+		errors.Is(err, note.ErrTemplateExists), errors.Is(err, note.ErrTampered):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, note.ErrInvalidSections), errors.Is(err, note.ErrMissingSections),
+		errors.Is(err, note.ErrInvalidTemplate), errors.Is(err, note.ErrTemplateInactive),
+		errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
+
+func parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	noteID, err := uuid.Parse(c.Param("noteId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid note ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	return patientID, noteID, true
+}
@@ -0,0 +1,323 @@
+package observation
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/observation"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *observation.Service
+}
+
+func NewHandler(service *observation.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.GET("/observations", h.ListObservations)
+		patients.POST("/observations", h.RecordObservations)
+		patients.GET("/observations/trends", h.GetTrend)
+		patients.GET("/observations/:observationId", h.GetObservation)
+		patients.POST("/observations/:observationId/entered-in-error", h.EnterInError)
+	}
+
+	observations := r.Group("/observations")
+	{
+		observations.GET("/types", h.ListTypes)
+		observations.GET("/reference-ranges", h.ListReferenceRanges)
+		observations.POST("/reference-ranges", h.CreateReferenceRange)
+		observations.DELETE("/reference-ranges/:rangeId", h.DeleteReferenceRange)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/observations", eventTracker.TrackEvent("OBSERVATION", "CREATE"), h.RecordObservations)
+		patients.POST("/observations/:observationId/entered-in-error", eventTracker.TrackEvent("OBSERVATION", "UPDATE"), h.EnterInError)
+		patients.GET("/observations", h.ListObservations)
+		patients.GET("/observations/trends", h.GetTrend)
+		patients.GET("/observations/:observationId", h.GetObservation)
+	}
+
+	observations := r.Group("/observations")
+	{
+		observations.POST("/reference-ranges", eventTracker.TrackEvent("REFERENCE_RANGE", "CREATE"), h.CreateReferenceRange)
+		observations.DELETE("/reference-ranges/:rangeId", eventTracker.TrackEvent("REFERENCE_RANGE", "DELETE"), h.DeleteReferenceRange)
+		observations.GET("/types", h.ListTypes)
+		observations.GET("/reference-ranges", h.ListReferenceRanges)
+	}
+}
+
+// RecordObservations saves a set of readings taken together, such as the
+// vitals of one visit
+func (h *Handler) RecordObservations(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.RecordObservationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	observations, err := h.service.Record(c.Request.Context(), patientID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(observations))
+}
+
+// ListObservations lists a patient's observations, newest first. They can be
+// narrowed by type, appointment and an RFC 3339 from/to window.
+func (h *Handler) ListObservations(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	from, to, ok := parseWindow(c)
+	if !ok {
+		return
+	}
+	filter := &model.ObservationFilter{
+		Type:          model.ObservationType(c.Query("type")),
+		From:          from,
+		To:            to,
+		IncludeErrors: c.Query("include_errors") == "true",
+	}
+	if appointment := c.Query("appointment_id"); appointment != "" {
+		appointmentID, err := uuid.Parse(appointment)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid appointment ID"))
+			return
+		}
+		filter.AppointmentID = &appointmentID
+	}
+
+	observations, err := h.service.List(c.Request.Context(), patientID, filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(observations))
+}
+
+// GetTrend returns ?type= observations bucketed for charting. ?interval= is
+// a duration such as 1h or 24h; without it one is chosen for the window.
+func (h *Handler) GetTrend(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	obsType := model.ObservationType(c.Query("type"))
+	if obsType == "" {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("type is required"))
+		return
+	}
+	from, to, ok := parseWindow(c)
+	if !ok {
+		return
+	}
+	var interval time.Duration
+	if v := c.Query("interval"); v != "" {
+		if interval, err = time.ParseDuration(v); err != nil || interval < time.Minute {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("interval must be a duration of at least 1m"))
+			return
+		}
+	}
+
+	trend, err := h.service.Trend(c.Request.Context(), patientID, obsType, from, to, interval)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(trend))
+}
+
+func (h *Handler) GetObservation(c *gin.Context) {
+	patientID, observationID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	o, err := h.service.Get(c.Request.Context(), patientID, observationID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(o))
+}
+
+func (h *Handler) EnterInError(c *gin.Context) {
+	patientID, observationID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.EnteredInErrorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	o, err := h.service.EnterInError(c.Request.Context(), patientID, observationID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(o))
+}
+
+func (h *Handler) ListTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(h.service.Types()))
+}
+
+func (h *Handler) ListReferenceRanges(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	ranges, err := h.service.ListReferenceRanges(c.Request.Context(), orgID, model.ObservationType(c.Query("type")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(ranges))
+}
+
+func (h *Handler) CreateReferenceRange(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateReferenceRangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	rng, err := h.service.CreateReferenceRange(c.Request.Context(), orgID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(rng))
+}
+
+func (h *Handler) DeleteReferenceRange(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	rangeID, err := uuid.Parse(c.Param("rangeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid reference range ID"))
+		return
+	}
+
+	if err := h.service.DeleteReferenceRange(c.Request.Context(), orgID, rangeID, c.GetString("user_type")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+func parseWindow(c *gin.Context) (*time.Time, *time.Time, bool) {
+	var from, to *time.Time
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid from date"))
+			return nil, nil, false
+		}
+		from = &t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid to date"))
+			return nil, nil, false
+		}
+		to = &t
+	}
+	return from, to, true
+}
+
+func parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	observationID, err := uuid.Parse(c.Param("observationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid observation ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return patientID, observationID, true
+}
+
+// callerOrganization returns the organization whose reference ranges the
+// caller manages
+func callerOrganization(c *gin.Context) (uuid.UUID, bool) {
+	v, _ := c.Get("organization_id")
+	orgID, ok := v.(uuid.UUID)
+	if !ok || orgID == uuid.Nil {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("no organization for the current user"))
+		return uuid.Nil, false
+	}
+	return orgID, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, observation.ErrNotFound), errors.Is(err, observation.ErrRangeNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, observation.ErrNotClinician), errors.Is(err, observation.ErrNotAdmin),
+		errors.Is(err, observation.ErrDefaultRange):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, observation.ErrAlreadyInError):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, observation.ErrImplausibleValue):
+		c.JSON(http.StatusUnprocessableEntity, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, observation.ErrUnknownType), errors.Is(err, observation.ErrInvalidUnit),
+		errors.Is(err, observation.ErrFutureObservation), errors.Is(err, observation.ErrAppointmentMismatch),
+		errors.Is(err, observation.ErrInvalidRange), errors.Is(err, observation.ErrInvalidWindow),
+		errors.Is(err, observation.ErrTooManyPoints), errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
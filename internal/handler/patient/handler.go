@@ -2,8 +2,11 @@ package patient
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -11,6 +14,7 @@ import (
 	"github.com/jwalitptl/admin-api/internal/handler"
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository/postgres"
+	"github.com/jwalitptl/admin-api/internal/service/insurance"
 	"github.com/jwalitptl/admin-api/internal/service/patient"
 	"github.com/jwalitptl/admin-api/internal/service/region"
 	"github.com/jwalitptl/admin-api/pkg/event"
@@ -18,14 +22,16 @@ import (
 
 type Handler struct {
 	service              patient.PatientService
+	insuranceSvc         *insurance.Service
 	outboxRepo           postgres.OutboxRepository
 	*handler.BaseHandler // Embed BaseHandler for region functionality
 }
 
-func NewHandler(service patient.PatientService, outboxRepo postgres.OutboxRepository, regionSvc *region.Service) *Handler {
+func NewHandler(service patient.PatientService, insuranceSvc *insurance.Service, outboxRepo postgres.OutboxRepository, regionSvc *region.Service) *Handler {
 	return &Handler{
-		service:    service,
-		outboxRepo: outboxRepo,
+		service:      service,
+		insuranceSvc: insuranceSvc,
+		outboxRepo:   outboxRepo,
 		BaseHandler: &handler.BaseHandler{
 			RegionSvc:     regionSvc,
 			DefaultConfig: regionSvc.GetDefaultConfig(),
@@ -53,6 +59,9 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 
 		patients.PUT("/:id/insurance", h.UpdateInsurance)
 		patients.GET("/:id/insurance", h.GetInsurance)
+		patients.GET("/:id/insurance/:coverageId/history", h.GetInsuranceHistory)
+		patients.POST("/:id/insurance/:coverageId/eligibility", h.CheckEligibility)
+		patients.GET("/:id/insurance/:coverageId/eligibility", h.ListEligibilityChecks)
 	}
 }
 
@@ -64,6 +73,12 @@ func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *eve
 		patients.DELETE("/:id", eventTracker.TrackEvent("PATIENT", "DELETE"), h.DeletePatient)
 		patients.GET("", h.ListPatients)
 		patients.GET("/:id", h.GetPatient)
+
+		patients.PUT("/:id/insurance", eventTracker.TrackEvent("INSURANCE", "UPDATE"), h.UpdateInsurance)
+		patients.GET("/:id/insurance", h.GetInsurance)
+		patients.GET("/:id/insurance/:coverageId/history", h.GetInsuranceHistory)
+		patients.POST("/:id/insurance/:coverageId/eligibility", h.CheckEligibility)
+		patients.GET("/:id/insurance/:coverageId/eligibility", h.ListEligibilityChecks)
 	}
 }
 
@@ -98,6 +113,7 @@ func (h *Handler) CreatePatient(c *gin.Context) {
 			ID: uuid.New(), // Generate new UUID
 		},
 		ClinicID:    clinicID,
+		UserID:      req.UserID,
 		FirstName:   req.FirstName,
 		LastName:    req.LastName,
 		Email:       req.Email,
@@ -105,6 +121,8 @@ func (h *Handler) CreatePatient(c *gin.Context) {
 		Phone:       req.Phone,
 		Address:     req.Address,
 		Status:      req.Status,
+		Locale:      req.Locale,
+		Timezone:    req.Timezone,
 	}
 
 	err = h.service.CreatePatient(c.Request.Context(), patient)
@@ -158,6 +176,19 @@ func (h *Handler) UpdatePatient(c *gin.Context) {
 		return
 	}
 
+	// Proxies may manage the profile but not the patient's email
+	if _, ok := c.Get("acting_for"); ok && req.Email != nil {
+		current, err := h.service.GetPatient(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+			return
+		}
+		if !strings.EqualFold(current.Email, *req.Email) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "proxies cannot change the patient's email"})
+			return
+		}
+	}
+
 	patient := &model.Patient{
 		Base:        model.Base{ID: id},
 		FirstName:   *req.FirstName,
@@ -168,6 +199,12 @@ func (h *Handler) UpdatePatient(c *gin.Context) {
 		Address:     *req.Address,
 		Status:      *req.Status,
 	}
+	if req.Locale != nil {
+		patient.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		patient.Timezone = *req.Timezone
+	}
 
 	if err := h.service.UpdatePatient(c.Request.Context(), patient); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -219,13 +256,16 @@ func (h *Handler) DeletePatient(c *gin.Context) {
 }
 
 func (h *Handler) ListPatients(c *gin.Context) {
-	filters := make(map[string]interface{})
-
-	if status := c.Query("status"); status != "" {
-		filters["status"] = status
+	filters := &model.PatientFilters{
+		SearchTerm:       c.Query("search"),
+		Status:           c.Query("status"),
+		Identifier:       c.Query("identifier"),
+		IdentifierSystem: c.Query("identifier_system"),
+		Email:            c.Query("email"),
+		Phone:            c.Query("phone"),
 	}
 
-	patients, err := h.service.ListPatients(c.Request.Context(), &model.PatientFilters{})
+	patients, err := h.service.ListPatients(c.Request.Context(), filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -281,10 +321,116 @@ func (h *Handler) CancelAppointment(c *gin.Context) {
 	// Implementation of CancelAppointment
 }
 
+// UpdateInsurance adds a coverage, or updates an existing one when the body
+// carries its id.
 func (h *Handler) UpdateInsurance(c *gin.Context) {
-	// Implementation of UpdateInsurance
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.CoverageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	coverage, err := h.insuranceSvc.SaveCoverage(c.Request.Context(), patientID, &req)
+	if err != nil {
+		if errors.Is(err, insurance.ErrPriorityTaken) {
+			c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(coverage))
 }
 
+// GetInsurance lists the patient's coverages by priority. Inactive coverages
+// are included with ?include_inactive=true.
 func (h *Handler) GetInsurance(c *gin.Context) {
-	// Implementation of GetInsurance
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	activeOnly := c.Query("include_inactive") != "true"
+	coverages, err := h.insuranceSvc.ListCoverages(c.Request.Context(), patientID, activeOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(coverages))
+}
+
+func (h *Handler) GetInsuranceHistory(c *gin.Context) {
+	patientID, coverageID, ok := parseCoverageParams(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.insuranceSvc.GetCoverageHistory(c.Request.Context(), patientID, coverageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(history))
+}
+
+func (h *Handler) CheckEligibility(c *gin.Context) {
+	patientID, coverageID, ok := parseCoverageParams(c)
+	if !ok {
+		return
+	}
+
+	var req model.EligibilityCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	check, err := h.insuranceSvc.CheckEligibility(c.Request.Context(), patientID, coverageID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(check))
+}
+
+func (h *Handler) ListEligibilityChecks(c *gin.Context) {
+	patientID, coverageID, ok := parseCoverageParams(c)
+	if !ok {
+		return
+	}
+
+	checks, err := h.insuranceSvc.ListEligibilityChecks(c.Request.Context(), patientID, coverageID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(checks))
+}
+
+func parseCoverageParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	coverageID, err := uuid.Parse(c.Param("coverageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid coverage ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return patientID, coverageID, true
 }
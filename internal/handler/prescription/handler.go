@@ -0,0 +1,225 @@
+package prescription
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/prescription"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *prescription.Service
+}
+
+func NewHandler(service *prescription.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.GET("/prescriptions", h.ListPrescriptions)
+		patients.POST("/prescriptions", h.Prescribe)
+		patients.POST("/prescriptions/check", h.CheckInteractions)
+		patients.GET("/prescriptions/:prescriptionId", h.GetPrescription)
+		patients.PUT("/prescriptions/:prescriptionId/status", h.UpdateStatus)
+	}
+
+	r.GET("/interaction-datasets", h.ListDatasets)
+	r.POST("/interaction-datasets", h.LoadDataset)
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/prescriptions", eventTracker.TrackEvent("PRESCRIPTION", "CREATE"), h.Prescribe)
+		patients.PUT("/prescriptions/:prescriptionId/status", eventTracker.TrackEvent("PRESCRIPTION", "UPDATE"), h.UpdateStatus)
+		patients.POST("/prescriptions/check", h.CheckInteractions)
+		patients.GET("/prescriptions", h.ListPrescriptions)
+		patients.GET("/prescriptions/:prescriptionId", h.GetPrescription)
+	}
+
+	r.POST("/interaction-datasets", eventTracker.TrackEvent("INTERACTION_DATASET", "CREATE"), h.LoadDataset)
+	r.GET("/interaction-datasets", h.ListDatasets)
+}
+
+// Prescribe saves a prescription once every interaction warning has been
+// acknowledged. Outstanding warnings come back with a 422 so the prescriber
+// can review them and resubmit with their keys.
+func (h *Handler) Prescribe(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.CreatePrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	p, err := h.service.Prescribe(c.Request.Context(), patientID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(p))
+}
+
+// CheckInteractions reports the warnings a drug would raise without saving
+// anything
+func (h *Handler) CheckInteractions(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req struct {
+		DrugCode string `json:"drug_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	warnings, err := h.service.CheckInteractions(c.Request.Context(), patientID, req.DrugCode)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(warnings))
+}
+
+func (h *Handler) ListPrescriptions(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	prescriptions, err := h.service.List(c.Request.Context(), patientID, model.PrescriptionStatus(c.Query("status")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(prescriptions))
+}
+
+func (h *Handler) GetPrescription(c *gin.Context) {
+	patientID, prescriptionID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	p, err := h.service.Get(c.Request.Context(), patientID, prescriptionID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(p))
+}
+
+func (h *Handler) UpdateStatus(c *gin.Context) {
+	patientID, prescriptionID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdatePrescriptionStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	p, err := h.service.UpdateStatus(c.Request.Context(), patientID, prescriptionID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(p))
+}
+
+// LoadDataset replaces an interaction dataset from the server's dataset
+// directory. Only administrators can load datasets.
+func (h *Handler) LoadDataset(c *gin.Context) {
+	if c.GetString("user_type") != model.UserTypeAdmin {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("only administrators can load interaction datasets"))
+		return
+	}
+
+	var req model.LoadInteractionDatasetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	dataset, err := h.service.LoadDataset(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(dataset))
+}
+
+func (h *Handler) ListDatasets(c *gin.Context) {
+	datasets, err := h.service.ListDatasets(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(datasets))
+}
+
+func respondError(c *gin.Context, err error) {
+	var warningsErr *prescription.WarningsError
+	switch {
+	case errors.As(err, &warningsErr):
+		c.JSON(http.StatusUnprocessableEntity, &handler.Response{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    warningsErr.Warnings,
+		})
+	case errors.Is(err, prescription.ErrNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, prescription.ErrNotPrescriber):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, prescription.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, prescription.ErrReasonRequired), errors.Is(err, prescription.ErrInvalidDates),
+		errors.Is(err, prescription.ErrUnknownDataset), errors.Is(err, prescription.ErrInvalidFile):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
+
+func parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	prescriptionID, err := uuid.Parse(c.Param("prescriptionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid prescription ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return patientID, prescriptionID, true
+}
@@ -0,0 +1,335 @@
+package referral
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/internal/service/referral"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *referral.Service
+}
+
+func NewHandler(service *referral.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	patientReferrals := r.Group("/patients/:id/referrals", staffOnly)
+	{
+		patientReferrals.GET("", h.ListPatientReferrals)
+		patientReferrals.POST("", h.CreateReferral)
+	}
+
+	referrals := r.Group("/referrals", staffOnly)
+	{
+		referrals.GET("", h.ListReferrals)
+		referrals.GET("/:referralId", h.GetReferral)
+		referrals.POST("/:referralId/accept", h.AcceptReferral)
+		referrals.POST("/:referralId/decline", h.DeclineReferral)
+		referrals.POST("/:referralId/complete", h.CompleteReferral)
+		referrals.POST("/:referralId/revoke", h.RevokeReferral)
+		referrals.GET("/:referralId/patient", h.GetSharedPatient)
+		referrals.GET("/:referralId/records", h.ListSharedRecords)
+		referrals.GET("/:referralId/records/:recordId", h.GetSharedRecord)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	patientReferrals := r.Group("/patients/:id/referrals", staffOnly)
+	{
+		patientReferrals.POST("", eventTracker.TrackEvent("REFERRAL", "CREATE"), h.CreateReferral)
+		patientReferrals.GET("", h.ListPatientReferrals)
+	}
+
+	referrals := r.Group("/referrals", staffOnly)
+	{
+		referrals.POST("/:referralId/accept", eventTracker.TrackEvent("REFERRAL", "UPDATE"), h.AcceptReferral)
+		referrals.POST("/:referralId/decline", eventTracker.TrackEvent("REFERRAL", "UPDATE"), h.DeclineReferral)
+		referrals.POST("/:referralId/complete", eventTracker.TrackEvent("REFERRAL", "UPDATE"), h.CompleteReferral)
+		referrals.POST("/:referralId/revoke", eventTracker.TrackEvent("REFERRAL", "UPDATE"), h.RevokeReferral)
+		referrals.GET("", h.ListReferrals)
+		referrals.GET("/:referralId", h.GetReferral)
+		referrals.GET("/:referralId/patient", h.GetSharedPatient)
+		referrals.GET("/:referralId/records", h.ListSharedRecords)
+		referrals.GET("/:referralId/records/:recordId", h.GetSharedRecord)
+	}
+}
+
+// staffOnly keeps referrals between organizations' staff
+func staffOnly(c *gin.Context) {
+	if c.GetString("user_type") == model.UserTypePatient {
+		c.AbortWithStatusJSON(http.StatusForbidden, handler.NewErrorResponse("referrals are not available to patients"))
+		return
+	}
+	c.Next()
+}
+
+func (h *Handler) CreateReferral(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateReferralRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	ref, err := h.service.Create(c.Request.Context(), patientID, orgID, recordReader(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(ref))
+}
+
+// ListPatientReferrals lists the referrals the caller's organization sent
+// for a patient
+func (h *Handler) ListPatientReferrals(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	referrals, err := h.service.List(c.Request.Context(), &model.ReferralFilter{
+		OrganizationID: orgID,
+		Direction:      "outgoing",
+		PatientID:      &patientID,
+		Status:         model.ReferralStatus(c.Query("status")),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(referrals))
+}
+
+// ListReferrals lists the caller's organization's referrals; ?direction=incoming
+// lists those it received, otherwise those it sent
+func (h *Handler) ListReferrals(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	direction := c.DefaultQuery("direction", "outgoing")
+	if direction != "incoming" && direction != "outgoing" {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("direction must be incoming or outgoing"))
+		return
+	}
+
+	referrals, err := h.service.List(c.Request.Context(), &model.ReferralFilter{
+		OrganizationID: orgID,
+		Direction:      direction,
+		Status:         model.ReferralStatus(c.Query("status")),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(referrals))
+}
+
+func (h *Handler) GetReferral(c *gin.Context) {
+	id, orgID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	ref, err := h.service.Get(c.Request.Context(), id, orgID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(ref))
+}
+
+func (h *Handler) AcceptReferral(c *gin.Context) {
+	h.decide(c, h.service.Accept)
+}
+
+func (h *Handler) DeclineReferral(c *gin.Context) {
+	h.decide(c, h.service.Decline)
+}
+
+func (h *Handler) CompleteReferral(c *gin.Context) {
+	h.decide(c, h.service.Complete)
+}
+
+func (h *Handler) RevokeReferral(c *gin.Context) {
+	id, orgID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	ref, err := h.service.Revoke(c.Request.Context(), id, orgID, callerID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(ref))
+}
+
+func (h *Handler) GetSharedPatient(c *gin.Context) {
+	id, orgID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	demographics, err := h.service.SharedDemographics(c.Request.Context(), id, orgID, callerID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(demographics))
+}
+
+func (h *Handler) ListSharedRecords(c *gin.Context) {
+	id, orgID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	records, err := h.service.SharedRecords(c.Request.Context(), id, orgID, callerID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(records))
+}
+
+func (h *Handler) GetSharedRecord(c *gin.Context) {
+	id, orgID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+	recordID, err := uuid.Parse(c.Param("recordId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid record ID"))
+		return
+	}
+
+	record, err := h.service.SharedRecord(c.Request.Context(), id, recordID, orgID, callerID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(record))
+}
+
+type decideFunc func(ctx context.Context, id, orgID, userID uuid.UUID, req *model.ReferralDecisionRequest) (*model.Referral, error)
+
+func (h *Handler) decide(c *gin.Context, fn decideFunc) {
+	id, orgID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.ReferralDecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	ref, err := fn(c.Request.Context(), id, orgID, callerID(c), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(ref))
+}
+
+// callerOrganization returns the organization the authenticated user acts
+// for; it decides which side of a referral they are on
+func callerOrganization(c *gin.Context) (uuid.UUID, bool) {
+	v, _ := c.Get("organization_id")
+	orgID, ok := v.(uuid.UUID)
+	if !ok || orgID == uuid.Nil {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("no organization for the current user"))
+		return uuid.Nil, false
+	}
+	return orgID, true
+}
+
+// callerID is the authenticated user, recorded as the actor on referrals
+func callerID(c *gin.Context) uuid.UUID {
+	userID, _ := c.Get("user_id")
+	uid, _ := userID.(uuid.UUID)
+	return uid
+}
+
+// recordReader describes the caller for the record access checks
+func recordReader(c *gin.Context) *model.RecordReader {
+	return &model.RecordReader{
+		UserID:   callerID(c),
+		UserType: c.GetString("user_type"),
+		Reason:   c.GetHeader("X-Access-Reason"),
+		Self:     c.GetBool("acting_for_self"),
+	}
+}
+
+func parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("referralId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid referral ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, orgID, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, referral.ErrNotFound), errors.Is(err, referral.ErrRecordNotShared):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, referral.ErrNotReferrer), errors.Is(err, referral.ErrNotRecipient),
+		errors.Is(err, referral.ErrNotSender), errors.Is(err, referral.ErrAccessExpired),
+		errors.Is(err, referral.ErrScopeNotShared), errors.Is(err, medical.ErrAccessDenied),
+		errors.Is(err, medical.ErrAccessReasonRequired):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, referral.ErrInvalidTransition), errors.Is(err, referral.ErrConflict):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, referral.ErrSameOrganization), errors.Is(err, referral.ErrInvalidRecords),
+		errors.Is(err, referral.ErrNoRecordsAttached), errors.Is(err, referral.ErrInvalidExpiry),
+		errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
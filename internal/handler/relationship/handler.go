@@ -0,0 +1,173 @@
+package relationship
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/relationship"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *relationship.Service
+}
+
+func NewHandler(service *relationship.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.GET("/relationships", h.ListRelationships)
+		patients.POST("/relationships", h.CreateRelationship)
+		patients.GET("/relationships/:relationshipId", h.GetRelationship)
+		patients.PUT("/relationships/:relationshipId", h.UpdateRelationship)
+		patients.DELETE("/relationships/:relationshipId", h.RevokeRelationship)
+	}
+
+	r.GET("/dependents", h.ListDependents)
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	patients := r.Group("/patients/:id")
+	{
+		patients.POST("/relationships", eventTracker.TrackEvent("RELATIONSHIP", "CREATE"), h.CreateRelationship)
+		patients.PUT("/relationships/:relationshipId", eventTracker.TrackEvent("RELATIONSHIP", "UPDATE"), h.UpdateRelationship)
+		patients.DELETE("/relationships/:relationshipId", eventTracker.TrackEvent("RELATIONSHIP", "DELETE"), h.RevokeRelationship)
+		patients.GET("/relationships", h.ListRelationships)
+		patients.GET("/relationships/:relationshipId", h.GetRelationship)
+	}
+
+	r.GET("/dependents", h.ListDependents)
+}
+
+func (h *Handler) CreateRelationship(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	var req model.CreateRelationshipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	rel, err := h.service.Create(c.Request.Context(), patientID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(rel))
+}
+
+func (h *Handler) GetRelationship(c *gin.Context) {
+	patientID, relationshipID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	rel, err := h.service.Get(c.Request.Context(), patientID, relationshipID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(rel))
+}
+
+func (h *Handler) UpdateRelationship(c *gin.Context) {
+	patientID, relationshipID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateRelationshipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	rel, err := h.service.Update(c.Request.Context(), patientID, relationshipID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(rel))
+}
+
+func (h *Handler) RevokeRelationship(c *gin.Context) {
+	patientID, relationshipID, ok := parseIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), patientID, relationshipID); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(gin.H{"message": "relationship revoked"}))
+}
+
+func (h *Handler) ListRelationships(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	rels, err := h.service.ListForPatient(c.Request.Context(), patientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(rels))
+}
+
+// ListDependents returns the patients the current user may act for
+func (h *Handler) ListDependents(c *gin.Context) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, handler.NewErrorResponse("user not authenticated"))
+		return
+	}
+	uid, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, handler.NewErrorResponse("user not authenticated"))
+		return
+	}
+
+	rels, err := h.service.ListDependents(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(rels))
+}
+
+func parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	relationshipID, err := uuid.Parse(c.Param("relationshipId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid relationship ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return patientID, relationshipID, true
+}
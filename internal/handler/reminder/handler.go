@@ -0,0 +1,189 @@
+package reminder
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/appointment"
+	"github.com/jwalitptl/admin-api/internal/service/reminder"
+	"github.com/jwalitptl/admin-api/pkg/event"
+	"github.com/jwalitptl/admin-api/pkg/security"
+)
+
+type Handler struct {
+	service      *reminder.Service
+	appointments *appointment.Service
+}
+
+func NewHandler(service *reminder.Service, appointments *appointment.Service) *Handler {
+	return &Handler{service: service, appointments: appointments}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	schedules := r.Group("/reminder-schedules")
+	{
+		schedules.GET("", h.ListSchedules)
+		schedules.PUT("", h.SetSchedule)
+		schedules.DELETE("/:id", h.DeleteSchedule)
+	}
+
+	r.GET("/appointments/:id/reminders", h.ListReminders)
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	schedules := r.Group("/reminder-schedules")
+	{
+		schedules.PUT("", eventTracker.TrackEvent("REMINDER_SCHEDULE", "UPDATE"), h.SetSchedule)
+		schedules.DELETE("/:id", eventTracker.TrackEvent("REMINDER_SCHEDULE", "DELETE"), h.DeleteSchedule)
+		schedules.GET("", h.ListSchedules)
+	}
+
+	r.GET("/appointments/:id/reminders", h.ListReminders)
+}
+
+// RegisterPublicRoutes mounts the confirm and cancel links sent in
+// reminders. They sit outside the authenticated group because the signed
+// link itself carries the authorization.
+func (h *Handler) RegisterPublicRoutes(r *gin.RouterGroup) {
+	responses := r.Group("/appointment-responses")
+	{
+		responses.GET("/:id/confirm", h.respond(model.ReminderActionConfirm))
+		responses.GET("/:id/cancel", h.respond(model.ReminderActionCancel))
+	}
+}
+
+func (h *Handler) ListSchedules(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	schedules, err := h.service.ListSchedules(c.Request.Context(), orgID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(schedules))
+}
+
+// SetSchedule replaces the organization's default reminder schedule, or a
+// service's when service_id is given
+func (h *Handler) SetSchedule(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req model.SetReminderScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	schedule, err := h.service.SetSchedule(c.Request.Context(), orgID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(schedule))
+}
+
+func (h *Handler) DeleteSchedule(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSchedule(c.Request.Context(), orgID, id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+func (h *Handler) ListReminders(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	reminders, err := h.appointments.ListReminders(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(reminders))
+}
+
+// respond answers a confirm or cancel link from a reminder
+func (h *Handler) respond(action model.ReminderAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseID(c, "id")
+		if !ok {
+			return
+		}
+
+		apt, err := h.appointments.RespondToReminder(c.Request.Context(), id, action, c.Request.URL.Path, c.Request.URL.Query())
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, handler.NewSuccessResponse(gin.H{
+			"appointment_id": apt.ID,
+			"status":         apt.Status,
+			"start_time":     apt.StartTime,
+		}))
+	}
+}
+
+func parseID(c *gin.Context, param string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid "+param))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func callerOrganization(c *gin.Context) (uuid.UUID, bool) {
+	v, _ := c.Get("organization_id")
+	orgID, ok := v.(uuid.UUID)
+	if !ok || orgID == uuid.Nil {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("no organization for the current user"))
+		return uuid.Nil, false
+	}
+	return orgID, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, security.ErrInvalidSignature):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, security.ErrSignatureExpired):
+		c.JSON(http.StatusGone, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, reminder.ErrScheduleNotFound), errors.Is(err, reminder.ErrServiceNotFound),
+		errors.Is(err, appointment.ErrAppointmentNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, appointment.ErrAppointmentClosed):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, reminder.ErrDuplicateRule), errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
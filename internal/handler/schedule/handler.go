@@ -0,0 +1,418 @@
+package schedule
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/schedule"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+// Ranges that are not given default to this long from their start
+const (
+	defaultAvailabilityRange = 7 * 24 * time.Hour
+	defaultExceptionRange    = 90 * 24 * time.Hour
+	defaultHolidayRange      = 365 * 24 * time.Hour
+)
+
+type Handler struct {
+	service *schedule.Service
+}
+
+func NewHandler(service *schedule.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	clinicians := r.Group("/clinicians/:id")
+	{
+		clinicians.GET("/availability", h.GetAvailability)
+		clinicians.GET("/availability-rules", h.ListRules)
+		clinicians.POST("/availability-rules", h.CreateRule)
+		clinicians.GET("/availability-rules/:ruleId", h.GetRule)
+		clinicians.PUT("/availability-rules/:ruleId", h.UpdateRule)
+		clinicians.DELETE("/availability-rules/:ruleId", h.DeleteRule)
+		clinicians.GET("/schedule-exceptions", h.ListExceptions)
+		clinicians.POST("/schedule-exceptions", h.CreateException)
+		clinicians.DELETE("/schedule-exceptions/:exceptionId", h.DeleteException)
+	}
+
+	holidays := r.Group("/holidays")
+	{
+		holidays.GET("", h.ListHolidays)
+		holidays.POST("", h.CreateHoliday)
+		holidays.DELETE("/:holidayId", h.DeleteHoliday)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	clinicians := r.Group("/clinicians/:id")
+	{
+		clinicians.POST("/availability-rules", eventTracker.TrackEvent("AVAILABILITY_RULE", "CREATE"), h.CreateRule)
+		clinicians.PUT("/availability-rules/:ruleId", eventTracker.TrackEvent("AVAILABILITY_RULE", "UPDATE"), h.UpdateRule)
+		clinicians.DELETE("/availability-rules/:ruleId", eventTracker.TrackEvent("AVAILABILITY_RULE", "DELETE"), h.DeleteRule)
+		clinicians.POST("/schedule-exceptions", eventTracker.TrackEvent("SCHEDULE_EXCEPTION", "CREATE"), h.CreateException)
+		clinicians.DELETE("/schedule-exceptions/:exceptionId", eventTracker.TrackEvent("SCHEDULE_EXCEPTION", "DELETE"), h.DeleteException)
+		clinicians.GET("/availability", h.GetAvailability)
+		clinicians.GET("/availability-rules", h.ListRules)
+		clinicians.GET("/availability-rules/:ruleId", h.GetRule)
+		clinicians.GET("/schedule-exceptions", h.ListExceptions)
+	}
+
+	holidays := r.Group("/holidays")
+	{
+		holidays.POST("", eventTracker.TrackEvent("HOLIDAY", "CREATE"), h.CreateHoliday)
+		holidays.DELETE("/:holidayId", eventTracker.TrackEvent("HOLIDAY", "DELETE"), h.DeleteHoliday)
+		holidays.GET("", h.ListHolidays)
+	}
+}
+
+// GetAvailability returns when the clinician can be booked, resolved from
+// their rules, exceptions and the organization's holidays
+func (h *Handler) GetAvailability(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, ok := parseClinicianID(c)
+	if !ok {
+		return
+	}
+	from, to, ok := parseRange(c, defaultAvailabilityRange)
+	if !ok {
+		return
+	}
+
+	windows, err := h.service.Availability(c.Request.Context(), orgID, clinicianID, from, to)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(windows))
+}
+
+func (h *Handler) ListRules(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, ok := parseClinicianID(c)
+	if !ok {
+		return
+	}
+
+	rules, err := h.service.ListRules(c.Request.Context(), orgID, clinicianID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(rules))
+}
+
+func (h *Handler) GetRule(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, ruleID, ok := parseIDs(c, "ruleId")
+	if !ok {
+		return
+	}
+
+	rule, err := h.service.GetRule(c.Request.Context(), orgID, clinicianID, ruleID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(rule))
+}
+
+func (h *Handler) CreateRule(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, ok := parseClinicianID(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateAvailabilityRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), orgID, clinicianID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(rule))
+}
+
+func (h *Handler) UpdateRule(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, ruleID, ok := parseIDs(c, "ruleId")
+	if !ok {
+		return
+	}
+
+	var req model.UpdateAvailabilityRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), orgID, clinicianID, ruleID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(rule))
+}
+
+func (h *Handler) DeleteRule(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, ruleID, ok := parseIDs(c, "ruleId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRule(c.Request.Context(), orgID, clinicianID, ruleID, c.GetString("user_type")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+func (h *Handler) ListExceptions(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, ok := parseClinicianID(c)
+	if !ok {
+		return
+	}
+	from, to, ok := parseRange(c, defaultExceptionRange)
+	if !ok {
+		return
+	}
+
+	exceptions, err := h.service.ListExceptions(c.Request.Context(), orgID, clinicianID, from, to)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(exceptions))
+}
+
+func (h *Handler) CreateException(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, ok := parseClinicianID(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateScheduleExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	e, err := h.service.CreateException(c.Request.Context(), orgID, clinicianID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(e))
+}
+
+func (h *Handler) DeleteException(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	clinicianID, exceptionID, ok := parseIDs(c, "exceptionId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteException(c.Request.Context(), orgID, clinicianID, exceptionID, c.GetString("user_type")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+func (h *Handler) ListHolidays(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	from, to, ok := parseRange(c, defaultHolidayRange)
+	if !ok {
+		return
+	}
+
+	holidays, err := h.service.ListHolidays(c.Request.Context(), orgID, from, to)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(holidays))
+}
+
+func (h *Handler) CreateHoliday(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateHolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	holiday, err := h.service.CreateHoliday(c.Request.Context(), orgID, c.GetString("user_type"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(holiday))
+}
+
+func (h *Handler) DeleteHoliday(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	holidayID, err := uuid.Parse(c.Param("holidayId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid holiday ID"))
+		return
+	}
+
+	if err := h.service.DeleteHoliday(c.Request.Context(), orgID, holidayID, c.GetString("user_type")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+// parseRange reads the from and to query parameters, as RFC 3339 times or
+// dates. From defaults to now and to to from plus def.
+func parseRange(c *gin.Context, def time.Duration) (time.Time, time.Time, bool) {
+	from := time.Now()
+	if v := c.Query("from"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid from"))
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+
+	to := from.Add(def)
+	if v := c.Query("to"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid to"))
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+	return from, to, true
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+func parseClinicianID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid clinician ID"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func parseIDs(c *gin.Context, param string) (uuid.UUID, uuid.UUID, bool) {
+	clinicianID, ok := parseClinicianID(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid "+param))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return clinicianID, id, true
+}
+
+func callerOrganization(c *gin.Context) (uuid.UUID, bool) {
+	v, _ := c.Get("organization_id")
+	orgID, ok := v.(uuid.UUID)
+	if !ok || orgID == uuid.Nil {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("no organization for the current user"))
+		return uuid.Nil, false
+	}
+	return orgID, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, schedule.ErrRuleNotFound), errors.Is(err, schedule.ErrExceptionNotFound),
+		errors.Is(err, schedule.ErrHolidayNotFound), errors.Is(err, schedule.ErrClinicianNotFound),
+		errors.Is(err, schedule.ErrClinicNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, schedule.ErrNotAllowed), errors.Is(err, schedule.ErrNotAdmin):
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, schedule.ErrOverlappingRule), errors.Is(err, schedule.ErrHolidayExists),
+		errors.Is(err, repository.ErrVersionConflict):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, schedule.ErrInvalidTime), errors.Is(err, schedule.ErrInvalidBreak),
+		errors.Is(err, schedule.ErrInvalidTimezone), errors.Is(err, schedule.ErrInvalidDates),
+		errors.Is(err, schedule.ErrInvalidRange), errors.Is(err, schedule.ErrRangeTooLong),
+		errors.Is(err, schedule.ErrClinicRequired), errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
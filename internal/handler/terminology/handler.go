@@ -0,0 +1,135 @@
+package terminology
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/terminology"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *terminology.Service
+}
+
+func NewHandler(service *terminology.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	terms := r.Group("/terminology")
+	{
+		terms.GET("/releases", h.ListReleases)
+		terms.POST("/releases", h.LoadRelease)
+		terms.POST("/validate", h.Validate)
+		terms.GET("/:system/concepts", h.Search)
+		terms.GET("/:system/concepts/:code", h.Lookup)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	terms := r.Group("/terminology")
+	{
+		terms.POST("/releases", eventTracker.TrackEvent("TERMINOLOGY_RELEASE", "CREATE"), h.LoadRelease)
+		terms.GET("/releases", h.ListReleases)
+		terms.POST("/validate", h.Validate)
+		terms.GET("/:system/concepts", h.Search)
+		terms.GET("/:system/concepts/:code", h.Lookup)
+	}
+}
+
+// LoadRelease imports a release file from the server's release directory.
+// Only administrators can load code systems.
+func (h *Handler) LoadRelease(c *gin.Context) {
+	if c.GetString("user_type") != model.UserTypeAdmin {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("only administrators can load terminology releases"))
+		return
+	}
+
+	var req model.LoadReleaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	release, err := h.service.LoadRelease(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(release))
+}
+
+func (h *Handler) ListReleases(c *gin.Context) {
+	releases, err := h.service.ListReleases(c.Request.Context(), c.Query("system"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(releases))
+}
+
+// Search is the typeahead endpoint: ?q= matches codes, displays and synonyms
+func (h *Handler) Search(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	concepts, err := h.service.Search(c.Request.Context(), c.Param("system"), c.Query("q"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(concepts))
+}
+
+func (h *Handler) Lookup(c *gin.Context) {
+	concept, err := h.service.Lookup(c.Request.Context(), c.Param("system"), c.Param("code"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(concept))
+}
+
+// Validate checks a list of coded entries and returns them normalized
+func (h *Handler) Validate(c *gin.Context) {
+	var entries []model.CodedEntry
+	if err := c.ShouldBindJSON(&entries); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	normalized, err := h.service.Validate(c.Request.Context(), entries)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(normalized))
+}
+
+func respondError(c *gin.Context, err error) {
+	var validationErr *terminology.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusUnprocessableEntity, &handler.Response{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    validationErr.Invalid,
+		})
+	case errors.Is(err, terminology.ErrNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, terminology.ErrUnknownSystem), errors.Is(err, terminology.ErrInvalidFile):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
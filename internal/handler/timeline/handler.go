@@ -0,0 +1,107 @@
+package timeline
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/timeline"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *timeline.Service
+}
+
+func NewHandler(service *timeline.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/patients/:id/timeline", h.GetTimeline)
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	r.GET("/patients/:id/timeline", h.GetTimeline)
+}
+
+// GetTimeline accepts from/to as RFC 3339 timestamps, kinds as a comma
+// separated list, and page_size and the previous page's next_cursor as
+// cursor for pagination. Restricted records
+// follow the medical record rules, including the X-Access-Reason header.
+func (h *Handler) GetTimeline(c *gin.Context) {
+	patientID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid patient ID"))
+		return
+	}
+
+	filter := &model.TimelineFilter{}
+	if err := c.ShouldBindQuery(filter); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	if from := c.Query("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid from date"))
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid to date"))
+			return
+		}
+	}
+
+	if kinds := c.Query("kinds"); kinds != "" {
+		for _, k := range strings.Split(kinds, ",") {
+			kind := model.TimelineKind(strings.TrimSpace(k))
+			if !isKind(kind) {
+				c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid kind: "+string(kind)))
+				return
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+
+	page, err := h.service.Get(c.Request.Context(), patientID, recordReader(c), filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, timeline.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(page))
+}
+
+// recordReader describes the caller for the record access checks
+func recordReader(c *gin.Context) *model.RecordReader {
+	userID, _ := c.Get("user_id")
+	uid, _ := userID.(uuid.UUID)
+	return &model.RecordReader{
+		UserID:   uid,
+		UserType: c.GetString("user_type"),
+		Reason:   c.GetHeader("X-Access-Reason"),
+		Self:     c.GetBool("acting_for_self"),
+	}
+}
+
+func isKind(kind model.TimelineKind) bool {
+	for _, k := range model.TimelineKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
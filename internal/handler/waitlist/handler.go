@@ -0,0 +1,275 @@
+package waitlist
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/handler"
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/waitlist"
+	"github.com/jwalitptl/admin-api/pkg/event"
+)
+
+type Handler struct {
+	service *waitlist.Service
+}
+
+func NewHandler(service *waitlist.Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	entries := r.Group("/waitlist")
+	{
+		entries.GET("", h.ListEntries)
+		entries.POST("", h.CreateEntry)
+		entries.GET("/:id", h.GetEntry)
+		entries.PUT("/:id", h.UpdateEntry)
+		entries.DELETE("/:id", h.RemoveEntry)
+		entries.GET("/:id/offers", h.ListOffers)
+	}
+
+	offers := r.Group("/waitlist-offers")
+	{
+		offers.POST("/:offerId/accept", h.AcceptOffer)
+		offers.POST("/:offerId/decline", h.DeclineOffer)
+	}
+}
+
+func (h *Handler) RegisterRoutesWithEvents(r *gin.RouterGroup, eventTracker *event.EventTrackerMiddleware) {
+	entries := r.Group("/waitlist")
+	{
+		entries.POST("", eventTracker.TrackEvent("WAITLIST_ENTRY", "CREATE"), h.CreateEntry)
+		entries.PUT("/:id", eventTracker.TrackEvent("WAITLIST_ENTRY", "UPDATE"), h.UpdateEntry)
+		entries.DELETE("/:id", eventTracker.TrackEvent("WAITLIST_ENTRY", "DELETE"), h.RemoveEntry)
+		entries.GET("", h.ListEntries)
+		entries.GET("/:id", h.GetEntry)
+		entries.GET("/:id/offers", h.ListOffers)
+	}
+
+	offers := r.Group("/waitlist-offers")
+	{
+		offers.POST("/:offerId/accept", eventTracker.TrackEvent("WAITLIST_OFFER", "ACCEPT"), h.AcceptOffer)
+		offers.POST("/:offerId/decline", eventTracker.TrackEvent("WAITLIST_OFFER", "DECLINE"), h.DeclineOffer)
+	}
+}
+
+// ListEntries returns the waitlist in offer order, optionally narrowed by
+// clinic_id, clinician_id, service_id and status
+func (h *Handler) ListEntries(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	filters := &model.WaitlistFilters{
+		Status: model.WaitlistStatus(c.Query("status")),
+	}
+	for param, dst := range map[string]**uuid.UUID{
+		"clinic_id":    &filters.ClinicID,
+		"clinician_id": &filters.ClinicianID,
+		"service_id":   &filters.ServiceID,
+	} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		id, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid "+param))
+			return
+		}
+		*dst = &id
+	}
+
+	entries, err := h.service.ListEntries(c.Request.Context(), orgID, filters)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(entries))
+}
+
+func (h *Handler) CreateEntry(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateWaitlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	entry, err := h.service.CreateEntry(c.Request.Context(), orgID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(entry))
+}
+
+func (h *Handler) GetEntry(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	entry, err := h.service.GetEntry(c.Request.Context(), orgID, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(entry))
+}
+
+func (h *Handler) UpdateEntry(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req model.UpdateWaitlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+		return
+	}
+
+	entry, err := h.service.UpdateEntry(c.Request.Context(), orgID, id, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(entry))
+}
+
+func (h *Handler) RemoveEntry(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveEntry(c.Request.Context(), orgID, id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+func (h *Handler) ListOffers(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	offers, err := h.service.ListOffers(c.Request.Context(), orgID, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(offers))
+}
+
+// AcceptOffer books the offered slot. When another patient accepted the
+// slot first it answers 409.
+func (h *Handler) AcceptOffer(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	offerID, ok := parseID(c, "offerId")
+	if !ok {
+		return
+	}
+
+	apt, err := h.service.AcceptOffer(c.Request.Context(), orgID, offerID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, handler.NewSuccessResponse(apt))
+}
+
+func (h *Handler) DeclineOffer(c *gin.Context) {
+	orgID, ok := callerOrganization(c)
+	if !ok {
+		return
+	}
+	offerID, ok := parseID(c, "offerId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeclineOffer(c.Request.Context(), orgID, offerID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, handler.NewSuccessResponse(nil))
+}
+
+func parseID(c *gin.Context, param string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse("invalid "+param))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func callerOrganization(c *gin.Context) (uuid.UUID, bool) {
+	v, _ := c.Get("organization_id")
+	orgID, ok := v.(uuid.UUID)
+	if !ok || orgID == uuid.Nil {
+		c.JSON(http.StatusForbidden, handler.NewErrorResponse("no organization for the current user"))
+		return uuid.Nil, false
+	}
+	return orgID, true
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, waitlist.ErrEntryNotFound), errors.Is(err, waitlist.ErrOfferNotFound),
+		errors.Is(err, waitlist.ErrPatientNotFound), errors.Is(err, waitlist.ErrClinicNotFound):
+		c.JSON(http.StatusNotFound, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, waitlist.ErrEntryClosed), errors.Is(err, waitlist.ErrEntryHasOpenOffer),
+		errors.Is(err, waitlist.ErrSlotTaken), errors.Is(err, waitlist.ErrOfferUnavailable),
+		errors.Is(err, repository.ErrDuplicate):
+		c.JSON(http.StatusConflict, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, waitlist.ErrOfferExpired):
+		c.JSON(http.StatusGone, handler.NewErrorResponse(err.Error()))
+	case errors.Is(err, waitlist.ErrInvalidTimezone), errors.Is(err, waitlist.ErrInvalidWindow),
+		errors.Is(err, waitlist.ErrNoChanges), errors.Is(err, repository.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, handler.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, handler.NewErrorResponse(err.Error()))
+	}
+}
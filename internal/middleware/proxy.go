@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/relationship"
+)
+
+// ProxyMiddleware lets patient users act for their dependents. Staff access
+// to patient routes is governed by RBAC and is left untouched.
+type ProxyMiddleware struct {
+	relationshipSvc *relationship.Service
+}
+
+func NewProxyMiddleware(relationshipSvc *relationship.Service) *ProxyMiddleware {
+	return &ProxyMiddleware{relationshipSvc: relationshipSvc}
+}
+
+// proxyScopes maps the sub-resource of /patients/:id and the request method to
+// the scope a proxy needs. Sub-resources not listed are closed to proxies.
+var proxyScopes = map[string]map[string]model.ProxyScope{
+	"": {
+		http.MethodGet: model.ProxyScopeProfileView,
+		http.MethodPut: model.ProxyScopeProfileManage,
+	},
+	"appointments": {
+		http.MethodGet:    model.ProxyScopeAppointmentsView,
+		http.MethodPost:   model.ProxyScopeAppointmentsBook,
+		http.MethodPut:    model.ProxyScopeAppointmentsBook,
+		http.MethodDelete: model.ProxyScopeAppointmentsBook,
+	},
+	"records": {
+		http.MethodGet: model.ProxyScopeRecordsView,
+	},
+	// A personal representative may ask for the dependent's record to be
+	// amended, but only clinicians decide
+	"amendments": {
+		http.MethodGet:  model.ProxyScopeRecordsView,
+		http.MethodPost: model.ProxyScopeProfileManage,
+	},
+	"documents": {
+		http.MethodGet: model.ProxyScopeRecordsView,
+	},
+	"prescriptions": {
+		http.MethodGet: model.ProxyScopeRecordsView,
+	},
+	"problems": {
+		http.MethodGet: model.ProxyScopeRecordsView,
+	},
+	"allergies": {
+		http.MethodGet: model.ProxyScopeRecordsView,
+	},
+	"identifiers": {
+		http.MethodGet: model.ProxyScopeProfileView,
+	},
+	"care-team": {
+		http.MethodGet: model.ProxyScopeProfileView,
+	},
+	"timeline": {
+		http.MethodGet: model.ProxyScopeRecordsView,
+	},
+	"insurance": {
+		http.MethodGet:  model.ProxyScopeInsuranceManage,
+		http.MethodPut:  model.ProxyScopeInsuranceManage,
+		http.MethodPost: model.ProxyScopeInsuranceManage,
+	},
+	"consents": {
+		http.MethodGet:    model.ProxyScopeConsentsManage,
+		http.MethodPost:   model.ProxyScopeConsentsManage,
+		http.MethodDelete: model.ProxyScopeConsentsManage,
+	},
+}
+
+// Authorize checks patient users against /patients/:id routes. A patient may
+// always act for themselves; anyone else needs an active relationship
+// granting the scope for the route. Proxy requests carry the dependent and
+// the relationship in the request context so the audit log records both.
+func (m *ProxyMiddleware) Authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user_type") != model.UserTypePatient {
+			c.Next()
+			return
+		}
+
+		resource, ok := patientSubResource(c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		patientID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		uid, _ := userID.(uuid.UUID)
+		ctx := c.Request.Context()
+
+		if m.relationshipSvc.IsSelf(ctx, uid, patientID) {
+			c.Set("acting_for_self", true)
+			c.Next()
+			return
+		}
+
+		scope, ok := proxyScopes[resource][c.Request.Method]
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "not available to proxies",
+			})
+			return
+		}
+
+		rel, err := m.relationshipSvc.Authorize(ctx, uid, patientID, scope)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, relationship.ErrProxyNotAuthorized) {
+				status = http.StatusForbidden
+			}
+			c.AbortWithStatusJSON(status, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.Set("acting_for", patientID)
+		c.Set("proxy_relationship_id", rel.ID)
+
+		ctx = context.WithValue(ctx, "user_id", uid)
+		ctx = context.WithValue(ctx, "acting_for", patientID)
+		ctx = context.WithValue(ctx, "proxy_relationship_id", rel.ID)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// patientSubResource returns the first path segment after /patients/:id, or
+// ok=false when the route is not scoped to a single patient.
+func patientSubResource(fullPath string) (string, bool) {
+	const prefix = "/patients/:id"
+	i := strings.LastIndex(fullPath, prefix)
+	if i < 0 {
+		return "", false
+	}
+
+	rest := fullPath[i+len(prefix):]
+	if rest != "" && rest[0] != '/' {
+		return "", false
+	}
+	rest = strings.TrimPrefix(rest, "/")
+	if j := strings.Index(rest, "/"); j >= 0 {
+		rest = rest[:j]
+	}
+	return rest, true
+}
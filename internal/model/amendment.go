@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MedicalRecordVersion is an immutable snapshot of a medical record. Every
+// create and update appends one; the record row only mirrors the latest.
+// Diagnosis and Treatment are stored encrypted, like on the record itself.
+type MedicalRecordVersion struct {
+	ID              uuid.UUID       `json:"id" db:"id"`
+	RecordID        uuid.UUID       `json:"record_id" db:"record_id"`
+	Version         int             `json:"version" db:"version"`
+	Type            string          `json:"type" db:"type"`
+	Description     string          `json:"description" db:"description"`
+	Diagnosis       json.RawMessage `json:"diagnosis" db:"diagnosis"`
+	Treatment       json.RawMessage `json:"treatment" db:"treatment"`
+	MedicationsJSON json.RawMessage `json:"medications" db:"medications"`
+	AttachmentsJSON json.RawMessage `json:"attachments" db:"attachments"`
+	AccessLevel     string          `json:"access_level" db:"access_level"`
+	AuthorID        uuid.UUID       `json:"author_id" db:"author_id"`
+	Reason          string          `json:"reason" db:"reason"`
+	AmendmentID     *uuid.UUID      `json:"amendment_id,omitempty" db:"amendment_id"`
+	EncryptionKeyID *uuid.UUID      `json:"-" db:"encryption_key_id"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+}
+
+// RecordRevision says who changed a record and why. Updates without one are
+// rejected so history is never replaced silently.
+type RecordRevision struct {
+	AuthorID    uuid.UUID
+	Reason      string
+	AmendmentID *uuid.UUID
+}
+
+type AmendmentStatus string
+
+const (
+	AmendmentStatusPending  AmendmentStatus = "pending"
+	AmendmentStatusAccepted AmendmentStatus = "accepted"
+	AmendmentStatusDenied   AmendmentStatus = "denied"
+)
+
+// AmendmentResponseDays is how long a covered entity has to act on an
+// amendment request under HIPAA (45 CFR 164.526)
+const AmendmentResponseDays = 60
+
+// AmendmentRequest is a patient's request to correct a medical record. An
+// accepted request produces a new record version that links back to it.
+type AmendmentRequest struct {
+	ID               uuid.UUID       `json:"id" db:"id"`
+	RecordID         uuid.UUID       `json:"record_id" db:"record_id"`
+	PatientID        uuid.UUID       `json:"patient_id" db:"patient_id"`
+	RequestedBy      uuid.UUID       `json:"requested_by" db:"requested_by"`
+	BaseVersion      int             `json:"base_version" db:"base_version"`
+	Justification    string          `json:"justification" db:"justification"`
+	ProposedChanges  json.RawMessage `json:"proposed_changes" db:"proposed_changes"`
+	Status           AmendmentStatus `json:"status" db:"status"`
+	ReviewedBy       *uuid.UUID      `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt       *time.Time      `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	DecisionReason   *string         `json:"decision_reason,omitempty" db:"decision_reason"`
+	ResultingVersion *int            `json:"resulting_version,omitempty" db:"resulting_version"`
+	DueAt            time.Time       `json:"due_at" db:"due_at"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// RecordChanges are the clinical fields an amendment may correct. Nil fields
+// are left as they are.
+type RecordChanges struct {
+	Description *string         `json:"description,omitempty"`
+	Diagnosis   json.RawMessage `json:"diagnosis,omitempty"`
+	Treatment   json.RawMessage `json:"treatment,omitempty"`
+}
+
+func (c *RecordChanges) Empty() bool {
+	return c.Description == nil && c.Diagnosis == nil && c.Treatment == nil
+}
+
+type CreateAmendmentRequest struct {
+	RecordID        uuid.UUID     `json:"record_id" binding:"required"`
+	Justification   string        `json:"justification" binding:"required"`
+	ProposedChanges RecordChanges `json:"proposed_changes"`
+}
+
+// DecideAmendmentRequest accepts or denies an amendment. Changes overrides
+// the patient's proposal when the clinician accepts it in a corrected form.
+type DecideAmendmentRequest struct {
+	Accept  bool           `json:"accept"`
+	Reason  string         `json:"reason" binding:"required"`
+	Changes *RecordChanges `json:"changes,omitempty"`
+}
+
+type UpdateMedicalRecordRequest struct {
+	Reason  string        `json:"reason" binding:"required"`
+	Version int           `json:"version" binding:"required"`
+	Changes RecordChanges `json:"changes"`
+}
@@ -27,6 +27,10 @@ type Appointment struct {
 	Notes        string            `db:"notes" json:"notes,omitempty"`
 	CancelReason *string           `json:"cancel_reason,omitempty" db:"cancel_reason"`
 	CompletedAt  *time.Time        `db:"completed_at" json:"completed_at,omitempty"`
+	// SeriesID is set on occurrences of a recurring series. OccurrenceStart
+	// is when the series' rule puts the occurrence, even if it was moved.
+	SeriesID        *uuid.UUID `db:"series_id" json:"series_id,omitempty"`
+	OccurrenceStart *time.Time `db:"occurrence_start" json:"occurrence_start,omitempty"`
 }
 
 type CreateAppointmentRequest struct {
@@ -39,6 +43,9 @@ type CreateAppointmentRequest struct {
 	EndTime         time.Time `json:"end_time" validate:"required,gtfield=StartTime"`
 	AppointmentType string    `json:"appointment_type" validate:"required,oneof=regular followup emergency"`
 	Notes           string    `json:"notes" validate:"max=1000"`
+	// HoldID books the slot reserved by a hold taken with the same
+	// clinician and times
+	HoldID *uuid.UUID `json:"hold_id,omitempty"`
 }
 
 type UpdateAppointmentRequest struct {
@@ -49,11 +56,64 @@ type UpdateAppointmentRequest struct {
 	CancelReason *string            `json:"cancel_reason"`
 }
 
+// AppointmentHold reserves a slot while a booking form is completed. Until
+// it expires or is booked, nobody else can book or hold an overlapping slot
+// with the clinician.
+type AppointmentHold struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	ClinicID    uuid.UUID  `db:"clinic_id" json:"clinic_id"`
+	ClinicianID uuid.UUID  `db:"clinician_id" json:"clinician_id"`
+	ServiceID   uuid.UUID  `db:"service_id" json:"service_id"`
+	PatientID   *uuid.UUID `db:"patient_id" json:"patient_id,omitempty"`
+	StartTime   time.Time  `db:"start_time" json:"start_time"`
+	EndTime     time.Time  `db:"end_time" json:"end_time"`
+	ExpiresAt   time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedBy   uuid.UUID  `db:"created_by" json:"created_by"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// CreateAppointmentHoldRequest holds a slot; PatientID limits booking it to
+// that patient
+type CreateAppointmentHoldRequest struct {
+	ClinicID    uuid.UUID  `json:"clinic_id" binding:"required"`
+	ClinicianID uuid.UUID  `json:"clinician_id" binding:"required"`
+	ServiceID   uuid.UUID  `json:"service_id" binding:"required"`
+	PatientID   *uuid.UUID `json:"patient_id"`
+	StartTime   time.Time  `json:"start_time" binding:"required"`
+	EndTime     time.Time  `json:"end_time" binding:"required,gtfield=StartTime"`
+}
+
 type TimeSlot struct {
 	Start time.Time `json:"start"`
 	End   time.Time `json:"end"`
 }
 
+// BusyPeriod is time a clinician is taken by an appointment, including the
+// buffers of the booked service
+type BusyPeriod struct {
+	ClinicianID uuid.UUID `db:"clinician_id" json:"clinician_id"`
+	Start       time.Time `db:"start_time" json:"start"`
+	End         time.Time `db:"end_time" json:"end"`
+}
+
+// SlotSearch asks for the bookable slots of a service with any of the
+// clinicians between From and To
+type SlotSearch struct {
+	ServiceID    uuid.UUID
+	ClinicianIDs []uuid.UUID
+	ClinicID     *uuid.UUID
+	From         time.Time
+	To           time.Time
+}
+
+// BookableSlot is a time the service can be booked with the clinician
+type BookableSlot struct {
+	ClinicianID uuid.UUID `json:"clinician_id"`
+	ClinicID    uuid.UUID `json:"clinic_id"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+}
+
 type AppointmentFilters struct {
 	ClinicID    uuid.UUID
 	ClinicianID uuid.UUID
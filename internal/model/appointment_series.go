@@ -0,0 +1,102 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AppointmentSeriesStatus string
+
+const (
+	AppointmentSeriesStatusActive    AppointmentSeriesStatus = "active"
+	AppointmentSeriesStatusCancelled AppointmentSeriesStatus = "cancelled"
+)
+
+// SeriesScope is which occurrences of a series an edit or cancellation
+// applies to
+type SeriesScope string
+
+const (
+	SeriesScopeThis      SeriesScope = "this"
+	SeriesScopeFollowing SeriesScope = "this_and_following"
+	SeriesScopeAll       SeriesScope = "all"
+)
+
+// AppointmentSeries is a recurring booking. Its occurrences are ordinary
+// appointments carrying the series ID, so each keeps its own status.
+// StartTime is the first occurrence and the rule is expanded from it in
+// Timezone. ExDates are rule occurrences that were left out or cancelled on
+// their own. A series split by a "this and following" edit points at the
+// series it came from with ParentID.
+type AppointmentSeries struct {
+	ID          uuid.UUID               `json:"id" db:"id"`
+	ClinicID    uuid.UUID               `json:"clinic_id" db:"clinic_id"`
+	ClinicianID uuid.UUID               `json:"clinician_id" db:"clinician_id"`
+	PatientID   uuid.UUID               `json:"patient_id" db:"patient_id"`
+	ServiceID   uuid.UUID               `json:"service_id" db:"service_id"`
+	RRule       string                  `json:"rrule" db:"rrule"`
+	StartTime   time.Time               `json:"start_time" db:"start_time"`
+	Duration    int                     `json:"duration" db:"duration"` // minutes
+	Timezone    string                  `json:"timezone" db:"timezone"`
+	ExDatesJSON json.RawMessage         `json:"-" db:"exdates"`
+	ExDates     []time.Time             `json:"exdates" db:"-"`
+	Notes       string                  `json:"notes,omitempty" db:"notes"`
+	Status      AppointmentSeriesStatus `json:"status" db:"status"`
+	ParentID    *uuid.UUID              `json:"parent_id,omitempty" db:"parent_id"`
+	CreatedBy   uuid.UUID               `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time               `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at" db:"updated_at"`
+}
+
+// CreateAppointmentSeriesRequest books a series whose first occurrence is
+// StartTime to EndTime. RRule is an iCalendar RRULE with COUNT or UNTIL.
+// With SkipConflicts, occurrences that conflict with other bookings are
+// left out of the series instead of failing the request.
+type CreateAppointmentSeriesRequest struct {
+	ClinicID      uuid.UUID   `json:"clinic_id" binding:"required"`
+	ClinicianID   uuid.UUID   `json:"clinician_id" binding:"required"`
+	PatientID     uuid.UUID   `json:"patient_id" binding:"required"`
+	ServiceID     uuid.UUID   `json:"service_id" binding:"required"`
+	StartTime     time.Time   `json:"start_time" binding:"required"`
+	EndTime       time.Time   `json:"end_time" binding:"required,gtfield=StartTime"`
+	Timezone      string      `json:"timezone" binding:"required"`
+	RRule         string      `json:"rrule" binding:"required"`
+	ExDates       []time.Time `json:"exdates"`
+	Notes         string      `json:"notes" binding:"max=1000"`
+	SkipConflicts bool        `json:"skip_conflicts"`
+}
+
+// SeriesOccurrence is one expanded occurrence and whether it conflicts
+// with another booking of the clinician
+type SeriesOccurrence struct {
+	AppointmentID *uuid.UUID `json:"appointment_id,omitempty"`
+	Start         time.Time  `json:"start"`
+	End           time.Time  `json:"end"`
+	Conflict      bool       `json:"conflict"`
+}
+
+// AppointmentSeriesResult is a series with its occurrences and, when
+// conflicts were skipped, the occurrences left out
+type AppointmentSeriesResult struct {
+	Series       *AppointmentSeries `json:"series"`
+	Appointments []*Appointment     `json:"appointments"`
+	Skipped      []SeriesOccurrence `json:"skipped,omitempty"`
+}
+
+// UpdateSeriesOccurrenceRequest edits the occurrence and, depending on
+// Scope, the ones after it or the whole series. For wider scopes a new
+// StartTime moves every affected occurrence to the same time of day,
+// shifted by as many days as the chosen occurrence moved.
+type UpdateSeriesOccurrenceRequest struct {
+	Scope     SeriesScope `json:"scope" binding:"required,oneof=this this_and_following all"`
+	StartTime *time.Time  `json:"start_time"`
+	EndTime   *time.Time  `json:"end_time"`
+	Notes     *string     `json:"notes" binding:"omitempty,max=1000"`
+}
+
+type CancelSeriesOccurrenceRequest struct {
+	Scope  SeriesScope `json:"scope" binding:"required,oneof=this this_and_following all"`
+	Reason string      `json:"reason" binding:"required"`
+}
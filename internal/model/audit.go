@@ -8,18 +8,20 @@ import (
 )
 
 type AuditLog struct {
-	ID             uuid.UUID       `json:"id" db:"id"`
-	UserID         uuid.UUID       `json:"user_id" db:"user_id"`
-	OrganizationID uuid.UUID       `json:"organization_id" db:"organization_id"`
-	Action         string          `json:"action" db:"action"`
-	EntityType     string          `json:"entity_type" db:"entity_type"`
-	EntityID       uuid.UUID       `json:"entity_id" db:"entity_id"`
-	Changes        json.RawMessage `json:"changes" db:"changes"`
-	Metadata       json.RawMessage `json:"metadata" db:"metadata"`
-	IPAddress      string          `json:"ip_address" db:"ip_address"`
-	UserAgent      string          `json:"user_agent" db:"user_agent"`
-	AccessReason   string          `json:"access_reason" db:"access_reason"`
-	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	ID                  uuid.UUID       `json:"id" db:"id"`
+	UserID              uuid.UUID       `json:"user_id" db:"user_id"`
+	OrganizationID      uuid.UUID       `json:"organization_id" db:"organization_id"`
+	Action              string          `json:"action" db:"action"`
+	EntityType          string          `json:"entity_type" db:"entity_type"`
+	EntityID            uuid.UUID       `json:"entity_id" db:"entity_id"`
+	Changes             json.RawMessage `json:"changes" db:"changes"`
+	Metadata            json.RawMessage `json:"metadata" db:"metadata"`
+	IPAddress           string          `json:"ip_address" db:"ip_address"`
+	UserAgent           string          `json:"user_agent" db:"user_agent"`
+	AccessReason        string          `json:"access_reason" db:"access_reason"`
+	OnBehalfOf          *uuid.UUID      `json:"on_behalf_of,omitempty" db:"on_behalf_of"`
+	ProxyRelationshipID *uuid.UUID      `json:"proxy_relationship_id,omitempty" db:"proxy_relationship_id"`
+	CreatedAt           time.Time       `json:"created_at" db:"created_at"`
 }
 
 const (
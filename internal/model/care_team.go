@@ -0,0 +1,59 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CareTeamMember is a user treating a patient. Membership is what opens the
+// patient's private and HIPAA medical records to them.
+type CareTeamMember struct {
+	PatientID uuid.UUID `json:"patient_id" db:"patient_id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Role      string    `json:"role" db:"role"`
+	AddedBy   uuid.UUID `json:"added_by" db:"added_by"`
+	AddedAt   time.Time `json:"added_at" db:"added_at"`
+}
+
+type AddCareTeamMemberRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
+	Role   string    `json:"role" binding:"required"`
+}
+
+// RecordReader is who is reading a patient's medical records
+type RecordReader struct {
+	UserID   uuid.UUID
+	UserType string
+	// Reason is the stated purpose of access, required for HIPAA records
+	Reason string
+	// Self is set when a patient reads their own records, all of which are
+	// open to them
+	Self bool
+}
+
+// Access returns the query restriction for the reader. Patients see all of
+// their own records and their proxies see public ones; staff also see
+// private records of patients whose care team they are on, and HIPAA
+// records once they give a reason.
+func (r *RecordReader) Access() *RecordAccess {
+	if r == nil || (r.Self && r.UserType == UserTypePatient) {
+		return nil
+	}
+	if r.UserType == UserTypePatient {
+		return &RecordAccess{PublicOnly: true}
+	}
+	return &RecordAccess{
+		UserID:       r.UserID,
+		IncludeHIPAA: strings.TrimSpace(r.Reason) != "",
+	}
+}
+
+// RecordAccess limits a medical record query to what one reader may see
+type RecordAccess struct {
+	PublicOnly bool
+	// UserID is matched against the patient's care team
+	UserID       uuid.UUID
+	IncludeHIPAA bool
+}
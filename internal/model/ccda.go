@@ -0,0 +1,118 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CCDAImportStatus string
+
+const (
+	// Staged imports wait for a clinician to decide on each entry
+	CCDAImportStatusStaged     CCDAImportStatus = "staged"
+	CCDAImportStatusReconciled CCDAImportStatus = "reconciled"
+	CCDAImportStatusDiscarded  CCDAImportStatus = "discarded"
+)
+
+// StagedEntryKind is the CCD section an imported entry came from
+type StagedEntryKind string
+
+const (
+	StagedEntryProblem    StagedEntryKind = "problem"
+	StagedEntryAllergy    StagedEntryKind = "allergy"
+	StagedEntryMedication StagedEntryKind = "medication"
+	StagedEntryResult     StagedEntryKind = "result"
+	StagedEntryEncounter  StagedEntryKind = "encounter"
+)
+
+type StagedEntryDecision string
+
+const (
+	StagedEntryPending  StagedEntryDecision = "pending"
+	StagedEntryAccepted StagedEntryDecision = "accepted"
+	StagedEntryRejected StagedEntryDecision = "rejected"
+)
+
+// CCDAImport is an inbound C-CDA document staged for reconciliation. The
+// document and its entries are encrypted at rest in Content.
+type CCDAImport struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	PatientID      uuid.UUID        `json:"patient_id" db:"patient_id"`
+	OrganizationID uuid.UUID        `json:"organization_id" db:"organization_id"`
+	DocumentID     string           `json:"document_id" db:"document_id"`
+	Title          string           `json:"title" db:"title"`
+	Source         string           `json:"source" db:"source"`
+	DocumentDate   *time.Time       `json:"document_date,omitempty" db:"document_date"`
+	Status         CCDAImportStatus `json:"status" db:"status"`
+	// DemographicsMatch is false when the document's patient name or birth
+	// date differ from the patient it was imported for
+	DemographicsMatch bool       `json:"demographics_match" db:"demographics_match"`
+	Content           []byte     `json:"-" db:"content"`
+	EncryptionKeyID   *uuid.UUID `json:"-" db:"encryption_key_id"`
+	ImportedBy        uuid.UUID  `json:"imported_by" db:"imported_by"`
+	ReconciledBy      *uuid.UUID `json:"reconciled_by,omitempty" db:"reconciled_by"`
+	ReconciledAt      *time.Time `json:"reconciled_at,omitempty" db:"reconciled_at"`
+	DiscardReason     *string    `json:"discard_reason,omitempty" db:"discard_reason"`
+	Version           int        `json:"version" db:"version"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	// Demographics and Entries are decrypted from Content when one import
+	// is read
+	Demographics *StagedDemographics `json:"demographics,omitempty" db:"-"`
+	Entries      []*StagedEntry      `json:"entries,omitempty" db:"-"`
+}
+
+// StagedDemographics is the patient as the sending organization described
+// them
+type StagedDemographics struct {
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	DateOfBirth time.Time `json:"date_of_birth"`
+	Gender      string    `json:"gender"`
+	Address     string    `json:"address,omitempty"`
+	Phone       string    `json:"phone,omitempty"`
+	Email       string    `json:"email,omitempty"`
+}
+
+// StagedEntry is one problem, allergy, medication, result or encounter taken
+// from an imported document
+type StagedEntry struct {
+	Index int             `json:"index"`
+	Kind  StagedEntryKind `json:"kind"`
+	Code  CodedEntry      `json:"code"`
+	// Coded is true when Code is usable as is: problem and allergy codes
+	// validated against the loaded terminology, RxNorm drugs and LOINC
+	// panels. Accepted problems and allergies that are not coded are
+	// committed as free text.
+	Coded     bool       `json:"coded"`
+	CodeIssue string     `json:"code_issue,omitempty"`
+	Active    bool       `json:"active"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	// Detail is the medication sig, the allergy reaction and severity, or
+	// the encounter description
+	Detail string     `json:"detail,omitempty"`
+	Result *LabResult `json:"result,omitempty"`
+	// ExistingRecordID points at a record of the patient's that already has
+	// this code, a likely duplicate
+	ExistingRecordID *uuid.UUID          `json:"existing_record_id,omitempty"`
+	Decision         StagedEntryDecision `json:"decision"`
+	RecordID         *uuid.UUID          `json:"record_id,omitempty"`
+}
+
+// CCDAEntryDecision accepts or rejects one staged entry by its index
+type CCDAEntryDecision struct {
+	Index  int  `json:"index" binding:"min=0"`
+	Accept bool `json:"accept"`
+}
+
+type ReconcileCCDAImportRequest struct {
+	// Decisions must cover every entry of the import
+	Decisions []CCDAEntryDecision `json:"decisions" binding:"required,dive"`
+	Version   int                 `json:"version" binding:"required"`
+}
+
+type DiscardCCDAImportRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClinicalStatus is the status of a problem or allergy list entry
+type ClinicalStatus string
+
+const (
+	ClinicalStatusActive   ClinicalStatus = "active"
+	ClinicalStatusResolved ClinicalStatus = "resolved"
+	// Refuted entries were recorded but found not to apply; they stay on the
+	// list so they are not added again from later records
+	ClinicalStatusRefuted ClinicalStatus = "refuted"
+)
+
+const (
+	SeverityMild     = "mild"
+	SeverityModerate = "moderate"
+	SeveritySevere   = "severe"
+)
+
+const (
+	AllergyCategoryFood        = "food"
+	AllergyCategoryMedication  = "medication"
+	AllergyCategoryEnvironment = "environment"
+	AllergyCategoryBiologic    = "biologic"
+)
+
+// Problem is an entry on a patient's problem list. Entries added from a
+// record keep the record in SourceRecordID.
+type Problem struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	PatientID      uuid.UUID      `json:"patient_id" db:"patient_id"`
+	OrganizationID uuid.UUID      `json:"organization_id" db:"organization_id"`
+	CodeSystem     string         `json:"code_system" db:"code_system"`
+	Code           string         `json:"code" db:"code"`
+	Display        string         `json:"display" db:"display"`
+	Status         ClinicalStatus `json:"status" db:"status"`
+	Severity       *string        `json:"severity,omitempty" db:"severity"`
+	OnsetDate      *time.Time     `json:"onset_date,omitempty" db:"onset_date"`
+	ResolvedDate   *time.Time     `json:"resolved_date,omitempty" db:"resolved_date"`
+	Note           *string        `json:"note,omitempty" db:"note"`
+	SourceRecordID *uuid.UUID     `json:"source_record_id,omitempty" db:"source_record_id"`
+	RecordedBy     uuid.UUID      `json:"recorded_by" db:"recorded_by"`
+	Version        int            `json:"version" db:"version"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Allergy is an entry on a patient's allergy list. The substance is coded
+// in SNOMED CT, or in RxNorm for drug allergies.
+type Allergy struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	PatientID      uuid.UUID         `json:"patient_id" db:"patient_id"`
+	OrganizationID uuid.UUID         `json:"organization_id" db:"organization_id"`
+	CodeSystem     string            `json:"code_system" db:"code_system"`
+	Code           string            `json:"code" db:"code"`
+	Display        string            `json:"display" db:"display"`
+	Category       *string           `json:"category,omitempty" db:"category"`
+	Status         ClinicalStatus    `json:"status" db:"status"`
+	Severity       *string           `json:"severity,omitempty" db:"severity"`
+	ReactionsJSON  json.RawMessage   `json:"-" db:"reactions"`
+	Reactions      []AllergyReaction `json:"reactions" db:"-"`
+	OnsetDate      *time.Time        `json:"onset_date,omitempty" db:"onset_date"`
+	ResolvedDate   *time.Time        `json:"resolved_date,omitempty" db:"resolved_date"`
+	Note           *string           `json:"note,omitempty" db:"note"`
+	SourceRecordID *uuid.UUID        `json:"source_record_id,omitempty" db:"source_record_id"`
+	RecordedBy     uuid.UUID         `json:"recorded_by" db:"recorded_by"`
+	Version        int               `json:"version" db:"version"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// AllergyReaction is a reaction the patient had. The manifestation may be
+// free text in Display without a code.
+type AllergyReaction struct {
+	Manifestation CodedEntry `json:"manifestation"`
+	Severity      string     `json:"severity,omitempty"`
+}
+
+type CreateProblemRequest struct {
+	Code      CodedEntry     `json:"code" binding:"required"`
+	Status    ClinicalStatus `json:"status" binding:"omitempty,oneof=active resolved refuted"`
+	Severity  *string        `json:"severity" binding:"omitempty,oneof=mild moderate severe"`
+	OnsetDate *time.Time     `json:"onset_date"`
+	Note      *string        `json:"note"`
+}
+
+// UpdateProblemRequest changes the fields that are set. Version must be the
+// version the change was made against.
+type UpdateProblemRequest struct {
+	Status       *ClinicalStatus `json:"status" binding:"omitempty,oneof=active resolved refuted"`
+	Severity     *string         `json:"severity" binding:"omitempty,oneof=mild moderate severe"`
+	OnsetDate    *time.Time      `json:"onset_date"`
+	ResolvedDate *time.Time      `json:"resolved_date"`
+	Note         *string         `json:"note"`
+	Version      int             `json:"version" binding:"required"`
+}
+
+type CreateAllergyRequest struct {
+	Code      CodedEntry        `json:"code" binding:"required"`
+	Category  *string           `json:"category" binding:"omitempty,oneof=food medication environment biologic"`
+	Status    ClinicalStatus    `json:"status" binding:"omitempty,oneof=active resolved refuted"`
+	Severity  *string           `json:"severity" binding:"omitempty,oneof=mild moderate severe"`
+	Reactions []AllergyReaction `json:"reactions" binding:"dive"`
+	OnsetDate *time.Time        `json:"onset_date"`
+	Note      *string           `json:"note"`
+}
+
+// UpdateAllergyRequest changes the fields that are set; Reactions replaces
+// the recorded reactions when present
+type UpdateAllergyRequest struct {
+	Category     *string            `json:"category" binding:"omitempty,oneof=food medication environment biologic"`
+	Status       *ClinicalStatus    `json:"status" binding:"omitempty,oneof=active resolved refuted"`
+	Severity     *string            `json:"severity" binding:"omitempty,oneof=mild moderate severe"`
+	Reactions    *[]AllergyReaction `json:"reactions"`
+	OnsetDate    *time.Time         `json:"onset_date"`
+	ResolvedDate *time.Time         `json:"resolved_date"`
+	Note         *string            `json:"note"`
+	Version      int                `json:"version" binding:"required"`
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NoteKind string
+
+const (
+	NoteKindSOAP      NoteKind = "soap"
+	NoteKindProgress  NoteKind = "progress"
+	NoteKindDischarge NoteKind = "discharge"
+)
+
+type SectionType string
+
+const (
+	SectionTypeText   SectionType = "text"
+	SectionTypeList   SectionType = "list"
+	SectionTypeNumber SectionType = "number"
+	SectionTypeDate   SectionType = "date"
+)
+
+// NoteSection is one typed section of a note template
+type NoteSection struct {
+	Key      string      `json:"key" binding:"required"`
+	Title    string      `json:"title" binding:"required"`
+	Type     SectionType `json:"type" binding:"required"`
+	Required bool        `json:"required"`
+}
+
+// DefaultNoteSections are the sections a template of each kind gets when it
+// is created without its own
+var DefaultNoteSections = map[NoteKind][]NoteSection{
+	NoteKindSOAP: {
+		{Key: "subjective", Title: "Subjective", Type: SectionTypeText, Required: true},
+		{Key: "objective", Title: "Objective", Type: SectionTypeText, Required: true},
+		{Key: "assessment", Title: "Assessment", Type: SectionTypeText, Required: true},
+		{Key: "plan", Title: "Plan", Type: SectionTypeText, Required: true},
+	},
+	NoteKindProgress: {
+		{Key: "interval_history", Title: "Interval history", Type: SectionTypeText},
+		{Key: "examination", Title: "Examination", Type: SectionTypeText},
+		{Key: "assessment", Title: "Assessment", Type: SectionTypeText, Required: true},
+		{Key: "plan", Title: "Plan", Type: SectionTypeText, Required: true},
+	},
+	NoteKindDischarge: {
+		{Key: "admission_date", Title: "Admission date", Type: SectionTypeDate, Required: true},
+		{Key: "admission_reason", Title: "Reason for admission", Type: SectionTypeText, Required: true},
+		{Key: "hospital_course", Title: "Hospital course", Type: SectionTypeText, Required: true},
+		{Key: "discharge_diagnoses", Title: "Discharge diagnoses", Type: SectionTypeList, Required: true},
+		{Key: "discharge_medications", Title: "Discharge medications", Type: SectionTypeList},
+		{Key: "follow_up", Title: "Follow-up", Type: SectionTypeText, Required: true},
+	},
+}
+
+// NoteTemplate is an organization's layout for one kind of clinical note
+type NoteTemplate struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	OrganizationID uuid.UUID       `json:"organization_id" db:"organization_id"`
+	Name           string          `json:"name" db:"name"`
+	Kind           NoteKind        `json:"kind" db:"kind"`
+	SectionsJSON   json.RawMessage `json:"-" db:"sections"`
+	Sections       []NoteSection   `json:"sections" db:"-"`
+	Active         bool            `json:"active" db:"active"`
+	CreatedBy      uuid.UUID       `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+type CreateNoteTemplateRequest struct {
+	OrganizationID uuid.UUID `json:"organization_id" binding:"required"`
+	Name           string    `json:"name" binding:"required"`
+	Kind           NoteKind  `json:"kind" binding:"required,oneof=soap progress discharge"`
+	// Sections default to the kind's standard sections
+	Sections []NoteSection `json:"sections" binding:"omitempty,dive"`
+}
+
+type UpdateNoteTemplateRequest struct {
+	Name     *string       `json:"name"`
+	Sections []NoteSection `json:"sections" binding:"omitempty,dive"`
+	Active   *bool         `json:"active"`
+}
+
+type NoteStatus string
+
+const (
+	// Drafts can be edited by their author
+	NoteStatusDraft NoteStatus = "draft"
+	// Signed notes are fixed and hashed; a trainee's note waits here for
+	// its co-signature
+	NoteStatusSigned NoteStatus = "signed"
+	// Locked notes only take addenda
+	NoteStatusLocked NoteStatus = "locked"
+)
+
+// ClinicalNote is a note written against a template. Its sections are kept
+// encrypted in Content; ContentHash is taken when the note is signed and is
+// also written to the audit log.
+type ClinicalNote struct {
+	ID              uuid.UUID                  `json:"id" db:"id"`
+	PatientID       uuid.UUID                  `json:"patient_id" db:"patient_id"`
+	OrganizationID  uuid.UUID                  `json:"organization_id" db:"organization_id"`
+	TemplateID      uuid.UUID                  `json:"template_id" db:"template_id"`
+	AuthorID        uuid.UUID                  `json:"author_id" db:"author_id"`
+	Status          NoteStatus                 `json:"status" db:"status"`
+	Sections        map[string]json.RawMessage `json:"sections" db:"-"`
+	Content         []byte                     `json:"-" db:"content"`
+	EncryptionKeyID *uuid.UUID                 `json:"-" db:"encryption_key_id"`
+	ContentHash     *string                    `json:"content_hash,omitempty" db:"content_hash"`
+	SignedAt        *time.Time                 `json:"signed_at,omitempty" db:"signed_at"`
+	CosignRequired  bool                       `json:"cosign_required" db:"cosign_required"`
+	CosignerID      *uuid.UUID                 `json:"cosigner_id,omitempty" db:"cosigner_id"`
+	CosignedAt      *time.Time                 `json:"cosigned_at,omitempty" db:"cosigned_at"`
+	LockedBy        *uuid.UUID                 `json:"locked_by,omitempty" db:"locked_by"`
+	LockedAt        *time.Time                 `json:"locked_at,omitempty" db:"locked_at"`
+	Version         int                        `json:"version" db:"version"`
+	CreatedAt       time.Time                  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time                  `json:"updated_at" db:"updated_at"`
+	Addenda         []*NoteAddendum            `json:"addenda,omitempty" db:"-"`
+}
+
+// NoteAddendum is text appended to a locked note. Addenda are never changed.
+type NoteAddendum struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	NoteID          uuid.UUID  `json:"note_id" db:"note_id"`
+	AuthorID        uuid.UUID  `json:"author_id" db:"author_id"`
+	Text            string     `json:"text" db:"-"`
+	Content         []byte     `json:"-" db:"content"`
+	EncryptionKeyID *uuid.UUID `json:"-" db:"encryption_key_id"`
+	ContentHash     string     `json:"content_hash" db:"content_hash"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateNoteRequest struct {
+	TemplateID uuid.UUID                  `json:"template_id" binding:"required"`
+	Sections   map[string]json.RawMessage `json:"sections"`
+}
+
+// UpdateNoteRequest replaces a draft's sections. Version is the version the
+// edit was made against.
+type UpdateNoteRequest struct {
+	Sections map[string]json.RawMessage `json:"sections" binding:"required"`
+	Version  int                        `json:"version" binding:"required"`
+}
+
+type AddAddendumRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// NoteVerification compares a note's current content with the hash audited
+// when it was signed
+type NoteVerification struct {
+	NoteID      uuid.UUID               `json:"note_id"`
+	ContentHash string                  `json:"content_hash"`
+	AuditedHash string                  `json:"audited_hash"`
+	Valid       bool                    `json:"valid"`
+	Addenda     []*AddendumVerification `json:"addenda,omitempty"`
+}
+
+type AddendumVerification struct {
+	AddendumID  uuid.UUID `json:"addendum_id"`
+	ContentHash string    `json:"content_hash"`
+	AuditedHash string    `json:"audited_hash"`
+	Valid       bool      `json:"valid"`
+}
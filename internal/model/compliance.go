@@ -0,0 +1,120 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ComplianceRequestType string
+
+const (
+	ComplianceRequestTypeAccess  ComplianceRequestType = "access"
+	ComplianceRequestTypeErasure ComplianceRequestType = "erasure"
+)
+
+type ComplianceRequestStatus string
+
+const (
+	ComplianceRequestStatusPending    ComplianceRequestStatus = "pending"
+	ComplianceRequestStatusProcessing ComplianceRequestStatus = "processing"
+	ComplianceRequestStatusCompleted  ComplianceRequestStatus = "completed"
+	ComplianceRequestStatusFailed     ComplianceRequestStatus = "failed"
+)
+
+// ComplianceRequest tracks a data subject request (GDPR/CCPA/HIPAA) from intake
+// to the artifact handed back to the requester.
+type ComplianceRequest struct {
+	ID               uuid.UUID               `json:"id" db:"id"`
+	OrganizationID   uuid.UUID               `json:"organization_id" db:"organization_id"`
+	PatientID        uuid.UUID               `json:"patient_id" db:"patient_id"`
+	Type             ComplianceRequestType   `json:"type" db:"type"`
+	Status           ComplianceRequestStatus `json:"status" db:"status"`
+	RequestedBy      uuid.UUID               `json:"requested_by" db:"requested_by"`
+	RegionCode       string                  `json:"region_code" db:"region_code"`
+	ArtifactPath     *string                 `json:"-" db:"artifact_path"`
+	ArtifactChecksum *string                 `json:"artifact_checksum,omitempty" db:"artifact_checksum"`
+	ArtifactExpires  *time.Time              `json:"artifact_expires_at,omitempty" db:"artifact_expires_at"`
+	Attempts         int                     `json:"attempts" db:"attempts"`
+	Error            *string                 `json:"error,omitempty" db:"error"`
+	StartedAt        *time.Time              `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt      *time.Time              `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt        time.Time               `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at" db:"updated_at"`
+}
+
+// PatientDataExport is the machine-readable body of a subject access export
+type PatientDataExport struct {
+	RequestID      uuid.UUID        `json:"request_id"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+	Patient        *Patient         `json:"patient"`
+	Appointments   []*Appointment   `json:"appointments"`
+	MedicalRecords []*MedicalRecord `json:"medical_records"`
+	Notifications  []*Notification  `json:"notifications"`
+	Consents       []*Consent       `json:"consents"`
+	AccessLog      []*AuditLog      `json:"access_log"`
+}
+
+// DataCategory groups patient data that shares an erasure rule
+type DataCategory string
+
+const (
+	DataCategoryPatient        DataCategory = "patient"
+	DataCategoryAppointments   DataCategory = "appointments"
+	DataCategoryMedicalRecords DataCategory = "medical_records"
+	DataCategoryNotifications  DataCategory = "notifications"
+	DataCategoryOutboxEvents   DataCategory = "outbox_events"
+	DataCategoryInsurance      DataCategory = "insurance"
+	DataCategoryConsents       DataCategory = "consents"
+	DataCategoryRelationships  DataCategory = "relationships"
+	DataCategoryIdentifiers    DataCategory = "identifiers"
+)
+
+type ErasureAction string
+
+const (
+	ErasureActionDelete    ErasureAction = "delete"
+	ErasureActionAnonymize ErasureAction = "anonymize"
+	ErasureActionRetain    ErasureAction = "retain"
+)
+
+// ErasureDecision is the rule applied to one data category. When Cutoff is
+// set, Action applies only to rows created before it and newer rows, which are
+// still inside the region's retention period, get Fallback instead.
+type ErasureDecision struct {
+	Category DataCategory  `json:"category"`
+	Action   ErasureAction `json:"action"`
+	Fallback ErasureAction `json:"fallback,omitempty"`
+	Cutoff   *time.Time    `json:"cutoff,omitempty"`
+	Reason   string        `json:"reason"`
+}
+
+// ErasurePlan is everything the repository needs to carry out an erasure in a
+// single transaction. Replacements maps patient columns to the irreversible
+// tokens that overwrite them.
+type ErasurePlan struct {
+	RequestID    uuid.UUID         `json:"request_id"`
+	PatientID    uuid.UUID         `json:"patient_id"`
+	Decisions    []ErasureDecision `json:"decisions"`
+	Replacements map[string]string `json:"-"`
+}
+
+type ErasureOutcome struct {
+	Category DataCategory  `json:"category"`
+	Action   ErasureAction `json:"action"`
+	Rows     int64         `json:"rows"`
+}
+
+// ErasureCertificate is issued to the requester once an erasure completes
+type ErasureCertificate struct {
+	RequestID      uuid.UUID         `json:"request_id"`
+	PatientID      uuid.UUID         `json:"patient_id"`
+	OrganizationID uuid.UUID         `json:"organization_id"`
+	RegionCode     string            `json:"region_code"`
+	RetentionDays  int               `json:"retention_days"`
+	LegalHolds     []uuid.UUID       `json:"legal_holds"`
+	Decisions      []ErasureDecision `json:"decisions"`
+	Outcomes       []ErasureOutcome  `json:"outcomes"`
+	RequestedAt    time.Time         `json:"requested_at"`
+	IssuedAt       time.Time         `json:"issued_at"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ConsentStatus string
+
+const (
+	ConsentStatusGranted   ConsentStatus = "granted"
+	ConsentStatusWithdrawn ConsentStatus = "withdrawn"
+)
+
+type Consent struct {
+	ID             uuid.UUID     `json:"id" db:"id"`
+	PatientID      uuid.UUID     `json:"patient_id" db:"patient_id"`
+	OrganizationID uuid.UUID     `json:"organization_id" db:"organization_id"`
+	Type           string        `json:"type" db:"type"` // e.g. treatment, data_processing, marketing
+	Version        string        `json:"version" db:"version"`
+	Status         ConsentStatus `json:"status" db:"status"`
+	Source         string        `json:"source" db:"source"` // e.g. portal, paper, verbal
+	RecordedBy     uuid.UUID     `json:"recorded_by" db:"recorded_by"`
+	GrantedAt      time.Time     `json:"granted_at" db:"granted_at"`
+	WithdrawnAt    *time.Time    `json:"withdrawn_at,omitempty" db:"withdrawn_at"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+type RecordConsentRequest struct {
+	Type    string `json:"type" binding:"required"`
+	Version string `json:"version" binding:"required"`
+	Source  string `json:"source" binding:"required"`
+}
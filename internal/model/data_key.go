@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DataKeyStatus string
+
+const (
+	// New data is encrypted under the organization's one active key
+	DataKeyStatusActive DataKeyStatus = "active"
+	// Retired keys still decrypt existing data until it is re-encrypted
+	DataKeyStatusRetired DataKeyStatus = "retired"
+)
+
+// DataKey is an organization's data encryption key, stored only wrapped by a
+// key-encryption key held in the KMS
+type DataKey struct {
+	ID             uuid.UUID     `json:"id" db:"id"`
+	OrganizationID uuid.UUID     `json:"organization_id" db:"organization_id"`
+	WrappedKey     []byte        `json:"-" db:"wrapped_key"`
+	KEKID          string        `json:"kek_id" db:"kek_id"`
+	Status         DataKeyStatus `json:"status" db:"status"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+	RetiredAt      *time.Time    `json:"retired_at,omitempty" db:"retired_at"`
+	RewrappedAt    *time.Time    `json:"rewrapped_at,omitempty" db:"rewrapped_at"`
+}
+
+// EncryptedFields are the sealed fields of one medical record or record
+// version that the re-encryption job moves onto the active data key
+type EncryptedFields struct {
+	ID              uuid.UUID  `db:"id"`
+	OrganizationID  uuid.UUID  `db:"organization_id"`
+	Diagnosis       []byte     `db:"diagnosis"`
+	Treatment       []byte     `db:"treatment"`
+	EncryptionKeyID *uuid.UUID `db:"encryption_key_id"`
+}
+
+// EncryptedContent is one sealed content column, such as a clinical note's,
+// that the re-encryption job moves onto the active data key
+type EncryptedContent struct {
+	ID              uuid.UUID  `db:"id"`
+	OrganizationID  uuid.UUID  `db:"organization_id"`
+	Content         []byte     `db:"content"`
+	EncryptionKeyID *uuid.UUID `db:"encryption_key_id"`
+}
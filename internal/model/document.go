@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Document is a file stored for a patient. The content lives in the blob store
+// under StorageKey, encrypted; the row only carries metadata.
+type Document struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	PatientID       uuid.UUID  `json:"patient_id" db:"patient_id"`
+	OrganizationID  uuid.UUID  `json:"organization_id" db:"organization_id"`
+	MedicalRecordID *uuid.UUID `json:"medical_record_id,omitempty" db:"medical_record_id"`
+	Name            string     `json:"name" db:"name"`
+	ContentType     string     `json:"content_type" db:"content_type"`
+	Size            int64      `json:"size" db:"size"`
+	Checksum        string     `json:"checksum" db:"checksum"`
+	Backend         string     `json:"-" db:"backend"`
+	StorageKey      string     `json:"-" db:"storage_key"`
+	UploadedBy      uuid.UUID  `json:"uploaded_by" db:"uploaded_by"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// Attachment returns the attachment entry linking the document to a record
+func (d *Document) Attachment() Attachment {
+	return Attachment{
+		ID:         d.ID,
+		Name:       d.Name,
+		Type:       d.ContentType,
+		Path:       d.StorageKey,
+		UploadedBy: d.UploadedBy,
+		UploadedAt: d.CreatedAt,
+	}
+}
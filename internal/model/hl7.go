@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type HL7MessageStatus string
+
+const (
+	HL7MessageStatusReceived  HL7MessageStatus = "received"
+	HL7MessageStatusProcessed HL7MessageStatus = "processed"
+	// Unmatched messages wait in the reconciliation queue for someone to
+	// pick the patient they belong to
+	HL7MessageStatusUnmatched HL7MessageStatus = "unmatched"
+	HL7MessageStatusResolved  HL7MessageStatus = "resolved"
+	HL7MessageStatusDiscarded HL7MessageStatus = "discarded"
+	HL7MessageStatusFailed    HL7MessageStatus = "failed"
+)
+
+// HL7Message is an inbound HL7 v2 message and what became of it. The raw
+// message is encrypted at rest.
+type HL7Message struct {
+	ID                 uuid.UUID        `json:"id" db:"id"`
+	ControlID          string           `json:"control_id" db:"control_id"`
+	SendingApplication string           `json:"sending_application" db:"sending_application"`
+	SendingFacility    string           `json:"sending_facility" db:"sending_facility"`
+	MessageType        string           `json:"message_type" db:"message_type"`
+	Status             HL7MessageStatus `json:"status" db:"status"`
+	Error              *string          `json:"error,omitempty" db:"error"`
+	PatientID          *uuid.UUID       `json:"patient_id,omitempty" db:"patient_id"`
+	Raw                []byte           `json:"-" db:"raw"`
+	ReceivedAt         time.Time        `json:"received_at" db:"received_at"`
+	ProcessedAt        *time.Time       `json:"processed_at,omitempty" db:"processed_at"`
+	ResolvedBy         *uuid.UUID       `json:"resolved_by,omitempty" db:"resolved_by"`
+	Resolution         *string          `json:"resolution,omitempty" db:"resolution"`
+	// Message is the decrypted raw message, filled in when one message is read
+	Message string `json:"message,omitempty" db:"-"`
+}
+
+type ResolveHL7MessageRequest struct {
+	PatientID uuid.UUID `json:"patient_id" binding:"required"`
+}
+
+type DiscardHL7MessageRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// LabResult is one lab order with its observations, as received in an
+// ORU^R01 OBR group
+type LabResult struct {
+	Order        CodedEntry       `json:"order"`
+	Status       string           `json:"status,omitempty"`
+	ObservedAt   *time.Time       `json:"observed_at,omitempty"`
+	Filler       string           `json:"filler_order_number,omitempty"`
+	Observations []LabObservation `json:"observations"`
+}
+
+type LabObservation struct {
+	Code           CodedEntry `json:"code"`
+	ValueType      string     `json:"value_type"`
+	Value          string     `json:"value"`
+	Units          string     `json:"units,omitempty"`
+	ReferenceRange string     `json:"reference_range,omitempty"`
+	AbnormalFlags  string     `json:"abnormal_flags,omitempty"`
+	Status         string     `json:"status,omitempty"`
+	ObservedAt     *time.Time `json:"observed_at,omitempty"`
+}
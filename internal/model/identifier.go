@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IdentifierType string
+
+const (
+	IdentifierTypeMRN      IdentifierType = "MR"
+	IdentifierTypeLab      IdentifierType = "LAB"
+	IdentifierTypePayer    IdentifierType = "PAYER"
+	IdentifierTypeNational IdentifierType = "NATIONAL"
+	IdentifierTypeOther    IdentifierType = "OTHER"
+)
+
+// PatientIdentifier is an identifier issued to the patient by some system: a
+// clinic's MRN, a lab's accession number, a payer's member ID or a national
+// registry number. A value is unique within its system.
+type PatientIdentifier struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	PatientID      uuid.UUID      `json:"patient_id" db:"patient_id"`
+	OrganizationID uuid.UUID      `json:"organization_id" db:"organization_id"`
+	System         string         `json:"system" db:"system"`
+	Value          string         `json:"value" db:"value"`
+	Type           IdentifierType `json:"type" db:"type"`
+	PeriodStart    time.Time      `json:"period_start" db:"period_start"`
+	PeriodEnd      *time.Time     `json:"period_end,omitempty" db:"period_end"`
+	CreatedBy      uuid.UUID      `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+}
+
+// IsActive reports whether the identifier is in use at the given time
+func (i *PatientIdentifier) IsActive(at time.Time) bool {
+	if at.Before(i.PeriodStart) {
+		return false
+	}
+	return i.PeriodEnd == nil || at.Before(*i.PeriodEnd)
+}
+
+type CheckDigitAlgorithm string
+
+const (
+	CheckDigitNone  CheckDigitAlgorithm = "none"
+	CheckDigitLuhn  CheckDigitAlgorithm = "luhn"
+	CheckDigitMod11 CheckDigitAlgorithm = "mod11"
+)
+
+// MRNConfig controls how a clinic numbers its patients. Pattern is a template
+// with the tokens {SEQ:n} (sequence zero-padded to n digits), {YYYY}, {YY} and
+// {CHECK}; for example "ABC-{YYYY}-{SEQ:6}{CHECK}".
+type MRNConfig struct {
+	ClinicID     uuid.UUID           `json:"clinic_id" db:"clinic_id"`
+	System       string              `json:"system" db:"system"`
+	Pattern      string              `json:"pattern" db:"pattern"`
+	NextSequence int64               `json:"next_sequence" db:"next_sequence"`
+	CheckDigit   CheckDigitAlgorithm `json:"check_digit" db:"check_digit"`
+	CreatedAt    time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+type MRNConfigRequest struct {
+	System       string              `json:"system"`
+	Pattern      string              `json:"pattern" binding:"required"`
+	NextSequence *int64              `json:"next_sequence"`
+	CheckDigit   CheckDigitAlgorithm `json:"check_digit" binding:"omitempty,oneof=none luhn mod11"`
+}
+
+type AddIdentifierRequest struct {
+	System      string         `json:"system" binding:"required"`
+	Value       string         `json:"value" binding:"required"`
+	Type        IdentifierType `json:"type" binding:"required,oneof=MR LAB PAYER NATIONAL OTHER"`
+	PeriodStart *time.Time     `json:"period_start"`
+	PeriodEnd   *time.Time     `json:"period_end"`
+}
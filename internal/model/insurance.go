@@ -0,0 +1,121 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CoveragePriority string
+
+const (
+	CoveragePriorityPrimary   CoveragePriority = "primary"
+	CoveragePrioritySecondary CoveragePriority = "secondary"
+	CoveragePriorityTertiary  CoveragePriority = "tertiary"
+)
+
+type CoverageStatus string
+
+const (
+	CoverageStatusActive   CoverageStatus = "active"
+	CoverageStatusInactive CoverageStatus = "inactive"
+)
+
+type SubscriberRelationship string
+
+const (
+	SubscriberRelationshipSelf   SubscriberRelationship = "self"
+	SubscriberRelationshipSpouse SubscriberRelationship = "spouse"
+	SubscriberRelationshipChild  SubscriberRelationship = "child"
+	SubscriberRelationshipOther  SubscriberRelationship = "other"
+)
+
+// InsuranceCoverage is one insurance policy covering a patient. A patient can
+// hold several at once, ordered by Priority for coordination of benefits.
+type InsuranceCoverage struct {
+	ID                     uuid.UUID              `json:"id" db:"id"`
+	PatientID              uuid.UUID              `json:"patient_id" db:"patient_id"`
+	OrganizationID         uuid.UUID              `json:"organization_id" db:"organization_id"`
+	PayerName              string                 `json:"payer_name" db:"payer_name"`
+	PayerID                string                 `json:"payer_id" db:"payer_id"`
+	PlanName               string                 `json:"plan_name" db:"plan_name"`
+	MemberNumber           string                 `json:"member_number" db:"member_number"`
+	GroupNumber            string                 `json:"group_number" db:"group_number"`
+	SubscriberName         string                 `json:"subscriber_name" db:"subscriber_name"`
+	SubscriberRelationship SubscriberRelationship `json:"subscriber_relationship" db:"subscriber_relationship"`
+	Priority               CoveragePriority       `json:"priority" db:"priority"`
+	Status                 CoverageStatus         `json:"status" db:"status"`
+	EffectiveFrom          time.Time              `json:"effective_from" db:"effective_from"`
+	EffectiveTo            *time.Time             `json:"effective_to,omitempty" db:"effective_to"`
+	CardFrontImage         *string                `json:"card_front_image,omitempty" db:"card_front_image"`
+	CardBackImage          *string                `json:"card_back_image,omitempty" db:"card_back_image"`
+	CreatedAt              time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// IsEffective reports whether the coverage is active on the given date
+func (c *InsuranceCoverage) IsEffective(on time.Time) bool {
+	if c.Status != CoverageStatusActive || on.Before(c.EffectiveFrom) {
+		return false
+	}
+	return c.EffectiveTo == nil || !on.After(*c.EffectiveTo)
+}
+
+// InsuranceCoverageHistory is a snapshot of a coverage taken on every change
+type InsuranceCoverageHistory struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	CoverageID uuid.UUID       `json:"coverage_id" db:"coverage_id"`
+	Action     string          `json:"action" db:"action"`
+	Snapshot   json.RawMessage `json:"snapshot" db:"snapshot"`
+	ChangedBy  uuid.UUID       `json:"changed_by" db:"changed_by"`
+	ChangedAt  time.Time       `json:"changed_at" db:"changed_at"`
+}
+
+type EligibilityStatus string
+
+const (
+	EligibilityStatusEligible   EligibilityStatus = "eligible"
+	EligibilityStatusIneligible EligibilityStatus = "ineligible"
+	EligibilityStatusUnknown    EligibilityStatus = "unknown"
+	EligibilityStatusError      EligibilityStatus = "error"
+)
+
+// EligibilityCheck records the result of verifying a coverage with the payer,
+// optionally for a specific appointment.
+type EligibilityCheck struct {
+	ID            uuid.UUID         `json:"id" db:"id"`
+	CoverageID    uuid.UUID         `json:"coverage_id" db:"coverage_id"`
+	PatientID     uuid.UUID         `json:"patient_id" db:"patient_id"`
+	AppointmentID *uuid.UUID        `json:"appointment_id,omitempty" db:"appointment_id"`
+	Provider      string            `json:"provider" db:"provider"`
+	Status        EligibilityStatus `json:"status" db:"status"`
+	ServiceDate   time.Time         `json:"service_date" db:"service_date"`
+	Copay         *float64          `json:"copay,omitempty" db:"copay"`
+	Message       string            `json:"message" db:"message"`
+	Response      json.RawMessage   `json:"response,omitempty" db:"response"`
+	CheckedBy     uuid.UUID         `json:"checked_by" db:"checked_by"`
+	CheckedAt     time.Time         `json:"checked_at" db:"checked_at"`
+}
+
+type CoverageRequest struct {
+	ID                     *uuid.UUID             `json:"id"`
+	PayerName              string                 `json:"payer_name" binding:"required"`
+	PayerID                string                 `json:"payer_id"`
+	PlanName               string                 `json:"plan_name"`
+	MemberNumber           string                 `json:"member_number" binding:"required"`
+	GroupNumber            string                 `json:"group_number"`
+	SubscriberName         string                 `json:"subscriber_name"`
+	SubscriberRelationship SubscriberRelationship `json:"subscriber_relationship" binding:"required,oneof=self spouse child other"`
+	Priority               CoveragePriority       `json:"priority" binding:"required,oneof=primary secondary tertiary"`
+	Status                 CoverageStatus         `json:"status" binding:"omitempty,oneof=active inactive"`
+	EffectiveFrom          time.Time              `json:"effective_from" binding:"required"`
+	EffectiveTo            *time.Time             `json:"effective_to"`
+	CardFrontImage         *string                `json:"card_front_image"`
+	CardBackImage          *string                `json:"card_back_image"`
+}
+
+type EligibilityCheckRequest struct {
+	AppointmentID *uuid.UUID `json:"appointment_id"`
+	ServiceDate   *time.Time `json:"service_date"`
+}
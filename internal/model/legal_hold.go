@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// LegalHold blocks erasure of a patient's data while litigation, an
+// investigation or a regulator request is open. An empty Categories list holds
+// every category.
+type LegalHold struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	OrganizationID uuid.UUID      `json:"organization_id" db:"organization_id"`
+	PatientID      uuid.UUID      `json:"patient_id" db:"patient_id"`
+	Reason         string         `json:"reason" db:"reason"`
+	Categories     pq.StringArray `json:"categories" db:"categories"`
+	PlacedBy       uuid.UUID      `json:"placed_by" db:"placed_by"`
+	PlacedAt       time.Time      `json:"placed_at" db:"placed_at"`
+	ReleasedBy     *uuid.UUID     `json:"released_by,omitempty" db:"released_by"`
+	ReleasedAt     *time.Time     `json:"released_at,omitempty" db:"released_at"`
+}
+
+// Covers reports whether the hold applies to the given category
+func (h *LegalHold) Covers(category DataCategory) bool {
+	if len(h.Categories) == 0 {
+		return true
+	}
+	for _, c := range h.Categories {
+		if DataCategory(c) == category {
+			return true
+		}
+	}
+	return false
+}
+
+type PlaceLegalHoldRequest struct {
+	Reason     string   `json:"reason" binding:"required"`
+	Categories []string `json:"categories"`
+}
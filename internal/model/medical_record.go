@@ -20,9 +20,15 @@ type MedicalRecord struct {
 	Medications     []Medication    `json:"-"`
 	Attachments     []Attachment    `json:"-"`
 	AccessLevel     string          `db:"access_level" json:"access_level"`
-	CreatedBy       uuid.UUID       `db:"created_by" json:"created_by"`
-	LastAccessedBy  uuid.UUID       `db:"last_accessed_by" json:"last_accessed_by"`
-	LastAccessedAt  time.Time       `db:"last_accessed_at" json:"last_accessed_at"`
+	Version         int             `db:"version" json:"version"`
+	// EncryptionKeyID is the data key Diagnosis and Treatment are sealed with
+	EncryptionKeyID *uuid.UUID `db:"encryption_key_id" json:"-"`
+	// Codes are the structured diagnosis entries, validated and normalized
+	// before Diagnosis is encrypted; they are stored separately for reporting
+	Codes          []CodedEntry `db:"-" json:"-"`
+	CreatedBy      uuid.UUID    `db:"created_by" json:"created_by"`
+	LastAccessedBy uuid.UUID    `db:"last_accessed_by" json:"last_accessed_by"`
+	LastAccessedAt time.Time    `db:"last_accessed_at" json:"last_accessed_at"`
 }
 
 type Medication struct {
@@ -16,21 +16,22 @@ const (
 )
 
 type Notification struct {
-	ID             uuid.UUID
-	UserID         uuid.UUID
-	OrganizationID uuid.UUID
-	Channel        string
-	Priority       string
-	Subject        string
-	Content        string
-	Recipient      string
-	Status         NotificationStatus
-	RetryCount     int
-	LastError      string
-	NextRetryAt    time.Time
-	SentAt         time.Time
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	ID             uuid.UUID          `json:"id" db:"id"`
+	UserID         uuid.UUID          `json:"user_id" db:"user_id"`
+	OrganizationID uuid.UUID          `json:"organization_id" db:"organization_id"`
+	PatientID      *uuid.UUID         `json:"patient_id,omitempty" db:"patient_id"`
+	Channel        string             `json:"channel" db:"channel"`
+	Priority       string             `json:"priority" db:"priority"`
+	Subject        string             `json:"subject" db:"subject"`
+	Content        string             `json:"content" db:"content"`
+	Recipient      string             `json:"recipient" db:"recipient"`
+	Status         NotificationStatus `json:"status" db:"status"`
+	RetryCount     int                `json:"retry_count" db:"retry_count"`
+	LastError      string             `json:"last_error,omitempty" db:"last_error"`
+	NextRetryAt    time.Time          `json:"next_retry_at" db:"next_retry_at"`
+	SentAt         time.Time          `json:"sent_at" db:"sent_at"`
+	CreatedAt      time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" db:"updated_at"`
 }
 
 type NotificationEvent struct {
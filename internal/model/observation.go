@@ -0,0 +1,220 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ObservationType is a kind of vital sign or point-of-care measurement
+type ObservationType string
+
+const (
+	ObservationSystolicBP       ObservationType = "systolic_bp"
+	ObservationDiastolicBP      ObservationType = "diastolic_bp"
+	ObservationHeartRate        ObservationType = "heart_rate"
+	ObservationBodyTemperature  ObservationType = "body_temperature"
+	ObservationBodyWeight       ObservationType = "body_weight"
+	ObservationBodyHeight       ObservationType = "body_height"
+	ObservationBMI              ObservationType = "bmi"
+	ObservationOxygenSaturation ObservationType = "oxygen_saturation"
+	ObservationBloodGlucose     ObservationType = "blood_glucose"
+)
+
+// ObservationDefinition describes how one observation type is coded and
+// stored. Values are normalized to Unit; those outside ValidMin and ValidMax
+// are rejected as implausible.
+type ObservationDefinition struct {
+	Type ObservationType `json:"type"`
+	Code CodedEntry      `json:"code"`
+	// Unit is the UCUM unit values are stored in
+	Unit string `json:"unit"`
+	// MolarMass in g/mol lets values given as substance concentrations be
+	// converted to the mass concentration they are stored in
+	MolarMass float64 `json:"-"`
+	ValidMin  float64 `json:"valid_min"`
+	ValidMax  float64 `json:"valid_max"`
+}
+
+// ObservationDefinitions lists the observation types that can be recorded
+var ObservationDefinitions = map[ObservationType]ObservationDefinition{
+	ObservationSystolicBP: {
+		Type: ObservationSystolicBP, Unit: "mm[Hg]", ValidMin: 20, ValidMax: 300,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "8480-6", Display: "Systolic blood pressure"},
+	},
+	ObservationDiastolicBP: {
+		Type: ObservationDiastolicBP, Unit: "mm[Hg]", ValidMin: 10, ValidMax: 200,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "8462-4", Display: "Diastolic blood pressure"},
+	},
+	ObservationHeartRate: {
+		Type: ObservationHeartRate, Unit: "/min", ValidMin: 10, ValidMax: 350,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "8867-4", Display: "Heart rate"},
+	},
+	ObservationBodyTemperature: {
+		Type: ObservationBodyTemperature, Unit: "Cel", ValidMin: 25, ValidMax: 45,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "8310-5", Display: "Body temperature"},
+	},
+	ObservationBodyWeight: {
+		Type: ObservationBodyWeight, Unit: "kg", ValidMin: 0.2, ValidMax: 650,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "29463-7", Display: "Body weight"},
+	},
+	ObservationBodyHeight: {
+		Type: ObservationBodyHeight, Unit: "cm", ValidMin: 20, ValidMax: 275,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "8302-2", Display: "Body height"},
+	},
+	ObservationBMI: {
+		Type: ObservationBMI, Unit: "kg/m2", ValidMin: 5, ValidMax: 150,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "39156-5", Display: "Body mass index (BMI) [Ratio]"},
+	},
+	ObservationOxygenSaturation: {
+		Type: ObservationOxygenSaturation, Unit: "%", ValidMin: 30, ValidMax: 100,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "59408-5", Display: "Oxygen saturation in Arterial blood by Pulse oximetry"},
+	},
+	ObservationBloodGlucose: {
+		Type: ObservationBloodGlucose, Unit: "mg/dL", MolarMass: 180.156, ValidMin: 5, ValidMax: 2000,
+		Code: CodedEntry{System: CodeSystemLOINC, Code: "2339-0", Display: "Glucose [Mass/volume] in Blood"},
+	},
+}
+
+type ObservationStatus string
+
+const (
+	ObservationStatusFinal          ObservationStatus = "final"
+	ObservationStatusEnteredInError ObservationStatus = "entered-in-error"
+)
+
+// ObservationFlag uses the HL7 v2 abnormal flags lab results carry
+type ObservationFlag string
+
+const (
+	ObservationFlagNormal       ObservationFlag = "N"
+	ObservationFlagLow          ObservationFlag = "L"
+	ObservationFlagHigh         ObservationFlag = "H"
+	ObservationFlagCriticalLow  ObservationFlag = "LL"
+	ObservationFlagCriticalHigh ObservationFlag = "HH"
+)
+
+// Observation is one measurement of a patient. Value is in the type's
+// storage unit; what was entered is kept in OriginalValue and OriginalUnit.
+// The reference range used to flag it is copied so later changes to ranges
+// don't change past flags.
+type Observation struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	PatientID      uuid.UUID         `json:"patient_id" db:"patient_id"`
+	OrganizationID uuid.UUID         `json:"organization_id" db:"organization_id"`
+	AppointmentID  *uuid.UUID        `json:"appointment_id,omitempty" db:"appointment_id"`
+	Type           ObservationType   `json:"type" db:"type"`
+	Code           string            `json:"code" db:"code"`
+	Value          float64           `json:"value" db:"value"`
+	Unit           string            `json:"unit" db:"unit"`
+	OriginalValue  float64           `json:"original_value" db:"original_value"`
+	OriginalUnit   string            `json:"original_unit" db:"original_unit"`
+	Flag           *ObservationFlag  `json:"flag,omitempty" db:"flag"`
+	ReferenceLow   *float64          `json:"reference_low,omitempty" db:"reference_low"`
+	ReferenceHigh  *float64          `json:"reference_high,omitempty" db:"reference_high"`
+	Status         ObservationStatus `json:"status" db:"status"`
+	StatusReason   *string           `json:"status_reason,omitempty" db:"status_reason"`
+	ObservedAt     time.Time         `json:"observed_at" db:"observed_at"`
+	RecordedBy     uuid.UUID         `json:"recorded_by" db:"recorded_by"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// Abnormal reports whether the observation was flagged outside its
+// reference range
+func (o *Observation) Abnormal() bool {
+	return o.Flag != nil && *o.Flag != ObservationFlagNormal
+}
+
+// ObservationReading is one value of a RecordObservationsRequest. Unit may
+// be any UCUM unit convertible to the type's unit, or one of its common
+// spellings; it defaults to the type's unit.
+type ObservationReading struct {
+	Type  ObservationType `json:"type" binding:"required"`
+	Value float64         `json:"value"`
+	Unit  string          `json:"unit"`
+}
+
+// RecordObservationsRequest records a set of vitals taken together, such as
+// both blood pressure values
+type RecordObservationsRequest struct {
+	AppointmentID *uuid.UUID           `json:"appointment_id"`
+	ObservedAt    *time.Time           `json:"observed_at"`
+	Readings      []ObservationReading `json:"readings" binding:"required,min=1,dive"`
+}
+
+type EnteredInErrorRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type ObservationFilter struct {
+	Type          ObservationType
+	AppointmentID *uuid.UUID
+	From          *time.Time
+	To            *time.Time
+	// IncludeErrors lists observations marked entered in error as well
+	IncludeErrors bool
+}
+
+// TrendPoint summarizes the observations of one time bucket
+type TrendPoint struct {
+	Start    time.Time `json:"start" db:"bucket"`
+	Count    int       `json:"count" db:"count"`
+	Min      float64   `json:"min" db:"min"`
+	Max      float64   `json:"max" db:"max"`
+	Mean     float64   `json:"mean" db:"mean"`
+	Abnormal int       `json:"abnormal" db:"abnormal"`
+}
+
+// ObservationTrend is a downsampled series of one observation type
+type ObservationTrend struct {
+	Type     ObservationType `json:"type"`
+	Unit     string          `json:"unit"`
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Interval string          `json:"interval"`
+	Points   []*TrendPoint   `json:"points"`
+}
+
+// ReferenceRange is the normal range of an observation type for patients of
+// a sex and age band. Ranges without an organization are the defaults;
+// an organization's own ranges take precedence over them.
+type ReferenceRange struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	OrganizationID *uuid.UUID      `json:"organization_id,omitempty" db:"organization_id"`
+	Type           ObservationType `json:"type" db:"type"`
+	// Sex is male or female, or empty for either
+	Sex string `json:"sex,omitempty" db:"sex"`
+	// MinAgeMonths is inclusive and MaxAgeMonths exclusive
+	MinAgeMonths int       `json:"min_age_months" db:"min_age_months"`
+	MaxAgeMonths *int      `json:"max_age_months,omitempty" db:"max_age_months"`
+	Low          *float64  `json:"low,omitempty" db:"low"`
+	High         *float64  `json:"high,omitempty" db:"high"`
+	CriticalLow  *float64  `json:"critical_low,omitempty" db:"critical_low"`
+	CriticalHigh *float64  `json:"critical_high,omitempty" db:"critical_high"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Matches reports whether the range applies to a patient of the given sex
+// and age
+func (r *ReferenceRange) Matches(sex string, ageMonths int) bool {
+	if r.Sex != "" && r.Sex != sex {
+		return false
+	}
+	if ageMonths < r.MinAgeMonths {
+		return false
+	}
+	return r.MaxAgeMonths == nil || ageMonths < *r.MaxAgeMonths
+}
+
+type CreateReferenceRangeRequest struct {
+	Type         ObservationType `json:"type" binding:"required"`
+	Sex          string          `json:"sex" binding:"omitempty,oneof=male female"`
+	MinAgeMonths int             `json:"min_age_months" binding:"min=0"`
+	MaxAgeMonths *int            `json:"max_age_months" binding:"omitempty,gt=0"`
+	// Values are in the type's unit
+	Low          *float64 `json:"low"`
+	High         *float64 `json:"high"`
+	CriticalLow  *float64 `json:"critical_low"`
+	CriticalHigh *float64 `json:"critical_high"`
+}
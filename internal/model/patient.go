@@ -15,31 +15,40 @@ const (
 
 type Patient struct {
 	Base
-	ID                   uuid.UUID         `json:"id" db:"id"`
-	ClinicID             uuid.UUID         `json:"clinic_id" db:"clinic_id"`
-	OrganizationID       uuid.UUID         `json:"organization_id" db:"organization_id"`
-	FirstName            string            `json:"first_name" db:"first_name"`
-	LastName             string            `json:"last_name" db:"last_name"`
-	Email                string            `json:"email" db:"email"`
-	Phone                string            `json:"phone" db:"phone"`
-	DateOfBirth          time.Time         `json:"date_of_birth" db:"date_of_birth"`
-	Gender               string            `json:"gender" db:"gender"`
-	Address              string            `json:"address" db:"address"`
-	EmergencyContact     *EmergencyContact `json:"emergency_contact" db:"-"`
-	InsuranceInfo        *InsuranceInfo    `json:"insurance_info" db:"-"`
-	Status               string            `json:"status" db:"status"`
-	CreatedAt            time.Time         `json:"created_at" db:"created_at"`
-	UpdatedAt            time.Time         `json:"updated_at" db:"updated_at"`
-	Name                 string            `db:"name" json:"name"`
-	PhoneNumber          string            `db:"phone_number" json:"phone_number"`
-	EmergencyContactJSON string            `db:"emergency_contact" json:"-"`
-	InsuranceInfoJSON    string            `db:"insurance_info" json:"-"`
+	ID             uuid.UUID `json:"id" db:"id"`
+	ClinicID       uuid.UUID `json:"clinic_id" db:"clinic_id"`
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	// UserID is the patient's own login, if they have one
+	UserID           *uuid.UUID           `json:"user_id,omitempty" db:"user_id"`
+	FirstName        string               `json:"first_name" db:"first_name"`
+	LastName         string               `json:"last_name" db:"last_name"`
+	Email            string               `json:"email" db:"email"`
+	Phone            string               `json:"phone" db:"phone"`
+	DateOfBirth      time.Time            `json:"date_of_birth" db:"date_of_birth"`
+	Gender           string               `json:"gender" db:"gender"`
+	Address          string               `json:"address" db:"address"`
+	EmergencyContact *EmergencyContact    `json:"emergency_contact" db:"-"`
+	InsuranceInfo    *InsuranceInfo       `json:"insurance_info" db:"-"`
+	Identifiers      []*PatientIdentifier `json:"identifiers,omitempty" db:"-"`
+	Status           string               `json:"status" db:"status"`
+	// Locale and Timezone are what patient-facing messages are written in
+	Locale               string    `json:"locale" db:"locale"`
+	Timezone             string    `json:"timezone" db:"timezone"`
+	CreatedAt            time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
+	Name                 string    `db:"name" json:"name"`
+	PhoneNumber          string    `db:"phone_number" json:"phone_number"`
+	EmergencyContactJSON string    `db:"emergency_contact" json:"-"`
+	InsuranceInfoJSON    string    `db:"insurance_info" json:"-"`
 }
 
 type EmergencyContact struct {
 	Name     string `json:"name"`
 	Relation string `json:"relation"`
 	Phone    string `json:"phone"`
+	// RelationshipID points at the PatientRelationship this contact was
+	// taken from, when there is one
+	RelationshipID *uuid.UUID `json:"relationship_id,omitempty"`
 }
 
 type InsuranceInfo struct {
@@ -56,15 +65,20 @@ type UpdatePatientRequest struct {
 	Phone       *string    `json:"phone"`
 	Address     *string    `json:"address"`
 	Status      *string    `json:"status"`
+	Locale      *string    `json:"locale"`
+	Timezone    *string    `json:"timezone"`
 }
 
 type CreatePatientRequest struct {
-	ClinicID  string    `json:"clinic_id" validate:"required,uuid"`
-	FirstName string    `json:"first_name" validate:"required"`
-	LastName  string    `json:"last_name" validate:"required"`
-	Email     string    `json:"email" validate:"required,email"`
-	Phone     string    `json:"phone" validate:"required"`
-	DOB       time.Time `json:"dob" validate:"required"`
-	Address   string    `json:"address" validate:"required"`
-	Status    string    `json:"status" validate:"required"`
+	ClinicID  string     `json:"clinic_id" validate:"required,uuid"`
+	UserID    *uuid.UUID `json:"user_id"`
+	FirstName string     `json:"first_name" validate:"required"`
+	LastName  string     `json:"last_name" validate:"required"`
+	Email     string     `json:"email" validate:"required,email"`
+	Phone     string     `json:"phone" validate:"required"`
+	DOB       time.Time  `json:"dob" validate:"required"`
+	Address   string     `json:"address" validate:"required"`
+	Status    string     `json:"status" validate:"required"`
+	Locale    string     `json:"locale"`
+	Timezone  string     `json:"timezone"`
 }
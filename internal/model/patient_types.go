@@ -11,10 +11,20 @@ type PatientFilters struct {
 	OrganizationID uuid.UUID `json:"organization_id"`
 	SearchTerm     string    `json:"search_term"`
 	Status         string    `json:"status"`
+	// Identifier matches any identifier value; IdentifierSystem narrows the
+	// match to one system.
+	Identifier       string `json:"identifier"`
+	IdentifierSystem string `json:"identifier_system"`
+	// Email and Phone are exact matches, compared after normalization so
+	// they work against encrypted rows through their blind indexes
+	Email string `json:"email"`
+	Phone string `json:"phone"`
 }
 
 type RecordFilters struct {
 	Type      string    `json:"type"`
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
+	// Access restricts the records by access level; nil returns them all
+	Access *RecordAccess `json:"-"`
 }
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DrugSystemRxNorm identifies drugs by RxNorm concept (RXCUI)
+const DrugSystemRxNorm = "http://www.nlm.nih.gov/research/umls/rxnorm"
+
+type PrescriptionStatus string
+
+const (
+	PrescriptionStatusActive       PrescriptionStatus = "active"
+	PrescriptionStatusPaused       PrescriptionStatus = "paused"
+	PrescriptionStatusDiscontinued PrescriptionStatus = "discontinued"
+)
+
+// Prescription is a medication order for a patient. Warnings raised by the
+// interaction check are kept with it together with who acknowledged them.
+type Prescription struct {
+	ID                   uuid.UUID          `json:"id" db:"id"`
+	PatientID            uuid.UUID          `json:"patient_id" db:"patient_id"`
+	OrganizationID       uuid.UUID          `json:"organization_id" db:"organization_id"`
+	PrescriberID         uuid.UUID          `json:"prescriber_id" db:"prescriber_id"`
+	DrugSystem           string             `json:"drug_system" db:"drug_system"`
+	DrugCode             string             `json:"drug_code" db:"drug_code"`
+	DrugName             string             `json:"drug_name" db:"drug_name"`
+	Strength             string             `json:"strength" db:"strength"`
+	Route                string             `json:"route" db:"route"`
+	Frequency            string             `json:"frequency" db:"frequency"`
+	Quantity             float64            `json:"quantity" db:"quantity"`
+	Refills              int                `json:"refills" db:"refills"`
+	Instructions         *string            `json:"instructions,omitempty" db:"instructions"`
+	StartDate            time.Time          `json:"start_date" db:"start_date"`
+	StopDate             *time.Time         `json:"stop_date,omitempty" db:"stop_date"`
+	Status               PrescriptionStatus `json:"status" db:"status"`
+	StatusReason         *string            `json:"status_reason,omitempty" db:"status_reason"`
+	AcknowledgedWarnings json.RawMessage    `json:"acknowledged_warnings,omitempty" db:"acknowledged_warnings"`
+	CreatedAt            time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+type CreatePrescriptionRequest struct {
+	DrugCode     string     `json:"drug_code" binding:"required"`
+	DrugName     string     `json:"drug_name" binding:"required"`
+	Strength     string     `json:"strength" binding:"required"`
+	Route        string     `json:"route" binding:"required"`
+	Frequency    string     `json:"frequency" binding:"required"`
+	Quantity     float64    `json:"quantity" binding:"required,gt=0"`
+	Refills      int        `json:"refills" binding:"min=0"`
+	Instructions *string    `json:"instructions"`
+	StartDate    *time.Time `json:"start_date"`
+	StopDate     *time.Time `json:"stop_date"`
+	// AcknowledgedWarnings holds the keys of the interaction warnings the
+	// prescriber has reviewed; every warning must be acknowledged
+	AcknowledgedWarnings []string `json:"acknowledged_warnings"`
+}
+
+type UpdatePrescriptionStatusRequest struct {
+	Status PrescriptionStatus `json:"status" binding:"required"`
+	Reason *string            `json:"reason"`
+	// AcknowledgedWarnings holds the keys of the interaction warnings found
+	// when resuming a paused prescription
+	AcknowledgedWarnings []string `json:"acknowledged_warnings"`
+}
+
+type InteractionType string
+
+const (
+	InteractionTypeDrugDrug    InteractionType = "drug-drug"
+	InteractionTypeDrugAllergy InteractionType = "drug-allergy"
+)
+
+// InteractionWarning is one interaction found when checking a drug for a
+// patient. Key identifies it so the prescriber can acknowledge it.
+type InteractionWarning struct {
+	Key         string          `json:"key"`
+	Type        InteractionType `json:"type"`
+	Severity    string          `json:"severity" db:"severity"`
+	Description string          `json:"description" db:"description"`
+	// InteractsWith is the conflicting drug or allergy code
+	InteractsWith        string     `json:"interacts_with" db:"interacts_with"`
+	InteractsWithDisplay string     `json:"interacts_with_display" db:"interacts_with_display"`
+	PrescriptionID       *uuid.UUID `json:"prescription_id,omitempty" db:"prescription_id"`
+}
+
+type InteractionDatasetKind string
+
+const (
+	InteractionDatasetDrugDrug    InteractionDatasetKind = "drug-drug"
+	InteractionDatasetDrugAllergy InteractionDatasetKind = "drug-allergy"
+)
+
+// DrugInteraction is one pair of interacting drugs from the loaded dataset
+type DrugInteraction struct {
+	DrugA       string
+	DrugB       string
+	Severity    string
+	Description string
+}
+
+// DrugAllergyInteraction links an allergy code, as recorded in a patient's
+// coded diagnoses, to a drug that must not be given with it
+type DrugAllergyInteraction struct {
+	AllergySystem string
+	AllergyCode   string
+	DrugCode      string
+	Severity      string
+	Description   string
+}
+
+// InteractionDataset records an interaction dataset file that was loaded
+type InteractionDataset struct {
+	ID         int64                  `json:"id" db:"id"`
+	Kind       InteractionDatasetKind `json:"kind" db:"kind"`
+	Version    string                 `json:"version" db:"version"`
+	File       string                 `json:"file" db:"file"`
+	EntryCount int                    `json:"entry_count" db:"entry_count"`
+	LoadedBy   *uuid.UUID             `json:"loaded_by,omitempty" db:"loaded_by"`
+	LoadedAt   time.Time              `json:"loaded_at" db:"loaded_at"`
+}
+
+type LoadInteractionDatasetRequest struct {
+	Kind    InteractionDatasetKind `json:"kind" binding:"required"`
+	Version string                 `json:"version" binding:"required"`
+	// File is relative to the configured interaction dataset directory
+	File string `json:"file" binding:"required"`
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ReferralStatus string
+
+const (
+	ReferralStatusSent      ReferralStatus = "sent"
+	ReferralStatusAccepted  ReferralStatus = "accepted"
+	ReferralStatusDeclined  ReferralStatus = "declined"
+	ReferralStatusCompleted ReferralStatus = "completed"
+)
+
+// ReferralScope is what a referral shares with the receiving organization
+type ReferralScope string
+
+const (
+	ReferralScopeDemographics ReferralScope = "demographics"
+	ReferralScopeRecords      ReferralScope = "records"
+)
+
+// Referral sends a patient to another organization. While it is open the
+// receiving organization has read-only access to what it shares, until the
+// grant expires or the sender revokes it.
+type Referral struct {
+	ID                   uuid.UUID      `json:"id" db:"id"`
+	PatientID            uuid.UUID      `json:"patient_id" db:"patient_id"`
+	SourceOrganizationID uuid.UUID      `json:"source_organization_id" db:"source_organization_id"`
+	TargetOrganizationID uuid.UUID      `json:"target_organization_id" db:"target_organization_id"`
+	ReferredBy           uuid.UUID      `json:"referred_by" db:"referred_by"`
+	Specialty            string         `json:"specialty" db:"specialty"`
+	Reason               *string        `json:"reason,omitempty" db:"reason"`
+	Status               ReferralStatus `json:"status" db:"status"`
+	Scopes               pq.StringArray `json:"scopes" db:"scopes"`
+	RecordIDs            pq.StringArray `json:"record_ids" db:"record_ids"`
+	ExpiresAt            time.Time      `json:"expires_at" db:"expires_at"`
+	DecidedBy            *uuid.UUID     `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedAt            *time.Time     `json:"decided_at,omitempty" db:"decided_at"`
+	DecisionNote         *string        `json:"decision_note,omitempty" db:"decision_note"`
+	CompletedAt          *time.Time     `json:"completed_at,omitempty" db:"completed_at"`
+	RevokedBy            *uuid.UUID     `json:"revoked_by,omitempty" db:"revoked_by"`
+	RevokedAt            *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt            time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at" db:"updated_at"`
+	// AccessActive says whether the receiving organization can read the
+	// shared data right now
+	AccessActive bool `json:"access_active" db:"-"`
+}
+
+// HasScope reports whether the referral shares scope
+func (r *Referral) HasScope(scope ReferralScope) bool {
+	for _, s := range r.Scopes {
+		if ReferralScope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantActive reports whether the receiving organization may read the
+// shared data at now: the referral is open, not revoked and not expired
+func (r *Referral) GrantActive(now time.Time) bool {
+	open := r.Status == ReferralStatusSent || r.Status == ReferralStatusAccepted
+	return open && r.RevokedAt == nil && now.Before(r.ExpiresAt)
+}
+
+type CreateReferralRequest struct {
+	TargetOrganizationID uuid.UUID       `json:"target_organization_id" binding:"required"`
+	Specialty            string          `json:"specialty" binding:"required"`
+	Reason               *string         `json:"reason"`
+	Scopes               []ReferralScope `json:"scopes" binding:"required,min=1,dive,oneof=demographics records"`
+	RecordIDs            []uuid.UUID     `json:"record_ids"`
+	// ExpiresAt ends the grant; it defaults to 30 days from now
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ReferralDecisionRequest struct {
+	Note *string `json:"note"`
+}
+
+type ReferralFilter struct {
+	// OrganizationID is the caller's organization; Direction picks referrals
+	// it sent ("outgoing") or received ("incoming")
+	OrganizationID uuid.UUID
+	Direction      string
+	PatientID      *uuid.UUID
+	Status         ReferralStatus
+}
+
+// SharedDemographics is the part of a patient's profile a referral shares
+type SharedDemographics struct {
+	PatientID   uuid.UUID `json:"patient_id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	DateOfBirth time.Time `json:"date_of_birth"`
+	Gender      string    `json:"gender"`
+	Phone       string    `json:"phone"`
+	Email       string    `json:"email"`
+	Address     string    `json:"address"`
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type RelationshipType string
+
+const (
+	RelationshipTypeParent          RelationshipType = "parent"
+	RelationshipTypeGuardian        RelationshipType = "guardian"
+	RelationshipTypeCaregiver       RelationshipType = "caregiver"
+	RelationshipTypePowerOfAttorney RelationshipType = "power_of_attorney"
+	RelationshipTypeFamily          RelationshipType = "family"
+)
+
+// ProxyScope is something a related person may do on the patient's behalf
+type ProxyScope string
+
+const (
+	ProxyScopeProfileView          ProxyScope = "profile:view"
+	ProxyScopeProfileManage        ProxyScope = "profile:manage"
+	ProxyScopeAppointmentsView     ProxyScope = "appointments:view"
+	ProxyScopeAppointmentsBook     ProxyScope = "appointments:book"
+	ProxyScopeRecordsView          ProxyScope = "records:view"
+	ProxyScopeInsuranceManage      ProxyScope = "insurance:manage"
+	ProxyScopeConsentsManage       ProxyScope = "consents:manage"
+	ProxyScopeNotificationsReceive ProxyScope = "notifications:receive"
+)
+
+var ProxyScopes = []ProxyScope{
+	ProxyScopeProfileView,
+	ProxyScopeProfileManage,
+	ProxyScopeAppointmentsView,
+	ProxyScopeAppointmentsBook,
+	ProxyScopeRecordsView,
+	ProxyScopeInsuranceManage,
+	ProxyScopeConsentsManage,
+	ProxyScopeNotificationsReceive,
+}
+
+// PatientRelationship links a patient to a person who may act for them: a
+// user account, another patient, or both.
+type PatientRelationship struct {
+	ID                 uuid.UUID        `json:"id" db:"id"`
+	PatientID          uuid.UUID        `json:"patient_id" db:"patient_id"`
+	OrganizationID     uuid.UUID        `json:"organization_id" db:"organization_id"`
+	RelatedPatientID   *uuid.UUID       `json:"related_patient_id,omitempty" db:"related_patient_id"`
+	RelatedUserID      *uuid.UUID       `json:"related_user_id,omitempty" db:"related_user_id"`
+	Type               RelationshipType `json:"type" db:"type"`
+	Scopes             pq.StringArray   `json:"scopes" db:"scopes"`
+	IsEmergencyContact bool             `json:"is_emergency_contact" db:"is_emergency_contact"`
+	ValidFrom          time.Time        `json:"valid_from" db:"valid_from"`
+	ValidTo            *time.Time       `json:"valid_to,omitempty" db:"valid_to"`
+	Notes              string           `json:"notes,omitempty" db:"notes"`
+	CreatedBy          uuid.UUID        `json:"created_by" db:"created_by"`
+	RevokedBy          *uuid.UUID       `json:"revoked_by,omitempty" db:"revoked_by"`
+	RevokedAt          *time.Time       `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// IsActive reports whether the relationship is in force at the given time
+func (r *PatientRelationship) IsActive(at time.Time) bool {
+	if r.RevokedAt != nil || at.Before(r.ValidFrom) {
+		return false
+	}
+	return r.ValidTo == nil || at.Before(*r.ValidTo)
+}
+
+func (r *PatientRelationship) HasScope(scope ProxyScope) bool {
+	for _, s := range r.Scopes {
+		if ProxyScope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateRelationshipRequest struct {
+	RelatedPatientID   *uuid.UUID       `json:"related_patient_id"`
+	RelatedUserID      *uuid.UUID       `json:"related_user_id"`
+	Type               RelationshipType `json:"type" binding:"required,oneof=parent guardian caregiver power_of_attorney family"`
+	Scopes             []string         `json:"scopes"`
+	IsEmergencyContact bool             `json:"is_emergency_contact"`
+	ValidFrom          *time.Time       `json:"valid_from"`
+	ValidTo            *time.Time       `json:"valid_to"`
+	Notes              string           `json:"notes"`
+}
+
+type UpdateRelationshipRequest struct {
+	Scopes             []string   `json:"scopes"`
+	IsEmergencyContact *bool      `json:"is_emergency_contact"`
+	ValidTo            *time.Time `json:"valid_to"`
+	Notes              *string    `json:"notes"`
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReminderStatus string
+
+const (
+	ReminderStatusPending ReminderStatus = "pending"
+	// ReminderStatusSending reminders are claimed by a worker; a claim that
+	// is not finished by LockedUntil is picked up again
+	ReminderStatusSending   ReminderStatus = "sending"
+	ReminderStatusSent      ReminderStatus = "sent"
+	ReminderStatusFailed    ReminderStatus = "failed"
+	ReminderStatusCancelled ReminderStatus = "cancelled"
+	// ReminderStatusSkipped reminders were due for an appointment that no
+	// longer needed them
+	ReminderStatusSkipped ReminderStatus = "skipped"
+)
+
+// ReminderAction is what a patient answers a reminder with
+type ReminderAction string
+
+const (
+	ReminderActionConfirm ReminderAction = "confirm"
+	ReminderActionCancel  ReminderAction = "cancel"
+)
+
+// ReminderRule sends a reminder on Channel, OffsetMinutes before the
+// appointment starts
+type ReminderRule struct {
+	OffsetMinutes int    `json:"offset_minutes" binding:"required,min=1,max=20160"`
+	Channel       string `json:"channel" binding:"required,oneof=email sms push"`
+}
+
+// ReminderSchedule is the reminders an organization sends before
+// appointments. A schedule with a ServiceID applies to that service and
+// overrides the organization's default schedule, which has none.
+type ReminderSchedule struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	OrganizationID uuid.UUID       `json:"organization_id" db:"organization_id"`
+	ServiceID      *uuid.UUID      `json:"service_id,omitempty" db:"service_id"`
+	RulesJSON      json.RawMessage `json:"-" db:"rules"`
+	Rules          []ReminderRule  `json:"rules" db:"-"`
+	CreatedBy      uuid.UUID       `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// AppointmentReminder is one reminder due to be sent for an appointment
+type AppointmentReminder struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	AppointmentID  uuid.UUID      `json:"appointment_id" db:"appointment_id"`
+	OrganizationID uuid.UUID      `json:"organization_id" db:"organization_id"`
+	PatientID      uuid.UUID      `json:"patient_id" db:"patient_id"`
+	Channel        string         `json:"channel" db:"channel"`
+	OffsetMinutes  int            `json:"offset_minutes" db:"offset_minutes"`
+	SendAt         time.Time      `json:"send_at" db:"send_at"`
+	Status         ReminderStatus `json:"status" db:"status"`
+	Attempts       int            `json:"attempts" db:"attempts"`
+	LastError      *string        `json:"last_error,omitempty" db:"last_error"`
+	NotificationID *uuid.UUID     `json:"notification_id,omitempty" db:"notification_id"`
+	LockedUntil    *time.Time     `json:"-" db:"locked_until"`
+	SentAt         *time.Time     `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// SetReminderScheduleRequest replaces the organization's default schedule,
+// or the service's when ServiceID is set
+type SetReminderScheduleRequest struct {
+	ServiceID *uuid.UUID     `json:"service_id"`
+	Rules     []ReminderRule `json:"rules" binding:"required,max=10,dive"`
+}
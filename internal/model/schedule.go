@@ -0,0 +1,122 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AvailabilityRule is a clinician's recurring weekly working hours at one
+// clinic. Times of day are "HH:MM" in the rule's timezone, so the hours stay
+// put across daylight saving changes. EffectiveUntil is inclusive; a rule
+// without it runs indefinitely.
+type AvailabilityRule struct {
+	ID             uuid.UUID           `json:"id" db:"id"`
+	OrganizationID uuid.UUID           `json:"organization_id" db:"organization_id"`
+	ClinicID       uuid.UUID           `json:"clinic_id" db:"clinic_id"`
+	ClinicianID    uuid.UUID           `json:"clinician_id" db:"clinician_id"`
+	Weekday        time.Weekday        `json:"weekday" db:"weekday"`
+	StartTime      string              `json:"start_time" db:"start_time"`
+	EndTime        string              `json:"end_time" db:"end_time"`
+	Timezone       string              `json:"timezone" db:"timezone"`
+	BreaksJSON     json.RawMessage     `json:"-" db:"breaks"`
+	Breaks         []AvailabilityBreak `json:"breaks" db:"-"`
+	EffectiveFrom  time.Time           `json:"effective_from" db:"effective_from"`
+	EffectiveUntil *time.Time          `json:"effective_until,omitempty" db:"effective_until"`
+	CreatedBy      uuid.UUID           `json:"created_by" db:"created_by"`
+	Version        int                 `json:"version" db:"version"`
+	CreatedAt      time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// AvailabilityBreak is a break within a rule's hours, such as lunch
+type AvailabilityBreak struct {
+	Start string `json:"start" binding:"required"`
+	End   string `json:"end" binding:"required"`
+}
+
+// Dates are "YYYY-MM-DD"
+type CreateAvailabilityRuleRequest struct {
+	ClinicID       uuid.UUID           `json:"clinic_id" binding:"required"`
+	Weekday        *time.Weekday       `json:"weekday" binding:"required,min=0,max=6"`
+	StartTime      string              `json:"start_time" binding:"required"`
+	EndTime        string              `json:"end_time" binding:"required"`
+	Timezone       string              `json:"timezone" binding:"required"`
+	Breaks         []AvailabilityBreak `json:"breaks" binding:"dive"`
+	EffectiveFrom  string              `json:"effective_from" binding:"required"`
+	EffectiveUntil *string             `json:"effective_until"`
+}
+
+// UpdateAvailabilityRuleRequest changes the fields that are set; Breaks
+// replaces all breaks. An empty EffectiveUntil makes the rule open-ended.
+type UpdateAvailabilityRuleRequest struct {
+	StartTime      *string              `json:"start_time"`
+	EndTime        *string              `json:"end_time"`
+	Timezone       *string              `json:"timezone"`
+	Breaks         *[]AvailabilityBreak `json:"breaks" binding:"omitempty,dive"`
+	EffectiveFrom  *string              `json:"effective_from"`
+	EffectiveUntil *string              `json:"effective_until"`
+	Version        int                  `json:"version" binding:"required"`
+}
+
+type ScheduleExceptionKind string
+
+const (
+	// Leave takes the clinician out of their weekly hours, at one clinic or
+	// at all of them
+	ScheduleExceptionLeave ScheduleExceptionKind = "leave"
+	// An extra session adds hours outside the weekly rules, holidays included
+	ScheduleExceptionExtraSession ScheduleExceptionKind = "extra_session"
+)
+
+// ScheduleException is a one-off change to a clinician's availability
+type ScheduleException struct {
+	ID             uuid.UUID             `json:"id" db:"id"`
+	OrganizationID uuid.UUID             `json:"organization_id" db:"organization_id"`
+	ClinicianID    uuid.UUID             `json:"clinician_id" db:"clinician_id"`
+	ClinicID       *uuid.UUID            `json:"clinic_id,omitempty" db:"clinic_id"`
+	Kind           ScheduleExceptionKind `json:"kind" db:"kind"`
+	StartsAt       time.Time             `json:"starts_at" db:"starts_at"`
+	EndsAt         time.Time             `json:"ends_at" db:"ends_at"`
+	Reason         *string               `json:"reason,omitempty" db:"reason"`
+	CreatedBy      uuid.UUID             `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+}
+
+type CreateScheduleExceptionRequest struct {
+	Kind     ScheduleExceptionKind `json:"kind" binding:"required,oneof=leave extra_session"`
+	ClinicID *uuid.UUID            `json:"clinic_id"`
+	StartsAt time.Time             `json:"starts_at" binding:"required"`
+	EndsAt   time.Time             `json:"ends_at" binding:"required"`
+	Reason   *string               `json:"reason"`
+}
+
+// Holiday closes an organization's clinics, or a single clinic, for a day.
+// The day is taken in the timezone of each availability rule.
+type Holiday struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	ClinicID       *uuid.UUID `json:"clinic_id,omitempty" db:"clinic_id"`
+	Date           time.Time  `json:"date" db:"date"`
+	Name           string     `json:"name" db:"name"`
+	CreatedBy      uuid.UUID  `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateHolidayRequest struct {
+	Date     string     `json:"date" binding:"required"`
+	Name     string     `json:"name" binding:"required"`
+	ClinicID *uuid.UUID `json:"clinic_id"`
+}
+
+// AvailabilityWindow is a stretch of time a clinician is available at a
+// clinic, resolved from their rules, exceptions and holidays
+type AvailabilityWindow struct {
+	ClinicID uuid.UUID `json:"clinic_id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	// RuleID or ExceptionID is what the window came from
+	RuleID      *uuid.UUID `json:"rule_id,omitempty"`
+	ExceptionID *uuid.UUID `json:"exception_id,omitempty"`
+}
@@ -8,10 +8,14 @@ import (
 
 type Service struct {
 	Base
-	ClinicID     uuid.UUID `db:"clinic_id" json:"clinic_id"`
-	Name         string    `db:"name" json:"name"`
-	Description  string    `db:"description" json:"description"`
-	Duration     int       `db:"duration" json:"duration"` // in minutes
+	ClinicID    uuid.UUID `db:"clinic_id" json:"clinic_id"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	Duration    int       `db:"duration" json:"duration"` // in minutes
+	// BufferBefore and BufferAfter are minutes kept free around each
+	// booking, for preparation and clean-up
+	BufferBefore int       `db:"buffer_before" json:"buffer_before"`
+	BufferAfter  int       `db:"buffer_after" json:"buffer_after"`
 	Price        float64   `db:"price" json:"price"`
 	IsActive     bool      `db:"is_active" json:"is_active"`
 	RequiresAuth bool      `db:"requires_auth" json:"requires_auth"`
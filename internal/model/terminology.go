@@ -0,0 +1,121 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Code systems are identified by their FHIR canonical URIs
+const (
+	CodeSystemICD10CM = "http://hl7.org/fhir/sid/icd-10-cm"
+	CodeSystemSNOMED  = "http://snomed.info/sct"
+	CodeSystemLOINC   = "http://loinc.org"
+)
+
+var codeSystemAliases = map[string]string{
+	"icd10":     CodeSystemICD10CM,
+	"icd-10":    CodeSystemICD10CM,
+	"icd10cm":   CodeSystemICD10CM,
+	"icd-10-cm": CodeSystemICD10CM,
+	"snomed":    CodeSystemSNOMED,
+	"snomedct":  CodeSystemSNOMED,
+	"sct":       CodeSystemSNOMED,
+	"loinc":     CodeSystemLOINC,
+}
+
+// CanonicalCodeSystem resolves a code system URI or short alias to its
+// canonical URI; unknown systems return "".
+func CanonicalCodeSystem(system string) string {
+	s := strings.ToLower(strings.TrimSpace(system))
+	if canonical, ok := codeSystemAliases[s]; ok {
+		return canonical
+	}
+	switch s {
+	case CodeSystemICD10CM, CodeSystemSNOMED, CodeSystemLOINC:
+		return s
+	}
+	return ""
+}
+
+// Concept is one code of a code system as loaded from its release files
+type Concept struct {
+	System    string         `json:"system" db:"system"`
+	Code      string         `json:"code" db:"code"`
+	Display   string         `json:"display" db:"display"`
+	Synonyms  pq.StringArray `json:"synonyms,omitempty" db:"synonyms"`
+	Active    bool           `json:"active" db:"active"`
+	Version   string         `json:"version" db:"version"`
+	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// TerminologyRelease records a code system release file that was loaded
+type TerminologyRelease struct {
+	ID           int64      `json:"id" db:"id"`
+	System       string     `json:"system" db:"system"`
+	Version      string     `json:"version" db:"version"`
+	File         string     `json:"file" db:"file"`
+	ConceptCount int        `json:"concept_count" db:"concept_count"`
+	LoadedBy     *uuid.UUID `json:"loaded_by,omitempty" db:"loaded_by"`
+	LoadedAt     time.Time  `json:"loaded_at" db:"loaded_at"`
+}
+
+type LoadReleaseRequest struct {
+	System  string `json:"system" binding:"required"`
+	Version string `json:"version" binding:"required"`
+	// File is relative to the configured terminology release directory
+	File string `json:"file" binding:"required"`
+}
+
+// InvalidCode describes one coded entry that failed validation
+type InvalidCode struct {
+	Index  int    `json:"index"`
+	System string `json:"system"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// CodedEntry is a structured diagnosis entry: a code from a code system and
+// the display text it was recorded with
+type CodedEntry struct {
+	System  string `json:"system"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+// ParseCodedEntries returns the structured entries of a diagnosis. Only a
+// JSON array is structured; any other value is free text and has none.
+func ParseCodedEntries(data json.RawMessage) ([]CodedEntry, bool, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "[") {
+		return nil, false, nil
+	}
+	var entries []CodedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, true, err
+	}
+	return entries, true, nil
+}
+
+// CodeReportFilter selects records for a report by code. Code matches
+// itself and, for hierarchical systems such as ICD-10, its descendants.
+type CodeReportFilter struct {
+	// OrganizationID limits the report to the organization's records
+	OrganizationID uuid.UUID
+	System         string
+	Code           string
+	From           *time.Time
+	To             *time.Time
+}
+
+// CodeReportRow counts the records and distinct patients coded with one code
+type CodeReportRow struct {
+	System   string `json:"system" db:"system"`
+	Code     string `json:"code" db:"code"`
+	Display  string `json:"display" db:"display"`
+	Records  int    `json:"records" db:"records"`
+	Patients int    `json:"patients" db:"patients"`
+}
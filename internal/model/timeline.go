@@ -0,0 +1,93 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TimelineKind string
+
+const (
+	TimelineKindAppointment       TimelineKind = "appointment"
+	TimelineKindAppointmentStatus TimelineKind = "appointment_status"
+	TimelineKindMedicalRecord     TimelineKind = "medical_record"
+	TimelineKindNotification      TimelineKind = "notification"
+	TimelineKindInsurance         TimelineKind = "insurance"
+	TimelineKindConsent           TimelineKind = "consent"
+)
+
+var TimelineKinds = []TimelineKind{
+	TimelineKindAppointment,
+	TimelineKindAppointmentStatus,
+	TimelineKindMedicalRecord,
+	TimelineKindNotification,
+	TimelineKindInsurance,
+	TimelineKindConsent,
+}
+
+// TimelineItem is one event in a patient's history. SourceID is the ID of the
+// appointment, record, notification or audit entry the item was built from.
+type TimelineItem struct {
+	Kind        TimelineKind           `json:"kind"`
+	OccurredAt  time.Time              `json:"occurred_at"`
+	SourceID    uuid.UUID              `json:"source_id"`
+	Title       string                 `json:"title"`
+	Summary     string                 `json:"summary,omitempty"`
+	AccessLevel string                 `json:"access_level"`
+	ActorID     *uuid.UUID             `json:"actor_id,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+}
+
+// TimelineFilter is bound from the query string for cursor and page_size
+// only; the handler parses the rest.
+type TimelineFilter struct {
+	From  time.Time      `form:"-"`
+	To    time.Time      `form:"-"`
+	Kinds []TimelineKind `form:"-"`
+	// Cursor is the next_cursor of the previous page; empty for the first
+	Cursor   string `form:"cursor"`
+	PageSize int    `form:"page_size"`
+}
+
+type TimelinePage struct {
+	Items      []*TimelineItem `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PageSize   int             `json:"page_size"`
+}
+
+// TimelineCursor is the position of the last item on a page. Items are
+// ordered newest first, ties broken by ID.
+type TimelineCursor struct {
+	OccurredAt time.Time
+	ID         uuid.UUID
+}
+
+// TimelineQuery is what the timeline repository reads: one page of the
+// merged stream, with every filter applied in the query
+type TimelineQuery struct {
+	Kinds []TimelineKind
+	From  time.Time
+	To    time.Time
+	// RecordAccess applies the medical record access rules
+	RecordAccess *RecordAccess
+	After        *TimelineCursor
+	Limit        int
+}
+
+// TimelineEntry is one row of the merged stream. ID is unique across the
+// stream; SourceID is the appointment, record, notification or audited
+// entity. Action holds the record type or the audited action, and Data the
+// kind's details or the audited changes.
+type TimelineEntry struct {
+	ID          uuid.UUID       `db:"id"`
+	Kind        TimelineKind    `db:"kind"`
+	OccurredAt  time.Time       `db:"occurred_at"`
+	SourceID    uuid.UUID       `db:"source_id"`
+	AccessLevel string          `db:"access_level"`
+	ActorID     *uuid.UUID      `db:"actor_id"`
+	Action      string          `db:"action"`
+	Summary     string          `db:"summary"`
+	Data        json.RawMessage `db:"data"`
+}
@@ -21,6 +21,11 @@ const (
 	UserTypeNurse   = "nurse"
 	UserTypeStaff   = "staff"
 	UserTypePatient = "patient"
+	// UserTypeTrainee is a clinician in training; their signed notes need a
+	// doctor's co-signature
+	UserTypeTrainee = "trainee"
+	// UserTypeCompliance manages legal holds and erasure requests
+	UserTypeCompliance = "compliance"
 )
 
 // User represents a system user
@@ -76,7 +81,7 @@ type UpdateUserRequest struct {
 	Email     *string `json:"email" binding:"omitempty,email"`
 	Phone     *string `json:"phone"`
 	Status    *string `json:"status" binding:"omitempty,oneof=active inactive pending locked"`
-	Type      *string `json:"type" binding:"omitempty,oneof=admin doctor nurse staff patient"`
+	Type      *string `json:"type" binding:"omitempty,oneof=admin doctor nurse staff patient trainee"`
 	Settings  JSONMap `json:"settings"`
 }
 
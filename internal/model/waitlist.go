@@ -0,0 +1,109 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WaitlistStatus string
+
+const (
+	// WaitlistStatusWaiting entries are offered freed slots
+	WaitlistStatusWaiting WaitlistStatus = "waiting"
+	// WaitlistStatusOffered entries hold a pending offer
+	WaitlistStatusOffered WaitlistStatus = "offered"
+	WaitlistStatusBooked  WaitlistStatus = "booked"
+	WaitlistStatusRemoved WaitlistStatus = "removed"
+)
+
+type WaitlistOfferStatus string
+
+const (
+	WaitlistOfferPending  WaitlistOfferStatus = "pending"
+	WaitlistOfferAccepted WaitlistOfferStatus = "accepted"
+	WaitlistOfferDeclined WaitlistOfferStatus = "declined"
+	WaitlistOfferExpired  WaitlistOfferStatus = "expired"
+	// WaitlistOfferSuperseded offers lost the slot to another acceptance
+	WaitlistOfferSuperseded WaitlistOfferStatus = "superseded"
+)
+
+// WaitlistEntry is a patient waiting for an earlier appointment of a service
+// with a clinician at a clinic. Entries are offered freed slots by Priority,
+// highest first, then by when they joined. A slot matches when it starts
+// within one of the preferred windows; an entry without windows takes any
+// time.
+type WaitlistEntry struct {
+	ID               uuid.UUID        `json:"id" db:"id"`
+	OrganizationID   uuid.UUID        `json:"organization_id" db:"organization_id"`
+	ClinicID         uuid.UUID        `json:"clinic_id" db:"clinic_id"`
+	ClinicianID      uuid.UUID        `json:"clinician_id" db:"clinician_id"`
+	ServiceID        uuid.UUID        `json:"service_id" db:"service_id"`
+	PatientID        uuid.UUID        `json:"patient_id" db:"patient_id"`
+	Priority         int              `json:"priority" db:"priority"`
+	Timezone         string           `json:"timezone" db:"timezone"`
+	WindowsJSON      json.RawMessage  `json:"-" db:"preferred_windows"`
+	PreferredWindows []WaitlistWindow `json:"preferred_windows" db:"-"`
+	Notes            string           `json:"notes,omitempty" db:"notes"`
+	Status           WaitlistStatus   `json:"status" db:"status"`
+	CreatedBy        uuid.UUID        `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// WaitlistWindow is a preferred time of day, "HH:MM" in the entry's
+// timezone, on the given weekdays or on any day when there are none
+type WaitlistWindow struct {
+	Weekdays []time.Weekday `json:"weekdays,omitempty" binding:"dive,min=0,max=6"`
+	Start    string         `json:"start" binding:"required"`
+	End      string         `json:"end" binding:"required"`
+}
+
+// WaitlistOffer offers a freed slot to a waitlisted patient. The slot is
+// held for the patient until ExpiresAt. Offers for the same slot share the
+// cancelled appointment they came from; the first to be accepted books it.
+type WaitlistOffer struct {
+	ID                  uuid.UUID           `json:"id" db:"id"`
+	EntryID             uuid.UUID           `json:"entry_id" db:"entry_id"`
+	PatientID           uuid.UUID           `json:"patient_id" db:"patient_id"`
+	ClinicID            uuid.UUID           `json:"clinic_id" db:"clinic_id"`
+	ClinicianID         uuid.UUID           `json:"clinician_id" db:"clinician_id"`
+	ServiceID           uuid.UUID           `json:"service_id" db:"service_id"`
+	SourceAppointmentID uuid.UUID           `json:"source_appointment_id" db:"source_appointment_id"`
+	StartTime           time.Time           `json:"start_time" db:"start_time"`
+	EndTime             time.Time           `json:"end_time" db:"end_time"`
+	Status              WaitlistOfferStatus `json:"status" db:"status"`
+	ExpiresAt           time.Time           `json:"expires_at" db:"expires_at"`
+	AppointmentID       *uuid.UUID          `json:"appointment_id,omitempty" db:"appointment_id"`
+	RespondedAt         *time.Time          `json:"responded_at,omitempty" db:"responded_at"`
+	CreatedAt           time.Time           `json:"created_at" db:"created_at"`
+}
+
+type CreateWaitlistEntryRequest struct {
+	ClinicID         uuid.UUID        `json:"clinic_id" binding:"required"`
+	ClinicianID      uuid.UUID        `json:"clinician_id" binding:"required"`
+	ServiceID        uuid.UUID        `json:"service_id" binding:"required"`
+	PatientID        uuid.UUID        `json:"patient_id" binding:"required"`
+	Priority         int              `json:"priority"`
+	Timezone         string           `json:"timezone" binding:"required"`
+	PreferredWindows []WaitlistWindow `json:"preferred_windows" binding:"dive"`
+	Notes            string           `json:"notes" binding:"max=1000"`
+}
+
+// UpdateWaitlistEntryRequest changes the fields that are set;
+// PreferredWindows replaces all windows
+type UpdateWaitlistEntryRequest struct {
+	Priority         *int              `json:"priority"`
+	Timezone         *string           `json:"timezone"`
+	PreferredWindows *[]WaitlistWindow `json:"preferred_windows" binding:"omitempty,dive"`
+	Notes            *string           `json:"notes" binding:"omitempty,max=1000"`
+}
+
+// WaitlistFilters narrows a waitlist to a clinic, clinician or service
+type WaitlistFilters struct {
+	ClinicID    *uuid.UUID
+	ClinicianID *uuid.UUID
+	ServiceID   *uuid.UUID
+	Status      WaitlistStatus
+}
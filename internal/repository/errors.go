@@ -0,0 +1,20 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrDuplicate is returned when a write violates a uniqueness constraint
+	ErrDuplicate = errors.New("duplicate record")
+	// ErrVersionConflict is returned when a versioned write was based on a
+	// version that is no longer current
+	ErrVersionConflict = errors.New("record was modified by another update")
+	// ErrInvalidReference is returned when a write refers to a record that
+	// does not exist
+	ErrInvalidReference = errors.New("referenced record does not exist")
+	// ErrSlotTaken is returned when a booking overlaps one the clinician
+	// already has, or a slot hold
+	ErrSlotTaken = errors.New("slot is no longer available")
+	// ErrHoldNotFound is returned when a slot hold does not exist, has
+	// expired or does not cover the booking made with it
+	ErrHoldNotFound = errors.New("slot hold not found or expired")
+)
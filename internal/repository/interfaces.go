@@ -38,7 +38,94 @@ type (
 		FindConflictingAppointments(ctx context.Context, staffID uuid.UUID, start, end time.Time) ([]*model.Appointment, error)
 		CheckConflicts(ctx context.Context, userID uuid.UUID, startTime, endTime time.Time, excludeID *uuid.UUID) (bool, error)
 		GetClinicianAppointments(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) ([]*model.Appointment, error)
-		GetClinicianSchedule(ctx context.Context, clinicianID uuid.UUID, date time.Time) ([]*model.TimeSlot, error)
+		// ListBusyPeriods returns the clinicians' booked time overlapping
+		// [from, to), widened by each booked service's buffers. Slots held
+		// by slot holds and pending waitlist offers are busy too.
+		ListBusyPeriods(ctx context.Context, clinicianIDs []uuid.UUID, from, to time.Time) ([]*model.BusyPeriod, error)
+		// CreateHold returns ErrSlotTaken when the slot overlaps a booking,
+		// a live hold or a pending waitlist offer
+		CreateHold(ctx context.Context, hold *model.AppointmentHold) error
+		GetHold(ctx context.Context, id uuid.UUID) (*model.AppointmentHold, error)
+		DeleteHold(ctx context.Context, id uuid.UUID) error
+		// CreateFromHold books the appointment and consumes the hold in one
+		// transaction. It returns ErrHoldNotFound when the hold has expired
+		// or does not cover the appointment.
+		CreateFromHold(ctx context.Context, appointment *model.Appointment, holdID uuid.UUID) error
+	}
+
+	// AppointmentSeriesRepository keeps recurring series. Writes that touch
+	// a series and its occurrences happen in one transaction.
+	AppointmentSeriesRepository interface {
+		Create(ctx context.Context, series *model.AppointmentSeries, occurrences []*model.Appointment) error
+		Get(ctx context.Context, id uuid.UUID) (*model.AppointmentSeries, error)
+		ListOccurrences(ctx context.Context, seriesID uuid.UUID) ([]*model.Appointment, error)
+		Update(ctx context.Context, series *model.AppointmentSeries, occurrences []*model.Appointment) error
+		// Split saves the truncated original, creates next and saves the
+		// occurrences, which the caller has moved to next
+		Split(ctx context.Context, original, next *model.AppointmentSeries, occurrences []*model.Appointment) error
+	}
+
+	// WaitlistRepository keeps waitlist entries and the offers of freed
+	// slots made to them. Offer responses return ErrVersionConflict when the
+	// offer is no longer pending.
+	WaitlistRepository interface {
+		CreateEntry(ctx context.Context, entry *model.WaitlistEntry) error
+		GetEntry(ctx context.Context, id uuid.UUID) (*model.WaitlistEntry, error)
+		ListEntries(ctx context.Context, organizationID uuid.UUID, filters *model.WaitlistFilters) ([]*model.WaitlistEntry, error)
+		UpdateEntry(ctx context.Context, entry *model.WaitlistEntry) error
+		// ListCandidates returns the waiting entries for the slot freed by
+		// the cancelled appointment, in priority order, leaving out the
+		// patient who cancelled and entries already offered the slot
+		ListCandidates(ctx context.Context, slot *model.Appointment) ([]*model.WaitlistEntry, error)
+
+		// CreateOffers saves up to limit offers of one slot, in order, and
+		// marks their entries offered. Offers whose entry has meanwhile been
+		// offered a slot or left the waitlist are skipped. It returns the
+		// offers saved, or ErrSlotTaken when the slot has since been booked
+		// or held.
+		CreateOffers(ctx context.Context, offers []*model.WaitlistOffer, limit int) ([]*model.WaitlistOffer, error)
+		GetOffer(ctx context.Context, id uuid.UUID) (*model.WaitlistOffer, error)
+		ListOffers(ctx context.Context, entryID uuid.UUID) ([]*model.WaitlistOffer, error)
+		// AcceptOffer books the appointment for the offer, supersedes the
+		// slot's other offers and returns their entries to the waitlist. It
+		// returns ErrSlotTaken if the slot was booked in the meantime.
+		AcceptOffer(ctx context.Context, offerID uuid.UUID, appointment *model.Appointment) error
+		DeclineOffer(ctx context.Context, offerID uuid.UUID) error
+		// ExpireOffers expires pending offers past their expiry, returns
+		// their entries to the waitlist and returns the expired offers
+		ExpireOffers(ctx context.Context, now time.Time) ([]*model.WaitlistOffer, error)
+		// HasOpenOffers reports whether the slot freed by the appointment
+		// has a pending or accepted offer
+		HasOpenOffers(ctx context.Context, sourceAppointmentID uuid.UUID) (bool, error)
+	}
+
+	// ReminderRepository keeps reminder schedules and the reminders due for
+	// appointments
+	ReminderRepository interface {
+		// SaveSchedule replaces the schedule with the same organization and
+		// service, if there is one
+		SaveSchedule(ctx context.Context, schedule *model.ReminderSchedule) error
+		GetSchedule(ctx context.Context, id uuid.UUID) (*model.ReminderSchedule, error)
+		ListSchedules(ctx context.Context, organizationID uuid.UUID) ([]*model.ReminderSchedule, error)
+		DeleteSchedule(ctx context.Context, id uuid.UUID) error
+		// ResolveSchedule returns the service's schedule, or the
+		// organization's default when the service has none, or nil
+		ResolveSchedule(ctx context.Context, organizationID, serviceID uuid.UUID) (*model.ReminderSchedule, error)
+
+		// ReplaceReminders cancels the appointment's pending reminders and
+		// saves the new ones in their place
+		ReplaceReminders(ctx context.Context, appointmentID uuid.UUID, reminders []*model.AppointmentReminder) error
+		CancelReminders(ctx context.Context, appointmentID uuid.UUID) error
+		ListReminders(ctx context.Context, appointmentID uuid.UUID) ([]*model.AppointmentReminder, error)
+		// ClaimDue marks up to limit due reminders as sending until
+		// lockedUntil and returns them. Claims that lapsed are claimed
+		// again.
+		ClaimDue(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*model.AppointmentReminder, error)
+		MarkSent(ctx context.Context, id, notificationID uuid.UUID) error
+		// MarkFailed records the error and retries the reminder at retryAt,
+		// or gives up on it when retryAt is nil
+		MarkFailed(ctx context.Context, id uuid.UUID, reason string, retryAt *time.Time) error
+		MarkSkipped(ctx context.Context, id uuid.UUID, reason string) error
 	}
 
 	PatientRepository interface {
@@ -50,6 +137,8 @@ type (
 		DeletePatientAppointments(ctx context.Context, patientID uuid.UUID) error
 		AddMedicalRecord(ctx context.Context, record *model.MedicalRecord) error
 		GetMedicalRecords(ctx context.Context, patientID uuid.UUID) ([]*model.MedicalRecord, error)
+		UpdateEmergencyContact(ctx context.Context, patientID uuid.UUID, contact *model.EmergencyContact) error
+		EncryptPlaintextBatch(ctx context.Context, limit int) (int, error)
 	}
 
 	RBACRepository interface {
@@ -84,6 +173,7 @@ type (
 		GetAggregateStats(ctx context.Context, filters map[string]interface{}) (*model.AggregateStats, error)
 		Cleanup(ctx context.Context, before time.Time) (int64, error)
 		DeleteBefore(ctx context.Context, cutoff time.Time) error
+		ListByEntityIDs(ctx context.Context, entityIDs []uuid.UUID) ([]*model.AuditLog, error)
 	}
 
 	TokenRepository interface {
@@ -137,20 +227,252 @@ type (
 	MedicalRecordRepository interface {
 		Get(ctx context.Context, id uuid.UUID) (*model.MedicalRecord, error)
 		List(ctx context.Context, patientID uuid.UUID, filters *model.RecordFilters) ([]*model.MedicalRecord, error)
-		CreateWithAudit(ctx context.Context, record *model.MedicalRecord) error
-		UpdateWithAudit(ctx context.Context, record *model.MedicalRecord) error
+		// CreateWithAudit creates the records in one transaction
+		CreateWithAudit(ctx context.Context, records ...*model.MedicalRecord) error
+		UpdateWithAudit(ctx context.Context, record *model.MedicalRecord, rev *model.RecordRevision) error
 		Delete(ctx context.Context, id uuid.UUID) error
+		ListVersions(ctx context.Context, recordID uuid.UUID) ([]*model.MedicalRecordVersion, error)
+		GetVersion(ctx context.Context, recordID uuid.UUID, version int) (*model.MedicalRecordVersion, error)
+		CreateAmendment(ctx context.Context, amendment *model.AmendmentRequest) error
+		GetAmendment(ctx context.Context, id uuid.UUID) (*model.AmendmentRequest, error)
+		ListAmendments(ctx context.Context, patientID uuid.UUID, access *model.RecordAccess, status model.AmendmentStatus) ([]*model.AmendmentRequest, error)
+		DecideAmendment(ctx context.Context, amendment *model.AmendmentRequest, record *model.MedicalRecord) error
+		ReportByCode(ctx context.Context, filter *model.CodeReportFilter, includeDescendants bool) ([]*model.CodeReportRow, error)
+		// ReencryptBatch hands rows not sealed under their organization's
+		// active data key to reencrypt and stores the fields it sets
+		ReencryptBatch(ctx context.Context, limit int, reencrypt func(*model.EncryptedFields) error) (int, error)
+	}
+
+	TimelineRepository interface {
+		// List returns up to query.Limit entries of the patient's timeline,
+		// newest first
+		List(ctx context.Context, patientID uuid.UUID, query *model.TimelineQuery) ([]*model.TimelineEntry, error)
+	}
+
+	ClinicalNoteRepository interface {
+		// CreateTemplate returns ErrDuplicate when the organization already
+		// has a template with the name
+		CreateTemplate(ctx context.Context, template *model.NoteTemplate) error
+		GetTemplate(ctx context.Context, id uuid.UUID) (*model.NoteTemplate, error)
+		ListTemplates(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]*model.NoteTemplate, error)
+		UpdateTemplate(ctx context.Context, template *model.NoteTemplate) error
+		Create(ctx context.Context, note *model.ClinicalNote) error
+		Get(ctx context.Context, id uuid.UUID) (*model.ClinicalNote, error)
+		List(ctx context.Context, patientID uuid.UUID, status model.NoteStatus) ([]*model.ClinicalNote, error)
+		// Update saves the note if it is still at note.Version, otherwise it
+		// returns ErrVersionConflict
+		Update(ctx context.Context, note *model.ClinicalNote) error
+		CreateAddendum(ctx context.Context, addendum *model.NoteAddendum) error
+		ListAddenda(ctx context.Context, noteID uuid.UUID) ([]*model.NoteAddendum, error)
+		ReencryptBatch(ctx context.Context, limit int, reencrypt func(*model.EncryptedContent) error) (int, error)
+	}
+
+	CCDARepository interface {
+		CreateImport(ctx context.Context, imp *model.CCDAImport) error
+		GetImport(ctx context.Context, id uuid.UUID) (*model.CCDAImport, error)
+		ListImports(ctx context.Context, patientID uuid.UUID, status model.CCDAImportStatus) ([]*model.CCDAImport, error)
+		UpdateImport(ctx context.Context, imp *model.CCDAImport) error
+		ReencryptBatch(ctx context.Context, limit int, reencrypt func(*model.EncryptedContent) error) (int, error)
+	}
+
+	// ScheduleRepository keeps clinicians' weekly availability, their one-off
+	// exceptions and organization holidays. The list methods return what
+	// overlaps [from, to).
+	ScheduleRepository interface {
+		CreateAvailabilityRule(ctx context.Context, rule *model.AvailabilityRule) error
+		GetAvailabilityRule(ctx context.Context, id uuid.UUID) (*model.AvailabilityRule, error)
+		ListAvailabilityRules(ctx context.Context, clinicianID uuid.UUID) ([]*model.AvailabilityRule, error)
+		UpdateAvailabilityRule(ctx context.Context, rule *model.AvailabilityRule) error
+		DeleteAvailabilityRule(ctx context.Context, id uuid.UUID) error
+
+		CreateException(ctx context.Context, e *model.ScheduleException) error
+		GetException(ctx context.Context, id uuid.UUID) (*model.ScheduleException, error)
+		ListExceptions(ctx context.Context, clinicianID uuid.UUID, from, to time.Time) ([]*model.ScheduleException, error)
+		DeleteException(ctx context.Context, id uuid.UUID) error
+
+		CreateHoliday(ctx context.Context, h *model.Holiday) error
+		GetHoliday(ctx context.Context, id uuid.UUID) (*model.Holiday, error)
+		ListHolidays(ctx context.Context, organizationID uuid.UUID, from, to time.Time) ([]*model.Holiday, error)
+		DeleteHoliday(ctx context.Context, id uuid.UUID) error
+	}
+
+	ClinicalListRepository interface {
+		CreateProblem(ctx context.Context, problem *model.Problem) error
+		GetProblem(ctx context.Context, id uuid.UUID) (*model.Problem, error)
+		// FindProblem returns the patient's entry for a code, or nil
+		FindProblem(ctx context.Context, patientID uuid.UUID, system, code string) (*model.Problem, error)
+		ListProblems(ctx context.Context, patientID uuid.UUID, status model.ClinicalStatus) ([]*model.Problem, error)
+		UpdateProblem(ctx context.Context, problem *model.Problem) error
+		CreateAllergy(ctx context.Context, allergy *model.Allergy) error
+		GetAllergy(ctx context.Context, id uuid.UUID) (*model.Allergy, error)
+		FindAllergy(ctx context.Context, patientID uuid.UUID, system, code string) (*model.Allergy, error)
+		ListAllergies(ctx context.Context, patientID uuid.UUID, status model.ClinicalStatus) ([]*model.Allergy, error)
+		UpdateAllergy(ctx context.Context, allergy *model.Allergy) error
+	}
+
+	ObservationRepository interface {
+		CreateBatch(ctx context.Context, observations []*model.Observation) error
+		Get(ctx context.Context, id uuid.UUID) (*model.Observation, error)
+		List(ctx context.Context, patientID uuid.UUID, filter *model.ObservationFilter) ([]*model.Observation, error)
+		UpdateStatus(ctx context.Context, observation *model.Observation) error
+		// Trend buckets a patient's observations of one type; observations
+		// entered in error are left out
+		Trend(ctx context.Context, patientID uuid.UUID, obsType model.ObservationType, from, to time.Time, interval time.Duration) ([]*model.TrendPoint, error)
+		// ListReferenceRanges returns the default ranges and, when
+		// organizationID is set, that organization's own
+		ListReferenceRanges(ctx context.Context, organizationID *uuid.UUID, obsType model.ObservationType) ([]*model.ReferenceRange, error)
+		CreateReferenceRange(ctx context.Context, rng *model.ReferenceRange) error
+		GetReferenceRange(ctx context.Context, id uuid.UUID) (*model.ReferenceRange, error)
+		DeleteReferenceRange(ctx context.Context, id uuid.UUID) error
+	}
+
+	ReferralRepository interface {
+		Create(ctx context.Context, referral *model.Referral) error
+		Get(ctx context.Context, id uuid.UUID) (*model.Referral, error)
+		List(ctx context.Context, filter *model.ReferralFilter) ([]*model.Referral, error)
+		// Update saves the referral if its status is still from, otherwise it
+		// returns ErrVersionConflict
+		Update(ctx context.Context, referral *model.Referral, from model.ReferralStatus) error
+	}
+
+	DataKeyRepository interface {
+		Get(ctx context.Context, id uuid.UUID) (*model.DataKey, error)
+		// GetActive returns sql.ErrNoRows when the organization has no key yet
+		GetActive(ctx context.Context, orgID uuid.UUID) (*model.DataKey, error)
+		List(ctx context.Context, orgID uuid.UUID) ([]*model.DataKey, error)
+		// Create adds the organization's first active key; ErrDuplicate means
+		// another instance created one first
+		Create(ctx context.Context, key *model.DataKey) error
+		// Rotate retires the active key and makes key the active one
+		Rotate(ctx context.Context, key *model.DataKey) error
+		// ListWrappedWithout returns keys wrapped by any key-encryption key
+		// other than kekID
+		ListWrappedWithout(ctx context.Context, kekID string, limit int) ([]*model.DataKey, error)
+		UpdateWrapping(ctx context.Context, key *model.DataKey) error
+	}
+
+	CareTeamRepository interface {
+		// Add puts the user on the patient's care team, updating the role of
+		// an existing member
+		Add(ctx context.Context, member *model.CareTeamMember) error
+		Remove(ctx context.Context, patientID, userID uuid.UUID) error
+		List(ctx context.Context, patientID uuid.UUID) ([]*model.CareTeamMember, error)
+		IsMember(ctx context.Context, patientID, userID uuid.UUID) (bool, error)
+		// CanManage reports whether the user belongs to the patient's
+		// organization and is one of its administrators or on the care team
+		CanManage(ctx context.Context, patientID, userID uuid.UUID) (bool, error)
+	}
+
+	TerminologyRepository interface {
+		ImportRelease(ctx context.Context, release *model.TerminologyRelease, each func(yield func(*model.Concept) error) error) error
+		GetConcept(ctx context.Context, system, code string) (*model.Concept, error)
+		GetConcepts(ctx context.Context, system string, codes []string) ([]*model.Concept, error)
+		SearchConcepts(ctx context.Context, system, query string, limit int) ([]*model.Concept, error)
+		ListReleases(ctx context.Context, system string) ([]*model.TerminologyRelease, error)
+	}
+
+	HL7Repository interface {
+		Create(ctx context.Context, msg *model.HL7Message) error
+		Get(ctx context.Context, id uuid.UUID) (*model.HL7Message, error)
+		FindByControlID(ctx context.Context, sendingApplication, sendingFacility, controlID string) (*model.HL7Message, error)
+		Update(ctx context.Context, msg *model.HL7Message) error
+		List(ctx context.Context, status model.HL7MessageStatus, limit int) ([]*model.HL7Message, error)
+	}
+
+	PrescriptionRepository interface {
+		Create(ctx context.Context, prescription *model.Prescription) error
+		Get(ctx context.Context, id uuid.UUID) (*model.Prescription, error)
+		UpdateStatus(ctx context.Context, prescription *model.Prescription) error
+		ListByPatient(ctx context.Context, patientID uuid.UUID, status model.PrescriptionStatus) ([]*model.Prescription, error)
+		FindDrugInteractions(ctx context.Context, patientID uuid.UUID, drugCode string) ([]*model.InteractionWarning, error)
+		FindAllergyInteractions(ctx context.Context, patientID uuid.UUID, drugCode string) ([]*model.InteractionWarning, error)
+		ReplaceDrugInteractions(ctx context.Context, dataset *model.InteractionDataset, interactions []*model.DrugInteraction) error
+		ReplaceAllergyInteractions(ctx context.Context, dataset *model.InteractionDataset, interactions []*model.DrugAllergyInteraction) error
+		ListDatasets(ctx context.Context) ([]*model.InteractionDataset, error)
 	}
 
 	NotificationRepository interface {
 		Create(ctx context.Context, notification *model.Notification) error
 		Update(ctx context.Context, notification *model.Notification) error
+		ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.Notification, error)
+	}
+
+	ServiceRepository interface {
+		Get(ctx context.Context, id uuid.UUID) (*model.Service, error)
 	}
 
 	ClinicianRepository interface {
 		Get(ctx context.Context, id uuid.UUID) (*model.Clinician, error)
 	}
 
+	ComplianceRepository interface {
+		Create(ctx context.Context, req *model.ComplianceRequest) error
+		Get(ctx context.Context, id uuid.UUID) (*model.ComplianceRequest, error)
+		Update(ctx context.Context, req *model.ComplianceRequest) error
+		ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.ComplianceRequest, error)
+		ClaimPending(ctx context.Context, limit int) ([]*model.ComplianceRequest, error)
+	}
+
+	ConsentRepository interface {
+		Create(ctx context.Context, consent *model.Consent) error
+		Get(ctx context.Context, id uuid.UUID) (*model.Consent, error)
+		Update(ctx context.Context, consent *model.Consent) error
+		ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.Consent, error)
+	}
+
+	InsuranceRepository interface {
+		CreateCoverage(ctx context.Context, coverage *model.InsuranceCoverage, changedBy uuid.UUID) error
+		GetCoverage(ctx context.Context, id uuid.UUID) (*model.InsuranceCoverage, error)
+		UpdateCoverage(ctx context.Context, coverage *model.InsuranceCoverage, changedBy uuid.UUID) error
+		ListCoverages(ctx context.Context, patientID uuid.UUID, activeOnly bool) ([]*model.InsuranceCoverage, error)
+		ListCoverageHistory(ctx context.Context, coverageID uuid.UUID) ([]*model.InsuranceCoverageHistory, error)
+		CreateEligibilityCheck(ctx context.Context, check *model.EligibilityCheck) error
+		ListEligibilityChecks(ctx context.Context, coverageID uuid.UUID) ([]*model.EligibilityCheck, error)
+	}
+
+	RelationshipRepository interface {
+		Create(ctx context.Context, rel *model.PatientRelationship) error
+		Get(ctx context.Context, id uuid.UUID) (*model.PatientRelationship, error)
+		Update(ctx context.Context, rel *model.PatientRelationship) error
+		ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.PatientRelationship, error)
+		ListByRelatedUser(ctx context.Context, userID uuid.UUID) ([]*model.PatientRelationship, error)
+		FindActive(ctx context.Context, patientID, userID uuid.UUID, at time.Time) ([]*model.PatientRelationship, error)
+	}
+
+	IdentifierRepository interface {
+		Create(ctx context.Context, identifier *model.PatientIdentifier) error
+		Get(ctx context.Context, id uuid.UUID) (*model.PatientIdentifier, error)
+		Update(ctx context.Context, identifier *model.PatientIdentifier) error
+		ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.PatientIdentifier, error)
+		FindByValue(ctx context.Context, organizationID uuid.UUID, system, value string) ([]*model.PatientIdentifier, error)
+		GetMRNConfig(ctx context.Context, clinicID uuid.UUID) (*model.MRNConfig, error)
+		// SaveMRNConfig moves the sequence only when nextSequence is given,
+		// and never back below numbers already issued
+		SaveMRNConfig(ctx context.Context, config *model.MRNConfig, nextSequence *int64) error
+		NextMRNSequence(ctx context.Context, clinicID uuid.UUID) (int64, error)
+	}
+
+	DocumentRepository interface {
+		Create(ctx context.Context, doc *model.Document) error
+		Get(ctx context.Context, id uuid.UUID) (*model.Document, error)
+		ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.Document, error)
+		// ListStored returns every document of the patient whose row is
+		// still on file, hidden ones included
+		ListStored(ctx context.Context, patientID uuid.UUID) ([]*model.Document, error)
+		SoftDelete(ctx context.Context, id uuid.UUID) error
+	}
+
+	LegalHoldRepository interface {
+		Create(ctx context.Context, hold *model.LegalHold) error
+		Get(ctx context.Context, id uuid.UUID) (*model.LegalHold, error)
+		Release(ctx context.Context, hold *model.LegalHold) error
+		ListByPatient(ctx context.Context, patientID uuid.UUID, activeOnly bool) ([]*model.LegalHold, error)
+	}
+
+	ErasureRepository interface {
+		Execute(ctx context.Context, plan *model.ErasurePlan) ([]model.ErasureOutcome, error)
+	}
+
 	PermissionRepository interface {
 		Create(ctx context.Context, permission *model.Permission) error
 		Get(ctx context.Context, id uuid.UUID) (*model.Permission, error)
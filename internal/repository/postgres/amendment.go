@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+func (r *medicalRecordRepository) CreateAmendment(ctx context.Context, amendment *model.AmendmentRequest) error {
+	query := `
+		INSERT INTO medical_record_amendments (
+			id, record_id, patient_id, requested_by, base_version, justification,
+			proposed_changes, status, due_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	amendment.ID = uuid.New()
+	amendment.CreatedAt = time.Now()
+	amendment.UpdatedAt = amendment.CreatedAt
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		amendment.ID,
+		amendment.RecordID,
+		amendment.PatientID,
+		amendment.RequestedBy,
+		amendment.BaseVersion,
+		amendment.Justification,
+		amendment.ProposedChanges,
+		amendment.Status,
+		amendment.DueAt,
+		amendment.CreatedAt,
+		amendment.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create amendment request: %w", err)
+	}
+	return nil
+}
+
+func (r *medicalRecordRepository) GetAmendment(ctx context.Context, id uuid.UUID) (*model.AmendmentRequest, error) {
+	query := `SELECT * FROM medical_record_amendments WHERE id = $1`
+	var amendment model.AmendmentRequest
+	if err := r.GetDB().GetContext(ctx, &amendment, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get amendment request: %w", err)
+	}
+	return &amendment, nil
+}
+
+// ListAmendments lists the patient's amendment requests. access limits them
+// to the records the reader may see, as in List.
+func (r *medicalRecordRepository) ListAmendments(ctx context.Context, patientID uuid.UUID, access *model.RecordAccess, status model.AmendmentStatus) ([]*model.AmendmentRequest, error) {
+	query := `
+		SELECT a.* FROM medical_record_amendments a
+		JOIN medical_records m ON m.id = a.record_id
+		WHERE a.patient_id = $1`
+	args := []interface{}{patientID}
+
+	if status != "" {
+		query += fmt.Sprintf(" AND a.status = $%d", len(args)+1)
+		args = append(args, status)
+	}
+
+	if access != nil {
+		if access.PublicOnly {
+			query += " AND m.access_level = 'public'"
+		} else {
+			query += fmt.Sprintf(` AND (m.access_level = 'public' OR EXISTS (
+				SELECT 1 FROM care_team_members ct
+				WHERE ct.patient_id = m.patient_id AND ct.user_id = $%d
+			))`, len(args)+1)
+			args = append(args, access.UserID)
+			if !access.IncludeHIPAA {
+				query += " AND m.access_level IS DISTINCT FROM 'hipaa'"
+			}
+		}
+	}
+
+	query += " ORDER BY a.created_at DESC"
+
+	var amendments []*model.AmendmentRequest
+	if err := r.GetDB().SelectContext(ctx, &amendments, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list amendment requests: %w", err)
+	}
+	return amendments, nil
+}
+
+// DecideAmendment records the decision on a pending amendment. For an
+// accepted amendment, record carries the corrected content; the new version
+// and the decision are written in one transaction so neither exists without
+// the other.
+func (r *medicalRecordRepository) DecideAmendment(ctx context.Context, amendment *model.AmendmentRequest, record *model.MedicalRecord) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if record != nil {
+			rev := &model.RecordRevision{
+				AuthorID:    *amendment.ReviewedBy,
+				Reason:      fmt.Sprintf("amendment %s accepted: %s", amendment.ID, *amendment.DecisionReason),
+				AmendmentID: &amendment.ID,
+			}
+			if err := r.updateVersioned(ctx, tx, record, rev); err != nil {
+				return err
+			}
+			amendment.ResultingVersion = &record.Version
+		}
+
+		amendment.UpdatedAt = time.Now()
+		query := `
+			UPDATE medical_record_amendments SET
+				status = $1,
+				reviewed_by = $2,
+				reviewed_at = $3,
+				decision_reason = $4,
+				resulting_version = $5,
+				updated_at = $6
+			WHERE id = $7 AND status = $8
+		`
+		result, err := tx.ExecContext(ctx, query,
+			amendment.Status,
+			amendment.ReviewedBy,
+			amendment.ReviewedAt,
+			amendment.DecisionReason,
+			amendment.ResultingVersion,
+			amendment.UpdatedAt,
+			amendment.ID,
+			model.AmendmentStatusPending,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update amendment request: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return fmt.Errorf("amendment request is no longer pending")
+		}
+		return nil
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/lib/pq"
 )
 
 type appointmentRepository struct {
@@ -23,34 +24,72 @@ func NewAppointmentRepository(base BaseRepository) repository.AppointmentReposit
 
 func (r *appointmentRepository) Create(ctx context.Context, appointment *model.Appointment) error {
 	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
-		query := `
-			INSERT INTO appointments (
-				id, patient_id, clinic_id, service_id, clinician_id,
-				start_time, end_time, status, notes, region_code,
-				created_at, updated_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		`
-
 		appointment.ID = uuid.New()
 		appointment.CreatedAt = time.Now()
 		appointment.UpdatedAt = time.Now()
+		return insertAppointment(ctx, tx, appointment, r.GetRegionFromContext(ctx))
+	})
+}
 
-		_, err := tx.ExecContext(ctx, query,
-			appointment.ID,
-			appointment.PatientID,
-			appointment.ClinicID,
-			appointment.ServiceID,
-			appointment.ClinicianID,
-			appointment.StartTime,
-			appointment.EndTime,
-			appointment.Status,
-			appointment.Notes,
-			r.GetRegionFromContext(ctx),
-			appointment.CreatedAt,
-			appointment.UpdatedAt,
-		)
+// insertAppointment writes the appointment as it is, ID included, so series
+// occurrences can be created in the same transaction as their series. An
+// overlapping booking or live hold gives ErrSlotTaken.
+func insertAppointment(ctx context.Context, tx *sqlx.Tx, appointment *model.Appointment, regionCode string) error {
+	if err := lockClinician(ctx, tx, appointment.ClinicianID); err != nil {
 		return err
-	})
+	}
+
+	// Live holds and pending waitlist offers keep their slots until they
+	// expire or are answered
+	var held bool
+	if err := tx.GetContext(ctx, &held, `
+		SELECT EXISTS (
+			SELECT 1 FROM appointment_holds
+			WHERE clinician_id = $1
+			AND expires_at > NOW()
+			AND start_time < $3 AND end_time > $2
+		) OR EXISTS (
+			SELECT 1 FROM waitlist_offers
+			WHERE clinician_id = $1
+			AND status = 'pending'
+			AND expires_at > NOW()
+			AND start_time < $3 AND end_time > $2
+		)
+	`, appointment.ClinicianID, appointment.StartTime, appointment.EndTime); err != nil {
+		return fmt.Errorf("failed to check slot holds: %w", err)
+	}
+	if held {
+		return repository.ErrSlotTaken
+	}
+
+	query := `
+		INSERT INTO appointments (
+			id, patient_id, clinic_id, service_id, clinician_id,
+			start_time, end_time, status, notes, region_code,
+			series_id, occurrence_start, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := tx.ExecContext(ctx, query,
+		appointment.ID,
+		appointment.PatientID,
+		appointment.ClinicID,
+		appointment.ServiceID,
+		appointment.ClinicianID,
+		appointment.StartTime,
+		appointment.EndTime,
+		appointment.Status,
+		appointment.Notes,
+		regionCode,
+		appointment.SeriesID,
+		appointment.OccurrenceStart,
+		appointment.CreatedAt,
+		appointment.UpdatedAt,
+	)
+	if isExclusionViolation(err) {
+		return repository.ErrSlotTaken
+	}
+	return err
 }
 
 func (r *appointmentRepository) Get(ctx context.Context, id uuid.UUID) (*model.Appointment, error) {
@@ -88,6 +127,9 @@ func (r *appointmentRepository) Update(ctx context.Context, appointment *model.A
 		appointment.ID,
 	)
 	if err != nil {
+		if isExclusionViolation(err) {
+			return repository.ErrSlotTaken
+		}
 		return fmt.Errorf("failed to update appointment: %w", err)
 	}
 
@@ -152,6 +194,11 @@ func (r *appointmentRepository) List(ctx context.Context, filters *model.Appoint
 		args = append(args, filters.ClinicianID)
 	}
 
+	if filters.PatientID != uuid.Nil {
+		query += fmt.Sprintf(" AND patient_id = $%d", len(args)+1)
+		args = append(args, filters.PatientID)
+	}
+
 	query += " ORDER BY start_time ASC"
 
 	var appointments []*model.Appointment
@@ -189,11 +236,25 @@ func (r *appointmentRepository) FindConflictingAppointments(ctx context.Context,
 	return appointments, nil
 }
 
+// CheckConflicts also counts live slot holds and slots held by pending
+// waitlist offers, so a held slot cannot be booked from under its holder
 func (r *appointmentRepository) CheckConflicts(ctx context.Context, userID uuid.UUID, startTime, endTime time.Time, excludeID *uuid.UUID) (bool, error) {
 	query := `
 		SELECT EXISTS (
+			SELECT 1 FROM appointment_holds
+			WHERE clinician_id = $1
+			AND expires_at > NOW()
+			AND start_time < $3 AND end_time > $2
+		) OR EXISTS (
+			SELECT 1 FROM waitlist_offers
+			WHERE clinician_id = $1
+			AND status = 'pending'
+			AND expires_at > NOW()
+			AND start_time < $3 AND end_time > $2
+		) OR EXISTS (
 			SELECT 1 FROM appointments
-			WHERE user_id = $1
+			WHERE clinician_id = $1
+			AND deleted_at IS NULL
 			AND status NOT IN ('cancelled', 'completed')
 			AND (
 				(start_time <= $2 AND end_time > $2)
@@ -220,13 +281,11 @@ func (r *appointmentRepository) CheckConflicts(ctx context.Context, userID uuid.
 
 func (r *appointmentRepository) GetClinicianAppointments(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) ([]*model.Appointment, error) {
 	query := `
-		SELECT id, clinic_id, user_id, patient_id,
-			   start_time, end_time, status, notes,
-			   created_at, updated_at
-		FROM appointments
-		WHERE user_id = $1
-		AND start_time >= $2
-		AND end_time <= $3
+		SELECT * FROM appointments
+		WHERE clinician_id = $1
+		AND deleted_at IS NULL
+		AND start_time < $3
+		AND end_time > $2
 		AND status NOT IN ('cancelled', 'completed')
 		ORDER BY start_time ASC
 	`
@@ -238,15 +297,41 @@ func (r *appointmentRepository) GetClinicianAppointments(ctx context.Context, us
 	return appointments, nil
 }
 
-func (r *appointmentRepository) GetClinicianSchedule(ctx context.Context, clinicianID uuid.UUID, date time.Time) ([]*model.TimeSlot, error) {
+func (r *appointmentRepository) ListBusyPeriods(ctx context.Context, clinicianIDs []uuid.UUID, from, to time.Time) ([]*model.BusyPeriod, error) {
 	query := `
-		SELECT start_time, end_time 
-		FROM clinician_schedules 
-		WHERE clinician_id = $1 
-		AND date(start_time) = date($2)
-		ORDER BY start_time
+		SELECT * FROM (
+			SELECT a.clinician_id,
+				a.start_time - make_interval(mins => COALESCE(s.buffer_before, 0)) AS start_time,
+				a.end_time + make_interval(mins => COALESCE(s.buffer_after, 0)) AS end_time
+			FROM appointments a
+			LEFT JOIN services s ON s.id = a.service_id
+			WHERE a.clinician_id = ANY($1)
+			AND a.deleted_at IS NULL
+			AND a.status != 'cancelled'
+			UNION ALL
+			SELECT o.clinician_id,
+				o.start_time - make_interval(mins => COALESCE(s.buffer_before, 0)),
+				o.end_time + make_interval(mins => COALESCE(s.buffer_after, 0))
+			FROM waitlist_offers o
+			LEFT JOIN services s ON s.id = o.service_id
+			WHERE o.clinician_id = ANY($1)
+			AND o.status = 'pending'
+			AND o.expires_at > NOW()
+			UNION ALL
+			SELECT h.clinician_id,
+				h.start_time - make_interval(mins => COALESCE(s.buffer_before, 0)),
+				h.end_time + make_interval(mins => COALESCE(s.buffer_after, 0))
+			FROM appointment_holds h
+			LEFT JOIN services s ON s.id = h.service_id
+			WHERE h.clinician_id = ANY($1)
+			AND h.expires_at > NOW()
+		) busy
+		WHERE start_time < $3 AND end_time > $2
+		ORDER BY clinician_id, start_time
 	`
-	var slots []*model.TimeSlot
-	err := r.GetDB().SelectContext(ctx, &slots, query, clinicianID, date)
-	return slots, err
+	var periods []*model.BusyPeriod
+	if err := r.GetDB().SelectContext(ctx, &periods, query, pq.Array(clinicianIDs), from, to); err != nil {
+		return nil, fmt.Errorf("failed to list busy periods: %w", err)
+	}
+	return periods, nil
 }
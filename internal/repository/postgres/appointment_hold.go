@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+// lockClinician serializes writes to a clinician's calendar until the
+// transaction ends. The exclusion constraints keep appointments apart and
+// holds apart; the lock keeps each from slipping past the other's check.
+func lockClinician(ctx context.Context, tx *sqlx.Tx, clinicianID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1::text))`, clinicianID); err != nil {
+		return fmt.Errorf("failed to lock clinician calendar: %w", err)
+	}
+	return nil
+}
+
+func (r *appointmentRepository) CreateHold(ctx context.Context, hold *model.AppointmentHold) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := lockClinician(ctx, tx, hold.ClinicianID); err != nil {
+			return err
+		}
+
+		// Expired holds would still trip the exclusion constraint
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM appointment_holds WHERE clinician_id = $1 AND expires_at <= NOW()
+		`, hold.ClinicianID); err != nil {
+			return fmt.Errorf("failed to clear expired holds: %w", err)
+		}
+
+		var taken bool
+		if err := tx.GetContext(ctx, &taken, `
+			SELECT EXISTS (
+				SELECT 1 FROM appointments
+				WHERE clinician_id = $1
+				AND deleted_at IS NULL
+				AND status IN ('scheduled', 'confirmed')
+				AND start_time < $3 AND end_time > $2
+			) OR EXISTS (
+				SELECT 1 FROM waitlist_offers
+				WHERE clinician_id = $1
+				AND status = 'pending'
+				AND expires_at > NOW()
+				AND start_time < $3 AND end_time > $2
+			)
+		`, hold.ClinicianID, hold.StartTime, hold.EndTime); err != nil {
+			return fmt.Errorf("failed to check slot: %w", err)
+		}
+		if taken {
+			return repository.ErrSlotTaken
+		}
+
+		hold.ID = uuid.New()
+		hold.CreatedAt = time.Now()
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO appointment_holds (
+				id, clinic_id, clinician_id, service_id, patient_id,
+				start_time, end_time, expires_at, created_by, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`,
+			hold.ID,
+			hold.ClinicID,
+			hold.ClinicianID,
+			hold.ServiceID,
+			hold.PatientID,
+			hold.StartTime,
+			hold.EndTime,
+			hold.ExpiresAt,
+			hold.CreatedBy,
+			hold.CreatedAt,
+		)
+		switch {
+		case isExclusionViolation(err):
+			return repository.ErrSlotTaken
+		case isForeignKeyViolation(err):
+			return repository.ErrInvalidReference
+		case err != nil:
+			return fmt.Errorf("failed to create slot hold: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetHold returns live holds only
+func (r *appointmentRepository) GetHold(ctx context.Context, id uuid.UUID) (*model.AppointmentHold, error) {
+	var hold model.AppointmentHold
+	err := r.GetDB().GetContext(ctx, &hold, `
+		SELECT * FROM appointment_holds WHERE id = $1 AND expires_at > NOW()
+	`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot hold: %w", err)
+	}
+	return &hold, nil
+}
+
+func (r *appointmentRepository) DeleteHold(ctx context.Context, id uuid.UUID) error {
+	result, err := r.GetDB().ExecContext(ctx, `DELETE FROM appointment_holds WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete slot hold: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return repository.ErrHoldNotFound
+	}
+	return nil
+}
+
+func (r *appointmentRepository) CreateFromHold(ctx context.Context, appointment *model.Appointment, holdID uuid.UUID) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := lockClinician(ctx, tx, appointment.ClinicianID); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM appointment_holds
+			WHERE id = $1 AND clinician_id = $2
+			AND start_time = $3 AND end_time = $4
+			AND expires_at > NOW()
+		`, holdID, appointment.ClinicianID, appointment.StartTime, appointment.EndTime)
+		if err != nil {
+			return fmt.Errorf("failed to consume slot hold: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return repository.ErrHoldNotFound
+		}
+
+		appointment.ID = uuid.New()
+		appointment.CreatedAt = time.Now()
+		appointment.UpdatedAt = time.Now()
+		return insertAppointment(ctx, tx, appointment, r.GetRegionFromContext(ctx))
+	})
+}
@@ -0,0 +1,231 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type appointmentSeriesRepository struct {
+	BaseRepository
+}
+
+func NewAppointmentSeriesRepository(base BaseRepository) repository.AppointmentSeriesRepository {
+	return &appointmentSeriesRepository{base}
+}
+
+func (r *appointmentSeriesRepository) Create(ctx context.Context, series *model.AppointmentSeries, occurrences []*model.Appointment) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := insertSeries(ctx, tx, series); err != nil {
+			return err
+		}
+		for _, apt := range occurrences {
+			if err := insertAppointment(ctx, tx, apt, r.GetRegionFromContext(ctx)); err != nil {
+				if isForeignKeyViolation(err) {
+					return repository.ErrInvalidReference
+				}
+				return fmt.Errorf("failed to create series occurrence: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *appointmentSeriesRepository) Get(ctx context.Context, id uuid.UUID) (*model.AppointmentSeries, error) {
+	var series model.AppointmentSeries
+	if err := r.GetDB().GetContext(ctx, &series, `SELECT * FROM appointment_series WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get appointment series: %w", err)
+	}
+	if err := unmarshalExDates(&series); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *appointmentSeriesRepository) ListOccurrences(ctx context.Context, seriesID uuid.UUID) ([]*model.Appointment, error) {
+	query := `
+		SELECT * FROM appointments
+		WHERE series_id = $1 AND deleted_at IS NULL
+		ORDER BY occurrence_start ASC
+	`
+	var appointments []*model.Appointment
+	if err := r.GetDB().SelectContext(ctx, &appointments, query, seriesID); err != nil {
+		return nil, fmt.Errorf("failed to list series occurrences: %w", err)
+	}
+	return appointments, nil
+}
+
+func (r *appointmentSeriesRepository) Update(ctx context.Context, series *model.AppointmentSeries, occurrences []*model.Appointment) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := updateSeries(ctx, tx, series); err != nil {
+			return err
+		}
+		return updateOccurrences(ctx, tx, occurrences)
+	})
+}
+
+func (r *appointmentSeriesRepository) Split(ctx context.Context, original, next *model.AppointmentSeries, occurrences []*model.Appointment) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := updateSeries(ctx, tx, original); err != nil {
+			return err
+		}
+		if err := insertSeries(ctx, tx, next); err != nil {
+			return err
+		}
+		return updateOccurrences(ctx, tx, occurrences)
+	})
+}
+
+func insertSeries(ctx context.Context, tx *sqlx.Tx, series *model.AppointmentSeries) error {
+	query := `
+		INSERT INTO appointment_series (
+			id, clinic_id, clinician_id, patient_id, service_id, rrule,
+			start_time, duration, timezone, exdates, notes, status,
+			parent_id, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+
+	exdates, err := marshalExDates(series.ExDates)
+	if err != nil {
+		return err
+	}
+	series.CreatedAt = time.Now()
+	series.UpdatedAt = series.CreatedAt
+
+	_, err = tx.ExecContext(ctx, query,
+		series.ID,
+		series.ClinicID,
+		series.ClinicianID,
+		series.PatientID,
+		series.ServiceID,
+		series.RRule,
+		series.StartTime,
+		series.Duration,
+		series.Timezone,
+		exdates,
+		series.Notes,
+		series.Status,
+		series.ParentID,
+		series.CreatedBy,
+		series.CreatedAt,
+		series.UpdatedAt,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create appointment series: %w", err)
+	}
+	series.ExDatesJSON = exdates
+	return nil
+}
+
+func updateSeries(ctx context.Context, tx *sqlx.Tx, series *model.AppointmentSeries) error {
+	query := `
+		UPDATE appointment_series SET
+			rrule = $2,
+			start_time = $3,
+			duration = $4,
+			exdates = $5,
+			notes = $6,
+			status = $7,
+			updated_at = $8
+		WHERE id = $1
+	`
+
+	exdates, err := marshalExDates(series.ExDates)
+	if err != nil {
+		return err
+	}
+	series.UpdatedAt = time.Now()
+
+	result, err := tx.ExecContext(ctx, query,
+		series.ID,
+		series.RRule,
+		series.StartTime,
+		series.Duration,
+		exdates,
+		series.Notes,
+		series.Status,
+		series.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update appointment series: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("appointment series not found")
+	}
+	series.ExDatesJSON = exdates
+	return nil
+}
+
+// updateOccurrences saves what a series edit can change on its occurrences,
+// including which series they belong to after a split
+func updateOccurrences(ctx context.Context, tx *sqlx.Tx, occurrences []*model.Appointment) error {
+	query := `
+		UPDATE appointments SET
+			start_time = $2,
+			end_time = $3,
+			status = $4,
+			notes = $5,
+			cancel_reason = $6,
+			series_id = $7,
+			occurrence_start = $8,
+			updated_at = $9
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	for _, apt := range occurrences {
+		apt.UpdatedAt = time.Now()
+		if _, err := tx.ExecContext(ctx, query,
+			apt.ID,
+			apt.StartTime,
+			apt.EndTime,
+			apt.Status,
+			apt.Notes,
+			apt.CancelReason,
+			apt.SeriesID,
+			apt.OccurrenceStart,
+			apt.UpdatedAt,
+		); err != nil {
+			if isExclusionViolation(err) {
+				return repository.ErrSlotTaken
+			}
+			return fmt.Errorf("failed to update series occurrence: %w", err)
+		}
+	}
+	return nil
+}
+
+func marshalExDates(exdates []time.Time) (json.RawMessage, error) {
+	if exdates == nil {
+		exdates = []time.Time{}
+	}
+	data, err := json.Marshal(exdates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal exdates: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalExDates(series *model.AppointmentSeries) error {
+	series.ExDates = []time.Time{}
+	if len(series.ExDatesJSON) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(series.ExDatesJSON, &series.ExDates); err != nil {
+		return fmt.Errorf("failed to unmarshal exdates: %w", err)
+	}
+	return nil
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository"
 )
@@ -22,8 +25,9 @@ func (r *auditRepository) Create(ctx context.Context, log *model.AuditLog) error
 	query := `
         INSERT INTO audit_logs (
             id, user_id, organization_id, action, entity_type, entity_id,
-            changes, metadata, ip_address, user_agent, region_code, created_at
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+            changes, metadata, ip_address, user_agent, on_behalf_of,
+            proxy_relationship_id, region_code, created_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
     `
 
 	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
@@ -38,6 +42,8 @@ func (r *auditRepository) Create(ctx context.Context, log *model.AuditLog) error
 			log.Metadata,
 			log.IPAddress,
 			log.UserAgent,
+			log.OnBehalfOf,
+			log.ProxyRelationshipID,
 			r.GetRegionFromContext(ctx),
 			log.CreatedAt,
 		)
@@ -232,3 +238,31 @@ func (r *auditRepository) DeleteBefore(ctx context.Context, cutoff time.Time) er
 	_, err := r.GetDB().ExecContext(ctx, query, cutoff)
 	return err
 }
+
+func (r *auditRepository) ListByEntityIDs(ctx context.Context, entityIDs []uuid.UUID) ([]*model.AuditLog, error) {
+	if len(entityIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(entityIDs))
+	for i, id := range entityIDs {
+		ids[i] = id.String()
+	}
+
+	query := `
+		SELECT id, user_id, organization_id, action, entity_type, entity_id,
+			changes, metadata, COALESCE(ip_address, '') AS ip_address,
+			COALESCE(user_agent, '') AS user_agent,
+			COALESCE(access_reason, '') AS access_reason,
+			on_behalf_of, proxy_relationship_id, created_at
+		FROM audit_logs
+		WHERE entity_id = ANY($1::uuid[]) OR on_behalf_of = ANY($1::uuid[])
+		ORDER BY created_at DESC
+	`
+
+	var logs []*model.AuditLog
+	if err := r.GetDB().SelectContext(ctx, &logs, query, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to list audit logs by entity: %w", err)
+	}
+	return logs, nil
+}
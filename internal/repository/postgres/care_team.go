@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type careTeamRepository struct {
+	BaseRepository
+}
+
+func NewCareTeamRepository(base BaseRepository) repository.CareTeamRepository {
+	return &careTeamRepository{base}
+}
+
+func (r *careTeamRepository) Add(ctx context.Context, member *model.CareTeamMember) error {
+	query := `
+		INSERT INTO care_team_members (patient_id, user_id, role, added_by, added_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (patient_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING added_by, added_at
+	`
+	err := r.GetDB().QueryRowxContext(ctx, query,
+		member.PatientID,
+		member.UserID,
+		member.Role,
+		member.AddedBy,
+		member.AddedAt,
+	).Scan(&member.AddedBy, &member.AddedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to add care team member: %w", err)
+	}
+	return nil
+}
+
+func (r *careTeamRepository) Remove(ctx context.Context, patientID, userID uuid.UUID) error {
+	result, err := r.GetDB().ExecContext(ctx,
+		`DELETE FROM care_team_members WHERE patient_id = $1 AND user_id = $2`, patientID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove care team member: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *careTeamRepository) List(ctx context.Context, patientID uuid.UUID) ([]*model.CareTeamMember, error) {
+	query := `
+		SELECT patient_id, user_id, role, added_by, added_at
+		FROM care_team_members
+		WHERE patient_id = $1
+		ORDER BY added_at
+	`
+	members := []*model.CareTeamMember{}
+	if err := r.GetDB().SelectContext(ctx, &members, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list care team: %w", err)
+	}
+	return members, nil
+}
+
+func (r *careTeamRepository) IsMember(ctx context.Context, patientID, userID uuid.UUID) (bool, error) {
+	var member bool
+	err := r.GetDB().GetContext(ctx, &member,
+		`SELECT EXISTS (SELECT 1 FROM care_team_members WHERE patient_id = $1 AND user_id = $2)`, patientID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check care team: %w", err)
+	}
+	return member, nil
+}
+
+func (r *careTeamRepository) CanManage(ctx context.Context, patientID, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM patients p
+			JOIN users u ON u.organization_id = p.organization_id
+			WHERE p.id = $1 AND u.id = $2
+			AND (u.type = $3 OR EXISTS (
+				SELECT 1 FROM care_team_members ct
+				WHERE ct.patient_id = p.id AND ct.user_id = u.id
+			))
+		)
+	`
+	var ok bool
+	if err := r.GetDB().GetContext(ctx, &ok, query, patientID, userID, model.UserTypeAdmin); err != nil {
+		return false, fmt.Errorf("failed to check care team manager: %w", err)
+	}
+	return ok, nil
+}
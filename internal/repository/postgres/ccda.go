@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type ccdaRepository struct {
+	BaseRepository
+}
+
+func NewCCDARepository(base BaseRepository) repository.CCDARepository {
+	return &ccdaRepository{base}
+}
+
+func (r *ccdaRepository) CreateImport(ctx context.Context, imp *model.CCDAImport) error {
+	imp.ID = uuid.New()
+	imp.Version = 1
+	imp.CreatedAt = time.Now()
+	imp.UpdatedAt = imp.CreatedAt
+
+	_, err := r.GetDB().ExecContext(ctx, `
+		INSERT INTO ccda_imports (
+			id, patient_id, organization_id, document_id, title, source, document_date, status,
+			demographics_match, content, encryption_key_id, imported_by, version, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`,
+		imp.ID,
+		imp.PatientID,
+		imp.OrganizationID,
+		imp.DocumentID,
+		imp.Title,
+		imp.Source,
+		imp.DocumentDate,
+		imp.Status,
+		imp.DemographicsMatch,
+		imp.Content,
+		imp.EncryptionKeyID,
+		imp.ImportedBy,
+		imp.Version,
+		imp.CreatedAt,
+		imp.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create C-CDA import: %w", err)
+	}
+	return nil
+}
+
+func (r *ccdaRepository) GetImport(ctx context.Context, id uuid.UUID) (*model.CCDAImport, error) {
+	var imp model.CCDAImport
+	if err := r.GetDB().GetContext(ctx, &imp, `SELECT * FROM ccda_imports WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get C-CDA import: %w", err)
+	}
+	return &imp, nil
+}
+
+func (r *ccdaRepository) ListImports(ctx context.Context, patientID uuid.UUID, status model.CCDAImportStatus) ([]*model.CCDAImport, error) {
+	query := `SELECT * FROM ccda_imports WHERE patient_id = $1`
+	args := []interface{}{patientID}
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	query += " ORDER BY created_at DESC"
+
+	imports := []*model.CCDAImport{}
+	if err := r.GetDB().SelectContext(ctx, &imports, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list C-CDA imports: %w", err)
+	}
+	return imports, nil
+}
+
+func (r *ccdaRepository) UpdateImport(ctx context.Context, imp *model.CCDAImport) error {
+	imp.UpdatedAt = time.Now()
+
+	var version int
+	err := r.GetDB().GetContext(ctx, &version, `
+		UPDATE ccda_imports SET
+			status = $3,
+			content = $4,
+			encryption_key_id = $5,
+			reconciled_by = $6,
+			reconciled_at = $7,
+			discard_reason = $8,
+			updated_at = $9,
+			version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version
+	`,
+		imp.ID,
+		imp.Version,
+		imp.Status,
+		imp.Content,
+		imp.EncryptionKeyID,
+		imp.ReconciledBy,
+		imp.ReconciledAt,
+		imp.DiscardReason,
+		imp.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update C-CDA import: %w", err)
+	}
+	imp.Version = version
+	return nil
+}
+
+// ReencryptBatch locks up to limit imports whose content is not under their
+// organization's active data key and passes each to reencrypt
+func (r *ccdaRepository) ReencryptBatch(ctx context.Context, limit int, reencrypt func(*model.EncryptedContent) error) (int, error) {
+	var done int
+	err := r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var imports []*model.EncryptedContent
+		err := tx.SelectContext(ctx, &imports, `
+			SELECT i.id, i.organization_id, i.content, i.encryption_key_id
+			FROM ccda_imports i
+			WHERE length(i.content) > 0
+				AND NOT EXISTS (
+					SELECT 1 FROM data_encryption_keys k
+					WHERE k.id = i.encryption_key_id AND k.status = 'active'
+				)
+			LIMIT $1
+			FOR UPDATE OF i SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return fmt.Errorf("failed to select C-CDA imports to re-encrypt: %w", err)
+		}
+		for _, c := range imports {
+			if err := reencrypt(c); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE ccda_imports SET content = $1, encryption_key_id = $2 WHERE id = $3
+			`, c.Content, c.EncryptionKeyID, c.ID); err != nil {
+				return fmt.Errorf("failed to re-encrypt C-CDA import: %w", err)
+			}
+		}
+		done = len(imports)
+		return nil
+	})
+	return done, err
+}
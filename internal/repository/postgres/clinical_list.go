@@ -0,0 +1,308 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type clinicalListRepository struct {
+	BaseRepository
+}
+
+func NewClinicalListRepository(base BaseRepository) repository.ClinicalListRepository {
+	return &clinicalListRepository{base}
+}
+
+func (r *clinicalListRepository) CreateProblem(ctx context.Context, p *model.Problem) error {
+	query := `
+		INSERT INTO patient_problems (
+			id, patient_id, organization_id, code_system, code, display, status,
+			severity, onset_date, resolved_date, note, source_record_id, recorded_by,
+			version, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+
+	p.ID = uuid.New()
+	p.Version = 1
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		p.ID,
+		p.PatientID,
+		p.OrganizationID,
+		p.CodeSystem,
+		p.Code,
+		p.Display,
+		p.Status,
+		p.Severity,
+		p.OnsetDate,
+		p.ResolvedDate,
+		p.Note,
+		p.SourceRecordID,
+		p.RecordedBy,
+		p.Version,
+		p.CreatedAt,
+		p.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create problem: %w", err)
+	}
+	return nil
+}
+
+func (r *clinicalListRepository) GetProblem(ctx context.Context, id uuid.UUID) (*model.Problem, error) {
+	var p model.Problem
+	if err := r.GetDB().GetContext(ctx, &p, `SELECT * FROM patient_problems WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get problem: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *clinicalListRepository) FindProblem(ctx context.Context, patientID uuid.UUID, system, code string) (*model.Problem, error) {
+	var p model.Problem
+	err := r.GetDB().GetContext(ctx, &p, `
+		SELECT * FROM patient_problems WHERE patient_id = $1 AND code_system = $2 AND code = $3
+	`, patientID, system, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find problem: %w", err)
+	}
+	return &p, nil
+}
+
+// ListProblems lists active problems first, each group by onset, newest first
+func (r *clinicalListRepository) ListProblems(ctx context.Context, patientID uuid.UUID, status model.ClinicalStatus) ([]*model.Problem, error) {
+	query := `SELECT * FROM patient_problems WHERE patient_id = $1`
+	args := []interface{}{patientID}
+	if status != "" {
+		query += " AND status = $2"
+		args = append(args, status)
+	}
+	query += " ORDER BY status = 'active' DESC, onset_date DESC NULLS LAST, created_at DESC"
+
+	var problems []*model.Problem
+	if err := r.GetDB().SelectContext(ctx, &problems, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list problems: %w", err)
+	}
+	return problems, nil
+}
+
+func (r *clinicalListRepository) UpdateProblem(ctx context.Context, p *model.Problem) error {
+	p.UpdatedAt = time.Now()
+
+	var version int
+	err := r.GetDB().GetContext(ctx, &version, `
+		UPDATE patient_problems SET
+			status = $3,
+			severity = $4,
+			onset_date = $5,
+			resolved_date = $6,
+			note = $7,
+			updated_at = $8,
+			version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version
+	`,
+		p.ID,
+		p.Version,
+		p.Status,
+		p.Severity,
+		p.OnsetDate,
+		p.ResolvedDate,
+		p.Note,
+		p.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update problem: %w", err)
+	}
+	p.Version = version
+	return nil
+}
+
+func (r *clinicalListRepository) CreateAllergy(ctx context.Context, a *model.Allergy) error {
+	query := `
+		INSERT INTO patient_allergies (
+			id, patient_id, organization_id, code_system, code, display, category,
+			status, severity, reactions, onset_date, resolved_date, note,
+			source_record_id, recorded_by, version, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`
+
+	reactions, err := marshalReactions(a.Reactions)
+	if err != nil {
+		return err
+	}
+
+	a.ID = uuid.New()
+	a.Version = 1
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = a.CreatedAt
+
+	_, err = r.GetDB().ExecContext(ctx, query,
+		a.ID,
+		a.PatientID,
+		a.OrganizationID,
+		a.CodeSystem,
+		a.Code,
+		a.Display,
+		a.Category,
+		a.Status,
+		a.Severity,
+		reactions,
+		a.OnsetDate,
+		a.ResolvedDate,
+		a.Note,
+		a.SourceRecordID,
+		a.RecordedBy,
+		a.Version,
+		a.CreatedAt,
+		a.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create allergy: %w", err)
+	}
+	a.ReactionsJSON = reactions
+	return nil
+}
+
+func (r *clinicalListRepository) GetAllergy(ctx context.Context, id uuid.UUID) (*model.Allergy, error) {
+	var a model.Allergy
+	if err := r.GetDB().GetContext(ctx, &a, `SELECT * FROM patient_allergies WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get allergy: %w", err)
+	}
+	if err := unmarshalReactions(&a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *clinicalListRepository) FindAllergy(ctx context.Context, patientID uuid.UUID, system, code string) (*model.Allergy, error) {
+	var a model.Allergy
+	err := r.GetDB().GetContext(ctx, &a, `
+		SELECT * FROM patient_allergies WHERE patient_id = $1 AND code_system = $2 AND code = $3
+	`, patientID, system, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find allergy: %w", err)
+	}
+	if err := unmarshalReactions(&a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *clinicalListRepository) ListAllergies(ctx context.Context, patientID uuid.UUID, status model.ClinicalStatus) ([]*model.Allergy, error) {
+	query := `SELECT * FROM patient_allergies WHERE patient_id = $1`
+	args := []interface{}{patientID}
+	if status != "" {
+		query += " AND status = $2"
+		args = append(args, status)
+	}
+	query += " ORDER BY status = 'active' DESC, severity = 'severe' DESC, display"
+
+	var allergies []*model.Allergy
+	if err := r.GetDB().SelectContext(ctx, &allergies, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list allergies: %w", err)
+	}
+	for _, a := range allergies {
+		if err := unmarshalReactions(a); err != nil {
+			return nil, err
+		}
+	}
+	return allergies, nil
+}
+
+func (r *clinicalListRepository) UpdateAllergy(ctx context.Context, a *model.Allergy) error {
+	reactions, err := marshalReactions(a.Reactions)
+	if err != nil {
+		return err
+	}
+	a.UpdatedAt = time.Now()
+
+	var version int
+	err = r.GetDB().GetContext(ctx, &version, `
+		UPDATE patient_allergies SET
+			category = $3,
+			status = $4,
+			severity = $5,
+			reactions = $6,
+			onset_date = $7,
+			resolved_date = $8,
+			note = $9,
+			updated_at = $10,
+			version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version
+	`,
+		a.ID,
+		a.Version,
+		a.Category,
+		a.Status,
+		a.Severity,
+		reactions,
+		a.OnsetDate,
+		a.ResolvedDate,
+		a.Note,
+		a.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update allergy: %w", err)
+	}
+	a.Version = version
+	a.ReactionsJSON = reactions
+	return nil
+}
+
+func marshalReactions(reactions []model.AllergyReaction) (json.RawMessage, error) {
+	if reactions == nil {
+		reactions = []model.AllergyReaction{}
+	}
+	data, err := json.Marshal(reactions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reactions: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalReactions(a *model.Allergy) error {
+	a.Reactions = []model.AllergyReaction{}
+	if len(a.ReactionsJSON) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(a.ReactionsJSON, &a.Reactions); err != nil {
+		return fmt.Errorf("failed to unmarshal reactions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type clinicalNoteRepository struct {
+	BaseRepository
+}
+
+func NewClinicalNoteRepository(base BaseRepository) repository.ClinicalNoteRepository {
+	return &clinicalNoteRepository{base}
+}
+
+func (r *clinicalNoteRepository) CreateTemplate(ctx context.Context, t *model.NoteTemplate) error {
+	sections, err := json.Marshal(t.Sections)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template sections: %w", err)
+	}
+
+	t.ID = uuid.New()
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+	t.SectionsJSON = sections
+
+	_, err = r.GetDB().ExecContext(ctx, `
+		INSERT INTO note_templates (
+			id, organization_id, name, kind, sections, active, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, t.ID, t.OrganizationID, t.Name, t.Kind, sections, t.Active, t.CreatedBy, t.CreatedAt, t.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		return fmt.Errorf("failed to create note template: %w", err)
+	}
+	return nil
+}
+
+func (r *clinicalNoteRepository) GetTemplate(ctx context.Context, id uuid.UUID) (*model.NoteTemplate, error) {
+	var t model.NoteTemplate
+	if err := r.GetDB().GetContext(ctx, &t, `SELECT * FROM note_templates WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get note template: %w", err)
+	}
+	if err := json.Unmarshal(t.SectionsJSON, &t.Sections); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template sections: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *clinicalNoteRepository) ListTemplates(ctx context.Context, orgID uuid.UUID, activeOnly bool) ([]*model.NoteTemplate, error) {
+	query := `SELECT * FROM note_templates WHERE organization_id = $1`
+	if activeOnly {
+		query += " AND active"
+	}
+	query += " ORDER BY kind, name"
+
+	templates := []*model.NoteTemplate{}
+	if err := r.GetDB().SelectContext(ctx, &templates, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to list note templates: %w", err)
+	}
+	for _, t := range templates {
+		if err := json.Unmarshal(t.SectionsJSON, &t.Sections); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal template sections: %w", err)
+		}
+	}
+	return templates, nil
+}
+
+func (r *clinicalNoteRepository) UpdateTemplate(ctx context.Context, t *model.NoteTemplate) error {
+	sections, err := json.Marshal(t.Sections)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template sections: %w", err)
+	}
+
+	t.UpdatedAt = time.Now()
+	t.SectionsJSON = sections
+
+	_, err = r.GetDB().ExecContext(ctx, `
+		UPDATE note_templates SET name = $2, sections = $3, active = $4, updated_at = $5
+		WHERE id = $1
+	`, t.ID, t.Name, sections, t.Active, t.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		return fmt.Errorf("failed to update note template: %w", err)
+	}
+	return nil
+}
+
+func (r *clinicalNoteRepository) Create(ctx context.Context, note *model.ClinicalNote) error {
+	note.Version = 1
+	_, err := r.GetDB().ExecContext(ctx, `
+		INSERT INTO clinical_notes (
+			id, patient_id, organization_id, template_id, author_id, status,
+			content, encryption_key_id, version, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`,
+		note.ID,
+		note.PatientID,
+		note.OrganizationID,
+		note.TemplateID,
+		note.AuthorID,
+		note.Status,
+		note.Content,
+		note.EncryptionKeyID,
+		note.Version,
+		note.CreatedAt,
+		note.UpdatedAt,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create clinical note: %w", err)
+	}
+	return nil
+}
+
+func (r *clinicalNoteRepository) Get(ctx context.Context, id uuid.UUID) (*model.ClinicalNote, error) {
+	var note model.ClinicalNote
+	if err := r.GetDB().GetContext(ctx, &note, `SELECT * FROM clinical_notes WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get clinical note: %w", err)
+	}
+	return &note, nil
+}
+
+func (r *clinicalNoteRepository) List(ctx context.Context, patientID uuid.UUID, status model.NoteStatus) ([]*model.ClinicalNote, error) {
+	query := `SELECT * FROM clinical_notes WHERE patient_id = $1`
+	args := []interface{}{patientID}
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	query += " ORDER BY created_at DESC"
+
+	notes := []*model.ClinicalNote{}
+	if err := r.GetDB().SelectContext(ctx, &notes, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list clinical notes: %w", err)
+	}
+	return notes, nil
+}
+
+func (r *clinicalNoteRepository) Update(ctx context.Context, note *model.ClinicalNote) error {
+	note.UpdatedAt = time.Now()
+
+	var version int
+	err := r.GetDB().GetContext(ctx, &version, `
+		UPDATE clinical_notes SET
+			status = $3,
+			content = $4,
+			encryption_key_id = $5,
+			content_hash = $6,
+			signed_at = $7,
+			cosign_required = $8,
+			cosigner_id = $9,
+			cosigned_at = $10,
+			locked_by = $11,
+			locked_at = $12,
+			updated_at = $13,
+			version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version
+	`,
+		note.ID,
+		note.Version,
+		note.Status,
+		note.Content,
+		note.EncryptionKeyID,
+		note.ContentHash,
+		note.SignedAt,
+		note.CosignRequired,
+		note.CosignerID,
+		note.CosignedAt,
+		note.LockedBy,
+		note.LockedAt,
+		note.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update clinical note: %w", err)
+	}
+	note.Version = version
+	return nil
+}
+
+func (r *clinicalNoteRepository) CreateAddendum(ctx context.Context, a *model.NoteAddendum) error {
+	_, err := r.GetDB().ExecContext(ctx, `
+		INSERT INTO note_addenda (id, note_id, author_id, content, encryption_key_id, content_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, a.ID, a.NoteID, a.AuthorID, a.Content, a.EncryptionKeyID, a.ContentHash, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create note addendum: %w", err)
+	}
+	return nil
+}
+
+func (r *clinicalNoteRepository) ListAddenda(ctx context.Context, noteID uuid.UUID) ([]*model.NoteAddendum, error) {
+	addenda := []*model.NoteAddendum{}
+	if err := r.GetDB().SelectContext(ctx, &addenda, `
+		SELECT * FROM note_addenda WHERE note_id = $1 ORDER BY created_at
+	`, noteID); err != nil {
+		return nil, fmt.Errorf("failed to list note addenda: %w", err)
+	}
+	return addenda, nil
+}
+
+// ReencryptBatch locks up to limit notes, then up to limit addenda, whose
+// content is not under their organization's active data key and passes each
+// to reencrypt. Only the ciphertext and its key change, so signed content and
+// its hash are unaffected.
+func (r *clinicalNoteRepository) ReencryptBatch(ctx context.Context, limit int, reencrypt func(*model.EncryptedContent) error) (int, error) {
+	var done int
+	err := r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var notes []*model.EncryptedContent
+		err := tx.SelectContext(ctx, &notes, `
+			SELECT n.id, n.organization_id, n.content, n.encryption_key_id
+			FROM clinical_notes n
+			WHERE length(n.content) > 0
+				AND NOT EXISTS (
+					SELECT 1 FROM data_encryption_keys k
+					WHERE k.id = n.encryption_key_id AND k.status = 'active'
+				)
+			LIMIT $1
+			FOR UPDATE OF n SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return fmt.Errorf("failed to select notes to re-encrypt: %w", err)
+		}
+		for _, c := range notes {
+			if err := reencrypt(c); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE clinical_notes SET content = $1, encryption_key_id = $2 WHERE id = $3
+			`, c.Content, c.EncryptionKeyID, c.ID); err != nil {
+				return fmt.Errorf("failed to re-encrypt clinical note: %w", err)
+			}
+		}
+
+		var addenda []*model.EncryptedContent
+		err = tx.SelectContext(ctx, &addenda, `
+			SELECT a.id, n.organization_id, a.content, a.encryption_key_id
+			FROM note_addenda a
+			JOIN clinical_notes n ON n.id = a.note_id
+			WHERE length(a.content) > 0
+				AND NOT EXISTS (
+					SELECT 1 FROM data_encryption_keys k
+					WHERE k.id = a.encryption_key_id AND k.status = 'active'
+				)
+			LIMIT $1
+			FOR UPDATE OF a SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return fmt.Errorf("failed to select note addenda to re-encrypt: %w", err)
+		}
+		for _, c := range addenda {
+			if err := reencrypt(c); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE note_addenda SET content = $1, encryption_key_id = $2 WHERE id = $3
+			`, c.Content, c.EncryptionKeyID, c.ID); err != nil {
+				return fmt.Errorf("failed to re-encrypt note addendum: %w", err)
+			}
+		}
+
+		done = len(notes) + len(addenda)
+		return nil
+	})
+	return done, err
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type complianceRepository struct {
+	BaseRepository
+}
+
+func NewComplianceRepository(base BaseRepository) repository.ComplianceRepository {
+	return &complianceRepository{base}
+}
+
+func (r *complianceRepository) Create(ctx context.Context, req *model.ComplianceRequest) error {
+	query := `
+		INSERT INTO compliance_requests (
+			id, organization_id, patient_id, type, status,
+			requested_by, region_code, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	if req.ID == uuid.Nil {
+		req.ID = uuid.New()
+	}
+	if req.RegionCode == "" {
+		req.RegionCode = r.GetRegionFromContext(ctx)
+	}
+	req.CreatedAt = time.Now()
+	req.UpdatedAt = time.Now()
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		req.ID,
+		req.OrganizationID,
+		req.PatientID,
+		req.Type,
+		req.Status,
+		req.RequestedBy,
+		req.RegionCode,
+		req.CreatedAt,
+		req.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create compliance request: %w", err)
+	}
+	return nil
+}
+
+func (r *complianceRepository) Get(ctx context.Context, id uuid.UUID) (*model.ComplianceRequest, error) {
+	query := `SELECT * FROM compliance_requests WHERE id = $1`
+
+	var req model.ComplianceRequest
+	if err := r.GetDB().GetContext(ctx, &req, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get compliance request: %w", err)
+	}
+	return &req, nil
+}
+
+func (r *complianceRepository) Update(ctx context.Context, req *model.ComplianceRequest) error {
+	query := `
+		UPDATE compliance_requests SET
+			status = $1,
+			artifact_path = $2,
+			artifact_checksum = $3,
+			artifact_expires_at = $4,
+			error = $5,
+			completed_at = $6,
+			updated_at = $7
+		WHERE id = $8
+	`
+
+	req.UpdatedAt = time.Now()
+	result, err := r.GetDB().ExecContext(ctx, query,
+		req.Status,
+		req.ArtifactPath,
+		req.ArtifactChecksum,
+		req.ArtifactExpires,
+		req.Error,
+		req.CompletedAt,
+		req.UpdatedAt,
+		req.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update compliance request: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("compliance request not found")
+	}
+	return nil
+}
+
+func (r *complianceRepository) ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.ComplianceRequest, error) {
+	query := `
+		SELECT * FROM compliance_requests
+		WHERE patient_id = $1
+		ORDER BY created_at DESC
+	`
+
+	var reqs []*model.ComplianceRequest
+	if err := r.GetDB().SelectContext(ctx, &reqs, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list compliance requests: %w", err)
+	}
+	return reqs, nil
+}
+
+// ClaimPending moves up to limit pending requests into processing and returns
+// them. SKIP LOCKED lets several workers poll the table without double work.
+func (r *complianceRepository) ClaimPending(ctx context.Context, limit int) ([]*model.ComplianceRequest, error) {
+	query := `
+		UPDATE compliance_requests SET
+			status = $1,
+			attempts = attempts + 1,
+			started_at = NOW(),
+			updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM compliance_requests
+			WHERE status = $2
+			ORDER BY created_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+
+	var reqs []*model.ComplianceRequest
+	if err := r.GetDB().SelectContext(ctx, &reqs, query,
+		model.ComplianceRequestStatusProcessing,
+		model.ComplianceRequestStatusPending,
+		limit,
+	); err != nil {
+		return nil, fmt.Errorf("failed to claim compliance requests: %w", err)
+	}
+	return reqs, nil
+}
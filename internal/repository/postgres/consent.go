@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type consentRepository struct {
+	BaseRepository
+}
+
+func NewConsentRepository(base BaseRepository) repository.ConsentRepository {
+	return &consentRepository{base}
+}
+
+func (r *consentRepository) Create(ctx context.Context, consent *model.Consent) error {
+	query := `
+		INSERT INTO patient_consents (
+			id, patient_id, organization_id, type, version, status,
+			source, recorded_by, granted_at, withdrawn_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	consent.ID = uuid.New()
+	consent.CreatedAt = time.Now()
+	consent.UpdatedAt = time.Now()
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		consent.ID,
+		consent.PatientID,
+		consent.OrganizationID,
+		consent.Type,
+		consent.Version,
+		consent.Status,
+		consent.Source,
+		consent.RecordedBy,
+		consent.GrantedAt,
+		consent.WithdrawnAt,
+		consent.CreatedAt,
+		consent.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create consent: %w", err)
+	}
+	return nil
+}
+
+func (r *consentRepository) Get(ctx context.Context, id uuid.UUID) (*model.Consent, error) {
+	query := `SELECT * FROM patient_consents WHERE id = $1`
+
+	var consent model.Consent
+	if err := r.GetDB().GetContext(ctx, &consent, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get consent: %w", err)
+	}
+	return &consent, nil
+}
+
+func (r *consentRepository) Update(ctx context.Context, consent *model.Consent) error {
+	query := `
+		UPDATE patient_consents SET
+			status = $1,
+			withdrawn_at = $2,
+			updated_at = $3
+		WHERE id = $4
+	`
+
+	consent.UpdatedAt = time.Now()
+	result, err := r.GetDB().ExecContext(ctx, query,
+		consent.Status,
+		consent.WithdrawnAt,
+		consent.UpdatedAt,
+		consent.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update consent: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("consent not found")
+	}
+	return nil
+}
+
+func (r *consentRepository) ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.Consent, error) {
+	query := `
+		SELECT * FROM patient_consents
+		WHERE patient_id = $1
+		ORDER BY granted_at DESC
+	`
+
+	var consents []*model.Consent
+	if err := r.GetDB().SelectContext(ctx, &consents, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list consents: %w", err)
+	}
+	return consents, nil
+}
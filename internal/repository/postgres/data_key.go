@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+const dataKeyColumns = `id, organization_id, wrapped_key, kek_id, status, created_at, retired_at, rewrapped_at`
+
+type dataKeyRepository struct {
+	BaseRepository
+}
+
+func NewDataKeyRepository(base BaseRepository) repository.DataKeyRepository {
+	return &dataKeyRepository{base}
+}
+
+func (r *dataKeyRepository) Get(ctx context.Context, id uuid.UUID) (*model.DataKey, error) {
+	var key model.DataKey
+	query := `SELECT ` + dataKeyColumns + ` FROM data_encryption_keys WHERE id = $1`
+	if err := r.GetDB().GetContext(ctx, &key, query, id); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *dataKeyRepository) GetActive(ctx context.Context, orgID uuid.UUID) (*model.DataKey, error) {
+	var key model.DataKey
+	query := `SELECT ` + dataKeyColumns + ` FROM data_encryption_keys WHERE organization_id = $1 AND status = 'active'`
+	if err := r.GetDB().GetContext(ctx, &key, query, orgID); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *dataKeyRepository) List(ctx context.Context, orgID uuid.UUID) ([]*model.DataKey, error) {
+	keys := []*model.DataKey{}
+	query := `SELECT ` + dataKeyColumns + ` FROM data_encryption_keys WHERE organization_id = $1 ORDER BY created_at DESC`
+	if err := r.GetDB().SelectContext(ctx, &keys, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to list data keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *dataKeyRepository) Create(ctx context.Context, key *model.DataKey) error {
+	if err := r.insert(ctx, r.GetDB(), key); err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		return fmt.Errorf("failed to create data key: %w", err)
+	}
+	return nil
+}
+
+func (r *dataKeyRepository) Rotate(ctx context.Context, key *model.DataKey) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE data_encryption_keys SET status = 'retired', retired_at = $2
+			WHERE organization_id = $1 AND status = 'active'
+		`, key.OrganizationID, key.CreatedAt); err != nil {
+			return fmt.Errorf("failed to retire data key: %w", err)
+		}
+		if err := r.insert(ctx, tx, key); err != nil {
+			if isUniqueViolation(err) {
+				return repository.ErrDuplicate
+			}
+			return fmt.Errorf("failed to create data key: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *dataKeyRepository) ListWrappedWithout(ctx context.Context, kekID string, limit int) ([]*model.DataKey, error) {
+	keys := []*model.DataKey{}
+	query := `SELECT ` + dataKeyColumns + ` FROM data_encryption_keys WHERE kek_id <> $1 ORDER BY created_at LIMIT $2`
+	if err := r.GetDB().SelectContext(ctx, &keys, query, kekID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list data keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *dataKeyRepository) UpdateWrapping(ctx context.Context, key *model.DataKey) error {
+	now := time.Now()
+	_, err := r.GetDB().ExecContext(ctx, `
+		UPDATE data_encryption_keys SET wrapped_key = $2, kek_id = $3, rewrapped_at = $4
+		WHERE id = $1
+	`, key.ID, key.WrappedKey, key.KEKID, now)
+	if err != nil {
+		return fmt.Errorf("failed to rewrap data key: %w", err)
+	}
+	key.RewrappedAt = &now
+	return nil
+}
+
+func (r *dataKeyRepository) insert(ctx context.Context, db sqlx.ExecerContext, key *model.DataKey) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO data_encryption_keys (id, organization_id, wrapped_key, kek_id, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, key.ID, key.OrganizationID, key.WrappedKey, key.KEKID, key.Status, key.CreatedAt)
+	return err
+}
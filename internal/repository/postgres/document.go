@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type documentRepository struct {
+	BaseRepository
+}
+
+func NewDocumentRepository(base BaseRepository) repository.DocumentRepository {
+	return &documentRepository{base}
+}
+
+func (r *documentRepository) Create(ctx context.Context, doc *model.Document) error {
+	query := `
+		INSERT INTO patient_documents (
+			id, patient_id, organization_id, medical_record_id, name, content_type,
+			size, checksum, backend, storage_key, uploaded_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = time.Now()
+	}
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		doc.ID,
+		doc.PatientID,
+		doc.OrganizationID,
+		doc.MedicalRecordID,
+		doc.Name,
+		doc.ContentType,
+		doc.Size,
+		doc.Checksum,
+		doc.Backend,
+		doc.StorageKey,
+		doc.UploadedBy,
+		doc.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+	return nil
+}
+
+func (r *documentRepository) Get(ctx context.Context, id uuid.UUID) (*model.Document, error) {
+	query := `SELECT * FROM patient_documents WHERE id = $1 AND deleted_at IS NULL`
+
+	var doc model.Document
+	if err := r.GetDB().GetContext(ctx, &doc, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	return &doc, nil
+}
+
+func (r *documentRepository) ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.Document, error) {
+	query := `
+		SELECT * FROM patient_documents
+		WHERE patient_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	var docs []*model.Document
+	if err := r.GetDB().SelectContext(ctx, &docs, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	return docs, nil
+}
+
+func (r *documentRepository) ListStored(ctx context.Context, patientID uuid.UUID) ([]*model.Document, error) {
+	query := `SELECT * FROM patient_documents WHERE patient_id = $1`
+
+	var docs []*model.Document
+	if err := r.GetDB().SelectContext(ctx, &docs, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	return docs, nil
+}
+
+func (r *documentRepository) SoftDelete(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE patient_documents SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+
+	result, err := r.GetDB().ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("document not found")
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+// erasureTarget describes how a data category maps onto a table. Match selects
+// the patient's rows with the patient ID as $1; Anonymize is the SET clause
+// that strips PII while keeping the row. Categories without an Anonymize
+// clause are retained when asked to anonymize. Append-only tables that can't
+// be updated set deleteToAnonymize to have their rows deleted instead.
+// Retention cutoffs compare against createdColumn, created_at by default.
+type erasureTarget struct {
+	table             string
+	match             string
+	anonymize         string
+	deleteToAnonymize bool
+	createdColumn     string
+}
+
+func (t erasureTarget) created() string {
+	if t.createdColumn == "" {
+		return "created_at"
+	}
+	return t.createdColumn
+}
+
+var erasureTargets = map[model.DataCategory][]erasureTarget{
+	model.DataCategoryAppointments: {
+		{
+			// Deleting a series leaves its occurrences without one
+			table:     "appointment_series",
+			match:     "patient_id = $1",
+			anonymize: "notes = '', updated_at = NOW()",
+		},
+		{
+			// Offers carry only the slot, so they stay when anonymizing
+			table: "waitlist_offers",
+			match: "patient_id = $1",
+		},
+		{
+			table:     "waitlist_entries",
+			match:     "patient_id = $1",
+			anonymize: "notes = '', preferred_windows = '[]'::jsonb, updated_at = NOW()",
+		},
+		{
+			table:     "appointments",
+			match:     "patient_id = $1",
+			anonymize: "notes = NULL, cancel_reason = NULL, updated_at = NOW()",
+		},
+	},
+	model.DataCategoryMedicalRecords: {
+		{
+			table:     "patient_allergies",
+			match:     "patient_id = $1",
+			anonymize: "note = NULL, updated_at = NOW()",
+		},
+		{
+			table:     "patient_problems",
+			match:     "patient_id = $1",
+			anonymize: "note = NULL, updated_at = NOW()",
+		},
+		{
+			table:     "observations",
+			match:     "patient_id = $1",
+			anonymize: "status_reason = NULL, updated_at = NOW()",
+		},
+		{
+			table:     "ccda_imports",
+			match:     "patient_id = $1",
+			anonymize: "content = ''::bytea, updated_at = NOW()",
+		},
+		{
+			table:     "referrals",
+			match:     "patient_id = $1",
+			anonymize: "reason = NULL, decision_note = NULL, record_ids = '{}', updated_at = NOW()",
+		},
+		{
+			table:     "note_addenda",
+			match:     "note_id IN (SELECT id FROM clinical_notes WHERE patient_id = $1)",
+			anonymize: "content = ''::bytea",
+		},
+		{
+			table:     "clinical_notes",
+			match:     "patient_id = $1",
+			anonymize: "content = ''::bytea, updated_at = NOW()",
+		},
+		{
+			table:         "hl7_messages",
+			match:         "patient_id = $1",
+			anonymize:     "raw = ''::bytea, error = NULL, resolution = NULL",
+			createdColumn: "received_at",
+		},
+		{
+			table:     "prescriptions",
+			match:     "patient_id = $1",
+			anonymize: "instructions = NULL, status_reason = NULL, acknowledged_warnings = NULL, updated_at = NOW()",
+		},
+		{
+			// Blobs of deleted documents are removed once the erasure commits
+			table: "patient_documents",
+			match: "patient_id = $1",
+		},
+		{
+			// The no-update rule on versions would silently skip an UPDATE,
+			// so their history is deleted even when anonymizing
+			table:             "medical_record_versions",
+			match:             "record_id IN (SELECT id FROM medical_records WHERE patient_id = $1)",
+			deleteToAnonymize: true,
+		},
+		{
+			table:     "medical_record_amendments",
+			match:     "patient_id = $1",
+			anonymize: `justification = '', proposed_changes = '{}'::jsonb, decision_reason = NULL, updated_at = NOW()`,
+		},
+		{
+			table:     "medical_records",
+			match:     "patient_id = $1",
+			anonymize: "description = NULL, diagnosis = NULL, treatment = NULL, medications = NULL, attachments = NULL, encrypted_data = NULL, updated_at = NOW()",
+		},
+	},
+	model.DataCategoryNotifications: {{
+		table:     "notifications",
+		match:     "patient_id = $1",
+		anonymize: "subject = NULL, content = '[erased]', recipient = '[erased]', updated_at = NOW()",
+	}},
+	model.DataCategoryOutboxEvents: {{
+		table:     "outbox_events",
+		match:     "payload::text LIKE '%' || $1::text || '%'",
+		anonymize: `payload = '{"redacted": true}'::jsonb, updated_at = NOW()`,
+	}},
+	model.DataCategoryInsurance: {
+		{
+			// History rows go first; their match depends on the coverage rows
+			table:         "insurance_coverage_history",
+			match:         "coverage_id IN (SELECT id FROM insurance_coverages WHERE patient_id = $1)",
+			anonymize:     `snapshot = '{"redacted": true}'::jsonb`,
+			createdColumn: "changed_at",
+		},
+		{
+			table:         "eligibility_checks",
+			match:         "patient_id = $1",
+			anonymize:     "message = NULL, response = NULL",
+			createdColumn: "checked_at",
+		},
+		{
+			table:     "insurance_coverages",
+			match:     "patient_id = $1",
+			anonymize: "member_number = '[erased]', group_number = '[erased]', subscriber_name = '[erased]', card_front_image = NULL, card_back_image = NULL, updated_at = NOW()",
+		},
+	},
+	model.DataCategoryRelationships: {{
+		table: "patient_relationships",
+		match: "(patient_id = $1 OR related_patient_id = $1)",
+	}},
+	model.DataCategoryIdentifiers: {{
+		table: "patient_identifiers",
+		match: "patient_id = $1",
+	}},
+	model.DataCategoryConsents: {{
+		table: "patient_consents",
+		match: "patient_id = $1",
+	}},
+}
+
+type erasureRepository struct {
+	BaseRepository
+}
+
+func NewErasureRepository(base BaseRepository) repository.ErasureRepository {
+	return &erasureRepository{base}
+}
+
+// Execute applies every decision in the plan inside one transaction so a
+// failed erasure never leaves the patient half-erased.
+func (r *erasureRepository) Execute(ctx context.Context, plan *model.ErasurePlan) ([]model.ErasureOutcome, error) {
+	var outcomes []model.ErasureOutcome
+
+	err := r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		// The patient row goes last because the other categories key off it
+		var patientDecision *model.ErasureDecision
+		for i := range plan.Decisions {
+			d := plan.Decisions[i]
+			if d.Category == model.DataCategoryPatient {
+				patientDecision = &d
+				continue
+			}
+
+			targets, ok := erasureTargets[d.Category]
+			if !ok {
+				return fmt.Errorf("unknown data category: %s", d.Category)
+			}
+
+			if d.Cutoff == nil {
+				rows, action, err := r.applyAll(ctx, tx, targets, d.Action, plan, "")
+				if err != nil {
+					return fmt.Errorf("failed to erase %s: %w", d.Category, err)
+				}
+				outcomes = append(outcomes, model.ErasureOutcome{Category: d.Category, Action: action, Rows: rows})
+				continue
+			}
+
+			rows, action, err := r.applyAll(ctx, tx, targets, d.Action, plan, " AND %s < $2", *d.Cutoff)
+			if err != nil {
+				return fmt.Errorf("failed to erase %s: %w", d.Category, err)
+			}
+			outcomes = append(outcomes, model.ErasureOutcome{Category: d.Category, Action: action, Rows: rows})
+
+			rows, action, err = r.applyAll(ctx, tx, targets, d.Fallback, plan, " AND %s >= $2", *d.Cutoff)
+			if err != nil {
+				return fmt.Errorf("failed to erase %s: %w", d.Category, err)
+			}
+			outcomes = append(outcomes, model.ErasureOutcome{Category: d.Category, Action: action, Rows: rows})
+		}
+
+		if patientDecision != nil {
+			rows, err := r.erasePatient(ctx, tx, patientDecision.Action, plan)
+			if err != nil {
+				return fmt.Errorf("failed to erase patient: %w", err)
+			}
+			outcomes = append(outcomes, model.ErasureOutcome{Category: model.DataCategoryPatient, Action: patientDecision.Action, Rows: rows})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outcomes, nil
+}
+
+// applyAll runs the action against every table of a category and reports the
+// rows touched in the category's main (last) table. extra is appended to each
+// target's match with %s standing for the target's created column.
+func (r *erasureRepository) applyAll(ctx context.Context, tx *sqlx.Tx, targets []erasureTarget, action model.ErasureAction, plan *model.ErasurePlan, extra string, extraArgs ...interface{}) (int64, model.ErasureAction, error) {
+	var rows int64
+	applied := action
+	for _, target := range targets {
+		n, a, err := r.apply(ctx, tx, target, action, plan, extra, extraArgs...)
+		if err != nil {
+			return 0, a, err
+		}
+		rows, applied = n, a
+	}
+	return rows, applied, nil
+}
+
+func (r *erasureRepository) apply(ctx context.Context, tx *sqlx.Tx, target erasureTarget, action model.ErasureAction, plan *model.ErasurePlan, extra string, extraArgs ...interface{}) (int64, model.ErasureAction, error) {
+	args := append([]interface{}{plan.PatientID}, extraArgs...)
+	where := target.match
+	if extra != "" {
+		where += fmt.Sprintf(extra, target.created())
+	}
+
+	if action == model.ErasureActionAnonymize && target.deleteToAnonymize {
+		action = model.ErasureActionDelete
+	}
+	if action == model.ErasureActionAnonymize && target.anonymize == "" {
+		action = model.ErasureActionRetain
+	}
+
+	var query string
+	switch action {
+	case model.ErasureActionDelete:
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s", target.table, where)
+	case model.ErasureActionAnonymize:
+		query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", target.table, target.anonymize, where)
+	case model.ErasureActionRetain:
+		var count int64
+		query = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", target.table, where)
+		if err := tx.GetContext(ctx, &count, query, args...); err != nil {
+			return 0, action, err
+		}
+		return count, action, nil
+	default:
+		return 0, action, fmt.Errorf("unknown erasure action: %s", action)
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, action, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, action, err
+	}
+	return rows, action, nil
+}
+
+func (r *erasureRepository) erasePatient(ctx context.Context, tx *sqlx.Tx, action model.ErasureAction, plan *model.ErasurePlan) (int64, error) {
+	switch action {
+	case model.ErasureActionRetain:
+		return 1, nil
+	case model.ErasureActionDelete:
+		result, err := tx.ExecContext(ctx, `DELETE FROM patients WHERE id = $1`, plan.PatientID)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+
+	// The tombstone is written in plaintext: its values are anonymization
+	// tokens, and the ciphertext and blind indexes would still identify the
+	// patient. Date of birth is generalised to the year so age-based
+	// statistics keep working without identifying the patient.
+	query := `
+		UPDATE patients SET
+			first_name = $1,
+			last_name = $2,
+			email = $3,
+			phone = $4,
+			address = $5,
+			gender = '',
+			emergency_contact = NULL,
+			insurance_info = NULL,
+			date_of_birth = NULLIF($6, '')::date,
+			email_encrypted = NULL,
+			phone_encrypted = NULL,
+			address_encrypted = NULL,
+			date_of_birth_encrypted = NULL,
+			email_index = NULL,
+			phone_index = NULL,
+			pii_key_version = 0,
+			status = $7,
+			deleted_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $8
+	`
+	result, err := tx.ExecContext(ctx, query,
+		plan.Replacements["first_name"],
+		plan.Replacements["last_name"],
+		plan.Replacements["email"],
+		plan.Replacements["phone"],
+		plan.Replacements["address"],
+		plan.Replacements["date_of_birth"],
+		model.PatientStatusInactive,
+		plan.PatientID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type hl7Repository struct {
+	BaseRepository
+}
+
+func NewHL7Repository(base BaseRepository) repository.HL7Repository {
+	return &hl7Repository{base}
+}
+
+func (r *hl7Repository) Create(ctx context.Context, msg *model.HL7Message) error {
+	query := `
+		INSERT INTO hl7_messages (
+			id, control_id, sending_application, sending_facility, message_type,
+			status, raw, received_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	msg.ID = uuid.New()
+	_, err := r.GetDB().ExecContext(ctx, query,
+		msg.ID,
+		msg.ControlID,
+		msg.SendingApplication,
+		msg.SendingFacility,
+		msg.MessageType,
+		msg.Status,
+		msg.Raw,
+		msg.ReceivedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		return fmt.Errorf("failed to create HL7 message: %w", err)
+	}
+	return nil
+}
+
+func (r *hl7Repository) Get(ctx context.Context, id uuid.UUID) (*model.HL7Message, error) {
+	var msg model.HL7Message
+	if err := r.GetDB().GetContext(ctx, &msg, `SELECT * FROM hl7_messages WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get HL7 message: %w", err)
+	}
+	return &msg, nil
+}
+
+// FindByControlID looks up a message by the sender's control ID, which is
+// unique per sending application and facility
+func (r *hl7Repository) FindByControlID(ctx context.Context, sendingApplication, sendingFacility, controlID string) (*model.HL7Message, error) {
+	query := `
+		SELECT * FROM hl7_messages
+		WHERE sending_application = $1 AND sending_facility = $2 AND control_id = $3
+	`
+	var msg model.HL7Message
+	if err := r.GetDB().GetContext(ctx, &msg, query, sendingApplication, sendingFacility, controlID); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *hl7Repository) Update(ctx context.Context, msg *model.HL7Message) error {
+	query := `
+		UPDATE hl7_messages SET
+			status = $2, error = $3, patient_id = $4, processed_at = $5,
+			resolved_by = $6, resolution = $7
+		WHERE id = $1
+	`
+	result, err := r.GetDB().ExecContext(ctx, query,
+		msg.ID,
+		msg.Status,
+		msg.Error,
+		msg.PatientID,
+		msg.ProcessedAt,
+		msg.ResolvedBy,
+		msg.Resolution,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update HL7 message: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// List returns messages newest first without their raw content
+func (r *hl7Repository) List(ctx context.Context, status model.HL7MessageStatus, limit int) ([]*model.HL7Message, error) {
+	query := `
+		SELECT id, control_id, sending_application, sending_facility, message_type,
+			status, error, patient_id, received_at, processed_at, resolved_by, resolution
+		FROM hl7_messages
+	`
+	args := []interface{}{}
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" WHERE status = $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY received_at DESC LIMIT $%d", len(args))
+
+	var messages []*model.HL7Message
+	if err := r.GetDB().SelectContext(ctx, &messages, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list HL7 messages: %w", err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	exclusionViolation  = "23P01"
+)
+
+type identifierRepository struct {
+	BaseRepository
+}
+
+func NewIdentifierRepository(base BaseRepository) repository.IdentifierRepository {
+	return &identifierRepository{base}
+}
+
+func (r *identifierRepository) Create(ctx context.Context, identifier *model.PatientIdentifier) error {
+	return insertIdentifier(ctx, r.GetDB(), identifier)
+}
+
+func insertIdentifier(ctx context.Context, db sqlx.ExecerContext, identifier *model.PatientIdentifier) error {
+	query := `
+		INSERT INTO patient_identifiers (
+			id, patient_id, organization_id, system, value, type,
+			period_start, period_end, created_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	identifier.ID = uuid.New()
+	identifier.CreatedAt = time.Now()
+	if identifier.PeriodStart.IsZero() {
+		identifier.PeriodStart = identifier.CreatedAt
+	}
+
+	_, err := db.ExecContext(ctx, query,
+		identifier.ID,
+		identifier.PatientID,
+		identifier.OrganizationID,
+		identifier.System,
+		identifier.Value,
+		identifier.Type,
+		identifier.PeriodStart,
+		identifier.PeriodEnd,
+		identifier.CreatedBy,
+		identifier.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		return fmt.Errorf("failed to create identifier: %w", err)
+	}
+	return nil
+}
+
+func (r *identifierRepository) Get(ctx context.Context, id uuid.UUID) (*model.PatientIdentifier, error) {
+	query := `SELECT * FROM patient_identifiers WHERE id = $1`
+
+	var identifier model.PatientIdentifier
+	if err := r.GetDB().GetContext(ctx, &identifier, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get identifier: %w", err)
+	}
+	return &identifier, nil
+}
+
+func (r *identifierRepository) Update(ctx context.Context, identifier *model.PatientIdentifier) error {
+	query := `UPDATE patient_identifiers SET period_start = $1, period_end = $2 WHERE id = $3`
+
+	result, err := r.GetDB().ExecContext(ctx, query, identifier.PeriodStart, identifier.PeriodEnd, identifier.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update identifier: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("identifier not found")
+	}
+	return nil
+}
+
+func (r *identifierRepository) ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.PatientIdentifier, error) {
+	query := `
+		SELECT * FROM patient_identifiers
+		WHERE patient_id = $1
+		ORDER BY type, period_start DESC
+	`
+
+	var identifiers []*model.PatientIdentifier
+	if err := r.GetDB().SelectContext(ctx, &identifiers, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list identifiers: %w", err)
+	}
+	return identifiers, nil
+}
+
+// FindByValue matches the value among the organization's identifiers in one
+// system, or in every system when system is empty.
+func (r *identifierRepository) FindByValue(ctx context.Context, organizationID uuid.UUID, system, value string) ([]*model.PatientIdentifier, error) {
+	query := `SELECT * FROM patient_identifiers WHERE organization_id = $1 AND value = $2`
+	args := []interface{}{organizationID, value}
+
+	if system != "" {
+		query += ` AND system = $3`
+		args = append(args, system)
+	}
+
+	var identifiers []*model.PatientIdentifier
+	if err := r.GetDB().SelectContext(ctx, &identifiers, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to find identifiers: %w", err)
+	}
+	return identifiers, nil
+}
+
+func (r *identifierRepository) GetMRNConfig(ctx context.Context, clinicID uuid.UUID) (*model.MRNConfig, error) {
+	query := `SELECT * FROM clinic_mrn_configs WHERE clinic_id = $1`
+
+	var config model.MRNConfig
+	if err := r.GetDB().GetContext(ctx, &config, query, clinicID); err != nil {
+		return nil, fmt.Errorf("failed to get MRN config: %w", err)
+	}
+	return &config, nil
+}
+
+func (r *identifierRepository) SaveMRNConfig(ctx context.Context, config *model.MRNConfig, nextSequence *int64) error {
+	query := `
+		INSERT INTO clinic_mrn_configs (
+			clinic_id, system, pattern, next_sequence, check_digit, created_at, updated_at
+		) VALUES ($1, $2, $3, COALESCE($4::BIGINT, 1), $5, $6, $6)
+		ON CONFLICT (clinic_id) DO UPDATE SET
+			system = EXCLUDED.system,
+			pattern = EXCLUDED.pattern,
+			next_sequence = CASE
+				WHEN $4::BIGINT IS NULL THEN clinic_mrn_configs.next_sequence
+				ELSE GREATEST(clinic_mrn_configs.next_sequence, $4::BIGINT)
+			END,
+			check_digit = EXCLUDED.check_digit,
+			updated_at = EXCLUDED.updated_at
+		RETURNING next_sequence, created_at, updated_at
+	`
+
+	row := r.GetDB().QueryRowxContext(ctx, query,
+		config.ClinicID,
+		config.System,
+		config.Pattern,
+		nextSequence,
+		config.CheckDigit,
+		time.Now(),
+	)
+	if err := row.Scan(&config.NextSequence, &config.CreatedAt, &config.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save MRN config: %w", err)
+	}
+	return nil
+}
+
+// NextMRNSequence claims the clinic's next sequence number. The increment is a
+// single statement, so concurrent registrations never get the same number.
+func (r *identifierRepository) NextMRNSequence(ctx context.Context, clinicID uuid.UUID) (int64, error) {
+	query := `
+		UPDATE clinic_mrn_configs
+		SET next_sequence = next_sequence + 1, updated_at = NOW()
+		WHERE clinic_id = $1
+		RETURNING next_sequence - 1
+	`
+
+	var seq int64
+	if err := r.GetDB().GetContext(ctx, &seq, query, clinicID); err != nil {
+		return 0, fmt.Errorf("failed to claim MRN sequence: %w", err)
+	}
+	return seq, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
+
+func isExclusionViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == exclusionViolation
+}
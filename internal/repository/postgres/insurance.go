@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type insuranceRepository struct {
+	BaseRepository
+}
+
+func NewInsuranceRepository(base BaseRepository) repository.InsuranceRepository {
+	return &insuranceRepository{base}
+}
+
+func (r *insuranceRepository) CreateCoverage(ctx context.Context, coverage *model.InsuranceCoverage, changedBy uuid.UUID) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		query := `
+			INSERT INTO insurance_coverages (
+				id, patient_id, organization_id, payer_name, payer_id, plan_name,
+				member_number, group_number, subscriber_name, subscriber_relationship,
+				priority, status, effective_from, effective_to,
+				card_front_image, card_back_image, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		`
+
+		coverage.ID = uuid.New()
+		coverage.CreatedAt = time.Now()
+		coverage.UpdatedAt = time.Now()
+
+		_, err := tx.ExecContext(ctx, query,
+			coverage.ID,
+			coverage.PatientID,
+			coverage.OrganizationID,
+			coverage.PayerName,
+			coverage.PayerID,
+			coverage.PlanName,
+			coverage.MemberNumber,
+			coverage.GroupNumber,
+			coverage.SubscriberName,
+			coverage.SubscriberRelationship,
+			coverage.Priority,
+			coverage.Status,
+			coverage.EffectiveFrom,
+			coverage.EffectiveTo,
+			coverage.CardFrontImage,
+			coverage.CardBackImage,
+			coverage.CreatedAt,
+			coverage.UpdatedAt,
+		)
+		if err != nil {
+			// The only unique index is on the active coverage's priority
+			if isUniqueViolation(err) {
+				return repository.ErrDuplicate
+			}
+			return fmt.Errorf("failed to create coverage: %w", err)
+		}
+
+		return r.recordHistory(ctx, tx, coverage, "create", changedBy)
+	})
+}
+
+func (r *insuranceRepository) GetCoverage(ctx context.Context, id uuid.UUID) (*model.InsuranceCoverage, error) {
+	query := `SELECT * FROM insurance_coverages WHERE id = $1`
+
+	var coverage model.InsuranceCoverage
+	if err := r.GetDB().GetContext(ctx, &coverage, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get coverage: %w", err)
+	}
+	return &coverage, nil
+}
+
+func (r *insuranceRepository) UpdateCoverage(ctx context.Context, coverage *model.InsuranceCoverage, changedBy uuid.UUID) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		query := `
+			UPDATE insurance_coverages SET
+				payer_name = $1,
+				payer_id = $2,
+				plan_name = $3,
+				member_number = $4,
+				group_number = $5,
+				subscriber_name = $6,
+				subscriber_relationship = $7,
+				priority = $8,
+				status = $9,
+				effective_from = $10,
+				effective_to = $11,
+				card_front_image = $12,
+				card_back_image = $13,
+				updated_at = $14
+			WHERE id = $15
+		`
+
+		coverage.UpdatedAt = time.Now()
+		result, err := tx.ExecContext(ctx, query,
+			coverage.PayerName,
+			coverage.PayerID,
+			coverage.PlanName,
+			coverage.MemberNumber,
+			coverage.GroupNumber,
+			coverage.SubscriberName,
+			coverage.SubscriberRelationship,
+			coverage.Priority,
+			coverage.Status,
+			coverage.EffectiveFrom,
+			coverage.EffectiveTo,
+			coverage.CardFrontImage,
+			coverage.CardBackImage,
+			coverage.UpdatedAt,
+			coverage.ID,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return repository.ErrDuplicate
+			}
+			return fmt.Errorf("failed to update coverage: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return fmt.Errorf("coverage not found")
+		}
+
+		return r.recordHistory(ctx, tx, coverage, "update", changedBy)
+	})
+}
+
+func (r *insuranceRepository) ListCoverages(ctx context.Context, patientID uuid.UUID, activeOnly bool) ([]*model.InsuranceCoverage, error) {
+	query := `SELECT * FROM insurance_coverages WHERE patient_id = $1`
+	if activeOnly {
+		query += ` AND status = 'active'`
+	}
+	query += `
+		ORDER BY CASE priority
+			WHEN 'primary' THEN 1
+			WHEN 'secondary' THEN 2
+			ELSE 3
+		END, effective_from DESC
+	`
+
+	var coverages []*model.InsuranceCoverage
+	if err := r.GetDB().SelectContext(ctx, &coverages, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list coverages: %w", err)
+	}
+	return coverages, nil
+}
+
+func (r *insuranceRepository) ListCoverageHistory(ctx context.Context, coverageID uuid.UUID) ([]*model.InsuranceCoverageHistory, error) {
+	query := `
+		SELECT * FROM insurance_coverage_history
+		WHERE coverage_id = $1
+		ORDER BY changed_at DESC
+	`
+
+	var history []*model.InsuranceCoverageHistory
+	if err := r.GetDB().SelectContext(ctx, &history, query, coverageID); err != nil {
+		return nil, fmt.Errorf("failed to list coverage history: %w", err)
+	}
+	return history, nil
+}
+
+func (r *insuranceRepository) CreateEligibilityCheck(ctx context.Context, check *model.EligibilityCheck) error {
+	query := `
+		INSERT INTO eligibility_checks (
+			id, coverage_id, patient_id, appointment_id, provider, status,
+			service_date, copay, message, response, checked_by, checked_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	check.ID = uuid.New()
+	if check.CheckedAt.IsZero() {
+		check.CheckedAt = time.Now()
+	}
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		check.ID,
+		check.CoverageID,
+		check.PatientID,
+		check.AppointmentID,
+		check.Provider,
+		check.Status,
+		check.ServiceDate,
+		check.Copay,
+		check.Message,
+		check.Response,
+		check.CheckedBy,
+		check.CheckedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create eligibility check: %w", err)
+	}
+	return nil
+}
+
+func (r *insuranceRepository) ListEligibilityChecks(ctx context.Context, coverageID uuid.UUID) ([]*model.EligibilityCheck, error) {
+	query := `
+		SELECT * FROM eligibility_checks
+		WHERE coverage_id = $1
+		ORDER BY checked_at DESC
+	`
+
+	var checks []*model.EligibilityCheck
+	if err := r.GetDB().SelectContext(ctx, &checks, query, coverageID); err != nil {
+		return nil, fmt.Errorf("failed to list eligibility checks: %w", err)
+	}
+	return checks, nil
+}
+
+func (r *insuranceRepository) recordHistory(ctx context.Context, tx *sqlx.Tx, coverage *model.InsuranceCoverage, action string, changedBy uuid.UUID) error {
+	snapshot, err := json.Marshal(coverage)
+	if err != nil {
+		return fmt.Errorf("failed to marshal coverage snapshot: %w", err)
+	}
+
+	query := `
+		INSERT INTO insurance_coverage_history (
+			id, coverage_id, action, snapshot, changed_by, changed_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := tx.ExecContext(ctx, query, uuid.New(), coverage.ID, action, snapshot, changedBy, time.Now()); err != nil {
+		return fmt.Errorf("failed to record coverage history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type legalHoldRepository struct {
+	BaseRepository
+}
+
+func NewLegalHoldRepository(base BaseRepository) repository.LegalHoldRepository {
+	return &legalHoldRepository{base}
+}
+
+func (r *legalHoldRepository) Create(ctx context.Context, hold *model.LegalHold) error {
+	query := `
+		INSERT INTO legal_holds (
+			id, organization_id, patient_id, reason, categories, placed_by, placed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	hold.ID = uuid.New()
+	hold.PlacedAt = time.Now()
+	if hold.Categories == nil {
+		hold.Categories = pq.StringArray{}
+	}
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		hold.ID,
+		hold.OrganizationID,
+		hold.PatientID,
+		hold.Reason,
+		hold.Categories,
+		hold.PlacedBy,
+		hold.PlacedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create legal hold: %w", err)
+	}
+	return nil
+}
+
+func (r *legalHoldRepository) Get(ctx context.Context, id uuid.UUID) (*model.LegalHold, error) {
+	query := `SELECT * FROM legal_holds WHERE id = $1`
+
+	var hold model.LegalHold
+	if err := r.GetDB().GetContext(ctx, &hold, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+func (r *legalHoldRepository) Release(ctx context.Context, hold *model.LegalHold) error {
+	query := `
+		UPDATE legal_holds SET released_by = $1, released_at = $2
+		WHERE id = $3 AND released_at IS NULL
+	`
+
+	result, err := r.GetDB().ExecContext(ctx, query, hold.ReleasedBy, hold.ReleasedAt, hold.ID)
+	if err != nil {
+		return fmt.Errorf("failed to release legal hold: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("legal hold not found or already released")
+	}
+	return nil
+}
+
+func (r *legalHoldRepository) ListByPatient(ctx context.Context, patientID uuid.UUID, activeOnly bool) ([]*model.LegalHold, error) {
+	query := `SELECT * FROM legal_holds WHERE patient_id = $1`
+	if activeOnly {
+		query += ` AND released_at IS NULL`
+	}
+	query += ` ORDER BY placed_at DESC`
+
+	var holds []*model.LegalHold
+	if err := r.GetDB().SelectContext(ctx, &holds, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	return holds, nil
+}
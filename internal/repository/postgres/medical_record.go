@@ -2,7 +2,9 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -60,6 +62,24 @@ func (r *medicalRecordRepository) List(ctx context.Context, patientID uuid.UUID,
 		args = append(args, filters.EndDate)
 	}
 
+	// Restricted records never leave the database for readers who may not
+	// see them: private ones need care team membership, HIPAA ones also a
+	// stated reason
+	if access := filters.Access; access != nil {
+		if access.PublicOnly {
+			query += " AND access_level = 'public'"
+		} else {
+			query += fmt.Sprintf(` AND (access_level = 'public' OR EXISTS (
+				SELECT 1 FROM care_team_members ct
+				WHERE ct.patient_id = medical_records.patient_id AND ct.user_id = $%d
+			))`, len(args)+1)
+			args = append(args, access.UserID)
+			if !access.IncludeHIPAA {
+				query += " AND access_level IS DISTINCT FROM 'hipaa'"
+			}
+		}
+	}
+
 	query += " ORDER BY created_at DESC"
 
 	var records []*model.MedicalRecord
@@ -77,107 +97,281 @@ func (r *medicalRecordRepository) List(ctx context.Context, patientID uuid.UUID,
 	return records, nil
 }
 
-func (r *medicalRecordRepository) CreateWithAudit(ctx context.Context, record *model.MedicalRecord) error {
+func (r *medicalRecordRepository) CreateWithAudit(ctx context.Context, records ...*model.MedicalRecord) error {
 	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
-		query := `
+		for _, record := range records {
+			if err := r.insertRecord(ctx, tx, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *medicalRecordRepository) insertRecord(ctx context.Context, tx *sqlx.Tx, record *model.MedicalRecord) error {
+	query := `
 			INSERT INTO medical_records (
-				id, patient_id, type, description, diagnosis,
+				id, patient_id, organization_id, type, description, diagnosis,
 				treatment, medications, attachments, access_level,
-				created_by, region_code, created_at, updated_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+				created_by, region_code, created_at, updated_at, version,
+				encryption_key_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, 1, $15)
 		`
 
-		medications, err := json.Marshal(record.Medications)
-		if err != nil {
-			return fmt.Errorf("failed to marshal medications: %w", err)
-		}
+	record.Version = 1
 
-		attachments, err := json.Marshal(record.Attachments)
-		if err != nil {
-			return fmt.Errorf("failed to marshal attachments: %w", err)
-		}
+	medications, err := json.Marshal(record.Medications)
+	if err != nil {
+		return fmt.Errorf("failed to marshal medications: %w", err)
+	}
 
-		_, err = tx.ExecContext(ctx, query,
-			record.ID,
-			record.PatientID,
-			record.Type,
-			record.Description,
-			record.Diagnosis,
-			record.Treatment,
-			medications,
-			attachments,
-			record.AccessLevel,
-			record.CreatedBy,
-			r.GetRegionFromContext(ctx),
-			record.CreatedAt,
-			record.UpdatedAt,
-		)
+	attachments, err := json.Marshal(record.Attachments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attachments: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, query,
+		record.ID,
+		record.PatientID,
+		record.OrganizationID,
+		record.Type,
+		record.Description,
+		record.Diagnosis,
+		record.Treatment,
+		medications,
+		attachments,
+		record.AccessLevel,
+		record.CreatedBy,
+		r.GetRegionFromContext(ctx),
+		record.CreatedAt,
+		record.UpdatedAt,
+		record.EncryptionKeyID,
+	)
+	if err != nil {
 		return err
-	})
+	}
+
+	if err := r.saveCodes(ctx, tx, record); err != nil {
+		return err
+	}
+
+	return r.insertVersion(ctx, tx, record, &model.RecordRevision{
+		AuthorID: record.CreatedBy,
+		Reason:   "created",
+	}, medications, attachments)
 }
 
-func (r *medicalRecordRepository) UpdateWithAudit(ctx context.Context, record *model.MedicalRecord) error {
+// UpdateWithAudit writes a new version of the record. record.Version must be
+// the version the change was based on; if the record has moved on since,
+// ErrVersionConflict is returned instead of overwriting the newer content.
+func (r *medicalRecordRepository) UpdateWithAudit(ctx context.Context, record *model.MedicalRecord, rev *model.RecordRevision) error {
 	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
-		record.UpdatedAt = time.Now()
+		return r.updateVersioned(ctx, tx, record, rev)
+	})
+}
 
-		query := `
-			UPDATE medical_records SET
-				type = $1,
-				description = $2,
-				diagnosis = $3,
-				treatment = $4,
-				medications = $5,
-				attachments = $6,
-				access_level = $7,
-				updated_at = $8
-			WHERE id = $9 AND deleted_at IS NULL
-		`
+func (r *medicalRecordRepository) updateVersioned(ctx context.Context, tx *sqlx.Tx, record *model.MedicalRecord, rev *model.RecordRevision) error {
+	if rev == nil || rev.Reason == "" {
+		return fmt.Errorf("a reason is required to change a medical record")
+	}
 
-		medications, err := json.Marshal(record.Medications)
-		if err != nil {
-			return fmt.Errorf("failed to marshal medications: %w", err)
-		}
+	record.UpdatedAt = time.Now()
 
-		attachments, err := json.Marshal(record.Attachments)
-		if err != nil {
-			return fmt.Errorf("failed to marshal attachments: %w", err)
-		}
+	query := `
+		UPDATE medical_records SET
+			type = $1,
+			description = $2,
+			diagnosis = $3,
+			treatment = $4,
+			medications = $5,
+			attachments = $6,
+			access_level = $7,
+			updated_at = $8,
+			encryption_key_id = $9,
+			version = version + 1
+		WHERE id = $10 AND version = $11 AND deleted_at IS NULL
+		RETURNING version
+	`
 
-		result, err := tx.ExecContext(ctx, query,
-			record.Type,
-			record.Description,
-			record.Diagnosis,
-			record.Treatment,
-			medications,
-			attachments,
-			record.AccessLevel,
-			record.UpdatedAt,
-			record.ID,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to update medical record: %w", err)
+	medications, err := json.Marshal(record.Medications)
+	if err != nil {
+		return fmt.Errorf("failed to marshal medications: %w", err)
+	}
+
+	attachments, err := json.Marshal(record.Attachments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attachments: %w", err)
+	}
+
+	var version int
+	err = tx.GetContext(ctx, &version, query,
+		record.Type,
+		record.Description,
+		record.Diagnosis,
+		record.Treatment,
+		medications,
+		attachments,
+		record.AccessLevel,
+		record.UpdatedAt,
+		record.EncryptionKeyID,
+		record.ID,
+		record.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM medical_records WHERE id = $1 AND deleted_at IS NULL)`, record.ID); err != nil {
+			return fmt.Errorf("failed to check medical record: %w", err)
+		}
+		if exists {
+			return repository.ErrVersionConflict
 		}
+		return fmt.Errorf("medical record not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update medical record: %w", err)
+	}
+	record.Version = version
 
-		rows, err := result.RowsAffected()
+	if err := r.saveCodes(ctx, tx, record); err != nil {
+		return err
+	}
+
+	if err := r.insertVersion(ctx, tx, record, rev, medications, attachments); err != nil {
+		return err
+	}
+
+	// Create audit log
+	auditLog := &model.AuditLog{
+		ID:         uuid.New(),
+		EntityType: "medical_record",
+		EntityID:   record.ID,
+		Action:     "update",
+		UserID:     rev.AuthorID,
+		CreatedAt:  time.Now(),
+	}
+
+	return r.CreateAuditLog(ctx, tx, auditLog)
+}
+
+// saveCodes replaces the record's reportable diagnosis codes with those of
+// its current content. Nil Codes means the diagnosis was not touched, so the
+// stored codes are kept.
+func (r *medicalRecordRepository) saveCodes(ctx context.Context, tx *sqlx.Tx, record *model.MedicalRecord) error {
+	if record.Codes == nil {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM medical_record_codes WHERE record_id = $1`, record.ID); err != nil {
+		return fmt.Errorf("failed to clear record codes: %w", err)
+	}
+
+	for _, code := range record.Codes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO medical_record_codes (record_id, patient_id, system, code, recorded_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT DO NOTHING
+		`, record.ID, record.PatientID, code.System, code.Code, record.CreatedAt)
 		if err != nil {
-			return fmt.Errorf("failed to get rows affected: %w", err)
-		}
-		if rows == 0 {
-			return fmt.Errorf("medical record not found")
+			return fmt.Errorf("failed to save record code: %w", err)
 		}
+	}
+	return nil
+}
 
-		// Create audit log
-		auditLog := &model.AuditLog{
-			ID:         uuid.New(),
-			EntityType: "medical_record",
-			EntityID:   record.ID,
-			Action:     "update",
-			UserID:     record.LastAccessedBy,
-			CreatedAt:  time.Now(),
+// ReportByCode counts the organization's records and patients per diagnosis
+// code
+func (r *medicalRecordRepository) ReportByCode(ctx context.Context, filter *model.CodeReportFilter, includeDescendants bool) ([]*model.CodeReportRow, error) {
+	query := `
+		SELECT c.system, c.code, COALESCE(t.display, '') AS display,
+			COUNT(DISTINCT c.record_id) AS records,
+			COUNT(DISTINCT c.patient_id) AS patients
+		FROM medical_record_codes c
+		JOIN medical_records m ON m.id = c.record_id AND m.deleted_at IS NULL
+		LEFT JOIN terminology_concepts t ON t.system = c.system AND t.code = c.code
+		WHERE m.organization_id = $1 AND c.system = $2
+	`
+	args := []interface{}{filter.OrganizationID, filter.System}
+
+	if filter.Code != "" {
+		args = append(args, filter.Code)
+		if includeDescendants {
+			query += fmt.Sprintf(" AND (c.code = $%d OR c.code LIKE $%d || '%%')", len(args), len(args))
+		} else {
+			query += fmt.Sprintf(" AND c.code = $%d", len(args))
 		}
+	}
 
-		return r.CreateAuditLog(ctx, tx, auditLog)
-	})
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		query += fmt.Sprintf(" AND c.recorded_at >= $%d", len(args))
+	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		query += fmt.Sprintf(" AND c.recorded_at < $%d", len(args))
+	}
+
+	query += " GROUP BY c.system, c.code, t.display ORDER BY records DESC, c.code"
+
+	var rows []*model.CodeReportRow
+	if err := r.GetDB().SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to report records by code: %w", err)
+	}
+	return rows, nil
+}
+
+// insertVersion appends the record's current content to its history
+func (r *medicalRecordRepository) insertVersion(ctx context.Context, tx *sqlx.Tx, record *model.MedicalRecord, rev *model.RecordRevision, medications, attachments []byte) error {
+	query := `
+		INSERT INTO medical_record_versions (
+			id, record_id, version, type, description, diagnosis, treatment,
+			medications, attachments, access_level, author_id, reason,
+			amendment_id, encryption_key_id, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+	_, err := tx.ExecContext(ctx, query,
+		uuid.New(),
+		record.ID,
+		record.Version,
+		record.Type,
+		record.Description,
+		record.Diagnosis,
+		record.Treatment,
+		medications,
+		attachments,
+		record.AccessLevel,
+		rev.AuthorID,
+		rev.Reason,
+		rev.AmendmentID,
+		record.EncryptionKeyID,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record medical record version: %w", err)
+	}
+	return nil
+}
+
+func (r *medicalRecordRepository) ListVersions(ctx context.Context, recordID uuid.UUID) ([]*model.MedicalRecordVersion, error) {
+	query := `
+		SELECT * FROM medical_record_versions
+		WHERE record_id = $1
+		ORDER BY version DESC
+	`
+	var versions []*model.MedicalRecordVersion
+	if err := r.GetDB().SelectContext(ctx, &versions, query, recordID); err != nil {
+		return nil, fmt.Errorf("failed to list medical record versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (r *medicalRecordRepository) GetVersion(ctx context.Context, recordID uuid.UUID, version int) (*model.MedicalRecordVersion, error) {
+	query := `SELECT * FROM medical_record_versions WHERE record_id = $1 AND version = $2`
+	var v model.MedicalRecordVersion
+	if err := r.GetDB().GetContext(ctx, &v, query, recordID, version); err != nil {
+		return nil, fmt.Errorf("failed to get medical record version: %w", err)
+	}
+	return &v, nil
 }
 
 func (r *medicalRecordRepository) unmarshalRecordFields(record *model.MedicalRecord) error {
@@ -251,3 +445,75 @@ func (r *medicalRecordRepository) Delete(ctx context.Context, id uuid.UUID) erro
 		return r.CreateAuditLog(ctx, tx, auditLog)
 	})
 }
+
+// ReencryptBatch locks up to limit records, then up to limit versions, whose
+// encrypted fields are not under their organization's active data key and
+// passes each to reencrypt. Rows locked by another worker are skipped, so
+// several instances can share the job.
+func (r *medicalRecordRepository) ReencryptBatch(ctx context.Context, limit int, reencrypt func(*model.EncryptedFields) error) (int, error) {
+	var done int
+	err := r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var records []*model.EncryptedFields
+		err := tx.SelectContext(ctx, &records, `
+			SELECT m.id, m.organization_id, m.diagnosis, m.treatment, m.encryption_key_id
+			FROM medical_records m
+			WHERE m.organization_id IS NOT NULL
+				AND (m.diagnosis IS NOT NULL OR m.treatment IS NOT NULL)
+				AND NOT EXISTS (
+					SELECT 1 FROM data_encryption_keys k
+					WHERE k.id = m.encryption_key_id AND k.status = 'active'
+				)
+			LIMIT $1
+			FOR UPDATE OF m SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return fmt.Errorf("failed to select records to re-encrypt: %w", err)
+		}
+		for _, f := range records {
+			if err := reencrypt(f); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `
+				UPDATE medical_records SET diagnosis = $1, treatment = $2, encryption_key_id = $3
+				WHERE id = $4
+			`, f.Diagnosis, f.Treatment, f.EncryptionKeyID, f.ID)
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt medical record: %w", err)
+			}
+		}
+
+		var versions []*model.EncryptedFields
+		err = tx.SelectContext(ctx, &versions, `
+			SELECT v.id, m.organization_id, v.diagnosis, v.treatment, v.encryption_key_id
+			FROM medical_record_versions v
+			JOIN medical_records m ON m.id = v.record_id
+			WHERE m.organization_id IS NOT NULL
+				AND (v.diagnosis IS NOT NULL OR v.treatment IS NOT NULL)
+				AND NOT EXISTS (
+					SELECT 1 FROM data_encryption_keys k
+					WHERE k.id = v.encryption_key_id AND k.status = 'active'
+				)
+			LIMIT $1
+			FOR UPDATE OF v SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return fmt.Errorf("failed to select record versions to re-encrypt: %w", err)
+		}
+		for _, f := range versions {
+			if err := reencrypt(f); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `
+				UPDATE medical_record_versions SET diagnosis = $1, treatment = $2, encryption_key_id = $3
+				WHERE id = $4
+			`, f.Diagnosis, f.Treatment, f.EncryptionKeyID, f.ID)
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt medical record version: %w", err)
+			}
+		}
+
+		done = len(records) + len(versions)
+		return nil
+	})
+	return done, err
+}
@@ -2,7 +2,9 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository"
 )
@@ -18,10 +20,10 @@ func NewNotificationRepository(base BaseRepository) repository.NotificationRepos
 }
 
 func (r *notificationRepository) Create(ctx context.Context, notification *model.Notification) error {
-	query := `INSERT INTO notifications (user_id, organization_id, channel, recipient, subject, content, status, created_at, updated_at) 
-             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	query := `INSERT INTO notifications (user_id, organization_id, patient_id, channel, recipient, subject, content, status, created_at, updated_at) 
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
 	return r.GetDB().QueryRowContext(ctx, query,
-		notification.UserID, notification.OrganizationID, notification.Channel,
+		notification.UserID, notification.OrganizationID, notification.PatientID, notification.Channel,
 		notification.Recipient, notification.Subject, notification.Content,
 		notification.Status, notification.CreatedAt, notification.UpdatedAt).Scan(&notification.ID)
 }
@@ -33,3 +35,22 @@ func (r *notificationRepository) Update(ctx context.Context, notification *model
 		notification.RetryCount, notification.NextRetryAt, notification.ID)
 	return err
 }
+
+func (r *notificationRepository) ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.Notification, error) {
+	query := `
+		SELECT id, user_id, organization_id, patient_id, channel,
+			COALESCE(priority, '') AS priority, COALESCE(subject, '') AS subject,
+			content, recipient, status, retry_count, COALESCE(last_error, '') AS last_error,
+			COALESCE(next_retry_at, 'epoch') AS next_retry_at, COALESCE(sent_at, 'epoch') AS sent_at,
+			created_at, updated_at
+		FROM notifications
+		WHERE patient_id = $1
+		ORDER BY created_at DESC
+	`
+
+	var notifications []*model.Notification
+	if err := r.GetDB().SelectContext(ctx, &notifications, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return notifications, nil
+}
@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type observationRepository struct {
+	BaseRepository
+}
+
+func NewObservationRepository(base BaseRepository) repository.ObservationRepository {
+	return &observationRepository{base}
+}
+
+// CreateBatch saves observations taken together; either all are saved or
+// none are
+func (r *observationRepository) CreateBatch(ctx context.Context, observations []*model.Observation) error {
+	query := `
+		INSERT INTO observations (
+			id, patient_id, organization_id, appointment_id, type, code, value, unit,
+			original_value, original_unit, flag, reference_low, reference_high,
+			status, observed_at, recorded_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`
+
+	now := time.Now()
+	err := r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		for _, o := range observations {
+			o.ID = uuid.New()
+			o.CreatedAt = now
+			o.UpdatedAt = now
+			_, err := tx.ExecContext(ctx, query,
+				o.ID,
+				o.PatientID,
+				o.OrganizationID,
+				o.AppointmentID,
+				o.Type,
+				o.Code,
+				o.Value,
+				o.Unit,
+				o.OriginalValue,
+				o.OriginalUnit,
+				o.Flag,
+				o.ReferenceLow,
+				o.ReferenceHigh,
+				o.Status,
+				o.ObservedAt,
+				o.RecordedBy,
+				o.CreatedAt,
+				o.UpdatedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create observations: %w", err)
+	}
+	return nil
+}
+
+func (r *observationRepository) Get(ctx context.Context, id uuid.UUID) (*model.Observation, error) {
+	var o model.Observation
+	if err := r.GetDB().GetContext(ctx, &o, `SELECT * FROM observations WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get observation: %w", err)
+	}
+	return &o, nil
+}
+
+func (r *observationRepository) List(ctx context.Context, patientID uuid.UUID, filter *model.ObservationFilter) ([]*model.Observation, error) {
+	query := `SELECT * FROM observations WHERE patient_id = $1`
+	args := []interface{}{patientID}
+	if !filter.IncludeErrors {
+		query += " AND status = 'final'"
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		query += fmt.Sprintf(" AND type = $%d", len(args))
+	}
+	if filter.AppointmentID != nil {
+		args = append(args, *filter.AppointmentID)
+		query += fmt.Sprintf(" AND appointment_id = $%d", len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		query += fmt.Sprintf(" AND observed_at >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		query += fmt.Sprintf(" AND observed_at < $%d", len(args))
+	}
+	query += " ORDER BY observed_at DESC, type"
+
+	var observations []*model.Observation
+	if err := r.GetDB().SelectContext(ctx, &observations, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list observations: %w", err)
+	}
+	return observations, nil
+}
+
+func (r *observationRepository) UpdateStatus(ctx context.Context, o *model.Observation) error {
+	query := `
+		UPDATE observations SET status = $2, status_reason = $3, updated_at = $4
+		WHERE id = $1
+	`
+
+	o.UpdatedAt = time.Now()
+	result, err := r.GetDB().ExecContext(ctx, query, o.ID, o.Status, o.StatusReason, o.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update observation: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Trend aligns buckets to the Unix epoch so the same interval always cuts
+// a series at the same instants
+func (r *observationRepository) Trend(ctx context.Context, patientID uuid.UUID, obsType model.ObservationType, from, to time.Time, interval time.Duration) ([]*model.TrendPoint, error) {
+	query := `
+		SELECT to_timestamp(floor(extract(epoch FROM observed_at) / $5) * $5) AS bucket,
+			COUNT(*) AS count,
+			MIN(value) AS min,
+			MAX(value) AS max,
+			AVG(value) AS mean,
+			COUNT(*) FILTER (WHERE flag IS NOT NULL AND flag <> 'N') AS abnormal
+		FROM observations
+		WHERE patient_id = $1 AND type = $2 AND status = 'final'
+			AND observed_at >= $3 AND observed_at < $4
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+
+	var points []*model.TrendPoint
+	if err := r.GetDB().SelectContext(ctx, &points, query, patientID, obsType, from, to, interval.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to query observation trend: %w", err)
+	}
+	return points, nil
+}
+
+func (r *observationRepository) ListReferenceRanges(ctx context.Context, organizationID *uuid.UUID, obsType model.ObservationType) ([]*model.ReferenceRange, error) {
+	query := `SELECT * FROM observation_reference_ranges WHERE (organization_id IS NULL OR organization_id = $1)`
+	args := []interface{}{organizationID}
+	if obsType != "" {
+		query += " AND type = $2"
+		args = append(args, obsType)
+	}
+	query += " ORDER BY type, organization_id NULLS FIRST, sex, min_age_months"
+
+	var ranges []*model.ReferenceRange
+	if err := r.GetDB().SelectContext(ctx, &ranges, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list reference ranges: %w", err)
+	}
+	return ranges, nil
+}
+
+func (r *observationRepository) CreateReferenceRange(ctx context.Context, rng *model.ReferenceRange) error {
+	query := `
+		INSERT INTO observation_reference_ranges (
+			id, organization_id, type, sex, min_age_months, max_age_months,
+			low, high, critical_low, critical_high, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	rng.ID = uuid.New()
+	rng.CreatedAt = time.Now()
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		rng.ID,
+		rng.OrganizationID,
+		rng.Type,
+		rng.Sex,
+		rng.MinAgeMonths,
+		rng.MaxAgeMonths,
+		rng.Low,
+		rng.High,
+		rng.CriticalLow,
+		rng.CriticalHigh,
+		rng.CreatedAt,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create reference range: %w", err)
+	}
+	return nil
+}
+
+func (r *observationRepository) GetReferenceRange(ctx context.Context, id uuid.UUID) (*model.ReferenceRange, error) {
+	var rng model.ReferenceRange
+	if err := r.GetDB().GetContext(ctx, &rng, `SELECT * FROM observation_reference_ranges WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get reference range: %w", err)
+	}
+	return &rng, nil
+}
+
+func (r *observationRepository) DeleteReferenceRange(ctx context.Context, id uuid.UUID) error {
+	result, err := r.GetDB().ExecContext(ctx, `DELETE FROM observation_reference_ranges WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete reference range: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
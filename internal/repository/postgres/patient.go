@@ -11,14 +11,19 @@ import (
 
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/pkg/security"
 )
 
 type patientRepository struct {
 	BaseRepository
+	cipher *security.FieldCipher
 }
 
-func NewPatientRepository(base BaseRepository) repository.PatientRepository {
-	return &patientRepository{base}
+// NewPatientRepository returns the patient repository. With a cipher, email,
+// phone, address and date of birth are encrypted on write and decrypted on
+// read; a nil cipher keeps them in plaintext.
+func NewPatientRepository(base BaseRepository, cipher *security.FieldCipher) repository.PatientRepository {
+	return &patientRepository{BaseRepository: base, cipher: cipher}
 }
 
 func (r *patientRepository) Create(ctx context.Context, patient *model.Patient) error {
@@ -28,8 +33,11 @@ func (r *patientRepository) Create(ctx context.Context, patient *model.Patient)
 				id, clinic_id, organization_id, first_name, last_name,
 				email, phone, date_of_birth, gender, address,
 				emergency_contact, insurance_info, status, region_code,
-				created_at, updated_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+				created_at, updated_at,
+				email_encrypted, phone_encrypted, address_encrypted, date_of_birth_encrypted,
+				email_index, phone_index, pii_key_version, locale, timezone, user_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+				$17, $18, $19, $20, $21, $22, $23, COALESCE(NULLIF($24, ''), 'en'), COALESCE(NULLIF($25, ''), 'UTC'), $26)
 		`
 
 		patient.ID = uuid.New()
@@ -46,25 +54,50 @@ func (r *patientRepository) Create(ctx context.Context, patient *model.Patient)
 			return fmt.Errorf("failed to marshal insurance info: %w", err)
 		}
 
+		pii, err := r.sealPII(patient.Email, patient.Phone, patient.Address, patient.DateOfBirth)
+		if err != nil {
+			return err
+		}
+
 		_, err = tx.ExecContext(ctx, query,
 			patient.ID,
 			patient.ClinicID,
 			patient.OrganizationID,
 			patient.FirstName,
 			patient.LastName,
-			patient.Email,
-			patient.Phone,
-			patient.DateOfBirth,
+			pii.email,
+			pii.phone,
+			pii.dateOfBirth,
 			patient.Gender,
-			patient.Address,
+			pii.address,
 			emergencyContact,
 			insuranceInfo,
 			patient.Status,
 			r.GetRegionFromContext(ctx),
 			patient.CreatedAt,
 			patient.UpdatedAt,
+			pii.emailEnc,
+			pii.phoneEnc,
+			pii.addressEnc,
+			pii.dobEnc,
+			pii.emailIndex,
+			pii.phoneIndex,
+			pii.keyVersion,
+			patient.Locale,
+			patient.Timezone,
+			patient.UserID,
 		)
-		return err
+		if err != nil {
+			return err
+		}
+
+		for _, identifier := range patient.Identifiers {
+			identifier.PatientID = patient.ID
+			if err := insertIdentifier(ctx, tx, identifier); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 }
 
@@ -73,22 +106,59 @@ func (r *patientRepository) Get(ctx context.Context, id uuid.UUID) (*model.Patie
 		SELECT * FROM patients 
 		WHERE id = $1 AND deleted_at IS NULL
 	`
-	var patient model.Patient
-	if err := r.db.GetContext(ctx, &patient, query, id); err != nil {
+	var row patientRow
+	if err := r.db.GetContext(ctx, &row, query, id); err != nil {
 		return nil, fmt.Errorf("failed to get patient: %w", err)
 	}
 
+	patient, err := r.openPII(&row)
+	if err != nil {
+		return nil, err
+	}
+
 	// Unmarshal JSON fields
-	if err := r.unmarshalPatientFields(&patient); err != nil {
+	if err := r.unmarshalPatientFields(patient); err != nil {
 		return nil, err
 	}
 
-	return &patient, nil
+	return patient, nil
 }
 
 func (r *patientRepository) Update(ctx context.Context, patient *model.Patient) error {
-	query := `UPDATE patients SET name = $1, email = $2, status = $3, updated_at = $4 WHERE id = $5`
-	_, err := r.db.ExecContext(ctx, query, patient.Name, patient.Email, patient.Status, time.Now(), patient.ID)
+	pii, err := r.sealPII(patient.Email, patient.Phone, patient.Address, patient.DateOfBirth)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE patients SET
+			name = $1, status = $2, updated_at = $3,
+			email = $4, phone = $5, address = $6, date_of_birth = $7,
+			email_encrypted = $8, phone_encrypted = $9,
+			address_encrypted = $10, date_of_birth_encrypted = $11,
+			email_index = $12, phone_index = $13, pii_key_version = $14,
+			locale = COALESCE(NULLIF($16, ''), locale),
+			timezone = COALESCE(NULLIF($17, ''), timezone)
+		WHERE id = $15
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		patient.Name, patient.Status, time.Now(),
+		pii.email, pii.phone, pii.address, pii.dateOfBirth,
+		pii.emailEnc, pii.phoneEnc, pii.addressEnc, pii.dobEnc,
+		pii.emailIndex, pii.phoneIndex, pii.keyVersion,
+		patient.ID, patient.Locale, patient.Timezone,
+	)
+	return err
+}
+
+func (r *patientRepository) UpdateEmergencyContact(ctx context.Context, patientID uuid.UUID, contact *model.EmergencyContact) error {
+	data, err := json.Marshal(contact)
+	if err != nil {
+		return fmt.Errorf("failed to marshal emergency contact: %w", err)
+	}
+
+	query := `UPDATE patients SET emergency_contact = $1, updated_at = $2 WHERE id = $3`
+	_, err = r.db.ExecContext(ctx, query, data, time.Now(), patientID)
 	return err
 }
 
@@ -120,34 +190,83 @@ func (r *patientRepository) List(ctx context.Context, filters *model.PatientFilt
 		args = append(args, filters.Status)
 	}
 
+	if filters.Identifier != "" {
+		query += fmt.Sprintf(" AND id IN (SELECT patient_id FROM patient_identifiers WHERE value = $%d", len(args)+1)
+		args = append(args, filters.Identifier)
+		if filters.IdentifierSystem != "" {
+			query += fmt.Sprintf(" AND system = $%d", len(args)+1)
+			args = append(args, filters.IdentifierSystem)
+		}
+		query += ")"
+	}
+
+	if filters.Email != "" {
+		query += " AND " + r.exactMatch("LOWER(TRIM(email))", "email_index", blindIndexEmail, normalizeEmail(filters.Email), &args)
+	}
+
+	if filters.Phone != "" {
+		query += " AND " + r.exactMatch("regexp_replace(phone, '[^0-9]', '', 'g')", "phone_index", blindIndexPhone, normalizePhone(filters.Phone), &args)
+	}
+
 	if filters.SearchTerm != "" {
+		// Encrypted email and phone can only be matched exactly, through their
+		// blind indexes; the ILIKE arms still cover rows not yet backfilled.
 		query += fmt.Sprintf(` AND (
 			first_name ILIKE $%d OR 
 			last_name ILIKE $%d OR 
 			email ILIKE $%d OR 
-			phone ILIKE $%d
-		)`, len(args)+1, len(args)+1, len(args)+1, len(args)+1)
-		searchTerm := "%" + filters.SearchTerm + "%"
-		args = append(args, searchTerm)
+			phone ILIKE $%d OR
+			id IN (SELECT patient_id FROM patient_identifiers WHERE value ILIKE $%d)`,
+			len(args)+1, len(args)+1, len(args)+1, len(args)+1, len(args)+1)
+		args = append(args, "%"+filters.SearchTerm+"%")
+		if index := r.blindIndex(blindIndexEmail, normalizeEmail(filters.SearchTerm)); index.Valid {
+			query += fmt.Sprintf(" OR email_index = $%d", len(args)+1)
+			args = append(args, index)
+		}
+		if index := r.blindIndex(blindIndexPhone, normalizePhone(filters.SearchTerm)); index.Valid {
+			query += fmt.Sprintf(" OR phone_index = $%d", len(args)+1)
+			args = append(args, index)
+		}
+		query += ")"
 	}
 
 	query += " ORDER BY created_at DESC"
 
-	var patients []*model.Patient
-	if err := r.db.SelectContext(ctx, &patients, query, args...); err != nil {
+	var rows []*patientRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to list patients: %w", err)
 	}
 
-	// Unmarshal JSON fields for each patient
-	for _, patient := range patients {
+	patients := make([]*model.Patient, 0, len(rows))
+	for _, row := range rows {
+		patient, err := r.openPII(row)
+		if err != nil {
+			return nil, err
+		}
+		// Unmarshal JSON fields
 		if err := r.unmarshalPatientFields(patient); err != nil {
 			return nil, err
 		}
+		patients = append(patients, patient)
 	}
 
 	return patients, nil
 }
 
+// exactMatch builds a condition matching an already normalized value: by
+// blind index for encrypted rows, and by the normalized plaintext expression
+// for rows the backfill has not reached yet.
+func (r *patientRepository) exactMatch(plaintext, indexColumn, field, value string, args *[]interface{}) string {
+	*args = append(*args, value)
+	cond := fmt.Sprintf("(pii_key_version = 0 AND %s = $%d)", plaintext, len(*args))
+
+	if index := r.blindIndex(field, value); index.Valid {
+		*args = append(*args, index)
+		cond = fmt.Sprintf("(%s OR %s = $%d)", cond, indexColumn, len(*args))
+	}
+	return cond
+}
+
 func (r *patientRepository) DeletePatientAppointments(ctx context.Context, patientID uuid.UUID) error {
 	query := `DELETE FROM appointments WHERE patient_id = $1`
 	_, err := r.db.ExecContext(ctx, query, patientID)
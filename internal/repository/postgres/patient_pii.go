@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+const (
+	blindIndexEmail = "patients.email"
+	blindIndexPhone = "patients.phone"
+	dobLayout       = "2006-01-02"
+)
+
+// patientRow is the stored form of a patient. Its PII columns shadow the
+// model's so both layouts scan: rows still in plaintext (pii_key_version 0)
+// and encrypted rows whose plaintext columns are NULL.
+type patientRow struct {
+	model.Patient
+	Email                sql.NullString `db:"email"`
+	Phone                sql.NullString `db:"phone"`
+	Address              sql.NullString `db:"address"`
+	DateOfBirth          sql.NullTime   `db:"date_of_birth"`
+	EmailEncrypted       []byte         `db:"email_encrypted"`
+	PhoneEncrypted       []byte         `db:"phone_encrypted"`
+	AddressEncrypted     []byte         `db:"address_encrypted"`
+	DateOfBirthEncrypted []byte         `db:"date_of_birth_encrypted"`
+	EmailIndex           sql.NullString `db:"email_index"`
+	PhoneIndex           sql.NullString `db:"phone_index"`
+	PIIKeyVersion        int            `db:"pii_key_version"`
+}
+
+// sealedPII holds the column values written for a patient's PII
+type sealedPII struct {
+	email, phone, address                  sql.NullString
+	dateOfBirth                            sql.NullTime
+	emailEnc, phoneEnc, addressEnc, dobEnc []byte
+	emailIndex, phoneIndex                 sql.NullString
+	keyVersion                             int
+}
+
+// sealPII prepares the PII columns for a write. Without a cipher the values
+// are stored in plaintext as before.
+func (r *patientRepository) sealPII(email, phone, address string, dob time.Time) (*sealedPII, error) {
+	if r.cipher == nil {
+		return &sealedPII{
+			email:       sql.NullString{String: email, Valid: true},
+			phone:       sql.NullString{String: phone, Valid: true},
+			address:     sql.NullString{String: address, Valid: true},
+			dateOfBirth: sql.NullTime{Time: dob, Valid: true},
+		}, nil
+	}
+
+	s := &sealedPII{keyVersion: r.cipher.Version()}
+	var err error
+	if s.emailEnc, err = r.cipher.EncryptString(email); err != nil {
+		return nil, fmt.Errorf("failed to encrypt email: %w", err)
+	}
+	if s.phoneEnc, err = r.cipher.EncryptString(phone); err != nil {
+		return nil, fmt.Errorf("failed to encrypt phone: %w", err)
+	}
+	if s.addressEnc, err = r.cipher.EncryptString(address); err != nil {
+		return nil, fmt.Errorf("failed to encrypt address: %w", err)
+	}
+	if !dob.IsZero() {
+		if s.dobEnc, err = r.cipher.EncryptString(dob.Format(dobLayout)); err != nil {
+			return nil, fmt.Errorf("failed to encrypt date of birth: %w", err)
+		}
+	}
+	s.emailIndex = r.blindIndex(blindIndexEmail, normalizeEmail(email))
+	s.phoneIndex = r.blindIndex(blindIndexPhone, normalizePhone(phone))
+	return s, nil
+}
+
+// openPII returns the patient with its PII decrypted
+func (r *patientRepository) openPII(row *patientRow) (*model.Patient, error) {
+	patient := row.Patient
+
+	if row.PIIKeyVersion == 0 {
+		patient.Email = row.Email.String
+		patient.Phone = row.Phone.String
+		patient.Address = row.Address.String
+		patient.DateOfBirth = row.DateOfBirth.Time
+		return &patient, nil
+	}
+
+	if r.cipher == nil {
+		return nil, fmt.Errorf("patient %s has encrypted fields but no field cipher is configured", patient.ID)
+	}
+
+	var err error
+	if patient.Email, err = r.cipher.DecryptString(row.EmailEncrypted); err != nil {
+		return nil, fmt.Errorf("failed to decrypt email: %w", err)
+	}
+	if patient.Phone, err = r.cipher.DecryptString(row.PhoneEncrypted); err != nil {
+		return nil, fmt.Errorf("failed to decrypt phone: %w", err)
+	}
+	if patient.Address, err = r.cipher.DecryptString(row.AddressEncrypted); err != nil {
+		return nil, fmt.Errorf("failed to decrypt address: %w", err)
+	}
+	dob, err := r.cipher.DecryptString(row.DateOfBirthEncrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt date of birth: %w", err)
+	}
+	if dob != "" {
+		if patient.DateOfBirth, err = time.Parse(dobLayout, dob); err != nil {
+			return nil, fmt.Errorf("failed to parse date of birth: %w", err)
+		}
+	}
+
+	return &patient, nil
+}
+
+func (r *patientRepository) blindIndex(field, value string) sql.NullString {
+	if r.cipher == nil || value == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: r.cipher.BlindIndex(field, value), Valid: true}
+}
+
+// normalizeEmail folds case and surrounding space so lookups match however
+// the address was typed
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizePhone keeps only the digits, so formatting differences such as
+// spaces, dashes and brackets do not defeat the index
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+// EncryptPlaintextBatch encrypts up to limit patients whose PII is still in
+// plaintext and returns how many it converted. Rows are locked with SKIP
+// LOCKED so several instances can run the backfill while the API keeps
+// serving; each batch commits on its own.
+func (r *patientRepository) EncryptPlaintextBatch(ctx context.Context, limit int) (int, error) {
+	if r.cipher == nil {
+		return 0, fmt.Errorf("no field cipher configured")
+	}
+
+	var converted int
+	err := r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var rows []struct {
+			ID          uuid.UUID      `db:"id"`
+			Email       sql.NullString `db:"email"`
+			Phone       sql.NullString `db:"phone"`
+			Address     sql.NullString `db:"address"`
+			DateOfBirth sql.NullTime   `db:"date_of_birth"`
+		}
+		query := `
+			SELECT id, email, phone, address, date_of_birth
+			FROM patients
+			WHERE pii_key_version = 0
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`
+		if err := tx.SelectContext(ctx, &rows, query, limit); err != nil {
+			return fmt.Errorf("failed to select plaintext patients: %w", err)
+		}
+
+		for _, row := range rows {
+			s, err := r.sealPII(row.Email.String, row.Phone.String, row.Address.String, row.DateOfBirth.Time)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE patients SET
+					email = NULL, phone = NULL, address = NULL, date_of_birth = NULL,
+					email_encrypted = $1, phone_encrypted = $2,
+					address_encrypted = $3, date_of_birth_encrypted = $4,
+					email_index = $5, phone_index = $6, pii_key_version = $7
+				WHERE id = $8 AND pii_key_version = 0
+			`, s.emailEnc, s.phoneEnc, s.addressEnc, s.dobEnc,
+				s.emailIndex, s.phoneIndex, s.keyVersion, row.ID); err != nil {
+				return fmt.Errorf("failed to encrypt patient %s: %w", row.ID, err)
+			}
+		}
+
+		converted = len(rows)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return converted, nil
+}
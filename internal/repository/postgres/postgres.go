@@ -14,7 +14,7 @@ func NewRepositories(db *sqlx.DB) *Repositories {
 		Organization:  NewOrganizationRepository(base),
 		User:          NewUserRepository(base),
 		Appointment:   NewAppointmentRepository(base),
-		Patient:       NewPatientRepository(base),
+		Patient:       NewPatientRepository(base, nil),
 		RBAC:          NewRBACRepository(base),
 		Audit:         NewAuditRepository(base),
 		Token:         NewTokenRepository(base),
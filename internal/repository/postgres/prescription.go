@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type prescriptionRepository struct {
+	BaseRepository
+}
+
+func NewPrescriptionRepository(base BaseRepository) repository.PrescriptionRepository {
+	return &prescriptionRepository{base}
+}
+
+func (r *prescriptionRepository) Create(ctx context.Context, p *model.Prescription) error {
+	query := `
+		INSERT INTO prescriptions (
+			id, patient_id, organization_id, prescriber_id, drug_system, drug_code,
+			drug_name, strength, route, frequency, quantity, refills, instructions,
+			start_date, stop_date, status, acknowledged_warnings, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+	`
+
+	p.ID = uuid.New()
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		p.ID,
+		p.PatientID,
+		p.OrganizationID,
+		p.PrescriberID,
+		p.DrugSystem,
+		p.DrugCode,
+		p.DrugName,
+		p.Strength,
+		p.Route,
+		p.Frequency,
+		p.Quantity,
+		p.Refills,
+		p.Instructions,
+		p.StartDate,
+		p.StopDate,
+		p.Status,
+		p.AcknowledgedWarnings,
+		p.CreatedAt,
+		p.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create prescription: %w", err)
+	}
+	return nil
+}
+
+func (r *prescriptionRepository) Get(ctx context.Context, id uuid.UUID) (*model.Prescription, error) {
+	var p model.Prescription
+	if err := r.GetDB().GetContext(ctx, &p, `SELECT * FROM prescriptions WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get prescription: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *prescriptionRepository) UpdateStatus(ctx context.Context, p *model.Prescription) error {
+	query := `
+		UPDATE prescriptions SET status = $2, status_reason = $3, stop_date = $4,
+			acknowledged_warnings = $5, updated_at = $6
+		WHERE id = $1
+	`
+
+	p.UpdatedAt = time.Now()
+	result, err := r.GetDB().ExecContext(ctx, query, p.ID, p.Status, p.StatusReason, p.StopDate, p.AcknowledgedWarnings, p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update prescription: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *prescriptionRepository) ListByPatient(ctx context.Context, patientID uuid.UUID, status model.PrescriptionStatus) ([]*model.Prescription, error) {
+	query := `SELECT * FROM prescriptions WHERE patient_id = $1`
+	args := []interface{}{patientID}
+	if status != "" {
+		query += " AND status = $2"
+		args = append(args, status)
+	}
+	query += " ORDER BY start_date DESC, created_at DESC"
+
+	var prescriptions []*model.Prescription
+	if err := r.GetDB().SelectContext(ctx, &prescriptions, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list prescriptions: %w", err)
+	}
+	return prescriptions, nil
+}
+
+// FindDrugInteractions matches a drug against the patient's current
+// prescriptions. Paused prescriptions count since they may be resumed.
+// Interaction pairs are stored with drug_a < drug_b.
+func (r *prescriptionRepository) FindDrugInteractions(ctx context.Context, patientID uuid.UUID, drugCode string) ([]*model.InteractionWarning, error) {
+	query := `
+		SELECT p.id AS prescription_id, p.drug_code AS interacts_with, p.drug_name AS interacts_with_display,
+			i.severity, i.description
+		FROM prescriptions p
+		JOIN drug_interactions i ON i.drug_a = LEAST($2, p.drug_code) AND i.drug_b = GREATEST($2, p.drug_code)
+		WHERE p.patient_id = $1
+			AND p.status IN ('active', 'paused')
+			AND (p.stop_date IS NULL OR p.stop_date >= CURRENT_DATE)
+		ORDER BY p.drug_code
+	`
+	var warnings []*model.InteractionWarning
+	if err := r.GetDB().SelectContext(ctx, &warnings, query, patientID, drugCode); err != nil {
+		return nil, fmt.Errorf("failed to find drug interactions: %w", err)
+	}
+	return warnings, nil
+}
+
+// FindAllergyInteractions matches a drug against the allergy codes recorded
+// in the patient's coded diagnoses
+func (r *prescriptionRepository) FindAllergyInteractions(ctx context.Context, patientID uuid.UUID, drugCode string) ([]*model.InteractionWarning, error) {
+	query := `
+		SELECT DISTINCT c.code AS interacts_with, COALESCE(t.display, c.code) AS interacts_with_display,
+			a.severity, a.description
+		FROM medical_record_codes c
+		JOIN medical_records m ON m.id = c.record_id AND m.deleted_at IS NULL
+		JOIN drug_allergy_interactions a ON a.allergy_system = c.system AND a.allergy_code = c.code
+		LEFT JOIN terminology_concepts t ON t.system = c.system AND t.code = c.code
+		WHERE c.patient_id = $1 AND a.drug_code = $2
+		ORDER BY c.code
+	`
+	var warnings []*model.InteractionWarning
+	if err := r.GetDB().SelectContext(ctx, &warnings, query, patientID, drugCode); err != nil {
+		return nil, fmt.Errorf("failed to find allergy interactions: %w", err)
+	}
+	return warnings, nil
+}
+
+func (r *prescriptionRepository) ReplaceDrugInteractions(ctx context.Context, dataset *model.InteractionDataset, interactions []*model.DrugInteraction) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM drug_interactions`); err != nil {
+			return fmt.Errorf("failed to clear drug interactions: %w", err)
+		}
+
+		rows := make([][]interface{}, 0, len(interactions))
+		for _, i := range interactions {
+			rows = append(rows, []interface{}{i.DrugA, i.DrugB, i.Severity, i.Description})
+		}
+		if err := copyRows(ctx, tx, "drug_interactions", []string{"drug_a", "drug_b", "severity", "description"}, rows); err != nil {
+			return fmt.Errorf("failed to load drug interactions: %w", err)
+		}
+
+		dataset.EntryCount = len(rows)
+		return insertDataset(ctx, tx, dataset)
+	})
+}
+
+func (r *prescriptionRepository) ReplaceAllergyInteractions(ctx context.Context, dataset *model.InteractionDataset, interactions []*model.DrugAllergyInteraction) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM drug_allergy_interactions`); err != nil {
+			return fmt.Errorf("failed to clear allergy interactions: %w", err)
+		}
+
+		rows := make([][]interface{}, 0, len(interactions))
+		for _, i := range interactions {
+			rows = append(rows, []interface{}{i.AllergySystem, i.AllergyCode, i.DrugCode, i.Severity, i.Description})
+		}
+		columns := []string{"allergy_system", "allergy_code", "drug_code", "severity", "description"}
+		if err := copyRows(ctx, tx, "drug_allergy_interactions", columns, rows); err != nil {
+			return fmt.Errorf("failed to load allergy interactions: %w", err)
+		}
+
+		dataset.EntryCount = len(rows)
+		return insertDataset(ctx, tx, dataset)
+	})
+}
+
+func (r *prescriptionRepository) ListDatasets(ctx context.Context) ([]*model.InteractionDataset, error) {
+	var datasets []*model.InteractionDataset
+	if err := r.GetDB().SelectContext(ctx, &datasets, `SELECT * FROM interaction_datasets ORDER BY loaded_at DESC`); err != nil {
+		return nil, fmt.Errorf("failed to list interaction datasets: %w", err)
+	}
+	return datasets, nil
+}
+
+func insertDataset(ctx context.Context, tx *sqlx.Tx, dataset *model.InteractionDataset) error {
+	return tx.QueryRowxContext(ctx, `
+		INSERT INTO interaction_datasets (kind, version, file, entry_count, loaded_by, loaded_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, loaded_at
+	`, dataset.Kind, dataset.Version, dataset.File, dataset.EntryCount, dataset.LoadedBy).
+		Scan(&dataset.ID, &dataset.LoadedAt)
+}
+
+// copyRows bulk loads rows into a table with COPY
+func copyRows(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]interface{}) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
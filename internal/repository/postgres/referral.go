@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type referralRepository struct {
+	BaseRepository
+}
+
+func NewReferralRepository(base BaseRepository) repository.ReferralRepository {
+	return &referralRepository{base}
+}
+
+func (r *referralRepository) Create(ctx context.Context, ref *model.Referral) error {
+	ref.ID = uuid.New()
+	ref.CreatedAt = time.Now()
+	ref.UpdatedAt = ref.CreatedAt
+
+	_, err := r.GetDB().ExecContext(ctx, `
+		INSERT INTO referrals (
+			id, patient_id, source_organization_id, target_organization_id, referred_by,
+			specialty, reason, status, scopes, record_ids, expires_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`,
+		ref.ID,
+		ref.PatientID,
+		ref.SourceOrganizationID,
+		ref.TargetOrganizationID,
+		ref.ReferredBy,
+		ref.Specialty,
+		ref.Reason,
+		ref.Status,
+		ref.Scopes,
+		ref.RecordIDs,
+		ref.ExpiresAt,
+		ref.CreatedAt,
+		ref.UpdatedAt,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create referral: %w", err)
+	}
+	return nil
+}
+
+func (r *referralRepository) Get(ctx context.Context, id uuid.UUID) (*model.Referral, error) {
+	var ref model.Referral
+	if err := r.GetDB().GetContext(ctx, &ref, `SELECT * FROM referrals WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get referral: %w", err)
+	}
+	return &ref, nil
+}
+
+func (r *referralRepository) List(ctx context.Context, filter *model.ReferralFilter) ([]*model.Referral, error) {
+	query := `SELECT * FROM referrals WHERE `
+	args := []interface{}{filter.OrganizationID}
+	if filter.Direction == "incoming" {
+		query += "target_organization_id = $1"
+	} else {
+		query += "source_organization_id = $1"
+	}
+
+	if filter.PatientID != nil {
+		args = append(args, *filter.PatientID)
+		query += fmt.Sprintf(" AND patient_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	query += " ORDER BY created_at DESC"
+
+	referrals := []*model.Referral{}
+	if err := r.GetDB().SelectContext(ctx, &referrals, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list referrals: %w", err)
+	}
+	return referrals, nil
+}
+
+func (r *referralRepository) Update(ctx context.Context, ref *model.Referral, from model.ReferralStatus) error {
+	ref.UpdatedAt = time.Now()
+
+	result, err := r.GetDB().ExecContext(ctx, `
+		UPDATE referrals SET
+			status = $3,
+			decided_by = $4,
+			decided_at = $5,
+			decision_note = $6,
+			completed_at = $7,
+			revoked_by = $8,
+			revoked_at = $9,
+			updated_at = $10
+		WHERE id = $1 AND status = $2
+	`,
+		ref.ID,
+		from,
+		ref.Status,
+		ref.DecidedBy,
+		ref.DecidedAt,
+		ref.DecisionNote,
+		ref.CompletedAt,
+		ref.RevokedBy,
+		ref.RevokedAt,
+		ref.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update referral: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update referral: %w", err)
+	}
+	if rows == 0 {
+		return repository.ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type relationshipRepository struct {
+	BaseRepository
+}
+
+func NewRelationshipRepository(base BaseRepository) repository.RelationshipRepository {
+	return &relationshipRepository{base}
+}
+
+func (r *relationshipRepository) Create(ctx context.Context, rel *model.PatientRelationship) error {
+	query := `
+		INSERT INTO patient_relationships (
+			id, patient_id, organization_id, related_patient_id, related_user_id,
+			type, scopes, is_emergency_contact, valid_from, valid_to, notes,
+			created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	rel.ID = uuid.New()
+	rel.CreatedAt = time.Now()
+	rel.UpdatedAt = time.Now()
+	if rel.Scopes == nil {
+		rel.Scopes = pq.StringArray{}
+	}
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		rel.ID,
+		rel.PatientID,
+		rel.OrganizationID,
+		rel.RelatedPatientID,
+		rel.RelatedUserID,
+		rel.Type,
+		rel.Scopes,
+		rel.IsEmergencyContact,
+		rel.ValidFrom,
+		rel.ValidTo,
+		rel.Notes,
+		rel.CreatedBy,
+		rel.CreatedAt,
+		rel.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create relationship: %w", err)
+	}
+	return nil
+}
+
+func (r *relationshipRepository) Get(ctx context.Context, id uuid.UUID) (*model.PatientRelationship, error) {
+	query := `SELECT * FROM patient_relationships WHERE id = $1`
+
+	var rel model.PatientRelationship
+	if err := r.GetDB().GetContext(ctx, &rel, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get relationship: %w", err)
+	}
+	return &rel, nil
+}
+
+func (r *relationshipRepository) Update(ctx context.Context, rel *model.PatientRelationship) error {
+	query := `
+		UPDATE patient_relationships SET
+			scopes = $1,
+			is_emergency_contact = $2,
+			valid_to = $3,
+			notes = $4,
+			revoked_by = $5,
+			revoked_at = $6,
+			updated_at = $7
+		WHERE id = $8
+	`
+
+	rel.UpdatedAt = time.Now()
+	if rel.Scopes == nil {
+		rel.Scopes = pq.StringArray{}
+	}
+
+	result, err := r.GetDB().ExecContext(ctx, query,
+		rel.Scopes,
+		rel.IsEmergencyContact,
+		rel.ValidTo,
+		rel.Notes,
+		rel.RevokedBy,
+		rel.RevokedAt,
+		rel.UpdatedAt,
+		rel.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update relationship: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("relationship not found")
+	}
+	return nil
+}
+
+func (r *relationshipRepository) ListByPatient(ctx context.Context, patientID uuid.UUID) ([]*model.PatientRelationship, error) {
+	query := `
+		SELECT * FROM patient_relationships
+		WHERE patient_id = $1
+		ORDER BY created_at DESC
+	`
+
+	var rels []*model.PatientRelationship
+	if err := r.GetDB().SelectContext(ctx, &rels, query, patientID); err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	return rels, nil
+}
+
+func (r *relationshipRepository) ListByRelatedUser(ctx context.Context, userID uuid.UUID) ([]*model.PatientRelationship, error) {
+	query := `
+		SELECT * FROM patient_relationships
+		WHERE related_user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	var rels []*model.PatientRelationship
+	if err := r.GetDB().SelectContext(ctx, &rels, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	return rels, nil
+}
+
+func (r *relationshipRepository) FindActive(ctx context.Context, patientID, userID uuid.UUID, at time.Time) ([]*model.PatientRelationship, error) {
+	query := `
+		SELECT * FROM patient_relationships
+		WHERE patient_id = $1
+			AND related_user_id = $2
+			AND revoked_at IS NULL
+			AND valid_from <= $3
+			AND (valid_to IS NULL OR valid_to > $3)
+	`
+
+	var rels []*model.PatientRelationship
+	if err := r.GetDB().SelectContext(ctx, &rels, query, patientID, userID, at); err != nil {
+		return nil, fmt.Errorf("failed to find relationships: %w", err)
+	}
+	return rels, nil
+}
@@ -0,0 +1,272 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type reminderRepository struct {
+	BaseRepository
+}
+
+func NewReminderRepository(base BaseRepository) repository.ReminderRepository {
+	return &reminderRepository{base}
+}
+
+func (r *reminderRepository) SaveSchedule(ctx context.Context, schedule *model.ReminderSchedule) error {
+	rules, err := marshalReminderRules(schedule.Rules)
+	if err != nil {
+		return err
+	}
+
+	schedule.UpdatedAt = time.Now()
+	err = r.GetDB().QueryRowxContext(ctx, `
+		INSERT INTO reminder_schedules (
+			id, organization_id, service_id, rules, created_by, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (organization_id, COALESCE(service_id, '00000000-0000-0000-0000-000000000000'))
+		DO UPDATE SET rules = EXCLUDED.rules, updated_at = EXCLUDED.updated_at
+		RETURNING id, created_by, created_at
+	`,
+		uuid.New(),
+		schedule.OrganizationID,
+		schedule.ServiceID,
+		rules,
+		schedule.CreatedBy,
+		schedule.UpdatedAt,
+	).Scan(&schedule.ID, &schedule.CreatedBy, &schedule.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to save reminder schedule: %w", err)
+	}
+	return nil
+}
+
+func (r *reminderRepository) GetSchedule(ctx context.Context, id uuid.UUID) (*model.ReminderSchedule, error) {
+	var schedule model.ReminderSchedule
+	if err := r.GetDB().GetContext(ctx, &schedule, `SELECT * FROM reminder_schedules WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get reminder schedule: %w", err)
+	}
+	if err := unmarshalReminderRules(&schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *reminderRepository) ListSchedules(ctx context.Context, organizationID uuid.UUID) ([]*model.ReminderSchedule, error) {
+	var schedules []*model.ReminderSchedule
+	if err := r.GetDB().SelectContext(ctx, &schedules, `
+		SELECT * FROM reminder_schedules
+		WHERE organization_id = $1
+		ORDER BY service_id NULLS FIRST, created_at
+	`, organizationID); err != nil {
+		return nil, fmt.Errorf("failed to list reminder schedules: %w", err)
+	}
+	for _, schedule := range schedules {
+		if err := unmarshalReminderRules(schedule); err != nil {
+			return nil, err
+		}
+	}
+	return schedules, nil
+}
+
+func (r *reminderRepository) DeleteSchedule(ctx context.Context, id uuid.UUID) error {
+	result, err := r.GetDB().ExecContext(ctx, `DELETE FROM reminder_schedules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete reminder schedule: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("reminder schedule not found")
+	}
+	return nil
+}
+
+func (r *reminderRepository) ResolveSchedule(ctx context.Context, organizationID, serviceID uuid.UUID) (*model.ReminderSchedule, error) {
+	var schedule model.ReminderSchedule
+	err := r.GetDB().GetContext(ctx, &schedule, `
+		SELECT * FROM reminder_schedules
+		WHERE organization_id = $1 AND (service_id = $2 OR service_id IS NULL)
+		ORDER BY service_id NULLS LAST
+		LIMIT 1
+	`, organizationID, serviceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reminder schedule: %w", err)
+	}
+	if err := unmarshalReminderRules(&schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *reminderRepository) ReplaceReminders(ctx context.Context, appointmentID uuid.UUID, reminders []*model.AppointmentReminder) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := cancelReminders(ctx, tx, appointmentID); err != nil {
+			return err
+		}
+
+		for _, reminder := range reminders {
+			reminder.ID = uuid.New()
+			reminder.CreatedAt = time.Now()
+			reminder.UpdatedAt = reminder.CreatedAt
+
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO appointment_reminders (
+					id, appointment_id, organization_id, patient_id, channel,
+					offset_minutes, send_at, status, attempts, created_at, updated_at
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 0, $9, $10)
+			`,
+				reminder.ID,
+				reminder.AppointmentID,
+				reminder.OrganizationID,
+				reminder.PatientID,
+				reminder.Channel,
+				reminder.OffsetMinutes,
+				reminder.SendAt,
+				reminder.Status,
+				reminder.CreatedAt,
+				reminder.UpdatedAt,
+			); err != nil {
+				return fmt.Errorf("failed to create appointment reminder: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *reminderRepository) CancelReminders(ctx context.Context, appointmentID uuid.UUID) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		return cancelReminders(ctx, tx, appointmentID)
+	})
+}
+
+// cancelReminders cancels the reminders not yet sent. One that a worker is
+// sending is left to it; the worker checks the appointment before sending.
+func cancelReminders(ctx context.Context, tx *sqlx.Tx, appointmentID uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE appointment_reminders SET status = 'cancelled', updated_at = NOW()
+		WHERE appointment_id = $1 AND status = 'pending'
+	`, appointmentID); err != nil {
+		return fmt.Errorf("failed to cancel appointment reminders: %w", err)
+	}
+	return nil
+}
+
+func (r *reminderRepository) ListReminders(ctx context.Context, appointmentID uuid.UUID) ([]*model.AppointmentReminder, error) {
+	var reminders []*model.AppointmentReminder
+	if err := r.GetDB().SelectContext(ctx, &reminders, `
+		SELECT * FROM appointment_reminders
+		WHERE appointment_id = $1
+		ORDER BY send_at, created_at
+	`, appointmentID); err != nil {
+		return nil, fmt.Errorf("failed to list appointment reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (r *reminderRepository) ClaimDue(ctx context.Context, now, lockedUntil time.Time, limit int) ([]*model.AppointmentReminder, error) {
+	var reminders []*model.AppointmentReminder
+	if err := r.GetDB().SelectContext(ctx, &reminders, `
+		UPDATE appointment_reminders SET
+			status = 'sending',
+			attempts = attempts + 1,
+			locked_until = $2,
+			updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM appointment_reminders
+			WHERE (status = 'pending' AND send_at <= $1)
+			OR (status = 'sending' AND locked_until < $1)
+			ORDER BY send_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, now, lockedUntil, limit); err != nil {
+		return nil, fmt.Errorf("failed to claim due reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (r *reminderRepository) MarkSent(ctx context.Context, id, notificationID uuid.UUID) error {
+	if _, err := r.GetDB().ExecContext(ctx, `
+		UPDATE appointment_reminders SET
+			status = 'sent',
+			notification_id = $2,
+			sent_at = NOW(),
+			locked_until = NULL,
+			updated_at = NOW()
+		WHERE id = $1
+	`, id, notificationID); err != nil {
+		return fmt.Errorf("failed to mark reminder sent: %w", err)
+	}
+	return nil
+}
+
+func (r *reminderRepository) MarkFailed(ctx context.Context, id uuid.UUID, reason string, retryAt *time.Time) error {
+	if _, err := r.GetDB().ExecContext(ctx, `
+		UPDATE appointment_reminders SET
+			status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			send_at = COALESCE($3, send_at),
+			last_error = $2,
+			locked_until = NULL,
+			updated_at = NOW()
+		WHERE id = $1
+	`, id, reason, retryAt); err != nil {
+		return fmt.Errorf("failed to mark reminder failed: %w", err)
+	}
+	return nil
+}
+
+func (r *reminderRepository) MarkSkipped(ctx context.Context, id uuid.UUID, reason string) error {
+	if _, err := r.GetDB().ExecContext(ctx, `
+		UPDATE appointment_reminders SET
+			status = 'skipped',
+			last_error = $2,
+			locked_until = NULL,
+			updated_at = NOW()
+		WHERE id = $1
+	`, id, reason); err != nil {
+		return fmt.Errorf("failed to mark reminder skipped: %w", err)
+	}
+	return nil
+}
+
+func marshalReminderRules(rules []model.ReminderRule) (json.RawMessage, error) {
+	if rules == nil {
+		rules = []model.ReminderRule{}
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reminder rules: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalReminderRules(schedule *model.ReminderSchedule) error {
+	schedule.Rules = []model.ReminderRule{}
+	if len(schedule.RulesJSON) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(schedule.RulesJSON, &schedule.Rules); err != nil {
+		return fmt.Errorf("failed to unmarshal reminder rules: %w", err)
+	}
+	return nil
+}
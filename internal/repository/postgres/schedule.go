@@ -0,0 +1,314 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type scheduleRepository struct {
+	BaseRepository
+}
+
+func NewScheduleRepository(base BaseRepository) repository.ScheduleRepository {
+	return &scheduleRepository{base}
+}
+
+// Times of day are stored as TIME and read back as "HH:MM"
+const availabilityRuleColumns = `
+	id, organization_id, clinic_id, clinician_id, weekday,
+	to_char(start_time, 'HH24:MI') AS start_time, to_char(end_time, 'HH24:MI') AS end_time,
+	timezone, breaks, effective_from, effective_until, created_by, version, created_at, updated_at
+`
+
+func (r *scheduleRepository) CreateAvailabilityRule(ctx context.Context, rule *model.AvailabilityRule) error {
+	query := `
+		INSERT INTO clinician_availability_rules (
+			id, organization_id, clinic_id, clinician_id, weekday, start_time, end_time,
+			timezone, breaks, effective_from, effective_until, created_by,
+			version, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+
+	breaks, err := marshalBreaks(rule.Breaks)
+	if err != nil {
+		return err
+	}
+
+	rule.ID = uuid.New()
+	rule.Version = 1
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+
+	_, err = r.GetDB().ExecContext(ctx, query,
+		rule.ID,
+		rule.OrganizationID,
+		rule.ClinicID,
+		rule.ClinicianID,
+		rule.Weekday,
+		rule.StartTime,
+		rule.EndTime,
+		rule.Timezone,
+		breaks,
+		rule.EffectiveFrom,
+		rule.EffectiveUntil,
+		rule.CreatedBy,
+		rule.Version,
+		rule.CreatedAt,
+		rule.UpdatedAt,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create availability rule: %w", err)
+	}
+	rule.BreaksJSON = breaks
+	return nil
+}
+
+func (r *scheduleRepository) GetAvailabilityRule(ctx context.Context, id uuid.UUID) (*model.AvailabilityRule, error) {
+	var rule model.AvailabilityRule
+	query := `SELECT ` + availabilityRuleColumns + ` FROM clinician_availability_rules WHERE id = $1`
+	if err := r.GetDB().GetContext(ctx, &rule, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get availability rule: %w", err)
+	}
+	if err := unmarshalBreaks(&rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *scheduleRepository) ListAvailabilityRules(ctx context.Context, clinicianID uuid.UUID) ([]*model.AvailabilityRule, error) {
+	query := `SELECT ` + availabilityRuleColumns + ` FROM clinician_availability_rules
+		WHERE clinician_id = $1
+		ORDER BY weekday, start_time, effective_from`
+
+	var rules []*model.AvailabilityRule
+	if err := r.GetDB().SelectContext(ctx, &rules, query, clinicianID); err != nil {
+		return nil, fmt.Errorf("failed to list availability rules: %w", err)
+	}
+	for _, rule := range rules {
+		if err := unmarshalBreaks(rule); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+func (r *scheduleRepository) UpdateAvailabilityRule(ctx context.Context, rule *model.AvailabilityRule) error {
+	breaks, err := marshalBreaks(rule.Breaks)
+	if err != nil {
+		return err
+	}
+	rule.UpdatedAt = time.Now()
+
+	var version int
+	err = r.GetDB().GetContext(ctx, &version, `
+		UPDATE clinician_availability_rules SET
+			start_time = $3,
+			end_time = $4,
+			timezone = $5,
+			breaks = $6,
+			effective_from = $7,
+			effective_until = $8,
+			updated_at = $9,
+			version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version
+	`,
+		rule.ID,
+		rule.Version,
+		rule.StartTime,
+		rule.EndTime,
+		rule.Timezone,
+		breaks,
+		rule.EffectiveFrom,
+		rule.EffectiveUntil,
+		rule.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update availability rule: %w", err)
+	}
+	rule.Version = version
+	rule.BreaksJSON = breaks
+	return nil
+}
+
+func (r *scheduleRepository) DeleteAvailabilityRule(ctx context.Context, id uuid.UUID) error {
+	result, err := r.GetDB().ExecContext(ctx, `DELETE FROM clinician_availability_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete availability rule: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *scheduleRepository) CreateException(ctx context.Context, e *model.ScheduleException) error {
+	query := `
+		INSERT INTO clinician_schedule_exceptions (
+			id, organization_id, clinician_id, clinic_id, kind,
+			starts_at, ends_at, reason, created_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	e.ID = uuid.New()
+	e.CreatedAt = time.Now()
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		e.ID,
+		e.OrganizationID,
+		e.ClinicianID,
+		e.ClinicID,
+		e.Kind,
+		e.StartsAt,
+		e.EndsAt,
+		e.Reason,
+		e.CreatedBy,
+		e.CreatedAt,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create schedule exception: %w", err)
+	}
+	return nil
+}
+
+func (r *scheduleRepository) GetException(ctx context.Context, id uuid.UUID) (*model.ScheduleException, error) {
+	var e model.ScheduleException
+	if err := r.GetDB().GetContext(ctx, &e, `SELECT * FROM clinician_schedule_exceptions WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get schedule exception: %w", err)
+	}
+	return &e, nil
+}
+
+func (r *scheduleRepository) ListExceptions(ctx context.Context, clinicianID uuid.UUID, from, to time.Time) ([]*model.ScheduleException, error) {
+	query := `
+		SELECT * FROM clinician_schedule_exceptions
+		WHERE clinician_id = $1 AND starts_at < $3 AND ends_at > $2
+		ORDER BY starts_at
+	`
+
+	var exceptions []*model.ScheduleException
+	if err := r.GetDB().SelectContext(ctx, &exceptions, query, clinicianID, from, to); err != nil {
+		return nil, fmt.Errorf("failed to list schedule exceptions: %w", err)
+	}
+	return exceptions, nil
+}
+
+func (r *scheduleRepository) DeleteException(ctx context.Context, id uuid.UUID) error {
+	result, err := r.GetDB().ExecContext(ctx, `DELETE FROM clinician_schedule_exceptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule exception: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *scheduleRepository) CreateHoliday(ctx context.Context, h *model.Holiday) error {
+	query := `
+		INSERT INTO organization_holidays (
+			id, organization_id, clinic_id, date, name, created_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	h.ID = uuid.New()
+	h.CreatedAt = time.Now()
+
+	_, err := r.GetDB().ExecContext(ctx, query,
+		h.ID,
+		h.OrganizationID,
+		h.ClinicID,
+		h.Date.Format("2006-01-02"),
+		h.Name,
+		h.CreatedBy,
+		h.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return repository.ErrDuplicate
+		}
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create holiday: %w", err)
+	}
+	return nil
+}
+
+func (r *scheduleRepository) GetHoliday(ctx context.Context, id uuid.UUID) (*model.Holiday, error) {
+	var h model.Holiday
+	if err := r.GetDB().GetContext(ctx, &h, `SELECT * FROM organization_holidays WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get holiday: %w", err)
+	}
+	return &h, nil
+}
+
+// ListHolidays compares calendar dates, so from and to are taken as dates
+func (r *scheduleRepository) ListHolidays(ctx context.Context, organizationID uuid.UUID, from, to time.Time) ([]*model.Holiday, error) {
+	query := `
+		SELECT * FROM organization_holidays
+		WHERE organization_id = $1 AND date >= $2 AND date < $3
+		ORDER BY date, clinic_id NULLS FIRST
+	`
+
+	var holidays []*model.Holiday
+	if err := r.GetDB().SelectContext(ctx, &holidays, query,
+		organizationID,
+		from.Format("2006-01-02"),
+		to.Format("2006-01-02"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to list holidays: %w", err)
+	}
+	return holidays, nil
+}
+
+func (r *scheduleRepository) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	result, err := r.GetDB().ExecContext(ctx, `DELETE FROM organization_holidays WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete holiday: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func marshalBreaks(breaks []model.AvailabilityBreak) (json.RawMessage, error) {
+	if breaks == nil {
+		breaks = []model.AvailabilityBreak{}
+	}
+	data, err := json.Marshal(breaks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal breaks: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalBreaks(rule *model.AvailabilityRule) error {
+	rule.Breaks = []model.AvailabilityBreak{}
+	if len(rule.BreaksJSON) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(rule.BreaksJSON, &rule.Breaks); err != nil {
+		return fmt.Errorf("failed to unmarshal breaks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type serviceRepository struct {
+	BaseRepository
+}
+
+func NewServiceRepository(base BaseRepository) repository.ServiceRepository {
+	return &serviceRepository{base}
+}
+
+func (r *serviceRepository) Get(ctx context.Context, id uuid.UUID) (*model.Service, error) {
+	var service model.Service
+	if err := r.GetDB().GetContext(ctx, &service, `SELECT * FROM services WHERE id = $1 AND deleted_at IS NULL`, id); err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+	return &service, nil
+}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type terminologyRepository struct {
+	BaseRepository
+}
+
+func NewTerminologyRepository(base BaseRepository) repository.TerminologyRepository {
+	return &terminologyRepository{base}
+}
+
+// ImportRelease loads a code system release in one transaction. Concepts are
+// copied into a staging table and merged, so readers see either the old or
+// the new release; codes the release no longer contains are retired rather
+// than deleted because existing records may still use them.
+func (r *terminologyRepository) ImportRelease(ctx context.Context, release *model.TerminologyRelease, each func(yield func(*model.Concept) error) error) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			CREATE TEMP TABLE terminology_staging (
+				code TEXT NOT NULL,
+				display TEXT NOT NULL,
+				synonyms TEXT[],
+				search_text TEXT NOT NULL,
+				active BOOLEAN NOT NULL
+			) ON COMMIT DROP
+		`); err != nil {
+			return fmt.Errorf("failed to create staging table: %w", err)
+		}
+
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("terminology_staging", "code", "display", "synonyms", "search_text", "active"))
+		if err != nil {
+			return fmt.Errorf("failed to start copy: %w", err)
+		}
+
+		count := 0
+		err = each(func(c *model.Concept) error {
+			count++
+			_, err := stmt.ExecContext(ctx, c.Code, c.Display, c.Synonyms, searchText(c), c.Active)
+			return err
+		})
+		if err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to stage concepts: %w", err)
+		}
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to flush concepts: %w", err)
+		}
+		if err := stmt.Close(); err != nil {
+			return fmt.Errorf("failed to finish copy: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO terminology_concepts (system, code, display, synonyms, search_text, active, version, updated_at)
+			SELECT $1, code, display, synonyms, search_text, active, $2, NOW()
+			FROM terminology_staging
+			ON CONFLICT (system, code) DO UPDATE SET
+				display = EXCLUDED.display,
+				synonyms = EXCLUDED.synonyms,
+				search_text = EXCLUDED.search_text,
+				active = EXCLUDED.active,
+				version = EXCLUDED.version,
+				updated_at = EXCLUDED.updated_at
+		`, release.System, release.Version); err != nil {
+			return fmt.Errorf("failed to merge concepts: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE terminology_concepts SET active = false, updated_at = NOW()
+			WHERE system = $1 AND version <> $2 AND active
+		`, release.System, release.Version); err != nil {
+			return fmt.Errorf("failed to retire concepts: %w", err)
+		}
+
+		release.ConceptCount = count
+		return tx.QueryRowxContext(ctx, `
+			INSERT INTO terminology_releases (system, version, file, concept_count, loaded_by, loaded_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			RETURNING id, loaded_at
+		`, release.System, release.Version, release.File, release.ConceptCount, release.LoadedBy).
+			Scan(&release.ID, &release.LoadedAt)
+	})
+}
+
+func (r *terminologyRepository) GetConcept(ctx context.Context, system, code string) (*model.Concept, error) {
+	query := `
+		SELECT system, code, display, synonyms, active, version, updated_at
+		FROM terminology_concepts
+		WHERE system = $1 AND code = $2
+	`
+	var concept model.Concept
+	if err := r.GetDB().GetContext(ctx, &concept, query, system, code); err != nil {
+		return nil, fmt.Errorf("failed to get concept: %w", err)
+	}
+	return &concept, nil
+}
+
+func (r *terminologyRepository) GetConcepts(ctx context.Context, system string, codes []string) ([]*model.Concept, error) {
+	query := `
+		SELECT system, code, display, synonyms, active, version, updated_at
+		FROM terminology_concepts
+		WHERE system = $1 AND code = ANY($2)
+	`
+	var concepts []*model.Concept
+	if err := r.GetDB().SelectContext(ctx, &concepts, query, system, pq.Array(codes)); err != nil {
+		return nil, fmt.Errorf("failed to get concepts: %w", err)
+	}
+	return concepts, nil
+}
+
+// SearchConcepts is the typeahead query: every word of the query must occur
+// in the code, display or a synonym. Code prefix matches rank first, then
+// shorter displays.
+func (r *terminologyRepository) SearchConcepts(ctx context.Context, system, query string, limit int) ([]*model.Concept, error) {
+	sql := `
+		SELECT system, code, display, synonyms, active, version, updated_at
+		FROM terminology_concepts
+		WHERE system = $1 AND active
+	`
+	args := []interface{}{system, escapeLike(strings.ToLower(query))}
+
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		args = append(args, "%"+escapeLike(word)+"%")
+		sql += fmt.Sprintf(" AND search_text LIKE $%d", len(args))
+	}
+
+	args = append(args, limit)
+	sql += fmt.Sprintf(`
+		ORDER BY (LOWER(code) LIKE $2 || '%%') DESC, LENGTH(display), code
+		LIMIT $%d
+	`, len(args))
+
+	var concepts []*model.Concept
+	if err := r.GetDB().SelectContext(ctx, &concepts, sql, args...); err != nil {
+		return nil, fmt.Errorf("failed to search concepts: %w", err)
+	}
+	return concepts, nil
+}
+
+func (r *terminologyRepository) ListReleases(ctx context.Context, system string) ([]*model.TerminologyRelease, error) {
+	query := `SELECT * FROM terminology_releases`
+	args := []interface{}{}
+	if system != "" {
+		query += " WHERE system = $1"
+		args = append(args, system)
+	}
+	query += " ORDER BY loaded_at DESC"
+
+	var releases []*model.TerminologyRelease
+	if err := r.GetDB().SelectContext(ctx, &releases, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list terminology releases: %w", err)
+	}
+	return releases, nil
+}
+
+// searchText is what typeahead matches against: code, display and synonyms,
+// lower-cased
+func searchText(c *model.Concept) string {
+	parts := append([]string{c.Code, c.Display}, c.Synonyms...)
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type timelineRepository struct {
+	BaseRepository
+}
+
+func NewTimelineRepository(base BaseRepository) repository.TimelineRepository {
+	return &timelineRepository{base}
+}
+
+// List merges the patient's appointments, records, sent notifications and
+// the audited changes to their appointments, coverages and consents. The
+// filters, cursor and limit apply to the merged stream, so only the page
+// leaves the database.
+func (r *timelineRepository) List(ctx context.Context, patientID uuid.UUID, query *model.TimelineQuery) ([]*model.TimelineEntry, error) {
+	kinds := make([]string, len(query.Kinds))
+	for i, k := range query.Kinds {
+		kinds[i] = string(k)
+	}
+	args := []interface{}{patientID, pq.Array(kinds)}
+
+	// Restricted records follow the same rules as the medical records list
+	recordAccess := ""
+	if access := query.RecordAccess; access != nil {
+		if access.PublicOnly {
+			recordAccess = " AND m.access_level = 'public'"
+		} else {
+			recordAccess = fmt.Sprintf(` AND (m.access_level = 'public' OR EXISTS (
+				SELECT 1 FROM care_team_members ct
+				WHERE ct.patient_id = m.patient_id AND ct.user_id = $%d
+			))`, len(args)+1)
+			args = append(args, access.UserID)
+			if !access.IncludeHIPAA {
+				recordAccess += " AND m.access_level IS DISTINCT FROM 'hipaa'"
+			}
+		}
+	}
+
+	sql := `
+		SELECT id, kind, occurred_at, source_id, access_level, actor_id, action, summary, data
+		FROM (
+			SELECT a.id, 'appointment' AS kind, a.start_time AS occurred_at, a.id AS source_id,
+				'public' AS access_level, NULL::uuid AS actor_id, '' AS action, a.status AS summary,
+				jsonb_build_object(
+					'clinic_id', a.clinic_id, 'clinician_id', a.clinician_id, 'service_id', a.service_id,
+					'start_time', a.start_time, 'end_time', a.end_time, 'status', a.status
+				) AS data
+			FROM appointments a
+			WHERE a.patient_id = $1 AND a.deleted_at IS NULL
+
+			UNION ALL
+
+			SELECT m.id, 'medical_record', m.created_at, m.id,
+				COALESCE(m.access_level, ''), m.created_by, m.type, COALESCE(m.description, ''),
+				jsonb_build_object('attachments', COALESCE(to_jsonb(m.attachments), '[]'::jsonb))
+			FROM medical_records m
+			WHERE m.patient_id = $1 AND m.deleted_at IS NULL` + recordAccess + `
+
+			UNION ALL
+
+			SELECT n.id, 'notification', COALESCE(n.sent_at, n.created_at), n.id,
+				'private', NULL, '', COALESCE(n.subject, ''),
+				jsonb_build_object('channel', n.channel, 'recipient', n.recipient)
+			FROM notifications n
+			WHERE n.patient_id = $1 AND n.status = 'sent'
+
+			UNION ALL
+
+			SELECT l.id,
+				CASE l.entity_type
+					WHEN 'appointment' THEN 'appointment_status'
+					WHEN 'insurance_coverage' THEN 'insurance'
+					ELSE 'consent'
+				END,
+				l.created_at, l.entity_id,
+				CASE l.entity_type WHEN 'insurance_coverage' THEN 'private' ELSE 'public' END,
+				NULLIF(l.user_id, '00000000-0000-0000-0000-000000000000'), l.action, '',
+				COALESCE(l.changes, '{}'::jsonb)
+			FROM audit_logs l
+			WHERE (l.entity_type = 'appointment' AND l.action IN ('update', 'cancel', 'complete')
+					AND l.entity_id IN (SELECT id FROM appointments WHERE patient_id = $1 AND deleted_at IS NULL))
+				OR (l.entity_type = 'insurance_coverage' AND l.action IN ('create', 'update', 'check_eligibility')
+					AND l.entity_id IN (SELECT id FROM insurance_coverages WHERE patient_id = $1))
+				OR (l.entity_type = 'consent' AND l.action IN ('grant', 'withdraw')
+					AND l.entity_id IN (SELECT id FROM patient_consents WHERE patient_id = $1))
+		) t
+		WHERE kind = ANY($2)
+	`
+
+	// Readers limited to public records see only the public rest of the
+	// stream as well
+	if access := query.RecordAccess; access != nil && access.PublicOnly {
+		sql += " AND access_level = 'public'"
+	}
+
+	if !query.From.IsZero() {
+		sql += fmt.Sprintf(" AND occurred_at >= $%d", len(args)+1)
+		args = append(args, query.From)
+	}
+	if !query.To.IsZero() {
+		sql += fmt.Sprintf(" AND occurred_at <= $%d", len(args)+1)
+		args = append(args, query.To)
+	}
+	if after := query.After; after != nil {
+		sql += fmt.Sprintf(" AND (occurred_at, id) < ($%d, $%d)", len(args)+1, len(args)+2)
+		args = append(args, after.OccurredAt, after.ID)
+	}
+
+	sql += fmt.Sprintf(" ORDER BY occurred_at DESC, id DESC LIMIT $%d", len(args)+1)
+	args = append(args, query.Limit)
+
+	var entries []*model.TimelineEntry
+	if err := r.GetDB().SelectContext(ctx, &entries, sql, args...); err != nil {
+		return nil, fmt.Errorf("failed to list timeline: %w", err)
+	}
+	return entries, nil
+}
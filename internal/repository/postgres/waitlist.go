@@ -0,0 +1,428 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+)
+
+type waitlistRepository struct {
+	BaseRepository
+}
+
+func NewWaitlistRepository(base BaseRepository) repository.WaitlistRepository {
+	return &waitlistRepository{base}
+}
+
+func (r *waitlistRepository) CreateEntry(ctx context.Context, entry *model.WaitlistEntry) error {
+	query := `
+		INSERT INTO waitlist_entries (
+			id, organization_id, clinic_id, clinician_id, service_id, patient_id,
+			priority, timezone, preferred_windows, notes, status, created_by,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	windows, err := marshalWindows(entry.PreferredWindows)
+	if err != nil {
+		return err
+	}
+
+	entry.ID = uuid.New()
+	entry.CreatedAt = time.Now()
+	entry.UpdatedAt = entry.CreatedAt
+
+	_, err = r.GetDB().ExecContext(ctx, query,
+		entry.ID,
+		entry.OrganizationID,
+		entry.ClinicID,
+		entry.ClinicianID,
+		entry.ServiceID,
+		entry.PatientID,
+		entry.Priority,
+		entry.Timezone,
+		windows,
+		entry.Notes,
+		entry.Status,
+		entry.CreatedBy,
+		entry.CreatedAt,
+		entry.UpdatedAt,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create waitlist entry: %w", err)
+	}
+	entry.WindowsJSON = windows
+	return nil
+}
+
+func (r *waitlistRepository) GetEntry(ctx context.Context, id uuid.UUID) (*model.WaitlistEntry, error) {
+	var entry model.WaitlistEntry
+	if err := r.GetDB().GetContext(ctx, &entry, `SELECT * FROM waitlist_entries WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entry: %w", err)
+	}
+	if err := unmarshalWindows(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *waitlistRepository) ListEntries(ctx context.Context, organizationID uuid.UUID, filters *model.WaitlistFilters) ([]*model.WaitlistEntry, error) {
+	query := `SELECT * FROM waitlist_entries WHERE organization_id = $1`
+	args := []interface{}{organizationID}
+
+	if filters.ClinicID != nil {
+		query += fmt.Sprintf(" AND clinic_id = $%d", len(args)+1)
+		args = append(args, *filters.ClinicID)
+	}
+	if filters.ClinicianID != nil {
+		query += fmt.Sprintf(" AND clinician_id = $%d", len(args)+1)
+		args = append(args, *filters.ClinicianID)
+	}
+	if filters.ServiceID != nil {
+		query += fmt.Sprintf(" AND service_id = $%d", len(args)+1)
+		args = append(args, *filters.ServiceID)
+	}
+	if filters.Status != "" {
+		query += fmt.Sprintf(" AND status = $%d", len(args)+1)
+		args = append(args, filters.Status)
+	}
+	query += " ORDER BY priority DESC, created_at ASC"
+
+	var entries []*model.WaitlistEntry
+	if err := r.GetDB().SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list waitlist entries: %w", err)
+	}
+	for _, entry := range entries {
+		if err := unmarshalWindows(entry); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func (r *waitlistRepository) UpdateEntry(ctx context.Context, entry *model.WaitlistEntry) error {
+	windows, err := marshalWindows(entry.PreferredWindows)
+	if err != nil {
+		return err
+	}
+	entry.UpdatedAt = time.Now()
+
+	result, err := r.GetDB().ExecContext(ctx, `
+		UPDATE waitlist_entries SET
+			priority = $2,
+			timezone = $3,
+			preferred_windows = $4,
+			notes = $5,
+			status = $6,
+			updated_at = $7
+		WHERE id = $1
+	`,
+		entry.ID,
+		entry.Priority,
+		entry.Timezone,
+		windows,
+		entry.Notes,
+		entry.Status,
+		entry.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("waitlist entry not found")
+	}
+	entry.WindowsJSON = windows
+	return nil
+}
+
+func (r *waitlistRepository) ListCandidates(ctx context.Context, slot *model.Appointment) ([]*model.WaitlistEntry, error) {
+	query := `
+		SELECT e.* FROM waitlist_entries e
+		WHERE e.clinic_id = $2
+		AND e.clinician_id = $3
+		AND e.service_id = $4
+		AND e.status = 'waiting'
+		AND e.patient_id <> COALESCE((SELECT patient_id FROM appointments WHERE id = $1), '00000000-0000-0000-0000-000000000000'::uuid)
+		AND NOT EXISTS (
+			SELECT 1 FROM waitlist_offers o
+			WHERE o.source_appointment_id = $1 AND o.entry_id = e.id
+		)
+		ORDER BY e.priority DESC, e.created_at ASC
+	`
+
+	var entries []*model.WaitlistEntry
+	if err := r.GetDB().SelectContext(ctx, &entries, query, slot.ID, slot.ClinicID, slot.ClinicianID, slot.ServiceID); err != nil {
+		return nil, fmt.Errorf("failed to list waitlist candidates: %w", err)
+	}
+	for _, entry := range entries {
+		if err := unmarshalWindows(entry); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func (r *waitlistRepository) CreateOffers(ctx context.Context, offers []*model.WaitlistOffer, limit int) ([]*model.WaitlistOffer, error) {
+	var created []*model.WaitlistOffer
+	err := r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		// Offers of a slot share it, so checking the first covers them all
+		if err := lockClinician(ctx, tx, offers[0].ClinicianID); err != nil {
+			return err
+		}
+		var taken bool
+		if err := tx.GetContext(ctx, &taken, `
+			SELECT EXISTS (
+				SELECT 1 FROM appointment_holds
+				WHERE clinician_id = $1
+				AND expires_at > NOW()
+				AND start_time < $3 AND end_time > $2
+			) OR EXISTS (
+				SELECT 1 FROM appointments
+				WHERE clinician_id = $1
+				AND deleted_at IS NULL
+				AND status IN ('scheduled', 'confirmed')
+				AND start_time < $3 AND end_time > $2
+			)
+		`, offers[0].ClinicianID, offers[0].StartTime, offers[0].EndTime); err != nil {
+			return fmt.Errorf("failed to check slot: %w", err)
+		}
+		if taken {
+			return repository.ErrSlotTaken
+		}
+
+		for _, offer := range offers {
+			if len(created) == limit {
+				break
+			}
+
+			// Claiming the entry first locks it, so an entry that was
+			// offered another slot or left the waitlist is passed over
+			result, err := tx.ExecContext(ctx, `
+				UPDATE waitlist_entries SET status = 'offered', updated_at = NOW()
+				WHERE id = $1 AND status = 'waiting'
+				AND NOT EXISTS (
+					SELECT 1 FROM waitlist_offers
+					WHERE source_appointment_id = $2 AND entry_id = $1
+				)
+			`, offer.EntryID, offer.SourceAppointmentID)
+			if err != nil {
+				return fmt.Errorf("failed to update waitlist entry: %w", err)
+			}
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to update waitlist entry: %w", err)
+			}
+			if rows == 0 {
+				continue
+			}
+
+			offer.ID = uuid.New()
+			offer.CreatedAt = time.Now()
+
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO waitlist_offers (
+					id, entry_id, patient_id, clinic_id, clinician_id, service_id,
+					source_appointment_id, start_time, end_time, status, expires_at, created_at
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			`,
+				offer.ID,
+				offer.EntryID,
+				offer.PatientID,
+				offer.ClinicID,
+				offer.ClinicianID,
+				offer.ServiceID,
+				offer.SourceAppointmentID,
+				offer.StartTime,
+				offer.EndTime,
+				offer.Status,
+				offer.ExpiresAt,
+				offer.CreatedAt,
+			)
+			if err != nil {
+				if isUniqueViolation(err) {
+					return repository.ErrDuplicate
+				}
+				return fmt.Errorf("failed to create waitlist offer: %w", err)
+			}
+			created = append(created, offer)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *waitlistRepository) GetOffer(ctx context.Context, id uuid.UUID) (*model.WaitlistOffer, error) {
+	var offer model.WaitlistOffer
+	if err := r.GetDB().GetContext(ctx, &offer, `SELECT * FROM waitlist_offers WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to get waitlist offer: %w", err)
+	}
+	return &offer, nil
+}
+
+func (r *waitlistRepository) ListOffers(ctx context.Context, entryID uuid.UUID) ([]*model.WaitlistOffer, error) {
+	var offers []*model.WaitlistOffer
+	query := `SELECT * FROM waitlist_offers WHERE entry_id = $1 ORDER BY created_at DESC`
+	if err := r.GetDB().SelectContext(ctx, &offers, query, entryID); err != nil {
+		return nil, fmt.Errorf("failed to list waitlist offers: %w", err)
+	}
+	return offers, nil
+}
+
+func (r *waitlistRepository) AcceptOffer(ctx context.Context, offerID uuid.UUID, appointment *model.Appointment) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		// Only one offer per slot can be accepted; a concurrent acceptance
+		// waits here on the unique index and then fails
+		var offer model.WaitlistOffer
+		err := tx.GetContext(ctx, &offer, `
+			UPDATE waitlist_offers SET
+				status = 'accepted',
+				appointment_id = $2,
+				responded_at = NOW()
+			WHERE id = $1 AND status = 'pending' AND expires_at > NOW()
+			RETURNING *
+		`, offerID, appointment.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrVersionConflict
+		}
+		if err != nil {
+			if isUniqueViolation(err) {
+				return repository.ErrSlotTaken
+			}
+			return fmt.Errorf("failed to accept waitlist offer: %w", err)
+		}
+
+		// The slot's other offers go first, since a pending offer keeps
+		// the slot from being booked
+		if _, err := tx.ExecContext(ctx, `
+			WITH superseded AS (
+				UPDATE waitlist_offers SET status = 'superseded', responded_at = NOW()
+				WHERE source_appointment_id = $1 AND status = 'pending'
+				RETURNING entry_id
+			)
+			UPDATE waitlist_entries SET status = 'waiting', updated_at = NOW()
+			WHERE id IN (SELECT entry_id FROM superseded) AND status = 'offered'
+		`, offer.SourceAppointmentID); err != nil {
+			return fmt.Errorf("failed to supersede waitlist offers: %w", err)
+		}
+
+		// The slot may have been booked some other way since it was offered
+		if err := insertAppointment(ctx, tx, appointment, r.GetRegionFromContext(ctx)); err != nil {
+			return fmt.Errorf("failed to create appointment: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE waitlist_entries SET status = 'booked', updated_at = NOW()
+			WHERE id = $1
+		`, offer.EntryID); err != nil {
+			return fmt.Errorf("failed to update waitlist entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *waitlistRepository) DeclineOffer(ctx context.Context, offerID uuid.UUID) error {
+	return r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		var entryID uuid.UUID
+		err := tx.GetContext(ctx, &entryID, `
+			UPDATE waitlist_offers SET status = 'declined', responded_at = NOW()
+			WHERE id = $1 AND status = 'pending'
+			RETURNING entry_id
+		`, offerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrVersionConflict
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decline waitlist offer: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE waitlist_entries SET status = 'waiting', updated_at = NOW()
+			WHERE id = $1 AND status = 'offered'
+		`, entryID); err != nil {
+			return fmt.Errorf("failed to update waitlist entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *waitlistRepository) ExpireOffers(ctx context.Context, now time.Time) ([]*model.WaitlistOffer, error) {
+	var offers []*model.WaitlistOffer
+	err := r.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := tx.SelectContext(ctx, &offers, `
+			UPDATE waitlist_offers SET status = 'expired'
+			WHERE status = 'pending' AND expires_at <= $1
+			RETURNING *
+		`, now); err != nil {
+			return fmt.Errorf("failed to expire waitlist offers: %w", err)
+		}
+
+		for _, offer := range offers {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE waitlist_entries SET status = 'waiting', updated_at = NOW()
+				WHERE id = $1 AND status = 'offered'
+			`, offer.EntryID); err != nil {
+				return fmt.Errorf("failed to update waitlist entry: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+func (r *waitlistRepository) HasOpenOffers(ctx context.Context, sourceAppointmentID uuid.UUID) (bool, error) {
+	var open bool
+	err := r.GetDB().GetContext(ctx, &open, `
+		SELECT EXISTS (
+			SELECT 1 FROM waitlist_offers
+			WHERE source_appointment_id = $1 AND status IN ('pending', 'accepted')
+		)
+	`, sourceAppointmentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check waitlist offers: %w", err)
+	}
+	return open, nil
+}
+
+func marshalWindows(windows []model.WaitlistWindow) (json.RawMessage, error) {
+	if windows == nil {
+		windows = []model.WaitlistWindow{}
+	}
+	data, err := json.Marshal(windows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal preferred windows: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalWindows(entry *model.WaitlistEntry) error {
+	entry.PreferredWindows = []model.WaitlistWindow{}
+	if len(entry.WindowsJSON) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(entry.WindowsJSON, &entry.PreferredWindows); err != nil {
+		return fmt.Errorf("failed to unmarshal preferred windows: %w", err)
+	}
+	return nil
+}
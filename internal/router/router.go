@@ -12,11 +12,29 @@ import (
 	"github.com/jwalitptl/admin-api/internal/handler/account"
 	"github.com/jwalitptl/admin-api/internal/handler/appointment"
 	authHandler "github.com/jwalitptl/admin-api/internal/handler/auth"
+	ccdaHandler "github.com/jwalitptl/admin-api/internal/handler/ccda"
 	"github.com/jwalitptl/admin-api/internal/handler/clinic"
+	clinicalListHandler "github.com/jwalitptl/admin-api/internal/handler/clinicallist"
+	complianceHandler "github.com/jwalitptl/admin-api/internal/handler/compliance"
+	documentHandler "github.com/jwalitptl/admin-api/internal/handler/document"
+	hl7Handler "github.com/jwalitptl/admin-api/internal/handler/hl7"
+	identifierHandler "github.com/jwalitptl/admin-api/internal/handler/identifier"
+	keyringHandler "github.com/jwalitptl/admin-api/internal/handler/keyring"
+	medicalHandler "github.com/jwalitptl/admin-api/internal/handler/medical"
+	noteHandler "github.com/jwalitptl/admin-api/internal/handler/note"
+	observationHandler "github.com/jwalitptl/admin-api/internal/handler/observation"
 	"github.com/jwalitptl/admin-api/internal/handler/patient"
 	permissionHandler "github.com/jwalitptl/admin-api/internal/handler/permission"
+	prescriptionHandler "github.com/jwalitptl/admin-api/internal/handler/prescription"
 	rbacHandler "github.com/jwalitptl/admin-api/internal/handler/rbac"
+	referralHandler "github.com/jwalitptl/admin-api/internal/handler/referral"
+	relationshipHandler "github.com/jwalitptl/admin-api/internal/handler/relationship"
+	reminderHandler "github.com/jwalitptl/admin-api/internal/handler/reminder"
+	scheduleHandler "github.com/jwalitptl/admin-api/internal/handler/schedule"
+	terminologyHandler "github.com/jwalitptl/admin-api/internal/handler/terminology"
+	timelineHandler "github.com/jwalitptl/admin-api/internal/handler/timeline"
 	"github.com/jwalitptl/admin-api/internal/handler/user"
+	waitlistHandler "github.com/jwalitptl/admin-api/internal/handler/waitlist"
 	"github.com/jwalitptl/admin-api/internal/middleware"
 	pkg_event "github.com/jwalitptl/admin-api/pkg/event"
 )
@@ -33,6 +51,7 @@ type EventHandler interface {
 type Router struct {
 	engine            *gin.Engine
 	auth              *middleware.AuthMiddleware
+	proxy             *middleware.ProxyMiddleware
 	accountH          EventHandler
 	authH             Handler
 	clinicH           EventHandler
@@ -40,6 +59,24 @@ type Router struct {
 	appointmentH      EventHandler
 	patientHandler    EventHandler
 	permissionHandler EventHandler
+	complianceH       *complianceHandler.Handler
+	relationshipH     EventHandler
+	documentH         *documentHandler.Handler
+	timelineH         EventHandler
+	identifierH       EventHandler
+	medicalH          EventHandler
+	terminologyH      EventHandler
+	prescriptionH     EventHandler
+	hl7H              EventHandler
+	keyringH          EventHandler
+	noteH             EventHandler
+	referralH         EventHandler
+	ccdaH             EventHandler
+	observationH      EventHandler
+	clinicalListH     EventHandler
+	scheduleH         EventHandler
+	waitlistH         EventHandler
+	reminderH         *reminderHandler.Handler
 	h                 *handler.Handler
 	eventTracker      *pkg_event.EventTrackerMiddleware
 	userHandler       EventHandler
@@ -61,20 +98,39 @@ type RouterConfig struct {
 }
 
 type Config struct {
-	AuthMiddleware     *middleware.AuthMiddleware
-	HIPAAMiddleware    *middleware.HIPAAMiddleware
-	RegionMiddleware   *middleware.RegionMiddleware
-	RegionValidation   *middleware.RegionValidationMiddleware
-	AccountHandler     *account.Handler
-	AuthHandler        *authHandler.Handler
-	ClinicHandler      *clinic.Handler
-	UserHandler        *user.Handler
-	RBACHandler        *rbacHandler.Handler
-	AppointmentHandler *appointment.Handler
-	PermissionHandler  *permissionHandler.Handler
-	PatientHandler     *patient.Handler
-	BaseHandler        *handler.Handler
-	EventTracker       *pkg_event.EventTrackerMiddleware
+	AuthMiddleware      *middleware.AuthMiddleware
+	HIPAAMiddleware     *middleware.HIPAAMiddleware
+	ProxyMiddleware     *middleware.ProxyMiddleware
+	RegionMiddleware    *middleware.RegionMiddleware
+	RegionValidation    *middleware.RegionValidationMiddleware
+	AccountHandler      *account.Handler
+	AuthHandler         *authHandler.Handler
+	ClinicHandler       *clinic.Handler
+	UserHandler         *user.Handler
+	RBACHandler         *rbacHandler.Handler
+	AppointmentHandler  *appointment.Handler
+	PermissionHandler   *permissionHandler.Handler
+	PatientHandler      *patient.Handler
+	ComplianceHandler   *complianceHandler.Handler
+	RelationshipHandler *relationshipHandler.Handler
+	DocumentHandler     *documentHandler.Handler
+	TimelineHandler     *timelineHandler.Handler
+	IdentifierHandler   *identifierHandler.Handler
+	MedicalHandler      *medicalHandler.Handler
+	TerminologyHandler  *terminologyHandler.Handler
+	PrescriptionHandler *prescriptionHandler.Handler
+	HL7Handler          *hl7Handler.Handler
+	KeyringHandler      *keyringHandler.Handler
+	NoteHandler         *noteHandler.Handler
+	ReferralHandler     *referralHandler.Handler
+	CCDAHandler         *ccdaHandler.Handler
+	ObservationHandler  *observationHandler.Handler
+	ClinicalListHandler *clinicalListHandler.Handler
+	ScheduleHandler     *scheduleHandler.Handler
+	WaitlistHandler     *waitlistHandler.Handler
+	ReminderHandler     *reminderHandler.Handler
+	BaseHandler         *handler.Handler
+	EventTracker        *pkg_event.EventTrackerMiddleware
 }
 
 func NewRouter(config Config) *Router {
@@ -84,6 +140,7 @@ func NewRouter(config Config) *Router {
 	return &Router{
 		engine:            engine,
 		auth:              config.AuthMiddleware,
+		proxy:             config.ProxyMiddleware,
 		accountH:          config.AccountHandler,
 		authH:             config.AuthHandler,
 		clinicH:           config.ClinicHandler,
@@ -91,6 +148,24 @@ func NewRouter(config Config) *Router {
 		appointmentH:      config.AppointmentHandler,
 		patientHandler:    config.PatientHandler,
 		permissionHandler: config.PermissionHandler,
+		complianceH:       config.ComplianceHandler,
+		relationshipH:     config.RelationshipHandler,
+		documentH:         config.DocumentHandler,
+		timelineH:         config.TimelineHandler,
+		identifierH:       config.IdentifierHandler,
+		medicalH:          config.MedicalHandler,
+		terminologyH:      config.TerminologyHandler,
+		prescriptionH:     config.PrescriptionHandler,
+		hl7H:              config.HL7Handler,
+		keyringH:          config.KeyringHandler,
+		noteH:             config.NoteHandler,
+		referralH:         config.ReferralHandler,
+		ccdaH:             config.CCDAHandler,
+		observationH:      config.ObservationHandler,
+		clinicalListH:     config.ClinicalListHandler,
+		scheduleH:         config.ScheduleHandler,
+		waitlistH:         config.WaitlistHandler,
+		reminderH:         config.ReminderHandler,
 		h:                 config.BaseHandler,
 		eventTracker:      config.EventTracker,
 		userHandler:       config.UserHandler,
@@ -122,6 +197,7 @@ func (r *Router) Setup() {
 	protected.Use(
 		r.auth.Authenticate(),
 		r.auth.ValidatePermissions(),
+		r.proxy.Authorize(),
 	)
 	r.setupProtectedRoutes(protected)
 }
@@ -138,6 +214,9 @@ func (r *Router) setupHealthCheck(rg *gin.RouterGroup) {
 func (r *Router) setupPublicRoutes(rg *gin.RouterGroup) {
 	r.authH.RegisterRoutes(rg)
 	r.accountH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.complianceH.RegisterPublicRoutes(rg)
+	r.documentH.RegisterPublicRoutes(rg)
+	r.reminderH.RegisterPublicRoutes(rg)
 }
 
 func (r *Router) setupProtectedRoutes(rg *gin.RouterGroup) {
@@ -151,6 +230,24 @@ func (r *Router) setupProtectedRoutes(rg *gin.RouterGroup) {
 	r.rbacH.RegisterRoutesWithEvents(rg, r.eventTracker)
 	r.appointmentH.RegisterRoutesWithEvents(rg, r.eventTracker)
 	r.permissionHandler.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.complianceH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.relationshipH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.documentH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.timelineH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.identifierH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.medicalH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.terminologyH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.prescriptionH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.hl7H.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.keyringH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.noteH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.referralH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.ccdaH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.observationH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.clinicalListH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.scheduleH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.waitlistH.RegisterRoutesWithEvents(rg, r.eventTracker)
+	r.reminderH.RegisterRoutesWithEvents(rg, r.eventTracker)
 }
 
 func (r *Router) setupPatientRoutes(rg *gin.RouterGroup) {
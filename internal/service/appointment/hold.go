@@ -0,0 +1,136 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+// SlotHoldTTL is how long a hold reserves its slot
+const SlotHoldTTL = 10 * time.Minute
+
+var (
+	ErrHoldNotFound = errors.New("slot hold not found or expired")
+	ErrHoldMismatch = errors.New("appointment does not match the slot hold")
+)
+
+// SlotConflictError is returned when a booking or hold overlaps another
+// booking or hold of the clinician. It matches repository.ErrSlotTaken.
+type SlotConflictError struct {
+	ClinicianID uuid.UUID `json:"clinician_id"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+}
+
+func (e *SlotConflictError) Error() string {
+	return "slot conflicts with an existing booking or hold"
+}
+
+func (e *SlotConflictError) Unwrap() error {
+	return repository.ErrSlotTaken
+}
+
+// HoldSlot reserves a slot for SlotHoldTTL so a booking can be completed
+// without someone else taking it
+func (s *Service) HoldSlot(ctx context.Context, req *model.CreateAppointmentHoldRequest) (*model.AppointmentHold, error) {
+	if err := validateBookingTime(req.StartTime, req.EndTime); err != nil {
+		return nil, fmt.Errorf("invalid slot hold: %w", err)
+	}
+	svc, err := s.services.Get(ctx, req.ServiceID)
+	if err != nil {
+		return nil, ErrServiceNotFound
+	}
+	if !svc.IsActive {
+		return nil, ErrServiceUnavailable
+	}
+
+	hold := &model.AppointmentHold{
+		ClinicID:    req.ClinicID,
+		ClinicianID: req.ClinicianID,
+		ServiceID:   req.ServiceID,
+		PatientID:   req.PatientID,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		ExpiresAt:   time.Now().Add(SlotHoldTTL),
+		CreatedBy:   s.getCurrentUserID(ctx),
+	}
+	if err := s.repo.CreateHold(ctx, hold); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return nil, &SlotConflictError{ClinicianID: hold.ClinicianID, StartTime: hold.StartTime, EndTime: hold.EndTime}
+		}
+		return nil, fmt.Errorf("failed to hold slot: %w", err)
+	}
+
+	s.auditor.Log(ctx, hold.CreatedBy, hold.ClinicID, "hold", "appointment_slot", hold.ID, &audit.LogOptions{
+		Changes: hold,
+	})
+
+	return hold, nil
+}
+
+func (s *Service) GetHold(ctx context.Context, id uuid.UUID) (*model.AppointmentHold, error) {
+	hold, err := s.repo.GetHold(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrHoldNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+// ReleaseHold frees a held slot before the hold expires
+func (s *Service) ReleaseHold(ctx context.Context, id uuid.UUID) error {
+	hold, err := s.GetHold(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteHold(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrHoldNotFound) {
+			return ErrHoldNotFound
+		}
+		return err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), hold.ClinicID, "release", "appointment_slot", id, nil)
+	return nil
+}
+
+// CreateAppointmentFromHold books the slot reserved by the hold. The
+// appointment must be for the hold's clinician, service and times, and for
+// its patient when the hold names one.
+func (s *Service) CreateAppointmentFromHold(ctx context.Context, holdID uuid.UUID, apt *model.Appointment) error {
+	hold, err := s.GetHold(ctx, holdID)
+	if err != nil {
+		return err
+	}
+	if hold.ClinicianID != apt.ClinicianID || hold.ClinicID != apt.ClinicID || hold.ServiceID != apt.ServiceID ||
+		!hold.StartTime.Equal(apt.StartTime) || !hold.EndTime.Equal(apt.EndTime) ||
+		(hold.PatientID != nil && *hold.PatientID != apt.PatientID) {
+		return ErrHoldMismatch
+	}
+	if err := s.validateAppointmentFields(apt); err != nil {
+		return fmt.Errorf("invalid appointment: %w", err)
+	}
+
+	apt.Status = model.AppointmentStatusScheduled
+	if err := s.repo.CreateFromHold(ctx, apt, holdID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrHoldNotFound):
+			return ErrHoldNotFound
+		case errors.Is(err, repository.ErrSlotTaken):
+			return &SlotConflictError{ClinicianID: apt.ClinicianID, StartTime: apt.StartTime, EndTime: apt.EndTime}
+		}
+		return fmt.Errorf("failed to create appointment: %w", err)
+	}
+
+	s.afterCreate(ctx, apt)
+	return nil
+}
@@ -0,0 +1,96 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+var (
+	ErrAppointmentNotFound = errors.New("appointment not found")
+	ErrAppointmentClosed   = errors.New("appointment can no longer be confirmed or cancelled")
+	ErrInvalidAction       = errors.New("unknown reminder response")
+)
+
+// RespondToReminder applies the patient's answer to a reminder, carried by
+// a signed confirm or cancel link. Answering twice with the same action is
+// not an error, so a link can be opened again.
+func (s *Service) RespondToReminder(ctx context.Context, id uuid.UUID, action model.ReminderAction, path string, query url.Values) (*model.Appointment, error) {
+	if err := s.reminders.VerifyLink(path, query); err != nil {
+		return nil, err
+	}
+
+	apt, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrAppointmentNotFound
+	}
+
+	switch action {
+	case model.ReminderActionConfirm:
+		if apt.Status == model.AppointmentStatusConfirmed {
+			return apt, nil
+		}
+		if apt.Status != model.AppointmentStatusScheduled || !apt.StartTime.After(time.Now()) {
+			return nil, ErrAppointmentClosed
+		}
+
+		apt.Status = model.AppointmentStatusConfirmed
+		apt.UpdatedAt = time.Now()
+		if err := s.repo.Update(ctx, apt); err != nil {
+			return nil, fmt.Errorf("failed to confirm appointment: %w", err)
+		}
+
+		s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "confirm", "appointment", apt.ID, &audit.LogOptions{
+			Changes: map[string]interface{}{
+				"status": apt.Status,
+			},
+			Metadata: map[string]interface{}{
+				"source": "reminder",
+			},
+		})
+		return apt, nil
+
+	case model.ReminderActionCancel:
+		if apt.Status == model.AppointmentStatusCancelled {
+			return apt, nil
+		}
+		if apt.Status == model.AppointmentStatusCompleted || !apt.StartTime.After(time.Now()) {
+			return nil, ErrAppointmentClosed
+		}
+		if err := s.CancelAppointment(ctx, id, "cancelled by patient from reminder"); err != nil {
+			return nil, err
+		}
+		return s.repo.Get(ctx, id)
+	}
+
+	return nil, ErrInvalidAction
+}
+
+// ListReminders returns the reminders scheduled for the appointment
+func (s *Service) ListReminders(ctx context.Context, id uuid.UUID) ([]*model.AppointmentReminder, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, ErrAppointmentNotFound
+	}
+	return s.reminders.ListReminders(ctx, id)
+}
+
+// notifyOccurrences reschedules the reminders of series occurrences that
+// were moved
+func (s *Service) notifyOccurrences(ctx context.Context, occurrences []*model.Appointment) {
+	for _, apt := range occurrences {
+		if err := s.notifyParticipants(ctx, apt, "appointment_updated"); err != nil {
+			s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "notification_failed", "appointment", apt.ID, &audit.LogOptions{
+				Metadata: map[string]interface{}{
+					"error": err.Error(),
+				},
+			})
+		}
+	}
+}
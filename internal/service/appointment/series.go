@@ -0,0 +1,568 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/pkg/rrule"
+)
+
+const (
+	// MaxSeriesOccurrences bounds how many appointments one series books
+	MaxSeriesOccurrences = 104
+	// MaxSeriesSpan is how far after its first occurrence a series may run
+	MaxSeriesSpan = 366 * 24 * time.Hour
+)
+
+var (
+	ErrSeriesNotFound    = errors.New("appointment series not found")
+	ErrNotInSeries       = errors.New("appointment is not an occurrence of this series")
+	ErrSeriesCancelled   = errors.New("appointment series is cancelled")
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+	ErrInvalidTimezone   = errors.New("unknown timezone")
+	ErrSeriesTooLong     = fmt.Errorf("a series can have at most %d occurrences within a year", MaxSeriesOccurrences)
+	ErrNoOccurrences     = errors.New("series has no occurrences to book")
+	ErrOccurrenceClosed  = errors.New("cancelled and completed occurrences cannot be changed")
+	ErrSeriesDateChange  = errors.New("only a single occurrence can be moved to another date")
+	ErrInvalidSeriesTime = errors.New("invalid occurrence time")
+)
+
+// ConflictsError is returned when occurrences of a series conflict with
+// other bookings of the clinician. Nothing is saved.
+type ConflictsError struct {
+	Total     int
+	Conflicts []model.SeriesOccurrence
+}
+
+func (e *ConflictsError) Error() string {
+	return fmt.Sprintf("%d of %d occurrences conflict with existing bookings", len(e.Conflicts), e.Total)
+}
+
+// CheckSeries expands the series a request would book and reports which
+// occurrences conflict, without booking anything
+func (s *Service) CheckSeries(ctx context.Context, req *model.CreateAppointmentSeriesRequest) ([]model.SeriesOccurrence, error) {
+	_, occurrences, err := s.expandRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.checkOccurrences(ctx, req.ClinicianID, occurrences)
+}
+
+// CreateSeries books every occurrence of the request's rule. If any of them
+// conflict the whole series is refused with a ConflictsError, unless the
+// request skips conflicts, in which case they become exceptions.
+func (s *Service) CreateSeries(ctx context.Context, req *model.CreateAppointmentSeriesRequest) (*model.AppointmentSeriesResult, error) {
+	series, occurrences, err := s.expandRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	checked, err := s.checkOccurrences(ctx, req.ClinicianID, occurrences)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.AppointmentSeriesResult{Series: series}
+	var conflicts []model.SeriesOccurrence
+	for _, occ := range checked {
+		if occ.Conflict {
+			conflicts = append(conflicts, occ)
+			continue
+		}
+		start := occ.Start
+		result.Appointments = append(result.Appointments, &model.Appointment{
+			Base: model.Base{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			ClinicID:        series.ClinicID,
+			ClinicianID:     series.ClinicianID,
+			PatientID:       series.PatientID,
+			ServiceID:       series.ServiceID,
+			StartTime:       occ.Start,
+			EndTime:         occ.End,
+			Status:          model.AppointmentStatusScheduled,
+			Notes:           series.Notes,
+			SeriesID:        &series.ID,
+			OccurrenceStart: &start,
+		})
+	}
+	if len(conflicts) > 0 {
+		if !req.SkipConflicts {
+			return nil, &ConflictsError{Total: len(checked), Conflicts: conflicts}
+		}
+		for _, occ := range conflicts {
+			series.ExDates = append(series.ExDates, occ.Start)
+		}
+		result.Skipped = conflicts
+	}
+	if len(result.Appointments) == 0 {
+		return nil, ErrNoOccurrences
+	}
+
+	if err := s.series.Create(ctx, series, result.Appointments); err != nil {
+		return nil, fmt.Errorf("failed to create appointment series: %w", err)
+	}
+
+	for _, apt := range result.Appointments {
+		if err := s.notifyParticipants(ctx, apt, "appointment_created"); err != nil {
+			s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "notification_failed", "appointment", apt.ID, &audit.LogOptions{
+				Metadata: map[string]interface{}{
+					"error": err.Error(),
+				},
+			})
+		}
+	}
+
+	s.auditor.Log(ctx, series.CreatedBy, series.ClinicID, "create", "appointment_series", series.ID, &audit.LogOptions{
+		Changes: series,
+		Metadata: map[string]interface{}{
+			"occurrences": len(result.Appointments),
+			"skipped":     len(result.Skipped),
+		},
+	})
+
+	return result, nil
+}
+
+func (s *Service) GetSeries(ctx context.Context, id uuid.UUID) (*model.AppointmentSeriesResult, error) {
+	series, err := s.series.Get(ctx, id)
+	if err != nil {
+		return nil, ErrSeriesNotFound
+	}
+	occurrences, err := s.series.ListOccurrences(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, series.PatientID, series.ClinicID, "read", "appointment_series", id, nil)
+	return &model.AppointmentSeriesResult{Series: series, Appointments: occurrences}, nil
+}
+
+// UpdateOccurrence edits one occurrence, it and the ones after it, or every
+// occurrence. Only upcoming occurrences that are still scheduled or
+// confirmed are changed by the wider scopes; the others keep their own
+// status and times. Editing "this and following" splits the series in two
+// at the occurrence.
+func (s *Service) UpdateOccurrence(ctx context.Context, seriesID, appointmentID uuid.UUID, req *model.UpdateSeriesOccurrenceRequest) (*model.AppointmentSeriesResult, error) {
+	series, occurrences, target, err := s.loadOccurrence(ctx, seriesID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := target.StartTime, target.EndTime
+	if req.StartTime != nil {
+		start = *req.StartTime
+		end = start.Add(target.EndTime.Sub(target.StartTime))
+	}
+	if req.EndTime != nil {
+		end = *req.EndTime
+	}
+	timeChanged := !start.Equal(target.StartTime) || !end.Equal(target.EndTime)
+	if timeChanged {
+		if err := s.validateAppointmentTime(start, end); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSeriesTime, err)
+		}
+	}
+
+	if req.Scope == model.SeriesScopeThis {
+		target.StartTime, target.EndTime = start, end
+		if req.Notes != nil {
+			target.Notes = *req.Notes
+		}
+		if timeChanged {
+			if err := s.checkMoves(ctx, []*model.Appointment{target}); err != nil {
+				return nil, err
+			}
+		}
+		target.UpdatedAt = time.Now()
+		if err := s.repo.Update(ctx, target); err != nil {
+			return nil, fmt.Errorf("failed to update appointment: %w", err)
+		}
+		if timeChanged {
+			s.notifyOccurrences(ctx, []*model.Appointment{target})
+		}
+		s.logSeriesChange(ctx, series, "update_occurrence", req.Scope, []*model.Appointment{target})
+		return &model.AppointmentSeriesResult{Series: series, Appointments: occurrences}, nil
+	}
+
+	loc, rule, err := parseSeries(series)
+	if err != nil {
+		return nil, err
+	}
+	// Wider scopes change the time of day of the series, so the new time
+	// has to be on the occurrence's scheduled date
+	if timeChanged && !sameDate(start, *target.OccurrenceStart, loc) {
+		return nil, ErrSeriesDateChange
+	}
+	duration := end.Sub(start)
+
+	split := req.Scope == model.SeriesScopeFollowing && target.OccurrenceStart.After(series.StartTime)
+	from := series.StartTime
+	if req.Scope == model.SeriesScopeFollowing {
+		from = *target.OccurrenceStart
+	}
+
+	var changed []*model.Appointment
+	now := time.Now()
+	for _, apt := range occurrences {
+		if apt.OccurrenceStart.Before(from) {
+			continue
+		}
+		if isOpen(apt) && apt.StartTime.After(now) {
+			if timeChanged {
+				moved := atClock(*apt.OccurrenceStart, start, loc)
+				apt.OccurrenceStart = &moved
+				apt.StartTime, apt.EndTime = moved, moved.Add(duration)
+			}
+			if req.Notes != nil {
+				apt.Notes = *req.Notes
+			}
+			changed = append(changed, apt)
+		} else if split {
+			// Past and closed occurrences are left alone but still move
+			// to the new series with the rest
+			changed = append(changed, apt)
+		}
+	}
+	if timeChanged {
+		if err := s.checkMoves(ctx, changed); err != nil {
+			return nil, err
+		}
+	}
+
+	if !split {
+		if timeChanged {
+			series.StartTime = atClock(series.StartTime, start, loc)
+			series.Duration = int(duration / time.Minute)
+			series.ExDates = atClockAll(series.ExDates, start, loc)
+			if rule.Until != nil {
+				series.RRule = rule.WithUntil(atClock(*rule.Until, start, loc)).String()
+			}
+		}
+		if req.Notes != nil {
+			series.Notes = *req.Notes
+		}
+		if err := s.series.Update(ctx, series, changed); err != nil {
+			return nil, fmt.Errorf("failed to update appointment series: %w", err)
+		}
+		if timeChanged {
+			s.notifyOccurrences(ctx, changed)
+		}
+		s.logSeriesChange(ctx, series, "update_occurrences", req.Scope, changed)
+		return s.GetSeries(ctx, series.ID)
+	}
+
+	next, err := s.splitSeries(ctx, series, loc, rule, from)
+	if err != nil {
+		return nil, err
+	}
+	if timeChanged {
+		next.StartTime = atClock(next.StartTime, start, loc)
+		next.Duration = int(duration / time.Minute)
+		next.ExDates = atClockAll(next.ExDates, start, loc)
+		if r, err := rrule.Parse(next.RRule); err == nil && r.Until != nil {
+			next.RRule = r.WithUntil(atClock(*r.Until, start, loc)).String()
+		}
+	}
+	if req.Notes != nil {
+		next.Notes = *req.Notes
+	}
+	for _, apt := range changed {
+		apt.SeriesID = &next.ID
+	}
+
+	if err := s.series.Split(ctx, series, next, changed); err != nil {
+		return nil, fmt.Errorf("failed to split appointment series: %w", err)
+	}
+	if timeChanged {
+		s.notifyOccurrences(ctx, changed)
+	}
+	s.logSeriesChange(ctx, next, "split", req.Scope, changed)
+	return s.GetSeries(ctx, next.ID)
+}
+
+// CancelOccurrence cancels one occurrence, it and the ones after it, or the
+// whole series. Cancelling a single occurrence adds it to the series'
+// exceptions; "this and following" ends the rule before the occurrence.
+// Past, completed and already cancelled occurrences are left as they are.
+func (s *Service) CancelOccurrence(ctx context.Context, seriesID, appointmentID uuid.UUID, req *model.CancelSeriesOccurrenceRequest) (*model.AppointmentSeriesResult, error) {
+	series, occurrences, target, err := s.loadOccurrence(ctx, seriesID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	_, rule, err := parseSeries(series)
+	if err != nil {
+		return nil, err
+	}
+
+	var cancelled []*model.Appointment
+	switch {
+	case req.Scope == model.SeriesScopeThis:
+		series.ExDates = append(series.ExDates, *target.OccurrenceStart)
+		cancelled = append(cancelled, target)
+	case req.Scope == model.SeriesScopeAll || !target.OccurrenceStart.After(series.StartTime):
+		series.Status = model.AppointmentSeriesStatusCancelled
+		cancelled = upcoming(occurrences, series.StartTime)
+	default:
+		series.RRule = rule.WithUntil(target.OccurrenceStart.Add(-time.Second)).String()
+		cancelled = upcoming(occurrences, *target.OccurrenceStart)
+	}
+
+	for _, apt := range cancelled {
+		apt.Status = model.AppointmentStatusCancelled
+		apt.CancelReason = &req.Reason
+	}
+	if err := s.series.Update(ctx, series, cancelled); err != nil {
+		return nil, fmt.Errorf("failed to cancel series occurrences: %w", err)
+	}
+
+	for _, apt := range cancelled {
+		if err := s.notifyParticipants(ctx, apt, "appointment_cancelled"); err != nil {
+			s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "notification_failed", "appointment", apt.ID, &audit.LogOptions{
+				Metadata: map[string]interface{}{
+					"error": err.Error(),
+				},
+			})
+		}
+		s.releaseSlot(ctx, apt)
+	}
+	s.logSeriesChange(ctx, series, "cancel_occurrences", req.Scope, cancelled)
+
+	return s.GetSeries(ctx, series.ID)
+}
+
+// expandRequest validates a series request and builds the series it
+// describes along with its occurrences
+func (s *Service) expandRequest(ctx context.Context, req *model.CreateAppointmentSeriesRequest) (*model.AppointmentSeries, []model.TimeSlot, error) {
+	if err := s.validateAppointmentTime(req.StartTime, req.EndTime); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSeriesTime, err)
+	}
+	if req.StartTime.Sub(time.Now()) < MinAdvanceBooking {
+		return nil, nil, fmt.Errorf("%w: must be at least %v in advance", ErrInvalidSeriesTime, MinAdvanceBooking)
+	}
+
+	svc, err := s.services.Get(ctx, req.ServiceID)
+	if err != nil {
+		return nil, nil, ErrServiceNotFound
+	}
+	if !svc.IsActive {
+		return nil, nil, ErrServiceUnavailable
+	}
+
+	series := &model.AppointmentSeries{
+		ID:          uuid.New(),
+		ClinicID:    req.ClinicID,
+		ClinicianID: req.ClinicianID,
+		PatientID:   req.PatientID,
+		ServiceID:   req.ServiceID,
+		RRule:       req.RRule,
+		StartTime:   req.StartTime,
+		Duration:    int(req.EndTime.Sub(req.StartTime) / time.Minute),
+		Timezone:    req.Timezone,
+		ExDates:     req.ExDates,
+		Notes:       req.Notes,
+		Status:      model.AppointmentSeriesStatusActive,
+		CreatedBy:   s.getCurrentUserID(ctx),
+	}
+	loc, rule, err := parseSeries(series)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Store the rule as it was understood
+	series.RRule = rule.String()
+
+	starts, err := rule.Occurrences(req.StartTime.In(loc), req.ExDates, MaxSeriesOccurrences)
+	if err != nil {
+		if errors.Is(err, rrule.ErrTooMany) {
+			return nil, nil, ErrSeriesTooLong
+		}
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	if len(starts) == 0 {
+		return nil, nil, ErrNoOccurrences
+	}
+	if starts[len(starts)-1].Sub(starts[0]) > MaxSeriesSpan {
+		return nil, nil, ErrSeriesTooLong
+	}
+
+	duration := req.EndTime.Sub(req.StartTime)
+	occurrences := make([]model.TimeSlot, len(starts))
+	for i, start := range starts {
+		occurrences[i] = model.TimeSlot{Start: start, End: start.Add(duration)}
+	}
+	return series, occurrences, nil
+}
+
+func (s *Service) checkOccurrences(ctx context.Context, clinicianID uuid.UUID, occurrences []model.TimeSlot) ([]model.SeriesOccurrence, error) {
+	checked := make([]model.SeriesOccurrence, 0, len(occurrences))
+	for _, occ := range occurrences {
+		conflict, err := s.repo.CheckConflicts(ctx, clinicianID, occ.Start, occ.End, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check conflicts: %w", err)
+		}
+		checked = append(checked, model.SeriesOccurrence{Start: occ.Start, End: occ.End, Conflict: conflict})
+	}
+	return checked, nil
+}
+
+// checkMoves returns a ConflictsError if any of the appointments would
+// conflict at their new times
+func (s *Service) checkMoves(ctx context.Context, appointments []*model.Appointment) error {
+	var conflicts []model.SeriesOccurrence
+	for _, apt := range appointments {
+		if !isOpen(apt) {
+			continue
+		}
+		conflict, err := s.repo.CheckConflicts(ctx, apt.ClinicianID, apt.StartTime, apt.EndTime, &apt.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check conflicts: %w", err)
+		}
+		if conflict {
+			id := apt.ID
+			conflicts = append(conflicts, model.SeriesOccurrence{AppointmentID: &id, Start: apt.StartTime, End: apt.EndTime, Conflict: true})
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ConflictsError{Total: len(appointments), Conflicts: conflicts}
+	}
+	return nil
+}
+
+func (s *Service) loadOccurrence(ctx context.Context, seriesID, appointmentID uuid.UUID) (*model.AppointmentSeries, []*model.Appointment, *model.Appointment, error) {
+	series, err := s.series.Get(ctx, seriesID)
+	if err != nil {
+		return nil, nil, nil, ErrSeriesNotFound
+	}
+	if series.Status == model.AppointmentSeriesStatusCancelled {
+		return nil, nil, nil, ErrSeriesCancelled
+	}
+	occurrences, err := s.series.ListOccurrences(ctx, seriesID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, apt := range occurrences {
+		if apt.ID != appointmentID {
+			continue
+		}
+		if apt.OccurrenceStart == nil {
+			return nil, nil, nil, ErrNotInSeries
+		}
+		if !isOpen(apt) {
+			return nil, nil, nil, ErrOccurrenceClosed
+		}
+		return series, occurrences, apt, nil
+	}
+	return nil, nil, nil, ErrNotInSeries
+}
+
+// splitSeries ends series before from and returns the series that carries
+// on from there with the rest of the rule
+func (s *Service) splitSeries(ctx context.Context, series *model.AppointmentSeries, loc *time.Location, rule *rrule.Rule, from time.Time) (*model.AppointmentSeries, error) {
+	next := *series
+	next.ID = uuid.New()
+	next.ParentID = &series.ID
+	next.StartTime = from
+	next.CreatedBy = s.getCurrentUserID(ctx)
+	series.ExDates, next.ExDates = partitionTimes(series.ExDates, from)
+
+	if rule.Count > 0 {
+		// Exceptions count towards COUNT, so count every position of the
+		// rule before the split
+		positions, err := rule.Occurrences(series.StartTime.In(loc), nil, MaxSeriesOccurrences)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+		}
+		before := 0
+		for _, p := range positions {
+			if p.Before(from) {
+				before++
+			}
+		}
+		next.RRule = rule.WithCount(rule.Count - before).String()
+	}
+	series.RRule = rule.WithUntil(from.Add(-time.Second)).String()
+	return &next, nil
+}
+
+func (s *Service) logSeriesChange(ctx context.Context, series *model.AppointmentSeries, action string, scope model.SeriesScope, changed []*model.Appointment) {
+	ids := make([]uuid.UUID, len(changed))
+	for i, apt := range changed {
+		ids[i] = apt.ID
+	}
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), series.ClinicID, action, "appointment_series", series.ID, &audit.LogOptions{
+		Changes: series,
+		Metadata: map[string]interface{}{
+			"scope":        scope,
+			"appointments": ids,
+		},
+	})
+}
+
+func parseSeries(series *model.AppointmentSeries) (*time.Location, *rrule.Rule, error) {
+	loc, err := time.LoadLocation(series.Timezone)
+	if err != nil {
+		return nil, nil, ErrInvalidTimezone
+	}
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	return loc, rule, nil
+}
+
+// upcoming is the open occurrences from from onwards that have not started
+func upcoming(occurrences []*model.Appointment, from time.Time) []*model.Appointment {
+	var open []*model.Appointment
+	now := time.Now()
+	for _, apt := range occurrences {
+		if isOpen(apt) && !apt.OccurrenceStart.Before(from) && apt.StartTime.After(now) {
+			open = append(open, apt)
+		}
+	}
+	return open
+}
+
+func isOpen(apt *model.Appointment) bool {
+	return apt.Status == model.AppointmentStatusScheduled || apt.Status == model.AppointmentStatusConfirmed
+}
+
+// atClock is t's date in loc at clock's time of day there
+func atClock(t, clock time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	h, m, sec := clock.In(loc).Clock()
+	return time.Date(t.Year(), t.Month(), t.Day(), h, m, sec, 0, loc)
+}
+
+func atClockAll(times []time.Time, clock time.Time, loc *time.Location) []time.Time {
+	moved := make([]time.Time, len(times))
+	for i, t := range times {
+		moved[i] = atClock(t, clock, loc)
+	}
+	return moved
+}
+
+func sameDate(a, b time.Time, loc *time.Location) bool {
+	ay, am, ad := a.In(loc).Date()
+	by, bm, bd := b.In(loc).Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// partitionTimes splits times into those before at and the rest
+func partitionTimes(times []time.Time, at time.Time) (before, after []time.Time) {
+	for _, t := range times {
+		if t.Before(at) {
+			before = append(before, t)
+		} else {
+			after = append(after, t)
+		}
+	}
+	return before, after
+}
@@ -2,6 +2,7 @@ package appointment
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,9 @@ import (
 	"github.com/jwalitptl/admin-api/internal/repository"
 	"github.com/jwalitptl/admin-api/internal/service/audit"
 	"github.com/jwalitptl/admin-api/internal/service/notification"
+	"github.com/jwalitptl/admin-api/internal/service/reminder"
+	"github.com/jwalitptl/admin-api/internal/service/schedule"
+	"github.com/jwalitptl/admin-api/internal/service/waitlist"
 )
 
 // Add these constants for business rules
@@ -23,16 +27,26 @@ const (
 
 type Service struct {
 	repo         repository.AppointmentRepository
+	series       repository.AppointmentSeriesRepository
 	notifSvc     notification.Service
 	auditor      *audit.Service
 	clinicianSvc repository.ClinicianRepository
+	services     repository.ServiceRepository
+	schedule     *schedule.Service
+	waitlist     *waitlist.Service
+	reminders    *reminder.Service
 }
 
-func NewService(repo repository.AppointmentRepository, notifSvc notification.Service, clinicianSvc repository.ClinicianRepository, auditor *audit.Service) *Service {
+func NewService(repo repository.AppointmentRepository, series repository.AppointmentSeriesRepository, notifSvc notification.Service, clinicianSvc repository.ClinicianRepository, services repository.ServiceRepository, schedule *schedule.Service, waitlist *waitlist.Service, reminders *reminder.Service, auditor *audit.Service) *Service {
 	return &Service{
 		repo:         repo,
+		series:       series,
 		notifSvc:     notifSvc,
 		clinicianSvc: clinicianSvc,
+		services:     services,
+		schedule:     schedule,
+		waitlist:     waitlist,
+		reminders:    reminders,
 		auditor:      auditor,
 	}
 }
@@ -57,8 +71,10 @@ func (s *Service) validateAppointmentTime(startTime, endTime time.Time) error {
 	return nil
 }
 
+// CreateAppointment books the appointment. An overlap with another booking
+// or a hold, including one that races this booking, is a SlotConflictError.
 func (s *Service) CreateAppointment(ctx context.Context, apt *model.Appointment) error {
-	if err := s.validateAppointment(apt); err != nil {
+	if err := s.validateAppointment(ctx, apt); err != nil {
 		return fmt.Errorf("invalid appointment: %w", err)
 	}
 
@@ -68,9 +84,18 @@ func (s *Service) CreateAppointment(ctx context.Context, apt *model.Appointment)
 	apt.UpdatedAt = time.Now()
 
 	if err := s.repo.Create(ctx, apt); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return &SlotConflictError{ClinicianID: apt.ClinicianID, StartTime: apt.StartTime, EndTime: apt.EndTime}
+		}
 		return fmt.Errorf("failed to create appointment: %w", err)
 	}
 
+	s.afterCreate(ctx, apt)
+	return nil
+}
+
+// afterCreate notifies and audits a new appointment
+func (s *Service) afterCreate(ctx context.Context, apt *model.Appointment) {
 	// Send notifications
 	if err := s.notifyParticipants(ctx, apt, "appointment_created"); err != nil {
 		s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "notification_failed", "appointment", apt.ID, &audit.LogOptions{
@@ -83,8 +108,6 @@ func (s *Service) CreateAppointment(ctx context.Context, apt *model.Appointment)
 	s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "create", "appointment", apt.ID, &audit.LogOptions{
 		Changes: apt,
 	})
-
-	return nil
 }
 
 func (s *Service) GetAppointment(ctx context.Context, id uuid.UUID) (*model.Appointment, error) {
@@ -98,12 +121,15 @@ func (s *Service) GetAppointment(ctx context.Context, id uuid.UUID) (*model.Appo
 }
 
 func (s *Service) UpdateAppointment(ctx context.Context, apt *model.Appointment) error {
-	if err := s.validateAppointment(apt); err != nil {
+	if err := s.validateAppointment(ctx, apt); err != nil {
 		return fmt.Errorf("invalid appointment: %w", err)
 	}
 
 	apt.UpdatedAt = time.Now()
 	if err := s.repo.Update(ctx, apt); err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return &SlotConflictError{ClinicianID: apt.ClinicianID, StartTime: apt.StartTime, EndTime: apt.EndTime}
+		}
 		return fmt.Errorf("failed to update appointment: %w", err)
 	}
 
@@ -161,9 +187,24 @@ func (s *Service) CancelAppointment(ctx context.Context, id uuid.UUID, reason st
 		},
 	})
 
+	s.releaseSlot(ctx, apt)
+
 	return nil
 }
 
+// releaseSlot offers a cancelled appointment's slot to the waitlist. A
+// failure is audited rather than failing the cancellation; the slot is
+// still free to book.
+func (s *Service) releaseSlot(ctx context.Context, apt *model.Appointment) {
+	if _, err := s.waitlist.OfferSlot(ctx, apt); err != nil {
+		s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "waitlist_offer_failed", "appointment", apt.ID, &audit.LogOptions{
+			Metadata: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+}
+
 func (s *Service) isTimeSlotAvailable(ctx context.Context, staffID uuid.UUID, start, end time.Time) (bool, error) {
 	conflicts, err := s.repo.FindConflictingAppointments(ctx, staffID, start, end)
 	if err != nil {
@@ -173,7 +214,7 @@ func (s *Service) isTimeSlotAvailable(ctx context.Context, staffID uuid.UUID, st
 }
 
 func (s *Service) GetClinicianAvailability(ctx context.Context, clinicianID uuid.UUID, date time.Time) ([]model.TimeSlot, error) {
-	schedule, err := s.repo.GetClinicianSchedule(ctx, clinicianID, date)
+	schedule, err := s.getClinicianSchedule(ctx, clinicianID, date)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get clinician schedule: %w", err)
 	}
@@ -211,7 +252,8 @@ func filterAvailableSlots(slots []model.TimeSlot, appointments []*model.Appointm
 	for _, slot := range slots {
 		conflict := false
 		for _, apt := range appointments {
-			if !(slot.End.Before(apt.StartTime) || slot.Start.After(apt.EndTime)) {
+			// Back-to-back bookings share an instant without overlapping
+			if slot.Start.Before(apt.EndTime) && apt.StartTime.Before(slot.End) {
 				conflict = true
 				break
 			}
@@ -237,7 +279,25 @@ func (s *Service) DeleteAppointment(ctx context.Context, id uuid.UUID) error {
 	return s.repo.Delete(ctx, id)
 }
 
-func (s *Service) validateAppointment(apt *model.Appointment) error {
+// validateAppointment checks the appointment and that its slot is free.
+// The database has the final say on overlaps when the booking is saved.
+func (s *Service) validateAppointment(ctx context.Context, apt *model.Appointment) error {
+	if err := s.validateAppointmentFields(apt); err != nil {
+		return err
+	}
+
+	hasConflict, err := s.repo.CheckConflicts(ctx, apt.ClinicianID, apt.StartTime, apt.EndTime, &apt.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check conflicts: %w", err)
+	}
+	if hasConflict {
+		return &SlotConflictError{ClinicianID: apt.ClinicianID, StartTime: apt.StartTime, EndTime: apt.EndTime}
+	}
+
+	return nil
+}
+
+func (s *Service) validateAppointmentFields(apt *model.Appointment) error {
 	if apt.PatientID == uuid.Nil {
 		return fmt.Errorf("patient ID is required")
 	}
@@ -250,34 +310,32 @@ func (s *Service) validateAppointment(apt *model.Appointment) error {
 		return fmt.Errorf("clinic ID is required")
 	}
 
-	duration := apt.EndTime.Sub(apt.StartTime)
+	return validateBookingTime(apt.StartTime, apt.EndTime)
+}
+
+func validateBookingTime(start, end time.Time) error {
+	duration := end.Sub(start)
 	if duration < MinAppointmentDuration || duration > MaxAppointmentDuration {
 		return fmt.Errorf("invalid appointment duration: must be between %v and %v", MinAppointmentDuration, MaxAppointmentDuration)
 	}
 
-	advance := apt.StartTime.Sub(time.Now())
+	advance := start.Sub(time.Now())
 	if advance < MinAdvanceBooking || advance > MaxAdvanceBooking {
 		return fmt.Errorf("invalid booking time: must be between %v and %v in advance", MinAdvanceBooking, MaxAdvanceBooking)
 	}
 
-	hasConflict, err := s.repo.CheckConflicts(context.Background(), apt.ClinicianID, apt.StartTime, apt.EndTime, &apt.ID)
-	if err != nil {
-		return fmt.Errorf("failed to check conflicts: %w", err)
-	}
-	if hasConflict {
-		return fmt.Errorf("appointment conflicts with existing booking")
-	}
-
 	return nil
 }
 
 func (s *Service) GetAvailableSlots(ctx context.Context, clinicianID uuid.UUID, date time.Time) ([]model.TimeSlot, error) {
-	clinician, err := s.clinicianSvc.Get(ctx, clinicianID)
-	if err != nil {
+	if _, err := s.clinicianSvc.Get(ctx, clinicianID); err != nil {
 		return nil, fmt.Errorf("failed to get clinician: %w", err)
 	}
 
-	schedule := s.getClinicianSchedule(clinician, date)
+	schedule, err := s.getClinicianSchedule(ctx, clinicianID, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clinician schedule: %w", err)
+	}
 	appointments, err := s.repo.GetClinicianAppointments(ctx, clinicianID, date, date.Add(24*time.Hour))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get clinician appointments: %w", err)
@@ -286,19 +344,44 @@ func (s *Service) GetAvailableSlots(ctx context.Context, clinicianID uuid.UUID,
 	return s.calculateAvailableSlots(schedule, appointments), nil
 }
 
+// notifyParticipants keeps the patient's reminders in step with the
+// appointment: booking or moving it schedules reminders for its new time,
+// and cancelling it cancels the ones still pending
 func (s *Service) notifyParticipants(ctx context.Context, apt *model.Appointment, event string) error {
-	// Implementation of notifyParticipants method
-	return nil
+	if event == "appointment_cancelled" {
+		return s.reminders.Cancel(ctx, apt.ID)
+	}
+	return s.reminders.Schedule(ctx, apt)
 }
 
-func (s *Service) getClinicianSchedule(clinician *model.Clinician, date time.Time) []*model.TimeSlot {
-	// Implementation of getClinicianSchedule method
-	return nil
+// getClinicianSchedule is when the clinician works in the 24 hours from
+// date, as resolved from their availability rules and exceptions
+func (s *Service) getClinicianSchedule(ctx context.Context, clinicianID uuid.UUID, date time.Time) ([]*model.TimeSlot, error) {
+	windows, err := s.schedule.Windows(ctx, clinicianID, date, date.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]*model.TimeSlot, 0, len(windows))
+	for _, w := range windows {
+		slots = append(slots, &model.TimeSlot{Start: w.Start, End: w.End})
+	}
+	return slots, nil
 }
 
+// calculateAvailableSlots is the clinician's working time not taken by an
+// appointment
 func (s *Service) calculateAvailableSlots(schedule []*model.TimeSlot, appointments []*model.Appointment) []model.TimeSlot {
-	// Implementation of calculateAvailableSlots method
-	return nil
+	busy := make([]model.TimeSlot, 0, len(appointments))
+	for _, apt := range appointments {
+		busy = append(busy, model.TimeSlot{Start: apt.StartTime, End: apt.EndTime})
+	}
+
+	available := []model.TimeSlot{}
+	for _, slot := range schedule {
+		available = append(available, subtractBusy(*slot, busy)...)
+	}
+	return available
 }
 
 func (s *Service) CheckConflicts(ctx context.Context, apt *model.Appointment) (bool, error) {
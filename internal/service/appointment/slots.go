@@ -0,0 +1,194 @@
+package appointment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+const (
+	// SlotGranularity is what slot start times are rounded up to
+	SlotGranularity = 5 * time.Minute
+	// MaxSlotClinicians bounds how many clinicians one slot search covers
+	MaxSlotClinicians = 25
+)
+
+var (
+	ErrServiceNotFound    = errors.New("service not found")
+	ErrServiceUnavailable = errors.New("service cannot be booked")
+	ErrNoClinicians       = errors.New("at least one clinician is required")
+	ErrTooManyClinicians  = fmt.Errorf("at most %d clinicians can be searched at once", MaxSlotClinicians)
+	ErrInvalidSlotRange   = errors.New("to must be after from")
+)
+
+// FindSlots lists when the service can be booked with each of the
+// clinicians between search.From and search.To, earliest first. A slot
+// starts no sooner than MinAdvanceBooking from now and no later than
+// MaxAdvanceBooking. The booking, with the service's buffers around it,
+// must fit within the clinician's availability and clear their other
+// appointments and those appointments' buffers.
+func (s *Service) FindSlots(ctx context.Context, search *model.SlotSearch) ([]model.BookableSlot, error) {
+	if len(search.ClinicianIDs) == 0 {
+		return nil, ErrNoClinicians
+	}
+	if len(search.ClinicianIDs) > MaxSlotClinicians {
+		return nil, ErrTooManyClinicians
+	}
+	if !search.To.After(search.From) {
+		return nil, ErrInvalidSlotRange
+	}
+
+	svc, err := s.services.Get(ctx, search.ServiceID)
+	if err != nil {
+		return nil, ErrServiceNotFound
+	}
+	if !svc.IsActive || svc.Duration <= 0 {
+		return nil, ErrServiceUnavailable
+	}
+	duration := time.Duration(svc.Duration) * time.Minute
+	before := time.Duration(svc.BufferBefore) * time.Minute
+	after := time.Duration(svc.BufferAfter) * time.Minute
+
+	now := time.Now()
+	earliest := laterOf(search.From, now.Add(MinAdvanceBooking))
+	latest := earlierOf(search.To, now.Add(MaxAdvanceBooking))
+	slots := []model.BookableSlot{}
+	if latest.Before(earliest) {
+		return slots, nil
+	}
+	// The last slot may start at latest, so look far enough past it for the
+	// booking and its buffers to fit
+	from, to := earliest.Add(-before), latest.Add(duration+after)
+
+	busy, err := s.repo.ListBusyPeriods(ctx, search.ClinicianIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	busyByClinician := make(map[uuid.UUID][]model.TimeSlot)
+	for _, b := range busy {
+		busyByClinician[b.ClinicianID] = append(busyByClinician[b.ClinicianID], model.TimeSlot{Start: b.Start, End: b.End})
+	}
+
+	for _, clinicianID := range dedupe(search.ClinicianIDs) {
+		windows, err := s.schedule.Windows(ctx, clinicianID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get availability of clinician %s: %w", clinicianID, err)
+		}
+
+		for _, w := range mergeWindows(windows) {
+			if search.ClinicID != nil && w.ClinicID != *search.ClinicID {
+				continue
+			}
+			for _, free := range subtractBusy(model.TimeSlot{Start: w.Start, End: w.End}, busyByClinician[clinicianID]) {
+				for _, start := range slotStarts(free, duration, before, after, earliest, latest) {
+					slots = append(slots, model.BookableSlot{
+						ClinicianID: clinicianID,
+						ClinicID:    w.ClinicID,
+						Start:       start,
+						End:         start.Add(duration),
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots, nil
+}
+
+// slotStarts lists the start times in a free stretch at which a booking of
+// duration fits with its buffers. Slots follow one another a duration apart
+// from the first start, which is rounded up to SlotGranularity.
+func slotStarts(free model.TimeSlot, duration, before, after time.Duration, earliest, latest time.Time) []time.Time {
+	first := laterOf(free.Start.Add(before), earliest)
+	if rounded := first.Truncate(SlotGranularity); rounded.Before(first) {
+		first = rounded.Add(SlotGranularity)
+	}
+
+	var starts []time.Time
+	for start := first; !start.After(latest) && !start.Add(duration+after).After(free.End); start = start.Add(duration) {
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// subtractBusy is what is left of a stretch of time once the busy periods
+// are taken out. Periods are half-open, so a booking may start the moment
+// another ends.
+func subtractBusy(slot model.TimeSlot, busy []model.TimeSlot) []model.TimeSlot {
+	free := []model.TimeSlot{slot}
+	for _, b := range busy {
+		next := free[:0:0]
+		for _, f := range free {
+			if !b.Start.Before(f.End) || !f.Start.Before(b.End) {
+				next = append(next, f)
+				continue
+			}
+			if f.Start.Before(b.Start) {
+				next = append(next, model.TimeSlot{Start: f.Start, End: b.Start})
+			}
+			if b.End.Before(f.End) {
+				next = append(next, model.TimeSlot{Start: b.End, End: f.End})
+			}
+		}
+		free = next
+	}
+	return free
+}
+
+// mergeWindows joins windows at the same clinic that overlap or touch, such
+// as an extra session run on from weekly hours, so slots are not offered
+// twice or cut at the seam
+func mergeWindows(windows []model.AvailabilityWindow) []model.AvailabilityWindow {
+	sorted := append([]model.AvailabilityWindow(nil), windows...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].ClinicID != sorted[j].ClinicID {
+			return sorted[i].ClinicID.String() < sorted[j].ClinicID.String()
+		}
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	var merged []model.AvailabilityWindow
+	for _, w := range sorted {
+		if n := len(merged); n > 0 && merged[n-1].ClinicID == w.ClinicID && !w.Start.After(merged[n-1].End) {
+			if w.End.After(merged[n-1].End) {
+				merged[n-1].End = w.End
+			}
+			continue
+		}
+		merged = append(merged, w)
+	}
+	return merged
+}
+
+func dedupe(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlierOf(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -85,6 +85,15 @@ func (s *Service) Log(ctx context.Context, userID, orgID uuid.UUID, action, enti
 		CreatedAt:      time.Now(),
 	}
 
+	// Requests made by a proxy carry the dependent patient and the
+	// relationship that authorized them; record both identities.
+	if patientID, ok := ctx.Value("acting_for").(uuid.UUID); ok {
+		log.OnBehalfOf = &patientID
+	}
+	if relationshipID, ok := ctx.Value("proxy_relationship_id").(uuid.UUID); ok {
+		log.ProxyRelationshipID = &relationshipID
+	}
+
 	return s.repo.Create(ctx, log)
 }
 
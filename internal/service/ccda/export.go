@@ -0,0 +1,256 @@
+package ccda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/pkg/ccda"
+)
+
+// Export writes the patient's Continuity of Care Document. Records are read
+// as reader, so the document holds only the records they may see and the
+// read is audited like any other. Problems and allergies follow the record
+// they were reconciled from; the rest of the clinical sections, which have
+// no access level of their own, are treated as private records.
+func (s *Service) Export(ctx context.Context, patientID uuid.UUID, reader *model.RecordReader) ([]byte, error) {
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+	org, err := s.orgRepo.GetOrganization(ctx, patient.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	identifiers, err := s.identifierRepo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identifiers: %w", err)
+	}
+	records, err := s.medicalSvc.ListMedicalRecords(ctx, patientID, reader, &model.RecordFilters{})
+	if err != nil {
+		return nil, err
+	}
+	private, err := s.medicalSvc.CanReadPrivate(ctx, patientID, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check record access: %w", err)
+	}
+	visible := make(map[uuid.UUID]bool, len(records))
+	for _, record := range records {
+		visible[record.ID] = true
+	}
+	readable := func(source *uuid.UUID) bool {
+		if source != nil {
+			return visible[*source]
+		}
+		return private
+	}
+
+	problems, err := s.clinicalLists.ListProblems(ctx, patientID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list problems: %w", err)
+	}
+	allergies, err := s.clinicalLists.ListAllergies(ctx, patientID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list allergies: %w", err)
+	}
+	var prescriptions []*model.Prescription
+	var appointments []*model.Appointment
+	if private {
+		if prescriptions, err = s.prescriptions.ListByPatient(ctx, patientID, ""); err != nil {
+			return nil, fmt.Errorf("failed to list prescriptions: %w", err)
+		}
+		appointments, err = s.appointmentRepo.List(ctx, &model.AppointmentFilters{
+			PatientID: patientID,
+			Status:    model.AppointmentStatusCompleted,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list appointments: %w", err)
+		}
+	}
+
+	orgID := ccda.Identifier{Root: org.ID.String()}
+	doc := &ccda.Document{
+		ID:        ccda.Identifier{Root: uuid.New().String()},
+		Title:     "Continuity of Care Document",
+		CreatedAt: time.Now(),
+		Patient:   documentPatient(patient, identifiers),
+		Author:    ccda.Organization{ID: orgID, Name: org.Name},
+		Custodian: ccda.Organization{ID: orgID, Name: org.Name},
+	}
+
+	for _, record := range records {
+		if err := addRecord(doc, record); err != nil {
+			return nil, fmt.Errorf("failed to export record %s: %w", record.ID, err)
+		}
+	}
+	// Refuted entries were never true of the patient and are left out
+	for _, p := range problems {
+		if p.Status != model.ClinicalStatusRefuted && readable(p.SourceRecordID) {
+			doc.Problems = append(doc.Problems, documentProblem(p))
+		}
+	}
+	for _, a := range allergies {
+		if a.Status != model.ClinicalStatusRefuted && readable(a.SourceRecordID) {
+			doc.Allergies = append(doc.Allergies, documentAllergy(a))
+		}
+	}
+	for _, p := range prescriptions {
+		doc.Medications = append(doc.Medications, documentPrescription(p))
+	}
+	for _, a := range appointments {
+		end := a.EndTime
+		doc.Encounters = append(doc.Encounters, ccda.Encounter{
+			ID:    ccda.Identifier{Root: a.ID.String()},
+			Code:  ccda.Code{Code: "AMB", System: ccda.SystemActCode, Display: "Ambulatory"},
+			Start: a.StartTime,
+			End:   &end,
+		})
+	}
+
+	data, err := ccda.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, reader.UserID, patient.OrganizationID, "export", "patient", patientID, &audit.LogOptions{
+		AccessReason: reader.Reason,
+		Metadata: map[string]interface{}{
+			"format":      "ccda",
+			"document_id": doc.ID.Root,
+			"records":     len(records),
+		},
+	})
+
+	return data, nil
+}
+
+// addRecord puts a medical record in the section its type belongs to.
+// Coded diagnoses reach the document through the problem and allergy lists
+// they are reconciled into; other free-text records are left out.
+func addRecord(doc *ccda.Document, record *model.MedicalRecord) error {
+	id := ccda.Identifier{Root: record.ID.String()}
+	onset := record.CreatedAt
+
+	switch record.Type {
+	case RecordTypeLabResult:
+		var result model.LabResult
+		if err := json.Unmarshal(record.Diagnosis, &result); err != nil {
+			return err
+		}
+		r := documentResult(&result)
+		r.ID = id
+		doc.Results = append(doc.Results, r)
+
+	case RecordTypeMedication:
+		for _, m := range record.Medications {
+			drug := ccda.Code{Display: m.Name}
+			// Medications committed from an imported document keep their code
+			var coded model.CodedEntry
+			if json.Unmarshal(record.Treatment, &coded) == nil && coded.Code != "" {
+				drug = documentCode(coded)
+			}
+			doc.Medications = append(doc.Medications, ccda.Medication{
+				ID:           id,
+				Drug:         drug,
+				Instructions: strings.TrimSpace(m.Dosage + " " + m.Schedule),
+				Active:       true,
+				Start:        &onset,
+			})
+		}
+
+	case RecordTypeEncounter:
+		doc.Encounters = append(doc.Encounters, ccda.Encounter{
+			ID:    id,
+			Code:  ccda.Code{Code: "AMB", System: ccda.SystemActCode, Display: "Ambulatory"},
+			Text:  record.Description,
+			Start: record.CreatedAt,
+		})
+
+	case RecordTypeAllergy:
+		// Coded allergies are exported from the allergy list, but one
+		// recorded as free text is still an allergy
+		if _, structured, err := model.ParseCodedEntries(record.Diagnosis); err != nil || !structured {
+			doc.Allergies = append(doc.Allergies, ccda.Allergy{
+				ID: id, Allergen: ccda.Code{Display: record.Description}, Active: true, Onset: &onset,
+			})
+		}
+	}
+	return nil
+}
+
+func documentPatient(p *model.Patient, identifiers []*model.PatientIdentifier) ccda.Patient {
+	patient := ccda.Patient{
+		IDs:        []ccda.Identifier{{Root: p.ID.String()}},
+		GivenName:  p.FirstName,
+		FamilyName: p.LastName,
+		Gender:     genderCode(p.Gender),
+		BirthDate:  p.DateOfBirth,
+		Address:    p.Address,
+		Phone:      p.Phone,
+		Email:      p.Email,
+	}
+	// Only identifiers issued under an OID can be written as CDA ids
+	for _, id := range identifiers {
+		if id.PeriodEnd != nil || !strings.HasPrefix(id.System, "urn:oid:") {
+			continue
+		}
+		patient.IDs = append(patient.IDs, ccda.Identifier{Root: strings.TrimPrefix(id.System, "urn:oid:"), Extension: id.Value})
+	}
+	return patient
+}
+
+func documentPrescription(p *model.Prescription) ccda.Medication {
+	var sig []string
+	for _, part := range []string{p.Strength, p.Route, p.Frequency} {
+		if part != "" {
+			sig = append(sig, part)
+		}
+	}
+	start := p.StartDate
+	return ccda.Medication{
+		ID:           ccda.Identifier{Root: p.ID.String()},
+		Drug:         ccda.Code{Code: p.DrugCode, System: oidFromSystem(p.DrugSystem), Display: p.DrugName},
+		Instructions: strings.Join(sig, "; "),
+		Active:       p.Status == model.PrescriptionStatusActive,
+		Start:        &start,
+		Stop:         p.StopDate,
+	}
+}
+
+func documentProblem(p *model.Problem) ccda.Problem {
+	return ccda.Problem{
+		ID:       ccda.Identifier{Root: p.ID.String()},
+		Code:     documentCode(model.CodedEntry{System: p.CodeSystem, Code: p.Code, Display: p.Display}),
+		Active:   p.Status == model.ClinicalStatusActive,
+		Onset:    p.OnsetDate,
+		Resolved: p.ResolvedDate,
+	}
+}
+
+// documentAllergy writes an allergy with its first reaction; the document
+// carries one manifestation per allergy
+func documentAllergy(a *model.Allergy) ccda.Allergy {
+	allergy := ccda.Allergy{
+		ID:       ccda.Identifier{Root: a.ID.String()},
+		Allergen: documentCode(model.CodedEntry{System: a.CodeSystem, Code: a.Code, Display: a.Display}),
+		Active:   a.Status == model.ClinicalStatusActive,
+		Onset:    a.OnsetDate,
+	}
+	if len(a.Reactions) > 0 {
+		reaction := documentCode(a.Reactions[0].Manifestation)
+		allergy.Reaction = &reaction
+	}
+	if a.Severity != nil {
+		if severity, ok := severityCodes[*a.Severity]; ok {
+			allergy.Severity = &severity
+		}
+	}
+	return allergy
+}
@@ -0,0 +1,157 @@
+package ccda
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/pkg/ccda"
+)
+
+// oidSystems maps the OIDs documents identify code systems by to the
+// canonical URIs used everywhere else
+var oidSystems = map[string]string{
+	ccda.SystemICD10CM: model.CodeSystemICD10CM,
+	ccda.SystemSNOMED:  model.CodeSystemSNOMED,
+	ccda.SystemLOINC:   model.CodeSystemLOINC,
+	ccda.SystemRxNorm:  model.DrugSystemRxNorm,
+}
+
+// systemFromOID returns the canonical URI of a known code system, or the
+// OID as a urn:oid: URI
+func systemFromOID(oid string) string {
+	if system, ok := oidSystems[oid]; ok {
+		return system
+	}
+	if oid == "" {
+		return ""
+	}
+	return "urn:oid:" + oid
+}
+
+func oidFromSystem(system string) string {
+	if canonical := model.CanonicalCodeSystem(system); canonical != "" {
+		system = canonical
+	}
+	for oid, s := range oidSystems {
+		if s == system {
+			return oid
+		}
+	}
+	return strings.TrimPrefix(system, "urn:oid:")
+}
+
+func codedEntry(c ccda.Code) model.CodedEntry {
+	return model.CodedEntry{System: systemFromOID(c.System), Code: c.Code, Display: c.Display}
+}
+
+func documentCode(e model.CodedEntry) ccda.Code {
+	return ccda.Code{Code: e.Code, System: oidFromSystem(e.System), Display: e.Display}
+}
+
+// severityCodes are the SNOMED CT codes for allergy severity
+var severityCodes = map[string]ccda.Code{
+	model.SeverityMild:     documentCode(model.CodedEntry{System: model.CodeSystemSNOMED, Code: "255604002", Display: "Mild"}),
+	model.SeverityModerate: documentCode(model.CodedEntry{System: model.CodeSystemSNOMED, Code: "6736007", Display: "Moderate"}),
+	model.SeveritySevere:   documentCode(model.CodedEntry{System: model.CodeSystemSNOMED, Code: "24484000", Display: "Severe"}),
+}
+
+// genderCodes maps patient genders to HL7 AdministrativeGender
+var genderCodes = map[string]string{
+	"male":   "M",
+	"female": "F",
+}
+
+func genderCode(gender string) string {
+	if code, ok := genderCodes[strings.ToLower(gender)]; ok {
+		return code
+	}
+	return "UN"
+}
+
+func genderFromCode(code string) string {
+	for gender, c := range genderCodes {
+		if c == code {
+			return gender
+		}
+	}
+	return "other"
+}
+
+// labResult converts a result panel to the form HL7 v2 lab results are
+// filed in
+func labResult(r ccda.Result) *model.LabResult {
+	result := &model.LabResult{
+		Order:        codedEntry(r.Panel),
+		Status:       "final",
+		ObservedAt:   r.Time,
+		Observations: make([]model.LabObservation, 0, len(r.Observations)),
+	}
+	for _, o := range r.Observations {
+		valueType := "ST"
+		if o.Unit != "" {
+			valueType = "NM"
+		}
+		result.Observations = append(result.Observations, model.LabObservation{
+			Code:           codedEntry(o.Code),
+			ValueType:      valueType,
+			Value:          o.Value,
+			Units:          o.Unit,
+			ReferenceRange: o.ReferenceRange,
+			AbnormalFlags:  o.Interpretation,
+			Status:         "F",
+			ObservedAt:     o.Time,
+		})
+	}
+	return result
+}
+
+func documentResult(r *model.LabResult) ccda.Result {
+	result := ccda.Result{Panel: documentCode(r.Order), Time: r.ObservedAt}
+	for _, o := range r.Observations {
+		obs := ccda.Observation{
+			Code:           documentCode(o.Code),
+			Value:          o.Value,
+			ReferenceRange: o.ReferenceRange,
+			Interpretation: o.AbnormalFlags,
+			Time:           o.ObservedAt,
+		}
+		// Only numeric values carry a unit; the rest are text
+		if o.ValueType == "NM" || o.ValueType == "SN" {
+			obs.Unit = o.Units
+		}
+		result.Observations = append(result.Observations, obs)
+	}
+	return result
+}
+
+// allergyDetail describes an allergy's reaction and severity
+func allergyDetail(a ccda.Allergy) string {
+	var parts []string
+	if a.Reaction != nil {
+		parts = append(parts, "reaction: "+codeText(*a.Reaction))
+	}
+	if a.Severity != nil {
+		parts = append(parts, "severity: "+codeText(*a.Severity))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func codeText(c ccda.Code) string {
+	if c.Display != "" {
+		return c.Display
+	}
+	return c.Code
+}
+
+// entryText is how a staged entry is described on the record it becomes
+func entryText(e *model.StagedEntry) string {
+	text := e.Code.Display
+	if text == "" {
+		text = fmt.Sprintf("%s %s", e.Code.System, e.Code.Code)
+	}
+	if e.Detail != "" {
+		text += " (" + e.Detail + ")"
+	}
+	return text
+}
@@ -0,0 +1,614 @@
+package ccda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/clinicallist"
+	"github.com/jwalitptl/admin-api/internal/service/hl7"
+	"github.com/jwalitptl/admin-api/internal/service/keyring"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/internal/service/terminology"
+	"github.com/jwalitptl/admin-api/pkg/ccda"
+)
+
+var (
+	ErrNotFound         = errors.New("C-CDA import not found")
+	ErrInvalidDocument  = errors.New("document is not a valid C-CDA")
+	ErrDocumentTooLarge = errors.New("document is too large")
+	ErrAlreadyImported  = errors.New("this document has already been imported for the patient")
+	ErrNotClinician     = errors.New("only clinicians can import and reconcile documents")
+	ErrNotStaged        = errors.New("import has already been reconciled or discarded")
+	ErrInvalidDecisions = errors.New("every staged entry needs exactly one decision")
+	ErrConflict         = errors.New("import was changed by another request")
+)
+
+// Record types of the medical records reconciled entries become. Lab results
+// are filed like HL7 v2 results.
+const (
+	RecordTypeProblem    = "problem"
+	RecordTypeAllergy    = clinicallist.AllergyRecordType
+	RecordTypeMedication = "medication"
+	RecordTypeEncounter  = "encounter"
+	RecordTypeLabResult  = hl7.LabRecordType
+)
+
+// clinicians may import documents and reconcile them
+var clinicians = map[string]bool{
+	model.UserTypeDoctor: true,
+	model.UserTypeNurse:  true,
+}
+
+type Config struct {
+	// ImportAccessLevel is the access level of records committed from
+	// imported documents
+	ImportAccessLevel string
+	// MaxDocumentBytes limits the size of an imported document
+	MaxDocumentBytes int64
+}
+
+type Service struct {
+	repo            repository.CCDARepository
+	patientRepo     repository.PatientRepository
+	orgRepo         repository.OrganizationRepository
+	identifierRepo  repository.IdentifierRepository
+	appointmentRepo repository.AppointmentRepository
+	prescriptions   repository.PrescriptionRepository
+	clinicalLists   repository.ClinicalListRepository
+	medicalSvc      *medical.Service
+	terminology     *terminology.Service
+	keys            *keyring.Service
+	auditor         *audit.Service
+	config          Config
+}
+
+func NewService(
+	repo repository.CCDARepository,
+	patientRepo repository.PatientRepository,
+	orgRepo repository.OrganizationRepository,
+	identifierRepo repository.IdentifierRepository,
+	appointmentRepo repository.AppointmentRepository,
+	prescriptions repository.PrescriptionRepository,
+	clinicalLists repository.ClinicalListRepository,
+	medicalSvc *medical.Service,
+	terminology *terminology.Service,
+	keys *keyring.Service,
+	auditor *audit.Service,
+	config Config,
+) *Service {
+	if config.ImportAccessLevel == "" {
+		config.ImportAccessLevel = "private"
+	}
+	if config.MaxDocumentBytes <= 0 {
+		config.MaxDocumentBytes = 5 << 20
+	}
+	return &Service{
+		repo:            repo,
+		patientRepo:     patientRepo,
+		orgRepo:         orgRepo,
+		identifierRepo:  identifierRepo,
+		appointmentRepo: appointmentRepo,
+		prescriptions:   prescriptions,
+		clinicalLists:   clinicalLists,
+		medicalSvc:      medicalSvc,
+		terminology:     terminology,
+		keys:            keys,
+		auditor:         auditor,
+		config:          config,
+	}
+}
+
+// importContent is what an import keeps encrypted: the document as received
+// and what was staged from it
+type importContent struct {
+	Document     string                    `json:"document"`
+	Demographics *model.StagedDemographics `json:"demographics"`
+	Entries      []*model.StagedEntry      `json:"entries"`
+}
+
+// Import parses an inbound C-CDA and stages its entries for reconciliation.
+// Nothing reaches the patient's record until a clinician reconciles it.
+func (s *Service) Import(ctx context.Context, patientID uuid.UUID, userType string, r io.Reader) (*model.CCDAImport, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.config.MaxDocumentBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	if int64(len(data)) > s.config.MaxDocumentBytes {
+		return nil, ErrDocumentTooLarge
+	}
+
+	doc, err := ccda.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	demographics := &model.StagedDemographics{
+		FirstName:   doc.Patient.GivenName,
+		LastName:    doc.Patient.FamilyName,
+		DateOfBirth: doc.Patient.BirthDate,
+		Gender:      genderFromCode(doc.Patient.Gender),
+		Address:     doc.Patient.Address,
+		Phone:       doc.Patient.Phone,
+		Email:       doc.Patient.Email,
+	}
+
+	entries, err := s.stage(ctx, patientID, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &model.CCDAImport{
+		PatientID:         patientID,
+		OrganizationID:    patient.OrganizationID,
+		DocumentID:        documentID(doc.ID),
+		Title:             doc.Title,
+		Source:            doc.Custodian.Name,
+		Status:            model.CCDAImportStatusStaged,
+		DemographicsMatch: sameDemographics(patient, demographics),
+		ImportedBy:        s.getCurrentUserID(ctx),
+		Demographics:      demographics,
+		Entries:           entries,
+	}
+	if imp.Source == "" {
+		imp.Source = doc.Author.Name
+	}
+	if !doc.CreatedAt.IsZero() {
+		imp.DocumentDate = &doc.CreatedAt
+	}
+
+	if err := s.encryptContent(ctx, imp, string(data)); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateImport(ctx, imp); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAlreadyImported
+		}
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, imp.ImportedBy, imp.OrganizationID, "import", "ccda_import", imp.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"patient_id":         patientID,
+			"document_id":        imp.DocumentID,
+			"source":             imp.Source,
+			"entries":            len(entries),
+			"demographics_match": imp.DemographicsMatch,
+		},
+	})
+
+	return imp, nil
+}
+
+// stage turns the document's entries into staged entries. Problem and
+// allergy codes are checked against the loaded terminology, and entries whose
+// code the patient already has on record point at that record.
+func (s *Service) stage(ctx context.Context, patientID uuid.UUID, doc *ccda.Document) ([]*model.StagedEntry, error) {
+	existing, err := s.existingCodes(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*model.StagedEntry
+	add := func(e *model.StagedEntry) {
+		e.Index = len(entries)
+		e.Decision = model.StagedEntryPending
+		if id, ok := existing[codeKey(e.Code)]; ok && e.Code.Code != "" {
+			e.ExistingRecordID = &id
+		}
+		entries = append(entries, e)
+	}
+
+	for _, p := range doc.Problems {
+		e := &model.StagedEntry{
+			Kind:      model.StagedEntryProblem,
+			Code:      codedEntry(p.Code),
+			Active:    p.Active,
+			StartedAt: p.Onset,
+			EndedAt:   p.Resolved,
+		}
+		if err := s.checkCode(ctx, e); err != nil {
+			return nil, err
+		}
+		add(e)
+	}
+	for _, a := range doc.Allergies {
+		e := &model.StagedEntry{
+			Kind:      model.StagedEntryAllergy,
+			Code:      codedEntry(a.Allergen),
+			Active:    a.Active,
+			StartedAt: a.Onset,
+			Detail:    allergyDetail(a),
+		}
+		if err := s.checkCode(ctx, e); err != nil {
+			return nil, err
+		}
+		add(e)
+	}
+	for _, m := range doc.Medications {
+		add(&model.StagedEntry{
+			Kind:      model.StagedEntryMedication,
+			Code:      codedEntry(m.Drug),
+			Coded:     m.Drug.System == ccda.SystemRxNorm,
+			Active:    m.Active,
+			StartedAt: m.Start,
+			EndedAt:   m.Stop,
+			Detail:    m.Instructions,
+		})
+	}
+	for _, r := range doc.Results {
+		add(&model.StagedEntry{
+			Kind:      model.StagedEntryResult,
+			Code:      codedEntry(r.Panel),
+			Coded:     r.Panel.System == ccda.SystemLOINC,
+			StartedAt: r.Time,
+			Result:    labResult(r),
+		})
+	}
+	for _, enc := range doc.Encounters {
+		start := enc.Start
+		add(&model.StagedEntry{
+			Kind:      model.StagedEntryEncounter,
+			Code:      codedEntry(enc.Code),
+			StartedAt: &start,
+			EndedAt:   enc.End,
+			Detail:    enc.Text,
+		})
+	}
+	return entries, nil
+}
+
+// checkCode validates a problem or allergy code. The document's display is
+// kept; senders often word it differently from the code system.
+func (s *Service) checkCode(ctx context.Context, e *model.StagedEntry) error {
+	normalized, err := s.terminology.Validate(ctx, []model.CodedEntry{{System: e.Code.System, Code: e.Code.Code}})
+	var invalid *terminology.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		e.CodeIssue = invalid.Invalid[0].Reason
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check codes: %w", err)
+	}
+	display := e.Code.Display
+	e.Code = normalized[0]
+	if display != "" {
+		e.Code.Display = display
+	}
+	e.Coded = true
+	return nil
+}
+
+// existingCodes maps the coded entries already on the patient's records to
+// the record they are on
+func (s *Service) existingCodes(ctx context.Context, patientID uuid.UUID) (map[string]uuid.UUID, error) {
+	records, err := s.medicalSvc.ListMedicalRecords(ctx, patientID, nil, &model.RecordFilters{})
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[string]uuid.UUID)
+	for _, record := range records {
+		if record.Type == RecordTypeLabResult {
+			var result model.LabResult
+			if json.Unmarshal(record.Diagnosis, &result) == nil {
+				codes[codeKey(result.Order)] = record.ID
+			}
+			continue
+		}
+		entries, _, _ := model.ParseCodedEntries(record.Diagnosis)
+		for _, e := range entries {
+			codes[codeKey(e)] = record.ID
+		}
+	}
+	return codes, nil
+}
+
+func (s *Service) Get(ctx context.Context, patientID, importID uuid.UUID) (*model.CCDAImport, error) {
+	imp, err := s.repo.GetImport(ctx, importID)
+	if err != nil || imp.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	if _, err := s.decryptContent(ctx, imp); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), imp.OrganizationID, "read", "ccda_import", imp.ID, nil)
+
+	return imp, nil
+}
+
+func (s *Service) List(ctx context.Context, patientID uuid.UUID, status model.CCDAImportStatus) ([]*model.CCDAImport, error) {
+	return s.repo.ListImports(ctx, patientID, status)
+}
+
+// Reconcile applies a clinician's decision on every staged entry. Accepted
+// entries are committed as medical records; the import remembers which record
+// each became, so a reconcile that failed part way can be retried without
+// committing an entry twice.
+func (s *Service) Reconcile(ctx context.Context, patientID, importID uuid.UUID, userType string, req *model.ReconcileCCDAImportRequest) (*model.CCDAImport, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	imp, err := s.repo.GetImport(ctx, importID)
+	if err != nil || imp.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	if imp.Status != model.CCDAImportStatusStaged {
+		return nil, ErrNotStaged
+	}
+	if imp.Version != req.Version {
+		return nil, ErrConflict
+	}
+	content, err := s.decryptContent(ctx, imp)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions := make(map[int]bool, len(req.Decisions))
+	for _, d := range req.Decisions {
+		if _, dup := decisions[d.Index]; dup || d.Index < 0 || d.Index >= len(imp.Entries) {
+			return nil, ErrInvalidDecisions
+		}
+		decisions[d.Index] = d.Accept
+	}
+	if len(decisions) != len(imp.Entries) {
+		return nil, ErrInvalidDecisions
+	}
+	for _, e := range imp.Entries {
+		// Entries committed by an earlier attempt stay accepted
+		if e.RecordID != nil {
+			continue
+		}
+		e.Decision = model.StagedEntryRejected
+		if decisions[e.Index] {
+			e.Decision = model.StagedEntryAccepted
+		}
+	}
+
+	// Saving the decisions first claims the import, so a concurrent
+	// reconcile fails here instead of committing the same entries
+	if err := s.save(ctx, imp, content.Document); err != nil {
+		return nil, err
+	}
+
+	userID := s.getCurrentUserID(ctx)
+	var committed []uuid.UUID
+	for _, e := range imp.Entries {
+		if e.Decision != model.StagedEntryAccepted || e.RecordID != nil {
+			continue
+		}
+		record, err := s.commit(ctx, imp, e, userID)
+		if err != nil {
+			// Keep what was committed so a retry picks up from here
+			_ = s.save(ctx, imp, content.Document)
+			return nil, fmt.Errorf("failed to commit entry %d: %w", e.Index, err)
+		}
+		e.RecordID = &record.ID
+		committed = append(committed, record.ID)
+	}
+
+	now := time.Now()
+	imp.Status = model.CCDAImportStatusReconciled
+	imp.ReconciledBy = &userID
+	imp.ReconciledAt = &now
+	if err := s.save(ctx, imp, content.Document); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, userID, imp.OrganizationID, "reconcile", "ccda_import", imp.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"patient_id": patientID,
+			"accepted":   len(committed),
+			"rejected":   countDecision(imp.Entries, model.StagedEntryRejected),
+			"record_ids": committed,
+		},
+	})
+
+	return imp, nil
+}
+
+// Discard closes an import without committing any of it
+func (s *Service) Discard(ctx context.Context, patientID, importID uuid.UUID, userType string, req *model.DiscardCCDAImportRequest) (*model.CCDAImport, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	imp, err := s.repo.GetImport(ctx, importID)
+	if err != nil || imp.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	if imp.Status != model.CCDAImportStatusStaged {
+		return nil, ErrNotStaged
+	}
+	content, err := s.decryptContent(ctx, imp)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range imp.Entries {
+		// A failed reconcile already committed some entries; it has to be
+		// finished rather than discarded
+		if e.RecordID != nil {
+			return nil, ErrNotStaged
+		}
+		e.Decision = model.StagedEntryRejected
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	imp.Status = model.CCDAImportStatusDiscarded
+	imp.DiscardReason = &reason
+	if err := s.save(ctx, imp, content.Document); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), imp.OrganizationID, "discard", "ccda_import", imp.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"reason": reason},
+	})
+
+	return imp, nil
+}
+
+// commit files one accepted entry as a medical record
+func (s *Service) commit(ctx context.Context, imp *model.CCDAImport, e *model.StagedEntry, userID uuid.UUID) (*model.MedicalRecord, error) {
+	record := &model.MedicalRecord{
+		PatientID:      imp.PatientID,
+		OrganizationID: imp.OrganizationID,
+		Description:    entryText(e),
+		AccessLevel:    s.config.ImportAccessLevel,
+		CreatedBy:      userID,
+	}
+
+	var err error
+	switch e.Kind {
+	case model.StagedEntryProblem, model.StagedEntryAllergy:
+		record.Type = RecordTypeProblem
+		if e.Kind == model.StagedEntryAllergy {
+			record.Type = RecordTypeAllergy
+		}
+		// The code's own display replaces the sender's wording, which the
+		// description keeps. Uncoded entries are kept as free text rather
+		// than failing terminology validation.
+		if e.Coded {
+			record.Diagnosis, err = json.Marshal([]model.CodedEntry{{System: e.Code.System, Code: e.Code.Code}})
+		} else {
+			record.Diagnosis, err = json.Marshal(record.Description)
+		}
+	case model.StagedEntryMedication:
+		record.Type = RecordTypeMedication
+		record.Medications = []model.Medication{{Name: e.Code.Display, Dosage: e.Detail}}
+		record.Treatment, err = json.Marshal(e.Code)
+	case model.StagedEntryResult:
+		record.Type = RecordTypeLabResult
+		record.Diagnosis, err = json.Marshal(e.Result)
+	case model.StagedEntryEncounter:
+		record.Type = RecordTypeEncounter
+		record.Treatment, err = json.Marshal(map[string]interface{}{"started_at": e.StartedAt, "ended_at": e.EndedAt})
+	default:
+		return nil, fmt.Errorf("unknown entry kind %q", e.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.medicalSvc.CreateMedicalRecord(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// save re-encrypts the import's content and stores it under the version
+// guard
+func (s *Service) save(ctx context.Context, imp *model.CCDAImport, document string) error {
+	if err := s.encryptContent(ctx, imp, document); err != nil {
+		return err
+	}
+	if err := s.repo.UpdateImport(ctx, imp); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// ReencryptBatch moves up to limit imports still sealed under a retired data
+// key to their organization's active key
+func (s *Service) ReencryptBatch(ctx context.Context, limit int) (int, error) {
+	return s.repo.ReencryptBatch(ctx, limit, func(c *model.EncryptedContent) error {
+		plaintext, err := s.keys.Decrypt(ctx, c.Content)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", c.ID, err)
+		}
+		content, keyID, err := s.keys.Encrypt(ctx, c.OrganizationID, plaintext)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", c.ID, err)
+		}
+		c.Content, c.EncryptionKeyID = content, &keyID
+		return nil
+	})
+}
+
+func (s *Service) encryptContent(ctx context.Context, imp *model.CCDAImport, document string) error {
+	content, err := json.Marshal(&importContent{Document: document, Demographics: imp.Demographics, Entries: imp.Entries})
+	if err != nil {
+		return err
+	}
+	var keyID uuid.UUID
+	imp.Content, keyID, err = s.keys.Encrypt(ctx, imp.OrganizationID, content)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt import: %w", err)
+	}
+	imp.EncryptionKeyID = &keyID
+	return nil
+}
+
+// decryptContent fills in the import's demographics and entries. Erasure
+// empties the content, leaving an import without either.
+func (s *Service) decryptContent(ctx context.Context, imp *model.CCDAImport) (*importContent, error) {
+	content := &importContent{}
+	if len(imp.Content) == 0 {
+		return content, nil
+	}
+	plaintext, err := s.keys.Decrypt(ctx, imp.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt import: %w", err)
+	}
+	if err := json.Unmarshal(plaintext, content); err != nil {
+		return nil, fmt.Errorf("failed to read import: %w", err)
+	}
+	imp.Demographics = content.Demographics
+	imp.Entries = content.Entries
+	return content, nil
+}
+
+func sameDemographics(patient *model.Patient, d *model.StagedDemographics) bool {
+	return strings.EqualFold(strings.TrimSpace(patient.FirstName), strings.TrimSpace(d.FirstName)) &&
+		strings.EqualFold(strings.TrimSpace(patient.LastName), strings.TrimSpace(d.LastName)) &&
+		patient.DateOfBirth.Format("2006-01-02") == d.DateOfBirth.Format("2006-01-02")
+}
+
+func documentID(id ccda.Identifier) string {
+	if id.Extension == "" {
+		return id.Root
+	}
+	return id.Root + "^" + id.Extension
+}
+
+func codeKey(e model.CodedEntry) string {
+	return e.System + "|" + strings.ToUpper(e.Code)
+}
+
+func countDecision(entries []*model.StagedEntry, decision model.StagedEntryDecision) int {
+	n := 0
+	for _, e := range entries {
+		if e.Decision == decision {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
@@ -0,0 +1,138 @@
+package clinicallist
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/pkg/fhir"
+)
+
+// severityCodes are the SNOMED CT codes FHIR uses for condition severity
+var severityCodes = map[string]fhir.Coding{
+	model.SeverityMild:     {System: fhir.SystemSNOMED, Code: "255604002", Display: "Mild"},
+	model.SeverityModerate: {System: fhir.SystemSNOMED, Code: "6736007", Display: "Moderate"},
+	model.SeveritySevere:   {System: fhir.SystemSNOMED, Code: "24484000", Display: "Severe"},
+}
+
+// Refuted entries are reported as inactive with a refuted verification
+// status; FHIR requires a clinical status on anything not entered in error
+var clinicalStatusCodes = map[model.ClinicalStatus]string{
+	model.ClinicalStatusActive:   "active",
+	model.ClinicalStatusResolved: "resolved",
+	model.ClinicalStatusRefuted:  "inactive",
+}
+
+// Condition represents a problem list entry as a FHIR Condition
+func Condition(p *model.Problem) *fhir.Condition {
+	verification := "confirmed"
+	if p.Status == model.ClinicalStatusRefuted {
+		verification = "refuted"
+	}
+
+	c := &fhir.Condition{
+		ResourceType:       fhir.ResourceTypeCondition,
+		ID:                 p.ID.String(),
+		Meta:               meta(p.Version, p.UpdatedAt),
+		ClinicalStatus:     fhir.Concept(fhir.SystemConditionClinical, clinicalStatusCodes[p.Status], ""),
+		VerificationStatus: fhir.Concept(fhir.SystemConditionVerification, verification, ""),
+		Category:           []fhir.CodeableConcept{*fhir.Concept(fhir.SystemConditionCategory, "problem-list-item", "Problem List Item")},
+		Code:               codeConcept(p.CodeSystem, p.Code, p.Display),
+		Subject:            fhir.Reference{Reference: "Patient/" + p.PatientID.String()},
+		OnsetDateTime:      dateTime(p.OnsetDate),
+		AbatementDateTime:  dateTime(p.ResolvedDate),
+		RecordedDate:       p.CreatedAt.UTC().Format(time.RFC3339),
+		Note:               notes(p.Note),
+	}
+	if p.Severity != nil {
+		if coding, ok := severityCodes[*p.Severity]; ok {
+			c.Severity = &fhir.CodeableConcept{Coding: []fhir.Coding{coding}}
+		}
+	}
+	return c
+}
+
+// AllergyIntolerance represents an allergy list entry as a FHIR
+// AllergyIntolerance. A severe allergy has high criticality.
+func AllergyIntolerance(a *model.Allergy) *fhir.AllergyIntolerance {
+	verification := "confirmed"
+	if a.Status == model.ClinicalStatusRefuted {
+		verification = "refuted"
+	}
+
+	r := &fhir.AllergyIntolerance{
+		ResourceType:       fhir.ResourceTypeAllergyIntolerance,
+		ID:                 a.ID.String(),
+		Meta:               meta(a.Version, a.UpdatedAt),
+		ClinicalStatus:     fhir.Concept(fhir.SystemAllergyClinical, clinicalStatusCodes[a.Status], ""),
+		VerificationStatus: fhir.Concept(fhir.SystemAllergyVerification, verification, ""),
+		Code:               codeConcept(a.CodeSystem, a.Code, a.Display),
+		Patient:            fhir.Reference{Reference: "Patient/" + a.PatientID.String()},
+		OnsetDateTime:      dateTime(a.OnsetDate),
+		RecordedDate:       a.CreatedAt.UTC().Format(time.RFC3339),
+		Note:               notes(a.Note),
+	}
+	if a.Category != nil {
+		r.Category = []string{*a.Category}
+	}
+	if a.Severity != nil {
+		r.Criticality = "low"
+		if *a.Severity == model.SeveritySevere {
+			r.Criticality = "high"
+		}
+	}
+	for _, reaction := range a.Reactions {
+		m := reaction.Manifestation
+		concept := fhir.CodeableConcept{Text: m.Display}
+		if m.Code != "" {
+			concept = *codeConcept(m.System, m.Code, m.Display)
+		}
+		r.Reaction = append(r.Reaction, fhir.AllergyReaction{
+			Manifestation: []fhir.CodeableConcept{concept},
+			Severity:      reaction.Severity,
+		})
+	}
+	return r
+}
+
+// ProblemBundle is the problem list as a FHIR searchset
+func ProblemBundle(problems []*model.Problem) *fhir.Bundle {
+	entries := make([]fhir.BundleEntry, 0, len(problems))
+	for _, p := range problems {
+		entries = append(entries, fhir.BundleEntry{FullURL: "Condition/" + p.ID.String(), Resource: Condition(p)})
+	}
+	return fhir.Searchset(entries)
+}
+
+// AllergyBundle is the allergy list as a FHIR searchset
+func AllergyBundle(allergies []*model.Allergy) *fhir.Bundle {
+	entries := make([]fhir.BundleEntry, 0, len(allergies))
+	for _, a := range allergies {
+		entries = append(entries, fhir.BundleEntry{FullURL: "AllergyIntolerance/" + a.ID.String(), Resource: AllergyIntolerance(a)})
+	}
+	return fhir.Searchset(entries)
+}
+
+func codeConcept(system, code, display string) *fhir.CodeableConcept {
+	c := fhir.Concept(system, code, display)
+	c.Text = display
+	return c
+}
+
+func meta(version int, updated time.Time) *fhir.Meta {
+	return &fhir.Meta{VersionID: strconv.Itoa(version), LastUpdated: updated.UTC().Format(time.RFC3339)}
+}
+
+func dateTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func notes(note *string) []fhir.Annotation {
+	if note == nil || *note == "" {
+		return nil
+	}
+	return []fhir.Annotation{{Text: *note}}
+}
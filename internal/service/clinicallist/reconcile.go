@@ -0,0 +1,130 @@
+package clinicallist
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+// RecordCreated reconciles the lists with a new record's diagnosis codes.
+// A code not yet listed is added as active. A resolved entry becomes active
+// again, since the record shows it has recurred. Active entries are already
+// right, and refuted ones stay refuted until a clinician says otherwise.
+func (s *Service) RecordCreated(ctx context.Context, record *model.MedicalRecord) error {
+	if len(record.Codes) == 0 {
+		return nil
+	}
+
+	patient, err := s.patientRepo.Get(ctx, record.PatientID)
+	if err != nil {
+		return fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	for _, code := range record.Codes {
+		if record.Type == AllergyRecordType {
+			err = s.reconcileAllergy(ctx, record, patient.OrganizationID, code)
+		} else {
+			err = s.reconcileProblem(ctx, record, patient.OrganizationID, code)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) reconcileProblem(ctx context.Context, record *model.MedicalRecord, orgID uuid.UUID, code model.CodedEntry) error {
+	existing, err := s.repo.FindProblem(ctx, record.PatientID, code.System, code.Code)
+	if err != nil {
+		return err
+	}
+	meta := map[string]interface{}{"source_record_id": record.ID}
+
+	if existing == nil {
+		onset := record.CreatedAt
+		problem := &model.Problem{
+			PatientID:      record.PatientID,
+			OrganizationID: orgID,
+			CodeSystem:     code.System,
+			Code:           code.Code,
+			Display:        code.Display,
+			Status:         model.ClinicalStatusActive,
+			OnsetDate:      &onset,
+			SourceRecordID: &record.ID,
+			RecordedBy:     record.CreatedBy,
+		}
+		if err := s.repo.CreateProblem(ctx, problem); err != nil {
+			return fmt.Errorf("failed to add problem %s: %w", code.Code, err)
+		}
+		s.auditor.Log(ctx, record.CreatedBy, orgID, "reconcile", "problem", problem.ID, &audit.LogOptions{
+			Changes:  problem,
+			Metadata: meta,
+		})
+		return nil
+	}
+
+	if existing.Status != model.ClinicalStatusResolved {
+		return nil
+	}
+	before := *existing
+	existing.Status = model.ClinicalStatusActive
+	existing.ResolvedDate = nil
+	if err := s.repo.UpdateProblem(ctx, existing); err != nil {
+		return fmt.Errorf("failed to reactivate problem %s: %w", existing.ID, err)
+	}
+	s.auditor.Log(ctx, record.CreatedBy, orgID, "reconcile", "problem", existing.ID, &audit.LogOptions{
+		Changes:  map[string]interface{}{"before": before, "after": existing},
+		Metadata: meta,
+	})
+	return nil
+}
+
+func (s *Service) reconcileAllergy(ctx context.Context, record *model.MedicalRecord, orgID uuid.UUID, code model.CodedEntry) error {
+	existing, err := s.repo.FindAllergy(ctx, record.PatientID, code.System, code.Code)
+	if err != nil {
+		return err
+	}
+	meta := map[string]interface{}{"source_record_id": record.ID}
+
+	if existing == nil {
+		onset := record.CreatedAt
+		allergy := &model.Allergy{
+			PatientID:      record.PatientID,
+			OrganizationID: orgID,
+			CodeSystem:     code.System,
+			Code:           code.Code,
+			Display:        code.Display,
+			Status:         model.ClinicalStatusActive,
+			OnsetDate:      &onset,
+			SourceRecordID: &record.ID,
+			RecordedBy:     record.CreatedBy,
+		}
+		if err := s.repo.CreateAllergy(ctx, allergy); err != nil {
+			return fmt.Errorf("failed to add allergy %s: %w", code.Code, err)
+		}
+		s.auditor.Log(ctx, record.CreatedBy, orgID, "reconcile", "allergy", allergy.ID, &audit.LogOptions{
+			Changes:  allergy,
+			Metadata: meta,
+		})
+		return nil
+	}
+
+	if existing.Status != model.ClinicalStatusResolved {
+		return nil
+	}
+	before := *existing
+	existing.Status = model.ClinicalStatusActive
+	existing.ResolvedDate = nil
+	if err := s.repo.UpdateAllergy(ctx, existing); err != nil {
+		return fmt.Errorf("failed to reactivate allergy %s: %w", existing.ID, err)
+	}
+	s.auditor.Log(ctx, record.CreatedBy, orgID, "reconcile", "allergy", existing.ID, &audit.LogOptions{
+		Changes:  map[string]interface{}{"before": before, "after": existing},
+		Metadata: meta,
+	})
+	return nil
+}
@@ -0,0 +1,401 @@
+package clinicallist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/terminology"
+)
+
+var (
+	ErrNotFound           = errors.New("list entry not found")
+	ErrNotClinician       = errors.New("only clinicians can change problem and allergy lists")
+	ErrAlreadyListed      = errors.New("this code is already on the patient's list")
+	ErrInvalidReaction    = errors.New("a reaction needs a coded or described manifestation")
+	ErrInvalidDates       = errors.New("resolved date must not be before onset date")
+	ErrResolvedNotAllowed = errors.New("only resolved entries have a resolved date")
+	ErrNoChanges          = errors.New("no changes to the entry were given")
+)
+
+// AllergyRecordType is the medical record type whose coded diagnoses are
+// allergies. Codes of records of every other type are problems.
+const AllergyRecordType = "allergy"
+
+var clinicians = map[string]bool{
+	model.UserTypeDoctor: true,
+	model.UserTypeNurse:  true,
+}
+
+type Service struct {
+	repo        repository.ClinicalListRepository
+	patientRepo repository.PatientRepository
+	terminology *terminology.Service
+	auditor     *audit.Service
+}
+
+func NewService(repo repository.ClinicalListRepository, patientRepo repository.PatientRepository, terminology *terminology.Service, auditor *audit.Service) *Service {
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		terminology: terminology,
+		auditor:     auditor,
+	}
+}
+
+// ListProblems returns the patient's problem list, active problems first
+func (s *Service) ListProblems(ctx context.Context, patientID uuid.UUID, status model.ClinicalStatus) ([]*model.Problem, error) {
+	problems, err := s.repo.ListProblems(ctx, patientID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), uuid.Nil, "read", "problem_list", patientID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"count": len(problems)},
+	})
+
+	return problems, nil
+}
+
+func (s *Service) GetProblem(ctx context.Context, patientID, problemID uuid.UUID) (*model.Problem, error) {
+	problem, err := s.repo.GetProblem(ctx, problemID)
+	if err != nil || problem.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	return problem, nil
+}
+
+// AddProblem puts a coded problem on the patient's list. A code can be on
+// the list once; an entry that was resolved or refuted is updated instead.
+func (s *Service) AddProblem(ctx context.Context, patientID uuid.UUID, userType string, req *model.CreateProblemRequest) (*model.Problem, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+	code, err := s.validateCode(ctx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	problem := &model.Problem{
+		PatientID:      patientID,
+		OrganizationID: patient.OrganizationID,
+		CodeSystem:     code.System,
+		Code:           code.Code,
+		Display:        code.Display,
+		Status:         statusOrActive(req.Status),
+		Severity:       req.Severity,
+		OnsetDate:      req.OnsetDate,
+		Note:           req.Note,
+		RecordedBy:     s.getCurrentUserID(ctx),
+	}
+	if problem.Status == model.ClinicalStatusResolved {
+		problem.ResolvedDate = today()
+	}
+
+	if err := s.repo.CreateProblem(ctx, problem); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAlreadyListed
+		}
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, problem.RecordedBy, problem.OrganizationID, "create", "problem", problem.ID, &audit.LogOptions{
+		Changes: problem,
+	})
+
+	return problem, nil
+}
+
+// UpdateProblem changes a problem's status, severity, dates or note.
+// Resolving a problem dates it today unless a resolved date is given;
+// reactivating it clears the date.
+func (s *Service) UpdateProblem(ctx context.Context, patientID, problemID uuid.UUID, userType string, req *model.UpdateProblemRequest) (*model.Problem, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	problem, err := s.GetProblem(ctx, patientID, problemID)
+	if err != nil {
+		return nil, err
+	}
+	before := *problem
+
+	if req.Status == nil && req.Severity == nil && req.OnsetDate == nil && req.ResolvedDate == nil && req.Note == nil {
+		return nil, ErrNoChanges
+	}
+	if req.Status != nil {
+		problem.Status = *req.Status
+	}
+	if req.Severity != nil {
+		problem.Severity = req.Severity
+	}
+	if req.OnsetDate != nil {
+		problem.OnsetDate = req.OnsetDate
+	}
+	if req.Note != nil {
+		problem.Note = req.Note
+	}
+	problem.ResolvedDate, err = resolvedDate(problem.Status, before.Status, problem.ResolvedDate, req.ResolvedDate)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDates(problem.OnsetDate, problem.ResolvedDate); err != nil {
+		return nil, err
+	}
+
+	problem.Version = req.Version
+	if err := s.repo.UpdateProblem(ctx, problem); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), problem.OrganizationID, "update", "problem", problem.ID, &audit.LogOptions{
+		Changes: map[string]interface{}{"before": before, "after": problem},
+	})
+
+	return problem, nil
+}
+
+// ListAllergies returns the patient's allergy list, active allergies first
+func (s *Service) ListAllergies(ctx context.Context, patientID uuid.UUID, status model.ClinicalStatus) ([]*model.Allergy, error) {
+	allergies, err := s.repo.ListAllergies(ctx, patientID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), uuid.Nil, "read", "allergy_list", patientID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"count": len(allergies)},
+	})
+
+	return allergies, nil
+}
+
+func (s *Service) GetAllergy(ctx context.Context, patientID, allergyID uuid.UUID) (*model.Allergy, error) {
+	allergy, err := s.repo.GetAllergy(ctx, allergyID)
+	if err != nil || allergy.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	return allergy, nil
+}
+
+// AddAllergy puts an allergy on the patient's list. Substances are coded in
+// the loaded terminologies, or in RxNorm for drug allergies.
+func (s *Service) AddAllergy(ctx context.Context, patientID uuid.UUID, userType string, req *model.CreateAllergyRequest) (*model.Allergy, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+	code, err := s.validateSubstance(ctx, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := s.validateReactions(ctx, req.Reactions)
+	if err != nil {
+		return nil, err
+	}
+
+	allergy := &model.Allergy{
+		PatientID:      patientID,
+		OrganizationID: patient.OrganizationID,
+		CodeSystem:     code.System,
+		Code:           code.Code,
+		Display:        code.Display,
+		Category:       req.Category,
+		Status:         statusOrActive(req.Status),
+		Severity:       req.Severity,
+		Reactions:      reactions,
+		OnsetDate:      req.OnsetDate,
+		Note:           req.Note,
+		RecordedBy:     s.getCurrentUserID(ctx),
+	}
+	if allergy.Category == nil && code.System == model.DrugSystemRxNorm {
+		category := model.AllergyCategoryMedication
+		allergy.Category = &category
+	}
+	if allergy.Status == model.ClinicalStatusResolved {
+		allergy.ResolvedDate = today()
+	}
+
+	if err := s.repo.CreateAllergy(ctx, allergy); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAlreadyListed
+		}
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, allergy.RecordedBy, allergy.OrganizationID, "create", "allergy", allergy.ID, &audit.LogOptions{
+		Changes: allergy,
+	})
+
+	return allergy, nil
+}
+
+// UpdateAllergy changes an allergy's category, status, severity, reactions,
+// dates or note. Resolved dates follow the status as for problems.
+func (s *Service) UpdateAllergy(ctx context.Context, patientID, allergyID uuid.UUID, userType string, req *model.UpdateAllergyRequest) (*model.Allergy, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	allergy, err := s.GetAllergy(ctx, patientID, allergyID)
+	if err != nil {
+		return nil, err
+	}
+	before := *allergy
+
+	if req.Category == nil && req.Status == nil && req.Severity == nil && req.Reactions == nil &&
+		req.OnsetDate == nil && req.ResolvedDate == nil && req.Note == nil {
+		return nil, ErrNoChanges
+	}
+	if req.Category != nil {
+		allergy.Category = req.Category
+	}
+	if req.Status != nil {
+		allergy.Status = *req.Status
+	}
+	if req.Severity != nil {
+		allergy.Severity = req.Severity
+	}
+	if req.Reactions != nil {
+		if allergy.Reactions, err = s.validateReactions(ctx, *req.Reactions); err != nil {
+			return nil, err
+		}
+	}
+	if req.OnsetDate != nil {
+		allergy.OnsetDate = req.OnsetDate
+	}
+	if req.Note != nil {
+		allergy.Note = req.Note
+	}
+	allergy.ResolvedDate, err = resolvedDate(allergy.Status, before.Status, allergy.ResolvedDate, req.ResolvedDate)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDates(allergy.OnsetDate, allergy.ResolvedDate); err != nil {
+		return nil, err
+	}
+
+	allergy.Version = req.Version
+	if err := s.repo.UpdateAllergy(ctx, allergy); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), allergy.OrganizationID, "update", "allergy", allergy.ID, &audit.LogOptions{
+		Changes: map[string]interface{}{"before": before, "after": allergy},
+	})
+
+	return allergy, nil
+}
+
+// validateCode checks a problem code against the loaded terminologies and
+// returns it normalized
+func (s *Service) validateCode(ctx context.Context, code model.CodedEntry) (model.CodedEntry, error) {
+	normalized, err := s.terminology.Validate(ctx, []model.CodedEntry{code})
+	if err != nil {
+		return model.CodedEntry{}, err
+	}
+	return normalized[0], nil
+}
+
+// validateSubstance accepts RxNorm drug codes as given, since RxNorm is not
+// loaded as a terminology; other substances are validated like problems
+func (s *Service) validateSubstance(ctx context.Context, code model.CodedEntry) (model.CodedEntry, error) {
+	if code.System == model.DrugSystemRxNorm {
+		code.Code = strings.TrimSpace(code.Code)
+		if code.Code == "" {
+			return model.CodedEntry{}, &terminology.ValidationError{Invalid: []model.InvalidCode{
+				{System: code.System, Code: code.Code, Reason: "code is required"},
+			}}
+		}
+		code.Display = strings.TrimSpace(code.Display)
+		return code, nil
+	}
+	return s.validateCode(ctx, code)
+}
+
+// validateReactions validates coded manifestations; a manifestation may
+// also be described in its display alone
+func (s *Service) validateReactions(ctx context.Context, reactions []model.AllergyReaction) ([]model.AllergyReaction, error) {
+	validated := make([]model.AllergyReaction, 0, len(reactions))
+	for _, r := range reactions {
+		switch {
+		case strings.TrimSpace(r.Manifestation.Code) != "":
+			code, err := s.validateCode(ctx, r.Manifestation)
+			if err != nil {
+				return nil, err
+			}
+			r.Manifestation = code
+		case strings.TrimSpace(r.Manifestation.Display) != "":
+			r.Manifestation = model.CodedEntry{Display: strings.TrimSpace(r.Manifestation.Display)}
+		default:
+			return nil, ErrInvalidReaction
+		}
+		if r.Severity != "" && r.Severity != model.SeverityMild && r.Severity != model.SeverityModerate && r.Severity != model.SeveritySevere {
+			return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidReaction, r.Severity)
+		}
+		validated = append(validated, r)
+	}
+	return validated, nil
+}
+
+// resolvedDate works out the resolved date after an update: only resolved
+// entries have one, and one that was just resolved defaults to today
+func resolvedDate(status, previous model.ClinicalStatus, current, requested *time.Time) (*time.Time, error) {
+	if status != model.ClinicalStatusResolved {
+		if requested != nil {
+			return nil, ErrResolvedNotAllowed
+		}
+		return nil, nil
+	}
+	if requested != nil {
+		return requested, nil
+	}
+	if previous != model.ClinicalStatusResolved || current == nil {
+		return today(), nil
+	}
+	return current, nil
+}
+
+func checkDates(onset, resolved *time.Time) error {
+	if onset != nil && resolved != nil && resolved.Before(*onset) {
+		return ErrInvalidDates
+	}
+	return nil
+}
+
+func statusOrActive(status model.ClinicalStatus) model.ClinicalStatus {
+	if status == "" {
+		return model.ClinicalStatusActive
+	}
+	return status
+}
+
+func today() *time.Time {
+	t := time.Now().UTC().Truncate(24 * time.Hour)
+	return &t
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
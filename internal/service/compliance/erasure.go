@@ -0,0 +1,319 @@
+package compliance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+// erasureOrder is the order decisions are listed in the plan and certificate
+var erasureOrder = []model.DataCategory{
+	model.DataCategoryAppointments,
+	model.DataCategoryMedicalRecords,
+	model.DataCategoryNotifications,
+	model.DataCategoryOutboxEvents,
+	model.DataCategoryInsurance,
+	model.DataCategoryConsents,
+	model.DataCategoryRelationships,
+	model.DataCategoryIdentifiers,
+	model.DataCategoryPatient,
+}
+
+// erase carries out a right-to-erasure request and writes the certificate of
+// erasure as the request artifact.
+func (s *Service) erase(ctx context.Context, req *model.ComplianceRequest) error {
+	patient, err := s.patientRepo.Get(ctx, req.PatientID)
+	if err != nil {
+		return fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	// Without a region we cannot tell how long clinical data must be kept, so
+	// the plan falls back to retaining it.
+	region, err := s.regionRepo.GetRegion(ctx, req.RegionCode)
+	if err != nil {
+		region = nil
+	}
+
+	holds, err := s.legalHoldRepo.ListByPatient(ctx, req.PatientID, true)
+	if err != nil {
+		return fmt.Errorf("failed to list legal holds: %w", err)
+	}
+
+	replacements, err := anonymizePatient(patient)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	plan := &model.ErasurePlan{
+		RequestID:    req.ID,
+		PatientID:    req.PatientID,
+		Decisions:    planErasure(region, holds, now),
+		Replacements: replacements,
+	}
+
+	// Blobs cannot be removed inside the erasure transaction, so the stored
+	// documents are noted first and those whose rows went are purged after
+	documents, err := s.documentRepo.ListStored(ctx, req.PatientID)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	outcomes, err := s.erasureRepo.Execute(ctx, plan)
+	if err != nil {
+		return fmt.Errorf("failed to execute erasure: %w", err)
+	}
+
+	s.purgeExports(ctx, req.PatientID)
+	s.purgeDocuments(ctx, req.PatientID, documents)
+
+	cert := &model.ErasureCertificate{
+		RequestID:      req.ID,
+		PatientID:      req.PatientID,
+		OrganizationID: req.OrganizationID,
+		RegionCode:     req.RegionCode,
+		RetentionDays:  -1,
+		LegalHolds:     make([]uuid.UUID, 0, len(holds)),
+		Decisions:      plan.Decisions,
+		Outcomes:       outcomes,
+		RequestedAt:    req.CreatedAt,
+		IssuedAt:       time.Now().UTC(),
+	}
+	if region != nil {
+		cert.RetentionDays = region.DataRetentionDays
+	}
+	for _, h := range holds {
+		cert.LegalHolds = append(cert.LegalHolds, h.ID)
+	}
+
+	if err := s.writeCertificate(req, cert); err != nil {
+		return err
+	}
+
+	// The audit log outlives the artifact, so it keeps a full copy
+	s.auditor.Log(ctx, uuid.Nil, req.OrganizationID, "erase", "patient", req.PatientID, &audit.LogOptions{
+		Changes: cert,
+	})
+
+	return nil
+}
+
+// planErasure decides, per data category, whether the patient's data is
+// deleted, anonymized or retained. Active legal holds win over everything;
+// otherwise clinical data inside the region's retention period is kept.
+func planErasure(region *model.Region, holds []*model.LegalHold, now time.Time) []model.ErasureDecision {
+	retentionDays := -1
+	if region != nil {
+		retentionDays = region.DataRetentionDays
+	}
+
+	var cutoff *time.Time
+	if retentionDays > 0 {
+		c := now.AddDate(0, 0, -retentionDays)
+		cutoff = &c
+	}
+
+	decisions := make([]model.ErasureDecision, 0, len(erasureOrder))
+	for _, category := range erasureOrder {
+		if hold := coveringHold(holds, category); hold != nil {
+			decisions = append(decisions, model.ErasureDecision{
+				Category: category,
+				Action:   model.ErasureActionRetain,
+				Reason:   fmt.Sprintf("legal hold %s: %s", hold.ID, hold.Reason),
+			})
+			continue
+		}
+
+		d := model.ErasureDecision{Category: category}
+		switch category {
+		case model.DataCategoryPatient:
+			d.Action = model.ErasureActionAnonymize
+			d.Reason = "patient row kept as an anonymized tombstone for referential integrity"
+
+		case model.DataCategoryAppointments:
+			switch {
+			case retentionDays < 0:
+				d.Action = model.ErasureActionAnonymize
+				d.Reason = "region retention period unknown"
+			case cutoff == nil:
+				d.Action = model.ErasureActionDelete
+				d.Reason = "no retention period in region"
+			default:
+				d.Action = model.ErasureActionDelete
+				d.Fallback = model.ErasureActionAnonymize
+				d.Cutoff = cutoff
+				d.Reason = fmt.Sprintf("appointments within the %d day retention period are anonymized", retentionDays)
+			}
+
+		case model.DataCategoryMedicalRecords:
+			switch {
+			case retentionDays < 0:
+				d.Action = model.ErasureActionRetain
+				d.Reason = "region retention period unknown"
+			case cutoff == nil:
+				d.Action = model.ErasureActionDelete
+				d.Reason = "no retention period in region"
+			default:
+				d.Action = model.ErasureActionDelete
+				d.Fallback = model.ErasureActionRetain
+				d.Cutoff = cutoff
+				d.Reason = fmt.Sprintf("records within the %d day retention period are retained", retentionDays)
+			}
+
+		case model.DataCategoryNotifications:
+			d.Action = model.ErasureActionDelete
+			d.Reason = "no retention obligation"
+
+		case model.DataCategoryOutboxEvents:
+			d.Action = model.ErasureActionAnonymize
+			d.Reason = "event history kept with payload redacted"
+
+		case model.DataCategoryInsurance:
+			d.Action = model.ErasureActionAnonymize
+			d.Reason = "coverage kept for billing history with member identifiers removed"
+
+		case model.DataCategoryRelationships:
+			d.Action = model.ErasureActionDelete
+			d.Reason = "proxy access ends with the patient record"
+
+		case model.DataCategoryIdentifiers:
+			d.Action = model.ErasureActionDelete
+			d.Reason = "external and national identifiers link the tombstone back to the patient"
+
+		case model.DataCategoryConsents:
+			d.Action = model.ErasureActionRetain
+			d.Reason = "evidence of lawful basis for past processing"
+		}
+		decisions = append(decisions, d)
+	}
+
+	return decisions
+}
+
+func coveringHold(holds []*model.LegalHold, category model.DataCategory) *model.LegalHold {
+	for _, h := range holds {
+		if h.Covers(category) {
+			return h
+		}
+	}
+	return nil
+}
+
+// anonymizePatient replaces identifying patient fields with tokens keyed by a
+// random salt that is thrown away, so the tokens cannot be reversed or linked
+// back to the original values.
+func anonymizePatient(patient *model.Patient) (map[string]string, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate anonymization salt: %w", err)
+	}
+
+	token := func(value string) string {
+		mac := hmac.New(sha256.New, salt)
+		mac.Write([]byte(value))
+		return "anon_" + hex.EncodeToString(mac.Sum(nil))[:16]
+	}
+
+	// The stored date of birth may be encrypted, so the generalised year is
+	// taken from the decrypted patient rather than computed in SQL
+	dateOfBirth := ""
+	if !patient.DateOfBirth.IsZero() {
+		dateOfBirth = time.Date(patient.DateOfBirth.Year(), time.January, 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	}
+
+	return map[string]string{
+		"first_name":    token(patient.FirstName),
+		"last_name":     token(patient.LastName),
+		"email":         token(patient.Email) + "@erased.invalid",
+		"phone":         token(patient.Phone),
+		"address":       token(patient.Address),
+		"date_of_birth": dateOfBirth,
+	}, nil
+}
+
+// purgeExports removes subject access archives generated before the erasure;
+// they would otherwise keep the patient's data on disk.
+func (s *Service) purgeExports(ctx context.Context, patientID uuid.UUID) {
+	reqs, err := s.repo.ListByPatient(ctx, patientID)
+	if err != nil {
+		log.Error().Err(err).Str("patient_id", patientID.String()).Msg("failed to list exports to purge")
+		return
+	}
+
+	for _, r := range reqs {
+		if r.Type != model.ComplianceRequestTypeAccess || r.ArtifactPath == nil {
+			continue
+		}
+		if err := os.Remove(*r.ArtifactPath); err != nil && !os.IsNotExist(err) {
+			log.Error().Err(err).Str("request_id", r.ID.String()).Msg("failed to remove export")
+			continue
+		}
+		now := time.Now()
+		r.ArtifactPath = nil
+		r.ArtifactExpires = &now
+		if err := s.repo.Update(ctx, r); err != nil {
+			log.Error().Err(err).Str("request_id", r.ID.String()).Msg("failed to update purged export")
+		}
+	}
+}
+
+// purgeDocuments removes the blobs of the documents whose rows the erasure
+// deleted; retained documents keep theirs.
+func (s *Service) purgeDocuments(ctx context.Context, patientID uuid.UUID, before []*model.Document) {
+	remaining, err := s.documentRepo.ListStored(ctx, patientID)
+	if err != nil {
+		log.Error().Err(err).Str("patient_id", patientID.String()).Msg("failed to list documents to purge")
+		return
+	}
+
+	kept := make(map[uuid.UUID]bool, len(remaining))
+	for _, doc := range remaining {
+		kept[doc.ID] = true
+	}
+	for _, doc := range before {
+		if kept[doc.ID] {
+			continue
+		}
+		if err := s.documentStore.Delete(ctx, doc.StorageKey); err != nil {
+			log.Error().Err(err).Str("document_id", doc.ID.String()).Msg("failed to remove document")
+		}
+	}
+}
+
+func (s *Service) writeCertificate(req *model.ComplianceRequest, cert *model.ErasureCertificate) error {
+	if err := os.MkdirAll(s.config.ArtifactDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(cert, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode certificate: %w", err)
+	}
+
+	path := filepath.Join(s.config.ArtifactDir, req.ID.String()+".json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	expiresAt := time.Now().Add(s.config.ExportTTL)
+	req.ArtifactPath = &path
+	req.ArtifactChecksum = &checksum
+	req.ArtifactExpires = &expiresAt
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package compliance
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+// buildExport gathers everything held about the patient and writes it to a
+// zip archive containing a machine-readable data.json and a human-readable
+// summary.txt.
+func (s *Service) buildExport(ctx context.Context, req *model.ComplianceRequest) error {
+	export, err := s.collect(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.config.ArtifactDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	path := filepath.Join(s.config.ArtifactDir, req.ID.String()+".zip")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if err := writeArchive(io.MultiWriter(f, hash), export); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	expiresAt := time.Now().Add(s.config.ExportTTL)
+	req.ArtifactPath = &path
+	req.ArtifactChecksum = &checksum
+	req.ArtifactExpires = &expiresAt
+
+	return nil
+}
+
+func (s *Service) collect(ctx context.Context, req *model.ComplianceRequest) (*model.PatientDataExport, error) {
+	patient, err := s.patientRepo.Get(ctx, req.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	appointments, err := s.appointmentRepo.List(ctx, &model.AppointmentFilters{PatientID: req.PatientID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list appointments: %w", err)
+	}
+
+	// The export is the patient's copy of their record set, so it is read
+	// without care team restrictions
+	records, err := s.medicalSvc.ListMedicalRecords(ctx, req.PatientID, nil, &model.RecordFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list medical records: %w", err)
+	}
+
+	notifications, err := s.notificationRepo.ListByPatient(ctx, req.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	consents, err := s.consentRepo.ListByPatient(ctx, req.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consents: %w", err)
+	}
+
+	// The access log covers the patient and every record or appointment
+	// that belongs to them.
+	entityIDs := []uuid.UUID{req.PatientID}
+	for _, a := range appointments {
+		entityIDs = append(entityIDs, a.ID)
+	}
+	for _, r := range records {
+		entityIDs = append(entityIDs, r.ID)
+	}
+
+	accessLog, err := s.auditRepo.ListByEntityIDs(ctx, entityIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access log: %w", err)
+	}
+
+	return &model.PatientDataExport{
+		RequestID:      req.ID,
+		GeneratedAt:    time.Now().UTC(),
+		Patient:        patient,
+		Appointments:   appointments,
+		MedicalRecords: records,
+		Notifications:  notifications,
+		Consents:       consents,
+		AccessLog:      accessLog,
+	}, nil
+}
+
+func writeArchive(w io.Writer, export *model.PatientDataExport) error {
+	zw := zip.NewWriter(w)
+
+	data, err := zw.Create("data.json")
+	if err != nil {
+		return fmt.Errorf("failed to add data.json: %w", err)
+	}
+	enc := json.NewEncoder(data)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+
+	summary, err := zw.Create("summary.txt")
+	if err != nil {
+		return fmt.Errorf("failed to add summary.txt: %w", err)
+	}
+	if _, err := io.WriteString(summary, renderSummary(export)); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	return nil
+}
+
+func renderSummary(export *model.PatientDataExport) string {
+	var b strings.Builder
+	p := export.Patient
+
+	fmt.Fprintf(&b, "Personal data export\n")
+	fmt.Fprintf(&b, "Request:   %s\n", export.RequestID)
+	fmt.Fprintf(&b, "Generated: %s\n\n", export.GeneratedAt.Format(time.RFC1123))
+
+	fmt.Fprintf(&b, "Patient\n")
+	fmt.Fprintf(&b, "  Name:          %s %s\n", p.FirstName, p.LastName)
+	fmt.Fprintf(&b, "  Email:         %s\n", p.Email)
+	fmt.Fprintf(&b, "  Date of birth: %s\n\n", p.DateOfBirth.Format("2006-01-02"))
+
+	fmt.Fprintf(&b, "Appointments (%d)\n", len(export.Appointments))
+	for _, a := range export.Appointments {
+		fmt.Fprintf(&b, "  %s  %s\n", a.StartTime.Format("2006-01-02 15:04"), a.Status)
+	}
+
+	fmt.Fprintf(&b, "\nMedical records (%d)\n", len(export.MedicalRecords))
+	for _, r := range export.MedicalRecords {
+		fmt.Fprintf(&b, "  %s  %s\n", r.CreatedAt.Format("2006-01-02"), r.Type)
+	}
+
+	fmt.Fprintf(&b, "\nNotifications (%d)\n", len(export.Notifications))
+	for _, n := range export.Notifications {
+		fmt.Fprintf(&b, "  %s  %s  %s\n", n.CreatedAt.Format("2006-01-02"), n.Channel, n.Subject)
+	}
+
+	fmt.Fprintf(&b, "\nConsents (%d)\n", len(export.Consents))
+	for _, c := range export.Consents {
+		fmt.Fprintf(&b, "  %s v%s  %s\n", c.Type, c.Version, c.Status)
+	}
+
+	fmt.Fprintf(&b, "\nAccess log (%d entries)\n", len(export.AccessLog))
+	for _, l := range export.AccessLog {
+		fmt.Fprintf(&b, "  %s  %s %s\n", l.CreatedAt.Format(time.RFC3339), l.Action, l.EntityType)
+	}
+
+	fmt.Fprintf(&b, "\nThe complete data set is in data.json.\n")
+	return b.String()
+}
@@ -0,0 +1,405 @@
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/pkg/security"
+	"github.com/jwalitptl/admin-api/pkg/storage"
+)
+
+const (
+	defaultLinkTTL   = 15 * time.Minute
+	defaultExportTTL = 7 * 24 * time.Hour
+
+	exportDownloadPath = "/api/v1/compliance/exports/%s/download"
+)
+
+type Config struct {
+	ArtifactDir string
+	BaseURL     string
+	LinkTTL     time.Duration
+	ExportTTL   time.Duration
+}
+
+type Service struct {
+	repo             repository.ComplianceRepository
+	consentRepo      repository.ConsentRepository
+	patientRepo      repository.PatientRepository
+	appointmentRepo  repository.AppointmentRepository
+	notificationRepo repository.NotificationRepository
+	auditRepo        repository.AuditRepository
+	regionRepo       repository.RegionRepository
+	legalHoldRepo    repository.LegalHoldRepository
+	erasureRepo      repository.ErasureRepository
+	documentRepo     repository.DocumentRepository
+	documentStore    storage.Store
+	medicalSvc       *medical.Service
+	signer           *security.URLSigner
+	auditor          *audit.Service
+	config           Config
+}
+
+func NewService(
+	repo repository.ComplianceRepository,
+	consentRepo repository.ConsentRepository,
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	notificationRepo repository.NotificationRepository,
+	auditRepo repository.AuditRepository,
+	regionRepo repository.RegionRepository,
+	legalHoldRepo repository.LegalHoldRepository,
+	erasureRepo repository.ErasureRepository,
+	documentRepo repository.DocumentRepository,
+	documentStore storage.Store,
+	medicalSvc *medical.Service,
+	signer *security.URLSigner,
+	auditor *audit.Service,
+	config Config,
+) *Service {
+	if config.LinkTTL <= 0 {
+		config.LinkTTL = defaultLinkTTL
+	}
+	if config.ExportTTL <= 0 {
+		config.ExportTTL = defaultExportTTL
+	}
+	if config.ArtifactDir == "" {
+		config.ArtifactDir = filepath.Join(os.TempDir(), "compliance")
+	}
+
+	return &Service{
+		repo:             repo,
+		consentRepo:      consentRepo,
+		patientRepo:      patientRepo,
+		appointmentRepo:  appointmentRepo,
+		notificationRepo: notificationRepo,
+		auditRepo:        auditRepo,
+		regionRepo:       regionRepo,
+		legalHoldRepo:    legalHoldRepo,
+		erasureRepo:      erasureRepo,
+		documentRepo:     documentRepo,
+		documentStore:    documentStore,
+		medicalSvc:       medicalSvc,
+		signer:           signer,
+		auditor:          auditor,
+		config:           config,
+	}
+}
+
+// RequestExport registers a subject access request. The archive itself is
+// assembled later by the compliance worker.
+func (s *Service) RequestExport(ctx context.Context, patientID uuid.UUID) (*model.ComplianceRequest, error) {
+	return s.createRequest(ctx, patientID, model.ComplianceRequestTypeAccess)
+}
+
+// RequestErasure registers a right-to-erasure request. What is deleted,
+// anonymized or retained is decided when the worker picks it up, so legal
+// holds placed in the meantime are honoured.
+func (s *Service) RequestErasure(ctx context.Context, patientID uuid.UUID) (*model.ComplianceRequest, error) {
+	return s.createRequest(ctx, patientID, model.ComplianceRequestTypeErasure)
+}
+
+func (s *Service) createRequest(ctx context.Context, patientID uuid.UUID, reqType model.ComplianceRequestType) (*model.ComplianceRequest, error) {
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	req := &model.ComplianceRequest{
+		OrganizationID: patient.OrganizationID,
+		PatientID:      patient.ID,
+		Type:           reqType,
+		Status:         model.ComplianceRequestStatusPending,
+		RequestedBy:    s.getCurrentUserID(ctx),
+	}
+
+	if err := s.repo.Create(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", reqType, err)
+	}
+
+	s.auditor.Log(ctx, req.RequestedBy, req.OrganizationID, "create", "compliance_request", req.ID, &audit.LogOptions{
+		Changes: req,
+	})
+
+	return req, nil
+}
+
+func (s *Service) GetRequest(ctx context.Context, id uuid.UUID) (*model.ComplianceRequest, error) {
+	req, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get compliance request: %w", err)
+	}
+	return req, nil
+}
+
+func (s *Service) ListRequests(ctx context.Context, patientID uuid.UUID) ([]*model.ComplianceRequest, error) {
+	reqs, err := s.repo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list compliance requests: %w", err)
+	}
+	return reqs, nil
+}
+
+// ProcessPending claims up to limit queued requests and runs them. Failures are
+// recorded on the request rather than returned so one bad request does not
+// block the rest of the batch.
+func (s *Service) ProcessPending(ctx context.Context, limit int) (int, error) {
+	reqs, err := s.repo.ClaimPending(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, req := range reqs {
+		s.process(ctx, req)
+	}
+
+	return len(reqs), nil
+}
+
+func (s *Service) process(ctx context.Context, req *model.ComplianceRequest) {
+	// Repositories scope some lookups by region, which the worker has no
+	// request context for.
+	ctx = context.WithValue(ctx, "region_code", req.RegionCode)
+
+	var err error
+	switch req.Type {
+	case model.ComplianceRequestTypeAccess:
+		err = s.buildExport(ctx, req)
+	case model.ComplianceRequestTypeErasure:
+		err = s.erase(ctx, req)
+	default:
+		err = fmt.Errorf("unsupported request type: %s", req.Type)
+	}
+
+	now := time.Now()
+	if err != nil {
+		msg := err.Error()
+		req.Status = model.ComplianceRequestStatusFailed
+		req.Error = &msg
+	} else {
+		req.Status = model.ComplianceRequestStatusCompleted
+		req.Error = nil
+		req.CompletedAt = &now
+	}
+
+	if updateErr := s.repo.Update(ctx, req); updateErr != nil {
+		log.Error().Err(updateErr).Str("request_id", req.ID.String()).Msg("failed to update compliance request")
+		return
+	}
+
+	s.auditor.Log(ctx, uuid.Nil, req.OrganizationID, string(req.Status), "compliance_request", req.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"type":       req.Type,
+			"patient_id": req.PatientID,
+			"error":      req.Error,
+		},
+	})
+}
+
+// DownloadURL returns a signed, expiring link to the artifact of a completed
+// request.
+func (s *Service) DownloadURL(ctx context.Context, req *model.ComplianceRequest) (string, time.Time, error) {
+	if req.Status != model.ComplianceRequestStatusCompleted || req.ArtifactPath == nil {
+		return "", time.Time{}, fmt.Errorf("compliance request has no artifact yet")
+	}
+	if req.ArtifactExpires != nil && time.Now().After(*req.ArtifactExpires) {
+		return "", time.Time{}, fmt.Errorf("compliance artifact has expired")
+	}
+
+	ttl := s.config.LinkTTL
+	if req.ArtifactExpires != nil && time.Until(*req.ArtifactExpires) < ttl {
+		ttl = time.Until(*req.ArtifactExpires)
+	}
+
+	return s.signer.SignURL(s.config.BaseURL+fmt.Sprintf(exportDownloadPath, req.ID), ttl)
+}
+
+// OpenArtifact verifies a signed download link and opens the artifact it
+// points at. The caller must close the returned file.
+func (s *Service) OpenArtifact(ctx context.Context, id uuid.UUID, path string, query url.Values) (*os.File, *model.ComplianceRequest, error) {
+	if err := s.signer.VerifyURL(path, query); err != nil {
+		return nil, nil, err
+	}
+
+	req, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get compliance request: %w", err)
+	}
+	if req.ArtifactPath == nil {
+		return nil, nil, fmt.Errorf("compliance request has no artifact")
+	}
+	if req.ArtifactExpires != nil && time.Now().After(*req.ArtifactExpires) {
+		return nil, nil, security.ErrSignatureExpired
+	}
+
+	f, err := os.Open(*req.ArtifactPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+
+	s.auditor.Log(ctx, uuid.Nil, req.OrganizationID, "download", "compliance_request", req.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"patient_id": req.PatientID,
+			"type":       req.Type,
+		},
+	})
+
+	return f, req, nil
+}
+
+func (s *Service) RecordConsent(ctx context.Context, patientID uuid.UUID, req *model.RecordConsentRequest) (*model.Consent, error) {
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	consent := &model.Consent{
+		PatientID:      patient.ID,
+		OrganizationID: patient.OrganizationID,
+		Type:           req.Type,
+		Version:        req.Version,
+		Status:         model.ConsentStatusGranted,
+		Source:         req.Source,
+		RecordedBy:     s.getCurrentUserID(ctx),
+		GrantedAt:      time.Now(),
+	}
+
+	if err := s.consentRepo.Create(ctx, consent); err != nil {
+		return nil, fmt.Errorf("failed to record consent: %w", err)
+	}
+
+	s.auditor.Log(ctx, consent.RecordedBy, consent.OrganizationID, "grant", "consent", consent.ID, &audit.LogOptions{
+		Changes: consent,
+	})
+
+	return consent, nil
+}
+
+func (s *Service) WithdrawConsent(ctx context.Context, patientID, consentID uuid.UUID) (*model.Consent, error) {
+	consent, err := s.consentRepo.Get(ctx, consentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consent: %w", err)
+	}
+	if consent.PatientID != patientID {
+		return nil, fmt.Errorf("consent does not belong to patient")
+	}
+	if consent.Status == model.ConsentStatusWithdrawn {
+		return nil, fmt.Errorf("consent is already withdrawn")
+	}
+
+	now := time.Now()
+	consent.Status = model.ConsentStatusWithdrawn
+	consent.WithdrawnAt = &now
+
+	if err := s.consentRepo.Update(ctx, consent); err != nil {
+		return nil, fmt.Errorf("failed to withdraw consent: %w", err)
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), consent.OrganizationID, "withdraw", "consent", consent.ID, &audit.LogOptions{
+		Changes: consent,
+	})
+
+	return consent, nil
+}
+
+func (s *Service) ListConsents(ctx context.Context, patientID uuid.UUID) ([]*model.Consent, error) {
+	consents, err := s.consentRepo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consents: %w", err)
+	}
+	return consents, nil
+}
+
+func (s *Service) PlaceLegalHold(ctx context.Context, patientID uuid.UUID, req *model.PlaceLegalHoldRequest) (*model.LegalHold, error) {
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	for _, c := range req.Categories {
+		if !isDataCategory(model.DataCategory(c)) {
+			return nil, fmt.Errorf("invalid data category: %s", c)
+		}
+	}
+
+	hold := &model.LegalHold{
+		OrganizationID: patient.OrganizationID,
+		PatientID:      patient.ID,
+		Reason:         req.Reason,
+		Categories:     req.Categories,
+		PlacedBy:       s.getCurrentUserID(ctx),
+	}
+
+	if err := s.legalHoldRepo.Create(ctx, hold); err != nil {
+		return nil, fmt.Errorf("failed to place legal hold: %w", err)
+	}
+
+	s.auditor.Log(ctx, hold.PlacedBy, hold.OrganizationID, "place", "legal_hold", hold.ID, &audit.LogOptions{
+		Changes: hold,
+	})
+
+	return hold, nil
+}
+
+func (s *Service) ReleaseLegalHold(ctx context.Context, patientID, holdID uuid.UUID) (*model.LegalHold, error) {
+	hold, err := s.legalHoldRepo.Get(ctx, holdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+	if hold.PatientID != patientID {
+		return nil, fmt.Errorf("legal hold does not belong to patient")
+	}
+
+	userID := s.getCurrentUserID(ctx)
+	now := time.Now()
+	hold.ReleasedBy = &userID
+	hold.ReleasedAt = &now
+
+	if err := s.legalHoldRepo.Release(ctx, hold); err != nil {
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+
+	s.auditor.Log(ctx, userID, hold.OrganizationID, "release", "legal_hold", hold.ID, &audit.LogOptions{
+		Changes: hold,
+	})
+
+	return hold, nil
+}
+
+func (s *Service) ListLegalHolds(ctx context.Context, patientID uuid.UUID, activeOnly bool) ([]*model.LegalHold, error) {
+	holds, err := s.legalHoldRepo.ListByPatient(ctx, patientID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	return holds, nil
+}
+
+func isDataCategory(c model.DataCategory) bool {
+	for _, known := range erasureOrder {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
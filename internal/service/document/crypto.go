@@ -0,0 +1,103 @@
+package document
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/jwalitptl/admin-api/pkg/security"
+)
+
+// Encrypted documents are stored as a sequence of independently sealed chunks,
+// each prefixed with its ciphertext length as a big-endian uint32. Chunking
+// lets uploads and downloads stream without holding the whole file in memory.
+const chunkSize = 64 * 1024
+
+type encryptingReader struct {
+	src       io.Reader
+	encryptor security.Encryptor
+	buf       bytes.Buffer
+	plain     []byte
+	eof       bool
+}
+
+func newEncryptingReader(src io.Reader, encryptor security.Encryptor) io.Reader {
+	return &encryptingReader{
+		src:       src,
+		encryptor: encryptor,
+		plain:     make([]byte, chunkSize),
+	}
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.src, r.plain)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			r.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			continue
+		}
+
+		sealed, err := r.encryptor.Encrypt(r.plain[:n])
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt chunk: %w", err)
+		}
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+		r.buf.Write(size[:])
+		r.buf.Write(sealed)
+	}
+
+	return r.buf.Read(p)
+}
+
+type decryptingReader struct {
+	src       io.ReadCloser
+	encryptor security.Encryptor
+	buf       bytes.Buffer
+}
+
+func newDecryptingReader(src io.ReadCloser, encryptor security.Encryptor) io.ReadCloser {
+	return &decryptingReader{src: src, encryptor: encryptor}
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		var size [4]byte
+		if _, err := io.ReadFull(r.src, size[:]); err != nil {
+			if err == io.EOF {
+				return 0, io.EOF
+			}
+			return 0, fmt.Errorf("failed to read chunk header: %w", err)
+		}
+
+		n := binary.BigEndian.Uint32(size[:])
+		if n > 2*chunkSize {
+			return 0, fmt.Errorf("chunk too large: %d bytes", n)
+		}
+		sealed := make([]byte, n)
+		if _, err := io.ReadFull(r.src, sealed); err != nil {
+			return 0, fmt.Errorf("failed to read chunk: %w", err)
+		}
+
+		plain, err := r.encryptor.Decrypt(sealed)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt chunk: %w", err)
+		}
+		r.buf.Write(plain)
+	}
+
+	return r.buf.Read(p)
+}
+
+func (r *decryptingReader) Close() error {
+	return r.src.Close()
+}
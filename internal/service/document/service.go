@@ -0,0 +1,375 @@
+package document
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/pkg/security"
+	"github.com/jwalitptl/admin-api/pkg/storage"
+)
+
+const (
+	defaultMaxUploadBytes = 25 << 20
+	defaultLinkTTL        = 5 * time.Minute
+
+	documentDownloadPath = "/api/v1/documents/%s/download"
+)
+
+var (
+	ErrTooLarge       = errors.New("document exceeds the maximum upload size")
+	ErrTypeNotAllowed = errors.New("document type is not allowed")
+	ErrCorrupt        = errors.New("document content does not match its checksum")
+)
+
+type Config struct {
+	BaseURL        string
+	LinkTTL        time.Duration
+	MaxUploadBytes int64
+	// AllowedTypes lists the sniffed content types accepted for upload; empty
+	// allows everything.
+	AllowedTypes []string
+}
+
+type Service struct {
+	repo        repository.DocumentRepository
+	patientRepo repository.PatientRepository
+	medicalRepo repository.MedicalRecordRepository
+	medicalSvc  *medical.Service
+	store       storage.Store
+	encryptor   security.Encryptor
+	signer      *security.URLSigner
+	auditor     *audit.Service
+	config      Config
+}
+
+func NewService(
+	repo repository.DocumentRepository,
+	patientRepo repository.PatientRepository,
+	medicalRepo repository.MedicalRecordRepository,
+	medicalSvc *medical.Service,
+	store storage.Store,
+	encryptor security.Encryptor,
+	signer *security.URLSigner,
+	auditor *audit.Service,
+	config Config,
+) *Service {
+	if config.MaxUploadBytes <= 0 {
+		config.MaxUploadBytes = defaultMaxUploadBytes
+	}
+	if config.LinkTTL <= 0 {
+		config.LinkTTL = defaultLinkTTL
+	}
+
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		medicalRepo: medicalRepo,
+		medicalSvc:  medicalSvc,
+		store:       store,
+		encryptor:   encryptor,
+		signer:      signer,
+		auditor:     auditor,
+		config:      config,
+	}
+}
+
+// MaxUploadBytes is the largest document the service accepts
+func (s *Service) MaxUploadBytes() int64 {
+	return s.config.MaxUploadBytes
+}
+
+// Upload streams the document into the blob store. The content type is sniffed
+// from the first bytes rather than trusted from the client, and the checksum
+// is taken over the plaintext before it is encrypted. When recordID is set the
+// document is also attached to that medical record, which the uploader must
+// be allowed to read.
+func (s *Service) Upload(ctx context.Context, patientID uuid.UUID, uploader *model.RecordReader, name string, body io.Reader, recordID *uuid.UUID) (*model.Document, error) {
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	var record *model.MedicalRecord
+	if recordID != nil {
+		record, err = s.medicalRepo.Get(ctx, *recordID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get medical record: %w", err)
+		}
+		if record.PatientID != patientID {
+			return nil, fmt.Errorf("medical record does not belong to patient")
+		}
+		if err := s.medicalSvc.CheckAccess(ctx, record, uploader); err != nil {
+			return nil, err
+		}
+	}
+
+	// Read one byte past the limit so an oversized upload can be detected
+	limited := bufio.NewReader(io.LimitReader(body, s.config.MaxUploadBytes+1))
+	head, err := limited.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	contentType := sniffContentType(head, name)
+	if !s.allowed(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	hasher := sha256.New()
+	counter := &countingWriter{}
+	plain := io.TeeReader(limited, io.MultiWriter(hasher, counter))
+
+	doc := &model.Document{
+		ID:              uuid.New(),
+		PatientID:       patient.ID,
+		OrganizationID:  patient.OrganizationID,
+		MedicalRecordID: recordID,
+		Name:            filepath.Base(name),
+		ContentType:     contentType,
+		Backend:         s.store.Name(),
+		UploadedBy:      uploader.UserID,
+		CreatedAt:       time.Now(),
+	}
+	doc.StorageKey = fmt.Sprintf("patients/%s/documents/%s", patient.ID, doc.ID)
+
+	if err := s.store.Put(ctx, doc.StorageKey, newEncryptingReader(plain, s.encryptor), -1); err != nil {
+		return nil, fmt.Errorf("failed to store document: %w", err)
+	}
+	if counter.n > s.config.MaxUploadBytes {
+		s.store.Delete(ctx, doc.StorageKey)
+		return nil, ErrTooLarge
+	}
+
+	doc.Size = counter.n
+	doc.Checksum = hex.EncodeToString(hasher.Sum(nil))
+
+	if err := s.repo.Create(ctx, doc); err != nil {
+		s.store.Delete(ctx, doc.StorageKey)
+		return nil, fmt.Errorf("failed to save document: %w", err)
+	}
+
+	if record != nil {
+		record.Attachments = append(record.Attachments, doc.Attachment())
+		record.UpdatedAt = time.Now()
+		rev := &model.RecordRevision{
+			AuthorID: doc.UploadedBy,
+			Reason:   fmt.Sprintf("attached document %s", doc.ID),
+		}
+		if err := s.medicalRepo.UpdateWithAudit(ctx, record, rev); err != nil {
+			return nil, fmt.Errorf("failed to attach document to record: %w", err)
+		}
+	}
+
+	s.auditor.Log(ctx, doc.UploadedBy, doc.OrganizationID, "upload", "document", doc.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"patient_id":        doc.PatientID,
+			"medical_record_id": doc.MedicalRecordID,
+			"content_type":      doc.ContentType,
+			"size":              doc.Size,
+			"checksum":          doc.Checksum,
+		},
+	})
+
+	return doc, nil
+}
+
+// Get returns the document metadata. Viewing metadata is audited like any
+// other access to the document.
+func (s *Service) Get(ctx context.Context, patientID, id uuid.UUID) (*model.Document, error) {
+	doc, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	if doc.PatientID != patientID {
+		return nil, fmt.Errorf("document does not belong to patient")
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), doc.OrganizationID, "read", "document", doc.ID, nil)
+
+	return doc, nil
+}
+
+func (s *Service) List(ctx context.Context, patientID uuid.UUID) ([]*model.Document, error) {
+	docs, err := s.repo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	return docs, nil
+}
+
+// Delete hides the document. The stored blob is kept for the retention
+// period like the medical record it may belong to.
+func (s *Service) Delete(ctx context.Context, patientID, id uuid.UUID) error {
+	doc, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get document: %w", err)
+	}
+	if doc.PatientID != patientID {
+		return fmt.Errorf("document does not belong to patient")
+	}
+
+	if err := s.repo.SoftDelete(ctx, id); err != nil {
+		return err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), doc.OrganizationID, "delete", "document", doc.ID, nil)
+
+	return nil
+}
+
+// DownloadURL returns a short-lived signed link to the document content
+func (s *Service) DownloadURL(ctx context.Context, doc *model.Document) (string, time.Time, error) {
+	link, expiresAt, err := s.signer.SignURL(s.config.BaseURL+fmt.Sprintf(documentDownloadPath, doc.ID), s.config.LinkTTL)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign download link: %w", err)
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), doc.OrganizationID, "share_link", "document", doc.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"expires_at": expiresAt,
+		},
+	})
+
+	return link, expiresAt, nil
+}
+
+// Open verifies a signed download link and returns the decrypted document
+// content. The content is checked against the upload checksum as it is read,
+// and the reader fails with ErrCorrupt before giving up the last byte of a
+// document that does not match. The caller must close the returned reader.
+func (s *Service) Open(ctx context.Context, id uuid.UUID, path string, query url.Values) (io.ReadCloser, *model.Document, error) {
+	if err := s.signer.VerifyURL(path, query); err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	// A link signed before the document was deleted must not reach the blob
+	if doc.DeletedAt != nil {
+		return nil, nil, fmt.Errorf("failed to get document: %w", sql.ErrNoRows)
+	}
+
+	blob, err := s.store.Get(ctx, doc.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open document: %w", err)
+	}
+
+	s.auditor.Log(ctx, uuid.Nil, doc.OrganizationID, "download", "document", doc.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"patient_id": doc.PatientID,
+		},
+	})
+
+	return newVerifyingReader(newDecryptingReader(blob, s.encryptor), doc), doc, nil
+}
+
+func (s *Service) allowed(contentType string) bool {
+	if len(s.config.AllowedTypes) == 0 {
+		return true
+	}
+	for _, t := range s.config.AllowedTypes {
+		if strings.EqualFold(t, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffContentType detects the type from the content. The file extension is
+// only used to refine a sniffed type into a more specific one of the same
+// family, such as text/plain into text/csv or a zip into a .docx; it can never
+// turn unrecognised binary into an allowed type.
+func sniffContentType(head []byte, name string) string {
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	if i := strings.Index(byExt, ";"); i >= 0 {
+		byExt = byExt[:i]
+	}
+
+	switch {
+	case contentType == "text/plain" && strings.HasPrefix(byExt, "text/"):
+		return byExt
+	case contentType == "application/zip" && strings.HasPrefix(byExt, "application/vnd.openxmlformats-officedocument."):
+		return byExt
+	}
+	return contentType
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// verifyingReader hashes the content as it is read. The document's size is
+// known, so the read that would complete it is held back until the checksum
+// is confirmed, and a client never receives all of a corrupt document.
+type verifyingReader struct {
+	src    io.ReadCloser
+	doc    *model.Document
+	hasher hash.Hash
+	n      int64
+}
+
+func newVerifyingReader(src io.ReadCloser, doc *model.Document) *verifyingReader {
+	return &verifyingReader{src: src, doc: doc, hasher: sha256.New()}
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	r.hasher.Write(p[:n])
+	r.n += int64(n)
+
+	switch {
+	case r.n > r.doc.Size:
+		return 0, ErrCorrupt
+	case r.n == r.doc.Size && n > 0:
+		if hex.EncodeToString(r.hasher.Sum(nil)) != r.doc.Checksum {
+			return 0, ErrCorrupt
+		}
+	case err == io.EOF && r.n < r.doc.Size:
+		return 0, ErrCorrupt
+	}
+	return n, err
+}
+
+func (r *verifyingReader) Close() error {
+	return r.src.Close()
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
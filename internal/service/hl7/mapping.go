@@ -0,0 +1,221 @@
+package hl7
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	hl7v2 "github.com/jwalitptl/admin-api/pkg/hl7"
+)
+
+// codingSystems maps HL7 table 0396 coding system names to code system URIs
+var codingSystems = map[string]string{
+	"LN":   model.CodeSystemLOINC,
+	"SCT":  model.CodeSystemSNOMED,
+	"SNM":  model.CodeSystemSNOMED,
+	"I10":  model.CodeSystemICD10CM,
+	"I10C": model.CodeSystemICD10CM,
+}
+
+// identifierTypes maps HL7 table 0203 identifier types onto ours
+var identifierTypes = map[string]model.IdentifierType{
+	"MR": model.IdentifierTypeMRN,
+	"PI": model.IdentifierTypeMRN,
+	"MB": model.IdentifierTypePayer,
+	"SS": model.IdentifierTypeNational,
+	"NI": model.IdentifierTypeNational,
+}
+
+// cx is one PID-3 patient identifier resolved to our identifier systems
+type cx struct {
+	system string
+	value  string
+	idType model.IdentifierType
+}
+
+// patientIdentifiers reads PID-3. The assigning authority (CX-4) decides the
+// system: a configured namespace mapping first, then its ISO OID. Identifiers
+// whose authority is unknown cannot be matched and are skipped.
+func (s *Service) patientIdentifiers(pid *hl7v2.Segment) []cx {
+	var ids []cx
+	for _, rep := range pid.Repetitions(3) {
+		value := strings.TrimSpace(rep.Get(1))
+		if value == "" {
+			continue
+		}
+
+		namespace, universal, universalType := rep.Sub(4, 1), rep.Sub(4, 2), rep.Sub(4, 3)
+		system := s.config.AssigningAuthorities[strings.ToLower(namespace)]
+		if system == "" && universal != "" && strings.EqualFold(universalType, "ISO") {
+			system = "urn:oid:" + universal
+		}
+		if system == "" {
+			continue
+		}
+
+		idType, ok := identifierTypes[rep.Get(5)]
+		if !ok {
+			idType = model.IdentifierTypeOther
+		}
+		ids = append(ids, cx{system: system, value: value, idType: idType})
+	}
+	return ids
+}
+
+// applyDemographics copies the PID fields that are present onto the patient.
+// Empty fields and the HL7 null ("") leave the current value alone.
+func applyDemographics(patient *model.Patient, pid *hl7v2.Segment) {
+	set := func(dst *string, v string) {
+		v = strings.TrimSpace(v)
+		if v != "" && v != `""` {
+			*dst = v
+		}
+	}
+
+	set(&patient.LastName, pid.Component(5, 1))
+	set(&patient.FirstName, pid.Component(5, 2))
+	if dob := parseTime(pid.Component(7, 1)); dob != nil {
+		patient.DateOfBirth = *dob
+	}
+	switch pid.Field(8) {
+	case "M":
+		patient.Gender = "male"
+	case "F":
+		patient.Gender = "female"
+	case "O", "A":
+		patient.Gender = "other"
+	}
+
+	if address := formatAddress(pid); address != "" {
+		patient.Address = address
+	}
+	// PID-13 XTN: the number is XTN-1, or XTN-12 in newer senders; XTN-4 is email
+	for _, rep := range pid.Repetitions(13) {
+		if email := rep.Get(4); email != "" {
+			set(&patient.Email, email)
+		}
+		phone := rep.Get(1)
+		if phone == "" {
+			phone = rep.Get(12)
+		}
+		set(&patient.Phone, phone)
+	}
+	patient.Name = strings.TrimSpace(patient.FirstName + " " + patient.LastName)
+}
+
+// formatAddress renders the first PID-11 address as one line
+func formatAddress(pid *hl7v2.Segment) string {
+	reps := pid.Repetitions(11)
+	if len(reps) == 0 {
+		return ""
+	}
+	a := reps[0]
+	var parts []string
+	for _, i := range []int{1, 2, 3, 4, 5, 6} {
+		if v := strings.TrimSpace(a.Get(i)); v != "" && v != `""` {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// labResults groups the OBX segments of an ORU^R01 under the OBR they follow
+func labResults(msg *hl7v2.Message) []*model.LabResult {
+	var results []*model.LabResult
+	var current *model.LabResult
+	for _, seg := range msg.Segments {
+		switch seg.Name {
+		case "OBR":
+			current = &model.LabResult{
+				Order:        codedEntry(seg, 4),
+				Status:       seg.Field(25),
+				ObservedAt:   parseTime(seg.Component(7, 1)),
+				Filler:       seg.Component(3, 1),
+				Observations: []model.LabObservation{},
+			}
+			results = append(results, current)
+		case "OBX":
+			if current == nil {
+				continue
+			}
+			current.Observations = append(current.Observations, model.LabObservation{
+				Code:           codedEntry(seg, 3),
+				ValueType:      seg.Field(2),
+				Value:          observationValue(seg),
+				Units:          seg.Component(6, 1),
+				ReferenceRange: seg.Field(7),
+				AbnormalFlags:  seg.Field(8),
+				Status:         seg.Field(11),
+				ObservedAt:     parseTime(seg.Component(14, 1)),
+			})
+		}
+	}
+	return results
+}
+
+// observationValue renders OBX-5. Coded values (CE/CWE) use their text,
+// structured numerics (SN) are joined, anything else is taken as is.
+func observationValue(obx *hl7v2.Segment) string {
+	reps := obx.Repetitions(5)
+	if len(reps) == 0 {
+		return ""
+	}
+	v := reps[0]
+	switch obx.Field(2) {
+	case "CE", "CWE":
+		if text := v.Get(2); text != "" {
+			return text
+		}
+		return v.Get(1)
+	case "SN":
+		var b strings.Builder
+		for i := 1; i <= v.Len(); i++ {
+			b.WriteString(v.Get(i))
+		}
+		return b.String()
+	}
+
+	values := make([]string, 0, len(reps))
+	for _, rep := range reps {
+		values = append(values, rep.Get(1))
+	}
+	return strings.Join(values, "\n")
+}
+
+// codedEntry reads a CE/CWE field: identifier, text, coding system
+func codedEntry(seg *hl7v2.Segment, n int) model.CodedEntry {
+	system := seg.Component(n, 3)
+	if uri, ok := codingSystems[system]; ok {
+		system = uri
+	}
+	return model.CodedEntry{
+		System:  system,
+		Code:    seg.Component(n, 1),
+		Display: seg.Component(n, 2),
+	}
+}
+
+// parseTime reads an HL7 DTM value at any of its precisions, with or
+// without a UTC offset
+func parseTime(v string) *time.Time {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		// drop fractional seconds, keep any offset after them
+		end := i + 1
+		for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+			end++
+		}
+		v = v[:i] + v[end:]
+	}
+
+	layouts := []string{"20060102150405-0700", "200601021504-0700", "20060102150405", "200601021504", "2006010215", "20060102", "200601", "2006"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package hl7
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/identifier"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+	"github.com/jwalitptl/admin-api/internal/service/patient"
+	hl7v2 "github.com/jwalitptl/admin-api/pkg/hl7"
+	"github.com/jwalitptl/admin-api/pkg/security"
+)
+
+var (
+	ErrNotFound          = errors.New("HL7 message not found")
+	ErrNotInQueue        = errors.New("HL7 message is not waiting for reconciliation")
+	ErrUnsupportedType   = errors.New("unsupported message type")
+	ErrMissingPID        = errors.New("message has no PID segment")
+	ErrAmbiguousPatient  = errors.New("identifiers match more than one patient")
+	ErrPatientNotMatched = errors.New("no patient matches the message identifiers")
+	ErrNoSystemUser      = errors.New("no HL7 system user is configured to file results as")
+)
+
+// LabRecordType is the medical record type lab results are filed under
+const LabRecordType = "lab_result"
+
+// supported lists the message types the engine accepts
+var supported = map[string]bool{
+	"ORU^R01": true,
+	"ADT^A04": true,
+	"ADT^A08": true,
+}
+
+type Config struct {
+	// AssigningAuthorities maps PID-3 assigning authority namespaces to
+	// identifier systems
+	AssigningAuthorities map[string]string
+	// LabAccessLevel is the access level of records created from results
+	LabAccessLevel string
+	// SystemUserID is the interface user records filed from messages are
+	// attributed to
+	SystemUserID uuid.UUID
+	// OrganizationID is the organization whose patients messages are
+	// matched against
+	OrganizationID uuid.UUID
+}
+
+type Service struct {
+	repo        repository.HL7Repository
+	patients    *patient.Service
+	identifiers *identifier.Service
+	medical     *medical.Service
+	encryptor   security.Encryptor
+	auditor     *audit.Service
+	config      Config
+}
+
+func NewService(repo repository.HL7Repository, patients *patient.Service, identifiers *identifier.Service, medical *medical.Service, encryptor security.Encryptor, auditor *audit.Service, config Config) *Service {
+	if config.LabAccessLevel == "" {
+		config.LabAccessLevel = "private"
+	}
+	// Namespaces are matched case-insensitively; config loading lowercases
+	// map keys anyway
+	authorities := make(map[string]string, len(config.AssigningAuthorities))
+	for namespace, system := range config.AssigningAuthorities {
+		authorities[strings.ToLower(namespace)] = system
+	}
+	config.AssigningAuthorities = authorities
+	return &Service{
+		repo:        repo,
+		patients:    patients,
+		identifiers: identifiers,
+		medical:     medical,
+		encryptor:   encryptor,
+		auditor:     auditor,
+		config:      config,
+	}
+}
+
+// HandleMessage implements hl7.Handler for the MLLP listener. Every message
+// is logged before it is processed. A message whose patient cannot be matched
+// is still accepted (AA) and queued for reconciliation; AE asks the sender to
+// retry after a processing failure and AR rejects what can never be processed.
+// A repeated control ID is acknowledged without processing it again unless
+// the earlier attempt failed.
+func (s *Service) HandleMessage(ctx context.Context, msg *hl7v2.Message, raw []byte) (hl7v2.AckCode, string) {
+	code, trigger := msg.Type()
+	msgType := code + "^" + trigger
+	if !supported[msgType] {
+		return hl7v2.AckReject, fmt.Sprintf("%s: %s", ErrUnsupportedType, msgType)
+	}
+	if msg.ControlID() == "" {
+		return hl7v2.AckReject, "MSH-10 message control ID is required"
+	}
+
+	record, err := s.repo.FindByControlID(ctx, msg.SendingApplication(), msg.SendingFacility(), msg.ControlID())
+	switch {
+	case err == nil && record.Status != model.HL7MessageStatusFailed:
+		return hl7v2.AckAccept, "duplicate message already received"
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return hl7v2.AckError, "failed to log message"
+	case err != nil:
+		encrypted, err := s.encryptor.Encrypt(raw)
+		if err != nil {
+			return hl7v2.AckError, "failed to log message"
+		}
+		record = &model.HL7Message{
+			ControlID:          msg.ControlID(),
+			SendingApplication: msg.SendingApplication(),
+			SendingFacility:    msg.SendingFacility(),
+			MessageType:        msgType,
+			Status:             model.HL7MessageStatusReceived,
+			Raw:                encrypted,
+			ReceivedAt:         time.Now(),
+		}
+		if err := s.repo.Create(ctx, record); err != nil {
+			if errors.Is(err, repository.ErrDuplicate) {
+				return hl7v2.AckAccept, "duplicate message already received"
+			}
+			return hl7v2.AckError, "failed to log message"
+		}
+	}
+
+	p, err := s.matchPatient(ctx, msg)
+	if err != nil {
+		if errors.Is(err, ErrPatientNotMatched) || errors.Is(err, ErrAmbiguousPatient) {
+			s.finish(ctx, record, model.HL7MessageStatusUnmatched, nil, err)
+			return hl7v2.AckAccept, "queued for patient reconciliation"
+		}
+		s.finish(ctx, record, model.HL7MessageStatusFailed, nil, err)
+		if errors.Is(err, ErrMissingPID) {
+			return hl7v2.AckReject, err.Error()
+		}
+		return hl7v2.AckError, "failed to match patient"
+	}
+
+	if err := s.apply(ctx, msg, p); err != nil {
+		s.finish(ctx, record, model.HL7MessageStatusFailed, &p.ID, err)
+		return hl7v2.AckError, err.Error()
+	}
+
+	s.finish(ctx, record, model.HL7MessageStatusProcessed, &p.ID, nil)
+	return hl7v2.AckAccept, ""
+}
+
+// matchPatient finds the one patient holding any of the PID-3 identifiers
+func (s *Service) matchPatient(ctx context.Context, msg *hl7v2.Message) (*model.Patient, error) {
+	pid := msg.Segment("PID")
+	if pid == nil {
+		return nil, ErrMissingPID
+	}
+
+	var match *model.Patient
+	for _, id := range s.patientIdentifiers(pid) {
+		patients, err := s.identifiers.Lookup(ctx, s.config.OrganizationID, id.system, id.value)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range patients {
+			if match != nil && match.ID != p.ID {
+				return nil, ErrAmbiguousPatient
+			}
+			match = p
+		}
+	}
+	if match == nil {
+		return nil, ErrPatientNotMatched
+	}
+	return match, nil
+}
+
+// apply maps the message onto the patient: ADT updates demographics, ORU files
+// each result group as a lab record. The results of one message are filed
+// together, so a retry after a failure cannot duplicate them.
+func (s *Service) apply(ctx context.Context, msg *hl7v2.Message, p *model.Patient) error {
+	code, _ := msg.Type()
+	switch code {
+	case "ADT":
+		current, err := s.patients.GetPatient(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		applyDemographics(current, msg.Segment("PID"))
+		return s.patients.UpdatePatient(ctx, current)
+
+	case "ORU":
+		if s.config.SystemUserID == uuid.Nil {
+			return ErrNoSystemUser
+		}
+		var records []*model.MedicalRecord
+		for _, result := range labResults(msg) {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			description := result.Order.Display
+			if description == "" {
+				description = result.Order.Code
+			}
+			record := &model.MedicalRecord{
+				PatientID:      p.ID,
+				OrganizationID: p.OrganizationID,
+				Type:           LabRecordType,
+				Description:    description,
+				// The result is kept as an object in the encrypted Diagnosis
+				// field; only arrays there are coded diagnosis entries
+				Diagnosis:   data,
+				AccessLevel: s.config.LabAccessLevel,
+				CreatedBy:   s.config.SystemUserID,
+			}
+			records = append(records, record)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := s.medical.CreateMedicalRecords(ctx, records...); err != nil {
+			return fmt.Errorf("failed to file lab results: %w", err)
+		}
+		return nil
+	}
+	return ErrUnsupportedType
+}
+
+func (s *Service) finish(ctx context.Context, record *model.HL7Message, status model.HL7MessageStatus, patientID *uuid.UUID, cause error) {
+	now := time.Now()
+	record.Status = status
+	record.PatientID = patientID
+	record.ProcessedAt = &now
+	record.Error = nil
+	if cause != nil {
+		msg := cause.Error()
+		record.Error = &msg
+	}
+	// The ACK already reflects the outcome; a failed status update only
+	// leaves the log behind
+	_ = s.repo.Update(ctx, record)
+}
+
+func (s *Service) List(ctx context.Context, status model.HL7MessageStatus, limit int) ([]*model.HL7Message, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.List(ctx, status, limit)
+}
+
+// Get returns one message with its decrypted content
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*model.HL7Message, error) {
+	record, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	// Erasure empties the raw message but keeps the log entry
+	if len(record.Raw) > 0 {
+		raw, err := s.encryptor.Decrypt(record.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt message: %w", err)
+		}
+		record.Message = string(raw)
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), uuid.Nil, "read", "hl7_message", id, nil)
+	return record, nil
+}
+
+// Resolve files a queued message against the patient chosen by the reviewer.
+// The message's identifiers are added to the patient so later messages from
+// the same sender match on their own.
+func (s *Service) Resolve(ctx context.Context, id uuid.UUID, req *model.ResolveHL7MessageRequest) (*model.HL7Message, error) {
+	record, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.Status != model.HL7MessageStatusUnmatched {
+		return nil, ErrNotInQueue
+	}
+
+	msg, err := hl7v2.Parse([]byte(record.Message))
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.patients.GetPatient(ctx, req.PatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.apply(ctx, msg, p); err != nil {
+		return nil, err
+	}
+
+	if pid := msg.Segment("PID"); pid != nil {
+		for _, cx := range s.patientIdentifiers(pid) {
+			_, err := s.identifiers.Add(ctx, p.ID, &model.AddIdentifierRequest{System: cx.system, Value: cx.value, Type: cx.idType})
+			if err != nil && !errors.Is(err, identifier.ErrIdentifierTaken) {
+				return nil, err
+			}
+		}
+	}
+
+	userID := s.getCurrentUserID(ctx)
+	now := time.Now()
+	record.Status = model.HL7MessageStatusResolved
+	record.PatientID = &p.ID
+	record.ProcessedAt = &now
+	record.ResolvedBy = &userID
+	if err := s.repo.Update(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, userID, p.OrganizationID, "resolve", "hl7_message", record.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"patient_id": p.ID, "message_type": record.MessageType},
+	})
+
+	return record, nil
+}
+
+// Discard takes a message out of the reconciliation queue without filing it
+func (s *Service) Discard(ctx context.Context, id uuid.UUID, req *model.DiscardHL7MessageRequest) (*model.HL7Message, error) {
+	record, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if record.Status != model.HL7MessageStatusUnmatched {
+		return nil, ErrNotInQueue
+	}
+
+	userID := s.getCurrentUserID(ctx)
+	record.Status = model.HL7MessageStatusDiscarded
+	record.ResolvedBy = &userID
+	record.Resolution = &req.Reason
+	if err := s.repo.Update(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, userID, uuid.Nil, "discard", "hl7_message", record.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"reason": req.Reason},
+	})
+
+	return record, nil
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
@@ -0,0 +1,116 @@
+package identifier
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+var (
+	seqToken  = regexp.MustCompile(`\{SEQ(?::(\d+))?\}`)
+	anyToken  = regexp.MustCompile(`\{[^}]*\}`)
+	knownToks = map[string]bool{"{YYYY}": true, "{YY}": true, "{CHECK}": true}
+)
+
+// validatePattern checks the pattern has exactly one sequence token and no
+// unknown tokens.
+func validatePattern(pattern string) error {
+	if n := len(seqToken.FindAllString(pattern, -1)); n != 1 {
+		return fmt.Errorf("pattern must contain exactly one {SEQ} or {SEQ:n} token")
+	}
+	for _, tok := range anyToken.FindAllString(pattern, -1) {
+		if !knownToks[tok] && !seqToken.MatchString(tok) {
+			return fmt.Errorf("unknown pattern token %s", tok)
+		}
+	}
+	if strings.Count(pattern, "{CHECK}") > 1 {
+		return fmt.Errorf("pattern may contain {CHECK} at most once")
+	}
+	return nil
+}
+
+// renderMRN fills in the pattern. The check digit covers the digits of the
+// rendered value without the check digit itself.
+func renderMRN(pattern string, seq int64, algorithm model.CheckDigitAlgorithm, now time.Time) (string, error) {
+	value := seqToken.ReplaceAllStringFunc(pattern, func(tok string) string {
+		width := 0
+		if m := seqToken.FindStringSubmatch(tok); m[1] != "" {
+			width, _ = strconv.Atoi(m[1])
+		}
+		return fmt.Sprintf("%0*d", width, seq)
+	})
+	value = strings.ReplaceAll(value, "{YYYY}", now.Format("2006"))
+	value = strings.ReplaceAll(value, "{YY}", now.Format("06"))
+
+	if !strings.Contains(value, "{CHECK}") {
+		return value, nil
+	}
+
+	check, err := checkDigit(strings.ReplaceAll(value, "{CHECK}", ""), algorithm)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(value, "{CHECK}", check), nil
+}
+
+func checkDigit(value string, algorithm model.CheckDigitAlgorithm) (string, error) {
+	var digits []int
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+
+	switch algorithm {
+	case model.CheckDigitNone, "":
+		return "", nil
+	case model.CheckDigitLuhn:
+		return strconv.Itoa(luhn(digits)), nil
+	case model.CheckDigitMod11:
+		return mod11(digits), nil
+	default:
+		return "", fmt.Errorf("unknown check digit algorithm: %s", algorithm)
+	}
+}
+
+// luhn returns the digit that makes the sequence pass the Luhn check
+func luhn(digits []int) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
+
+// mod11 uses weights 2..7 from the right; a remainder that would need 10 is
+// written as X.
+func mod11(digits []int) string {
+	sum := 0
+	weight := 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += digits[i] * weight
+		weight++
+		if weight > 7 {
+			weight = 2
+		}
+	}
+	switch check := (11 - sum%11) % 11; check {
+	case 10:
+		return "X"
+	default:
+		return strconv.Itoa(check)
+	}
+}
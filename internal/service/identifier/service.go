@@ -0,0 +1,235 @@
+package identifier
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+var ErrIdentifierTaken = errors.New("identifier is already assigned in this system")
+
+type Service struct {
+	repo        repository.IdentifierRepository
+	patientRepo repository.PatientRepository
+	auditor     *audit.Service
+}
+
+func NewService(repo repository.IdentifierRepository, patientRepo repository.PatientRepository, auditor *audit.Service) *Service {
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		auditor:     auditor,
+	}
+}
+
+// DefaultMRNSystem is the system used for a clinic's MRNs unless configured
+func DefaultMRNSystem(clinicID uuid.UUID) string {
+	return fmt.Sprintf("urn:admin-api:clinic:%s:mrn", clinicID)
+}
+
+func (s *Service) GetMRNConfig(ctx context.Context, clinicID uuid.UUID) (*model.MRNConfig, error) {
+	config, err := s.repo.GetMRNConfig(ctx, clinicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MRN config: %w", err)
+	}
+	return config, nil
+}
+
+func (s *Service) SaveMRNConfig(ctx context.Context, clinicID uuid.UUID, req *model.MRNConfigRequest) (*model.MRNConfig, error) {
+	if err := validatePattern(req.Pattern); err != nil {
+		return nil, err
+	}
+
+	config, err := s.repo.GetMRNConfig(ctx, clinicID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get MRN config: %w", err)
+		}
+		config = &model.MRNConfig{ClinicID: clinicID, NextSequence: 1}
+	}
+
+	config.Pattern = req.Pattern
+	config.System = req.System
+	if config.System == "" {
+		config.System = DefaultMRNSystem(clinicID)
+	}
+	config.CheckDigit = req.CheckDigit
+	if config.CheckDigit == "" {
+		config.CheckDigit = model.CheckDigitLuhn
+	}
+	if req.NextSequence != nil {
+		if *req.NextSequence < 1 {
+			return nil, fmt.Errorf("next_sequence must be positive")
+		}
+		config.NextSequence = *req.NextSequence
+	}
+
+	// Render once so a bad configuration fails here rather than at registration
+	if _, err := renderMRN(config.Pattern, config.NextSequence, config.CheckDigit, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// The sequence moves only when asked to, so saving a config read before
+	// other registrations cannot hand out their numbers again
+	if err := s.repo.SaveMRNConfig(ctx, config, req.NextSequence); err != nil {
+		return nil, fmt.Errorf("failed to save MRN config: %w", err)
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), uuid.Nil, "update", "mrn_config", clinicID, &audit.LogOptions{
+		Changes: config,
+	})
+
+	return config, nil
+}
+
+// NextMRN claims the next MRN of the patient's clinic. The identifier is saved
+// with the patient, so a failed registration leaves a gap in the numbering
+// rather than a patient without an MRN. Clinics without an MRN configuration
+// do not number their patients, and nil is returned.
+func (s *Service) NextMRN(ctx context.Context, patient *model.Patient) (*model.PatientIdentifier, error) {
+	config, err := s.repo.GetMRNConfig(ctx, patient.ClinicID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get MRN config: %w", err)
+	}
+
+	seq, err := s.repo.NextMRNSequence(ctx, patient.ClinicID)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := renderMRN(config.Pattern, seq, config.CheckDigit, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate MRN: %w", err)
+	}
+
+	return &model.PatientIdentifier{
+		OrganizationID: patient.OrganizationID,
+		System:         config.System,
+		Value:          value,
+		Type:           model.IdentifierTypeMRN,
+		CreatedBy:      s.getCurrentUserID(ctx),
+	}, nil
+}
+
+func (s *Service) Add(ctx context.Context, patientID uuid.UUID, req *model.AddIdentifierRequest) (*model.PatientIdentifier, error) {
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	identifier := &model.PatientIdentifier{
+		PatientID:      patient.ID,
+		OrganizationID: patient.OrganizationID,
+		System:         req.System,
+		Value:          req.Value,
+		Type:           req.Type,
+		PeriodEnd:      req.PeriodEnd,
+		CreatedBy:      s.getCurrentUserID(ctx),
+	}
+	if req.PeriodStart != nil {
+		identifier.PeriodStart = *req.PeriodStart
+	}
+	if identifier.PeriodEnd != nil && !identifier.PeriodStart.IsZero() && !identifier.PeriodEnd.After(identifier.PeriodStart) {
+		return nil, fmt.Errorf("period_end must be after period_start")
+	}
+
+	if err := s.repo.Create(ctx, identifier); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrIdentifierTaken
+		}
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, identifier.CreatedBy, identifier.OrganizationID, "create", "patient_identifier", identifier.ID, &audit.LogOptions{
+		Changes: identifier,
+	})
+
+	return identifier, nil
+}
+
+func (s *Service) List(ctx context.Context, patientID uuid.UUID) ([]*model.PatientIdentifier, error) {
+	identifiers, err := s.repo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identifiers: %w", err)
+	}
+	return identifiers, nil
+}
+
+// End closes the identifier's period. Ended identifiers stay on file, and
+// still resolve in lookups, so old paperwork can be matched.
+func (s *Service) End(ctx context.Context, patientID, id uuid.UUID) (*model.PatientIdentifier, error) {
+	identifier, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identifier: %w", err)
+	}
+	if identifier.PatientID != patientID {
+		return nil, fmt.Errorf("identifier does not belong to patient")
+	}
+	if identifier.PeriodEnd != nil && !identifier.PeriodEnd.After(time.Now()) {
+		return identifier, nil
+	}
+
+	now := time.Now()
+	identifier.PeriodEnd = &now
+	if err := s.repo.Update(ctx, identifier); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), identifier.OrganizationID, "end", "patient_identifier", identifier.ID, nil)
+
+	return identifier, nil
+}
+
+// Lookup returns the organization's patients holding the identifier value, in
+// the given system or in any system when it is empty.
+func (s *Service) Lookup(ctx context.Context, organizationID uuid.UUID, system, value string) ([]*model.Patient, error) {
+	identifiers, err := s.repo.FindByValue(ctx, organizationID, system, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up identifier: %w", err)
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	patients := make([]*model.Patient, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		if seen[identifier.PatientID] {
+			continue
+		}
+		seen[identifier.PatientID] = true
+
+		patient, err := s.patientRepo.Get(ctx, identifier.PatientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get patient: %w", err)
+		}
+		patients = append(patients, patient)
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "lookup", "patient_identifier", uuid.Nil, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"system":  system,
+			"matches": len(patients),
+		},
+	})
+
+	return patients, nil
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
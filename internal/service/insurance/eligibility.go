@@ -0,0 +1,23 @@
+package insurance
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+// EligibilityProvider verifies a coverage with the payer. Implementations wrap
+// a clearinghouse or payer API; FakeEligibilityProvider is used locally.
+type EligibilityProvider interface {
+	Name() string
+	Check(ctx context.Context, coverage *model.InsuranceCoverage, serviceDate time.Time) (*EligibilityResult, error)
+}
+
+type EligibilityResult struct {
+	Status   model.EligibilityStatus
+	Copay    *float64
+	Message  string
+	Response json.RawMessage
+}
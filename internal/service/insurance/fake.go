@@ -0,0 +1,60 @@
+package insurance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+// FakeEligibilityProvider answers eligibility checks without calling a payer.
+// Member numbers starting with "INELIG" are reported ineligible and ones
+// starting with "ERR" fail, so both paths can be exercised in development.
+type FakeEligibilityProvider struct {
+	Copay float64
+}
+
+func NewFakeEligibilityProvider() *FakeEligibilityProvider {
+	return &FakeEligibilityProvider{Copay: 25}
+}
+
+func (p *FakeEligibilityProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeEligibilityProvider) Check(ctx context.Context, coverage *model.InsuranceCoverage, serviceDate time.Time) (*EligibilityResult, error) {
+	member := strings.ToUpper(coverage.MemberNumber)
+	if strings.HasPrefix(member, "ERR") {
+		return nil, fmt.Errorf("payer %s unavailable", coverage.PayerName)
+	}
+
+	result := &EligibilityResult{Status: model.EligibilityStatusEligible}
+	switch {
+	case !coverage.IsEffective(serviceDate):
+		result.Status = model.EligibilityStatusIneligible
+		result.Message = "coverage not effective on service date"
+	case strings.HasPrefix(member, "INELIG"):
+		result.Status = model.EligibilityStatusIneligible
+		result.Message = "member not found"
+	default:
+		copay := p.Copay
+		result.Copay = &copay
+		result.Message = "active coverage"
+	}
+
+	raw, err := json.Marshal(map[string]interface{}{
+		"payer_id":      coverage.PayerID,
+		"member_number": coverage.MemberNumber,
+		"service_date":  serviceDate.Format("2006-01-02"),
+		"status":        result.Status,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Response = raw
+
+	return result, nil
+}
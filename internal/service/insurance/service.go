@@ -0,0 +1,250 @@
+package insurance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+var ErrPriorityTaken = errors.New("patient already has an active coverage with this priority")
+
+type Service struct {
+	repo            repository.InsuranceRepository
+	patientRepo     repository.PatientRepository
+	appointmentRepo repository.AppointmentRepository
+	provider        EligibilityProvider
+	auditor         *audit.Service
+}
+
+func NewService(
+	repo repository.InsuranceRepository,
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	provider EligibilityProvider,
+	auditor *audit.Service,
+) *Service {
+	return &Service{
+		repo:            repo,
+		patientRepo:     patientRepo,
+		appointmentRepo: appointmentRepo,
+		provider:        provider,
+		auditor:         auditor,
+	}
+}
+
+// SaveCoverage creates a coverage, or updates it when req.ID is set. Every
+// change is snapshotted into the coverage history by the repository.
+func (s *Service) SaveCoverage(ctx context.Context, patientID uuid.UUID, req *model.CoverageRequest) (*model.InsuranceCoverage, error) {
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	if req.EffectiveTo != nil && req.EffectiveTo.Before(req.EffectiveFrom) {
+		return nil, fmt.Errorf("effective_to must not be before effective_from")
+	}
+
+	coverage := &model.InsuranceCoverage{
+		PatientID:      patient.ID,
+		OrganizationID: patient.OrganizationID,
+	}
+	action := "create"
+
+	if req.ID != nil {
+		existing, err := s.repo.GetCoverage(ctx, *req.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get coverage: %w", err)
+		}
+		if existing.PatientID != patientID {
+			return nil, fmt.Errorf("coverage does not belong to patient")
+		}
+		coverage = existing
+		action = "update"
+	}
+
+	coverage.PayerName = req.PayerName
+	coverage.PayerID = req.PayerID
+	coverage.PlanName = req.PlanName
+	coverage.MemberNumber = req.MemberNumber
+	coverage.GroupNumber = req.GroupNumber
+	coverage.SubscriberName = req.SubscriberName
+	coverage.SubscriberRelationship = req.SubscriberRelationship
+	coverage.Priority = req.Priority
+	coverage.EffectiveFrom = req.EffectiveFrom
+	coverage.EffectiveTo = req.EffectiveTo
+	coverage.CardFrontImage = req.CardFrontImage
+	coverage.CardBackImage = req.CardBackImage
+	coverage.Status = req.Status
+	if coverage.Status == "" {
+		coverage.Status = model.CoverageStatusActive
+	}
+
+	if coverage.Status == model.CoverageStatusActive {
+		if err := s.checkPriority(ctx, coverage); err != nil {
+			return nil, err
+		}
+	}
+
+	userID := s.getCurrentUserID(ctx)
+	if action == "create" {
+		err = s.repo.CreateCoverage(ctx, coverage, userID)
+	} else {
+		err = s.repo.UpdateCoverage(ctx, coverage, userID)
+	}
+	if err != nil {
+		// A concurrent save took the priority after checkPriority ran
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrPriorityTaken
+		}
+		return nil, fmt.Errorf("failed to save coverage: %w", err)
+	}
+
+	s.auditor.Log(ctx, userID, coverage.OrganizationID, action, "insurance_coverage", coverage.ID, &audit.LogOptions{
+		Changes: coverage,
+	})
+
+	return coverage, nil
+}
+
+func (s *Service) GetCoverage(ctx context.Context, id uuid.UUID) (*model.InsuranceCoverage, error) {
+	coverage, err := s.repo.GetCoverage(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coverage: %w", err)
+	}
+	return coverage, nil
+}
+
+// ListCoverages returns the patient's coverages ordered by priority
+func (s *Service) ListCoverages(ctx context.Context, patientID uuid.UUID, activeOnly bool) ([]*model.InsuranceCoverage, error) {
+	coverages, err := s.repo.ListCoverages(ctx, patientID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list coverages: %w", err)
+	}
+	return coverages, nil
+}
+
+func (s *Service) GetCoverageHistory(ctx context.Context, patientID, coverageID uuid.UUID) ([]*model.InsuranceCoverageHistory, error) {
+	if _, err := s.coverageForPatient(ctx, patientID, coverageID); err != nil {
+		return nil, err
+	}
+
+	history, err := s.repo.ListCoverageHistory(ctx, coverageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list coverage history: %w", err)
+	}
+	return history, nil
+}
+
+// CheckEligibility verifies the coverage with the configured provider and
+// records the result, against the appointment when one is given. Provider
+// failures are recorded as an error result rather than returned.
+func (s *Service) CheckEligibility(ctx context.Context, patientID, coverageID uuid.UUID, req *model.EligibilityCheckRequest) (*model.EligibilityCheck, error) {
+	coverage, err := s.coverageForPatient(ctx, patientID, coverageID)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceDate := time.Now()
+	if req.AppointmentID != nil {
+		apt, err := s.appointmentRepo.Get(ctx, *req.AppointmentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get appointment: %w", err)
+		}
+		if apt.PatientID != patientID {
+			return nil, fmt.Errorf("appointment does not belong to patient")
+		}
+		serviceDate = apt.StartTime
+	}
+	if req.ServiceDate != nil {
+		serviceDate = *req.ServiceDate
+	}
+
+	check := &model.EligibilityCheck{
+		CoverageID:    coverage.ID,
+		PatientID:     patientID,
+		AppointmentID: req.AppointmentID,
+		Provider:      s.provider.Name(),
+		ServiceDate:   serviceDate,
+		CheckedBy:     s.getCurrentUserID(ctx),
+	}
+
+	result, err := s.provider.Check(ctx, coverage, serviceDate)
+	if err != nil {
+		check.Status = model.EligibilityStatusError
+		check.Message = err.Error()
+	} else {
+		check.Status = result.Status
+		check.Copay = result.Copay
+		check.Message = result.Message
+		check.Response = result.Response
+	}
+
+	if err := s.repo.CreateEligibilityCheck(ctx, check); err != nil {
+		return nil, fmt.Errorf("failed to record eligibility check: %w", err)
+	}
+
+	s.auditor.Log(ctx, check.CheckedBy, coverage.OrganizationID, "check_eligibility", "insurance_coverage", coverage.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"appointment_id": check.AppointmentID,
+			"status":         check.Status,
+			"provider":       check.Provider,
+		},
+	})
+
+	return check, nil
+}
+
+func (s *Service) ListEligibilityChecks(ctx context.Context, patientID, coverageID uuid.UUID) ([]*model.EligibilityCheck, error) {
+	if _, err := s.coverageForPatient(ctx, patientID, coverageID); err != nil {
+		return nil, err
+	}
+
+	checks, err := s.repo.ListEligibilityChecks(ctx, coverageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list eligibility checks: %w", err)
+	}
+	return checks, nil
+}
+
+func (s *Service) coverageForPatient(ctx context.Context, patientID, coverageID uuid.UUID) (*model.InsuranceCoverage, error) {
+	coverage, err := s.repo.GetCoverage(ctx, coverageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coverage: %w", err)
+	}
+	if coverage.PatientID != patientID {
+		return nil, fmt.Errorf("coverage does not belong to patient")
+	}
+	return coverage, nil
+}
+
+// checkPriority keeps at most one active coverage per priority level
+func (s *Service) checkPriority(ctx context.Context, coverage *model.InsuranceCoverage) error {
+	active, err := s.repo.ListCoverages(ctx, coverage.PatientID, true)
+	if err != nil {
+		return fmt.Errorf("failed to list coverages: %w", err)
+	}
+
+	for _, c := range active {
+		if c.ID != coverage.ID && c.Priority == coverage.Priority {
+			return ErrPriorityTaken
+		}
+	}
+	return nil
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
@@ -0,0 +1,276 @@
+package keyring
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/pkg/security"
+)
+
+var (
+	ErrNoOrganization = errors.New("an organization is required to encrypt data")
+	ErrKeyNotFound    = errors.New("data encryption key not found")
+)
+
+type Config struct {
+	// ActiveKeyTTL is how long an organization's active key is cached. A
+	// rotation made on another instance is picked up within this time;
+	// anything encrypted under the old key meanwhile is caught by the
+	// re-encryption job.
+	ActiveKeyTTL time.Duration
+}
+
+type activeKey struct {
+	id       uuid.UUID
+	loadedAt time.Time
+}
+
+// Service does envelope encryption. Every organization has its own data
+// encryption key, stored wrapped by a key-encryption key from the KMS, and
+// every ciphertext names the data key it was sealed with. Ciphertexts from
+// before envelope encryption are still opened with the legacy key.
+type Service struct {
+	repo    repository.DataKeyRepository
+	kms     security.KMS
+	legacy  security.Encryptor
+	auditor *audit.Service
+	config  Config
+
+	mu     sync.RWMutex
+	keys   map[uuid.UUID][]byte
+	active map[uuid.UUID]activeKey
+}
+
+func NewService(repo repository.DataKeyRepository, kms security.KMS, legacy security.Encryptor, auditor *audit.Service, config Config) *Service {
+	if config.ActiveKeyTTL <= 0 {
+		config.ActiveKeyTTL = time.Minute
+	}
+	return &Service{
+		repo:    repo,
+		kms:     kms,
+		legacy:  legacy,
+		auditor: auditor,
+		config:  config,
+		keys:    make(map[uuid.UUID][]byte),
+		active:  make(map[uuid.UUID]activeKey),
+	}
+}
+
+// Encrypt seals plaintext under the organization's active data key and
+// returns the key's ID along with the ciphertext
+func (s *Service) Encrypt(ctx context.Context, orgID uuid.UUID, plaintext []byte) ([]byte, uuid.UUID, error) {
+	keyID, err := s.ActiveKeyID(ctx, orgID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	ciphertext, err := s.EncryptWithKey(ctx, keyID, plaintext)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	return ciphertext, keyID, nil
+}
+
+// EncryptWithKey seals plaintext under a given data key, so that several
+// fields of one row can share the key returned by ActiveKeyID
+func (s *Service) EncryptWithKey(ctx context.Context, keyID uuid.UUID, plaintext []byte) ([]byte, error) {
+	dataKey, err := s.dataKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return security.SealEnvelope(dataKey, keyID, plaintext)
+}
+
+// Decrypt opens a ciphertext with the data key named in it
+func (s *Service) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	keyID, err := security.EnvelopeKeyID(ciphertext)
+	if errors.Is(err, security.ErrNotEnvelope) {
+		return s.legacy.Decrypt(ciphertext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := s.dataKey(ctx, keyID)
+	if errors.Is(err, ErrKeyNotFound) {
+		// A legacy ciphertext whose random nonce happens to start like an
+		// envelope
+		if plaintext, legacyErr := s.legacy.Decrypt(ciphertext); legacyErr == nil {
+			return plaintext, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return security.OpenEnvelope(dataKey, ciphertext)
+}
+
+// ActiveKeyID returns the organization's active data key, creating the
+// first one on demand
+func (s *Service) ActiveKeyID(ctx context.Context, orgID uuid.UUID) (uuid.UUID, error) {
+	if orgID == uuid.Nil {
+		return uuid.Nil, ErrNoOrganization
+	}
+
+	s.mu.RLock()
+	cached, ok := s.active[orgID]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < s.config.ActiveKeyTTL {
+		return cached.id, nil
+	}
+
+	key, err := s.repo.GetActive(ctx, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		key, err = s.createKey(ctx, orgID, false)
+		if errors.Is(err, repository.ErrDuplicate) {
+			// Another instance created it first
+			key, err = s.repo.GetActive(ctx, orgID)
+		}
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to load data key: %w", err)
+	}
+
+	s.mu.Lock()
+	s.active[orgID] = activeKey{id: key.ID, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return key.ID, nil
+}
+
+// Rotate gives the organization a new active data key. The old key is
+// retired but still decrypts; the re-encryption job moves existing data
+// onto the new key in the background.
+func (s *Service) Rotate(ctx context.Context, orgID uuid.UUID) (*model.DataKey, error) {
+	if orgID == uuid.Nil {
+		return nil, ErrNoOrganization
+	}
+
+	key, err := s.createKey(ctx, orgID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate data key: %w", err)
+	}
+
+	s.mu.Lock()
+	s.active[orgID] = activeKey{id: key.ID, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), orgID, "rotate", "data_encryption_key", key.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"kek_id": key.KEKID},
+	})
+
+	return key, nil
+}
+
+func (s *Service) List(ctx context.Context, orgID uuid.UUID) ([]*model.DataKey, error) {
+	return s.repo.List(ctx, orgID)
+}
+
+// RewrapBatch rewraps up to limit data keys that are not yet under the
+// KMS's current key-encryption key. Only the wrapping changes, so rotating
+// a key-encryption key touches no encrypted data.
+func (s *Service) RewrapBatch(ctx context.Context, limit int) (int, error) {
+	kekID, err := s.kms.CurrentKeyID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	keys, err := s.repo.ListWrappedWithout(ctx, kekID, limit)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		dataKey, err := s.kms.Unwrap(ctx, key.KEKID, key.WrappedKey, key.ID[:])
+		if err != nil {
+			return 0, fmt.Errorf("failed to unwrap data key %s: %w", key.ID, err)
+		}
+		wrapped, newKEK, err := s.kms.Wrap(ctx, dataKey, key.ID[:])
+		if err != nil {
+			return 0, fmt.Errorf("failed to wrap data key %s: %w", key.ID, err)
+		}
+		key.WrappedKey, key.KEKID = wrapped, newKEK
+		if err := s.repo.UpdateWrapping(ctx, key); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+func (s *Service) createKey(ctx context.Context, orgID uuid.UUID, rotate bool) (*model.DataKey, error) {
+	dataKey, err := security.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &model.DataKey{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Status:         model.DataKeyStatusActive,
+		CreatedAt:      time.Now(),
+	}
+	// The key ID is bound to the wrapped key so wrapped keys cannot be
+	// swapped between rows
+	key.WrappedKey, key.KEKID, err = s.kms.Wrap(ctx, dataKey, key.ID[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	if rotate {
+		err = s.repo.Rotate(ctx, key)
+	} else {
+		err = s.repo.Create(ctx, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys[key.ID] = dataKey
+	s.mu.Unlock()
+	return key, nil
+}
+
+// dataKey returns the unwrapped data key, unwrapping it through the KMS the
+// first time it is used
+func (s *Service) dataKey(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	s.mu.RLock()
+	dataKey, ok := s.keys[id]
+	s.mu.RUnlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	key, err := s.repo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to load data key: %w", err)
+	}
+	dataKey, err = s.kms.Unwrap(ctx, key.KEKID, key.WrappedKey, key.ID[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %s: %w", id, err)
+	}
+
+	s.mu.Lock()
+	s.keys[id] = dataKey
+	s.mu.Unlock()
+	return dataKey, nil
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
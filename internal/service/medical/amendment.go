@@ -0,0 +1,284 @@
+package medical
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+// clinicians may correct records and decide amendment requests
+var clinicians = map[string]bool{
+	model.UserTypeDoctor: true,
+	model.UserTypeNurse:  true,
+}
+
+// CorrectRecord applies a clinician's correction to a patient's record as a
+// new version
+func (s *Service) CorrectRecord(ctx context.Context, patientID, recordID uuid.UUID, editor *model.RecordReader, req *model.UpdateMedicalRecordRequest) (*model.MedicalRecord, error) {
+	if !clinicians[editor.UserType] {
+		return nil, ErrNotClinician
+	}
+	if req.Changes.Empty() {
+		return nil, ErrNoChanges
+	}
+
+	record, err := s.loadRecord(ctx, patientID, recordID, editor)
+	if err != nil {
+		return nil, err
+	}
+	// The caller's base version, not the one just loaded, decides whether
+	// the correction is stale
+	record.Version = req.Version
+	applyChanges(record, &req.Changes)
+
+	if err := s.UpdateMedicalRecord(ctx, record, editor.UserID, req.Reason); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *Service) ListVersions(ctx context.Context, patientID, recordID uuid.UUID, reader *model.RecordReader) ([]*model.MedicalRecordVersion, error) {
+	record, err := s.loadRecord(ctx, patientID, recordID, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := s.repo.ListVersions(ctx, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+	for _, v := range versions {
+		if err := s.decryptVersion(ctx, v); err != nil {
+			return nil, fmt.Errorf("failed to decrypt version %d: %w", v.Version, err)
+		}
+	}
+
+	s.auditor.Log(ctx, reader.UserID, uuid.Nil, "read_history", "medical_record", recordID, &audit.LogOptions{
+		AccessLevel:  record.AccessLevel,
+		AccessReason: reader.Reason,
+	})
+
+	return versions, nil
+}
+
+func (s *Service) GetVersion(ctx context.Context, patientID, recordID uuid.UUID, version int, reader *model.RecordReader) (*model.MedicalRecordVersion, error) {
+	record, err := s.loadRecord(ctx, patientID, recordID, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.repo.GetVersion(ctx, recordID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+	if err := s.decryptVersion(ctx, v); err != nil {
+		return nil, fmt.Errorf("failed to decrypt version: %w", err)
+	}
+
+	s.auditor.Log(ctx, reader.UserID, uuid.Nil, "read_history", "medical_record", recordID, &audit.LogOptions{
+		AccessLevel:  record.AccessLevel,
+		AccessReason: reader.Reason,
+		Metadata:     map[string]interface{}{"version": version},
+	})
+
+	return v, nil
+}
+
+// RequestAmendment files a patient's request to amend one of their records.
+// The record is not touched until a clinician accepts the request.
+func (s *Service) RequestAmendment(ctx context.Context, patientID uuid.UUID, requester *model.RecordReader, req *model.CreateAmendmentRequest) (*model.AmendmentRequest, error) {
+	if req.ProposedChanges.Empty() {
+		return nil, ErrNoChanges
+	}
+	recordID := req.RecordID
+
+	record, err := s.loadRecord(ctx, patientID, recordID, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	proposed, err := json.Marshal(req.ProposedChanges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode proposed changes: %w", err)
+	}
+
+	now := time.Now()
+	amendment := &model.AmendmentRequest{
+		RecordID:        recordID,
+		PatientID:       patientID,
+		RequestedBy:     requester.UserID,
+		BaseVersion:     record.Version,
+		Justification:   req.Justification,
+		ProposedChanges: proposed,
+		Status:          model.AmendmentStatusPending,
+		DueAt:           now.AddDate(0, 0, model.AmendmentResponseDays),
+	}
+	if err := s.repo.CreateAmendment(ctx, amendment); err != nil {
+		return nil, fmt.Errorf("failed to create amendment request: %w", err)
+	}
+
+	s.auditor.Log(ctx, amendment.RequestedBy, uuid.Nil, "request", "amendment_request", amendment.ID, &audit.LogOptions{
+		AccessLevel: record.AccessLevel,
+		Metadata: map[string]interface{}{
+			"record_id":    recordID,
+			"base_version": amendment.BaseVersion,
+		},
+	})
+
+	return amendment, nil
+}
+
+// GetAmendment returns an amendment request if the reader may see the record
+// it amends
+func (s *Service) GetAmendment(ctx context.Context, patientID, amendmentID uuid.UUID, reader *model.RecordReader) (*model.AmendmentRequest, error) {
+	amendment, err := s.repo.GetAmendment(ctx, amendmentID)
+	if err != nil || amendment.PatientID != patientID {
+		return nil, ErrAmendmentNotFound
+	}
+	record, err := s.repo.Get(ctx, amendment.RecordID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+	if err := s.authorize(ctx, record, reader); err != nil {
+		return nil, err
+	}
+	return amendment, nil
+}
+
+// ListAmendments returns the patient's amendment requests on the records the
+// reader may see
+func (s *Service) ListAmendments(ctx context.Context, patientID uuid.UUID, reader *model.RecordReader, status model.AmendmentStatus) ([]*model.AmendmentRequest, error) {
+	amendments, err := s.repo.ListAmendments(ctx, patientID, reader.Access(), status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list amendment requests: %w", err)
+	}
+	return amendments, nil
+}
+
+// DecideAmendment accepts or denies a pending amendment. A denial keeps the
+// record as it is and stores the reason for the patient; an acceptance
+// writes the corrected content as a new version linked to the request.
+func (s *Service) DecideAmendment(ctx context.Context, patientID, amendmentID uuid.UUID, reviewer *model.RecordReader, req *model.DecideAmendmentRequest) (*model.AmendmentRequest, error) {
+	if !clinicians[reviewer.UserType] {
+		return nil, ErrNotClinician
+	}
+	if req.Reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	amendment, err := s.GetAmendment(ctx, patientID, amendmentID, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	if amendment.Status != model.AmendmentStatusPending {
+		return nil, ErrAmendmentClosed
+	}
+
+	now := time.Now()
+	amendment.ReviewedBy = &reviewer.UserID
+	amendment.ReviewedAt = &now
+	amendment.DecisionReason = &req.Reason
+	amendment.Status = model.AmendmentStatusDenied
+
+	var record *model.MedicalRecord
+	if req.Accept {
+		changes := req.Changes
+		if changes == nil {
+			changes = &model.RecordChanges{}
+			if err := json.Unmarshal(amendment.ProposedChanges, changes); err != nil {
+				return nil, fmt.Errorf("failed to decode proposed changes: %w", err)
+			}
+		}
+		if changes.Empty() {
+			return nil, ErrNoChanges
+		}
+
+		record, err = s.loadRecord(ctx, patientID, amendment.RecordID, reviewer)
+		if err != nil {
+			return nil, err
+		}
+		previous := record.Diagnosis
+		applyChanges(record, changes)
+		if err := s.validateRecord(record); err != nil {
+			return nil, fmt.Errorf("invalid record: %w", err)
+		}
+		if err := s.codeDiagnosis(ctx, record, previous); err != nil {
+			return nil, err
+		}
+		if err := s.encryptSensitiveData(ctx, record); err != nil {
+			return nil, fmt.Errorf("failed to encrypt data: %w", err)
+		}
+		amendment.Status = model.AmendmentStatusAccepted
+	}
+
+	if err := s.repo.DecideAmendment(ctx, amendment, record); err != nil {
+		return nil, fmt.Errorf("failed to decide amendment request: %w", err)
+	}
+
+	s.auditor.Log(ctx, reviewer.UserID, uuid.Nil, string(amendment.Status), "amendment_request", amendment.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"record_id":         amendment.RecordID,
+			"reason":            req.Reason,
+			"resulting_version": amendment.ResultingVersion,
+		},
+	})
+
+	return amendment, nil
+}
+
+// loadRecord fetches a record of the patient the reader may see, with its
+// sensitive fields decrypted
+func (s *Service) loadRecord(ctx context.Context, patientID, recordID uuid.UUID, reader *model.RecordReader) (*model.MedicalRecord, error) {
+	record, err := s.repo.Get(ctx, recordID)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+	if record.PatientID != patientID {
+		return nil, ErrRecordNotFound
+	}
+	if err := s.authorize(ctx, record, reader); err != nil {
+		return nil, err
+	}
+	if err := s.decryptSensitiveData(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	return record, nil
+}
+
+func (s *Service) decryptVersion(ctx context.Context, v *model.MedicalRecordVersion) error {
+	if v.Diagnosis != nil {
+		decrypted, err := s.keys.Decrypt(ctx, v.Diagnosis)
+		if err != nil {
+			return err
+		}
+		v.Diagnosis = decrypted
+	}
+
+	if v.Treatment != nil {
+		decrypted, err := s.keys.Decrypt(ctx, v.Treatment)
+		if err != nil {
+			return err
+		}
+		v.Treatment = decrypted
+	}
+
+	return nil
+}
+
+func applyChanges(record *model.MedicalRecord, changes *model.RecordChanges) {
+	if changes.Description != nil {
+		record.Description = *changes.Description
+	}
+	if changes.Diagnosis != nil {
+		record.Diagnosis = changes.Diagnosis
+	}
+	if changes.Treatment != nil {
+		record.Treatment = changes.Treatment
+	}
+}
@@ -0,0 +1,89 @@
+package medical
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+// careTeamManagers may add and remove care team members
+var careTeamManagers = map[string]bool{
+	model.UserTypeAdmin:  true,
+	model.UserTypeDoctor: true,
+}
+
+func (s *Service) ListCareTeam(ctx context.Context, patientID uuid.UUID) ([]*model.CareTeamMember, error) {
+	members, err := s.careTeam.List(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list care team: %w", err)
+	}
+	return members, nil
+}
+
+// AddCareTeamMember gives the user access to the patient's restricted
+// records. Adding an existing member changes their role.
+func (s *Service) AddCareTeamMember(ctx context.Context, patientID uuid.UUID, manager *model.RecordReader, req *model.AddCareTeamMemberRequest) (*model.CareTeamMember, error) {
+	if err := s.checkCareTeamManager(ctx, patientID, manager); err != nil {
+		return nil, err
+	}
+
+	member := &model.CareTeamMember{
+		PatientID: patientID,
+		UserID:    req.UserID,
+		Role:      strings.TrimSpace(req.Role),
+		AddedBy:   manager.UserID,
+		AddedAt:   time.Now(),
+	}
+	if err := s.careTeam.Add(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to add care team member: %w", err)
+	}
+
+	s.auditor.Log(ctx, member.AddedBy, uuid.Nil, "add_member", "care_team", patientID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"user_id": member.UserID, "role": member.Role},
+	})
+
+	return member, nil
+}
+
+func (s *Service) RemoveCareTeamMember(ctx context.Context, patientID, userID uuid.UUID, manager *model.RecordReader) error {
+	if err := s.checkCareTeamManager(ctx, patientID, manager); err != nil {
+		return err
+	}
+
+	if err := s.careTeam.Remove(ctx, patientID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotOnCareTeam
+		}
+		return fmt.Errorf("failed to remove care team member: %w", err)
+	}
+
+	s.auditor.Log(ctx, manager.UserID, uuid.Nil, "remove_member", "care_team", patientID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"user_id": userID},
+	})
+
+	return nil
+}
+
+// checkCareTeamManager lets administrators of the patient's organization,
+// and doctors already on the patient's care team, change the team
+func (s *Service) checkCareTeamManager(ctx context.Context, patientID uuid.UUID, manager *model.RecordReader) error {
+	if manager == nil || !careTeamManagers[manager.UserType] || manager.UserID == uuid.Nil {
+		return ErrNotCareTeamManager
+	}
+	ok, err := s.careTeam.CanManage(ctx, patientID, manager.UserID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotCareTeamManager
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package medical
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+// ReencryptBatch moves up to limit records and limit versions onto their
+// organization's active data key: those written before envelope encryption
+// and those sealed under a key that has since been rotated. It returns how
+// many rows were re-encrypted; zero means the job has caught up.
+func (s *Service) ReencryptBatch(ctx context.Context, limit int) (int, error) {
+	return s.repo.ReencryptBatch(ctx, limit, func(f *model.EncryptedFields) error {
+		keyID, err := s.keys.ActiveKeyID(ctx, f.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		for _, field := range []*[]byte{&f.Diagnosis, &f.Treatment} {
+			if *field == nil {
+				continue
+			}
+			plaintext, err := s.keys.Decrypt(ctx, *field)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", f.ID, err)
+			}
+			if *field, err = s.keys.EncryptWithKey(ctx, keyID, plaintext); err != nil {
+				return fmt.Errorf("failed to encrypt %s: %w", f.ID, err)
+			}
+		}
+		f.EncryptionKeyID = &keyID
+		return nil
+	})
+}
@@ -3,14 +3,33 @@ package medical
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository"
 	"github.com/jwalitptl/admin-api/internal/service/audit"
-	"github.com/jwalitptl/admin-api/pkg/security"
+	"github.com/jwalitptl/admin-api/internal/service/keyring"
+	"github.com/jwalitptl/admin-api/internal/service/terminology"
+)
+
+var (
+	ErrRecordNotFound    = errors.New("medical record not found")
+	ErrInvalidDiagnosis  = errors.New("diagnosis is not a valid list of coded entries")
+	ErrReasonRequired    = errors.New("a reason is required to change a medical record")
+	ErrNoChanges         = errors.New("no changes to the record were given")
+	ErrNotClinician      = errors.New("only clinicians can change medical records")
+	ErrAmendmentClosed   = errors.New("amendment request has already been decided")
+	ErrAmendmentNotFound = errors.New("amendment request not found")
+
+	ErrAccessDenied         = errors.New("medical record is restricted to the patient's care team")
+	ErrAccessReasonRequired = errors.New("an access reason is required to read HIPAA records")
+	ErrNotCareTeamManager   = errors.New("only the organization's administrators and doctors on the care team can change it")
+	ErrNotOnCareTeam        = errors.New("user is not on the patient's care team")
 )
 
 const (
@@ -19,94 +38,154 @@ const (
 	accessLevelHIPAA   = "hipaa"
 )
 
+// RecordListener is told about each record after it has been created, with
+// its diagnosis codes in Codes. A failing listener does not undo the record.
+type RecordListener interface {
+	RecordCreated(ctx context.Context, record *model.MedicalRecord) error
+}
+
 type Service struct {
-	repo      repository.MedicalRecordRepository
-	encryptor security.Encryptor
-	auditor   *audit.Service
+	repo        repository.MedicalRecordRepository
+	careTeam    repository.CareTeamRepository
+	keys        *keyring.Service
+	terminology *terminology.Service
+	auditor     *audit.Service
+	listeners   []RecordListener
 }
 
-func NewService(repo repository.MedicalRecordRepository, encryptor security.Encryptor, auditor *audit.Service) *Service {
+func NewService(repo repository.MedicalRecordRepository, careTeam repository.CareTeamRepository, keys *keyring.Service, terminology *terminology.Service, auditor *audit.Service) *Service {
 	return &Service{
-		repo:      repo,
-		encryptor: encryptor,
-		auditor:   auditor,
+		repo:        repo,
+		careTeam:    careTeam,
+		keys:        keys,
+		terminology: terminology,
+		auditor:     auditor,
 	}
 }
 
+// AddRecordListener registers l to be told about records as they are created
+func (s *Service) AddRecordListener(l RecordListener) {
+	s.listeners = append(s.listeners, l)
+}
+
 func (s *Service) CreateMedicalRecord(ctx context.Context, record *model.MedicalRecord) error {
-	if err := s.validateRecord(record); err != nil {
-		return fmt.Errorf("invalid record: %w", err)
-	}
+	return s.CreateMedicalRecords(ctx, record)
+}
 
-	record.ID = uuid.New()
-	record.CreatedAt = time.Now()
-	record.UpdatedAt = time.Now()
-	record.LastAccessedAt = time.Now()
+// CreateMedicalRecords creates the records together: if one cannot be
+// created, none are
+func (s *Service) CreateMedicalRecords(ctx context.Context, records ...*model.MedicalRecord) error {
+	for _, record := range records {
+		if err := s.validateRecord(record); err != nil {
+			return fmt.Errorf("invalid record: %w", err)
+		}
 
-	// Encrypt sensitive data
-	if err := s.encryptSensitiveData(record); err != nil {
-		return fmt.Errorf("failed to encrypt data: %w", err)
+		record.ID = uuid.New()
+		record.CreatedAt = time.Now()
+		record.UpdatedAt = time.Now()
+		record.LastAccessedAt = time.Now()
+
+		if err := s.codeDiagnosis(ctx, record, nil); err != nil {
+			return err
+		}
+
+		// Encrypt sensitive data
+		if err := s.encryptSensitiveData(ctx, record); err != nil {
+			return fmt.Errorf("failed to encrypt data: %w", err)
+		}
 	}
 
-	if err := s.repo.CreateWithAudit(ctx, record); err != nil {
+	if err := s.repo.CreateWithAudit(ctx, records...); err != nil {
 		return fmt.Errorf("failed to create record: %w", err)
 	}
 
-	s.auditor.Log(ctx, record.CreatedBy, uuid.Nil, "create", "medical_record", record.ID, &audit.LogOptions{
-		AccessLevel: record.AccessLevel,
-	})
+	for _, record := range records {
+		s.auditor.Log(ctx, record.CreatedBy, uuid.Nil, "create", "medical_record", record.ID, &audit.LogOptions{
+			AccessLevel: record.AccessLevel,
+		})
+
+		for _, l := range s.listeners {
+			if err := l.RecordCreated(ctx, record); err != nil {
+				log.Printf("record listener failed for medical record %s: %v", record.ID, err)
+			}
+		}
+	}
 
 	return nil
 }
 
-func (s *Service) GetMedicalRecord(ctx context.Context, id uuid.UUID, accessReason string) (*model.MedicalRecord, error) {
-	record, err := s.repo.Get(ctx, id)
+// GetMedicalRecord returns one of the patient's records if its access level
+// lets the reader see it
+func (s *Service) GetMedicalRecord(ctx context.Context, patientID, recordID uuid.UUID, reader *model.RecordReader) (*model.MedicalRecord, error) {
+	record, err := s.loadRecord(ctx, patientID, recordID, reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get record: %w", err)
-	}
-
-	// Decrypt sensitive data
-	if err := s.decryptSensitiveData(record); err != nil {
-		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+		return nil, err
 	}
 
 	// Update access metadata
 	record.LastAccessedAt = time.Now()
-	record.LastAccessedBy = s.getCurrentUserID(ctx)
+	record.LastAccessedBy = reader.UserID
 
-	s.auditor.Log(ctx, record.LastAccessedBy, uuid.Nil, "read", "medical_record", id, &audit.LogOptions{
+	s.auditor.Log(ctx, reader.UserID, record.OrganizationID, "read", "medical_record", recordID, &audit.LogOptions{
 		AccessLevel:  record.AccessLevel,
-		AccessReason: accessReason,
+		AccessReason: reader.Reason,
 	})
 
 	return record, nil
 }
 
-func (s *Service) UpdateMedicalRecord(ctx context.Context, record *model.MedicalRecord) error {
+// UpdateMedicalRecord stores record as a new version by authorID.
+// record.Version must be the version the change was made against, and
+// reason is kept with the version so the history explains every change.
+func (s *Service) UpdateMedicalRecord(ctx context.Context, record *model.MedicalRecord, authorID uuid.UUID, reason string) error {
 	if err := s.validateRecord(record); err != nil {
 		return fmt.Errorf("invalid record: %w", err)
 	}
+	if reason == "" {
+		return ErrReasonRequired
+	}
+
+	stored, err := s.repo.Get(ctx, record.ID)
+	if err != nil {
+		return ErrRecordNotFound
+	}
+	var previous json.RawMessage
+	if stored.Diagnosis != nil {
+		if previous, err = s.keys.Decrypt(ctx, stored.Diagnosis); err != nil {
+			return fmt.Errorf("failed to decrypt data: %w", err)
+		}
+	}
+	if err := s.codeDiagnosis(ctx, record, previous); err != nil {
+		return err
+	}
 
 	record.UpdatedAt = time.Now()
 
 	// Encrypt sensitive data
-	if err := s.encryptSensitiveData(record); err != nil {
+	if err := s.encryptSensitiveData(ctx, record); err != nil {
 		return fmt.Errorf("failed to encrypt data: %w", err)
 	}
 
-	if err := s.repo.UpdateWithAudit(ctx, record); err != nil {
+	rev := &model.RecordRevision{AuthorID: authorID, Reason: reason}
+	if err := s.repo.UpdateWithAudit(ctx, record, rev); err != nil {
 		return fmt.Errorf("failed to update record: %w", err)
 	}
 
-	s.auditor.Log(ctx, s.getCurrentUserID(ctx), uuid.Nil, "update", "medical_record", record.ID, &audit.LogOptions{
+	s.auditor.Log(ctx, rev.AuthorID, uuid.Nil, "update", "medical_record", record.ID, &audit.LogOptions{
 		AccessLevel: record.AccessLevel,
 		Changes:     record,
+		Metadata:    map[string]interface{}{"version": record.Version, "reason": reason},
 	})
 
 	return nil
 }
 
-func (s *Service) ListMedicalRecords(ctx context.Context, patientID uuid.UUID, filters *model.RecordFilters) ([]*model.MedicalRecord, error) {
+// ListMedicalRecords returns the patient's records the reader may see; the
+// rest are filtered out by the query before anything is decrypted. A nil
+// reader is a system read, such as a patient data export, and sees every
+// record.
+func (s *Service) ListMedicalRecords(ctx context.Context, patientID uuid.UUID, reader *model.RecordReader, filters *model.RecordFilters) ([]*model.MedicalRecord, error) {
+	filters.Access = reader.Access()
 	records, err := s.repo.List(ctx, patientID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list records: %w", err)
@@ -114,19 +193,87 @@ func (s *Service) ListMedicalRecords(ctx context.Context, patientID uuid.UUID, f
 
 	// Decrypt records
 	for _, record := range records {
-		if err := s.decryptSensitiveData(record); err != nil {
+		if err := s.decryptSensitiveData(ctx, record); err != nil {
 			return nil, fmt.Errorf("failed to decrypt record %s: %w", record.ID, err)
 		}
 	}
 
+	if reader != nil {
+		s.auditor.Log(ctx, reader.UserID, uuid.Nil, "read", "patient_medical_records", patientID, &audit.LogOptions{
+			AccessReason: reader.Reason,
+			Metadata:     map[string]interface{}{"count": len(records)},
+		})
+	}
+
 	return records, nil
 }
 
+// CanReadPrivate reports whether the reader may see the patient's private
+// records: the patient themselves, their care team and system reads
+func (s *Service) CanReadPrivate(ctx context.Context, patientID uuid.UUID, reader *model.RecordReader) (bool, error) {
+	if reader == nil || (reader.Self && reader.UserType == model.UserTypePatient) {
+		return true, nil
+	}
+	if reader.UserType == model.UserTypePatient {
+		return false, nil
+	}
+	return s.careTeam.IsMember(ctx, patientID, reader.UserID)
+}
+
+// CheckAccess applies the record's access level to the reader, as reading
+// the record would
+func (s *Service) CheckAccess(ctx context.Context, record *model.MedicalRecord, reader *model.RecordReader) error {
+	return s.authorize(ctx, record, reader)
+}
+
+// authorize checks the record's access level against the reader. Public
+// records are open to anyone who reaches them and every record to the
+// patient themselves; private ones to the patient's care team, and HIPAA
+// ones to the care team with a stated reason. Denied attempts are audited.
+func (s *Service) authorize(ctx context.Context, record *model.MedicalRecord, reader *model.RecordReader) error {
+	if reader == nil || record.AccessLevel == accessLevelPublic {
+		return nil
+	}
+	if reader.Self && reader.UserType == model.UserTypePatient {
+		return nil
+	}
+
+	var denied error
+	switch {
+	case reader.UserType == model.UserTypePatient:
+		denied = ErrAccessDenied
+	case record.AccessLevel == accessLevelHIPAA && strings.TrimSpace(reader.Reason) == "":
+		denied = ErrAccessReasonRequired
+	default:
+		member, err := s.careTeam.IsMember(ctx, record.PatientID, reader.UserID)
+		if err != nil {
+			return err
+		}
+		if !member {
+			denied = ErrAccessDenied
+		}
+	}
+
+	if denied != nil {
+		s.auditor.Log(ctx, reader.UserID, record.OrganizationID, "access_denied", "medical_record", record.ID, &audit.LogOptions{
+			AccessLevel:  record.AccessLevel,
+			AccessReason: reader.Reason,
+			Metadata:     map[string]interface{}{"patient_id": record.PatientID, "denial": denied.Error()},
+		})
+	}
+	return denied
+}
+
 func (s *Service) validateRecord(record *model.MedicalRecord) error {
 	if record.PatientID == uuid.Nil {
 		return fmt.Errorf("patient ID is required")
 	}
 
+	// The organization's data key encrypts the record
+	if record.OrganizationID == uuid.Nil {
+		return fmt.Errorf("organization ID is required")
+	}
+
 	if record.Type == "" {
 		return fmt.Errorf("record type is required")
 	}
@@ -142,14 +289,113 @@ func (s *Service) validateRecord(record *model.MedicalRecord) error {
 	return nil
 }
 
-func (s *Service) encryptSensitiveData(record *model.MedicalRecord) error {
+// codeDiagnosis validates the structured entries of the record's diagnosis
+// against the terminology service and normalizes them in place. A
+// free-text diagnosis has no codes. Entries already in the previous
+// diagnosis were validated when they were added and are kept as they are,
+// so a code retired since does not block other changes to the record.
+func (s *Service) codeDiagnosis(ctx context.Context, record *model.MedicalRecord, previous json.RawMessage) error {
+	record.Codes = []model.CodedEntry{}
+	if len(record.Diagnosis) == 0 {
+		return nil
+	}
+
+	entries, structured, err := model.ParseCodedEntries(record.Diagnosis)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDiagnosis, err)
+	}
+	if !structured || len(entries) == 0 {
+		return nil
+	}
+
+	kept := make(map[model.CodedEntry]bool)
+	if len(previous) > 0 {
+		if old, ok, err := model.ParseCodedEntries(previous); err == nil && ok {
+			for _, e := range old {
+				kept[e] = true
+			}
+		}
+	}
+
+	normalized := make([]model.CodedEntry, len(entries))
+	var changed []model.CodedEntry
+	var changedIdx []int
+	for i, e := range entries {
+		if kept[e] {
+			normalized[i] = e
+			continue
+		}
+		changed = append(changed, e)
+		changedIdx = append(changedIdx, i)
+	}
+
+	if len(changed) > 0 {
+		validated, err := s.terminology.Validate(ctx, changed)
+		if err != nil {
+			var validationErr *terminology.ValidationError
+			if errors.As(err, &validationErr) {
+				for i := range validationErr.Invalid {
+					validationErr.Invalid[i].Index = changedIdx[validationErr.Invalid[i].Index]
+				}
+			}
+			return err
+		}
+		for i, e := range validated {
+			normalized[changedIdx[i]] = e
+		}
+	}
+
+	diagnosis, err := json.Marshal(normalized)
+	if err != nil {
+		return err
+	}
+	record.Diagnosis = diagnosis
+	record.Codes = normalized
+	return nil
+}
+
+// ReportByCode counts records and patients per diagnosis code. ICD-10-CM
+// codes also match their subcodes, so E11 reports every E11.x.
+func (s *Service) ReportByCode(ctx context.Context, filter *model.CodeReportFilter) ([]*model.CodeReportRow, error) {
+	if filter.OrganizationID == uuid.Nil {
+		return nil, fmt.Errorf("organization ID is required")
+	}
+	filter.System = model.CanonicalCodeSystem(filter.System)
+	if filter.System == "" {
+		return nil, terminology.ErrUnknownSystem
+	}
+	if filter.Code != "" {
+		filter.Code = terminology.NormalizeCode(filter.System, filter.Code)
+	}
+
+	rows, err := s.repo.ReportByCode(ctx, filter, filter.System == model.CodeSystemICD10CM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build code report: %w", err)
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), filter.OrganizationID, "report", "medical_record", uuid.Nil, &audit.LogOptions{
+		Metadata: filter,
+	})
+
+	return rows, nil
+}
+
+// encryptSensitiveData seals the record's sensitive fields under its
+// organization's active data key
+func (s *Service) encryptSensitiveData(ctx context.Context, record *model.MedicalRecord) error {
+	keyID, err := s.keys.ActiveKeyID(ctx, record.OrganizationID)
+	if err != nil {
+		return err
+	}
+	record.EncryptionKeyID = &keyID
+
 	if record.Diagnosis != nil {
 		diagnosisJSON, err := json.Marshal(record.Diagnosis)
 		if err != nil {
 			return err
 		}
 
-		encrypted, err := s.encryptor.Encrypt(diagnosisJSON)
+		encrypted, err := s.keys.EncryptWithKey(ctx, keyID, diagnosisJSON)
 		if err != nil {
 			return err
 		}
@@ -162,7 +408,7 @@ func (s *Service) encryptSensitiveData(record *model.MedicalRecord) error {
 			return err
 		}
 
-		encrypted, err := s.encryptor.Encrypt(treatmentJSON)
+		encrypted, err := s.keys.EncryptWithKey(ctx, keyID, treatmentJSON)
 		if err != nil {
 			return err
 		}
@@ -172,9 +418,9 @@ func (s *Service) encryptSensitiveData(record *model.MedicalRecord) error {
 	return nil
 }
 
-func (s *Service) decryptSensitiveData(record *model.MedicalRecord) error {
+func (s *Service) decryptSensitiveData(ctx context.Context, record *model.MedicalRecord) error {
 	if record.Diagnosis != nil {
-		decrypted, err := s.encryptor.Decrypt(record.Diagnosis)
+		decrypted, err := s.keys.Decrypt(ctx, record.Diagnosis)
 		if err != nil {
 			return err
 		}
@@ -182,7 +428,7 @@ func (s *Service) decryptSensitiveData(record *model.MedicalRecord) error {
 	}
 
 	if record.Treatment != nil {
-		decrypted, err := s.encryptor.Decrypt(record.Treatment)
+		decrypted, err := s.keys.Decrypt(ctx, record.Treatment)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,597 @@
+package note
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/keyring"
+)
+
+var (
+	ErrNotFound           = errors.New("clinical note not found")
+	ErrTemplateNotFound   = errors.New("note template not found")
+	ErrTemplateExists     = errors.New("the organization already has a note template with this name")
+	ErrTemplateInactive   = errors.New("note template is no longer in use")
+	ErrInvalidTemplate    = errors.New("invalid note template")
+	ErrInvalidSections    = errors.New("note does not match its template")
+	ErrMissingSections    = errors.New("note is incomplete")
+	ErrNotTemplateManager = errors.New("only administrators can manage note templates")
+	ErrNotClinician       = errors.New("only clinicians can write notes")
+	ErrNotAuthor          = errors.New("only the authoring clinician can change or sign this note")
+	ErrNotDraft           = errors.New("only draft notes can be changed")
+	ErrNotSigned          = errors.New("note must be signed first")
+	ErrNotLocked          = errors.New("addenda can only be added to locked notes")
+	ErrNotCosigner        = errors.New("only a doctor other than the author can co-sign a note")
+	ErrCosignNotNeeded    = errors.New("note does not need a co-signature")
+	ErrCosignRequired     = errors.New("a trainee's note must be co-signed before it is locked")
+	ErrTampered           = errors.New("note content does not match its signature")
+)
+
+// AuditActionSign is the audit action that records a signed note's hash
+const AuditActionSign = "sign"
+
+// clinicians may write notes
+var clinicians = map[string]bool{
+	model.UserTypeDoctor:  true,
+	model.UserTypeNurse:   true,
+	model.UserTypeTrainee: true,
+}
+
+type Service struct {
+	repo        repository.ClinicalNoteRepository
+	patientRepo repository.PatientRepository
+	auditRepo   repository.AuditRepository
+	keys        *keyring.Service
+	auditor     *audit.Service
+}
+
+func NewService(repo repository.ClinicalNoteRepository, patientRepo repository.PatientRepository, auditRepo repository.AuditRepository, keys *keyring.Service, auditor *audit.Service) *Service {
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		auditRepo:   auditRepo,
+		keys:        keys,
+		auditor:     auditor,
+	}
+}
+
+// Create starts a draft note for the patient from one of the patient's
+// organization's templates
+func (s *Service) Create(ctx context.Context, patientID uuid.UUID, authorType string, req *model.CreateNoteRequest) (*model.ClinicalNote, error) {
+	if !clinicians[authorType] {
+		return nil, ErrNotClinician
+	}
+
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+	template, err := s.repo.GetTemplate(ctx, req.TemplateID)
+	if err != nil || template.OrganizationID != patient.OrganizationID {
+		return nil, ErrTemplateNotFound
+	}
+	if !template.Active {
+		return nil, ErrTemplateInactive
+	}
+
+	sections := req.Sections
+	if sections == nil {
+		sections = map[string]json.RawMessage{}
+	}
+	if err := validateSections(template, sections, false); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	note := &model.ClinicalNote{
+		ID:             uuid.New(),
+		PatientID:      patient.ID,
+		OrganizationID: patient.OrganizationID,
+		TemplateID:     template.ID,
+		AuthorID:       s.getCurrentUserID(ctx),
+		Status:         model.NoteStatusDraft,
+		Sections:       sections,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.encryptContent(ctx, note); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, note); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, note.AuthorID, note.OrganizationID, "create", "clinical_note", note.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"patient_id": note.PatientID, "template_id": note.TemplateID},
+	})
+
+	return note, nil
+}
+
+// Get returns a note with its addenda. Drafts are only visible to their
+// author.
+func (s *Service) Get(ctx context.Context, patientID, noteID uuid.UUID) (*model.ClinicalNote, error) {
+	note, err := s.load(ctx, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	addenda, err := s.repo.ListAddenda(ctx, note.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addenda {
+		if err := s.decryptAddendum(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+	note.Addenda = addenda
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), note.OrganizationID, "read", "clinical_note", note.ID, nil)
+
+	return note, nil
+}
+
+// List returns the patient's notes, leaving out other clinicians' drafts
+func (s *Service) List(ctx context.Context, patientID uuid.UUID, status model.NoteStatus) ([]*model.ClinicalNote, error) {
+	notes, err := s.repo.List(ctx, patientID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := s.getCurrentUserID(ctx)
+	visible := make([]*model.ClinicalNote, 0, len(notes))
+	for _, note := range notes {
+		if note.Status == model.NoteStatusDraft && note.AuthorID != userID {
+			continue
+		}
+		if err := s.decryptContent(ctx, note); err != nil {
+			return nil, err
+		}
+		visible = append(visible, note)
+	}
+
+	s.auditor.Log(ctx, userID, uuid.Nil, "read", "patient_clinical_notes", patientID, nil)
+
+	return visible, nil
+}
+
+// Update replaces the sections of the author's draft
+func (s *Service) Update(ctx context.Context, patientID, noteID uuid.UUID, req *model.UpdateNoteRequest) (*model.ClinicalNote, error) {
+	note, err := s.load(ctx, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.AuthorID != s.getCurrentUserID(ctx) {
+		return nil, ErrNotAuthor
+	}
+	if note.Status != model.NoteStatusDraft {
+		return nil, ErrNotDraft
+	}
+
+	template, err := s.GetTemplate(ctx, note.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSections(template, req.Sections, false); err != nil {
+		return nil, err
+	}
+
+	note.Sections = req.Sections
+	note.Version = req.Version
+	if err := s.encryptContent(ctx, note); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, note); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, note.AuthorID, note.OrganizationID, "update", "clinical_note", note.ID, nil)
+
+	return note, nil
+}
+
+// Sign fixes the author's draft. The note is hashed and the hash is written
+// to the audit log, where Verify later checks the note against it. A
+// trainee's note then waits for a co-signature before it can be locked.
+func (s *Service) Sign(ctx context.Context, patientID, noteID uuid.UUID, signerType string) (*model.ClinicalNote, error) {
+	note, err := s.load(ctx, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	userID := s.getCurrentUserID(ctx)
+	if note.AuthorID != userID {
+		return nil, ErrNotAuthor
+	}
+	if note.Status != model.NoteStatusDraft {
+		return nil, ErrNotDraft
+	}
+
+	template, err := s.GetTemplate(ctx, note.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSections(template, note.Sections, true); err != nil {
+		return nil, err
+	}
+
+	// Postgres keeps microseconds; the hash must survive the round trip
+	signedAt := time.Now().UTC().Truncate(time.Microsecond)
+	note.SignedAt = &signedAt
+	hash, err := contentHash(note)
+	if err != nil {
+		return nil, err
+	}
+	note.ContentHash = &hash
+	note.Status = model.NoteStatusSigned
+	note.CosignRequired = signerType == model.UserTypeTrainee
+
+	// The audit entry is the reference copy of the hash, so the note is not
+	// signed without it
+	if err := s.auditor.Log(ctx, userID, note.OrganizationID, AuditActionSign, "clinical_note", note.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"content_hash": hash, "cosign_required": note.CosignRequired},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to audit signature: %w", err)
+	}
+
+	if err := s.repo.Update(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Cosign records a doctor's co-signature on a trainee's signed note
+func (s *Service) Cosign(ctx context.Context, patientID, noteID uuid.UUID, cosignerType string) (*model.ClinicalNote, error) {
+	note, err := s.load(ctx, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	userID := s.getCurrentUserID(ctx)
+	if cosignerType != model.UserTypeDoctor || note.AuthorID == userID {
+		return nil, ErrNotCosigner
+	}
+	if note.Status != model.NoteStatusSigned {
+		return nil, ErrNotSigned
+	}
+	if !note.CosignRequired || note.CosignerID != nil {
+		return nil, ErrCosignNotNeeded
+	}
+	if err := s.checkHash(note); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	note.CosignerID = &userID
+	note.CosignedAt = &now
+	if err := s.repo.Update(ctx, note); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, userID, note.OrganizationID, "cosign", "clinical_note", note.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"content_hash": *note.ContentHash},
+	})
+
+	return note, nil
+}
+
+// Lock closes a signed note. From then on it only takes addenda.
+func (s *Service) Lock(ctx context.Context, patientID, noteID uuid.UUID, lockerType string) (*model.ClinicalNote, error) {
+	note, err := s.load(ctx, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	userID := s.getCurrentUserID(ctx)
+	if note.AuthorID != userID && lockerType != model.UserTypeDoctor {
+		return nil, ErrNotAuthor
+	}
+	if note.Status != model.NoteStatusSigned {
+		return nil, ErrNotSigned
+	}
+	if note.CosignRequired && note.CosignerID == nil {
+		return nil, ErrCosignRequired
+	}
+	if err := s.checkHash(note); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	note.Status = model.NoteStatusLocked
+	note.LockedBy = &userID
+	note.LockedAt = &now
+	if err := s.repo.Update(ctx, note); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, userID, note.OrganizationID, "lock", "clinical_note", note.ID, nil)
+
+	return note, nil
+}
+
+// AddAddendum appends text to a locked note. The addendum is hashed together
+// with the note's hash and audited like a signature.
+func (s *Service) AddAddendum(ctx context.Context, patientID, noteID uuid.UUID, authorType string, req *model.AddAddendumRequest) (*model.NoteAddendum, error) {
+	if !clinicians[authorType] {
+		return nil, ErrNotClinician
+	}
+	note, err := s.load(ctx, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.Status != model.NoteStatusLocked {
+		return nil, ErrNotLocked
+	}
+
+	addendum := &model.NoteAddendum{
+		ID:        uuid.New(),
+		NoteID:    note.ID,
+		AuthorID:  s.getCurrentUserID(ctx),
+		Text:      strings.TrimSpace(req.Text),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if addendum.Text == "" {
+		return nil, fmt.Errorf("%w: addendum text is empty", ErrInvalidSections)
+	}
+	hash, err := addendumHash(note, addendum)
+	if err != nil {
+		return nil, err
+	}
+	addendum.ContentHash = hash
+
+	var keyID uuid.UUID
+	addendum.Content, keyID, err = s.keys.Encrypt(ctx, note.OrganizationID, []byte(addendum.Text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt addendum: %w", err)
+	}
+	addendum.EncryptionKeyID = &keyID
+
+	if err := s.auditor.Log(ctx, addendum.AuthorID, note.OrganizationID, AuditActionSign, "note_addendum", addendum.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"note_id": note.ID, "content_hash": hash},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to audit addendum: %w", err)
+	}
+	if err := s.repo.CreateAddendum(ctx, addendum); err != nil {
+		return nil, err
+	}
+	return addendum, nil
+}
+
+// Verify recomputes the hashes of a signed note and its addenda and compares
+// them with the ones audited when they were signed. A mismatch means the
+// stored content was changed outside the application.
+func (s *Service) Verify(ctx context.Context, patientID, noteID uuid.UUID) (*model.NoteVerification, error) {
+	note, err := s.load(ctx, patientID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.Status == model.NoteStatusDraft {
+		return nil, ErrNotSigned
+	}
+	addenda, err := s.repo.ListAddenda(ctx, note.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uuid.UUID{note.ID}
+	for _, a := range addenda {
+		ids = append(ids, a.ID)
+	}
+	audited, err := s.auditedHashes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.NoteVerification{NoteID: note.ID, AuditedHash: audited[note.ID]}
+	if hash, err := contentHash(note); err == nil {
+		result.ContentHash = hash
+	}
+	result.Valid = result.ContentHash != "" && result.ContentHash == result.AuditedHash &&
+		note.ContentHash != nil && *note.ContentHash == result.AuditedHash
+
+	for _, a := range addenda {
+		v := &model.AddendumVerification{AddendumID: a.ID, AuditedHash: audited[a.ID]}
+		if err := s.decryptAddendum(ctx, a); err == nil {
+			if hash, err := addendumHash(note, a); err == nil {
+				v.ContentHash = hash
+			}
+		}
+		v.Valid = v.ContentHash != "" && v.ContentHash == v.AuditedHash && a.ContentHash == v.AuditedHash
+		result.Addenda = append(result.Addenda, v)
+		result.Valid = result.Valid && v.Valid
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), note.OrganizationID, "verify", "clinical_note", note.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"valid": result.Valid},
+	})
+
+	return result, nil
+}
+
+// ReencryptBatch moves up to limit notes and limit addenda onto their
+// organization's active data key
+func (s *Service) ReencryptBatch(ctx context.Context, limit int) (int, error) {
+	return s.repo.ReencryptBatch(ctx, limit, func(c *model.EncryptedContent) error {
+		plaintext, err := s.keys.Decrypt(ctx, c.Content)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", c.ID, err)
+		}
+		content, keyID, err := s.keys.Encrypt(ctx, c.OrganizationID, plaintext)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", c.ID, err)
+		}
+		c.Content, c.EncryptionKeyID = content, &keyID
+		return nil
+	})
+}
+
+// load returns one of the patient's notes with its content decrypted
+func (s *Service) load(ctx context.Context, patientID, noteID uuid.UUID) (*model.ClinicalNote, error) {
+	note, err := s.repo.Get(ctx, noteID)
+	if err != nil || note.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	if note.Status == model.NoteStatusDraft && note.AuthorID != s.getCurrentUserID(ctx) {
+		return nil, ErrNotFound
+	}
+	if err := s.decryptContent(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// checkHash refuses to co-sign or lock a note whose content no longer
+// matches the hash taken when it was signed
+func (s *Service) checkHash(note *model.ClinicalNote) error {
+	hash, err := contentHash(note)
+	if err != nil {
+		return err
+	}
+	if note.ContentHash == nil || *note.ContentHash != hash {
+		return ErrTampered
+	}
+	return nil
+}
+
+// auditedHashes returns the hash recorded by the latest signing audit entry
+// of each entity
+func (s *Service) auditedHashes(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	logs, err := s.auditRepo.ListByEntityIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[uuid.UUID]string, len(ids))
+	for _, log := range logs {
+		if log.Action != AuditActionSign {
+			continue
+		}
+		if _, seen := hashes[log.EntityID]; seen {
+			continue
+		}
+		var metadata struct {
+			ContentHash string `json:"content_hash"`
+		}
+		if json.Unmarshal(log.Metadata, &metadata) == nil {
+			hashes[log.EntityID] = metadata.ContentHash
+		}
+	}
+	return hashes, nil
+}
+
+// contentHash is the SHA-256 of what the author signed: the note's identity,
+// signing time and sections. Section keys are sorted and values compacted by
+// encoding/json, so the hash does not depend on how the note was stored.
+func contentHash(note *model.ClinicalNote) (string, error) {
+	if note.SignedAt == nil {
+		return "", ErrNotSigned
+	}
+	if note.Sections == nil {
+		return "", nil
+	}
+	signed, err := json.Marshal(struct {
+		NoteID     uuid.UUID                  `json:"note_id"`
+		PatientID  uuid.UUID                  `json:"patient_id"`
+		TemplateID uuid.UUID                  `json:"template_id"`
+		AuthorID   uuid.UUID                  `json:"author_id"`
+		SignedAt   string                     `json:"signed_at"`
+		Sections   map[string]json.RawMessage `json:"sections"`
+	}{
+		NoteID:     note.ID,
+		PatientID:  note.PatientID,
+		TemplateID: note.TemplateID,
+		AuthorID:   note.AuthorID,
+		SignedAt:   note.SignedAt.UTC().Format(time.RFC3339Nano),
+		Sections:   note.Sections,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(signed)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// addendumHash chains the addendum to the note's signed hash
+func addendumHash(note *model.ClinicalNote, a *model.NoteAddendum) (string, error) {
+	if note.ContentHash == nil {
+		return "", ErrNotSigned
+	}
+	signed, err := json.Marshal(struct {
+		AddendumID uuid.UUID `json:"addendum_id"`
+		NoteID     uuid.UUID `json:"note_id"`
+		NoteHash   string    `json:"note_hash"`
+		AuthorID   uuid.UUID `json:"author_id"`
+		CreatedAt  string    `json:"created_at"`
+		Text       string    `json:"text"`
+	}{
+		AddendumID: a.ID,
+		NoteID:     note.ID,
+		NoteHash:   *note.ContentHash,
+		AuthorID:   a.AuthorID,
+		CreatedAt:  a.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Text:       a.Text,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(signed)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *Service) encryptContent(ctx context.Context, note *model.ClinicalNote) error {
+	content, err := json.Marshal(note.Sections)
+	if err != nil {
+		return err
+	}
+	var keyID uuid.UUID
+	note.Content, keyID, err = s.keys.Encrypt(ctx, note.OrganizationID, content)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt note: %w", err)
+	}
+	note.EncryptionKeyID = &keyID
+	return nil
+}
+
+// decryptContent fills in the note's sections. Erasure empties the content
+// but keeps the note, which is then left without sections.
+func (s *Service) decryptContent(ctx context.Context, note *model.ClinicalNote) error {
+	if len(note.Content) == 0 {
+		return nil
+	}
+	content, err := s.keys.Decrypt(ctx, note.Content)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt note: %w", err)
+	}
+	return json.Unmarshal(content, &note.Sections)
+}
+
+func (s *Service) decryptAddendum(ctx context.Context, a *model.NoteAddendum) error {
+	if len(a.Content) == 0 {
+		return nil
+	}
+	text, err := s.keys.Decrypt(ctx, a.Content)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt addendum: %w", err)
+	}
+	a.Text = string(text)
+	return nil
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
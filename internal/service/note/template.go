@@ -0,0 +1,208 @@
+package note
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+// templateManagers may create and change note templates
+var templateManagers = map[string]bool{
+	model.UserTypeAdmin: true,
+}
+
+var sectionKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var sectionTypes = map[model.SectionType]bool{
+	model.SectionTypeText:   true,
+	model.SectionTypeList:   true,
+	model.SectionTypeNumber: true,
+	model.SectionTypeDate:   true,
+}
+
+func (s *Service) CreateTemplate(ctx context.Context, managerType string, req *model.CreateNoteTemplateRequest) (*model.NoteTemplate, error) {
+	if !templateManagers[managerType] {
+		return nil, ErrNotTemplateManager
+	}
+
+	sections := req.Sections
+	if len(sections) == 0 {
+		sections = model.DefaultNoteSections[req.Kind]
+	}
+	if err := validateTemplateSections(sections); err != nil {
+		return nil, err
+	}
+
+	template := &model.NoteTemplate{
+		OrganizationID: req.OrganizationID,
+		Name:           strings.TrimSpace(req.Name),
+		Kind:           req.Kind,
+		Sections:       sections,
+		Active:         true,
+		CreatedBy:      s.getCurrentUserID(ctx),
+	}
+	if err := s.repo.CreateTemplate(ctx, template); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrTemplateExists
+		}
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, template.CreatedBy, template.OrganizationID, "create", "note_template", template.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"name": template.Name, "kind": template.Kind},
+	})
+
+	return template, nil
+}
+
+func (s *Service) GetTemplate(ctx context.Context, id uuid.UUID) (*model.NoteTemplate, error) {
+	template, err := s.repo.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+func (s *Service) ListTemplates(ctx context.Context, orgID uuid.UUID, includeInactive bool) ([]*model.NoteTemplate, error) {
+	return s.repo.ListTemplates(ctx, orgID, !includeInactive)
+}
+
+// UpdateTemplate changes a template for notes written from now on. Existing
+// notes keep their content; signed ones keep their hash.
+func (s *Service) UpdateTemplate(ctx context.Context, id uuid.UUID, managerType string, req *model.UpdateNoteTemplateRequest) (*model.NoteTemplate, error) {
+	if !templateManagers[managerType] {
+		return nil, ErrNotTemplateManager
+	}
+
+	template, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		template.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Sections != nil {
+		if err := validateTemplateSections(req.Sections); err != nil {
+			return nil, err
+		}
+		template.Sections = req.Sections
+	}
+	if req.Active != nil {
+		template.Active = *req.Active
+	}
+
+	if err := s.repo.UpdateTemplate(ctx, template); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrTemplateExists
+		}
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), template.OrganizationID, "update", "note_template", template.ID, &audit.LogOptions{
+		Changes: req,
+	})
+
+	return template, nil
+}
+
+func validateTemplateSections(sections []model.NoteSection) error {
+	if len(sections) == 0 {
+		return fmt.Errorf("%w: a template needs at least one section", ErrInvalidTemplate)
+	}
+	seen := make(map[string]bool, len(sections))
+	for _, section := range sections {
+		if !sectionKeyPattern.MatchString(section.Key) {
+			return fmt.Errorf("%w: section key %q must be lowercase letters, digits and underscores", ErrInvalidTemplate, section.Key)
+		}
+		if seen[section.Key] {
+			return fmt.Errorf("%w: section key %q is used twice", ErrInvalidTemplate, section.Key)
+		}
+		seen[section.Key] = true
+		if !sectionTypes[section.Type] {
+			return fmt.Errorf("%w: section %q has unknown type %q", ErrInvalidTemplate, section.Key, section.Type)
+		}
+	}
+	return nil
+}
+
+// validateSections checks note content against its template. Drafts may leave
+// sections out; complete also requires every required section to be filled.
+// Null values are dropped.
+func validateSections(template *model.NoteTemplate, sections map[string]json.RawMessage, complete bool) error {
+	defined := make(map[string]model.NoteSection, len(template.Sections))
+	for _, section := range template.Sections {
+		defined[section.Key] = section
+	}
+
+	for key, value := range sections {
+		section, ok := defined[key]
+		if !ok {
+			return fmt.Errorf("%w: the template has no section %q", ErrInvalidSections, key)
+		}
+		if string(value) == "null" {
+			delete(sections, key)
+			continue
+		}
+		if err := checkSectionValue(section, value); err != nil {
+			return err
+		}
+	}
+
+	if complete {
+		for _, section := range template.Sections {
+			if section.Required && sectionEmpty(sections[section.Key]) {
+				return fmt.Errorf("%w: section %q is required", ErrMissingSections, section.Key)
+			}
+		}
+	}
+	return nil
+}
+
+func checkSectionValue(section model.NoteSection, value json.RawMessage) error {
+	var err error
+	switch section.Type {
+	case model.SectionTypeText:
+		var text string
+		err = json.Unmarshal(value, &text)
+	case model.SectionTypeList:
+		var items []string
+		err = json.Unmarshal(value, &items)
+	case model.SectionTypeNumber:
+		var number float64
+		err = json.Unmarshal(value, &number)
+	case model.SectionTypeDate:
+		var date string
+		if err = json.Unmarshal(value, &date); err == nil {
+			_, err = time.Parse("2006-01-02", date)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: section %q must be a %s", ErrInvalidSections, section.Key, section.Type)
+	}
+	return nil
+}
+
+func sectionEmpty(value json.RawMessage) bool {
+	if len(value) == 0 {
+		return true
+	}
+	var text string
+	if json.Unmarshal(value, &text) == nil {
+		return strings.TrimSpace(text) == ""
+	}
+	var items []string
+	if json.Unmarshal(value, &items) == nil {
+		return len(items) == 0
+	}
+	return false
+}
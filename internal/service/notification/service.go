@@ -27,18 +27,26 @@ type Service interface {
 	Send(ctx context.Context, notification *model.Notification) error
 }
 
+// ProxyRecipients finds the users, such as a parent or a personal
+// representative, who receive a patient's notifications on their behalf
+type ProxyRecipients interface {
+	NotificationRecipients(ctx context.Context, patientID uuid.UUID) ([]*model.User, error)
+}
+
 type service struct {
 	repo     repository.NotificationRepository
 	emailSvc email.Service
 	broker   messaging.Broker
+	proxies  ProxyRecipients
 	auditor  *audit.Service
 }
 
-func NewService(repo repository.NotificationRepository, emailSvc email.Service, broker messaging.Broker, auditor *audit.Service) Service {
+func NewService(repo repository.NotificationRepository, emailSvc email.Service, broker messaging.Broker, proxies ProxyRecipients, auditor *audit.Service) Service {
 	return &service{
 		repo:     repo,
 		emailSvc: emailSvc,
 		broker:   broker,
+		proxies:  proxies,
 		auditor:  auditor,
 	}
 }
@@ -64,9 +72,64 @@ func (s *service) Send(ctx context.Context, notification *model.Notification) er
 	// Process notification asynchronously
 	go s.processNotification(ctx, notification)
 
+	if notification.PatientID != nil && s.proxies != nil {
+		s.sendToProxies(ctx, notification)
+	}
+
 	return nil
 }
 
+// sendToProxies sends a copy of the patient's notification to each proxy
+// allowed to receive it. A failed copy does not fail the patient's own.
+func (s *service) sendToProxies(ctx context.Context, notification *model.Notification) {
+	users, err := s.proxies.NotificationRecipients(ctx, *notification.PatientID)
+	if err != nil {
+		s.auditor.Log(ctx, notification.UserID, notification.OrganizationID, "proxy_failed", "notification", notification.ID, &audit.LogOptions{
+			Metadata: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+		return
+	}
+
+	for _, user := range users {
+		var recipient string
+		switch notification.Channel {
+		case channelEmail:
+			recipient = user.Email
+		case channelSMS:
+			recipient = user.Phone
+		default:
+			recipient = user.ID.String()
+		}
+		if recipient == "" || user.ID == notification.UserID {
+			continue
+		}
+
+		proxied := *notification
+		proxied.ID = uuid.New()
+		proxied.UserID = user.ID
+		proxied.Recipient = recipient
+		if err := s.repo.Create(ctx, &proxied); err != nil {
+			s.auditor.Log(ctx, user.ID, notification.OrganizationID, "proxy_failed", "notification", notification.ID, &audit.LogOptions{
+				Metadata: map[string]interface{}{
+					"error": err.Error(),
+				},
+			})
+			continue
+		}
+
+		s.auditor.Log(ctx, user.ID, proxied.OrganizationID, "create", "notification", proxied.ID, &audit.LogOptions{
+			Metadata: map[string]interface{}{
+				"on_behalf_of": *notification.PatientID,
+				"source_id":    notification.ID,
+			},
+		})
+
+		go s.processNotification(ctx, &proxied)
+	}
+}
+
 func (s *service) processNotification(ctx context.Context, notification *model.Notification) {
 	if ctx == nil {
 		ctx = context.Background()
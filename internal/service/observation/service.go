@@ -0,0 +1,441 @@
+package observation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/pkg/ucum"
+)
+
+var (
+	ErrNotFound            = errors.New("observation not found")
+	ErrRangeNotFound       = errors.New("reference range not found")
+	ErrNotClinician        = errors.New("only clinicians can record observations")
+	ErrNotAdmin            = errors.New("only administrators can change reference ranges")
+	ErrUnknownType         = errors.New("unknown observation type")
+	ErrInvalidUnit         = errors.New("unit is not valid for this observation type")
+	ErrImplausibleValue    = errors.New("value is outside the plausible range for this observation type")
+	ErrFutureObservation   = errors.New("observations cannot be recorded in the future")
+	ErrAppointmentMismatch = errors.New("appointment does not belong to the patient")
+	ErrAlreadyInError      = errors.New("observation is already marked entered in error")
+	ErrInvalidRange        = errors.New("reference range bounds are out of order")
+	ErrDefaultRange        = errors.New("default reference ranges cannot be deleted")
+	ErrInvalidWindow       = errors.New("trend window must end after it starts")
+	ErrTooManyPoints       = errors.New("trend interval is too small for the window")
+)
+
+var clinicians = map[string]bool{
+	model.UserTypeDoctor: true,
+	model.UserTypeNurse:  true,
+}
+
+const (
+	// defaultTrendWindow is how far back a trend reaches without a start
+	defaultTrendWindow = 90 * 24 * time.Hour
+	// targetTrendPoints is about how many points an automatic interval gives
+	targetTrendPoints = 120
+	// maxTrendPoints bounds an explicitly requested interval
+	maxTrendPoints = 2000
+	// clockSkew tolerates devices whose clocks run slightly ahead
+	clockSkew = 5 * time.Minute
+)
+
+// trendIntervals are the bucket sizes an automatic interval is chosen from
+var trendIntervals = []time.Duration{
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+}
+
+type Service struct {
+	repo            repository.ObservationRepository
+	patientRepo     repository.PatientRepository
+	appointmentRepo repository.AppointmentRepository
+	auditor         *audit.Service
+}
+
+func NewService(repo repository.ObservationRepository, patientRepo repository.PatientRepository, appointmentRepo repository.AppointmentRepository, auditor *audit.Service) *Service {
+	return &Service{
+		repo:            repo,
+		patientRepo:     patientRepo,
+		appointmentRepo: appointmentRepo,
+		auditor:         auditor,
+	}
+}
+
+// Types lists the observation types that can be recorded
+func (s *Service) Types() []model.ObservationDefinition {
+	types := make([]model.ObservationDefinition, 0, len(model.ObservationDefinitions))
+	for _, def := range model.ObservationDefinitions {
+		types = append(types, def)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Type < types[j].Type })
+	return types
+}
+
+// Record saves a set of readings taken together. Each value is converted to
+// its type's unit and flagged against the reference range for the patient's
+// sex and age when it was taken.
+func (s *Service) Record(ctx context.Context, patientID uuid.UUID, userType string, req *model.RecordObservationsRequest) ([]*model.Observation, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	if req.AppointmentID != nil {
+		appointment, err := s.appointmentRepo.Get(ctx, *req.AppointmentID)
+		if err != nil || appointment.PatientID != patientID {
+			return nil, ErrAppointmentMismatch
+		}
+	}
+
+	observedAt := time.Now().UTC()
+	if req.ObservedAt != nil {
+		if req.ObservedAt.After(observedAt.Add(clockSkew)) {
+			return nil, ErrFutureObservation
+		}
+		observedAt = req.ObservedAt.UTC()
+	}
+
+	ranges, err := s.repo.ListReferenceRanges(ctx, &patient.OrganizationID, "")
+	if err != nil {
+		return nil, err
+	}
+	sex := strings.ToLower(patient.Gender)
+	age := ageInMonths(patient.DateOfBirth, observedAt)
+
+	recordedBy := s.getCurrentUserID(ctx)
+	observations := make([]*model.Observation, 0, len(req.Readings))
+	for i, reading := range req.Readings {
+		def, ok := model.ObservationDefinitions[reading.Type]
+		if !ok {
+			return nil, fmt.Errorf("reading %d: %w: %q", i, ErrUnknownType, reading.Type)
+		}
+		value, unit, err := normalize(def, reading)
+		if err != nil {
+			return nil, fmt.Errorf("reading %d: %w", i, err)
+		}
+
+		o := &model.Observation{
+			PatientID:      patientID,
+			OrganizationID: patient.OrganizationID,
+			AppointmentID:  req.AppointmentID,
+			Type:           def.Type,
+			Code:           def.Code.Code,
+			Value:          value,
+			Unit:           def.Unit,
+			OriginalValue:  reading.Value,
+			OriginalUnit:   unit,
+			Status:         model.ObservationStatusFinal,
+			ObservedAt:     observedAt,
+			RecordedBy:     recordedBy,
+		}
+		if rng := selectRange(ranges, def.Type, sex, age); rng != nil {
+			flag := flagValue(rng, value)
+			o.Flag = &flag
+			o.ReferenceLow = rng.Low
+			o.ReferenceHigh = rng.High
+		}
+		observations = append(observations, o)
+	}
+
+	if err := s.repo.CreateBatch(ctx, observations); err != nil {
+		return nil, err
+	}
+
+	for _, o := range observations {
+		s.auditor.Log(ctx, recordedBy, o.OrganizationID, "create", "observation", o.ID, &audit.LogOptions{
+			Changes: o,
+		})
+	}
+
+	return observations, nil
+}
+
+// normalize converts a reading to its type's unit, returning the value and
+// the UCUM code of the unit it was given in
+func normalize(def model.ObservationDefinition, reading model.ObservationReading) (float64, string, error) {
+	unit := def.Unit
+	if strings.TrimSpace(reading.Unit) != "" {
+		parsed, err := ucum.Parse(reading.Unit)
+		if err != nil {
+			return 0, "", fmt.Errorf("%w: %q", ErrInvalidUnit, reading.Unit)
+		}
+		unit = parsed
+	}
+
+	value, err := ucum.ConvertSubstance(reading.Value, unit, def.Unit, def.MolarMass)
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: %s is not convertible to %s", ErrInvalidUnit, unit, def.Unit)
+	}
+	if value < def.ValidMin || value > def.ValidMax {
+		return 0, "", fmt.Errorf("%w: %g %s", ErrImplausibleValue, value, def.Unit)
+	}
+	return value, unit, nil
+}
+
+// selectRange picks the range that applies to a patient. An organization's
+// own ranges win over the defaults, then a range for the patient's sex wins
+// over one for either sex, then the narrower age band wins.
+func selectRange(ranges []*model.ReferenceRange, obsType model.ObservationType, sex string, ageMonths int) *model.ReferenceRange {
+	var best *model.ReferenceRange
+	for _, rng := range ranges {
+		if rng.Type != obsType || !rng.Matches(sex, ageMonths) {
+			continue
+		}
+		if best == nil || moreSpecific(rng, best) {
+			best = rng
+		}
+	}
+	return best
+}
+
+func moreSpecific(a, b *model.ReferenceRange) bool {
+	if (a.OrganizationID != nil) != (b.OrganizationID != nil) {
+		return a.OrganizationID != nil
+	}
+	if (a.Sex != "") != (b.Sex != "") {
+		return a.Sex != ""
+	}
+	return ageSpan(a) < ageSpan(b)
+}
+
+func ageSpan(r *model.ReferenceRange) int {
+	if r.MaxAgeMonths == nil {
+		// Open-ended bands are the widest
+		return int(^uint(0) >> 1)
+	}
+	return *r.MaxAgeMonths - r.MinAgeMonths
+}
+
+func flagValue(rng *model.ReferenceRange, value float64) model.ObservationFlag {
+	switch {
+	case rng.CriticalLow != nil && value <= *rng.CriticalLow:
+		return model.ObservationFlagCriticalLow
+	case rng.CriticalHigh != nil && value >= *rng.CriticalHigh:
+		return model.ObservationFlagCriticalHigh
+	case rng.Low != nil && value < *rng.Low:
+		return model.ObservationFlagLow
+	case rng.High != nil && value > *rng.High:
+		return model.ObservationFlagHigh
+	}
+	return model.ObservationFlagNormal
+}
+
+func ageInMonths(birth, at time.Time) int {
+	months := (at.Year()-birth.Year())*12 + int(at.Month()) - int(birth.Month())
+	if at.Day() < birth.Day() {
+		months--
+	}
+	if months < 0 {
+		return 0
+	}
+	return months
+}
+
+func (s *Service) Get(ctx context.Context, patientID, observationID uuid.UUID) (*model.Observation, error) {
+	o, err := s.repo.Get(ctx, observationID)
+	if err != nil || o.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	return o, nil
+}
+
+func (s *Service) List(ctx context.Context, patientID uuid.UUID, filter *model.ObservationFilter) ([]*model.Observation, error) {
+	if filter.Type != "" {
+		if _, ok := model.ObservationDefinitions[filter.Type]; !ok {
+			return nil, ErrUnknownType
+		}
+	}
+	return s.repo.List(ctx, patientID, filter)
+}
+
+// EnterInError retracts an observation that was recorded wrongly. It stays
+// in the patient's history but leaves lists and trends.
+func (s *Service) EnterInError(ctx context.Context, patientID, observationID uuid.UUID, userType string, req *model.EnteredInErrorRequest) (*model.Observation, error) {
+	if !clinicians[userType] {
+		return nil, ErrNotClinician
+	}
+
+	o, err := s.Get(ctx, patientID, observationID)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status == model.ObservationStatusEnteredInError {
+		return nil, ErrAlreadyInError
+	}
+
+	o.Status = model.ObservationStatusEnteredInError
+	o.StatusReason = &req.Reason
+	if err := s.repo.UpdateStatus(ctx, o); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), o.OrganizationID, "update", "observation", o.ID, &audit.LogOptions{
+		Changes: map[string]interface{}{
+			"status": o.Status,
+			"reason": req.Reason,
+		},
+	})
+
+	return o, nil
+}
+
+// Trend downsamples a patient's observations of one type for charting. The
+// window defaults to the last 90 days. Without an interval one is chosen
+// that gives about 120 points.
+func (s *Service) Trend(ctx context.Context, patientID uuid.UUID, obsType model.ObservationType, from, to *time.Time, interval time.Duration) (*model.ObservationTrend, error) {
+	def, ok := model.ObservationDefinitions[obsType]
+	if !ok {
+		return nil, ErrUnknownType
+	}
+
+	end := time.Now().UTC()
+	if to != nil {
+		end = to.UTC()
+	}
+	start := end.Add(-defaultTrendWindow)
+	if from != nil {
+		start = from.UTC()
+	}
+	if !end.After(start) {
+		return nil, ErrInvalidWindow
+	}
+
+	window := end.Sub(start)
+	if interval <= 0 {
+		interval = autoInterval(window)
+	} else if window/interval > maxTrendPoints {
+		return nil, ErrTooManyPoints
+	}
+
+	points, err := s.repo.Trend(ctx, patientID, obsType, start, end, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ObservationTrend{
+		Type:     obsType,
+		Unit:     def.Unit,
+		From:     start,
+		To:       end,
+		Interval: interval.String(),
+		Points:   points,
+	}, nil
+}
+
+func autoInterval(window time.Duration) time.Duration {
+	for _, interval := range trendIntervals {
+		if window/interval <= targetTrendPoints {
+			return interval
+		}
+	}
+	return trendIntervals[len(trendIntervals)-1]
+}
+
+// ListReferenceRanges lists the default ranges and the organization's own
+func (s *Service) ListReferenceRanges(ctx context.Context, organizationID uuid.UUID, obsType model.ObservationType) ([]*model.ReferenceRange, error) {
+	return s.repo.ListReferenceRanges(ctx, &organizationID, obsType)
+}
+
+// CreateReferenceRange adds a range of the organization's own. It applies
+// to observations recorded from now on.
+func (s *Service) CreateReferenceRange(ctx context.Context, organizationID uuid.UUID, userType string, req *model.CreateReferenceRangeRequest) (*model.ReferenceRange, error) {
+	if userType != model.UserTypeAdmin {
+		return nil, ErrNotAdmin
+	}
+	if _, ok := model.ObservationDefinitions[req.Type]; !ok {
+		return nil, ErrUnknownType
+	}
+	if !ordered(req.CriticalLow, req.Low, req.High, req.CriticalHigh) ||
+		(req.MaxAgeMonths != nil && *req.MaxAgeMonths <= req.MinAgeMonths) {
+		return nil, ErrInvalidRange
+	}
+
+	rng := &model.ReferenceRange{
+		OrganizationID: &organizationID,
+		Type:           req.Type,
+		Sex:            req.Sex,
+		MinAgeMonths:   req.MinAgeMonths,
+		MaxAgeMonths:   req.MaxAgeMonths,
+		Low:            req.Low,
+		High:           req.High,
+		CriticalLow:    req.CriticalLow,
+		CriticalHigh:   req.CriticalHigh,
+	}
+	if err := s.repo.CreateReferenceRange(ctx, rng); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "create", "reference_range", rng.ID, &audit.LogOptions{
+		Changes: rng,
+	})
+
+	return rng, nil
+}
+
+// ordered reports whether the bounds that are set ascend
+func ordered(bounds ...*float64) bool {
+	var prev *float64
+	for _, b := range bounds {
+		if b == nil {
+			continue
+		}
+		if prev != nil && *b < *prev {
+			return false
+		}
+		prev = b
+	}
+	return true
+}
+
+func (s *Service) DeleteReferenceRange(ctx context.Context, organizationID, rangeID uuid.UUID, userType string) error {
+	if userType != model.UserTypeAdmin {
+		return ErrNotAdmin
+	}
+
+	rng, err := s.repo.GetReferenceRange(ctx, rangeID)
+	if err != nil {
+		return ErrRangeNotFound
+	}
+	if rng.OrganizationID == nil {
+		return ErrDefaultRange
+	}
+	if *rng.OrganizationID != organizationID {
+		return ErrRangeNotFound
+	}
+
+	if err := s.repo.DeleteReferenceRange(ctx, rangeID); err != nil {
+		return err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "delete", "reference_range", rangeID, nil)
+	return nil
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
@@ -3,12 +3,14 @@ package patient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jwalitptl/admin-api/internal/model"
 	"github.com/jwalitptl/admin-api/internal/repository"
 	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/identifier"
 
 	"github.com/google/uuid"
 )
@@ -29,13 +31,15 @@ type Service struct {
 	auditor         *audit.Service
 	medicalRepo     repository.MedicalRecordRepository
 	appointmentRepo repository.AppointmentRepository
+	identifiers     *identifier.Service
 }
 
-func NewService(repo repository.PatientRepository, medicalRepo repository.MedicalRecordRepository, appointmentRepo repository.AppointmentRepository, auditor *audit.Service) *Service {
+func NewService(repo repository.PatientRepository, medicalRepo repository.MedicalRecordRepository, appointmentRepo repository.AppointmentRepository, identifiers *identifier.Service, auditor *audit.Service) *Service {
 	return &Service{
 		repo:            repo,
 		medicalRepo:     medicalRepo,
 		appointmentRepo: appointmentRepo,
+		identifiers:     identifiers,
 		auditor:         auditor,
 	}
 }
@@ -55,13 +59,35 @@ func (s *Service) CreatePatient(ctx context.Context, patient *model.Patient) err
 		return fmt.Errorf("failed to marshal JSON fields: %w", err)
 	}
 
+	// The MRN is saved with the patient, so a patient is never registered
+	// without one
+	mrn, err := s.identifiers.NextMRN(ctx, patient)
+	if err != nil {
+		return fmt.Errorf("failed to assign MRN: %w", err)
+	}
+	patient.Identifiers = nil
+	if mrn != nil {
+		patient.Identifiers = append(patient.Identifiers, mrn)
+	}
+
 	if err := s.repo.Create(ctx, patient); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) && mrn != nil {
+			return fmt.Errorf("%w: %s", identifier.ErrIdentifierTaken, mrn.Value)
+		}
 		return fmt.Errorf("failed to create patient: %w", err)
 	}
 
 	s.auditor.Log(ctx, s.getCurrentUserID(ctx), patient.OrganizationID, "create", "patient", patient.ID, &audit.LogOptions{
 		Changes: patient,
 	})
+	if mrn != nil {
+		s.auditor.Log(ctx, mrn.CreatedBy, mrn.OrganizationID, "assign_mrn", "patient", patient.ID, &audit.LogOptions{
+			Metadata: map[string]interface{}{
+				"system": mrn.System,
+				"value":  mrn.Value,
+			},
+		})
+	}
 
 	return nil
 }
@@ -77,6 +103,10 @@ func (s *Service) GetPatient(ctx context.Context, id uuid.UUID) (*model.Patient,
 		return nil, fmt.Errorf("failed to unmarshal JSON fields: %w", err)
 	}
 
+	if patient.Identifiers, err = s.identifiers.List(ctx, id); err != nil {
+		return nil, err
+	}
+
 	s.auditor.Log(ctx, s.getCurrentUserID(ctx), patient.OrganizationID, "read", "patient", id, nil)
 	return patient, nil
 }
@@ -159,6 +189,12 @@ func (s *Service) validatePatient(patient *model.Patient) error {
 		return fmt.Errorf("date of birth is required")
 	}
 
+	if patient.Timezone != "" {
+		if _, err := time.LoadLocation(patient.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", patient.Timezone)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,128 @@
+package prescription
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/service/terminology"
+)
+
+// Interaction datasets are CSV files with a header row:
+//
+//	drug-drug:    drug_a,drug_b,severity,description
+//	drug-allergy: allergy_system,allergy_code,drug_code,severity,description
+//
+// Drugs are RxNorm codes. Allergy codes are matched against the codes of the
+// patient's structured diagnoses, so they use the same systems (for example
+// SNOMED CT 91936005 or ICD-10-CM Z88.0).
+
+var severities = map[string]bool{
+	"minor":           true,
+	"moderate":        true,
+	"major":           true,
+	"contraindicated": true,
+}
+
+func readDrugInteractions(r io.Reader) ([]*model.DrugInteraction, error) {
+	var interactions []*model.DrugInteraction
+	seen := make(map[[2]string]bool)
+	err := readDataset(r, []string{"drug_a", "drug_b", "severity", "description"}, func(line int, f map[string]string) error {
+		a, b := f["drug_a"], f["drug_b"]
+		if a == "" || b == "" || a == b {
+			return fmt.Errorf("line %d: two different drugs are required", line)
+		}
+		if a > b {
+			a, b = b, a
+		}
+		if seen[[2]string{a, b}] {
+			return fmt.Errorf("line %d: duplicate pair %s, %s", line, a, b)
+		}
+		seen[[2]string{a, b}] = true
+		interactions = append(interactions, &model.DrugInteraction{
+			DrugA:       a,
+			DrugB:       b,
+			Severity:    f["severity"],
+			Description: f["description"],
+		})
+		return nil
+	})
+	return interactions, err
+}
+
+func readAllergyInteractions(r io.Reader) ([]*model.DrugAllergyInteraction, error) {
+	var interactions []*model.DrugAllergyInteraction
+	seen := make(map[[3]string]bool)
+	columns := []string{"allergy_system", "allergy_code", "drug_code", "severity", "description"}
+	err := readDataset(r, columns, func(line int, f map[string]string) error {
+		system := model.CanonicalCodeSystem(f["allergy_system"])
+		if system == "" {
+			return fmt.Errorf("line %d: unknown code system %q", line, f["allergy_system"])
+		}
+		if f["allergy_code"] == "" || f["drug_code"] == "" {
+			return fmt.Errorf("line %d: allergy code and drug code are required", line)
+		}
+		code := terminology.NormalizeCode(system, f["allergy_code"])
+		key := [3]string{system, code, f["drug_code"]}
+		if seen[key] {
+			return fmt.Errorf("line %d: duplicate entry %s %s", line, code, f["drug_code"])
+		}
+		seen[key] = true
+
+		interactions = append(interactions, &model.DrugAllergyInteraction{
+			AllergySystem: system,
+			AllergyCode:   code,
+			DrugCode:      f["drug_code"],
+			Severity:      f["severity"],
+			Description:   f["description"],
+		})
+		return nil
+	})
+	return interactions, err
+}
+
+// readDataset reads a CSV dataset by header name and checks the severity of
+// every row
+func readDataset(r io.Reader, columns []string, row func(line int, fields map[string]string) error) error {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range columns {
+		if _, ok := col[name]; !ok {
+			return fmt.Errorf("missing column %s", name)
+		}
+	}
+
+	for line := 2; ; line++ {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fields := make(map[string]string, len(columns))
+		for _, name := range columns {
+			if i := col[name]; i < len(rec) {
+				fields[name] = strings.TrimSpace(rec[i])
+			}
+		}
+		fields["severity"] = strings.ToLower(fields["severity"])
+		if !severities[fields["severity"]] {
+			return fmt.Errorf("line %d: unknown severity %q", line, fields["severity"])
+		}
+		if err := row(line, fields); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package prescription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/pkg/storage"
+)
+
+var (
+	ErrNotFound          = errors.New("prescription not found")
+	ErrNotPrescriber     = errors.New("only doctors can prescribe or change prescriptions")
+	ErrInvalidTransition = errors.New("prescription status cannot change this way")
+	ErrReasonRequired    = errors.New("a reason is required to discontinue a prescription")
+	ErrInvalidDates      = errors.New("stop date must not be before start date")
+	ErrUnknownDataset    = errors.New("unknown interaction dataset kind")
+	ErrInvalidFile       = errors.New("dataset file must be inside the dataset directory")
+)
+
+// WarningsError is returned when a prescription has interaction warnings the
+// prescriber has not acknowledged. Nothing is saved until they are.
+type WarningsError struct {
+	Warnings []*model.InteractionWarning
+}
+
+func (e *WarningsError) Error() string {
+	return fmt.Sprintf("%d interaction warning(s) must be acknowledged", len(e.Warnings))
+}
+
+// transitions lists the statuses each status may move to. Discontinued is
+// final; a paused prescription may be resumed.
+var transitions = map[model.PrescriptionStatus][]model.PrescriptionStatus{
+	model.PrescriptionStatusActive:       {model.PrescriptionStatusPaused, model.PrescriptionStatusDiscontinued},
+	model.PrescriptionStatusPaused:       {model.PrescriptionStatusActive, model.PrescriptionStatusDiscontinued},
+	model.PrescriptionStatusDiscontinued: {},
+}
+
+type Config struct {
+	// DatasetDir holds the interaction dataset files that can be loaded
+	DatasetDir string
+}
+
+type Service struct {
+	repo        repository.PrescriptionRepository
+	patientRepo repository.PatientRepository
+	auditor     *audit.Service
+	config      Config
+}
+
+func NewService(repo repository.PrescriptionRepository, patientRepo repository.PatientRepository, auditor *audit.Service, config Config) *Service {
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		auditor:     auditor,
+		config:      config,
+	}
+}
+
+// CheckInteractions returns the drug-drug and drug-allergy warnings for
+// prescribing a drug to the patient
+func (s *Service) CheckInteractions(ctx context.Context, patientID uuid.UUID, drugCode string) ([]*model.InteractionWarning, error) {
+	drugCode = strings.TrimSpace(drugCode)
+
+	drugs, err := s.repo.FindDrugInteractions(ctx, patientID, drugCode)
+	if err != nil {
+		return nil, err
+	}
+	allergies, err := s.repo.FindAllergyInteractions(ctx, patientID, drugCode)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := make([]*model.InteractionWarning, 0, len(drugs)+len(allergies))
+	for _, w := range drugs {
+		w.Type = model.InteractionTypeDrugDrug
+		w.Key = fmt.Sprintf("%s:%s:%s", w.Type, drugCode, w.InteractsWith)
+		warnings = append(warnings, w)
+	}
+	for _, w := range allergies {
+		w.Type = model.InteractionTypeDrugAllergy
+		w.Key = fmt.Sprintf("%s:%s:%s", w.Type, drugCode, w.InteractsWith)
+		warnings = append(warnings, w)
+	}
+	return warnings, nil
+}
+
+// Prescribe creates a prescription after checking it for interactions. Every
+// warning must be listed in the request's acknowledged warnings, otherwise a
+// WarningsError with the outstanding warnings is returned.
+func (s *Service) Prescribe(ctx context.Context, patientID uuid.UUID, prescriberType string, req *model.CreatePrescriptionRequest) (*model.Prescription, error) {
+	if prescriberType != model.UserTypeDoctor {
+		return nil, ErrNotPrescriber
+	}
+
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	if req.StopDate != nil && req.StopDate.Before(start) {
+		return nil, ErrInvalidDates
+	}
+
+	drugCode := strings.TrimSpace(req.DrugCode)
+	warnings, err := s.CheckInteractions(ctx, patientID, drugCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check interactions: %w", err)
+	}
+
+	if outstanding := unacknowledged(warnings, req.AcknowledgedWarnings); len(outstanding) > 0 {
+		return nil, &WarningsError{Warnings: outstanding}
+	}
+
+	prescription := &model.Prescription{
+		PatientID:      patient.ID,
+		OrganizationID: patient.OrganizationID,
+		PrescriberID:   s.getCurrentUserID(ctx),
+		DrugSystem:     model.DrugSystemRxNorm,
+		DrugCode:       drugCode,
+		DrugName:       strings.TrimSpace(req.DrugName),
+		Strength:       req.Strength,
+		Route:          req.Route,
+		Frequency:      req.Frequency,
+		Quantity:       req.Quantity,
+		Refills:        req.Refills,
+		Instructions:   req.Instructions,
+		StartDate:      start,
+		StopDate:       req.StopDate,
+		Status:         model.PrescriptionStatusActive,
+	}
+	if len(warnings) > 0 {
+		if prescription.AcknowledgedWarnings, err = json.Marshal(warnings); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Create(ctx, prescription); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, prescription.PrescriberID, prescription.OrganizationID, "create", "prescription", prescription.ID, &audit.LogOptions{
+		Changes: prescription,
+	})
+
+	return prescription, nil
+}
+
+func (s *Service) Get(ctx context.Context, patientID, prescriptionID uuid.UUID) (*model.Prescription, error) {
+	prescription, err := s.repo.Get(ctx, prescriptionID)
+	if err != nil || prescription.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	return prescription, nil
+}
+
+func (s *Service) List(ctx context.Context, patientID uuid.UUID, status model.PrescriptionStatus) ([]*model.Prescription, error) {
+	return s.repo.ListByPatient(ctx, patientID, status)
+}
+
+// UpdateStatus pauses, resumes or discontinues a prescription. Discontinuing
+// needs a reason and ends the prescription today unless it already ended.
+// Resuming checks the prescription for interactions again, since the
+// patient's drugs and allergies may have changed while it was paused.
+func (s *Service) UpdateStatus(ctx context.Context, patientID, prescriptionID uuid.UUID, editorType string, req *model.UpdatePrescriptionStatusRequest) (*model.Prescription, error) {
+	if editorType != model.UserTypeDoctor {
+		return nil, ErrNotPrescriber
+	}
+
+	prescription, err := s.Get(ctx, patientID, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := false
+	for _, next := range transitions[prescription.Status] {
+		allowed = allowed || next == req.Status
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, prescription.Status, req.Status)
+	}
+	if req.Status == model.PrescriptionStatusDiscontinued {
+		if req.Reason == nil || strings.TrimSpace(*req.Reason) == "" {
+			return nil, ErrReasonRequired
+		}
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		if prescription.StopDate == nil || prescription.StopDate.After(today) {
+			prescription.StopDate = &today
+		}
+	}
+	if req.Status == model.PrescriptionStatusActive {
+		if err := s.recheckInteractions(ctx, prescription, req.AcknowledgedWarnings); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := prescription.Status
+	prescription.Status = req.Status
+	prescription.StatusReason = req.Reason
+
+	if err := s.repo.UpdateStatus(ctx, prescription); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), prescription.OrganizationID, "update", "prescription", prescription.ID, &audit.LogOptions{
+		Changes: map[string]interface{}{
+			"status": map[string]interface{}{"from": previous, "to": prescription.Status},
+			"reason": req.Reason,
+		},
+	})
+
+	return prescription, nil
+}
+
+// recheckInteractions checks a prescription being resumed against the
+// patient's current drugs and allergies. Every warning must be acknowledged
+// again; the acknowledged warnings are saved with the prescription.
+func (s *Service) recheckInteractions(ctx context.Context, prescription *model.Prescription, acknowledgedKeys []string) error {
+	found, err := s.CheckInteractions(ctx, prescription.PatientID, prescription.DrugCode)
+	if err != nil {
+		return fmt.Errorf("failed to check interactions: %w", err)
+	}
+
+	// The paused prescription is itself one of the patient's current drugs
+	warnings := make([]*model.InteractionWarning, 0, len(found))
+	for _, w := range found {
+		if w.PrescriptionID == nil || *w.PrescriptionID != prescription.ID {
+			warnings = append(warnings, w)
+		}
+	}
+
+	if outstanding := unacknowledged(warnings, acknowledgedKeys); len(outstanding) > 0 {
+		return &WarningsError{Warnings: outstanding}
+	}
+
+	prescription.AcknowledgedWarnings = nil
+	if len(warnings) > 0 {
+		if prescription.AcknowledgedWarnings, err = json.Marshal(warnings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unacknowledged returns the warnings whose keys were not acknowledged
+func unacknowledged(warnings []*model.InteractionWarning, keys []string) []*model.InteractionWarning {
+	acknowledged := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		acknowledged[key] = true
+	}
+	var outstanding []*model.InteractionWarning
+	for _, w := range warnings {
+		if !acknowledged[w.Key] {
+			outstanding = append(outstanding, w)
+		}
+	}
+	return outstanding
+}
+
+// LoadDataset replaces the drug-drug or drug-allergy interaction data with
+// the contents of a dataset file
+func (s *Service) LoadDataset(ctx context.Context, req *model.LoadInteractionDatasetRequest) (*model.InteractionDataset, error) {
+	if req.Kind != model.InteractionDatasetDrugDrug && req.Kind != model.InteractionDatasetDrugAllergy {
+		return nil, ErrUnknownDataset
+	}
+
+	path, err := storage.ResolveFile(s.config.DatasetDir, req.File)
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset file: %w", err)
+	}
+	defer f.Close()
+
+	dataset := &model.InteractionDataset{
+		Kind:    req.Kind,
+		Version: req.Version,
+		File:    req.File,
+	}
+	userID := s.getCurrentUserID(ctx)
+	if userID != uuid.Nil {
+		dataset.LoadedBy = &userID
+	}
+
+	switch req.Kind {
+	case model.InteractionDatasetDrugDrug:
+		interactions, err := readDrugInteractions(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dataset: %w", err)
+		}
+		if err := s.repo.ReplaceDrugInteractions(ctx, dataset, interactions); err != nil {
+			return nil, fmt.Errorf("failed to load %s dataset: %w", req.Kind, err)
+		}
+	case model.InteractionDatasetDrugAllergy:
+		interactions, err := readAllergyInteractions(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dataset: %w", err)
+		}
+		if err := s.repo.ReplaceAllergyInteractions(ctx, dataset, interactions); err != nil {
+			return nil, fmt.Errorf("failed to load %s dataset: %w", req.Kind, err)
+		}
+	default:
+		return nil, ErrUnknownDataset
+	}
+
+	s.auditor.Log(ctx, userID, uuid.Nil, "load", "interaction_dataset", uuid.Nil, &audit.LogOptions{
+		Metadata: dataset,
+	})
+
+	return dataset, nil
+}
+
+func (s *Service) ListDatasets(ctx context.Context) ([]*model.InteractionDataset, error) {
+	return s.repo.ListDatasets(ctx)
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
@@ -0,0 +1,395 @@
+package referral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/medical"
+)
+
+var (
+	ErrNotFound          = errors.New("referral not found")
+	ErrNotReferrer       = errors.New("only clinicians of the patient's organization can refer")
+	ErrSameOrganization  = errors.New("a referral must go to another organization")
+	ErrInvalidRecords    = errors.New("attached records must belong to the patient")
+	ErrNoRecordsAttached = errors.New("the records scope needs at least one attached record")
+	ErrInvalidExpiry     = errors.New("grant expiry must be in the future and within the maximum grant period")
+	ErrNotRecipient      = errors.New("only the receiving organization can act on this referral")
+	ErrNotSender         = errors.New("only the referring organization can revoke this referral")
+	ErrInvalidTransition = errors.New("referral cannot move to this status")
+	ErrAccessExpired     = errors.New("access under this referral has ended")
+	ErrScopeNotShared    = errors.New("the referral does not share this data")
+	ErrRecordNotShared   = errors.New("record is not attached to this referral")
+	ErrConflict          = errors.New("referral was changed by another request")
+)
+
+const (
+	defaultGrantPeriod = 30 * 24 * time.Hour
+	maxGrantPeriod     = 365 * 24 * time.Hour
+)
+
+// referrers may refer patients to other organizations
+var referrers = map[string]bool{
+	model.UserTypeDoctor: true,
+	model.UserTypeNurse:  true,
+}
+
+// transitions lists the statuses each status may move to
+var transitions = map[model.ReferralStatus][]model.ReferralStatus{
+	model.ReferralStatusSent:     {model.ReferralStatusAccepted, model.ReferralStatusDeclined},
+	model.ReferralStatusAccepted: {model.ReferralStatusCompleted},
+}
+
+type Service struct {
+	repo        repository.ReferralRepository
+	patientRepo repository.PatientRepository
+	medicalRepo repository.MedicalRecordRepository
+	medicalSvc  *medical.Service
+	auditor     *audit.Service
+}
+
+func NewService(repo repository.ReferralRepository, patientRepo repository.PatientRepository, medicalRepo repository.MedicalRecordRepository, medicalSvc *medical.Service, auditor *audit.Service) *Service {
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		medicalRepo: medicalRepo,
+		medicalSvc:  medicalSvc,
+		auditor:     auditor,
+	}
+}
+
+// Create refers a patient of orgID to another organization and opens a
+// read-only grant on the chosen scopes until the referral's expiry. The
+// referrer can only share records they can read themselves.
+func (s *Service) Create(ctx context.Context, patientID, orgID uuid.UUID, referrer *model.RecordReader, req *model.CreateReferralRequest) (*model.Referral, error) {
+	if referrer == nil || !referrers[referrer.UserType] {
+		return nil, ErrNotReferrer
+	}
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+	if patient.OrganizationID != orgID {
+		return nil, ErrNotReferrer
+	}
+	if req.TargetOrganizationID == orgID {
+		return nil, ErrSameOrganization
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultGrantPeriod)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) || req.ExpiresAt.Sub(now) > maxGrantPeriod {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	referral := &model.Referral{
+		PatientID:            patientID,
+		SourceOrganizationID: orgID,
+		TargetOrganizationID: req.TargetOrganizationID,
+		ReferredBy:           referrer.UserID,
+		Specialty:            strings.TrimSpace(req.Specialty),
+		Reason:               req.Reason,
+		Status:               model.ReferralStatusSent,
+		Scopes:               []string{},
+		RecordIDs:            []string{},
+		ExpiresAt:            expiresAt,
+	}
+	for _, scope := range req.Scopes {
+		if !referral.HasScope(scope) {
+			referral.Scopes = append(referral.Scopes, string(scope))
+		}
+	}
+
+	if referral.HasScope(model.ReferralScopeRecords) {
+		if len(req.RecordIDs) == 0 {
+			return nil, ErrNoRecordsAttached
+		}
+		seen := make(map[uuid.UUID]bool, len(req.RecordIDs))
+		for _, id := range req.RecordIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			record, err := s.medicalRepo.Get(ctx, id)
+			if err != nil || record.PatientID != patientID {
+				return nil, ErrInvalidRecords
+			}
+			if err := s.medicalSvc.CheckAccess(ctx, record, referrer); err != nil {
+				return nil, err
+			}
+			referral.RecordIDs = append(referral.RecordIDs, id.String())
+		}
+	} else if len(req.RecordIDs) > 0 {
+		return nil, ErrScopeNotShared
+	}
+
+	if err := s.repo.Create(ctx, referral); err != nil {
+		if errors.Is(err, repository.ErrInvalidReference) {
+			return nil, fmt.Errorf("unknown target organization: %w", err)
+		}
+		return nil, err
+	}
+	referral.AccessActive = referral.GrantActive(now)
+
+	s.auditor.Log(ctx, referral.ReferredBy, orgID, "create", "referral", referral.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"patient_id":             patientID,
+			"target_organization_id": referral.TargetOrganizationID,
+			"scopes":                 referral.Scopes,
+			"record_ids":             referral.RecordIDs,
+			"expires_at":             referral.ExpiresAt,
+		},
+	})
+
+	return referral, nil
+}
+
+// Get returns a referral to either of its organizations
+func (s *Service) Get(ctx context.Context, id, orgID uuid.UUID) (*model.Referral, error) {
+	referral, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if referral.SourceOrganizationID != orgID && referral.TargetOrganizationID != orgID {
+		return nil, ErrNotFound
+	}
+	referral.AccessActive = referral.GrantActive(time.Now())
+	return referral, nil
+}
+
+func (s *Service) List(ctx context.Context, filter *model.ReferralFilter) ([]*model.Referral, error) {
+	referrals, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, referral := range referrals {
+		referral.AccessActive = referral.GrantActive(now)
+	}
+	return referrals, nil
+}
+
+// Accept takes the patient on. The grant stays open until it expires, the
+// referral is completed or the sender revokes it.
+func (s *Service) Accept(ctx context.Context, id, orgID, userID uuid.UUID, req *model.ReferralDecisionRequest) (*model.Referral, error) {
+	return s.decide(ctx, id, orgID, userID, model.ReferralStatusAccepted, req)
+}
+
+// Decline turns the referral down and ends the grant
+func (s *Service) Decline(ctx context.Context, id, orgID, userID uuid.UUID, req *model.ReferralDecisionRequest) (*model.Referral, error) {
+	return s.decide(ctx, id, orgID, userID, model.ReferralStatusDeclined, req)
+}
+
+// Complete closes an accepted referral and ends the grant
+func (s *Service) Complete(ctx context.Context, id, orgID, userID uuid.UUID, req *model.ReferralDecisionRequest) (*model.Referral, error) {
+	return s.decide(ctx, id, orgID, userID, model.ReferralStatusCompleted, req)
+}
+
+func (s *Service) decide(ctx context.Context, id, orgID, userID uuid.UUID, to model.ReferralStatus, req *model.ReferralDecisionRequest) (*model.Referral, error) {
+	referral, err := s.Get(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if referral.TargetOrganizationID != orgID {
+		return nil, ErrNotRecipient
+	}
+	if referral.RevokedAt != nil || !canMove(referral.Status, to) {
+		return nil, ErrInvalidTransition
+	}
+
+	from := referral.Status
+	now := time.Now()
+	referral.Status = to
+	if to == model.ReferralStatusCompleted {
+		referral.CompletedAt = &now
+	} else {
+		referral.DecidedBy = &userID
+		referral.DecidedAt = &now
+	}
+	if req != nil && req.Note != nil {
+		referral.DecisionNote = req.Note
+	}
+
+	if err := s.repo.Update(ctx, referral, from); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, ErrConflict
+		}
+		return nil, err
+	}
+	referral.AccessActive = referral.GrantActive(now)
+
+	// The sender sees the outcome in its own audit log
+	s.auditor.Log(ctx, userID, referral.SourceOrganizationID, string(to), "referral", referral.ID, &audit.LogOptions{
+		Changes:  map[string]interface{}{"status": map[string]interface{}{"from": from, "to": to}},
+		Metadata: map[string]interface{}{"recipient_organization_id": orgID},
+	})
+
+	return referral, nil
+}
+
+// Revoke ends the recipient's access before the grant expires. The referral
+// keeps its status so both sides can still see what happened.
+func (s *Service) Revoke(ctx context.Context, id, orgID, userID uuid.UUID) (*model.Referral, error) {
+	referral, err := s.Get(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if referral.SourceOrganizationID != orgID {
+		return nil, ErrNotSender
+	}
+	if !referral.GrantActive(time.Now()) {
+		return nil, ErrAccessExpired
+	}
+
+	now := time.Now()
+	referral.RevokedBy = &userID
+	referral.RevokedAt = &now
+	if err := s.repo.Update(ctx, referral, referral.Status); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, ErrConflict
+		}
+		return nil, err
+	}
+	referral.AccessActive = false
+
+	s.auditor.Log(ctx, userID, orgID, "revoke", "referral", referral.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{"target_organization_id": referral.TargetOrganizationID},
+	})
+
+	return referral, nil
+}
+
+// SharedDemographics returns the referred patient's demographics to the
+// receiving organization
+func (s *Service) SharedDemographics(ctx context.Context, id, orgID, userID uuid.UUID) (*model.SharedDemographics, error) {
+	referral, err := s.grant(ctx, id, orgID, model.ReferralScopeDemographics)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := s.patientRepo.Get(ctx, referral.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	s.logAccess(ctx, referral, orgID, userID, "patient", patient.ID, nil)
+
+	return &model.SharedDemographics{
+		PatientID:   patient.ID,
+		FirstName:   patient.FirstName,
+		LastName:    patient.LastName,
+		DateOfBirth: patient.DateOfBirth,
+		Gender:      patient.Gender,
+		Phone:       patient.Phone,
+		Email:       patient.Email,
+		Address:     patient.Address,
+	}, nil
+}
+
+// SharedRecords returns the records attached to the referral
+func (s *Service) SharedRecords(ctx context.Context, id, orgID, userID uuid.UUID) ([]*model.MedicalRecord, error) {
+	referral, err := s.grant(ctx, id, orgID, model.ReferralScopeRecords)
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.sharedRecords(ctx, referral)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAccess(ctx, referral, orgID, userID, "patient_medical_records", referral.PatientID, map[string]interface{}{"count": len(records)})
+
+	return records, nil
+}
+
+// SharedRecord returns one record attached to the referral
+func (s *Service) SharedRecord(ctx context.Context, id, recordID, orgID, userID uuid.UUID) (*model.MedicalRecord, error) {
+	referral, err := s.grant(ctx, id, orgID, model.ReferralScopeRecords)
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.sharedRecords(ctx, referral)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if record.ID == recordID {
+			s.logAccess(ctx, referral, orgID, userID, "medical_record", record.ID, nil)
+			return record, nil
+		}
+	}
+	return nil, ErrRecordNotShared
+}
+
+// grant checks that orgID is the referral's recipient and that its access to
+// scope is still open
+func (s *Service) grant(ctx context.Context, id, orgID uuid.UUID, scope model.ReferralScope) (*model.Referral, error) {
+	referral, err := s.Get(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if referral.TargetOrganizationID != orgID {
+		return nil, ErrNotRecipient
+	}
+	if !referral.AccessActive {
+		return nil, ErrAccessExpired
+	}
+	if !referral.HasScope(scope) {
+		return nil, ErrScopeNotShared
+	}
+	return referral, nil
+}
+
+func (s *Service) sharedRecords(ctx context.Context, referral *model.Referral) ([]*model.MedicalRecord, error) {
+	attached := make(map[string]bool, len(referral.RecordIDs))
+	for _, id := range referral.RecordIDs {
+		attached[id] = true
+	}
+
+	// A system read: the referral, not the recipient's care team membership,
+	// decides what is visible
+	all, err := s.medicalSvc.ListMedicalRecords(ctx, referral.PatientID, nil, &model.RecordFilters{})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*model.MedicalRecord, 0, len(attached))
+	for _, record := range all {
+		if attached[record.ID.String()] {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// logAccess records a recipient read in the sending organization's audit log
+func (s *Service) logAccess(ctx context.Context, referral *model.Referral, orgID, userID uuid.UUID, entityType string, entityID uuid.UUID, metadata map[string]interface{}) {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["referral_id"] = referral.ID
+	metadata["recipient_organization_id"] = orgID
+
+	s.auditor.Log(ctx, userID, referral.SourceOrganizationID, "read", entityType, entityID, &audit.LogOptions{
+		AccessReason: "referral",
+		Metadata:     metadata,
+	})
+}
+
+func canMove(from, to model.ReferralStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,325 @@
+package relationship
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+var (
+	ErrProxyNotAuthorized = errors.New("no active relationship grants this scope")
+	ErrInvalidScope       = errors.New("invalid proxy scope")
+)
+
+type Service struct {
+	repo        repository.RelationshipRepository
+	patientRepo repository.PatientRepository
+	userRepo    repository.UserRepository
+	auditor     *audit.Service
+}
+
+func NewService(
+	repo repository.RelationshipRepository,
+	patientRepo repository.PatientRepository,
+	userRepo repository.UserRepository,
+	auditor *audit.Service,
+) *Service {
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		userRepo:    userRepo,
+		auditor:     auditor,
+	}
+}
+
+// Create links the patient to a related user and/or patient. When the
+// relationship is marked as the emergency contact, the patient's
+// EmergencyContact is replaced with the related person's details.
+func (s *Service) Create(ctx context.Context, patientID uuid.UUID, req *model.CreateRelationshipRequest) (*model.PatientRelationship, error) {
+	if req.RelatedUserID == nil && req.RelatedPatientID == nil {
+		return nil, fmt.Errorf("related_user_id or related_patient_id is required")
+	}
+	if req.RelatedPatientID != nil && *req.RelatedPatientID == patientID {
+		return nil, fmt.Errorf("patient cannot be related to themselves")
+	}
+	if err := validateScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	rel := &model.PatientRelationship{
+		PatientID:          patient.ID,
+		OrganizationID:     patient.OrganizationID,
+		RelatedPatientID:   req.RelatedPatientID,
+		RelatedUserID:      req.RelatedUserID,
+		Type:               req.Type,
+		Scopes:             pq.StringArray(req.Scopes),
+		IsEmergencyContact: req.IsEmergencyContact,
+		ValidFrom:          time.Now(),
+		ValidTo:            req.ValidTo,
+		Notes:              req.Notes,
+		CreatedBy:          s.getCurrentUserID(ctx),
+	}
+	if req.ValidFrom != nil {
+		rel.ValidFrom = *req.ValidFrom
+	}
+	if rel.ValidTo != nil && !rel.ValidTo.After(rel.ValidFrom) {
+		return nil, fmt.Errorf("valid_to must be after valid_from")
+	}
+
+	contact, err := s.contactFor(ctx, rel)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, rel); err != nil {
+		return nil, fmt.Errorf("failed to create relationship: %w", err)
+	}
+
+	if rel.IsEmergencyContact {
+		contact.RelationshipID = &rel.ID
+		if err := s.patientRepo.UpdateEmergencyContact(ctx, patientID, contact); err != nil {
+			return nil, fmt.Errorf("failed to update emergency contact: %w", err)
+		}
+	}
+
+	s.auditor.Log(ctx, rel.CreatedBy, rel.OrganizationID, "create", "patient_relationship", rel.ID, &audit.LogOptions{
+		Changes: rel,
+	})
+
+	return rel, nil
+}
+
+func (s *Service) Get(ctx context.Context, patientID, id uuid.UUID) (*model.PatientRelationship, error) {
+	rel, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationship: %w", err)
+	}
+	if rel.PatientID != patientID {
+		return nil, fmt.Errorf("relationship does not belong to patient")
+	}
+	return rel, nil
+}
+
+func (s *Service) Update(ctx context.Context, patientID, id uuid.UUID, req *model.UpdateRelationshipRequest) (*model.PatientRelationship, error) {
+	rel, err := s.Get(ctx, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if rel.RevokedAt != nil {
+		return nil, fmt.Errorf("relationship has been revoked")
+	}
+
+	if req.Scopes != nil {
+		if err := validateScopes(req.Scopes); err != nil {
+			return nil, err
+		}
+		rel.Scopes = pq.StringArray(req.Scopes)
+	}
+	if req.ValidTo != nil {
+		if !req.ValidTo.After(rel.ValidFrom) {
+			return nil, fmt.Errorf("valid_to must be after valid_from")
+		}
+		rel.ValidTo = req.ValidTo
+	}
+	if req.Notes != nil {
+		rel.Notes = *req.Notes
+	}
+
+	linkContact := req.IsEmergencyContact != nil && *req.IsEmergencyContact && !rel.IsEmergencyContact
+	if req.IsEmergencyContact != nil {
+		rel.IsEmergencyContact = *req.IsEmergencyContact
+	}
+
+	if err := s.repo.Update(ctx, rel); err != nil {
+		return nil, fmt.Errorf("failed to update relationship: %w", err)
+	}
+
+	if linkContact {
+		contact, err := s.contactFor(ctx, rel)
+		if err != nil {
+			return nil, err
+		}
+		contact.RelationshipID = &rel.ID
+		if err := s.patientRepo.UpdateEmergencyContact(ctx, patientID, contact); err != nil {
+			return nil, fmt.Errorf("failed to update emergency contact: %w", err)
+		}
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), rel.OrganizationID, "update", "patient_relationship", rel.ID, &audit.LogOptions{
+		Changes: rel,
+	})
+
+	return rel, nil
+}
+
+// Revoke ends the relationship immediately. The record is kept so past proxy
+// actions can still be traced back to it.
+func (s *Service) Revoke(ctx context.Context, patientID, id uuid.UUID) error {
+	rel, err := s.Get(ctx, patientID, id)
+	if err != nil {
+		return err
+	}
+	if rel.RevokedAt != nil {
+		return nil
+	}
+
+	userID := s.getCurrentUserID(ctx)
+	now := time.Now()
+	rel.RevokedAt = &now
+	rel.RevokedBy = &userID
+
+	if err := s.repo.Update(ctx, rel); err != nil {
+		return fmt.Errorf("failed to revoke relationship: %w", err)
+	}
+
+	s.auditor.Log(ctx, userID, rel.OrganizationID, "revoke", "patient_relationship", rel.ID, nil)
+
+	return nil
+}
+
+func (s *Service) ListForPatient(ctx context.Context, patientID uuid.UUID) ([]*model.PatientRelationship, error) {
+	rels, err := s.repo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	return rels, nil
+}
+
+// ListDependents returns the relationships in which the user acts for a patient
+func (s *Service) ListDependents(ctx context.Context, userID uuid.UUID) ([]*model.PatientRelationship, error) {
+	rels, err := s.repo.ListByRelatedUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dependents: %w", err)
+	}
+
+	now := time.Now()
+	active := make([]*model.PatientRelationship, 0, len(rels))
+	for _, rel := range rels {
+		if rel.IsActive(now) {
+			active = append(active, rel)
+		}
+	}
+	return active, nil
+}
+
+// Authorize returns the active relationship through which the user may act
+// for the patient with the given scope, or ErrProxyNotAuthorized.
+func (s *Service) Authorize(ctx context.Context, userID, patientID uuid.UUID, scope model.ProxyScope) (*model.PatientRelationship, error) {
+	rels, err := s.repo.FindActive(ctx, patientID, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find relationships: %w", err)
+	}
+
+	for _, rel := range rels {
+		if rel.HasScope(scope) {
+			return rel, nil
+		}
+	}
+	return nil, ErrProxyNotAuthorized
+}
+
+// IsSelf reports whether the user is the patient, i.e. the login linked to
+// the patient record.
+func (s *Service) IsSelf(ctx context.Context, userID, patientID uuid.UUID) bool {
+	if userID == uuid.Nil {
+		return false
+	}
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return false
+	}
+	return patient.UserID != nil && *patient.UserID == userID
+}
+
+// NotificationRecipients returns the users who should receive notifications
+// sent to the patient on their behalf.
+func (s *Service) NotificationRecipients(ctx context.Context, patientID uuid.UUID) ([]*model.User, error) {
+	rels, err := s.repo.ListByPatient(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+
+	now := time.Now()
+	var users []*model.User
+	for _, rel := range rels {
+		if rel.RelatedUserID == nil || !rel.IsActive(now) || !rel.HasScope(model.ProxyScopeNotificationsReceive) {
+			continue
+		}
+		user, err := s.userRepo.Get(ctx, *rel.RelatedUserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// contactFor builds an emergency contact from the related person, checking
+// that they exist.
+func (s *Service) contactFor(ctx context.Context, rel *model.PatientRelationship) (*model.EmergencyContact, error) {
+	contact := &model.EmergencyContact{Relation: string(rel.Type)}
+
+	if rel.RelatedPatientID != nil {
+		related, err := s.patientRepo.Get(ctx, *rel.RelatedPatientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get related patient: %w", err)
+		}
+		contact.Name = strings.TrimSpace(related.FirstName + " " + related.LastName)
+		contact.Phone = related.Phone
+	}
+
+	if rel.RelatedUserID != nil {
+		user, err := s.userRepo.Get(ctx, *rel.RelatedUserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get related user: %w", err)
+		}
+		if contact.Name == "" {
+			contact.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		}
+		if contact.Phone == "" {
+			contact.Phone = user.Phone
+		}
+	}
+
+	return contact, nil
+}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		valid := false
+		for _, known := range model.ProxyScopes {
+			if model.ProxyScope(scope) == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
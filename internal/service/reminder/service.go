@@ -0,0 +1,349 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/notification"
+	"github.com/jwalitptl/admin-api/pkg/security"
+)
+
+const (
+	defaultBatchSize   = 50
+	defaultMaxAttempts = 5
+	defaultClaimTTL    = 5 * time.Minute
+
+	responsePath = "/api/v1/appointment-responses/%s/%s"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("reminder schedule not found")
+	ErrDuplicateRule    = errors.New("reminder rules must not repeat a channel and offset")
+	ErrServiceNotFound  = errors.New("service not found")
+)
+
+type Config struct {
+	// BaseURL is where the confirm and cancel links in reminders point
+	BaseURL string
+	// DefaultRules apply to organizations without a reminder schedule
+	DefaultRules []model.ReminderRule
+	BatchSize    int
+	// MaxAttempts is how often a reminder is tried before it is failed
+	MaxAttempts int
+	// ClaimTTL is how long a worker has to send a claimed reminder before
+	// another worker may pick it up
+	ClaimTTL time.Duration
+}
+
+// Service schedules the reminders of appointments and sends them when
+// they fall due
+type Service struct {
+	repo         repository.ReminderRepository
+	appointments repository.AppointmentRepository
+	patientRepo  repository.PatientRepository
+	services     repository.ServiceRepository
+	clinicRepo   repository.ClinicRepository
+	notifSvc     notification.Service
+	signer       *security.URLSigner
+	auditor      *audit.Service
+	config       Config
+}
+
+func NewService(
+	repo repository.ReminderRepository,
+	appointments repository.AppointmentRepository,
+	patientRepo repository.PatientRepository,
+	services repository.ServiceRepository,
+	clinicRepo repository.ClinicRepository,
+	notifSvc notification.Service,
+	signer *security.URLSigner,
+	auditor *audit.Service,
+	config Config,
+) *Service {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.ClaimTTL <= 0 {
+		config.ClaimTTL = defaultClaimTTL
+	}
+	return &Service{
+		repo:         repo,
+		appointments: appointments,
+		patientRepo:  patientRepo,
+		services:     services,
+		clinicRepo:   clinicRepo,
+		notifSvc:     notifSvc,
+		signer:       signer,
+		auditor:      auditor,
+		config:       config,
+	}
+}
+
+// SetSchedule replaces the organization's default reminder schedule, or the
+// service's when the request names one. An empty rule list turns reminders
+// off.
+func (s *Service) SetSchedule(ctx context.Context, organizationID uuid.UUID, req *model.SetReminderScheduleRequest) (*model.ReminderSchedule, error) {
+	seen := make(map[model.ReminderRule]bool, len(req.Rules))
+	for _, rule := range req.Rules {
+		if seen[rule] {
+			return nil, ErrDuplicateRule
+		}
+		seen[rule] = true
+	}
+	if req.ServiceID != nil {
+		svc, err := s.services.Get(ctx, *req.ServiceID)
+		if err != nil {
+			return nil, ErrServiceNotFound
+		}
+		clinic, err := s.clinicRepo.Get(ctx, svc.ClinicID)
+		if err != nil || clinic.OrganizationID != organizationID {
+			return nil, ErrServiceNotFound
+		}
+	}
+
+	schedule := &model.ReminderSchedule{
+		OrganizationID: organizationID,
+		ServiceID:      req.ServiceID,
+		Rules:          req.Rules,
+		CreatedBy:      s.getCurrentUserID(ctx),
+	}
+	if err := s.repo.SaveSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "update", "reminder_schedule", schedule.ID, &audit.LogOptions{
+		Changes: schedule,
+	})
+
+	return schedule, nil
+}
+
+func (s *Service) ListSchedules(ctx context.Context, organizationID uuid.UUID) ([]*model.ReminderSchedule, error) {
+	return s.repo.ListSchedules(ctx, organizationID)
+}
+
+func (s *Service) DeleteSchedule(ctx context.Context, organizationID, id uuid.UUID) error {
+	schedule, err := s.repo.GetSchedule(ctx, id)
+	if err != nil || schedule.OrganizationID != organizationID {
+		return ErrScheduleNotFound
+	}
+	if err := s.repo.DeleteSchedule(ctx, id); err != nil {
+		return err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "delete", "reminder_schedule", id, nil)
+	return nil
+}
+
+// Schedule replaces the appointment's pending reminders with ones for its
+// current time. Reminders that would already be due are left out, and an
+// appointment that is no longer going ahead gets none.
+func (s *Service) Schedule(ctx context.Context, apt *model.Appointment) error {
+	now := time.Now()
+	if !isActive(apt) || !apt.StartTime.After(now) {
+		return s.Cancel(ctx, apt.ID)
+	}
+
+	patient, err := s.patientRepo.Get(ctx, apt.PatientID)
+	if err != nil {
+		return fmt.Errorf("failed to get patient: %w", err)
+	}
+	rules := s.config.DefaultRules
+	schedule, err := s.repo.ResolveSchedule(ctx, patient.OrganizationID, apt.ServiceID)
+	if err != nil {
+		return err
+	}
+	if schedule != nil {
+		rules = schedule.Rules
+	}
+
+	reminders := make([]*model.AppointmentReminder, 0, len(rules))
+	for _, rule := range rules {
+		sendAt := apt.StartTime.Add(-time.Duration(rule.OffsetMinutes) * time.Minute)
+		if !sendAt.After(now) {
+			continue
+		}
+		reminders = append(reminders, &model.AppointmentReminder{
+			AppointmentID:  apt.ID,
+			OrganizationID: patient.OrganizationID,
+			PatientID:      apt.PatientID,
+			Channel:        rule.Channel,
+			OffsetMinutes:  rule.OffsetMinutes,
+			SendAt:         sendAt,
+			Status:         model.ReminderStatusPending,
+		})
+	}
+	return s.repo.ReplaceReminders(ctx, apt.ID, reminders)
+}
+
+// Cancel cancels the appointment's pending reminders
+func (s *Service) Cancel(ctx context.Context, appointmentID uuid.UUID) error {
+	return s.repo.CancelReminders(ctx, appointmentID)
+}
+
+func (s *Service) ListReminders(ctx context.Context, appointmentID uuid.UUID) ([]*model.AppointmentReminder, error) {
+	return s.repo.ListReminders(ctx, appointmentID)
+}
+
+// SendDue claims the reminders that have fallen due and sends them. A
+// reminder that fails is retried with a growing delay until MaxAttempts.
+// It returns how many reminders were sent.
+func (s *Service) SendDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := s.repo.ClaimDue(ctx, now, now.Add(s.config.ClaimTTL), s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, reminder := range due {
+		skip, err := s.send(ctx, reminder)
+		switch {
+		case err != nil:
+			s.fail(ctx, reminder, err)
+		case skip != "":
+			if err := s.repo.MarkSkipped(ctx, reminder.ID, skip); err != nil {
+				return sent, err
+			}
+		default:
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// VerifyLink checks the signature of a confirm or cancel link
+func (s *Service) VerifyLink(path string, query url.Values) error {
+	return s.signer.VerifyURL(path, query)
+}
+
+// send delivers the reminder, or returns why it is no longer needed
+func (s *Service) send(ctx context.Context, reminder *model.AppointmentReminder) (string, error) {
+	apt, err := s.appointments.Get(ctx, reminder.AppointmentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get appointment: %w", err)
+	}
+	if !isActive(apt) {
+		return "appointment is " + string(apt.Status), nil
+	}
+	if !apt.StartTime.After(time.Now()) {
+		return "appointment has started", nil
+	}
+
+	patient, err := s.patientRepo.Get(ctx, reminder.PatientID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get patient: %w", err)
+	}
+	var recipient string
+	switch reminder.Channel {
+	case "email":
+		recipient = patient.Email
+	case "sms":
+		recipient = patient.Phone
+	case "push":
+		recipient = patient.ID.String()
+	}
+	if recipient == "" {
+		return "patient has no " + reminder.Channel + " contact", nil
+	}
+
+	msg, err := s.compose(reminder, apt, patient)
+	if err != nil {
+		return "", err
+	}
+	n := &model.Notification{
+		UserID:         patient.ID,
+		OrganizationID: reminder.OrganizationID,
+		PatientID:      &patient.ID,
+		Channel:        reminder.Channel,
+		Priority:       "normal",
+		Subject:        msg.subject,
+		Content:        msg.content,
+		Recipient:      recipient,
+	}
+	if err := s.notifSvc.Send(ctx, n); err != nil {
+		return "", err
+	}
+	// The notification is out; failing the reminder now would send it twice
+	if err := s.repo.MarkSent(ctx, reminder.ID, n.ID); err != nil {
+		log.Printf("failed to mark reminder %s sent: %v", reminder.ID, err)
+	}
+
+	s.auditor.Log(ctx, uuid.Nil, reminder.OrganizationID, "sent", "appointment_reminder", reminder.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"appointment_id":  reminder.AppointmentID,
+			"channel":         reminder.Channel,
+			"notification_id": n.ID,
+		},
+	})
+	return "", nil
+}
+
+// compose writes the reminder in the patient's language and timezone with
+// signed links that stay valid until the appointment starts
+func (s *Service) compose(reminder *model.AppointmentReminder, apt *model.Appointment, patient *model.Patient) (*message, error) {
+	loc, err := time.LoadLocation(patient.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	ttl := time.Until(apt.StartTime)
+
+	confirm, _, err := s.signer.SignURL(s.config.BaseURL+fmt.Sprintf(responsePath, apt.ID, model.ReminderActionConfirm), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign confirm link: %w", err)
+	}
+	cancel, _, err := s.signer.SignURL(s.config.BaseURL+fmt.Sprintf(responsePath, apt.ID, model.ReminderActionCancel), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign cancel link: %w", err)
+	}
+
+	return render(patient.Locale, reminder.Channel, apt.StartTime.In(loc), confirm, cancel), nil
+}
+
+// fail schedules another attempt for the reminder, or fails it once it has
+// used its attempts
+func (s *Service) fail(ctx context.Context, reminder *model.AppointmentReminder, cause error) {
+	var retryAt *time.Time
+	if reminder.Attempts < s.config.MaxAttempts {
+		at := time.Now().Add(time.Duration(reminder.Attempts*reminder.Attempts) * time.Minute)
+		retryAt = &at
+	}
+	if err := s.repo.MarkFailed(ctx, reminder.ID, cause.Error(), retryAt); err != nil {
+		cause = fmt.Errorf("%v; %w", cause, err)
+	}
+	if retryAt != nil {
+		return
+	}
+
+	s.auditor.Log(ctx, uuid.Nil, reminder.OrganizationID, "send_failed", "appointment_reminder", reminder.ID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"appointment_id": reminder.AppointmentID,
+			"channel":        reminder.Channel,
+			"attempts":       reminder.Attempts,
+			"error":          cause.Error(),
+		},
+	})
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
+
+func isActive(apt *model.Appointment) bool {
+	return apt.Status == model.AppointmentStatusScheduled || apt.Status == model.AppointmentStatusConfirmed
+}
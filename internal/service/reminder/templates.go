@@ -0,0 +1,63 @@
+package reminder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// appointmentTimeLayout avoids month and day names so one layout reads in
+// every language
+const appointmentTimeLayout = "2006-01-02 15:04 MST"
+
+type message struct {
+	subject string
+	content string
+}
+
+type template struct {
+	subject string
+	// body takes the appointment time, the confirm link and the cancel link
+	body string
+	// short is the body of SMS and push reminders
+	short string
+}
+
+// templates are keyed by the language part of the patient's locale
+var templates = map[string]template{
+	"en": {
+		subject: "Appointment reminder",
+		body:    "You have an appointment on %s.\n\nConfirm you are coming: %s\nCancel the appointment: %s",
+		short:   "Reminder: appointment on %s. Confirm: %s Cancel: %s",
+	},
+	"es": {
+		subject: "Recordatorio de cita",
+		body:    "Tiene una cita el %s.\n\nConfirme su asistencia: %s\nCancele la cita: %s",
+		short:   "Recordatorio: cita el %s. Confirmar: %s Cancelar: %s",
+	},
+	"fr": {
+		subject: "Rappel de rendez-vous",
+		body:    "Vous avez un rendez-vous le %s.\n\nConfirmez votre venue : %s\nAnnulez le rendez-vous : %s",
+		short:   "Rappel : rendez-vous le %s. Confirmer : %s Annuler : %s",
+	},
+}
+
+// render writes the reminder for the channel in the locale's language,
+// falling back to English
+func render(locale, channel string, start time.Time, confirm, cancel string) *message {
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	lang, _, _ = strings.Cut(lang, "_")
+	t, ok := templates[lang]
+	if !ok {
+		t = templates["en"]
+	}
+
+	body := t.body
+	if channel != "email" {
+		body = t.short
+	}
+	return &message{
+		subject: t.subject,
+		content: fmt.Sprintf(body, start.Format(appointmentTimeLayout), confirm, cancel),
+	}
+}
@@ -0,0 +1,124 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+// resolve works out the availability windows in [from, to). Each rule
+// contributes its hours on the matching local dates it is in effect, less
+// breaks, unless a holiday closes its clinic that day. Extra sessions are
+// added as they are, and leave is taken out of everything it overlaps.
+func resolve(rules []*model.AvailabilityRule, exceptions []*model.ScheduleException, holidays []*model.Holiday, from, to time.Time) []model.AvailabilityWindow {
+	var windows []model.AvailabilityWindow
+
+	for _, rule := range rules {
+		loc, err := time.LoadLocation(rule.Timezone)
+		if err != nil {
+			continue
+		}
+		ruleID := rule.ID
+		effectiveFrom := rule.EffectiveFrom.Format(dateLayout)
+
+		local := from.In(loc)
+		for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+			if day.Weekday() != rule.Weekday {
+				continue
+			}
+			date := day.Format(dateLayout)
+			if date < effectiveFrom || (rule.EffectiveUntil != nil && date > rule.EffectiveUntil.Format(dateLayout)) {
+				continue
+			}
+			if closed(holidays, rule.ClinicID, date) {
+				continue
+			}
+
+			start := at(day, rule.StartTime)
+			for _, b := range rule.Breaks {
+				windows = append(windows, model.AvailabilityWindow{ClinicID: rule.ClinicID, Start: start, End: at(day, b.Start), RuleID: &ruleID})
+				start = at(day, b.End)
+			}
+			windows = append(windows, model.AvailabilityWindow{ClinicID: rule.ClinicID, Start: start, End: at(day, rule.EndTime), RuleID: &ruleID})
+		}
+	}
+
+	for _, e := range exceptions {
+		if e.Kind == model.ScheduleExceptionExtraSession && e.ClinicID != nil {
+			exceptionID := e.ID
+			windows = append(windows, model.AvailabilityWindow{ClinicID: *e.ClinicID, Start: e.StartsAt, End: e.EndsAt, ExceptionID: &exceptionID})
+		}
+	}
+	for _, e := range exceptions {
+		if e.Kind == model.ScheduleExceptionLeave {
+			windows = subtract(windows, e.ClinicID, e.StartsAt, e.EndsAt)
+		}
+	}
+
+	// Rules are resolved a whole day at a time, so clip to the range asked for
+	windows = subtract(windows, nil, time.Time{}, from)
+	windows = subtract(windows, nil, to, to.AddDate(1, 0, 0))
+
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows
+}
+
+// closed reports whether a holiday closes the clinic on the date
+func closed(holidays []*model.Holiday, clinicID uuid.UUID, date string) bool {
+	for _, h := range holidays {
+		if h.Date.Format(dateLayout) == date && (h.ClinicID == nil || *h.ClinicID == clinicID) {
+			return true
+		}
+	}
+	return false
+}
+
+// subtract takes [start, end) out of the windows at the clinic, or at every
+// clinic when clinicID is nil. Windows left empty are dropped.
+func subtract(windows []model.AvailabilityWindow, clinicID *uuid.UUID, start, end time.Time) []model.AvailabilityWindow {
+	result := make([]model.AvailabilityWindow, 0, len(windows))
+	for _, w := range windows {
+		if !w.Start.Before(w.End) {
+			continue
+		}
+		if (clinicID != nil && *clinicID != w.ClinicID) || !w.Start.Before(end) || !start.Before(w.End) {
+			result = append(result, w)
+			continue
+		}
+		if w.Start.Before(start) {
+			left := w
+			left.End = start
+			result = append(result, left)
+		}
+		if end.Before(w.End) {
+			right := w
+			right.Start = end
+			result = append(result, right)
+		}
+	}
+	return result
+}
+
+// at is the time of day on the day, in the day's location. A time skipped
+// by a daylight saving change moves forward with the clocks.
+func at(day time.Time, clock string) time.Time {
+	minutes, _ := parseClock(clock)
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// parseClock parses "HH:MM" to minutes after midnight
+func parseClock(clock string) (int, bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,488 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+var (
+	ErrRuleNotFound      = errors.New("availability rule not found")
+	ErrExceptionNotFound = errors.New("schedule exception not found")
+	ErrHolidayNotFound   = errors.New("holiday not found")
+	ErrClinicianNotFound = errors.New("clinician not found")
+	ErrClinicNotFound    = errors.New("clinic not found")
+	ErrNotAllowed        = errors.New("only administrators and the clinician themselves can change a schedule")
+	ErrNotAdmin          = errors.New("only administrators can change holidays")
+	ErrInvalidTime       = errors.New("times of day must be HH:MM and end after they start")
+	ErrInvalidBreak      = errors.New("breaks must fall within the working hours without overlapping")
+	ErrInvalidTimezone   = errors.New("unknown timezone")
+	ErrInvalidDates      = errors.New("dates must be YYYY-MM-DD and effective_until cannot be before effective_from")
+	ErrOverlappingRule   = errors.New("rule overlaps another of the clinician's rules")
+	ErrInvalidRange      = errors.New("end must be after start")
+	ErrRangeTooLong      = errors.New("availability can be resolved for at most 92 days at a time")
+	ErrClinicRequired    = errors.New("an extra session needs a clinic")
+	ErrHolidayExists     = errors.New("a holiday is already set for this date")
+)
+
+// MaxAvailabilityRange bounds how far availability is resolved in one call
+const MaxAvailabilityRange = 92 * 24 * time.Hour
+
+const dateLayout = "2006-01-02"
+
+// clinicians are the user types that have schedules
+var clinicians = map[string]bool{
+	model.UserTypeDoctor: true,
+	model.UserTypeNurse:  true,
+}
+
+type Service struct {
+	repo       repository.ScheduleRepository
+	userRepo   repository.UserRepository
+	clinicRepo repository.ClinicRepository
+	auditor    *audit.Service
+}
+
+func NewService(repo repository.ScheduleRepository, userRepo repository.UserRepository, clinicRepo repository.ClinicRepository, auditor *audit.Service) *Service {
+	return &Service{
+		repo:       repo,
+		userRepo:   userRepo,
+		clinicRepo: clinicRepo,
+		auditor:    auditor,
+	}
+}
+
+func (s *Service) ListRules(ctx context.Context, organizationID, clinicianID uuid.UUID) ([]*model.AvailabilityRule, error) {
+	if err := s.checkClinician(ctx, organizationID, clinicianID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAvailabilityRules(ctx, clinicianID)
+}
+
+func (s *Service) GetRule(ctx context.Context, organizationID, clinicianID, ruleID uuid.UUID) (*model.AvailabilityRule, error) {
+	rule, err := s.repo.GetAvailabilityRule(ctx, ruleID)
+	if err != nil || rule.OrganizationID != organizationID || rule.ClinicianID != clinicianID {
+		return nil, ErrRuleNotFound
+	}
+	return rule, nil
+}
+
+// CreateRule adds weekly hours at a clinic. A clinician cannot work two
+// overlapping rules, at the same clinic or at different ones.
+func (s *Service) CreateRule(ctx context.Context, organizationID, clinicianID uuid.UUID, userType string, req *model.CreateAvailabilityRuleRequest) (*model.AvailabilityRule, error) {
+	if !s.canManage(ctx, clinicianID, userType) {
+		return nil, ErrNotAllowed
+	}
+	if err := s.checkClinician(ctx, organizationID, clinicianID); err != nil {
+		return nil, err
+	}
+	if err := s.checkClinic(ctx, organizationID, req.ClinicID); err != nil {
+		return nil, err
+	}
+
+	from, err := time.Parse(dateLayout, req.EffectiveFrom)
+	if err != nil {
+		return nil, ErrInvalidDates
+	}
+	until, err := parseOptionalDate(req.EffectiveUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &model.AvailabilityRule{
+		OrganizationID: organizationID,
+		ClinicID:       req.ClinicID,
+		ClinicianID:    clinicianID,
+		Weekday:        *req.Weekday,
+		StartTime:      req.StartTime,
+		EndTime:        req.EndTime,
+		Timezone:       req.Timezone,
+		Breaks:         req.Breaks,
+		EffectiveFrom:  from,
+		EffectiveUntil: until,
+		CreatedBy:      s.getCurrentUserID(ctx),
+	}
+	if err := s.validateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateAvailabilityRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "create", "availability_rule", rule.ID, &audit.LogOptions{
+		Changes: rule,
+	})
+
+	return rule, nil
+}
+
+func (s *Service) UpdateRule(ctx context.Context, organizationID, clinicianID, ruleID uuid.UUID, userType string, req *model.UpdateAvailabilityRuleRequest) (*model.AvailabilityRule, error) {
+	if !s.canManage(ctx, clinicianID, userType) {
+		return nil, ErrNotAllowed
+	}
+	rule, err := s.GetRule(ctx, organizationID, clinicianID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	before := *rule
+
+	if req.StartTime != nil {
+		rule.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		rule.EndTime = *req.EndTime
+	}
+	if req.Timezone != nil {
+		rule.Timezone = *req.Timezone
+	}
+	if req.Breaks != nil {
+		rule.Breaks = *req.Breaks
+	}
+	if req.EffectiveFrom != nil {
+		from, err := time.Parse(dateLayout, *req.EffectiveFrom)
+		if err != nil {
+			return nil, ErrInvalidDates
+		}
+		rule.EffectiveFrom = from
+	}
+	if req.EffectiveUntil != nil {
+		if rule.EffectiveUntil, err = parseOptionalDate(req.EffectiveUntil); err != nil {
+			return nil, err
+		}
+	}
+	rule.Version = req.Version
+
+	if err := s.validateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateAvailabilityRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "update", "availability_rule", rule.ID, &audit.LogOptions{
+		Changes: map[string]interface{}{"before": before, "after": rule},
+	})
+
+	return rule, nil
+}
+
+func (s *Service) DeleteRule(ctx context.Context, organizationID, clinicianID, ruleID uuid.UUID, userType string) error {
+	if !s.canManage(ctx, clinicianID, userType) {
+		return ErrNotAllowed
+	}
+	rule, err := s.GetRule(ctx, organizationID, clinicianID, ruleID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteAvailabilityRule(ctx, ruleID); err != nil {
+		return ErrRuleNotFound
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "delete", "availability_rule", ruleID, &audit.LogOptions{
+		Changes: rule,
+	})
+	return nil
+}
+
+func (s *Service) ListExceptions(ctx context.Context, organizationID, clinicianID uuid.UUID, from, to time.Time) ([]*model.ScheduleException, error) {
+	if !to.After(from) {
+		return nil, ErrInvalidRange
+	}
+	if err := s.checkClinician(ctx, organizationID, clinicianID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListExceptions(ctx, clinicianID, from, to)
+}
+
+// CreateException records leave or an extra session. Leave without a clinic
+// applies at all of the clinician's clinics.
+func (s *Service) CreateException(ctx context.Context, organizationID, clinicianID uuid.UUID, userType string, req *model.CreateScheduleExceptionRequest) (*model.ScheduleException, error) {
+	if !s.canManage(ctx, clinicianID, userType) {
+		return nil, ErrNotAllowed
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, ErrInvalidRange
+	}
+	if req.Kind == model.ScheduleExceptionExtraSession && req.ClinicID == nil {
+		return nil, ErrClinicRequired
+	}
+	if err := s.checkClinician(ctx, organizationID, clinicianID); err != nil {
+		return nil, err
+	}
+	if req.ClinicID != nil {
+		if err := s.checkClinic(ctx, organizationID, *req.ClinicID); err != nil {
+			return nil, err
+		}
+	}
+
+	e := &model.ScheduleException{
+		OrganizationID: organizationID,
+		ClinicianID:    clinicianID,
+		ClinicID:       req.ClinicID,
+		Kind:           req.Kind,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		Reason:         req.Reason,
+		CreatedBy:      s.getCurrentUserID(ctx),
+	}
+	if err := s.repo.CreateException(ctx, e); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "create", "schedule_exception", e.ID, &audit.LogOptions{
+		Changes: e,
+	})
+
+	return e, nil
+}
+
+func (s *Service) DeleteException(ctx context.Context, organizationID, clinicianID, exceptionID uuid.UUID, userType string) error {
+	if !s.canManage(ctx, clinicianID, userType) {
+		return ErrNotAllowed
+	}
+	e, err := s.repo.GetException(ctx, exceptionID)
+	if err != nil || e.OrganizationID != organizationID || e.ClinicianID != clinicianID {
+		return ErrExceptionNotFound
+	}
+
+	if err := s.repo.DeleteException(ctx, exceptionID); err != nil {
+		return ErrExceptionNotFound
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "delete", "schedule_exception", exceptionID, &audit.LogOptions{
+		Changes: e,
+	})
+	return nil
+}
+
+// ListHolidays lists the holidays dated from from up to, but not including, to
+func (s *Service) ListHolidays(ctx context.Context, organizationID uuid.UUID, from, to time.Time) ([]*model.Holiday, error) {
+	if !to.After(from) {
+		return nil, ErrInvalidRange
+	}
+	return s.repo.ListHolidays(ctx, organizationID, from, to)
+}
+
+func (s *Service) CreateHoliday(ctx context.Context, organizationID uuid.UUID, userType string, req *model.CreateHolidayRequest) (*model.Holiday, error) {
+	if userType != model.UserTypeAdmin {
+		return nil, ErrNotAdmin
+	}
+	date, err := time.Parse(dateLayout, req.Date)
+	if err != nil {
+		return nil, ErrInvalidDates
+	}
+	if req.ClinicID != nil {
+		if err := s.checkClinic(ctx, organizationID, *req.ClinicID); err != nil {
+			return nil, err
+		}
+	}
+
+	h := &model.Holiday{
+		OrganizationID: organizationID,
+		ClinicID:       req.ClinicID,
+		Date:           date,
+		Name:           req.Name,
+		CreatedBy:      s.getCurrentUserID(ctx),
+	}
+	if err := s.repo.CreateHoliday(ctx, h); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrHolidayExists
+		}
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "create", "holiday", h.ID, &audit.LogOptions{
+		Changes: h,
+	})
+
+	return h, nil
+}
+
+func (s *Service) DeleteHoliday(ctx context.Context, organizationID, holidayID uuid.UUID, userType string) error {
+	if userType != model.UserTypeAdmin {
+		return ErrNotAdmin
+	}
+	h, err := s.repo.GetHoliday(ctx, holidayID)
+	if err != nil || h.OrganizationID != organizationID {
+		return ErrHolidayNotFound
+	}
+
+	if err := s.repo.DeleteHoliday(ctx, holidayID); err != nil {
+		return ErrHolidayNotFound
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "delete", "holiday", holidayID, &audit.LogOptions{
+		Changes: h,
+	})
+	return nil
+}
+
+// Availability resolves when a clinician of the organization is available
+func (s *Service) Availability(ctx context.Context, organizationID, clinicianID uuid.UUID, from, to time.Time) ([]model.AvailabilityWindow, error) {
+	if err := s.checkClinician(ctx, organizationID, clinicianID); err != nil {
+		return nil, err
+	}
+	return s.Windows(ctx, clinicianID, from, to)
+}
+
+// Windows resolves a clinician's availability in [from, to) from their
+// weekly rules, less holidays and leave, plus extra sessions. It is the
+// source of truth for when a clinician can be booked.
+func (s *Service) Windows(ctx context.Context, clinicianID uuid.UUID, from, to time.Time) ([]model.AvailabilityWindow, error) {
+	if !to.After(from) {
+		return nil, ErrInvalidRange
+	}
+	if to.Sub(from) > MaxAvailabilityRange {
+		return nil, ErrRangeTooLong
+	}
+
+	clinician, err := s.userRepo.Get(ctx, clinicianID)
+	if err != nil {
+		return nil, ErrClinicianNotFound
+	}
+
+	rules, err := s.repo.ListAvailabilityRules(ctx, clinicianID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := s.repo.ListExceptions(ctx, clinicianID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	// Holidays are dated in each rule's timezone, which may be a day either
+	// side of UTC
+	holidays, err := s.repo.ListHolidays(ctx, clinician.OrganizationID, from.AddDate(0, 0, -1), to.AddDate(0, 0, 2))
+	if err != nil {
+		return nil, err
+	}
+
+	return resolve(rules, exceptions, holidays, from, to), nil
+}
+
+// validateRule checks a rule's hours, timezone and dates, and that it does
+// not overlap the clinician's other rules
+func (s *Service) validateRule(ctx context.Context, rule *model.AvailabilityRule) error {
+	start, ok := parseClock(rule.StartTime)
+	if !ok {
+		return ErrInvalidTime
+	}
+	end, ok := parseClock(rule.EndTime)
+	if !ok || end <= start {
+		return ErrInvalidTime
+	}
+	if _, err := time.LoadLocation(rule.Timezone); err != nil || rule.Timezone == "" {
+		return ErrInvalidTimezone
+	}
+	if rule.EffectiveUntil != nil && rule.EffectiveUntil.Before(rule.EffectiveFrom) {
+		return ErrInvalidDates
+	}
+
+	// Breaks are checked in order, with times written out in full so they
+	// sort and compare as written
+	type span struct{ start, end int }
+	spans := make([]span, 0, len(rule.Breaks))
+	for _, b := range rule.Breaks {
+		bs, ok1 := parseClock(b.Start)
+		be, ok2 := parseClock(b.End)
+		if !ok1 || !ok2 || be <= bs {
+			return ErrInvalidBreak
+		}
+		spans = append(spans, span{bs, be})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	prev := start
+	breaks := make([]model.AvailabilityBreak, 0, len(spans))
+	for _, b := range spans {
+		if b.start < prev || b.end > end {
+			return ErrInvalidBreak
+		}
+		prev = b.end
+		breaks = append(breaks, model.AvailabilityBreak{Start: formatClock(b.start), End: formatClock(b.end)})
+	}
+	rule.StartTime = formatClock(start)
+	rule.EndTime = formatClock(end)
+	rule.Breaks = breaks
+
+	others, err := s.repo.ListAvailabilityRules(ctx, rule.ClinicianID)
+	if err != nil {
+		return fmt.Errorf("failed to list availability rules: %w", err)
+	}
+	for _, other := range others {
+		if other.ID == rule.ID || other.Weekday != rule.Weekday || !datesOverlap(rule, other) {
+			continue
+		}
+		otherStart, _ := parseClock(other.StartTime)
+		otherEnd, _ := parseClock(other.EndTime)
+		if start < otherEnd && otherStart < end {
+			return ErrOverlappingRule
+		}
+	}
+	return nil
+}
+
+func datesOverlap(a, b *model.AvailabilityRule) bool {
+	if a.EffectiveUntil != nil && a.EffectiveUntil.Before(b.EffectiveFrom) {
+		return false
+	}
+	if b.EffectiveUntil != nil && b.EffectiveUntil.Before(a.EffectiveFrom) {
+		return false
+	}
+	return true
+}
+
+// canManage reports whether the caller may change the clinician's schedule:
+// administrators may change anyone's, clinicians their own
+func (s *Service) canManage(ctx context.Context, clinicianID uuid.UUID, userType string) bool {
+	if userType == model.UserTypeAdmin {
+		return true
+	}
+	return clinicians[userType] && s.getCurrentUserID(ctx) == clinicianID
+}
+
+func (s *Service) checkClinician(ctx context.Context, organizationID, clinicianID uuid.UUID) error {
+	user, err := s.userRepo.Get(ctx, clinicianID)
+	if err != nil || user.OrganizationID != organizationID || !clinicians[user.Type] {
+		return ErrClinicianNotFound
+	}
+	return nil
+}
+
+func (s *Service) checkClinic(ctx context.Context, organizationID, clinicID uuid.UUID) error {
+	clinic, err := s.clinicRepo.Get(ctx, clinicID)
+	if err != nil || clinic.OrganizationID != organizationID {
+		return ErrClinicNotFound
+	}
+	return nil
+}
+
+// parseOptionalDate parses an optional date; an empty one is no date
+func parseOptionalDate(s *string) (*time.Time, error) {
+	if s == nil || *s == "" {
+		return nil, nil
+	}
+	d, err := time.Parse(dateLayout, *s)
+	if err != nil {
+		return nil, ErrInvalidDates
+	}
+	return &d, nil
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
@@ -0,0 +1,158 @@
+package terminology
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+)
+
+// loader streams the concepts of one release file to yield
+type loader func(r io.Reader, yield func(*model.Concept) error) error
+
+var loaders = map[string]loader{
+	model.CodeSystemICD10CM: loadICD10CM,
+	model.CodeSystemSNOMED:  loadSNOMED,
+	model.CodeSystemLOINC:   loadLOINC,
+}
+
+// loadICD10CM reads the CMS code descriptions file (icd10cm_codes_YYYY.txt):
+// one code per line, the undotted code followed by whitespace and the
+// description. Only billable codes are listed there, which is what
+// diagnoses should be recorded with.
+func loadICD10CM(r io.Reader, yield func(*model.Concept) error) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("line %d: expected code and description", line)
+		}
+		if err := yield(&model.Concept{
+			Code:    NormalizeCode(model.CodeSystemICD10CM, fields[0]),
+			Display: strings.TrimSpace(fields[1]),
+			Active:  true,
+		}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+const (
+	snomedFSN     = "900000000000003001"
+	snomedSynonym = "900000000000013009"
+)
+
+// loadSNOMED reads an RF2 description snapshot
+// (sct2_Description_Snapshot-en_*.txt). The fully specified name becomes the
+// display and active synonyms are kept for search. A concept counts as
+// active while it has an active fully specified name.
+func loadSNOMED(r io.Reader, yield func(*model.Concept) error) error {
+	concepts := make(map[string]*model.Concept)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if line == 1 {
+			continue // header
+		}
+		// id effectiveTime active moduleId conceptId languageCode typeId term caseSignificanceId
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 9 {
+			return fmt.Errorf("line %d: expected 9 columns, got %d", line, len(cols))
+		}
+		active, conceptID, typeID, term := cols[2] == "1", cols[4], cols[6], cols[7]
+
+		c, ok := concepts[conceptID]
+		if !ok {
+			c = &model.Concept{Code: conceptID}
+			concepts[conceptID] = c
+		}
+		switch {
+		case typeID == snomedFSN && (active || c.Display == ""):
+			c.Display = term
+			c.Active = c.Active || active
+		case typeID == snomedSynonym && active:
+			c.Synonyms = append(c.Synonyms, term)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	codes := make([]string, 0, len(concepts))
+	for code, c := range concepts {
+		if c.Display == "" {
+			continue
+		}
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if err := yield(concepts[code]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLOINC reads the LOINC table (Loinc.csv). The long common name is the
+// display; the short name and component are kept as synonyms. Deprecated and
+// discouraged codes load as inactive.
+func loadLOINC(r io.Reader, yield func(*model.Concept) error) error {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.ToUpper(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"LOINC_NUM", "LONG_COMMON_NAME", "STATUS"} {
+		if _, ok := col[required]; !ok {
+			return fmt.Errorf("missing column %s", required)
+		}
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		c := &model.Concept{
+			Code:    field(rec, "LOINC_NUM"),
+			Display: field(rec, "LONG_COMMON_NAME"),
+			Active:  field(rec, "STATUS") == "ACTIVE",
+		}
+		for _, name := range []string{"SHORTNAME", "COMPONENT"} {
+			if v := field(rec, name); v != "" && v != c.Display {
+				c.Synonyms = append(c.Synonyms, v)
+			}
+		}
+		if err := yield(c); err != nil {
+			return err
+		}
+	}
+}
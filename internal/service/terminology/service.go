@@ -0,0 +1,229 @@
+package terminology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/pkg/storage"
+)
+
+var (
+	ErrUnknownSystem = errors.New("unknown code system")
+	ErrInvalidFile   = errors.New("release file must be inside the release directory")
+	ErrNotFound      = errors.New("code not found")
+)
+
+// ValidationError lists every coded entry that failed validation
+type ValidationError struct {
+	Invalid []model.InvalidCode
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Invalid))
+	for _, inv := range e.Invalid {
+		parts = append(parts, fmt.Sprintf("entry %d (%s %s): %s", inv.Index, inv.System, inv.Code, inv.Reason))
+	}
+	return "invalid codes: " + strings.Join(parts, "; ")
+}
+
+type Config struct {
+	// ReleaseDir holds the code system release files that can be loaded
+	ReleaseDir string
+}
+
+type Service struct {
+	repo    repository.TerminologyRepository
+	auditor *audit.Service
+	config  Config
+}
+
+func NewService(repo repository.TerminologyRepository, auditor *audit.Service, config Config) *Service {
+	return &Service{
+		repo:    repo,
+		auditor: auditor,
+		config:  config,
+	}
+}
+
+// NormalizeCode puts a code in the form it is stored in. ICD-10-CM codes are
+// upper-cased and dotted after the category (E119 becomes E11.9).
+func NormalizeCode(system, code string) string {
+	code = strings.TrimSpace(code)
+	if system == model.CodeSystemICD10CM {
+		code = strings.ToUpper(strings.ReplaceAll(code, ".", ""))
+		if len(code) > 3 {
+			code = code[:3] + "." + code[3:]
+		}
+	}
+	return code
+}
+
+// LoadRelease imports a release file for a code system. Loading a release
+// replaces the previous one; codes it no longer contains become inactive.
+func (s *Service) LoadRelease(ctx context.Context, req *model.LoadReleaseRequest) (*model.TerminologyRelease, error) {
+	system := model.CanonicalCodeSystem(req.System)
+	load, ok := loaders[system]
+	if !ok {
+		return nil, ErrUnknownSystem
+	}
+
+	path, err := storage.ResolveFile(s.config.ReleaseDir, req.File)
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open release file: %w", err)
+	}
+	defer f.Close()
+
+	release := &model.TerminologyRelease{
+		System:  system,
+		Version: req.Version,
+		File:    req.File,
+	}
+	userID := s.getCurrentUserID(ctx)
+	if userID != uuid.Nil {
+		release.LoadedBy = &userID
+	}
+
+	err = s.repo.ImportRelease(ctx, release, func(yield func(*model.Concept) error) error {
+		return load(f, yield)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s release: %w", system, err)
+	}
+
+	s.auditor.Log(ctx, userID, uuid.Nil, "load", "terminology_release", uuid.Nil, &audit.LogOptions{
+		Metadata: release,
+	})
+
+	return release, nil
+}
+
+func (s *Service) ListReleases(ctx context.Context, system string) ([]*model.TerminologyRelease, error) {
+	if system != "" {
+		if system = model.CanonicalCodeSystem(system); system == "" {
+			return nil, ErrUnknownSystem
+		}
+	}
+	return s.repo.ListReleases(ctx, system)
+}
+
+func (s *Service) Lookup(ctx context.Context, system, code string) (*model.Concept, error) {
+	system = model.CanonicalCodeSystem(system)
+	if system == "" {
+		return nil, ErrUnknownSystem
+	}
+	concept, err := s.repo.GetConcept(ctx, system, NormalizeCode(system, code))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return concept, nil
+}
+
+// Search is the typeahead lookup over codes, displays and synonyms
+func (s *Service) Search(ctx context.Context, system, query string, limit int) ([]*model.Concept, error) {
+	system = model.CanonicalCodeSystem(system)
+	if system == "" {
+		return nil, ErrUnknownSystem
+	}
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []*model.Concept{}, nil
+	}
+	return s.repo.SearchConcepts(ctx, system, query, limit)
+}
+
+// Validate checks coded entries against the loaded code systems and returns
+// them normalized: canonical system, stored code form, and the concept's
+// display when the entry had none. Entries must use active codes, and a
+// display given with an entry must match the concept's display or one of
+// its synonyms.
+func (s *Service) Validate(ctx context.Context, entries []model.CodedEntry) ([]model.CodedEntry, error) {
+	normalized := make([]model.CodedEntry, len(entries))
+	var invalid []model.InvalidCode
+
+	bySystem := make(map[string][]int)
+	for i, e := range entries {
+		system := model.CanonicalCodeSystem(e.System)
+		if system == "" {
+			invalid = append(invalid, model.InvalidCode{Index: i, System: e.System, Code: e.Code, Reason: "unknown code system"})
+			continue
+		}
+		if strings.TrimSpace(e.Code) == "" {
+			invalid = append(invalid, model.InvalidCode{Index: i, System: e.System, Code: e.Code, Reason: "code is required"})
+			continue
+		}
+		normalized[i] = model.CodedEntry{System: system, Code: NormalizeCode(system, e.Code), Display: strings.TrimSpace(e.Display)}
+		bySystem[system] = append(bySystem[system], i)
+	}
+
+	for system, idx := range bySystem {
+		codes := make([]string, 0, len(idx))
+		for _, i := range idx {
+			codes = append(codes, normalized[i].Code)
+		}
+		concepts, err := s.repo.GetConcepts(ctx, system, codes)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]*model.Concept, len(concepts))
+		for _, c := range concepts {
+			known[c.Code] = c
+		}
+
+		for _, i := range idx {
+			e := &normalized[i]
+			c, ok := known[e.Code]
+			switch {
+			case !ok:
+				invalid = append(invalid, model.InvalidCode{Index: i, System: e.System, Code: e.Code, Reason: "code not found"})
+			case !c.Active:
+				invalid = append(invalid, model.InvalidCode{Index: i, System: e.System, Code: e.Code, Reason: "code is inactive"})
+			case e.Display == "":
+				e.Display = c.Display
+			case !matchesDisplay(c, e.Display):
+				invalid = append(invalid, model.InvalidCode{Index: i, System: e.System, Code: e.Code, Reason: fmt.Sprintf("display does not match %q", c.Display)})
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		return nil, &ValidationError{Invalid: invalid}
+	}
+	return normalized, nil
+}
+
+func matchesDisplay(c *model.Concept, display string) bool {
+	if strings.EqualFold(c.Display, display) {
+		return true
+	}
+	for _, syn := range c.Synonyms {
+		if strings.EqualFold(syn, display) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
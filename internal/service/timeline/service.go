@@ -0,0 +1,197 @@
+package timeline
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid timeline cursor")
+
+type Service struct {
+	repo        repository.TimelineRepository
+	patientRepo repository.PatientRepository
+	auditor     *audit.Service
+}
+
+func NewService(repo repository.TimelineRepository, patientRepo repository.PatientRepository, auditor *audit.Service) *Service {
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		auditor:     auditor,
+	}
+}
+
+// Get returns one page of the patient's appointments, records, notifications
+// and the audit trail of their appointments, coverages and consents merged
+// into one stream, newest first. The page after it starts at NextCursor.
+// Records follow the medical record access rules for the reader.
+func (s *Service) Get(ctx context.Context, patientID uuid.UUID, reader *model.RecordReader, filter *model.TimelineFilter) (*model.TimelinePage, error) {
+	patient, err := s.patientRepo.Get(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	after, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := filter.PageSize
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	kinds := filter.Kinds
+	if len(kinds) == 0 {
+		kinds = model.TimelineKinds
+	}
+
+	// One extra entry tells whether there is a next page
+	entries, err := s.repo.List(ctx, patientID, &model.TimelineQuery{
+		Kinds:        kinds,
+		From:         filter.From,
+		To:           filter.To,
+		RecordAccess: reader.Access(),
+		After:        after,
+		Limit:        pageSize + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.TimelinePage{PageSize: pageSize}
+	if len(entries) > pageSize {
+		entries = entries[:pageSize]
+		last := entries[len(entries)-1]
+		page.NextCursor = encodeCursor(&model.TimelineCursor{OccurredAt: last.OccurredAt, ID: last.ID})
+	}
+	page.Items = make([]*model.TimelineItem, 0, len(entries))
+	for _, e := range entries {
+		page.Items = append(page.Items, item(e))
+	}
+
+	s.auditor.Log(ctx, reader.UserID, patient.OrganizationID, "read", "patient_timeline", patientID, &audit.LogOptions{
+		AccessReason: reader.Reason,
+		Metadata: map[string]interface{}{
+			"from":   filter.From,
+			"to":     filter.To,
+			"kinds":  filter.Kinds,
+			"cursor": filter.Cursor,
+		},
+	})
+
+	return page, nil
+}
+
+// encodeCursor makes the opaque next_cursor handed to clients
+func encodeCursor(c *model.TimelineCursor) string {
+	raw := c.OccurredAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*model.TimelineCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	c := &model.TimelineCursor{}
+	if c.OccurredAt, err = time.Parse(time.RFC3339Nano, at); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// item turns a timeline entry into the item shown to the client
+func item(e *model.TimelineEntry) *model.TimelineItem {
+	it := &model.TimelineItem{
+		Kind:        e.Kind,
+		OccurredAt:  e.OccurredAt,
+		SourceID:    e.SourceID,
+		Summary:     e.Summary,
+		AccessLevel: e.AccessLevel,
+		ActorID:     e.ActorID,
+	}
+
+	var data map[string]interface{}
+	if len(e.Data) > 0 {
+		json.Unmarshal(e.Data, &data)
+	}
+
+	switch e.Kind {
+	case model.TimelineKindAppointment:
+		it.Title = "Appointment"
+		it.Data = data
+
+	// The clinical content is left out; it is encrypted at rest and is read
+	// through the medical records endpoints, which audit the access reason.
+	case model.TimelineKindMedicalRecord:
+		it.Title = "Medical record: " + e.Action
+		attachments, _ := data["attachments"].([]interface{})
+		it.Data = map[string]interface{}{
+			"type":        e.Action,
+			"attachments": len(attachments),
+		}
+
+	case model.TimelineKindNotification:
+		it.Title = "Notification sent"
+		it.Data = data
+
+	// Audited changes carry the changed fields in data
+	case model.TimelineKindAppointmentStatus:
+		it.Title = "Appointment " + e.Action
+		if status, ok := data["status"].(string); ok {
+			it.Summary = status
+		}
+		it.Data = map[string]interface{}{"status": data["status"]}
+		if reason, ok := data["cancel_reason"]; ok {
+			it.Data["cancel_reason"] = reason
+		}
+
+	case model.TimelineKindInsurance:
+		it.Title = "Insurance " + e.Action
+		if payer, ok := data["payer_name"].(string); ok {
+			it.Summary = payer
+		}
+		it.Data = map[string]interface{}{
+			"priority": data["priority"],
+			"status":   data["status"],
+		}
+
+	case model.TimelineKindConsent:
+		it.Title = "Consent " + e.Action
+		if consentType, ok := data["type"].(string); ok {
+			it.Summary = consentType
+		}
+	}
+
+	return it
+}
@@ -0,0 +1,479 @@
+package waitlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jwalitptl/admin-api/internal/model"
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/internal/service/audit"
+	"github.com/jwalitptl/admin-api/internal/service/notification"
+	"github.com/jwalitptl/admin-api/internal/service/reminder"
+)
+
+var (
+	ErrEntryNotFound     = errors.New("waitlist entry not found")
+	ErrOfferNotFound     = errors.New("waitlist offer not found")
+	ErrPatientNotFound   = errors.New("patient not found")
+	ErrClinicNotFound    = errors.New("clinic not found")
+	ErrInvalidTimezone   = errors.New("unknown timezone")
+	ErrInvalidWindow     = errors.New("preferred windows must be HH:MM and end after they start")
+	ErrEntryClosed       = errors.New("waitlist entry has been booked or removed")
+	ErrOfferExpired      = errors.New("waitlist offer has expired")
+	ErrOfferUnavailable  = errors.New("waitlist offer is no longer open")
+	ErrSlotTaken         = errors.New("slot has already been booked")
+	ErrNoChanges         = errors.New("no changes to the entry were given")
+	ErrEntryHasOpenOffer = errors.New("waitlist entry has a pending offer")
+)
+
+const timeOfDayLayout = "15:04"
+
+type Config struct {
+	// OfferTTL is how long an offer holds its slot for the patient
+	OfferTTL time.Duration
+	// OffersPerSlot is how many patients a freed slot is offered to at once
+	OffersPerSlot int
+}
+
+type Service struct {
+	repo        repository.WaitlistRepository
+	patientRepo repository.PatientRepository
+	clinicRepo  repository.ClinicRepository
+	notifSvc    notification.Service
+	reminders   *reminder.Service
+	auditor     *audit.Service
+	config      Config
+}
+
+func NewService(repo repository.WaitlistRepository, patientRepo repository.PatientRepository, clinicRepo repository.ClinicRepository, notifSvc notification.Service, reminders *reminder.Service, auditor *audit.Service, config Config) *Service {
+	if config.OfferTTL <= 0 {
+		config.OfferTTL = 30 * time.Minute
+	}
+	if config.OffersPerSlot <= 0 {
+		config.OffersPerSlot = 3
+	}
+	return &Service{
+		repo:        repo,
+		patientRepo: patientRepo,
+		clinicRepo:  clinicRepo,
+		notifSvc:    notifSvc,
+		reminders:   reminders,
+		auditor:     auditor,
+		config:      config,
+	}
+}
+
+func (s *Service) CreateEntry(ctx context.Context, organizationID uuid.UUID, req *model.CreateWaitlistEntryRequest) (*model.WaitlistEntry, error) {
+	if err := validateWindows(req.Timezone, req.PreferredWindows); err != nil {
+		return nil, err
+	}
+	patient, err := s.patientRepo.Get(ctx, req.PatientID)
+	if err != nil || patient.OrganizationID != organizationID {
+		return nil, ErrPatientNotFound
+	}
+	clinic, err := s.clinicRepo.Get(ctx, req.ClinicID)
+	if err != nil || clinic.OrganizationID != organizationID {
+		return nil, ErrClinicNotFound
+	}
+
+	entry := &model.WaitlistEntry{
+		OrganizationID:   organizationID,
+		ClinicID:         req.ClinicID,
+		ClinicianID:      req.ClinicianID,
+		ServiceID:        req.ServiceID,
+		PatientID:        req.PatientID,
+		Priority:         req.Priority,
+		Timezone:         req.Timezone,
+		PreferredWindows: req.PreferredWindows,
+		Notes:            req.Notes,
+		Status:           model.WaitlistStatusWaiting,
+		CreatedBy:        s.getCurrentUserID(ctx),
+	}
+	if err := s.repo.CreateEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, entry.CreatedBy, organizationID, "create", "waitlist_entry", entry.ID, &audit.LogOptions{
+		Changes: entry,
+	})
+
+	return entry, nil
+}
+
+func (s *Service) GetEntry(ctx context.Context, organizationID, id uuid.UUID) (*model.WaitlistEntry, error) {
+	entry, err := s.repo.GetEntry(ctx, id)
+	if err != nil || entry.OrganizationID != organizationID {
+		return nil, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+// ListEntries returns the organization's waitlist in the order slots are
+// offered
+func (s *Service) ListEntries(ctx context.Context, organizationID uuid.UUID, filters *model.WaitlistFilters) ([]*model.WaitlistEntry, error) {
+	return s.repo.ListEntries(ctx, organizationID, filters)
+}
+
+func (s *Service) UpdateEntry(ctx context.Context, organizationID, id uuid.UUID, req *model.UpdateWaitlistEntryRequest) (*model.WaitlistEntry, error) {
+	if req.Priority == nil && req.Timezone == nil && req.PreferredWindows == nil && req.Notes == nil {
+		return nil, ErrNoChanges
+	}
+	entry, err := s.GetEntry(ctx, organizationID, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status == model.WaitlistStatusBooked || entry.Status == model.WaitlistStatusRemoved {
+		return nil, ErrEntryClosed
+	}
+
+	if req.Priority != nil {
+		entry.Priority = *req.Priority
+	}
+	if req.Timezone != nil {
+		entry.Timezone = *req.Timezone
+	}
+	if req.PreferredWindows != nil {
+		entry.PreferredWindows = *req.PreferredWindows
+	}
+	if req.Notes != nil {
+		entry.Notes = *req.Notes
+	}
+	if err := validateWindows(entry.Timezone, entry.PreferredWindows); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "update", "waitlist_entry", entry.ID, &audit.LogOptions{
+		Changes: req,
+	})
+
+	return entry, nil
+}
+
+// RemoveEntry takes the patient off the waitlist. An entry holding a
+// pending offer has to have the offer declined first.
+func (s *Service) RemoveEntry(ctx context.Context, organizationID, id uuid.UUID) error {
+	entry, err := s.GetEntry(ctx, organizationID, id)
+	if err != nil {
+		return err
+	}
+	switch entry.Status {
+	case model.WaitlistStatusBooked, model.WaitlistStatusRemoved:
+		return ErrEntryClosed
+	case model.WaitlistStatusOffered:
+		return ErrEntryHasOpenOffer
+	}
+
+	entry.Status = model.WaitlistStatusRemoved
+	if err := s.repo.UpdateEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "remove", "waitlist_entry", entry.ID, nil)
+	return nil
+}
+
+func (s *Service) ListOffers(ctx context.Context, organizationID, entryID uuid.UUID) ([]*model.WaitlistOffer, error) {
+	if _, err := s.GetEntry(ctx, organizationID, entryID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListOffers(ctx, entryID)
+}
+
+// OfferSlot offers the slot freed by a cancelled appointment to the
+// matching waitlisted patients, best priority first. Each offer holds the
+// slot until it expires or another offer for it is accepted. It returns
+// how many offers were made.
+func (s *Service) OfferSlot(ctx context.Context, slot *model.Appointment) (int, error) {
+	now := time.Now()
+	if !slot.StartTime.After(now) {
+		return 0, nil
+	}
+
+	candidates, err := s.repo.ListCandidates(ctx, slot)
+	if err != nil {
+		return 0, err
+	}
+
+	expiresAt := now.Add(s.config.OfferTTL)
+	if slot.StartTime.Before(expiresAt) {
+		expiresAt = slot.StartTime
+	}
+
+	var offers []*model.WaitlistOffer
+	for _, entry := range candidates {
+		if !matchesWindows(entry, slot.StartTime) {
+			continue
+		}
+		offers = append(offers, &model.WaitlistOffer{
+			EntryID:             entry.ID,
+			PatientID:           entry.PatientID,
+			ClinicID:            slot.ClinicID,
+			ClinicianID:         slot.ClinicianID,
+			ServiceID:           slot.ServiceID,
+			SourceAppointmentID: slot.ID,
+			StartTime:           slot.StartTime,
+			EndTime:             slot.EndTime,
+			Status:              model.WaitlistOfferPending,
+			ExpiresAt:           expiresAt,
+		})
+	}
+	if len(offers) == 0 {
+		return 0, nil
+	}
+
+	// Every matching entry is passed on so that entries taken by another
+	// run in the meantime are replaced by the next in line
+	offers, err = s.repo.CreateOffers(ctx, offers, s.config.OffersPerSlot)
+	if err != nil {
+		if errors.Is(err, repository.ErrSlotTaken) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to create waitlist offers: %w", err)
+	}
+
+	for _, offer := range offers {
+		s.notifyOffer(ctx, offer)
+		s.auditor.Log(ctx, offer.PatientID, offer.ClinicID, "offer", "waitlist_offer", offer.ID, &audit.LogOptions{
+			Changes: offer,
+		})
+	}
+	return len(offers), nil
+}
+
+// AcceptOffer books the offered slot for the patient. Only the first
+// acceptance of a slot succeeds; the slot's other offers are withdrawn.
+func (s *Service) AcceptOffer(ctx context.Context, organizationID, offerID uuid.UUID) (*model.Appointment, error) {
+	offer, entry, err := s.getOffer(ctx, organizationID, offerID)
+	if err != nil {
+		return nil, err
+	}
+
+	apt := &model.Appointment{
+		Base: model.Base{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		ClinicID:    offer.ClinicID,
+		ClinicianID: offer.ClinicianID,
+		PatientID:   offer.PatientID,
+		ServiceID:   offer.ServiceID,
+		StartTime:   offer.StartTime,
+		EndTime:     offer.EndTime,
+		Status:      model.AppointmentStatusScheduled,
+		Notes:       entry.Notes,
+	}
+
+	if err := s.repo.AcceptOffer(ctx, offerID, apt); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrSlotTaken):
+			return nil, ErrSlotTaken
+		case errors.Is(err, repository.ErrVersionConflict):
+			return nil, s.closedOfferError(ctx, offerID)
+		}
+		return nil, err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "accept", "waitlist_offer", offerID, &audit.LogOptions{
+		Metadata: map[string]interface{}{
+			"appointment_id": apt.ID,
+			"entry_id":       entry.ID,
+		},
+	})
+	s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "create", "appointment", apt.ID, &audit.LogOptions{
+		Changes: apt,
+	})
+	if err := s.reminders.Schedule(ctx, apt); err != nil {
+		s.auditor.Log(ctx, apt.PatientID, apt.ClinicID, "notification_failed", "appointment", apt.ID, &audit.LogOptions{
+			Metadata: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+
+	return apt, nil
+}
+
+// DeclineOffer releases the offer's hold on the slot and puts the entry back
+// on the waitlist. Once every offer for the slot is declined or expired the
+// slot is offered to the next patients.
+func (s *Service) DeclineOffer(ctx context.Context, organizationID, offerID uuid.UUID) error {
+	offer, _, err := s.getOffer(ctx, organizationID, offerID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeclineOffer(ctx, offerID); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return s.closedOfferError(ctx, offerID)
+		}
+		return err
+	}
+
+	s.auditor.Log(ctx, s.getCurrentUserID(ctx), organizationID, "decline", "waitlist_offer", offerID, nil)
+
+	if _, err := s.reoffer(ctx, offer); err != nil {
+		return fmt.Errorf("failed to offer slot again: %w", err)
+	}
+	return nil
+}
+
+// ExpireOffers expires offers nobody answered in time and offers their
+// slots to the next patients. It returns how many offers expired.
+func (s *Service) ExpireOffers(ctx context.Context) (int, error) {
+	expired, err := s.repo.ExpireOffers(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for _, offer := range expired {
+		if seen[offer.SourceAppointmentID] {
+			continue
+		}
+		seen[offer.SourceAppointmentID] = true
+		if _, err := s.reoffer(ctx, offer); err != nil {
+			return len(expired), fmt.Errorf("failed to offer slot again: %w", err)
+		}
+	}
+	return len(expired), nil
+}
+
+// reoffer offers the slot of a closed offer to the next patients, unless
+// it is still held by another offer or was booked
+func (s *Service) reoffer(ctx context.Context, closed *model.WaitlistOffer) (int, error) {
+	open, err := s.repo.HasOpenOffers(ctx, closed.SourceAppointmentID)
+	if err != nil || open {
+		return 0, err
+	}
+	return s.OfferSlot(ctx, &model.Appointment{
+		Base:        model.Base{ID: closed.SourceAppointmentID},
+		ClinicID:    closed.ClinicID,
+		ClinicianID: closed.ClinicianID,
+		ServiceID:   closed.ServiceID,
+		StartTime:   closed.StartTime,
+		EndTime:     closed.EndTime,
+	})
+}
+
+func (s *Service) getOffer(ctx context.Context, organizationID, offerID uuid.UUID) (*model.WaitlistOffer, *model.WaitlistEntry, error) {
+	offer, err := s.repo.GetOffer(ctx, offerID)
+	if err != nil {
+		return nil, nil, ErrOfferNotFound
+	}
+	entry, err := s.repo.GetEntry(ctx, offer.EntryID)
+	if err != nil || entry.OrganizationID != organizationID {
+		return nil, nil, ErrOfferNotFound
+	}
+	return offer, entry, nil
+}
+
+// closedOfferError explains why an offer could not be answered
+func (s *Service) closedOfferError(ctx context.Context, offerID uuid.UUID) error {
+	offer, err := s.repo.GetOffer(ctx, offerID)
+	if err == nil && (offer.Status == model.WaitlistOfferExpired ||
+		(offer.Status == model.WaitlistOfferPending && !offer.ExpiresAt.After(time.Now()))) {
+		return ErrOfferExpired
+	}
+	if err == nil && offer.Status == model.WaitlistOfferSuperseded {
+		return ErrSlotTaken
+	}
+	return ErrOfferUnavailable
+}
+
+// notifyOffer tells the patient about the offer. A failed notification is
+// audited; the offer still stands and can be answered from the front desk.
+func (s *Service) notifyOffer(ctx context.Context, offer *model.WaitlistOffer) {
+	patient, err := s.patientRepo.Get(ctx, offer.PatientID)
+	if err == nil && patient.Email == "" {
+		return
+	}
+	if err == nil {
+		err = s.notifSvc.Send(ctx, &model.Notification{
+			UserID:         patient.ID,
+			OrganizationID: patient.OrganizationID,
+			PatientID:      &patient.ID,
+			Channel:        "email",
+			Priority:       "high",
+			Subject:        "An earlier appointment is available",
+			Content: fmt.Sprintf(
+				"An appointment on %s is available. It is held for you until %s; reply with offer %s to book it.",
+				offer.StartTime.Format(time.RFC1123), offer.ExpiresAt.Format(time.RFC1123), offer.ID,
+			),
+			Recipient: patient.Email,
+		})
+	}
+	if err != nil {
+		s.auditor.Log(ctx, offer.PatientID, offer.ClinicID, "notification_failed", "waitlist_offer", offer.ID, &audit.LogOptions{
+			Metadata: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
+	}
+}
+
+func (s *Service) getCurrentUserID(ctx context.Context) uuid.UUID {
+	if userID, ok := ctx.Value("user_id").(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
+
+// matchesWindows reports whether start falls in one of the entry's
+// preferred windows
+func matchesWindows(entry *model.WaitlistEntry, start time.Time) bool {
+	if len(entry.PreferredWindows) == 0 {
+		return true
+	}
+	loc, err := time.LoadLocation(entry.Timezone)
+	if err != nil {
+		return false
+	}
+	local := start.In(loc)
+	clock := local.Format(timeOfDayLayout)
+
+	for _, w := range entry.PreferredWindows {
+		if len(w.Weekdays) > 0 && !containsWeekday(w.Weekdays, local.Weekday()) {
+			continue
+		}
+		// "HH:MM" strings compare in time order
+		if clock >= w.Start && clock < w.End {
+			return true
+		}
+	}
+	return false
+}
+
+func validateWindows(timezone string, windows []model.WaitlistWindow) error {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return ErrInvalidTimezone
+	}
+	for _, w := range windows {
+		start, err := time.Parse(timeOfDayLayout, w.Start)
+		if err != nil {
+			return ErrInvalidWindow
+		}
+		end, err := time.Parse(timeOfDayLayout, w.End)
+		if err != nil || !end.After(start) {
+			return ErrInvalidWindow
+		}
+	}
+	return nil
+}
+
+func containsWeekday(days []time.Weekday, wd time.Weekday) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
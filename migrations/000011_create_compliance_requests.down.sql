@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_notifications_patient;
+ALTER TABLE notifications DROP COLUMN IF EXISTS patient_id;
+
+DROP TABLE IF EXISTS patient_consents;
+DROP TABLE IF EXISTS compliance_requests;
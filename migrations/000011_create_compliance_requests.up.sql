@@ -0,0 +1,62 @@
+-- Data subject requests (GDPR access/erasure) and their generated artifacts
+CREATE TABLE compliance_requests (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    type VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    requested_by UUID,
+    region_code VARCHAR(10),
+    artifact_path TEXT,
+    artifact_checksum VARCHAR(64),
+    artifact_expires_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_compliance_requests_patient ON compliance_requests(patient_id);
+CREATE INDEX idx_compliance_requests_pending ON compliance_requests(created_at) WHERE status = 'pending';
+
+-- Consent history; withdrawn consents are kept for the record
+CREATE TABLE patient_consents (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    type VARCHAR(50) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    recorded_by UUID,
+    granted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    withdrawn_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_patient_consents_patient ON patient_consents(patient_id);
+
+-- Notifications are linked to the patient they concern so they can be exported
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID,
+    organization_id UUID,
+    channel VARCHAR(50) NOT NULL,
+    priority VARCHAR(20),
+    subject TEXT,
+    content TEXT NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_retry_at TIMESTAMP WITH TIME ZONE,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS patient_id UUID REFERENCES patients(id);
+CREATE INDEX IF NOT EXISTS idx_notifications_patient ON notifications(patient_id);
@@ -0,0 +1,14 @@
+-- Legal holds block erasure of the listed data categories (all when empty)
+CREATE TABLE legal_holds (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    reason TEXT NOT NULL,
+    categories TEXT[] NOT NULL DEFAULT '{}',
+    placed_by UUID,
+    placed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    released_by UUID,
+    released_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_legal_holds_patient_active ON legal_holds(patient_id) WHERE released_at IS NULL;
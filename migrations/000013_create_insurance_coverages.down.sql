@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS eligibility_checks;
+DROP TABLE IF EXISTS insurance_coverage_history;
+DROP TABLE IF EXISTS insurance_coverages;
@@ -0,0 +1,54 @@
+CREATE TABLE insurance_coverages (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    payer_name VARCHAR(255) NOT NULL,
+    payer_id VARCHAR(100),
+    plan_name VARCHAR(255),
+    member_number VARCHAR(100) NOT NULL,
+    group_number VARCHAR(100),
+    subscriber_name VARCHAR(255),
+    subscriber_relationship VARCHAR(20) NOT NULL,
+    priority VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    effective_from DATE NOT NULL,
+    effective_to DATE,
+    card_front_image TEXT,
+    card_back_image TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_insurance_coverages_patient ON insurance_coverages(patient_id);
+-- One active coverage per priority level per patient
+CREATE UNIQUE INDEX idx_insurance_coverages_active_priority
+    ON insurance_coverages(patient_id, priority) WHERE status = 'active';
+
+CREATE TABLE insurance_coverage_history (
+    id UUID PRIMARY KEY,
+    coverage_id UUID NOT NULL REFERENCES insurance_coverages(id),
+    action VARCHAR(20) NOT NULL,
+    snapshot JSONB NOT NULL,
+    changed_by UUID,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_insurance_coverage_history_coverage ON insurance_coverage_history(coverage_id);
+
+CREATE TABLE eligibility_checks (
+    id UUID PRIMARY KEY,
+    coverage_id UUID NOT NULL REFERENCES insurance_coverages(id),
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    appointment_id UUID REFERENCES appointments(id) ON DELETE SET NULL,
+    provider VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    service_date DATE NOT NULL,
+    copay NUMERIC(10, 2),
+    message TEXT,
+    response JSONB,
+    checked_by UUID,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_eligibility_checks_coverage ON eligibility_checks(coverage_id);
+CREATE INDEX idx_eligibility_checks_appointment ON eligibility_checks(appointment_id);
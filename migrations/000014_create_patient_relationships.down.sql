@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_audit_logs_on_behalf_of;
+
+ALTER TABLE audit_logs
+    DROP COLUMN IF EXISTS proxy_relationship_id,
+    DROP COLUMN IF EXISTS on_behalf_of;
+
+DROP TABLE IF EXISTS patient_relationships;
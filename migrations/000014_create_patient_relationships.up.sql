@@ -0,0 +1,31 @@
+-- Relationships let a related user or patient act for the patient within the
+-- granted scopes while the relationship is valid
+CREATE TABLE patient_relationships (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    related_patient_id UUID REFERENCES patients(id),
+    related_user_id UUID REFERENCES users(id),
+    type VARCHAR(50) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    is_emergency_contact BOOLEAN NOT NULL DEFAULT FALSE,
+    valid_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    valid_to TIMESTAMP WITH TIME ZONE,
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID,
+    revoked_by UUID,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CHECK (related_patient_id IS NOT NULL OR related_user_id IS NOT NULL)
+);
+
+CREATE INDEX idx_patient_relationships_patient ON patient_relationships(patient_id);
+CREATE INDEX idx_patient_relationships_user ON patient_relationships(related_user_id) WHERE revoked_at IS NULL;
+
+-- Proxy actions record the dependent and the relationship alongside the actor
+ALTER TABLE audit_logs
+    ADD COLUMN on_behalf_of UUID,
+    ADD COLUMN proxy_relationship_id UUID;
+
+CREATE INDEX idx_audit_logs_on_behalf_of ON audit_logs(on_behalf_of) WHERE on_behalf_of IS NOT NULL;
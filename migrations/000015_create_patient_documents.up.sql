@@ -0,0 +1,19 @@
+-- Metadata for patient documents; the encrypted content lives in the blob store
+CREATE TABLE patient_documents (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    medical_record_id UUID REFERENCES medical_records(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    backend VARCHAR(20) NOT NULL,
+    storage_key TEXT NOT NULL,
+    uploaded_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_patient_documents_patient ON patient_documents(patient_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_patient_documents_record ON patient_documents(medical_record_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS clinic_mrn_configs;
+DROP TABLE IF EXISTS patient_identifiers;
@@ -0,0 +1,29 @@
+-- Identifiers issued to patients by clinics, labs, payers and registries.
+-- A value is unique within its system in each organization.
+CREATE TABLE patient_identifiers (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    system VARCHAR(255) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (organization_id, system, value)
+);
+
+CREATE INDEX idx_patient_identifiers_patient ON patient_identifiers(patient_id);
+CREATE INDEX idx_patient_identifiers_value ON patient_identifiers(organization_id, value);
+
+-- Per-clinic MRN numbering
+CREATE TABLE clinic_mrn_configs (
+    clinic_id UUID PRIMARY KEY REFERENCES clinics(id),
+    system VARCHAR(255) NOT NULL,
+    pattern VARCHAR(100) NOT NULL,
+    next_sequence BIGINT NOT NULL DEFAULT 1,
+    check_digit VARCHAR(10) NOT NULL DEFAULT 'luhn',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
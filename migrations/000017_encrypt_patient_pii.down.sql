@@ -0,0 +1,10 @@
+-- Encrypted rows must be decrypted back into the plaintext columns before
+-- rolling back; dropping the columns discards their ciphertext.
+ALTER TABLE patients
+    DROP COLUMN IF EXISTS pii_key_version,
+    DROP COLUMN IF EXISTS phone_index,
+    DROP COLUMN IF EXISTS email_index,
+    DROP COLUMN IF EXISTS date_of_birth_encrypted,
+    DROP COLUMN IF EXISTS address_encrypted,
+    DROP COLUMN IF EXISTS phone_encrypted,
+    DROP COLUMN IF EXISTS email_encrypted;
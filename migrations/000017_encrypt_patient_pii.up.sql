@@ -0,0 +1,21 @@
+-- Field-level encryption of patient PII. Rows start at pii_key_version 0
+-- (plaintext) and are converted in place by the backfill job; encrypted rows
+-- keep NULL in the plaintext columns. Adding nullable columns and a constant
+-- default does not rewrite the table.
+ALTER TABLE patients
+    ADD COLUMN email_encrypted BYTEA,
+    ADD COLUMN phone_encrypted BYTEA,
+    ADD COLUMN address_encrypted BYTEA,
+    ADD COLUMN date_of_birth_encrypted BYTEA,
+    ADD COLUMN email_index VARCHAR(64),
+    ADD COLUMN phone_index VARCHAR(64),
+    ADD COLUMN pii_key_version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE patients
+    ALTER COLUMN email DROP NOT NULL,
+    ALTER COLUMN phone DROP NOT NULL,
+    ALTER COLUMN address DROP NOT NULL,
+    ALTER COLUMN date_of_birth DROP NOT NULL;
+
+-- The indexes on the new columns are built concurrently by 000035-000037 so
+-- the patients table stays writable while they build
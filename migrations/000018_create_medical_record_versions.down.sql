@@ -0,0 +1,5 @@
+ALTER TABLE medical_record_versions DROP CONSTRAINT IF EXISTS fk_medical_record_versions_amendment;
+DROP TABLE IF EXISTS medical_record_amendments;
+DROP RULE IF EXISTS medical_record_versions_no_update ON medical_record_versions;
+DROP TABLE IF EXISTS medical_record_versions;
+ALTER TABLE medical_records DROP COLUMN IF EXISTS version;
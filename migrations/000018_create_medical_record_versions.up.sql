@@ -0,0 +1,62 @@
+-- Append-only history of medical records. The record row mirrors the latest
+-- version; every create, correction and accepted amendment adds a row here.
+ALTER TABLE medical_records ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Versions and amendments go with their record when an erasure deletes it
+CREATE TABLE medical_record_versions (
+    id UUID PRIMARY KEY,
+    record_id UUID NOT NULL REFERENCES medical_records(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    description TEXT,
+    diagnosis BYTEA,
+    treatment BYTEA,
+    medications JSONB,
+    attachments JSONB,
+    access_level VARCHAR(50),
+    author_id UUID,
+    reason TEXT NOT NULL,
+    amendment_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (record_id, version)
+);
+
+-- Existing records start their history at version 1
+INSERT INTO medical_record_versions (
+    id, record_id, version, type, description, diagnosis, treatment,
+    medications, attachments, access_level, author_id, reason, created_at
+)
+SELECT
+    gen_random_uuid(), id, 1, type, description, diagnosis, treatment,
+    to_jsonb(medications), to_jsonb(attachments), access_level, created_by,
+    'imported', updated_at
+FROM medical_records;
+
+-- History rows are never changed; erasure removes them with the record
+CREATE RULE medical_record_versions_no_update AS
+    ON UPDATE TO medical_record_versions DO INSTEAD NOTHING;
+
+CREATE TABLE medical_record_amendments (
+    id UUID PRIMARY KEY,
+    record_id UUID NOT NULL REFERENCES medical_records(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    requested_by UUID NOT NULL,
+    base_version INTEGER NOT NULL,
+    justification TEXT NOT NULL,
+    proposed_changes JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reviewed_by UUID,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    decision_reason TEXT,
+    resulting_version INTEGER,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_medical_record_amendments_patient ON medical_record_amendments(patient_id, status);
+CREATE INDEX idx_medical_record_amendments_record ON medical_record_amendments(record_id);
+
+ALTER TABLE medical_record_versions
+    ADD CONSTRAINT fk_medical_record_versions_amendment
+    FOREIGN KEY (amendment_id) REFERENCES medical_record_amendments(id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS medical_record_codes;
+DROP TABLE IF EXISTS terminology_releases;
+DROP TABLE IF EXISTS terminology_concepts;
@@ -0,0 +1,42 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Codes loaded from ICD-10-CM, SNOMED CT and LOINC release files. Codes a
+-- newer release drops are marked inactive rather than deleted.
+CREATE TABLE terminology_concepts (
+    system TEXT NOT NULL,
+    code TEXT NOT NULL,
+    display TEXT NOT NULL,
+    synonyms TEXT[],
+    search_text TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    version TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (system, code)
+);
+
+CREATE INDEX idx_terminology_concepts_search ON terminology_concepts USING GIN (search_text gin_trgm_ops);
+
+CREATE TABLE terminology_releases (
+    id BIGSERIAL PRIMARY KEY,
+    system TEXT NOT NULL,
+    version TEXT NOT NULL,
+    file TEXT NOT NULL,
+    concept_count INTEGER NOT NULL DEFAULT 0,
+    loaded_by UUID,
+    loaded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_terminology_releases_system ON terminology_releases(system, loaded_at DESC);
+
+-- Diagnosis is encrypted, so the codes of structured diagnoses are kept
+-- alongside for reporting
+CREATE TABLE medical_record_codes (
+    record_id UUID NOT NULL REFERENCES medical_records(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL,
+    system TEXT NOT NULL,
+    code TEXT NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (record_id, system, code)
+);
+
+CREATE INDEX idx_medical_record_codes_code ON medical_record_codes(system, code text_pattern_ops);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS interaction_datasets;
+DROP TABLE IF EXISTS drug_allergy_interactions;
+DROP TABLE IF EXISTS drug_interactions;
+DROP TABLE IF EXISTS prescriptions;
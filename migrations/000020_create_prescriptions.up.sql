@@ -0,0 +1,56 @@
+CREATE TABLE prescriptions (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id),
+    organization_id UUID NOT NULL,
+    prescriber_id UUID NOT NULL,
+    drug_system TEXT NOT NULL,
+    drug_code TEXT NOT NULL,
+    drug_name TEXT NOT NULL,
+    strength TEXT NOT NULL,
+    route TEXT NOT NULL,
+    frequency TEXT NOT NULL,
+    quantity NUMERIC NOT NULL CHECK (quantity > 0),
+    refills INTEGER NOT NULL DEFAULT 0 CHECK (refills >= 0),
+    instructions TEXT,
+    start_date DATE NOT NULL,
+    stop_date DATE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('active', 'paused', 'discontinued')),
+    status_reason TEXT,
+    acknowledged_warnings JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_prescriptions_patient ON prescriptions(patient_id, status);
+
+-- Interaction datasets are replaced wholesale on each load. Drug pairs are
+-- stored with drug_a < drug_b.
+CREATE TABLE drug_interactions (
+    drug_a TEXT NOT NULL,
+    drug_b TEXT NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    description TEXT NOT NULL,
+    PRIMARY KEY (drug_a, drug_b),
+    CHECK (drug_a < drug_b)
+);
+
+CREATE TABLE drug_allergy_interactions (
+    allergy_system TEXT NOT NULL,
+    allergy_code TEXT NOT NULL,
+    drug_code TEXT NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    description TEXT NOT NULL,
+    PRIMARY KEY (allergy_system, allergy_code, drug_code)
+);
+
+CREATE INDEX idx_drug_allergy_interactions_drug ON drug_allergy_interactions(drug_code);
+
+CREATE TABLE interaction_datasets (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    version TEXT NOT NULL,
+    file TEXT NOT NULL,
+    entry_count INTEGER NOT NULL DEFAULT 0,
+    loaded_by UUID,
+    loaded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
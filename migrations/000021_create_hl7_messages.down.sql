@@ -0,0 +1 @@
+DROP TABLE IF EXISTS hl7_messages;
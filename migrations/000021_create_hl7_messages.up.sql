@@ -0,0 +1,21 @@
+-- Inbound HL7 v2 messages. The raw message is encrypted; patient_id stays
+-- empty while a message waits in the reconciliation queue.
+CREATE TABLE hl7_messages (
+    id UUID PRIMARY KEY,
+    control_id TEXT NOT NULL,
+    sending_application TEXT NOT NULL,
+    sending_facility TEXT NOT NULL,
+    message_type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('received', 'processed', 'unmatched', 'resolved', 'discarded', 'failed')),
+    error TEXT,
+    patient_id UUID REFERENCES patients(id),
+    raw BYTEA NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    resolved_by UUID,
+    resolution TEXT,
+    UNIQUE (sending_application, sending_facility, control_id)
+);
+
+CREATE INDEX idx_hl7_messages_status ON hl7_messages(status, received_at);
+CREATE INDEX idx_hl7_messages_patient ON hl7_messages(patient_id);
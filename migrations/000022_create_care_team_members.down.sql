@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_medical_records_access;
+DROP TABLE IF EXISTS care_team_members;
@@ -0,0 +1,13 @@
+-- Care team membership opens a patient's private and HIPAA medical records
+-- to the user
+CREATE TABLE care_team_members (
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    added_by UUID NOT NULL,
+    added_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (patient_id, user_id)
+);
+
+CREATE INDEX idx_care_team_members_user ON care_team_members(user_id);
+CREATE INDEX idx_medical_records_access ON medical_records(patient_id, access_level);
@@ -0,0 +1,10 @@
+DROP RULE IF EXISTS medical_record_versions_no_update ON medical_record_versions;
+
+CREATE RULE medical_record_versions_no_update AS
+    ON UPDATE TO medical_record_versions DO INSTEAD NOTHING;
+
+DROP INDEX IF EXISTS idx_medical_record_versions_encryption_key;
+DROP INDEX IF EXISTS idx_medical_records_encryption_key;
+ALTER TABLE medical_record_versions DROP COLUMN IF EXISTS encryption_key_id;
+ALTER TABLE medical_records DROP COLUMN IF EXISTS organization_id;
+DROP TABLE IF EXISTS data_encryption_keys;
@@ -0,0 +1,45 @@
+-- Per-organization data encryption keys, stored only wrapped by a
+-- key-encryption key held in the KMS
+CREATE TABLE data_encryption_keys (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    wrapped_key BYTEA NOT NULL,
+    kek_id TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    retired_at TIMESTAMP WITH TIME ZONE,
+    rewrapped_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One active key per organization
+CREATE UNIQUE INDEX idx_data_encryption_keys_active
+    ON data_encryption_keys(organization_id) WHERE status = 'active';
+CREATE INDEX idx_data_encryption_keys_kek ON data_encryption_keys(kek_id);
+
+-- Records carry their organization so they can be sealed under its key
+ALTER TABLE medical_records ADD COLUMN IF NOT EXISTS organization_id UUID;
+
+UPDATE medical_records m SET organization_id = p.organization_id
+FROM patients p
+WHERE p.id = m.patient_id AND m.organization_id IS NULL;
+
+ALTER TABLE medical_record_versions ADD COLUMN IF NOT EXISTS encryption_key_id UUID;
+
+CREATE INDEX idx_medical_records_encryption_key ON medical_records(encryption_key_id);
+CREATE INDEX idx_medical_record_versions_encryption_key ON medical_record_versions(encryption_key_id);
+
+-- History stays append-only, except that re-encryption may reseal a
+-- version's encrypted fields under a new data key
+DROP RULE medical_record_versions_no_update ON medical_record_versions;
+
+CREATE RULE medical_record_versions_no_update AS
+    ON UPDATE TO medical_record_versions
+    WHERE NEW.encryption_key_id IS NOT DISTINCT FROM OLD.encryption_key_id
+        OR (NEW.id, NEW.record_id, NEW.version, NEW.type, NEW.description,
+            NEW.medications, NEW.attachments, NEW.access_level, NEW.author_id,
+            NEW.reason, NEW.amendment_id, NEW.created_at)
+        IS DISTINCT FROM
+           (OLD.id, OLD.record_id, OLD.version, OLD.type, OLD.description,
+            OLD.medications, OLD.attachments, OLD.access_level, OLD.author_id,
+            OLD.reason, OLD.amendment_id, OLD.created_at)
+    DO INSTEAD NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS note_addenda;
+DROP TABLE IF EXISTS clinical_notes;
+DROP TABLE IF EXISTS note_templates;
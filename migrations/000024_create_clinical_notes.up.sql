@@ -0,0 +1,53 @@
+-- Organization-defined note layouts with typed sections
+CREATE TABLE note_templates (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    sections JSONB NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (organization_id, name)
+);
+
+-- Notes move from draft to signed to locked. Content is encrypted under the
+-- organization's data key; content_hash is taken at signing and audited.
+CREATE TABLE clinical_notes (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL,
+    template_id UUID NOT NULL REFERENCES note_templates(id),
+    author_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    content BYTEA NOT NULL,
+    encryption_key_id UUID,
+    content_hash TEXT,
+    signed_at TIMESTAMP WITH TIME ZONE,
+    cosign_required BOOLEAN NOT NULL DEFAULT FALSE,
+    cosigner_id UUID,
+    cosigned_at TIMESTAMP WITH TIME ZONE,
+    locked_by UUID,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_clinical_notes_patient ON clinical_notes(patient_id, created_at);
+CREATE INDEX idx_clinical_notes_encryption_key ON clinical_notes(encryption_key_id);
+
+-- Addenda are the only change a locked note takes
+CREATE TABLE note_addenda (
+    id UUID PRIMARY KEY,
+    note_id UUID NOT NULL REFERENCES clinical_notes(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL,
+    content BYTEA NOT NULL,
+    encryption_key_id UUID,
+    content_hash TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_note_addenda_note ON note_addenda(note_id, created_at);
+CREATE INDEX idx_note_addenda_encryption_key ON note_addenda(encryption_key_id);
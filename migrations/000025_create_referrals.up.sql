@@ -0,0 +1,28 @@
+-- Referrals to another organization. While one is open and unexpired the
+-- receiving organization can read the shared scopes and attached records.
+CREATE TABLE referrals (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    source_organization_id UUID NOT NULL REFERENCES organizations(id),
+    target_organization_id UUID NOT NULL REFERENCES organizations(id),
+    referred_by UUID NOT NULL,
+    specialty TEXT NOT NULL,
+    reason TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'sent',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    record_ids UUID[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    decided_by UUID,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    decision_note TEXT,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    revoked_by UUID,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CHECK (source_organization_id <> target_organization_id)
+);
+
+CREATE INDEX idx_referrals_source ON referrals(source_organization_id, created_at);
+CREATE INDEX idx_referrals_target ON referrals(target_organization_id, created_at);
+CREATE INDEX idx_referrals_patient ON referrals(patient_id);
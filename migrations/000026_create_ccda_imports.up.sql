@@ -0,0 +1,27 @@
+-- Inbound C-CDA documents staged for a clinician to reconcile. The document
+-- and its staged entries are encrypted in content.
+CREATE TABLE ccda_imports (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    document_id TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT '',
+    document_date TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(20) NOT NULL DEFAULT 'staged',
+    demographics_match BOOLEAN NOT NULL DEFAULT TRUE,
+    content BYTEA NOT NULL,
+    encryption_key_id UUID,
+    imported_by UUID NOT NULL,
+    reconciled_by UUID,
+    reconciled_at TIMESTAMP WITH TIME ZONE,
+    discard_reason TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- A document can be imported again only after its earlier import was discarded
+CREATE UNIQUE INDEX idx_ccda_imports_document ON ccda_imports(patient_id, document_id) WHERE status <> 'discarded';
+CREATE INDEX idx_ccda_imports_patient ON ccda_imports(patient_id, created_at);
+CREATE INDEX idx_ccda_imports_key ON ccda_imports(encryption_key_id);
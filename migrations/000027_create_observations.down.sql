@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS observation_reference_ranges;
+DROP TABLE IF EXISTS observations;
@@ -0,0 +1,65 @@
+-- Vital signs and point-of-care measurements, one row per value. Values are
+-- normalized to the type's UCUM unit; what was entered is kept alongside.
+CREATE TABLE observations (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    appointment_id UUID REFERENCES appointments(id) ON DELETE SET NULL,
+    type VARCHAR(40) NOT NULL,
+    code VARCHAR(20) NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    unit VARCHAR(20) NOT NULL,
+    original_value DOUBLE PRECISION NOT NULL,
+    original_unit VARCHAR(20) NOT NULL,
+    flag VARCHAR(2),
+    reference_low DOUBLE PRECISION,
+    reference_high DOUBLE PRECISION,
+    status VARCHAR(20) NOT NULL DEFAULT 'final',
+    status_reason TEXT,
+    observed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    recorded_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_observations_series ON observations(patient_id, type, observed_at);
+CREATE INDEX idx_observations_appointment ON observations(appointment_id) WHERE appointment_id IS NOT NULL;
+
+-- Normal ranges by sex and age band. Rows without an organization are the
+-- defaults; ages are in months, max exclusive.
+CREATE TABLE observation_reference_ranges (
+    id UUID PRIMARY KEY,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    type VARCHAR(40) NOT NULL,
+    sex VARCHAR(10) NOT NULL DEFAULT '',
+    min_age_months INTEGER NOT NULL DEFAULT 0,
+    max_age_months INTEGER,
+    low DOUBLE PRECISION,
+    high DOUBLE PRECISION,
+    critical_low DOUBLE PRECISION,
+    critical_high DOUBLE PRECISION,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (max_age_months IS NULL OR max_age_months > min_age_months)
+);
+
+CREATE INDEX idx_observation_reference_ranges_type ON observation_reference_ranges(type, organization_id);
+
+INSERT INTO observation_reference_ranges (id, type, min_age_months, max_age_months, low, high, critical_low, critical_high) VALUES
+    (gen_random_uuid(), 'heart_rate', 0, 12, 100, 160, 70, 220),
+    (gen_random_uuid(), 'heart_rate', 12, 36, 90, 150, 60, 200),
+    (gen_random_uuid(), 'heart_rate', 36, 72, 80, 140, 55, 180),
+    (gen_random_uuid(), 'heart_rate', 72, 144, 70, 120, 50, 170),
+    (gen_random_uuid(), 'heart_rate', 144, 216, 60, 100, 45, 150),
+    (gen_random_uuid(), 'heart_rate', 216, NULL, 60, 100, 40, 130),
+    (gen_random_uuid(), 'systolic_bp', 12, 72, 80, 110, 60, 150),
+    (gen_random_uuid(), 'systolic_bp', 72, 144, 90, 115, 70, 160),
+    (gen_random_uuid(), 'systolic_bp', 144, 216, 90, 120, 70, 170),
+    (gen_random_uuid(), 'systolic_bp', 216, NULL, 90, 129, 70, 180),
+    (gen_random_uuid(), 'diastolic_bp', 12, 72, 50, 70, 35, 100),
+    (gen_random_uuid(), 'diastolic_bp', 72, 144, 55, 75, 40, 105),
+    (gen_random_uuid(), 'diastolic_bp', 144, 216, 60, 80, 40, 110),
+    (gen_random_uuid(), 'diastolic_bp', 216, NULL, 60, 80, 40, 120),
+    (gen_random_uuid(), 'body_temperature', 0, NULL, 36.1, 37.5, 35, 40),
+    (gen_random_uuid(), 'oxygen_saturation', 0, NULL, 95, 100, 88, NULL),
+    (gen_random_uuid(), 'blood_glucose', 0, NULL, 70, 140, 54, 400),
+    (gen_random_uuid(), 'bmi', 216, NULL, 18.5, 24.9, NULL, NULL);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS patient_allergies;
+DROP TABLE IF EXISTS patient_problems;
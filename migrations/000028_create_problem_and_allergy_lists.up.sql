@@ -0,0 +1,75 @@
+-- Problem and allergy lists. A code is listed at most once per patient;
+-- resolved and refuted entries stay so they are not added again.
+CREATE TABLE patient_problems (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    code_system TEXT NOT NULL,
+    code TEXT NOT NULL,
+    display TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    severity VARCHAR(20),
+    onset_date TIMESTAMP WITH TIME ZONE,
+    resolved_date TIMESTAMP WITH TIME ZONE,
+    note TEXT,
+    source_record_id UUID REFERENCES medical_records(id) ON DELETE SET NULL,
+    recorded_by UUID NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (patient_id, code_system, code)
+);
+
+CREATE TABLE patient_allergies (
+    id UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    code_system TEXT NOT NULL,
+    code TEXT NOT NULL,
+    display TEXT NOT NULL,
+    category VARCHAR(20),
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    severity VARCHAR(20),
+    reactions JSONB NOT NULL DEFAULT '[]',
+    onset_date TIMESTAMP WITH TIME ZONE,
+    resolved_date TIMESTAMP WITH TIME ZONE,
+    note TEXT,
+    source_record_id UUID REFERENCES medical_records(id) ON DELETE SET NULL,
+    recorded_by UUID NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (patient_id, code_system, code)
+);
+
+CREATE INDEX idx_patient_allergies_code ON patient_allergies(code_system, code) WHERE status = 'active';
+
+-- Start both lists from the coded diagnoses already recorded, each code from
+-- the first record it appeared in
+INSERT INTO patient_problems (
+    id, patient_id, organization_id, code_system, code, display, status,
+    onset_date, source_record_id, recorded_by, created_at, updated_at
+)
+SELECT DISTINCT ON (c.patient_id, c.system, c.code)
+    gen_random_uuid(), c.patient_id, p.organization_id, c.system, c.code, COALESCE(t.display, c.code),
+    'active', c.recorded_at, c.record_id, m.created_by, NOW(), NOW()
+FROM medical_record_codes c
+JOIN medical_records m ON m.id = c.record_id AND m.deleted_at IS NULL
+JOIN patients p ON p.id = c.patient_id
+LEFT JOIN terminology_concepts t ON t.system = c.system AND t.code = c.code
+WHERE m.type <> 'allergy'
+ORDER BY c.patient_id, c.system, c.code, c.recorded_at;
+
+INSERT INTO patient_allergies (
+    id, patient_id, organization_id, code_system, code, display, status,
+    onset_date, source_record_id, recorded_by, created_at, updated_at
+)
+SELECT DISTINCT ON (c.patient_id, c.system, c.code)
+    gen_random_uuid(), c.patient_id, p.organization_id, c.system, c.code, COALESCE(t.display, c.code),
+    'active', c.recorded_at, c.record_id, m.created_by, NOW(), NOW()
+FROM medical_record_codes c
+JOIN medical_records m ON m.id = c.record_id AND m.deleted_at IS NULL
+JOIN patients p ON p.id = c.patient_id
+LEFT JOIN terminology_concepts t ON t.system = c.system AND t.code = c.code
+WHERE m.type = 'allergy'
+ORDER BY c.patient_id, c.system, c.code, c.recorded_at;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS organization_holidays;
+DROP TABLE IF EXISTS clinician_schedule_exceptions;
+DROP TABLE IF EXISTS clinician_availability_rules;
@@ -0,0 +1,57 @@
+-- Clinician availability: weekly rules per clinic, one-off exceptions and
+-- organization holidays. These replace the clinician_schedules table the
+-- appointment repository used to query but no migration created.
+CREATE TABLE clinician_availability_rules (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    clinician_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    timezone TEXT NOT NULL,
+    breaks JSONB NOT NULL DEFAULT '[]',
+    effective_from DATE NOT NULL,
+    effective_until DATE,
+    created_by UUID NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CHECK (end_time > start_time),
+    CHECK (effective_until IS NULL OR effective_until >= effective_from)
+);
+
+CREATE INDEX idx_availability_rules_clinician ON clinician_availability_rules(clinician_id, weekday);
+CREATE INDEX idx_availability_rules_clinic ON clinician_availability_rules(clinic_id);
+
+CREATE TABLE clinician_schedule_exceptions (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    clinician_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Leave without a clinic applies at every clinic
+    clinic_id UUID REFERENCES clinics(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('leave', 'extra_session')),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason TEXT,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CHECK (ends_at > starts_at),
+    CHECK (kind = 'leave' OR clinic_id IS NOT NULL)
+);
+
+CREATE INDEX idx_schedule_exceptions_clinician ON clinician_schedule_exceptions(clinician_id, starts_at);
+
+CREATE TABLE organization_holidays (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- A holiday without a clinic closes every clinic of the organization
+    clinic_id UUID REFERENCES clinics(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    name TEXT NOT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_organization_holidays_unique
+    ON organization_holidays(organization_id, COALESCE(clinic_id, '00000000-0000-0000-0000-000000000000'::uuid), date);
@@ -0,0 +1,3 @@
+ALTER TABLE services
+    DROP COLUMN IF EXISTS buffer_after,
+    DROP COLUMN IF EXISTS buffer_before;
@@ -0,0 +1,21 @@
+-- Bookable services. medical_records and appointments have referred to this
+-- table without a migration creating it, so it is created only if missing.
+CREATE TABLE IF NOT EXISTS services (
+    id UUID PRIMARY KEY,
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    duration INTEGER NOT NULL CHECK (duration > 0),
+    price NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    requires_auth BOOLEAN NOT NULL DEFAULT FALSE,
+    max_capacity INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Minutes kept free before and after each booking of the service
+ALTER TABLE services
+    ADD COLUMN IF NOT EXISTS buffer_before INTEGER NOT NULL DEFAULT 0 CHECK (buffer_before >= 0),
+    ADD COLUMN IF NOT EXISTS buffer_after INTEGER NOT NULL DEFAULT 0 CHECK (buffer_after >= 0);
@@ -0,0 +1,30 @@
+-- Recurring appointment series. Occurrences are rows in appointments that
+-- point back at their series; occurrence_start is where the series' rule
+-- puts the occurrence, which stays fixed when the occurrence is moved.
+CREATE TABLE appointment_series (
+    id UUID PRIMARY KEY,
+    clinic_id UUID NOT NULL REFERENCES clinics(id),
+    clinician_id UUID NOT NULL REFERENCES users(id),
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    service_id UUID NOT NULL REFERENCES services(id),
+    rrule TEXT NOT NULL,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    duration INTEGER NOT NULL CHECK (duration > 0),
+    timezone TEXT NOT NULL,
+    exdates JSONB NOT NULL DEFAULT '[]',
+    notes TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('active', 'cancelled')),
+    parent_id UUID REFERENCES appointment_series(id),
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_appointment_series_patient ON appointment_series(patient_id);
+CREATE INDEX idx_appointment_series_clinician ON appointment_series(clinician_id);
+
+ALTER TABLE appointments
+    ADD COLUMN series_id UUID REFERENCES appointment_series(id) ON DELETE SET NULL,
+    ADD COLUMN occurrence_start TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_appointments_series ON appointments(series_id, occurrence_start) WHERE series_id IS NOT NULL;
@@ -0,0 +1,47 @@
+-- Waitlist of patients wanting an earlier appointment, and the offers of
+-- freed slots made to them. A pending offer holds its slot until it expires.
+CREATE TABLE waitlist_entries (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    clinician_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_id UUID NOT NULL REFERENCES services(id),
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    timezone TEXT NOT NULL,
+    preferred_windows JSONB NOT NULL DEFAULT '[]',
+    notes TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('waiting', 'offered', 'booked', 'removed')),
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_waitlist_entries_match
+    ON waitlist_entries(clinic_id, clinician_id, service_id, priority DESC, created_at)
+    WHERE status = 'waiting';
+CREATE INDEX idx_waitlist_entries_patient ON waitlist_entries(patient_id);
+
+CREATE TABLE waitlist_offers (
+    id UUID PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES waitlist_entries(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    clinician_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_id UUID NOT NULL REFERENCES services(id),
+    source_appointment_id UUID NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'accepted', 'declined', 'expired', 'superseded')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    appointment_id UUID REFERENCES appointments(id) ON DELETE SET NULL,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CHECK (end_time > start_time)
+);
+
+-- An entry is offered a slot once, and a slot is booked by one acceptance
+CREATE UNIQUE INDEX idx_waitlist_offers_entry_slot ON waitlist_offers(source_appointment_id, entry_id);
+CREATE UNIQUE INDEX idx_waitlist_offers_accepted ON waitlist_offers(source_appointment_id) WHERE status = 'accepted';
+CREATE INDEX idx_waitlist_offers_holds ON waitlist_offers(clinician_id, start_time) WHERE status = 'pending';
+CREATE INDEX idx_waitlist_offers_expiry ON waitlist_offers(expires_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS appointment_holds;
+ALTER TABLE appointments DROP CONSTRAINT IF EXISTS appointments_clinician_no_overlap;
@@ -0,0 +1,28 @@
+-- A clinician cannot have two active appointments that overlap. Overlapping
+-- rows already in the table have to be resolved before this applies.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE appointments ADD CONSTRAINT appointments_clinician_no_overlap
+    EXCLUDE USING gist (clinician_id WITH =, tstzrange(start_time, end_time, '[)') WITH &&)
+    WHERE (deleted_at IS NULL AND status IN ('scheduled', 'confirmed'));
+
+-- Short-lived reservations of a slot while a booking is completed. Expired
+-- holds are ignored, and a clinician's are cleared before a new hold is
+-- taken, so the constraint only compares live holds.
+CREATE TABLE appointment_holds (
+    id UUID PRIMARY KEY,
+    clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+    clinician_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    service_id UUID NOT NULL REFERENCES services(id),
+    patient_id UUID REFERENCES patients(id) ON DELETE CASCADE,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CHECK (end_time > start_time),
+    CONSTRAINT appointment_holds_no_overlap
+        EXCLUDE USING gist (clinician_id WITH =, tstzrange(start_time, end_time, '[)') WITH &&)
+);
+
+CREATE INDEX idx_appointment_holds_expiry ON appointment_holds(expires_at);
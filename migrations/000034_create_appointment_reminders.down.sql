@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS appointment_reminders;
+DROP TABLE IF EXISTS reminder_schedules;
+ALTER TABLE patients DROP COLUMN IF EXISTS timezone, DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,42 @@
+-- Reminders are written in the patient's language and local time
+ALTER TABLE patients
+    ADD COLUMN locale TEXT NOT NULL DEFAULT 'en',
+    ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+
+-- Reminder schedules per organization, optionally narrowed to a service
+CREATE TABLE reminder_schedules (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    service_id UUID REFERENCES services(id) ON DELETE CASCADE,
+    rules JSONB NOT NULL DEFAULT '[]',
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_reminder_schedules_scope
+    ON reminder_schedules(organization_id, COALESCE(service_id, '00000000-0000-0000-0000-000000000000'));
+
+-- The reminders due for each appointment. Workers claim due rows with
+-- SKIP LOCKED; a claim that is not finished by locked_until is retried.
+CREATE TABLE appointment_reminders (
+    id UUID PRIMARY KEY,
+    appointment_id UUID NOT NULL REFERENCES appointments(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL CHECK (channel IN ('email', 'sms', 'push')),
+    offset_minutes INTEGER NOT NULL,
+    send_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'sending', 'sent', 'failed', 'cancelled', 'skipped')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    -- Erasing notifications must not be blocked by the reminders that sent them
+    notification_id UUID REFERENCES notifications(id) ON DELETE SET NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_appointment_reminders_due ON appointment_reminders(send_at) WHERE status IN ('pending', 'sending');
+CREATE INDEX idx_appointment_reminders_appointment ON appointment_reminders(appointment_id);
@@ -0,0 +1,4 @@
+-- Exact-match lookup by email goes through the blind index. CONCURRENTLY
+-- keeps patients writable during the build; it cannot run in a transaction,
+-- so this migration holds this one statement only.
+CREATE INDEX CONCURRENTLY idx_patients_email_index ON patients(email_index);
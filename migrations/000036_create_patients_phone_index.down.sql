@@ -0,0 +1 @@
+DROP INDEX CONCURRENTLY IF EXISTS idx_patients_phone_index;
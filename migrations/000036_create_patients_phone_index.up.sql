@@ -0,0 +1,3 @@
+-- Exact-match lookup by phone goes through the blind index. Built
+-- concurrently on its own, like 000035.
+CREATE INDEX CONCURRENTLY idx_patients_phone_index ON patients(phone_index);
@@ -0,0 +1,3 @@
+-- Lets the backfill find the remaining plaintext rows cheaply. Built
+-- concurrently on its own, like 000035.
+CREATE INDEX CONCURRENTLY idx_patients_pii_plaintext ON patients(id) WHERE pii_key_version = 0;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_patients_user_id;
+ALTER TABLE patients DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,13 @@
+-- Links a patient record to the patient's own login. Proxy requests treat
+-- the caller as the patient only through this link, never through email.
+ALTER TABLE patients ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_patients_user_id ON patients(user_id) WHERE user_id IS NOT NULL;
+
+-- Existing accounts were matched by email; link the plaintext rows once.
+-- Encrypted rows have to be linked explicitly.
+UPDATE patients p SET user_id = u.id
+FROM users u
+WHERE u.type = 'patient' AND u.deleted_at IS NULL
+    AND p.email IS NOT NULL AND lower(p.email) = lower(u.email)
+    AND NOT EXISTS (SELECT 1 FROM patients o WHERE o.user_id = u.id);
@@ -0,0 +1,397 @@
+// Package ccda writes and reads HL7 Consolidated CDA (C-CDA R2.1)
+// Continuity of Care Documents.
+package ccda
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotCDA       = errors.New("ccda: document is not an HL7 CDA ClinicalDocument")
+	ErrNoPatient    = errors.New("ccda: document has no patient")
+	ErrMalformed    = errors.New("ccda: malformed document")
+	ErrNoDocumentID = errors.New("ccda: document has no id")
+)
+
+// Code systems are identified by OID in CDA documents
+const (
+	SystemLOINC                = "2.16.840.1.113883.6.1"
+	SystemSNOMED               = "2.16.840.1.113883.6.96"
+	SystemRxNorm               = "2.16.840.1.113883.6.88"
+	SystemICD10CM              = "2.16.840.1.113883.6.90"
+	SystemActCode              = "2.16.840.1.113883.5.4"
+	SystemAdministrativeGender = "2.16.840.1.113883.5.1"
+	SystemObservationInterp    = "2.16.840.1.113883.5.83"
+)
+
+// Section codes (LOINC) of the sections a CCD carries
+const (
+	SectionProblems    = "11450-4"
+	SectionAllergies   = "48765-2"
+	SectionMedications = "10160-0"
+	SectionResults     = "30954-2"
+	SectionEncounters  = "46240-8"
+)
+
+// Code is a coded concept as it appears in a document
+type Code struct {
+	Code    string `json:"code"`
+	System  string `json:"system"`
+	Display string `json:"display,omitempty"`
+}
+
+// Identifier is an instance identifier: an OID or UUID root and an optional
+// extension within it
+type Identifier struct {
+	Root      string `json:"root"`
+	Extension string `json:"extension,omitempty"`
+}
+
+// Document is a Continuity of Care Document
+type Document struct {
+	ID        Identifier
+	Title     string
+	CreatedAt time.Time
+	Patient   Patient
+	// Author and Custodian are the organization that produced the document
+	// and the one that keeps it
+	Author      Organization
+	Custodian   Organization
+	Problems    []Problem
+	Allergies   []Allergy
+	Medications []Medication
+	Results     []Result
+	Encounters  []Encounter
+}
+
+type Organization struct {
+	ID   Identifier
+	Name string
+}
+
+type Patient struct {
+	IDs        []Identifier
+	GivenName  string
+	FamilyName string
+	// Gender is an HL7 AdministrativeGender code: M, F or UN
+	Gender    string
+	BirthDate time.Time
+	Address   string
+	Phone     string
+	Email     string
+}
+
+type Problem struct {
+	ID       Identifier
+	Code     Code
+	Active   bool
+	Onset    *time.Time
+	Resolved *time.Time
+}
+
+type Allergy struct {
+	ID       Identifier
+	Allergen Code
+	Active   bool
+	Onset    *time.Time
+	Reaction *Code
+	Severity *Code
+}
+
+type Medication struct {
+	ID   Identifier
+	Drug Code
+	// Instructions is the free-text sig: strength, route and frequency
+	Instructions string
+	Active       bool
+	Start        *time.Time
+	Stop         *time.Time
+}
+
+// Result is a panel of observations, such as one lab order
+type Result struct {
+	ID           Identifier
+	Panel        Code
+	Time         *time.Time
+	Observations []Observation
+}
+
+type Observation struct {
+	Code Code
+	// Value is numeric when Unit is set, otherwise text
+	Value          string
+	Unit           string
+	ReferenceRange string
+	// Interpretation is an ObservationInterpretation code such as H, L or N
+	Interpretation string
+	Time           *time.Time
+}
+
+type Encounter struct {
+	ID    Identifier
+	Code  Code
+	Text  string
+	Start time.Time
+	End   *time.Time
+}
+
+// Template identifiers of the C-CDA R2.1 templates this package writes
+var (
+	templateUSRealmHeader = ii{Root: "2.16.840.1.113883.10.20.22.1.1", Extension: "2015-08-01"}
+	templateCCD           = ii{Root: "2.16.840.1.113883.10.20.22.1.2", Extension: "2015-08-01"}
+
+	templateProblemSection     = ii{Root: "2.16.840.1.113883.10.20.22.2.5.1", Extension: "2015-08-01"}
+	templateProblemConcern     = ii{Root: "2.16.840.1.113883.10.20.22.4.3", Extension: "2015-08-01"}
+	templateProblemObservation = ii{Root: "2.16.840.1.113883.10.20.22.4.4", Extension: "2015-08-01"}
+	templateAllergySection     = ii{Root: "2.16.840.1.113883.10.20.22.2.6.1", Extension: "2015-08-01"}
+	templateAllergyConcern     = ii{Root: "2.16.840.1.113883.10.20.22.4.30", Extension: "2015-08-01"}
+	templateAllergyObservation = ii{Root: "2.16.840.1.113883.10.20.22.4.7", Extension: "2014-06-09"}
+	templateReaction           = ii{Root: "2.16.840.1.113883.10.20.22.4.9", Extension: "2014-06-09"}
+	templateSeverity           = ii{Root: "2.16.840.1.113883.10.20.22.4.8", Extension: "2014-06-09"}
+	templateMedicationSection  = ii{Root: "2.16.840.1.113883.10.20.22.2.1.1", Extension: "2014-06-09"}
+	templateMedicationActivity = ii{Root: "2.16.840.1.113883.10.20.22.4.16", Extension: "2014-06-09"}
+	templateMedicationInfo     = ii{Root: "2.16.840.1.113883.10.20.22.4.23", Extension: "2014-06-09"}
+	templateResultSection      = ii{Root: "2.16.840.1.113883.10.20.22.2.3.1", Extension: "2015-08-01"}
+	templateResultOrganizer    = ii{Root: "2.16.840.1.113883.10.20.22.4.1", Extension: "2015-08-01"}
+	templateResultObservation  = ii{Root: "2.16.840.1.113883.10.20.22.4.2", Extension: "2015-08-01"}
+	templateEncounterSection   = ii{Root: "2.16.840.1.113883.10.20.22.2.22.1", Extension: "2015-08-01"}
+	templateEncounterActivity  = ii{Root: "2.16.840.1.113883.10.20.22.4.49", Extension: "2015-08-01"}
+)
+
+// Marshal writes doc as a C-CDA R2.1 Continuity of Care Document
+func Marshal(doc *Document) ([]byte, error) {
+	p := doc.Patient
+	role := patientRole{
+		Patient: patient{
+			Name:                     personName{Given: nonEmpty(p.GivenName), Family: p.FamilyName},
+			AdministrativeGenderCode: cd{Code: p.Gender, CodeSystem: SystemAdministrativeGender},
+			BirthTime:                newDate(p.BirthDate),
+		},
+	}
+	for _, id := range p.IDs {
+		role.IDs = append(role.IDs, ii{Root: id.Root, Extension: id.Extension})
+	}
+	if p.Address != "" {
+		role.Addr = &addr{Use: "HP", StreetAddressLine: []string{p.Address}}
+	}
+	if p.Phone != "" {
+		role.Telecoms = append(role.Telecoms, telecom{Use: "HP", Value: "tel:" + p.Phone})
+	}
+	if p.Email != "" {
+		role.Telecoms = append(role.Telecoms, telecom{Value: "mailto:" + p.Email})
+	}
+
+	out := clinicalDocument{
+		XSI:                 namespaceXSI,
+		RealmCode:           &cd{Code: "US"},
+		TypeID:              &ii{Root: "2.16.840.1.113883.1.3", Extension: "POCD_HD000040"},
+		TemplateIDs:         []ii{templateUSRealmHeader, templateCCD},
+		ID:                  ii{Root: doc.ID.Root, Extension: doc.ID.Extension},
+		Code:                cd{Code: "34133-9", CodeSystem: SystemLOINC, CodeSystemName: "LOINC", DisplayName: "Summarization of Episode Note"},
+		Title:               doc.Title,
+		EffectiveTime:       newTS(doc.CreatedAt),
+		ConfidentialityCode: cd{Code: "N", CodeSystem: "2.16.840.1.113883.5.25"},
+		LanguageCode:        &cd{Code: "en-US"},
+		RecordTarget:        recordTarget{PatientRole: role},
+		Author: &author{
+			Time: newTS(doc.CreatedAt),
+			AssignedAuthor: assignedEntity{
+				ID:                      ii{Root: doc.Author.ID.Root, Extension: doc.Author.ID.Extension},
+				RepresentedOrganization: &organization{IDs: []ii{{Root: doc.Author.ID.Root}}, Name: doc.Author.Name},
+			},
+		},
+		Custodian: &custodian{},
+	}
+	out.Custodian.AssignedCustodian.RepresentedCustodianOrganization = organization{
+		IDs:  []ii{{Root: doc.Custodian.ID.Root, Extension: doc.Custodian.ID.Extension}},
+		Name: doc.Custodian.Name,
+	}
+
+	out.Component.StructuredBody.Components = []sectionComponent{
+		{Section: problemSection(doc.Problems)},
+		{Section: allergySection(doc.Allergies)},
+		{Section: medicationSection(doc.Medications)},
+		{Section: resultSection(doc.Results)},
+		{Section: encounterSection(doc.Encounters)},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return nil, fmt.Errorf("ccda: failed to encode document: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// Parse reads a CDA document and returns the patient and the entries of the
+// CCD sections it recognizes. Sections are matched by their LOINC code;
+// entries without a usable code are skipped.
+func Parse(data []byte) (*Document, error) {
+	var in clinicalDocument
+	if err := xml.Unmarshal(data, &in); err != nil {
+		var unexpected xml.UnmarshalError
+		if errors.As(err, &unexpected) {
+			return nil, ErrNotCDA
+		}
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if in.ID.Root == "" {
+		return nil, ErrNoDocumentID
+	}
+
+	role := in.RecordTarget.PatientRole
+	if role.Patient.Name.Family == "" && len(role.Patient.Name.Given) == 0 {
+		return nil, ErrNoPatient
+	}
+
+	doc := &Document{
+		ID:    Identifier{Root: in.ID.Root, Extension: in.ID.Extension},
+		Title: strings.TrimSpace(in.Title),
+		Patient: Patient{
+			GivenName:  strings.Join(role.Patient.Name.Given, " "),
+			FamilyName: role.Patient.Name.Family,
+			Gender:     role.Patient.AdministrativeGenderCode.Code,
+		},
+	}
+	if t := parseTS(in.EffectiveTime.Value); t != nil {
+		doc.CreatedAt = *t
+	}
+	if t := parseTS(role.Patient.BirthTime.Value); t != nil {
+		doc.Patient.BirthDate = *t
+	}
+	for _, id := range role.IDs {
+		if id.Root != "" {
+			doc.Patient.IDs = append(doc.Patient.IDs, Identifier{Root: id.Root, Extension: id.Extension})
+		}
+	}
+	if role.Addr != nil {
+		parts := append([]string{}, role.Addr.StreetAddressLine...)
+		for _, part := range []string{role.Addr.City, role.Addr.State, role.Addr.PostalCode, role.Addr.Country} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		doc.Patient.Address = strings.Join(parts, ", ")
+	}
+	for _, t := range role.Telecoms {
+		switch {
+		case strings.HasPrefix(t.Value, "tel:") && doc.Patient.Phone == "":
+			doc.Patient.Phone = strings.TrimPrefix(t.Value, "tel:")
+		case strings.HasPrefix(t.Value, "mailto:") && doc.Patient.Email == "":
+			doc.Patient.Email = strings.TrimPrefix(t.Value, "mailto:")
+		}
+	}
+	if in.Author != nil && in.Author.AssignedAuthor.RepresentedOrganization != nil {
+		doc.Author.Name = in.Author.AssignedAuthor.RepresentedOrganization.Name
+	}
+	if in.Custodian != nil {
+		org := in.Custodian.AssignedCustodian.RepresentedCustodianOrganization
+		doc.Custodian.Name = org.Name
+		if len(org.IDs) > 0 {
+			doc.Custodian.ID = Identifier{Root: org.IDs[0].Root, Extension: org.IDs[0].Extension}
+		}
+	}
+
+	for _, c := range in.Component.StructuredBody.Components {
+		s := c.Section
+		switch s.Code.Code {
+		case SectionProblems:
+			doc.Problems = append(doc.Problems, readProblems(s)...)
+		case SectionAllergies:
+			doc.Allergies = append(doc.Allergies, readAllergies(s)...)
+		case SectionMedications:
+			doc.Medications = append(doc.Medications, readMedications(s)...)
+		case SectionResults:
+			doc.Results = append(doc.Results, readResults(s)...)
+		case SectionEncounters:
+			doc.Encounters = append(doc.Encounters, readEncounters(s)...)
+		}
+	}
+	return doc, nil
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// codeOf writes an uncoded concept as its original text
+func codeOf(c Code) cd {
+	if c.Code == "" {
+		return cd{NullFlavor: "OTH", OriginalText: c.Display}
+	}
+	return cd{Code: c.Code, CodeSystem: c.System, DisplayName: c.Display}
+}
+
+func codeFrom(c cd) (Code, bool) {
+	if c.Code == "" || c.NullFlavor != "" {
+		return Code{}, false
+	}
+	display := c.DisplayName
+	if display == "" {
+		display = strings.TrimSpace(c.OriginalText)
+	}
+	return Code{Code: c.Code, System: c.CodeSystem, Display: display}, true
+}
+
+func idOf(id Identifier) ii {
+	if id.Root == "" {
+		return ii{NullFlavor: "NI"}
+	}
+	return ii{Root: id.Root, Extension: id.Extension}
+}
+
+func idFrom(id *ii) Identifier {
+	if id == nil {
+		return Identifier{}
+	}
+	return Identifier{Root: id.Root, Extension: id.Extension}
+}
+
+func interval(low, high *time.Time) *ivlts {
+	v := &ivlts{Low: optionalTS(low)}
+	if high != nil {
+		v.High = optionalTS(high)
+	}
+	return v
+}
+
+func intervalFrom(v *ivlts) (low, high *time.Time) {
+	if v == nil {
+		return nil, nil
+	}
+	if v.Low != nil {
+		low = parseTS(v.Low.Value)
+	}
+	if v.High != nil {
+		high = parseTS(v.High.Value)
+	}
+	if low == nil {
+		low = parseTS(v.Value)
+	}
+	return low, high
+}
+
+func statusOf(active bool) cs {
+	if active {
+		return cs{Code: "active"}
+	}
+	return cs{Code: "completed"}
+}
+
+func display(c Code) string {
+	if c.Display != "" {
+		return c.Display
+	}
+	return c.Code
+}
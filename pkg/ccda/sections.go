@@ -0,0 +1,466 @@
+package ccda
+
+import (
+	"fmt"
+	"strings"
+)
+
+func problemSection(problems []Problem) section {
+	s := section{
+		TemplateIDs: []ii{templateProblemSection},
+		Code:        cd{Code: SectionProblems, CodeSystem: SystemLOINC, CodeSystemName: "LOINC", DisplayName: "Problem List"},
+		Title:       "Problems",
+	}
+	for _, p := range problems {
+		status := "Resolved"
+		if p.Active {
+			status = "Active"
+		}
+		s.Text.Items = append(s.Text.Items, fmt.Sprintf("%s (%s)", display(p.Code), status))
+
+		value := codeOf(p.Code)
+		value.Type = "CD"
+		s.Entries = append(s.Entries, entry{
+			TypeCode: "DRIV",
+			Act: &act{
+				ClassCode:     "ACT",
+				MoodCode:      "EVN",
+				TemplateIDs:   []ii{templateProblemConcern},
+				ID:            idOf(p.ID),
+				Code:          cd{Code: "CONC", CodeSystem: "2.16.840.1.113883.5.6", DisplayName: "Concern"},
+				StatusCode:    statusOf(p.Active),
+				EffectiveTime: interval(p.Onset, p.Resolved),
+				EntryRelationships: []entryRelationship{{
+					TypeCode: "SUBJ",
+					Observation: &observation{
+						ClassCode:     "OBS",
+						MoodCode:      "EVN",
+						TemplateIDs:   []ii{templateProblemObservation},
+						Code:          cd{Code: "55607006", CodeSystem: SystemSNOMED, DisplayName: "Problem"},
+						StatusCode:    cs{Code: "completed"},
+						EffectiveTime: interval(p.Onset, p.Resolved),
+						Value:         valueOf(value),
+					},
+				}},
+			},
+		})
+	}
+	if len(problems) == 0 {
+		s.Text.Items = []string{"No known problems"}
+	}
+	return s
+}
+
+func readProblems(s section) []Problem {
+	var problems []Problem
+	for _, e := range s.Entries {
+		if e.Act == nil {
+			continue
+		}
+		for _, rel := range e.Act.EntryRelationships {
+			if rel.Observation == nil || rel.Observation.Value == nil {
+				continue
+			}
+			code, ok := codeFrom(cdFrom(rel.Observation.Value))
+			if !ok {
+				continue
+			}
+			onset, resolved := intervalFrom(rel.Observation.EffectiveTime)
+			if onset == nil && resolved == nil {
+				onset, resolved = intervalFrom(e.Act.EffectiveTime)
+			}
+			problems = append(problems, Problem{
+				ID:       idFrom(&e.Act.ID),
+				Code:     code,
+				Active:   e.Act.StatusCode.Code != "completed" && resolved == nil,
+				Onset:    onset,
+				Resolved: resolved,
+			})
+		}
+	}
+	return problems
+}
+
+func allergySection(allergies []Allergy) section {
+	s := section{
+		TemplateIDs: []ii{templateAllergySection},
+		Code:        cd{Code: SectionAllergies, CodeSystem: SystemLOINC, CodeSystemName: "LOINC", DisplayName: "Allergies and adverse reactions"},
+		Title:       "Allergies",
+	}
+	for _, a := range allergies {
+		item := display(a.Allergen)
+		if a.Reaction != nil {
+			item += ": " + display(*a.Reaction)
+		}
+		if a.Severity != nil {
+			item += " (" + display(*a.Severity) + ")"
+		}
+		s.Text.Items = append(s.Text.Items, item)
+
+		obs := &observation{
+			ClassCode:     "OBS",
+			MoodCode:      "EVN",
+			TemplateIDs:   []ii{templateAllergyObservation},
+			Code:          cd{Code: "ASSERTION", CodeSystem: SystemActCode},
+			StatusCode:    cs{Code: "completed"},
+			EffectiveTime: interval(a.Onset, nil),
+			Value:         &anyValue{Type: "CD", Code: "419199007", CodeSystem: SystemSNOMED, DisplayName: "Allergy to substance"},
+		}
+		var p participant
+		p.TypeCode = "CSM"
+		p.ParticipantRole.ClassCode = "MANU"
+		p.ParticipantRole.PlayingEntity.ClassCode = "MMAT"
+		p.ParticipantRole.PlayingEntity.Code = codeOf(a.Allergen)
+		obs.Participants = []participant{p}
+
+		if a.Reaction != nil {
+			value := codeOf(*a.Reaction)
+			value.Type = "CD"
+			obs.EntryRelationships = append(obs.EntryRelationships, entryRelationship{
+				TypeCode:     "MFST",
+				InversionInd: "true",
+				Observation: &observation{
+					ClassCode:   "OBS",
+					MoodCode:    "EVN",
+					TemplateIDs: []ii{templateReaction},
+					Code:        cd{Code: "ASSERTION", CodeSystem: SystemActCode},
+					StatusCode:  cs{Code: "completed"},
+					Value:       valueOf(value),
+				},
+			})
+		}
+		if a.Severity != nil {
+			value := codeOf(*a.Severity)
+			value.Type = "CD"
+			obs.EntryRelationships = append(obs.EntryRelationships, entryRelationship{
+				TypeCode:     "SUBJ",
+				InversionInd: "true",
+				Observation: &observation{
+					ClassCode:   "OBS",
+					MoodCode:    "EVN",
+					TemplateIDs: []ii{templateSeverity},
+					Code:        cd{Code: "SEV", CodeSystem: SystemActCode},
+					StatusCode:  cs{Code: "completed"},
+					Value:       valueOf(value),
+				},
+			})
+		}
+
+		s.Entries = append(s.Entries, entry{
+			TypeCode: "DRIV",
+			Act: &act{
+				ClassCode:          "ACT",
+				MoodCode:           "EVN",
+				TemplateIDs:        []ii{templateAllergyConcern},
+				ID:                 idOf(a.ID),
+				Code:               cd{Code: "CONC", CodeSystem: "2.16.840.1.113883.5.6", DisplayName: "Concern"},
+				StatusCode:         statusOf(a.Active),
+				EffectiveTime:      interval(a.Onset, nil),
+				EntryRelationships: []entryRelationship{{TypeCode: "SUBJ", Observation: obs}},
+			},
+		})
+	}
+	if len(allergies) == 0 {
+		s.Text.Items = []string{"No known allergies"}
+	}
+	return s
+}
+
+func readAllergies(s section) []Allergy {
+	var allergies []Allergy
+	for _, e := range s.Entries {
+		if e.Act == nil {
+			continue
+		}
+		for _, rel := range e.Act.EntryRelationships {
+			obs := rel.Observation
+			if obs == nil || len(obs.Participants) == 0 {
+				continue
+			}
+			allergen, ok := codeFrom(obs.Participants[0].ParticipantRole.PlayingEntity.Code)
+			if !ok {
+				continue
+			}
+			onset, _ := intervalFrom(obs.EffectiveTime)
+			allergy := Allergy{
+				ID:       idFrom(&e.Act.ID),
+				Allergen: allergen,
+				Active:   e.Act.StatusCode.Code != "completed",
+				Onset:    onset,
+			}
+			for _, sub := range obs.EntryRelationships {
+				if sub.Observation == nil || sub.Observation.Value == nil {
+					continue
+				}
+				code, ok := codeFrom(cdFrom(sub.Observation.Value))
+				if !ok {
+					continue
+				}
+				switch {
+				case sub.TypeCode == "MFST" && allergy.Reaction == nil:
+					allergy.Reaction = &code
+				case hasTemplate(sub.Observation.TemplateIDs, templateSeverity.Root) || sub.Observation.Code.Code == "SEV":
+					allergy.Severity = &code
+				}
+			}
+			allergies = append(allergies, allergy)
+		}
+	}
+	return allergies
+}
+
+func medicationSection(medications []Medication) section {
+	s := section{
+		TemplateIDs: []ii{templateMedicationSection},
+		Code:        cd{Code: SectionMedications, CodeSystem: SystemLOINC, CodeSystemName: "LOINC", DisplayName: "History of medication use"},
+		Title:       "Medications",
+	}
+	for _, m := range medications {
+		item := display(m.Drug)
+		if m.Instructions != "" {
+			item += " - " + m.Instructions
+		}
+		s.Text.Items = append(s.Text.Items, item)
+
+		sa := &substanceAdministration{
+			ClassCode:     "SBADM",
+			MoodCode:      "INT",
+			TemplateIDs:   []ii{templateMedicationActivity},
+			ID:            idOf(m.ID),
+			Text:          m.Instructions,
+			StatusCode:    statusOf(m.Active),
+			EffectiveTime: []ivlts{*interval(m.Start, m.Stop)},
+		}
+		sa.EffectiveTime[0].Type = "IVL_TS"
+		sa.Consumable.ManufacturedProduct.ClassCode = "MANU"
+		sa.Consumable.ManufacturedProduct.TemplateIDs = []ii{templateMedicationInfo}
+		sa.Consumable.ManufacturedProduct.ManufacturedMaterial.Code = codeOf(m.Drug)
+		s.Entries = append(s.Entries, entry{TypeCode: "DRIV", SubstanceAdministration: sa})
+	}
+	if len(medications) == 0 {
+		s.Text.Items = []string{"No known medications"}
+	}
+	return s
+}
+
+func readMedications(s section) []Medication {
+	var medications []Medication
+	for _, e := range s.Entries {
+		sa := e.SubstanceAdministration
+		if sa == nil {
+			continue
+		}
+		drug, ok := codeFrom(sa.Consumable.ManufacturedProduct.ManufacturedMaterial.Code)
+		if !ok {
+			continue
+		}
+		m := Medication{
+			ID:           idFrom(&sa.ID),
+			Drug:         drug,
+			Instructions: strings.TrimSpace(sa.Text),
+			Active:       sa.StatusCode.Code == "active",
+		}
+		// The first effectiveTime is the period; a second one, if any, is
+		// the dosing frequency
+		if len(sa.EffectiveTime) > 0 {
+			m.Start, m.Stop = intervalFrom(&sa.EffectiveTime[0])
+		}
+		medications = append(medications, m)
+	}
+	return medications
+}
+
+func resultSection(results []Result) section {
+	s := section{
+		TemplateIDs: []ii{templateResultSection},
+		Code:        cd{Code: SectionResults, CodeSystem: SystemLOINC, CodeSystemName: "LOINC", DisplayName: "Relevant diagnostic tests and/or laboratory data"},
+		Title:       "Results",
+	}
+	for _, r := range results {
+		org := &organizer{
+			ClassCode:   "BATTERY",
+			MoodCode:    "EVN",
+			TemplateIDs: []ii{templateResultOrganizer},
+			ID:          idOf(r.ID),
+			Code:        codeOf(r.Panel),
+			StatusCode:  cs{Code: "completed"},
+		}
+		if r.Time != nil {
+			org.EffectiveTime = interval(r.Time, nil)
+		}
+		for _, o := range r.Observations {
+			item := fmt.Sprintf("%s: %s: %s", display(r.Panel), display(o.Code), o.Value)
+			if o.Unit != "" {
+				item += " " + o.Unit
+			}
+			s.Text.Items = append(s.Text.Items, item)
+
+			value := &anyValue{Type: "ST", Text: o.Value}
+			if o.Unit != "" {
+				value = &anyValue{Type: "PQ", Value: o.Value, Unit: o.Unit}
+			}
+			obs := observation{
+				ClassCode:   "OBS",
+				MoodCode:    "EVN",
+				TemplateIDs: []ii{templateResultObservation},
+				Code:        codeOf(o.Code),
+				StatusCode:  cs{Code: "completed"},
+				Value:       value,
+			}
+			t := o.Time
+			if t == nil {
+				t = r.Time
+			}
+			if t != nil {
+				obs.EffectiveTime = &ivlts{Value: newTS(*t).Value}
+			} else {
+				obs.EffectiveTime = &ivlts{Low: optionalTS(nil)}
+			}
+			if o.Interpretation != "" {
+				obs.InterpretationCode = &cd{Code: o.Interpretation, CodeSystem: SystemObservationInterp}
+			}
+			if o.ReferenceRange != "" {
+				obs.ReferenceRange = &referenceRange{}
+				obs.ReferenceRange.ObservationRange.Text = o.ReferenceRange
+			}
+			org.Components = append(org.Components, struct {
+				Observation observation `xml:"observation"`
+			}{obs})
+		}
+		s.Entries = append(s.Entries, entry{TypeCode: "DRIV", Organizer: org})
+	}
+	if len(results) == 0 {
+		s.Text.Items = []string{"No results"}
+	}
+	return s
+}
+
+func readResults(s section) []Result {
+	var results []Result
+	for _, e := range s.Entries {
+		org := e.Organizer
+		if org == nil {
+			continue
+		}
+		panel, ok := codeFrom(org.Code)
+		if !ok {
+			continue
+		}
+		r := Result{ID: idFrom(&org.ID), Panel: panel}
+		r.Time, _ = intervalFrom(org.EffectiveTime)
+		for _, c := range org.Components {
+			obs := c.Observation
+			code, ok := codeFrom(obs.Code)
+			if !ok || obs.Value == nil {
+				continue
+			}
+			o := Observation{Code: code}
+			switch {
+			case obs.Value.Unit != "" || obs.Value.Type == "PQ":
+				o.Value, o.Unit = obs.Value.Value, obs.Value.Unit
+			case obs.Value.Code != "":
+				o.Value = obs.Value.DisplayName
+				if o.Value == "" {
+					o.Value = obs.Value.Code
+				}
+			case obs.Value.Value != "":
+				o.Value = obs.Value.Value
+			default:
+				o.Value = strings.TrimSpace(obs.Value.Text)
+			}
+			if obs.InterpretationCode != nil {
+				o.Interpretation = obs.InterpretationCode.Code
+			}
+			if obs.ReferenceRange != nil {
+				o.ReferenceRange = strings.TrimSpace(obs.ReferenceRange.ObservationRange.Text)
+			}
+			o.Time, _ = intervalFrom(obs.EffectiveTime)
+			r.Observations = append(r.Observations, o)
+		}
+		if r.Time == nil && len(r.Observations) > 0 {
+			r.Time = r.Observations[0].Time
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func encounterSection(encounters []Encounter) section {
+	s := section{
+		TemplateIDs: []ii{templateEncounterSection},
+		Code:        cd{Code: SectionEncounters, CodeSystem: SystemLOINC, CodeSystemName: "LOINC", DisplayName: "Encounters"},
+		Title:       "Encounters",
+	}
+	for _, enc := range encounters {
+		item := enc.Start.UTC().Format("2006-01-02 15:04") + " " + display(enc.Code)
+		if enc.Text != "" {
+			item += " - " + enc.Text
+		}
+		s.Text.Items = append(s.Text.Items, item)
+
+		start := enc.Start
+		s.Entries = append(s.Entries, entry{
+			TypeCode: "DRIV",
+			Encounter: &encounter{
+				ClassCode:     "ENC",
+				MoodCode:      "EVN",
+				TemplateIDs:   []ii{templateEncounterActivity},
+				ID:            idOf(enc.ID),
+				Code:          codeOf(enc.Code),
+				Text:          enc.Text,
+				EffectiveTime: *interval(&start, enc.End),
+			},
+		})
+	}
+	if len(encounters) == 0 {
+		s.Text.Items = []string{"No encounters"}
+	}
+	return s
+}
+
+func readEncounters(s section) []Encounter {
+	var encounters []Encounter
+	for _, e := range s.Entries {
+		enc := e.Encounter
+		if enc == nil {
+			continue
+		}
+		start, end := intervalFrom(&enc.EffectiveTime)
+		if start == nil {
+			continue
+		}
+		code, _ := codeFrom(enc.Code)
+		encounters = append(encounters, Encounter{
+			ID:    idFrom(&enc.ID),
+			Code:  code,
+			Text:  strings.TrimSpace(enc.Text),
+			Start: *start,
+			End:   end,
+		})
+	}
+	return encounters
+}
+
+func valueOf(c cd) *anyValue {
+	return &anyValue{
+		Type:         c.Type,
+		Code:         c.Code,
+		CodeSystem:   c.CodeSystem,
+		DisplayName:  c.DisplayName,
+		NullFlavor:   c.NullFlavor,
+		OriginalText: c.OriginalText,
+	}
+}
+
+func cdFrom(v *anyValue) cd {
+	return cd{Code: v.Code, CodeSystem: v.CodeSystem, DisplayName: v.DisplayName, NullFlavor: v.NullFlavor, OriginalText: v.OriginalText}
+}
+
+func hasTemplate(ids []ii, root string) bool {
+	for _, id := range ids {
+		if id.Root == root {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package ccda
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+const namespaceXSI = "http://www.w3.org/2001/XMLSchema-instance"
+
+// xsiType is written as a prefixed xsi:type attribute and read back by its
+// namespace, whatever prefix the sender used
+type xsiType string
+
+func (t xsiType) MarshalXMLAttr(xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: string(t)}, nil
+}
+
+// The types below cover the parts of CDA R2 a Continuity of Care Document
+// needs. Unknown elements are ignored when parsing.
+
+type clinicalDocument struct {
+	XMLName             xml.Name      `xml:"urn:hl7-org:v3 ClinicalDocument"`
+	XSI                 string        `xml:"xmlns:xsi,attr,omitempty"`
+	RealmCode           *cd           `xml:"realmCode"`
+	TypeID              *ii           `xml:"typeId"`
+	TemplateIDs         []ii          `xml:"templateId"`
+	ID                  ii            `xml:"id"`
+	Code                cd            `xml:"code"`
+	Title               string        `xml:"title"`
+	EffectiveTime       ts            `xml:"effectiveTime"`
+	ConfidentialityCode cd            `xml:"confidentialityCode"`
+	LanguageCode        *cd           `xml:"languageCode"`
+	RecordTarget        recordTarget  `xml:"recordTarget"`
+	Author              *author       `xml:"author"`
+	Custodian           *custodian    `xml:"custodian"`
+	Component           bodyComponent `xml:"component"`
+}
+
+type recordTarget struct {
+	PatientRole patientRole `xml:"patientRole"`
+}
+
+type patientRole struct {
+	IDs      []ii      `xml:"id"`
+	Addr     *addr     `xml:"addr"`
+	Telecoms []telecom `xml:"telecom"`
+	Patient  patient   `xml:"patient"`
+}
+
+type patient struct {
+	Name                     personName `xml:"name"`
+	AdministrativeGenderCode cd         `xml:"administrativeGenderCode"`
+	BirthTime                ts         `xml:"birthTime"`
+}
+
+type personName struct {
+	Given  []string `xml:"given"`
+	Family string   `xml:"family"`
+}
+
+type addr struct {
+	Use               string   `xml:"use,attr,omitempty"`
+	StreetAddressLine []string `xml:"streetAddressLine"`
+	City              string   `xml:"city,omitempty"`
+	State             string   `xml:"state,omitempty"`
+	PostalCode        string   `xml:"postalCode,omitempty"`
+	Country           string   `xml:"country,omitempty"`
+}
+
+type telecom struct {
+	Use   string `xml:"use,attr,omitempty"`
+	Value string `xml:"value,attr"`
+}
+
+type author struct {
+	Time           ts             `xml:"time"`
+	AssignedAuthor assignedEntity `xml:"assignedAuthor"`
+}
+
+type assignedEntity struct {
+	ID                      ii            `xml:"id"`
+	RepresentedOrganization *organization `xml:"representedOrganization"`
+}
+
+type custodian struct {
+	AssignedCustodian struct {
+		RepresentedCustodianOrganization organization `xml:"representedCustodianOrganization"`
+	} `xml:"assignedCustodian"`
+}
+
+type organization struct {
+	IDs  []ii   `xml:"id"`
+	Name string `xml:"name"`
+}
+
+type bodyComponent struct {
+	StructuredBody struct {
+		Components []sectionComponent `xml:"component"`
+	} `xml:"structuredBody"`
+}
+
+type sectionComponent struct {
+	Section section `xml:"section"`
+}
+
+type section struct {
+	TemplateIDs []ii      `xml:"templateId"`
+	Code        cd        `xml:"code"`
+	Title       string    `xml:"title"`
+	Text        narrative `xml:"text"`
+	Entries     []entry   `xml:"entry"`
+}
+
+// narrative is the human-readable part of a section, written as a plain
+// list. Parsing reads the coded entries and ignores it.
+type narrative struct {
+	Items []string `xml:"list>item,omitempty"`
+}
+
+type entry struct {
+	TypeCode                string                   `xml:"typeCode,attr,omitempty"`
+	Act                     *act                     `xml:"act"`
+	Observation             *observation             `xml:"observation"`
+	SubstanceAdministration *substanceAdministration `xml:"substanceAdministration"`
+	Encounter               *encounter               `xml:"encounter"`
+	Organizer               *organizer               `xml:"organizer"`
+}
+
+type act struct {
+	ClassCode          string              `xml:"classCode,attr"`
+	MoodCode           string              `xml:"moodCode,attr"`
+	TemplateIDs        []ii                `xml:"templateId"`
+	ID                 ii                  `xml:"id"`
+	Code               cd                  `xml:"code"`
+	StatusCode         cs                  `xml:"statusCode"`
+	EffectiveTime      *ivlts              `xml:"effectiveTime"`
+	EntryRelationships []entryRelationship `xml:"entryRelationship"`
+}
+
+type entryRelationship struct {
+	TypeCode     string       `xml:"typeCode,attr"`
+	InversionInd string       `xml:"inversionInd,attr,omitempty"`
+	Observation  *observation `xml:"observation"`
+}
+
+type observation struct {
+	ClassCode          string              `xml:"classCode,attr"`
+	MoodCode           string              `xml:"moodCode,attr"`
+	TemplateIDs        []ii                `xml:"templateId"`
+	ID                 *ii                 `xml:"id"`
+	Code               cd                  `xml:"code"`
+	Text               string              `xml:"text,omitempty"`
+	StatusCode         cs                  `xml:"statusCode"`
+	EffectiveTime      *ivlts              `xml:"effectiveTime"`
+	Value              *anyValue           `xml:"value"`
+	InterpretationCode *cd                 `xml:"interpretationCode"`
+	Participants       []participant       `xml:"participant"`
+	EntryRelationships []entryRelationship `xml:"entryRelationship"`
+	ReferenceRange     *referenceRange     `xml:"referenceRange"`
+}
+
+type referenceRange struct {
+	ObservationRange struct {
+		Text string `xml:"text"`
+	} `xml:"observationRange"`
+}
+
+type participant struct {
+	TypeCode        string `xml:"typeCode,attr"`
+	ParticipantRole struct {
+		ClassCode     string `xml:"classCode,attr"`
+		PlayingEntity struct {
+			ClassCode string `xml:"classCode,attr"`
+			Code      cd     `xml:"code"`
+		} `xml:"playingEntity"`
+	} `xml:"participantRole"`
+}
+
+type substanceAdministration struct {
+	ClassCode     string  `xml:"classCode,attr"`
+	MoodCode      string  `xml:"moodCode,attr"`
+	TemplateIDs   []ii    `xml:"templateId"`
+	ID            ii      `xml:"id"`
+	Text          string  `xml:"text,omitempty"`
+	StatusCode    cs      `xml:"statusCode"`
+	EffectiveTime []ivlts `xml:"effectiveTime"`
+	Consumable    struct {
+		ManufacturedProduct struct {
+			ClassCode            string `xml:"classCode,attr"`
+			TemplateIDs          []ii   `xml:"templateId"`
+			ManufacturedMaterial struct {
+				Code cd `xml:"code"`
+			} `xml:"manufacturedMaterial"`
+		} `xml:"manufacturedProduct"`
+	} `xml:"consumable"`
+}
+
+type encounter struct {
+	ClassCode     string `xml:"classCode,attr"`
+	MoodCode      string `xml:"moodCode,attr"`
+	TemplateIDs   []ii   `xml:"templateId"`
+	ID            ii     `xml:"id"`
+	Code          cd     `xml:"code"`
+	Text          string `xml:"text,omitempty"`
+	EffectiveTime ivlts  `xml:"effectiveTime"`
+}
+
+type organizer struct {
+	ClassCode     string `xml:"classCode,attr"`
+	MoodCode      string `xml:"moodCode,attr"`
+	TemplateIDs   []ii   `xml:"templateId"`
+	ID            ii     `xml:"id"`
+	Code          cd     `xml:"code"`
+	StatusCode    cs     `xml:"statusCode"`
+	EffectiveTime *ivlts `xml:"effectiveTime"`
+	Components    []struct {
+		Observation observation `xml:"observation"`
+	} `xml:"component"`
+}
+
+// ii is an instance identifier
+type ii struct {
+	Root       string `xml:"root,attr,omitempty"`
+	Extension  string `xml:"extension,attr,omitempty"`
+	NullFlavor string `xml:"nullFlavor,attr,omitempty"`
+}
+
+// cd is a coded value
+type cd struct {
+	Type           xsiType `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr,omitempty"`
+	Code           string  `xml:"code,attr,omitempty"`
+	CodeSystem     string  `xml:"codeSystem,attr,omitempty"`
+	CodeSystemName string  `xml:"codeSystemName,attr,omitempty"`
+	DisplayName    string  `xml:"displayName,attr,omitempty"`
+	NullFlavor     string  `xml:"nullFlavor,attr,omitempty"`
+	OriginalText   string  `xml:"originalText,omitempty"`
+}
+
+type cs struct {
+	Code string `xml:"code,attr"`
+}
+
+type ts struct {
+	Value      string `xml:"value,attr,omitempty"`
+	NullFlavor string `xml:"nullFlavor,attr,omitempty"`
+}
+
+type ivlts struct {
+	Type  xsiType `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr,omitempty"`
+	Value string  `xml:"value,attr,omitempty"`
+	Low   *ts     `xml:"low"`
+	High  *ts     `xml:"high"`
+}
+
+// anyValue holds an observation value of type CD, PQ or ST
+type anyValue struct {
+	Type         xsiType `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr,omitempty"`
+	Code         string  `xml:"code,attr,omitempty"`
+	CodeSystem   string  `xml:"codeSystem,attr,omitempty"`
+	DisplayName  string  `xml:"displayName,attr,omitempty"`
+	NullFlavor   string  `xml:"nullFlavor,attr,omitempty"`
+	Value        string  `xml:"value,attr,omitempty"`
+	Unit         string  `xml:"unit,attr,omitempty"`
+	OriginalText string  `xml:"originalText,omitempty"`
+	Text         string  `xml:",chardata"`
+}
+
+// Timestamps are written in UTC to the second, or as a bare date
+const (
+	tsLayout     = "20060102150405-0700"
+	tsDateLayout = "20060102"
+)
+
+func newTS(t time.Time) ts {
+	return ts{Value: t.UTC().Format(tsLayout)}
+}
+
+func newDate(t time.Time) ts {
+	return ts{Value: t.Format(tsDateLayout)}
+}
+
+func optionalTS(t *time.Time) *ts {
+	if t == nil {
+		return &ts{NullFlavor: "UNK"}
+	}
+	v := newTS(*t)
+	return &v
+}
+
+// parseTS reads an HL7 timestamp of any precision from year to second, with
+// or without a zone offset
+func parseTS(v string) *time.Time {
+	if v == "" {
+		return nil
+	}
+	value, zone := v, ""
+	if i := strings.IndexAny(v, "+-"); i > 0 {
+		value, zone = v[:i], v[i:]
+	}
+	if dot := strings.IndexByte(value, '.'); dot >= 0 {
+		value = value[:dot]
+	}
+	layouts := map[int]string{4: "2006", 6: "200601", 8: "20060102", 10: "2006010215", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(value)]
+	if !ok {
+		return nil
+	}
+	if zone != "" {
+		layout += "-0700"
+		value += zone
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,110 @@
+// Package fhir holds the FHIR R4 resources the API can serve. Only the
+// elements that are populated are modelled.
+package fhir
+
+// ContentType is the media type FHIR consumers ask for
+const ContentType = "application/fhir+json"
+
+// Terminology systems for the codes FHIR defines itself
+const (
+	SystemConditionClinical     = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	SystemConditionVerification = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+	SystemConditionCategory     = "http://terminology.hl7.org/CodeSystem/condition-category"
+	SystemAllergyClinical       = "http://terminology.hl7.org/CodeSystem/allergyintolerance-clinical"
+	SystemAllergyVerification   = "http://terminology.hl7.org/CodeSystem/allergyintolerance-verification"
+	SystemSNOMED                = "http://snomed.info/sct"
+)
+
+const (
+	ResourceTypeBundle             = "Bundle"
+	ResourceTypeCondition          = "Condition"
+	ResourceTypeAllergyIntolerance = "AllergyIntolerance"
+
+	BundleTypeSearchset = "searchset"
+)
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Concept is a CodeableConcept with a single coding
+func Concept(system, code, display string) *CodeableConcept {
+	return &CodeableConcept{Coding: []Coding{{System: system, Code: code, Display: display}}}
+}
+
+type Reference struct {
+	Reference string `json:"reference"`
+}
+
+type Meta struct {
+	VersionID   string `json:"versionId,omitempty"`
+	LastUpdated string `json:"lastUpdated,omitempty"`
+}
+
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+type Condition struct {
+	ResourceType       string            `json:"resourceType"`
+	ID                 string            `json:"id"`
+	Meta               *Meta             `json:"meta,omitempty"`
+	ClinicalStatus     *CodeableConcept  `json:"clinicalStatus,omitempty"`
+	VerificationStatus *CodeableConcept  `json:"verificationStatus,omitempty"`
+	Category           []CodeableConcept `json:"category,omitempty"`
+	Severity           *CodeableConcept  `json:"severity,omitempty"`
+	Code               *CodeableConcept  `json:"code"`
+	Subject            Reference         `json:"subject"`
+	OnsetDateTime      string            `json:"onsetDateTime,omitempty"`
+	AbatementDateTime  string            `json:"abatementDateTime,omitempty"`
+	RecordedDate       string            `json:"recordedDate,omitempty"`
+	Note               []Annotation      `json:"note,omitempty"`
+}
+
+type AllergyIntolerance struct {
+	ResourceType       string            `json:"resourceType"`
+	ID                 string            `json:"id"`
+	Meta               *Meta             `json:"meta,omitempty"`
+	ClinicalStatus     *CodeableConcept  `json:"clinicalStatus,omitempty"`
+	VerificationStatus *CodeableConcept  `json:"verificationStatus,omitempty"`
+	Category           []string          `json:"category,omitempty"`
+	Criticality        string            `json:"criticality,omitempty"`
+	Code               *CodeableConcept  `json:"code"`
+	Patient            Reference         `json:"patient"`
+	OnsetDateTime      string            `json:"onsetDateTime,omitempty"`
+	RecordedDate       string            `json:"recordedDate,omitempty"`
+	Reaction           []AllergyReaction `json:"reaction,omitempty"`
+	Note               []Annotation      `json:"note,omitempty"`
+}
+
+type AllergyReaction struct {
+	Manifestation []CodeableConcept `json:"manifestation"`
+	Severity      string            `json:"severity,omitempty"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Total        int           `json:"total"`
+	Entry        []BundleEntry `json:"entry"`
+}
+
+type BundleEntry struct {
+	FullURL  string      `json:"fullUrl,omitempty"`
+	Resource interface{} `json:"resource"`
+}
+
+// Searchset bundles resources returned by a search
+func Searchset(entries []BundleEntry) *Bundle {
+	if entries == nil {
+		entries = []BundleEntry{}
+	}
+	return &Bundle{ResourceType: ResourceTypeBundle, Type: BundleTypeSearchset, Total: len(entries), Entry: entries}
+}
@@ -0,0 +1,78 @@
+package hl7
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AckCode is the MSA-1 acknowledgment code
+type AckCode string
+
+const (
+	// AckAccept means the message was processed or safely queued
+	AckAccept AckCode = "AA"
+	// AckError means processing failed and the sender may retry
+	AckError AckCode = "AE"
+	// AckReject means the message can never be processed as sent
+	AckReject AckCode = "AR"
+)
+
+// TimestampFormat is the HL7 DTM format used in MSH-7
+const TimestampFormat = "20060102150405"
+
+// NewAck builds the acknowledgment for msg. msg may be nil when the message
+// could not be parsed; the ACK then carries no original control ID. For
+// anything but AA the text is sent in MSA-3 and an ERR segment.
+func NewAck(msg *Message, code AckCode, text string, now time.Time) []byte {
+	d := DefaultDelimiters
+	var sendApp, sendFac, recvApp, recvFac, trigger, controlID, processingID, version string
+	if msg != nil {
+		d = msg.Delimiters
+		h := msg.header()
+		sendApp, sendFac = h.raw(5), h.raw(6)
+		recvApp, recvFac = h.raw(3), h.raw(4)
+		_, trigger = msg.Type()
+		controlID = msg.ControlID()
+		processingID = h.raw(11)
+		version = h.raw(12)
+	}
+	if processingID == "" {
+		processingID = "P"
+	}
+	if version == "" {
+		version = "2.5.1"
+	}
+
+	f := string(d.Field)
+	msh := []string{
+		"MSH", d.encodingCharacters(), sendApp, sendFac, recvApp, recvFac,
+		now.UTC().Format(TimestampFormat), "",
+		"ACK" + string(d.Component) + trigger + string(d.Component) + "ACK",
+		fmt.Sprintf("ACK%d", now.UnixNano()), processingID, version,
+	}
+	segments := []string{
+		strings.Join(msh, f),
+		strings.Join([]string{"MSA", string(code), d.EscapeValue(controlID), d.EscapeValue(text)}, f),
+	}
+	if code != AckAccept && text != "" {
+		// ERR-3 from table 0357: 207 application internal error for AE, 200
+		// unsupported message for AR; ERR-4 severity E. ERR-8 carries the text.
+		errCode := "207"
+		if code == AckReject {
+			errCode = "200"
+		}
+		segments = append(segments, strings.Join([]string{"ERR", "", "", errCode, "E", "", "", "", d.EscapeValue(text)}, f))
+	}
+
+	return []byte(strings.Join(segments, "\r") + "\r")
+}
+
+// AckStatus reads MSA-1 and MSA-3 from an acknowledgment message
+func AckStatus(ack *Message) (AckCode, string) {
+	msa := ack.Segment("MSA")
+	if msa == nil {
+		return "", ""
+	}
+	return AckCode(msa.Field(1)), msa.Field(3)
+}
@@ -0,0 +1,304 @@
+// Package hl7 parses HL7 v2 messages and carries them over MLLP.
+package hl7
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrEmptyMessage = errors.New("hl7: empty message")
+	ErrNoMSH        = errors.New("hl7: message does not start with an MSH segment")
+)
+
+// Delimiters are the encoding characters declared in MSH-1 and MSH-2
+type Delimiters struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+// DefaultDelimiters are the encoding characters almost every sender uses
+var DefaultDelimiters = Delimiters{Field: '|', Component: '^', Repetition: '~', Escape: '\\', Subcomponent: '&'}
+
+func (d Delimiters) encodingCharacters() string {
+	return string([]byte{d.Component, d.Repetition, d.Escape, d.Subcomponent})
+}
+
+// Message is a parsed HL7 v2 message
+type Message struct {
+	Delimiters Delimiters
+	Segments   []*Segment
+}
+
+// Segment is one segment of a message. Fields are kept as received and only
+// split and unescaped when read.
+type Segment struct {
+	Name   string
+	fields []string
+	d      *Delimiters
+}
+
+// Parse reads a message. Segments may be terminated by CR, LF or CRLF.
+func Parse(data []byte) (*Message, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if len(data) < 8 || string(data[:3]) != "MSH" {
+		return nil, ErrNoMSH
+	}
+
+	msg := &Message{Delimiters: Delimiters{
+		Field:        data[3],
+		Component:    data[4],
+		Repetition:   data[5],
+		Escape:       data[6],
+		Subcomponent: data[7],
+	}}
+	if data[7] == msg.Delimiters.Field {
+		// MSH-2 with only three characters, no subcomponent separator
+		msg.Delimiters.Subcomponent = DefaultDelimiters.Subcomponent
+	}
+
+	lines := strings.FieldsFunc(string(data), func(r rune) bool { return r == '\r' || r == '\n' })
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, string(msg.Delimiters.Field))
+		if len(fields[0]) != 3 {
+			return nil, fmt.Errorf("hl7: segment %d has an invalid name %q", i+1, fields[0])
+		}
+		msg.Segments = append(msg.Segments, &Segment{Name: fields[0], fields: fields, d: &msg.Delimiters})
+	}
+
+	return msg, nil
+}
+
+// Segment returns the first segment with the given name, or nil
+func (m *Message) Segment(name string) *Segment {
+	for _, s := range m.Segments {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// SegmentsNamed returns every segment with the given name, in order
+func (m *Message) SegmentsNamed(name string) []*Segment {
+	var segments []*Segment
+	for _, s := range m.Segments {
+		if s.Name == name {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+func (m *Message) header() *Segment {
+	if len(m.Segments) == 0 {
+		return &Segment{Name: "MSH", d: &m.Delimiters}
+	}
+	return m.Segments[0]
+}
+
+// Type returns the message code and trigger event of MSH-9, e.g. ORU and R01
+func (m *Message) Type() (string, string) {
+	return m.header().Component(9, 1), m.header().Component(9, 2)
+}
+
+// ControlID is MSH-10, the sender's unique ID for this message
+func (m *Message) ControlID() string {
+	return m.header().Field(10)
+}
+
+// SendingApplication and SendingFacility are the namespace IDs of MSH-3 and MSH-4
+func (m *Message) SendingApplication() string {
+	return m.header().Component(3, 1)
+}
+
+func (m *Message) SendingFacility() string {
+	return m.header().Component(4, 1)
+}
+
+// Encode writes the message back out with CR segment terminators
+func (m *Message) Encode() []byte {
+	var buf bytes.Buffer
+	for _, s := range m.Segments {
+		buf.WriteString(strings.Join(s.fields, string(m.Delimiters.Field)))
+		buf.WriteByte('\r')
+	}
+	return buf.Bytes()
+}
+
+// raw returns field n as received. MSH is numbered so that MSH-1 is the field
+// separator itself, as in the standard.
+func (s *Segment) raw(n int) string {
+	if s.Name == "MSH" {
+		if n == 1 {
+			return string(s.d.Field)
+		}
+		n--
+	}
+	if n <= 0 || n >= len(s.fields) {
+		return ""
+	}
+	return s.fields[n]
+}
+
+// Repetitions returns the repetitions of field n, each split into components
+func (s *Segment) Repetitions(n int) []Components {
+	raw := s.raw(n)
+	if raw == "" {
+		return nil
+	}
+	if s.Name == "MSH" && n <= 2 {
+		return []Components{{values: []string{raw}, d: &Delimiters{}}}
+	}
+	var reps []Components
+	for _, rep := range strings.Split(raw, string(s.d.Repetition)) {
+		reps = append(reps, Components{values: strings.Split(rep, string(s.d.Component)), d: s.d})
+	}
+	return reps
+}
+
+// Field returns the first repetition of field n, unescaped. Component
+// separators in it are left as they are.
+func (s *Segment) Field(n int) string {
+	raw := s.raw(n)
+	if s.Name == "MSH" && n <= 2 {
+		return raw
+	}
+	if i := strings.IndexByte(raw, s.d.Repetition); i >= 0 {
+		raw = raw[:i]
+	}
+	return s.d.Unescape(raw)
+}
+
+// Component returns component c (1-based) of the first repetition of field n
+func (s *Segment) Component(n, c int) string {
+	reps := s.Repetitions(n)
+	if len(reps) == 0 {
+		return ""
+	}
+	return reps[0].Get(c)
+}
+
+// Int reads field n as an integer, returning 0 when it is empty or invalid
+func (s *Segment) Int(n int) int {
+	v, _ := strconv.Atoi(strings.TrimSpace(s.Field(n)))
+	return v
+}
+
+// Components are the components of one field repetition
+type Components struct {
+	values []string
+	d      *Delimiters
+}
+
+// Len is the number of components
+func (c Components) Len() int {
+	return len(c.values)
+}
+
+// Get returns component i (1-based), unescaped, with any subcomponent
+// separators left in place
+func (c Components) Get(i int) string {
+	if i < 1 || i > len(c.values) {
+		return ""
+	}
+	return c.d.Unescape(c.values[i-1])
+}
+
+// Sub returns subcomponent j of component i, both 1-based
+func (c Components) Sub(i, j int) string {
+	if i < 1 || i > len(c.values) {
+		return ""
+	}
+	subs := strings.Split(c.values[i-1], string(c.d.Subcomponent))
+	if j < 1 || j > len(subs) {
+		return ""
+	}
+	return c.d.Unescape(subs[j-1])
+}
+
+// Unescape resolves the standard escape sequences: \F\ \S\ \T\ \R\ \E\,
+// \.br\ for a line break and \Xhh..\ for hex data. Unknown sequences are
+// kept as received.
+func (d Delimiters) Unescape(s string) string {
+	if strings.IndexByte(s, d.Escape) < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != d.Escape {
+			b.WriteByte(s[i])
+			continue
+		}
+		end := strings.IndexByte(s[i+1:], d.Escape)
+		if end < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+		seq := s[i+1 : i+1+end]
+		switch {
+		case seq == "F":
+			b.WriteByte(d.Field)
+		case seq == "S":
+			b.WriteByte(d.Component)
+		case seq == "T":
+			b.WriteByte(d.Subcomponent)
+		case seq == "R":
+			b.WriteByte(d.Repetition)
+		case seq == "E":
+			b.WriteByte(d.Escape)
+		case seq == ".br":
+			b.WriteByte('\n')
+		case len(seq) > 1 && seq[0] == 'X' && len(seq)%2 == 1:
+			for j := 1; j+1 < len(seq); j += 2 {
+				v, err := strconv.ParseUint(seq[j:j+2], 16, 8)
+				if err != nil {
+					break
+				}
+				b.WriteByte(byte(v))
+			}
+		default:
+			b.WriteString(s[i : i+2+end])
+		}
+		i += end + 1
+	}
+	return b.String()
+}
+
+// EscapeValue encodes the delimiter characters in s so it can be written as
+// a field value
+func (d Delimiters) EscapeValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case d.Escape:
+			b.WriteString(string(d.Escape) + "E" + string(d.Escape))
+		case d.Field:
+			b.WriteString(string(d.Escape) + "F" + string(d.Escape))
+		case d.Component:
+			b.WriteString(string(d.Escape) + "S" + string(d.Escape))
+		case d.Subcomponent:
+			b.WriteString(string(d.Escape) + "T" + string(d.Escape))
+		case d.Repetition:
+			b.WriteString(string(d.Escape) + "R" + string(d.Escape))
+		case '\r', '\n':
+			b.WriteString(string(d.Escape) + ".br" + string(d.Escape))
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
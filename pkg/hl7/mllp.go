@@ -0,0 +1,204 @@
+package hl7
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jwalitptl/admin-api/pkg/logger"
+)
+
+// MLLP frames each message as <VT> message <FS><CR>
+const (
+	startBlock     = 0x0b
+	endBlock       = 0x1c
+	carriageReturn = 0x0d
+)
+
+var ErrFrameTooLarge = errors.New("hl7: MLLP frame exceeds the maximum size")
+
+// ReadFrame reads the next MLLP frame and returns its payload. Bytes before
+// the start block are skipped.
+func ReadFrame(r *bufio.Reader, maxBytes int) ([]byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == startBlock {
+			break
+		}
+	}
+
+	var payload []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if b == endBlock {
+			next, err := r.ReadByte()
+			if err != nil {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if next == carriageReturn {
+				return payload, nil
+			}
+			payload = append(payload, b, next)
+			continue
+		}
+		payload = append(payload, b)
+		if maxBytes > 0 && len(payload) > maxBytes {
+			return nil, ErrFrameTooLarge
+		}
+	}
+}
+
+// WriteFrame writes payload as one MLLP frame
+func WriteFrame(w io.Writer, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+3)
+	frame = append(frame, startBlock)
+	frame = append(frame, payload...)
+	frame = append(frame, endBlock, carriageReturn)
+	_, err := w.Write(frame)
+	return err
+}
+
+// Handler processes one inbound message. The returned code and text are
+// sent back in the ACK.
+type Handler interface {
+	HandleMessage(ctx context.Context, msg *Message, raw []byte) (AckCode, string)
+}
+
+// Server accepts MLLP connections and answers every message with an ACK.
+// Messages on one connection are handled in order, as MLLP senders wait for
+// each ACK before sending the next message.
+type Server struct {
+	Addr            string
+	Handler         Handler
+	Logger          *logger.Logger
+	IdleTimeout     time.Duration
+	MaxMessageBytes int
+
+	wg sync.WaitGroup
+}
+
+// ListenAndServe listens on Addr until ctx is cancelled
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.Addr, err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is cancelled, then waits for
+// open connections to finish their current message
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	s.Logger.Info("MLLP listener started", "addr", ln.Addr().String())
+	defer s.wg.Wait()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	remote := conn.RemoteAddr().String()
+	reader := bufio.NewReader(conn)
+
+	// Unblock the read when shutting down
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	for ctx.Err() == nil {
+		if s.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		}
+		payload, err := ReadFrame(reader, s.MaxMessageBytes)
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				s.Logger.Warn("MLLP connection closed", "remote", remote, "error", err.Error())
+			}
+			return
+		}
+
+		var ack []byte
+		msg, err := Parse(payload)
+		if err != nil {
+			ack = NewAck(nil, AckReject, err.Error(), time.Now())
+		} else {
+			code, text := s.Handler.HandleMessage(ctx, msg, payload)
+			ack = NewAck(msg, code, text, time.Now())
+		}
+
+		if err := WriteFrame(conn, ack); err != nil {
+			s.Logger.Warn("failed to write ACK", "remote", remote, "error", err.Error())
+			return
+		}
+	}
+}
+
+// Client sends messages over one MLLP connection
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// Send writes msg and waits for its ACK
+func (c *Client) Send(msg []byte, timeout time.Duration) (*Message, error) {
+	if timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if err := WriteFrame(c.conn, msg); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+	payload, err := ReadFrame(c.reader, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACK: %w", err)
+	}
+	return Parse(payload)
+}
@@ -0,0 +1,314 @@
+// Package rrule parses and expands the iCalendar (RFC 5545) recurrence rules
+// appointment series are booked with. It supports DAILY, WEEKLY and MONTHLY
+// rules with INTERVAL, COUNT, UNTIL, BYDAY and, for monthly rules,
+// BYMONTHDAY. Ordinal BYDAY values such as 1MO are not supported.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+var (
+	ErrInvalid     = errors.New("invalid recurrence rule")
+	ErrUnsupported = errors.New("unsupported recurrence rule")
+	ErrUnbounded   = errors.New("recurrence rule needs COUNT or UNTIL")
+	ErrTooMany     = errors.New("recurrence rule has too many occurrences")
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// maxIterations stops expansion of rules whose candidates rarely match,
+// such as BYMONTHDAY=31 every twelve months
+const maxIterations = 10000
+
+// Rule is a parsed RRULE. Until, when set, is inclusive.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+}
+
+// Parse parses an RRULE value, with or without the "RRULE:" prefix
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalid, part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		switch key {
+		case "FREQ":
+			switch f := Frequency(value); f {
+			case Daily, Weekly, Monthly:
+				r.Freq = f
+			default:
+				return nil, fmt.Errorf("%w: FREQ=%s", ErrUnsupported, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL=%s", ErrInvalid, value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT=%s", ErrInvalid, value)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("%w: BYDAY=%s", ErrUnsupported, day)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n < 1 || n > 31 {
+					return nil, fmt.Errorf("%w: BYMONTHDAY=%s", ErrUnsupported, day)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "WKST":
+			if value != "MO" {
+				return nil, fmt.Errorf("%w: WKST=%s", ErrUnsupported, value)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupported, key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalid)
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot both be set", ErrInvalid)
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		return nil, fmt.Errorf("%w: BYMONTHDAY is only supported for MONTHLY rules", ErrUnsupported)
+	}
+	if len(r.ByDay) > 0 && r.Freq == Monthly {
+		return nil, fmt.Errorf("%w: BYDAY is not supported for MONTHLY rules", ErrUnsupported)
+	}
+	sort.Slice(r.ByDay, func(i, j int) bool { return weekOffset(r.ByDay[i]) < weekOffset(r.ByDay[j]) })
+	sort.Ints(r.ByMonthDay)
+	return r, nil
+}
+
+// UNTIL is a UTC date-time or a date, which runs to the end of the day UTC
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: UNTIL=%s", ErrInvalid, value)
+}
+
+// String writes the rule back as an RRULE value
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = weekdayNames[wd]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// WithUntil is a copy of the rule ending at until instead
+func (r *Rule) WithUntil(until time.Time) *Rule {
+	c := *r
+	c.Count = 0
+	c.Until = &until
+	return &c
+}
+
+// WithCount is a copy of the rule ending after count occurrences instead
+func (r *Rule) WithCount(count int) *Rule {
+	c := *r
+	c.Count = count
+	c.Until = nil
+	return &c
+}
+
+// Occurrences expands the rule from dtstart, which is the first occurrence.
+// Occurrences keep dtstart's wall-clock time in its location, so a series
+// stays at 9:00 across daylight saving changes. Times in exdates are left
+// out; as in RFC 5545 they still count towards COUNT. Expansion fails if the
+// rule is unbounded or has more than limit occurrences.
+func (r *Rule) Occurrences(dtstart time.Time, exdates []time.Time, limit int) ([]time.Time, error) {
+	if r.Count == 0 && r.Until == nil {
+		return nil, ErrUnbounded
+	}
+	if r.Count > limit {
+		return nil, ErrTooMany
+	}
+
+	var all []time.Time
+	next := r.candidates(dtstart)
+	for i := 0; i < maxIterations; i++ {
+		t, ok := next()
+		if !ok || (r.Until != nil && t.After(*r.Until)) {
+			break
+		}
+		if t.Before(dtstart) {
+			continue
+		}
+		all = append(all, t)
+		if r.Count > 0 && len(all) == r.Count {
+			break
+		}
+		if len(all) > limit {
+			return nil, ErrTooMany
+		}
+	}
+
+	occurrences := make([]time.Time, 0, len(all))
+	for _, t := range all {
+		if !excluded(t, exdates) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences, nil
+}
+
+// candidates returns a generator of the times matching the rule's pattern
+// in order, from the period dtstart falls in
+func (r *Rule) candidates(dtstart time.Time) func() (time.Time, bool) {
+	loc := dtstart.Location()
+	hour, min, sec := dtstart.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, min, sec, 0, loc)
+	}
+
+	var queue []time.Time
+	period := 0
+	fill := func() {
+		switch r.Freq {
+		case Daily:
+			day := at(dtstart.Year(), dtstart.Month(), dtstart.Day()+period*r.Interval)
+			if len(r.ByDay) == 0 || containsWeekday(r.ByDay, day.Weekday()) {
+				queue = append(queue, day)
+			}
+		case Weekly:
+			days := r.ByDay
+			if len(days) == 0 {
+				days = []time.Weekday{dtstart.Weekday()}
+			}
+			monday := dtstart.Day() - weekOffset(dtstart.Weekday()) + period*r.Interval*7
+			for _, wd := range days {
+				queue = append(queue, at(dtstart.Year(), dtstart.Month(), monday+weekOffset(wd)))
+			}
+		case Monthly:
+			days := r.ByMonthDay
+			if len(days) == 0 {
+				days = []int{dtstart.Day()}
+			}
+			first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(period*r.Interval), 1, 0, 0, 0, 0, loc)
+			for _, d := range days {
+				// Months without the day are skipped, as RFC 5545 requires
+				if t := at(first.Year(), first.Month(), d); t.Month() == first.Month() {
+					queue = append(queue, t)
+				}
+			}
+		}
+		period++
+	}
+
+	return func() (time.Time, bool) {
+		for len(queue) == 0 {
+			if period > maxIterations {
+				return time.Time{}, false
+			}
+			fill()
+		}
+		t := queue[0]
+		queue = queue[1:]
+		return t, true
+	}
+}
+
+// weekOffset is the weekday's position in a week starting on Monday
+func weekOffset(wd time.Weekday) int {
+	return (int(wd) + 6) % 7
+}
+
+func containsWeekday(days []time.Weekday, wd time.Weekday) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func excluded(t time.Time, exdates []time.Time) bool {
+	for _, ex := range exdates {
+		if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
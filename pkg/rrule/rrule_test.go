@@ -0,0 +1,277 @@
+package rrule
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	until := func(s string) *time.Time {
+		u, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &u
+	}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    *Rule
+		str     string
+		wantErr error
+	}{
+		{
+			name: "weekly by day is sorted from Monday",
+			in:   "RRULE:FREQ=WEEKLY;BYDAY=SU,FR,MO;COUNT=4",
+			want: &Rule{Freq: Weekly, Interval: 1, Count: 4, ByDay: []time.Weekday{time.Monday, time.Friday, time.Sunday}},
+			str:  "FREQ=WEEKLY;BYDAY=MO,FR,SU;COUNT=4",
+		},
+		{
+			name: "lower case and interval",
+			in:   "freq=daily;interval=2;count=3",
+			want: &Rule{Freq: Daily, Interval: 2, Count: 3},
+			str:  "FREQ=DAILY;INTERVAL=2;COUNT=3",
+		},
+		{
+			name: "until date runs to the end of the day",
+			in:   "FREQ=DAILY;UNTIL=20250110",
+			want: &Rule{Freq: Daily, Interval: 1, Until: until("2025-01-10T23:59:59Z")},
+			str:  "FREQ=DAILY;UNTIL=20250110T235959Z",
+		},
+		{
+			name: "until date-time",
+			in:   "FREQ=MONTHLY;BYMONTHDAY=15,1;UNTIL=20250601T090000Z",
+			want: &Rule{Freq: Monthly, Interval: 1, ByMonthDay: []int{1, 15}, Until: until("2025-06-01T09:00:00Z")},
+			str:  "FREQ=MONTHLY;BYMONTHDAY=1,15;UNTIL=20250601T090000Z",
+		},
+		{name: "missing freq", in: "COUNT=3", wantErr: ErrInvalid},
+		{name: "part without value", in: "FREQ=DAILY;COUNT", wantErr: ErrInvalid},
+		{name: "yearly", in: "FREQ=YEARLY;COUNT=2", wantErr: ErrUnsupported},
+		{name: "zero interval", in: "FREQ=DAILY;INTERVAL=0;COUNT=2", wantErr: ErrInvalid},
+		{name: "zero count", in: "FREQ=DAILY;COUNT=0", wantErr: ErrInvalid},
+		{name: "count and until", in: "FREQ=DAILY;COUNT=2;UNTIL=20250110", wantErr: ErrInvalid},
+		{name: "bad until", in: "FREQ=DAILY;UNTIL=2025-01-10", wantErr: ErrInvalid},
+		{name: "ordinal by day", in: "FREQ=WEEKLY;BYDAY=1MO;COUNT=2", wantErr: ErrUnsupported},
+		{name: "by day on monthly", in: "FREQ=MONTHLY;BYDAY=MO;COUNT=2", wantErr: ErrUnsupported},
+		{name: "by month day on weekly", in: "FREQ=WEEKLY;BYMONTHDAY=3;COUNT=2", wantErr: ErrUnsupported},
+		{name: "by month day out of range", in: "FREQ=MONTHLY;BYMONTHDAY=32;COUNT=2", wantErr: ErrUnsupported},
+		{name: "week start other than Monday", in: "FREQ=WEEKLY;WKST=SU;COUNT=2", wantErr: ErrUnsupported},
+		{name: "unknown part", in: "FREQ=DAILY;BYHOUR=9;COUNT=2", wantErr: ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if s := got.String(); s != tt.str {
+				t.Errorf("String() = %q, want %q", s, tt.str)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	local := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, newYork)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		exdates []time.Time
+		limit   int
+		want    []time.Time
+		wantErr error
+	}{
+		{
+			name:    "daily count",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: utc("2025-01-01 09:00"),
+			want:    []time.Time{utc("2025-01-01 09:00"), utc("2025-01-02 09:00"), utc("2025-01-03 09:00")},
+		},
+		{
+			name:    "daily until is inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20250103T090000Z",
+			dtstart: utc("2025-01-01 09:00"),
+			want:    []time.Time{utc("2025-01-01 09:00"), utc("2025-01-02 09:00"), utc("2025-01-03 09:00")},
+		},
+		{
+			name:    "daily until before the time of day",
+			rule:    "FREQ=DAILY;UNTIL=20250103T085959Z",
+			dtstart: utc("2025-01-01 09:00"),
+			want:    []time.Time{utc("2025-01-01 09:00"), utc("2025-01-02 09:00")},
+		},
+		{
+			name:    "daily limited to weekdays",
+			rule:    "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=3",
+			dtstart: utc("2025-01-03 09:00"),
+			want:    []time.Time{utc("2025-01-03 09:00"), utc("2025-01-06 09:00"), utc("2025-01-07 09:00")},
+		},
+		{
+			name:    "weekly by day crosses the month",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5",
+			dtstart: utc("2024-12-30 09:00"),
+			want: []time.Time{
+				utc("2024-12-30 09:00"), utc("2025-01-01 09:00"), utc("2025-01-03 09:00"),
+				utc("2025-01-06 09:00"), utc("2025-01-08 09:00"),
+			},
+		},
+		{
+			name:    "weekly by day skips days before dtstart",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3",
+			dtstart: utc("2025-01-01 09:00"),
+			want:    []time.Time{utc("2025-01-03 09:00"), utc("2025-01-06 09:00"), utc("2025-01-10 09:00")},
+		},
+		{
+			name:    "every other week on dtstart's weekday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			dtstart: utc("2025-01-07 14:30"),
+			want:    []time.Time{utc("2025-01-07 14:30"), utc("2025-01-21 14:30"), utc("2025-02-04 14:30")},
+		},
+		{
+			name:    "monthly skips months without the day",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: utc("2025-01-31 09:00"),
+			want:    []time.Time{utc("2025-01-31 09:00"), utc("2025-03-31 09:00"), utc("2025-05-31 09:00")},
+		},
+		{
+			name:    "monthly by month day",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=1,15;COUNT=4",
+			dtstart: utc("2025-01-15 09:00"),
+			want:    []time.Time{utc("2025-01-15 09:00"), utc("2025-02-01 09:00"), utc("2025-02-15 09:00"), utc("2025-03-01 09:00")},
+		},
+		{
+			name:    "wall-clock time kept across spring forward",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: local("2025-03-08 09:00"),
+			want:    []time.Time{utc("2025-03-08 14:00"), utc("2025-03-09 13:00"), utc("2025-03-10 13:00")},
+		},
+		{
+			name:    "wall-clock time kept across fall back",
+			rule:    "FREQ=WEEKLY;COUNT=2",
+			dtstart: local("2025-10-27 09:00"),
+			want:    []time.Time{utc("2025-10-27 13:00"), utc("2025-11-03 14:00")},
+		},
+		{
+			name:    "exdates still count towards COUNT",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: utc("2025-01-01 09:00"),
+			exdates: []time.Time{utc("2025-01-02 09:00")},
+			want:    []time.Time{utc("2025-01-01 09:00"), utc("2025-01-03 09:00")},
+		},
+		{
+			name:    "exdates match the instant in any location",
+			rule:    "FREQ=DAILY;COUNT=2",
+			dtstart: local("2025-01-01 09:00"),
+			exdates: []time.Time{utc("2025-01-02 14:00")},
+			want:    []time.Time{utc("2025-01-01 14:00")},
+		},
+		{
+			name:    "unbounded",
+			rule:    "FREQ=DAILY",
+			dtstart: utc("2025-01-01 09:00"),
+			wantErr: ErrUnbounded,
+		},
+		{
+			name:    "count over the limit",
+			rule:    "FREQ=DAILY;COUNT=10",
+			dtstart: utc("2025-01-01 09:00"),
+			limit:   5,
+			wantErr: ErrTooMany,
+		},
+		{
+			name:    "until over the limit",
+			rule:    "FREQ=DAILY;UNTIL=20260101",
+			dtstart: utc("2025-01-01 09:00"),
+			limit:   5,
+			wantErr: ErrTooMany,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.rule, err)
+			}
+			limit := tt.limit
+			if limit == 0 {
+				limit = 100
+			}
+
+			got, err := rule.Occurrences(tt.dtstart, tt.exdates, limit)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Occurrences() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Occurrences() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Occurrences() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+				if got[i].Location() != tt.dtstart.Location() {
+					t.Errorf("occurrence %d is in %v, want dtstart's %v", i, got[i].Location(), tt.dtstart.Location())
+				}
+			}
+		})
+	}
+}
+
+func TestWithUntilAndCount(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=TU;COUNT=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	bounded := rule.WithUntil(until)
+	if got, want := bounded.String(), "FREQ=WEEKLY;BYDAY=TU;UNTIL=20250201T000000Z"; got != want {
+		t.Errorf("WithUntil().String() = %q, want %q", got, want)
+	}
+	if rule.Count != 10 || rule.Until != nil {
+		t.Errorf("WithUntil changed the original rule: %+v", rule)
+	}
+
+	counted := bounded.WithCount(2)
+	if got, want := counted.String(), "FREQ=WEEKLY;BYDAY=TU;COUNT=2"; got != want {
+		t.Errorf("WithCount().String() = %q, want %q", got, want)
+	}
+}
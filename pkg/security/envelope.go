@@ -0,0 +1,98 @@
+package security
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// DataKeySize is the size of an AES-256 data encryption key
+const DataKeySize = 32
+
+// envelopeMagic starts every envelope ciphertext. Ciphertexts from the plain
+// AES encryptor begin with a random nonce instead.
+var envelopeMagic = []byte{'E', 'K', 1}
+
+// envelopeHeaderSize is the magic followed by the data key ID
+var envelopeHeaderSize = len(envelopeMagic) + 16
+
+var ErrNotEnvelope = errors.New("ciphertext is not envelope encrypted")
+
+// NewDataKey generates a random data encryption key
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, ErrEncryption
+	}
+	return key, nil
+}
+
+// SealEnvelope encrypts plaintext with AES-GCM under dataKey. The result
+// carries keyID in a header that is authenticated with the ciphertext:
+// magic | key ID | nonce | sealed data.
+func SealEnvelope(dataKey []byte, keyID uuid.UUID, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, envelopeHeaderSize+gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	out = append(out, envelopeMagic...)
+	out = append(out, keyID[:]...)
+	header := out[:envelopeHeaderSize]
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, ErrEncryption
+	}
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, header), nil
+}
+
+// EnvelopeKeyID returns the data key ID an envelope ciphertext was sealed
+// with
+func EnvelopeKeyID(ciphertext []byte) (uuid.UUID, error) {
+	if len(ciphertext) < envelopeHeaderSize || !bytes.Equal(ciphertext[:len(envelopeMagic)], envelopeMagic) {
+		return uuid.Nil, ErrNotEnvelope
+	}
+	return uuid.FromBytes(ciphertext[len(envelopeMagic):envelopeHeaderSize])
+}
+
+// OpenEnvelope decrypts an envelope ciphertext with the data key named in
+// its header
+func OpenEnvelope(dataKey []byte, ciphertext []byte) ([]byte, error) {
+	if _, err := EnvelopeKeyID(ciphertext); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header, rest := ciphertext[:envelopeHeaderSize], ciphertext[envelopeHeaderSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrDecryption
+	}
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidKeySize
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, ErrEncryption
+	}
+	return gcm, nil
+}
@@ -0,0 +1,71 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+var ErrBlindIndexKey = errors.New("blind index key must be at least 32 bytes")
+
+// FieldCipher encrypts individual column values and derives keyed blind
+// indexes for them, so an encrypted column can still be matched exactly
+// without decrypting every row.
+type FieldCipher struct {
+	encryptor Encryptor
+	indexKey  []byte
+	version   int
+}
+
+// NewFieldCipher wraps encryptor for column use. indexKey must be independent
+// of the encryption key; version is stored alongside each encrypted row so a
+// later key rotation can find the rows it still has to re-encrypt.
+func NewFieldCipher(encryptor Encryptor, indexKey []byte, version int) (*FieldCipher, error) {
+	if len(indexKey) < 32 {
+		return nil, ErrBlindIndexKey
+	}
+	if version <= 0 {
+		version = 1
+	}
+	return &FieldCipher{encryptor: encryptor, indexKey: indexKey, version: version}, nil
+}
+
+// Version is the key version recorded on rows this cipher encrypts
+func (c *FieldCipher) Version() int {
+	return c.version
+}
+
+// EncryptString encrypts a column value; empty values stay empty (nil) so
+// optional columns remain NULL.
+func (c *FieldCipher) EncryptString(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return c.encryptor.Encrypt([]byte(value))
+}
+
+func (c *FieldCipher) DecryptString(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+	plaintext, err := c.encryptor.Decrypt(data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex returns the hex HMAC-SHA256 of value under the index key. The
+// field name is mixed in so equal values in different columns do not share
+// an index. Callers normalize value first; an empty value has no index.
+func (c *FieldCipher) BlindIndex(field, value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
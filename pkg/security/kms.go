@@ -0,0 +1,172 @@
+package security
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownKEK = errors.New("unknown key-encryption key")
+	ErrNoKEK      = errors.New("no current key-encryption key configured")
+	ErrNoKEKFile  = errors.New("no KMS key file configured")
+)
+
+// KMS wraps data encryption keys under key-encryption keys it never hands
+// out. Implementations may be a cloud KMS, an HSM or the local simulator.
+type KMS interface {
+	// Wrap encrypts dataKey under the current key-encryption key and returns
+	// the ID of that key. aad is bound to the wrapped key and must be given
+	// again to unwrap it.
+	Wrap(ctx context.Context, dataKey, aad []byte) (wrapped []byte, kekID string, err error)
+	Unwrap(ctx context.Context, kekID string, wrapped, aad []byte) ([]byte, error)
+	// CurrentKeyID is the key-encryption key new data keys are wrapped with
+	CurrentKeyID(ctx context.Context) (string, error)
+}
+
+// LocalKMS simulates an HSM with key-encryption keys kept in a JSON file:
+//
+//	{"current": "kek-2024-01", "keys": {"kek-2024-01": "<64 hex chars>"}}
+//
+// The file is re-read when it changes, so a new key-encryption key is
+// rotated in by adding it and pointing "current" at it; older keys must stay
+// in the file until every data key has been rewrapped.
+type LocalKMS struct {
+	path string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+type localKeyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+func NewLocalKMS(path string) (*LocalKMS, error) {
+	if path == "" {
+		return nil, ErrNoKEKFile
+	}
+	k := &LocalKMS{path: path}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *LocalKMS) Wrap(ctx context.Context, dataKey, aad []byte) ([]byte, string, error) {
+	if err := k.reload(); err != nil {
+		return nil, "", err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.current == "" {
+		return nil, "", ErrNoKEK
+	}
+	gcm := k.keys[k.current]
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, "", ErrEncryption
+	}
+	return gcm.Seal(nonce, nonce, dataKey, aad), k.current, nil
+}
+
+func (k *LocalKMS) Unwrap(ctx context.Context, kekID string, wrapped, aad []byte) ([]byte, error) {
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	gcm, ok := k.keys[kekID]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKEK, kekID)
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(wrapped) < nonceSize {
+		return nil, ErrDecryption
+	}
+	dataKey, err := gcm.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], aad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return dataKey, nil
+}
+
+func (k *LocalKMS) CurrentKeyID(ctx context.Context) (string, error) {
+	if err := k.reload(); err != nil {
+		return "", err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.current == "" {
+		return "", ErrNoKEK
+	}
+	return k.current, nil
+}
+
+// reload reads the key file if it changed since it was last loaded
+func (k *LocalKMS) reload() error {
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return fmt.Errorf("failed to read KMS key file: %w", err)
+	}
+
+	k.mu.RLock()
+	fresh := info.ModTime().Equal(k.modTime) && k.keys != nil
+	k.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return fmt.Errorf("failed to read KMS key file: %w", err)
+	}
+	var file localKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid KMS key file: %w", err)
+	}
+
+	keys := make(map[string]cipher.AEAD, len(file.Keys))
+	for id, hexKey := range file.Keys {
+		raw, err := hex.DecodeString(hexKey)
+		if err != nil || len(raw) != 32 {
+			return fmt.Errorf("invalid KMS key %s: %w", id, ErrInvalidKeySize)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return ErrInvalidKeySize
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return ErrEncryption
+		}
+		keys[id] = gcm
+	}
+	if _, ok := keys[file.Current]; file.Current != "" && !ok {
+		return fmt.Errorf("%w: current key %s is not in the key file", ErrUnknownKEK, file.Current)
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.current = file.Current
+	k.modTime = info.ModTime()
+	k.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Store is a blob backend. Keys are slash-separated relative paths; the
+// backend decides how they map onto files or objects.
+type Store interface {
+	// Put writes the object, replacing any existing one. size is -1 when the
+	// length is not known up front.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Name() string
+}
+
+// Config selects and configures a backend
+type Config struct {
+	Backend  string
+	LocalDir string
+	S3       S3Config
+}
+
+// New builds the backend named in the config
+func New(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+	}
+}
+
+// cleanKey rejects keys that could escape the store's root
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || strings.HasPrefix(cleaned, "../") || cleaned == ".." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// ResolveFile joins a relative file name onto dir, rejecting names that
+// would resolve outside of it
+func ResolveFile(dir, file string) (string, error) {
+	if dir == "" || file == "" || filepath.IsAbs(file) {
+		return "", ErrInvalidKey
+	}
+	clean := filepath.Clean(file)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(dir, clean), nil
+}
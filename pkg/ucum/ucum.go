@@ -0,0 +1,165 @@
+// Package ucum converts measurements between the UCUM units used for vital
+// signs and common laboratory values.
+package ucum
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownUnit  = errors.New("ucum: unknown unit")
+	ErrIncompatible = errors.New("ucum: units measure different quantities")
+)
+
+// dimension names the kind of quantity a unit measures; only units of the
+// same dimension convert into each other
+type dimension string
+
+const (
+	dimPressure      dimension = "pressure"
+	dimRate          dimension = "rate"
+	dimTemperature   dimension = "temperature"
+	dimMass          dimension = "mass"
+	dimLength        dimension = "length"
+	dimArealDensity  dimension = "areal-density"
+	dimFraction      dimension = "fraction"
+	dimMassConc      dimension = "mass-concentration"
+	dimSubstanceConc dimension = "substance-concentration"
+)
+
+// unit converts to the base unit of its dimension as base = value*factor + offset
+type unit struct {
+	dim    dimension
+	factor float64
+	offset float64
+}
+
+var units = map[string]unit{
+	"mm[Hg]": {dim: dimPressure, factor: 1},
+	"kPa":    {dim: dimPressure, factor: 7.500615758},
+
+	"/min":          {dim: dimRate, factor: 1},
+	"{beats}/min":   {dim: dimRate, factor: 1},
+	"{breaths}/min": {dim: dimRate, factor: 1},
+
+	"Cel":    {dim: dimTemperature, factor: 1},
+	"[degF]": {dim: dimTemperature, factor: 5.0 / 9.0, offset: -32 * 5.0 / 9.0},
+	"K":      {dim: dimTemperature, factor: 1, offset: -273.15},
+
+	"kg":      {dim: dimMass, factor: 1},
+	"g":       {dim: dimMass, factor: 0.001},
+	"[lb_av]": {dim: dimMass, factor: 0.45359237},
+	"[oz_av]": {dim: dimMass, factor: 0.028349523125},
+
+	"cm":     {dim: dimLength, factor: 1},
+	"m":      {dim: dimLength, factor: 100},
+	"mm":     {dim: dimLength, factor: 0.1},
+	"[in_i]": {dim: dimLength, factor: 2.54},
+	"[ft_i]": {dim: dimLength, factor: 30.48},
+
+	"kg/m2": {dim: dimArealDensity, factor: 1},
+
+	"%": {dim: dimFraction, factor: 1},
+	"1": {dim: dimFraction, factor: 100},
+
+	"mg/dL": {dim: dimMassConc, factor: 1},
+	"g/L":   {dim: dimMassConc, factor: 100},
+	"mg/L":  {dim: dimMassConc, factor: 0.1},
+
+	"mmol/L": {dim: dimSubstanceConc, factor: 1},
+	"umol/L": {dim: dimSubstanceConc, factor: 0.001},
+}
+
+// aliases maps spellings people and devices send to UCUM codes
+var aliases = map[string]string{
+	"mmhg":      "mm[Hg]",
+	"bpm":       "/min",
+	"beats/min": "/min",
+	"1/min":     "/min",
+	"c":         "Cel",
+	"°c":        "Cel",
+	"degc":      "Cel",
+	"f":         "[degF]",
+	"°f":        "[degF]",
+	"degf":      "[degF]",
+	"lb":        "[lb_av]",
+	"lbs":       "[lb_av]",
+	"oz":        "[oz_av]",
+	"in":        "[in_i]",
+	"inch":      "[in_i]",
+	"ft":        "[ft_i]",
+	"kg/m^2":    "kg/m2",
+	"mg/dl":     "mg/dL",
+	"mmol/l":    "mmol/L",
+}
+
+// Parse returns the UCUM code for a unit given either as a UCUM code or as
+// one of its common spellings
+func Parse(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if _, ok := units[s]; ok {
+		return s, nil
+	}
+	if code, ok := aliases[strings.ToLower(s)]; ok {
+		return code, nil
+	}
+	// UCUM codes are case sensitive, but a lowercased code is not ambiguous
+	// among the units known here
+	for code := range units {
+		if strings.EqualFold(code, s) {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownUnit, s)
+}
+
+// Convert converts a value between two UCUM units of the same dimension
+func Convert(value float64, from, to string) (float64, error) {
+	f, ok := units[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	t, ok := units[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if f.dim != t.dim {
+		return 0, fmt.Errorf("%w: %s and %s", ErrIncompatible, from, to)
+	}
+	if from == to {
+		return value, nil
+	}
+	base := value*f.factor + f.offset
+	return (base - t.offset) / t.factor, nil
+}
+
+// ConvertSubstance is Convert that can also move between mass and substance
+// concentrations, such as mg/dL and mmol/L, given the molar mass of the
+// substance in g/mol
+func ConvertSubstance(value float64, from, to string, molarMass float64) (float64, error) {
+	f, ok := units[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	t, ok := units[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if f.dim == t.dim || molarMass <= 0 {
+		return Convert(value, from, to)
+	}
+
+	base := value * f.factor
+	switch {
+	case f.dim == dimMassConc && t.dim == dimSubstanceConc:
+		// mg/dL to mmol/L: mg/dL * 10 = mg/L, and mg/L / (g/mol) = mmol/L
+		base = base * 10 / molarMass
+	case f.dim == dimSubstanceConc && t.dim == dimMassConc:
+		base = base * molarMass / 10
+	default:
+		return 0, fmt.Errorf("%w: %s and %s", ErrIncompatible, from, to)
+	}
+	return base / t.factor, nil
+}
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/service/compliance"
+	"github.com/jwalitptl/admin-api/pkg/logger"
+)
+
+// ComplianceWorker drains queued data subject requests
+type ComplianceWorker struct {
+	service   *compliance.Service
+	batchSize int
+	interval  time.Duration
+	logger    *logger.Logger
+}
+
+func NewComplianceWorker(service *compliance.Service, batchSize int, interval time.Duration, logger *logger.Logger) *ComplianceWorker {
+	if batchSize <= 0 {
+		batchSize = 10
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &ComplianceWorker{
+		service:   service,
+		batchSize: batchSize,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+func (w *ComplianceWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := w.service.ProcessPending(ctx, w.batchSize)
+			if err != nil {
+				w.logger.Error(err, "failed to process compliance requests")
+				continue
+			}
+			if n > 0 {
+				w.logger.Info("processed compliance requests", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/jwalitptl/admin-api/pkg/logger"
+)
+
+// Rewrapper rewraps data keys under the current key-encryption key
+type Rewrapper interface {
+	RewrapBatch(ctx context.Context, limit int) (int, error)
+}
+
+// Reencrypter moves encrypted rows onto their organization's active data key
+type Reencrypter interface {
+	ReencryptBatch(ctx context.Context, limit int) (int, error)
+}
+
+// KeyRotationWorker finishes key rotations in the background. After a
+// key-encryption key rotation it rewraps the data keys; after a data key
+// rotation it re-encrypts rows still sealed under a retired key. Old keys keep
+// decrypting meanwhile, so nothing waits on the job.
+type KeyRotationWorker struct {
+	keys      Rewrapper
+	data      []Reencrypter
+	batchSize int
+	interval  time.Duration
+	logger    *logger.Logger
+}
+
+func NewKeyRotationWorker(keys Rewrapper, data []Reencrypter, batchSize int, interval time.Duration, logger *logger.Logger) *KeyRotationWorker {
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &KeyRotationWorker{
+		keys:      keys,
+		data:      data,
+		batchSize: batchSize,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Start drains both backlogs batch by batch, then polls at the interval for
+// the next rotation.
+func (w *KeyRotationWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx, "rewrapped data keys", w.keys.RewrapBatch)
+		for _, d := range w.data {
+			w.drain(ctx, "re-encrypted rows", d.ReencryptBatch)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *KeyRotationWorker) drain(ctx context.Context, what string, batch func(context.Context, int) (int, error)) {
+	for ctx.Err() == nil {
+		n, err := batch(ctx, w.batchSize)
+		if err != nil {
+			w.logger.Error(err, "key rotation batch failed", "job", what)
+			return
+		}
+		if n > 0 {
+			w.logger.Info(what, "count", n)
+		}
+		if n < w.batchSize {
+			return
+		}
+	}
+}
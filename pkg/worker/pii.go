@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/repository"
+	"github.com/jwalitptl/admin-api/pkg/logger"
+)
+
+// PIIBackfillWorker encrypts patient rows that still hold plaintext PII. It
+// works in small batches, each in its own transaction, so the table stays
+// available while existing data is migrated.
+type PIIBackfillWorker struct {
+	repo      repository.PatientRepository
+	batchSize int
+	interval  time.Duration
+	logger    *logger.Logger
+}
+
+func NewPIIBackfillWorker(repo repository.PatientRepository, batchSize int, interval time.Duration, logger *logger.Logger) *PIIBackfillWorker {
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &PIIBackfillWorker{
+		repo:      repo,
+		batchSize: batchSize,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Start drains the backlog batch by batch, then keeps polling at the interval
+// to pick up rows written by instances that still run without encryption.
+func (w *PIIBackfillWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := w.repo.EncryptPlaintextBatch(ctx, w.batchSize)
+			if err != nil {
+				w.logger.Error(err, "failed to encrypt patient PII batch")
+				break
+			}
+			if n > 0 {
+				w.logger.Info("encrypted patient PII", "count", n)
+			}
+			if n < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/service/reminder"
+	"github.com/jwalitptl/admin-api/pkg/logger"
+)
+
+// ReminderWorker sends appointment reminders as they fall due. Reminders are
+// claimed in the database, so several workers can run side by side.
+type ReminderWorker struct {
+	service  *reminder.Service
+	interval time.Duration
+	logger   *logger.Logger
+}
+
+func NewReminderWorker(service *reminder.Service, interval time.Duration, logger *logger.Logger) *ReminderWorker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &ReminderWorker{
+		service:  service,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *ReminderWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := w.service.SendDue(ctx)
+			if err != nil {
+				w.logger.Error(err, "failed to send appointment reminders")
+				continue
+			}
+			if n > 0 {
+				w.logger.Info("sent appointment reminders", "count", n)
+			}
+		}
+	}
+}
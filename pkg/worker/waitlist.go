@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/jwalitptl/admin-api/internal/service/waitlist"
+	"github.com/jwalitptl/admin-api/pkg/logger"
+)
+
+// WaitlistWorker expires unanswered waitlist offers so their slots are
+// offered to the next patients
+type WaitlistWorker struct {
+	service  *waitlist.Service
+	interval time.Duration
+	logger   *logger.Logger
+}
+
+func NewWaitlistWorker(service *waitlist.Service, interval time.Duration, logger *logger.Logger) *WaitlistWorker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &WaitlistWorker{
+		service:  service,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+func (w *WaitlistWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := w.service.ExpireOffers(ctx)
+			if err != nil {
+				w.logger.Error(err, "failed to expire waitlist offers")
+				continue
+			}
+			if n > 0 {
+				w.logger.Info("expired waitlist offers", "count", n)
+			}
+		}
+	}
+}